@@ -0,0 +1,201 @@
+package authguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// Identifier identifies a login attempt. Failures are counted per account and IP pair, so an attacker
+// cannot lock an account out for its owner from a single IP, and per IP across accounts
+type Identifier struct {
+	Account string
+	IP      string
+}
+
+// Config configures a Guard, empty fields get defaults
+type Config struct {
+	// MaxAttempts is the number of failures of an account from one IP before it is locked, 5 by default
+	MaxAttempts int64
+	// IPMaxAttempts is the number of failures from one IP across accounts before the IP is locked,
+	// 20 by default, negative disables the IP counter
+	IPMaxAttempts int64
+	// AccountMaxAttempts is the number of failures of an account across IPs before it is locked from
+	// everywhere, disabled by default since it lets attackers lock accounts out
+	AccountMaxAttempts int64
+	// BaseLockout is the first lockout duration, doubled (see Multiplier) on every further failure,
+	// 1 minute by default
+	BaseLockout time.Duration
+	// MaxLockout caps the lockout duration, 1 hour by default
+	MaxLockout time.Duration
+	// Multiplier is the growth factor of consecutive lockouts, 2 by default
+	Multiplier float64
+	// FailureWindow is how long failures are remembered after the last one, 15 minutes by default
+	FailureWindow time.Duration
+	// KeyPrefix prefixes the Redis keys, "authguard" by default
+	KeyPrefix string
+	// Logger logs Redis failures, the standard logrus logger by default
+	Logger *logrus.Logger
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+}
+
+// withDefaults fills the empty fields of cfg
+func (cfg Config) withDefaults() Config {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.IPMaxAttempts == 0 {
+		cfg.IPMaxAttempts = 20
+	}
+	if cfg.BaseLockout <= 0 {
+		cfg.BaseLockout = time.Minute
+	}
+	if cfg.MaxLockout <= 0 {
+		cfg.MaxLockout = time.Hour
+	}
+	if cfg.MaxLockout < cfg.BaseLockout {
+		cfg.MaxLockout = cfg.BaseLockout
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = 2
+	}
+	if cfg.FailureWindow <= 0 {
+		cfg.FailureWindow = 15 * time.Minute
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "authguard"
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return cfg
+}
+
+// Guard throttles login attempts with exponential lockouts stored in Redis
+type Guard struct {
+	rc  redis.RedisClient
+	cfg Config
+}
+
+// New returns a Guard storing its counters in rc
+func New(rc redis.RedisClient, cfg Config) *Guard {
+	return &Guard{rc: rc, cfg: cfg.withDefaults()}
+}
+
+// counter is one failure counter of an attempt with its lockout threshold
+type counter struct {
+	key       string
+	threshold int64
+}
+
+// counters returns the counters of id, the account and IP pair first
+func (g *Guard) counters(id Identifier) []counter {
+	account := hashAccount(id.Account)
+	counters := []counter{{key: "pair:" + account + ":" + id.IP, threshold: g.cfg.MaxAttempts}}
+	if g.cfg.IPMaxAttempts > 0 && id.IP != "" {
+		counters = append(counters, counter{key: "ip:" + id.IP, threshold: g.cfg.IPMaxAttempts})
+	}
+	if g.cfg.AccountMaxAttempts > 0 {
+		counters = append(counters, counter{key: "account:" + account, threshold: g.cfg.AccountMaxAttempts})
+	}
+	return counters
+}
+
+// CheckLoginAllowed reports whether a login attempt may proceed and, when locked, how long to wait
+func (g *Guard) CheckLoginAllowed(ctx context.Context, id Identifier) (bool, time.Duration, error) {
+	now := g.cfg.Now()
+	var retryAfter time.Duration
+	for _, counter := range g.counters(id) {
+		value, err := g.rc.Get(ctx, g.lockKey(counter.key))
+		if errors.Is(err, goredis.Nil) {
+			continue
+		}
+		if err != nil {
+			return true, 0, err
+		}
+		until, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		retryAfter = max(retryAfter, time.Unix(0, until).Sub(now))
+	}
+	return retryAfter <= 0, max(retryAfter, 0), nil
+}
+
+// RecordFailedLogin counts a failed attempt, locking the counters reaching their threshold for
+// BaseLockout * Multiplier^(failures - threshold), at most MaxLockout
+func (g *Guard) RecordFailedLogin(ctx context.Context, id Identifier) error {
+	now := g.cfg.Now()
+	var errs []error
+	for _, counter := range g.counters(id) {
+		failures, err := g.rc.Incr(ctx, g.failuresKey(counter.key))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lockout := g.lockout(failures - counter.threshold)
+		// Failures decay once no attempt failed for FailureWindow after the lockout
+		if err := g.rc.Expire(ctx, g.failuresKey(counter.key), lockout+g.cfg.FailureWindow); err != nil {
+			errs = append(errs, err)
+		}
+		if lockout <= 0 {
+			continue
+		}
+		until := now.Add(lockout).UnixNano()
+		if err := g.rc.Set(ctx, g.lockKey(counter.key), strconv.FormatInt(until, 10), lockout); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		g.cfg.Logger.WithField("counter", counter.key).Warnf("authguard: login locked for %s after %d failures", lockout, failures)
+	}
+	return errors.Join(errs...)
+}
+
+// ResetOnSuccess clears the failures and lockout of the account and IP pair after a successful login.
+// The IP and account counters keep running so a sprayer succeeding once is still throttled
+func (g *Guard) ResetOnSuccess(ctx context.Context, id Identifier) error {
+	pair := g.counters(id)[0]
+	return errors.Join(
+		g.rc.Del(ctx, g.failuresKey(pair.key)),
+		g.rc.Del(ctx, g.lockKey(pair.key)),
+	)
+}
+
+// lockout returns the lockout duration after excess failures past the threshold, 0 below it
+func (g *Guard) lockout(excess int64) time.Duration {
+	if excess < 0 {
+		return 0
+	}
+	lockout := float64(g.cfg.BaseLockout) * math.Pow(g.cfg.Multiplier, float64(excess))
+	if lockout >= float64(g.cfg.MaxLockout) {
+		return g.cfg.MaxLockout
+	}
+	return time.Duration(lockout)
+}
+
+func (g *Guard) failuresKey(key string) string {
+	return g.cfg.KeyPrefix + ":failures:" + key
+}
+
+func (g *Guard) lockKey(key string) string {
+	return g.cfg.KeyPrefix + ":lock:" + key
+}
+
+// hashAccount keeps account names out of Redis and bounds the key length
+func hashAccount(account string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(account))))
+	return hex.EncodeToString(sum[:16])
+}
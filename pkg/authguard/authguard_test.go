@@ -0,0 +1,167 @@
+package authguard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+)
+
+// newTestGuard returns a Guard over a fake Redis whose clock it uses
+func newTestGuard(cfg Config) (*Guard, *redistest.Client) {
+	rc := redistest.New()
+	logger, _ := test.NewNullLogger()
+	cfg.Logger = logger
+	cfg.Now = rc.Now
+	return New(rc, cfg), rc
+}
+
+// fail records n failed logins of id
+func fail(t *testing.T, g *Guard, id Identifier, n int) {
+	t.Helper()
+	for range n {
+		if err := g.RecordFailedLogin(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// retryAfter returns how long id is locked, zero when allowed
+func retryAfter(t *testing.T, g *Guard, id Identifier) time.Duration {
+	t.Helper()
+	allowed, retryAfter, err := g.CheckLoginAllowed(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed != (retryAfter == 0) {
+		t.Fatalf("allowed = %v with retryAfter %s", allowed, retryAfter)
+	}
+	return retryAfter
+}
+
+func TestExponentialLockout(t *testing.T) {
+	g, rc := newTestGuard(Config{MaxAttempts: 3, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute})
+	id := Identifier{Account: "alice@example.com", IP: "10.0.0.1"}
+
+	fail(t, g, id, 2)
+	if d := retryAfter(t, g, id); d != 0 {
+		t.Fatalf("locked for %s below the threshold", d)
+	}
+
+	// Each failure past the threshold doubles the lockout up to MaxLockout
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		fail(t, g, id, 1)
+		if d := retryAfter(t, g, id); d != want {
+			t.Fatalf("lockout = %s, want %s", d, want)
+		}
+	}
+
+	rc.Advance(2 * time.Minute)
+	if d := retryAfter(t, g, id); d != 3*time.Minute {
+		t.Errorf("lockout after 2m = %s, want the 3m left", d)
+	}
+	rc.Advance(3 * time.Minute)
+	if d := retryAfter(t, g, id); d != 0 {
+		t.Errorf("still locked for %s once the lockout elapsed", d)
+	}
+}
+
+func TestFailuresDecay(t *testing.T) {
+	g, rc := newTestGuard(Config{MaxAttempts: 3, BaseLockout: time.Minute, FailureWindow: 10 * time.Minute})
+	id := Identifier{Account: "alice@example.com", IP: "10.0.0.1"}
+
+	fail(t, g, id, 2)
+	rc.Advance(9 * time.Minute)
+	fail(t, g, id, 1)
+	if d := retryAfter(t, g, id); d != time.Minute {
+		t.Fatalf("lockout = %s, want the failures counted within the window", d)
+	}
+
+	// The failures are forgotten FailureWindow after the lockout ends
+	rc.Advance(time.Minute + 10*time.Minute)
+	fail(t, g, id, 2)
+	if d := retryAfter(t, g, id); d != 0 {
+		t.Errorf("locked for %s after the failures decayed", d)
+	}
+	fail(t, g, id, 1)
+	if d := retryAfter(t, g, id); d != time.Minute {
+		t.Errorf("lockout = %s, want the base lockout again after decay", d)
+	}
+}
+
+func TestSeparateCounters(t *testing.T) {
+	g, _ := newTestGuard(Config{MaxAttempts: 2, IPMaxAttempts: 3})
+	attacker := Identifier{Account: "alice@example.com", IP: "10.0.0.1"}
+
+	fail(t, g, attacker, 2)
+	if retryAfter(t, g, attacker) == 0 {
+		t.Fatal("account and IP pair not locked")
+	}
+	if d := retryAfter(t, g, Identifier{Account: "Alice@Example.com ", IP: "10.0.0.2"}); d != 0 {
+		t.Errorf("owner locked out from another IP for %s", d)
+	}
+
+	// The IP counter spans accounts
+	fail(t, g, Identifier{Account: "bob@example.com", IP: "10.0.0.1"}, 1)
+	if retryAfter(t, g, Identifier{Account: "carol@example.com", IP: "10.0.0.1"}) == 0 {
+		t.Error("IP not locked after IPMaxAttempts failures across accounts")
+	}
+	if d := retryAfter(t, g, Identifier{Account: "carol@example.com", IP: "10.0.0.3"}); d != 0 {
+		t.Errorf("other IP locked for %s", d)
+	}
+}
+
+func TestAccountCounter(t *testing.T) {
+	g, _ := newTestGuard(Config{MaxAttempts: 10, IPMaxAttempts: -1, AccountMaxAttempts: 3})
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		fail(t, g, Identifier{Account: "alice@example.com", IP: ip}, 1)
+		locked := retryAfter(t, g, Identifier{Account: "alice@example.com", IP: "10.0.0.9"}) > 0
+		if want := i == 2; locked != want {
+			t.Errorf("after %d failures across IPs locked = %v, want %v", i+1, locked, want)
+		}
+	}
+}
+
+func TestResetOnSuccess(t *testing.T) {
+	g, _ := newTestGuard(Config{MaxAttempts: 2, IPMaxAttempts: 3})
+	id := Identifier{Account: "alice@example.com", IP: "10.0.0.1"}
+
+	fail(t, g, id, 2)
+	if err := g.ResetOnSuccess(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	if d := retryAfter(t, g, id); d != 0 {
+		t.Fatalf("locked for %s after a successful login", d)
+	}
+	fail(t, g, id, 1)
+	if retryAfter(t, g, id) == 0 {
+		t.Error("the IP counter was reset by a successful login")
+	}
+}
+
+func TestRedisDown(t *testing.T) {
+	g, rc := newTestGuard(Config{})
+	id := Identifier{Account: "alice@example.com", IP: "10.0.0.1"}
+	rc.SetDown(true)
+
+	if allowed, _, err := g.CheckLoginAllowed(context.Background(), id); !allowed || !errors.Is(err, redistest.ErrDown) {
+		t.Errorf("CheckLoginAllowed = %v, %v, want allowed with the Redis error", allowed, err)
+	}
+	if err := g.RecordFailedLogin(context.Background(), id); !errors.Is(err, redistest.ErrDown) {
+		t.Errorf("RecordFailedLogin error = %v, want the Redis error", err)
+	}
+}
+
+func TestAccountKeysAreHashed(t *testing.T) {
+	g, rc := newTestGuard(Config{})
+	fail(t, g, Identifier{Account: "alice@example.com", IP: "10.0.0.1"}, 1)
+	for _, key := range rc.Keys() {
+		if strings.Contains(key, "alice") {
+			t.Errorf("key %q contains the account name", key)
+		}
+	}
+}
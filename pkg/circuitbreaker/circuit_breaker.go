@@ -0,0 +1,273 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// StateClosed lets calls through while recording their outcome
+	StateClosed State = iota
+	// StateOpen rejects calls with ErrOpen until the cool-down elapses
+	StateOpen
+	// StateHalfOpen lets a few probe calls through to decide whether the dependency recovered
+	StateHalfOpen
+)
+
+// String returns the state name
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrOpen is returned without calling the dependency while the circuit is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// windowBuckets is the number of buckets of the sliding window
+const windowBuckets = 10
+
+// Config configures circuit breakers, empty fields get defaults
+type Config struct {
+	// Window is the sliding window over which the failure rate is computed, 10 seconds by default
+	Window time.Duration
+	// MinRequests is the number of calls in the window before the breaker may open, 20 by default
+	MinRequests int
+	// FailureRate opens the breaker when reached, between 0 and 1, 0.5 by default
+	FailureRate float64
+	// CoolDown is how long the breaker stays open before probing, 30 seconds by default
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of successful probes closing the breaker, 1 by default
+	HalfOpenRequests int
+	// IsFailure reports whether an error counts as failure, by default any error except context cancellation
+	IsFailure func(err error) bool
+	// OnStateChange is called on every transition, e.g. to export metrics
+	OnStateChange func(name string, from, to State)
+	// Logger logs transitions, the standard logrus logger by default
+	Logger *logrus.Logger
+	// Now returns the current time, time.Now by default. Tests may inject a fake clock
+	Now func() time.Time
+}
+
+// withDefaults fills the empty fields of cfg
+func (cfg Config) withDefaults() Config {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return cfg
+}
+
+// bucket counts the calls of one slice of the sliding window
+type bucket struct {
+	epoch    int64
+	total    int
+	failures int
+}
+
+// Breaker is a circuit breaker protecting one dependency
+type Breaker struct {
+	name string
+	cfg  Config
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	openedAt   time.Time
+	buckets    [windowBuckets]bucket
+	probes     int
+	successes  int
+}
+
+// New creates a closed circuit breaker
+func New(name string, cfg Config) *Breaker {
+	return &Breaker{name: name, cfg: cfg.withDefaults()}
+}
+
+// Name returns the breaker name
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state, moving an open breaker past its cool-down to half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(b.cfg.Now())
+	return b.state
+}
+
+// RetryAfter returns how long an open breaker keeps rejecting calls, zero when it is not open
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateOpen {
+		return 0
+	}
+	return max(b.cfg.CoolDown-b.cfg.Now().Sub(b.openedAt), 0)
+}
+
+// Allow reserves a call, returning ErrOpen when it must be rejected. Otherwise the returned done
+// must be called exactly once with the outcome of the call
+func (b *Breaker) Allow() (done func(failed bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(b.cfg.Now())
+	switch b.state {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+
+	generation := b.generation
+	var once sync.Once
+	return func(failed bool) {
+		once.Do(func() { b.record(generation, failed) })
+	}, nil
+}
+
+// Do calls fn unless the breaker is open, recording its outcome
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx)
+	done(b.cfg.IsFailure(err))
+	return err
+}
+
+// record adds the outcome of a call allowed during generation, outcomes of previous states are ignored
+func (b *Breaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+	now := b.cfg.Now()
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.transition(StateOpen, now)
+			return
+		}
+		if b.successes++; b.successes >= b.cfg.HalfOpenRequests {
+			b.transition(StateClosed, now)
+		}
+	case StateClosed:
+		current := b.bucketAt(now)
+		current.total++
+		if failed {
+			current.failures++
+		}
+
+		total, failures := b.counts(now)
+		if total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.FailureRate {
+			b.transition(StateOpen, now)
+		}
+	}
+}
+
+// refresh moves an open breaker whose cool-down elapsed to half-open
+func (b *Breaker) refresh(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cfg.CoolDown {
+		b.transition(StateHalfOpen, now)
+	}
+}
+
+// transition changes the state, resetting the counters of the previous state
+func (b *Breaker) transition(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.probes, b.successes = 0, 0
+	b.buckets = [windowBuckets]bucket{}
+	if to == StateOpen {
+		b.openedAt = now
+	}
+
+	logger := b.cfg.Logger.WithFields(logrus.Fields{
+		"circuit_breaker": b.name,
+		"from":            from.String(),
+		"to":              to.String(),
+	})
+	if to == StateOpen {
+		logger.Warn("circuit breaker opened")
+	} else {
+		logger.Info("circuit breaker state changed")
+	}
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.name, from, to)
+	}
+}
+
+// bucketAt returns the window bucket of now, reset when it belongs to an elapsed slice
+func (b *Breaker) bucketAt(now time.Time) *bucket {
+	epoch := now.UnixNano() / int64(b.bucketDuration())
+	current := &b.buckets[epoch%windowBuckets]
+	if current.epoch != epoch {
+		*current = bucket{epoch: epoch}
+	}
+	return current
+}
+
+// counts sums the calls and failures of the sliding window ending at now
+func (b *Breaker) counts(now time.Time) (total, failures int) {
+	epoch := now.UnixNano() / int64(b.bucketDuration())
+	for _, bucket := range b.buckets {
+		if bucket.epoch > epoch-windowBuckets {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+	return total, failures
+}
+
+// bucketDuration is the time slice covered by one window bucket
+func (b *Breaker) bucketDuration() time.Duration {
+	return max(b.cfg.Window/windowBuckets, time.Nanosecond)
+}
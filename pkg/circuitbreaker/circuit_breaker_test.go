@@ -0,0 +1,221 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+var errDependency = errors.New("dependency down")
+
+// newTestBreaker returns a breaker opening after 4 calls at a 50% failure rate over 10 seconds, with a
+// 30 seconds cool-down, recording its transitions
+func newTestBreaker(clock *fakeClock, transitions *[]string) *Breaker {
+	logger, _ := test.NewNullLogger()
+	return New("db", Config{
+		Window:      10 * time.Second,
+		MinRequests: 4,
+		FailureRate: 0.5,
+		CoolDown:    30 * time.Second,
+		Logger:      logger,
+		Now:         clock.Now,
+		OnStateChange: func(name string, from, to State) {
+			*transitions = append(*transitions, from.String()+">"+to.String())
+		},
+	})
+}
+
+// call runs a call through b failing when failed is true
+func call(b *Breaker, failed bool) error {
+	return b.Do(context.Background(), func(ctx context.Context) error {
+		if failed {
+			return errDependency
+		}
+		return nil
+	})
+}
+
+func TestBreakerOpensOnFailureRate(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	var transitions []string
+	b := newTestBreaker(clock, &transitions)
+
+	call(b, true)
+	call(b, true)
+	call(b, true)
+	if b.State() != StateClosed {
+		t.Fatalf("state = %s below MinRequests, want closed", b.State())
+	}
+	call(b, false)
+	if b.State() != StateOpen {
+		t.Fatalf("state = %s at 75%% failures, want open", b.State())
+	}
+
+	called := false
+	err := b.Do(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrOpen) || called {
+		t.Errorf("Do while open = %v, called %v, want ErrOpen without calling fn", err, called)
+	}
+	if got := b.RetryAfter(); got != 30*time.Second {
+		t.Errorf("RetryAfter = %s, want the cool-down", got)
+	}
+	clock.Advance(10 * time.Second)
+	if got := b.RetryAfter(); got != 20*time.Second {
+		t.Errorf("RetryAfter = %s, want the remaining cool-down", got)
+	}
+	if len(transitions) != 1 || transitions[0] != "closed>open" {
+		t.Errorf("transitions = %v", transitions)
+	}
+}
+
+func TestBreakerStaysClosedUnderThreshold(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	var transitions []string
+	b := newTestBreaker(clock, &transitions)
+
+	for range 10 {
+		call(b, false)
+		call(b, false)
+		call(b, true)
+	}
+	if b.State() != StateClosed {
+		t.Errorf("state = %s at 33%% failures, want closed", b.State())
+	}
+}
+
+func TestBreakerSlidingWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	var transitions []string
+	b := newTestBreaker(clock, &transitions)
+
+	call(b, true)
+	call(b, true)
+	call(b, true)
+	// The failures leave the window before the next calls
+	clock.Advance(11 * time.Second)
+	call(b, true)
+	call(b, false)
+	call(b, false)
+	if b.State() != StateClosed {
+		t.Errorf("state = %s, want the expired failures not counted", b.State())
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	var transitions []string
+	b := newTestBreaker(clock, &transitions)
+	for range 4 {
+		call(b, true)
+	}
+
+	clock.Advance(30 * time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state = %s after the cool-down, want half-open", b.State())
+	}
+
+	// A single probe is allowed at a time
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("second probe = %v, want ErrOpen", err)
+	}
+	done(true)
+	if b.State() != StateOpen {
+		t.Fatalf("state = %s after a failed probe, want open", b.State())
+	}
+
+	clock.Advance(30 * time.Second)
+	if err := call(b, false); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != StateClosed {
+		t.Errorf("state = %s after a successful probe, want closed", b.State())
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions = %v, want %v", transitions, want)
+			break
+		}
+	}
+}
+
+func TestBreakerIgnoresStaleOutcomes(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	var transitions []string
+	b := newTestBreaker(clock, &transitions)
+
+	// A call allowed while closed finishes after the breaker opened and half-opened
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		call(b, true)
+	}
+	clock.Advance(30 * time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatal("breaker not half-open")
+	}
+	done(true)
+	if b.State() != StateHalfOpen {
+		t.Errorf("state = %s, want the outcome of the closed state ignored", b.State())
+	}
+}
+
+func TestBreakerIgnoresCancellation(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	var transitions []string
+	b := newTestBreaker(clock, &transitions)
+
+	for range 10 {
+		b.Do(context.Background(), func(ctx context.Context) error { return context.Canceled })
+	}
+	if b.State() != StateClosed {
+		t.Errorf("state = %s, want cancellations not counted as failures", b.State())
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	registry := NewRegistry(Config{MinRequests: 1, Logger: logger})
+
+	if registry.Get("redis") != registry.Get("redis") {
+		t.Error("Get created a second breaker for the same name")
+	}
+	registry.Do(context.Background(), "db", func(ctx context.Context) error { return errDependency })
+
+	states := registry.States()
+	if states["db"] != "open" || states["redis"] != "closed" {
+		t.Errorf("States() = %v", states)
+	}
+	if got := registry.Expvar().String(); got != `{"db":"open","redis":"closed"}` {
+		t.Errorf("Expvar() = %s", got)
+	}
+}
@@ -0,0 +1,76 @@
+package circuitbreaker
+
+import (
+	"context"
+	"expvar"
+	"sync"
+)
+
+// Registry creates and keeps one breaker per name, sharing a configuration
+type Registry struct {
+	cfg Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry creates a registry whose breakers use cfg
+func NewRegistry(cfg Config) *Registry {
+	return &Registry{
+		cfg:      cfg,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Get returns the breaker of name, creating it on first use
+func (r *Registry) Get(name string) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breaker, ok := r.breakers[name]
+	if !ok {
+		breaker = New(name, r.cfg)
+		r.breakers[name] = breaker
+	}
+	return breaker
+}
+
+// Do calls fn through the breaker of name
+func (r *Registry) Do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return r.Get(name).Do(ctx, fn)
+}
+
+// States returns the state name of every breaker
+func (r *Registry) States() map[string]string {
+	r.mu.Lock()
+	breakers := make([]*Breaker, 0, len(r.breakers))
+	for _, breaker := range r.breakers {
+		breakers = append(breakers, breaker)
+	}
+	r.mu.Unlock()
+
+	states := make(map[string]string, len(breakers))
+	for _, breaker := range breakers {
+		states[breaker.Name()] = breaker.State().String()
+	}
+	return states
+}
+
+// Expvar returns an expvar variable reporting States, to register with expvar.Publish
+func (r *Registry) Expvar() expvar.Var {
+	return expvar.Func(func() any { return r.States() })
+}
+
+// defaultRegistry backs the package level Do
+var defaultRegistry = NewRegistry(Config{})
+
+// Default returns the registry used by Do
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Do calls fn through the breaker of name from the default registry, e.g. around repository,
+// Redis or HTTP calls. It returns ErrOpen without calling fn while the dependency is failing
+func Do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return defaultRegistry.Do(ctx, name, fn)
+}
@@ -0,0 +1,201 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrorCodeDefinition describes a registered application error code
+type ErrorCodeDefinition struct {
+	// Code is the business error identity exposed to clients (e.g. "USER_EMAIL_TAKEN")
+	Code string
+	// ResponseCode is the code written in the response body
+	ResponseCode ResponseCode
+	// HTTPStatus is the HTTP status of the response
+	HTTPStatus int
+	// MessageKey is the i18n key of the client-facing message
+	MessageKey string
+}
+
+var (
+	errorCodeRegistry   = make(map[string]ErrorCodeDefinition)
+	errorCodeRegistryMu sync.RWMutex
+)
+
+// RegisterErrorCode registers an application error code in the catalog.
+// It returns an error if the code is empty or already registered
+func RegisterErrorCode(code string, responseCode ResponseCode, httpStatus int, messageKey string) error {
+	if code == "" {
+		return fmt.Errorf("error code must not be empty")
+	}
+
+	errorCodeRegistryMu.Lock()
+	defer errorCodeRegistryMu.Unlock()
+
+	if _, exists := errorCodeRegistry[code]; exists {
+		return fmt.Errorf("error code %s is already registered", code)
+	}
+
+	errorCodeRegistry[code] = ErrorCodeDefinition{
+		Code:         code,
+		ResponseCode: responseCode,
+		HTTPStatus:   httpStatus,
+		MessageKey:   messageKey,
+	}
+	return nil
+}
+
+// MustRegisterErrorCode registers an application error code and panics on failure.
+// It is intended for package-level initialization
+func MustRegisterErrorCode(code string, responseCode ResponseCode, httpStatus int, messageKey string) {
+	if err := RegisterErrorCode(code, responseCode, httpStatus, messageKey); err != nil {
+		panic(err)
+	}
+}
+
+// LookupErrorCode returns the definition of a registered application error code
+func LookupErrorCode(code string) (ErrorCodeDefinition, bool) {
+	errorCodeRegistryMu.RLock()
+	defer errorCodeRegistryMu.RUnlock()
+
+	definition, ok := errorCodeRegistry[code]
+	return definition, ok
+}
+
+// AppError represents a business error identified by a string code
+type AppError struct {
+	Code         string
+	ResponseCode ResponseCode
+	HTTPStatus   int
+	MessageKey   string
+	Details      []ErrorDetail
+}
+
+// NewAppError creates an AppError from a registered error code.
+// Unknown codes are reported as internal errors while keeping the code for diagnosis
+func NewAppError(code string) *AppError {
+	definition, ok := LookupErrorCode(code)
+	if !ok {
+		return &AppError{
+			Code:         code,
+			ResponseCode: INTERNAL_ERROR,
+			HTTPStatus:   http.StatusInternalServerError,
+			MessageKey:   MsgErrorInternal,
+		}
+	}
+
+	return &AppError{
+		Code:         definition.Code,
+		ResponseCode: definition.ResponseCode,
+		HTTPStatus:   definition.HTTPStatus,
+		MessageKey:   definition.MessageKey,
+	}
+}
+
+// WithDetails appends error details
+func (e *AppError) WithDetails(details ...ErrorDetail) *AppError {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
+// Error implements the error interface
+func (e *AppError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.MessageKey)
+}
+
+// ToErrorResponse converts the AppError to an ErrorResponse carrying the error code.
+// Message holds the i18n key, which BaseController.Error translates with the request locale
+func (e *AppError) ToErrorResponse() *ErrorResponse {
+	errorResponse := CreateErrorResponse(e.ResponseCode, e.MessageKey, e.Details...)
+	errorResponse.ErrorCode = e.Code
+	return errorResponse
+}
+
+// HTTPStatusFor returns the HTTP status for an error response.
+// Registered error codes take precedence over the generic ResponseCode mapping
+func HTTPStatusFor(err *ErrorResponse) int {
+	if err == nil {
+		return http.StatusInternalServerError
+	}
+	if err.ErrorCode != "" {
+		if definition, ok := LookupErrorCode(err.ErrorCode); ok && definition.HTTPStatus != 0 {
+			return definition.HTTPStatus
+		}
+	}
+	return httpStatusForCode(err.Code)
+}
+
+// httpStatusForCode maps a ResponseCode to an HTTP status code
+func httpStatusForCode(code ResponseCode) int {
+	switch code {
+	case VALIDATION_ERROR: // VALIDATION_ERROR or BAD_REQUEST
+		return http.StatusBadRequest
+	case NOT_FOUND:
+		return http.StatusNotFound
+	case UNAUTHORIZED:
+		return http.StatusUnauthorized
+	case FORBIDDEN:
+		return http.StatusForbidden
+	case CONFLICT:
+		return http.StatusConflict
+	case INTERNAL_ERROR:
+		return http.StatusInternalServerError
+	case METHOD_NOT_ALLOWED:
+		return http.StatusMethodNotAllowed
+	case REQUEST_TIMEOUT:
+		return http.StatusRequestTimeout
+	case TOO_MANY_REQUESTS:
+		return http.StatusTooManyRequests
+	case SERVICE_UNAVAILABLE:
+		return http.StatusServiceUnavailable
+	case GATEWAY_TIMEOUT:
+		return http.StatusGatewayTimeout
+	default:
+		// Codes carrying an HTTP error status (e.g. from an echo.HTTPError) keep it
+		if code >= 400 && code < 600 {
+			return int(code)
+		}
+		return http.StatusInternalServerError
+	}
+}
+
+// ResponseCodeForHTTPStatus maps an HTTP error status to a ResponseCode, the reverse of HTTPStatusFor.
+// Statuses outside the 4xx and 5xx ranges are reported as INTERNAL_ERROR
+func ResponseCodeForHTTPStatus(status int) ResponseCode {
+	if status < 400 || status >= 600 {
+		return INTERNAL_ERROR
+	}
+	return ResponseCode(status)
+}
+
+// MessageKeyForCode returns the i18n key of the generic message of a ResponseCode
+func MessageKeyForCode(code ResponseCode) string {
+	switch code {
+	case BAD_REQUEST: // BAD_REQUEST or VALIDATION_ERROR
+		return MsgErrorBadRequest
+	case UNAUTHORIZED:
+		return MsgErrorUnauthorized
+	case FORBIDDEN:
+		return MsgErrorForbidden
+	case NOT_FOUND:
+		return MsgErrorNotFound
+	case METHOD_NOT_ALLOWED:
+		return MsgErrorMethodNotAllowed
+	case REQUEST_TIMEOUT:
+		return MsgErrorRequestTimeout
+	case CONFLICT:
+		return MsgErrorConflict
+	case TOO_MANY_REQUESTS:
+		return MsgErrorTooManyRequests
+	case SERVICE_UNAVAILABLE:
+		return MsgErrorServiceUnavailable
+	case GATEWAY_TIMEOUT:
+		return MsgErrorGatewayTimeout
+	default:
+		if code >= 400 && code < 500 {
+			return MsgErrorBadRequest
+		}
+		return MsgErrorInternal
+	}
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRegisterErrorCode(t *testing.T) {
+	if err := RegisterErrorCode("TEST_REGISTER_CODE", CONFLICT, http.StatusConflict, "errors.test_register"); err != nil {
+		t.Fatalf("RegisterErrorCode: %v", err)
+	}
+
+	definition, ok := LookupErrorCode("TEST_REGISTER_CODE")
+	if !ok {
+		t.Fatal("registered code not found")
+	}
+	if definition.ResponseCode != CONFLICT || definition.HTTPStatus != http.StatusConflict || definition.MessageKey != "errors.test_register" {
+		t.Errorf("definition = %+v", definition)
+	}
+
+	if err := RegisterErrorCode("TEST_REGISTER_CODE", NOT_FOUND, http.StatusNotFound, "other"); err == nil {
+		t.Error("duplicate registration succeeded")
+	}
+	if definition, _ := LookupErrorCode("TEST_REGISTER_CODE"); definition.HTTPStatus != http.StatusConflict {
+		t.Error("duplicate registration replaced the definition")
+	}
+	if err := RegisterErrorCode("", CONFLICT, http.StatusConflict, "key"); err == nil {
+		t.Error("empty code registered")
+	}
+}
+
+func TestMustRegisterErrorCodePanicsOnDuplicate(t *testing.T) {
+	MustRegisterErrorCode("TEST_MUST_REGISTER", CONFLICT, http.StatusConflict, "errors.test_must")
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate MustRegisterErrorCode did not panic")
+		}
+	}()
+	MustRegisterErrorCode("TEST_MUST_REGISTER", CONFLICT, http.StatusConflict, "errors.test_must")
+}
+
+func TestNewAppError(t *testing.T) {
+	MustRegisterErrorCode("TEST_EMAIL_TAKEN", CONFLICT, http.StatusConflict, "errors.email_taken")
+
+	err := NewAppError("TEST_EMAIL_TAKEN").WithDetails(ErrorDetail{Field: "email", Message: "taken"})
+	if err.HTTPStatus != http.StatusConflict || err.ResponseCode != CONFLICT || len(err.Details) != 1 {
+		t.Errorf("AppError = %+v", err)
+	}
+
+	unknown := NewAppError("TEST_NOT_REGISTERED")
+	if unknown.Code != "TEST_NOT_REGISTERED" || unknown.HTTPStatus != http.StatusInternalServerError {
+		t.Errorf("unknown AppError = %+v, want an internal error keeping the code", unknown)
+	}
+}
+
+func TestHTTPStatusFor(t *testing.T) {
+	MustRegisterErrorCode("TEST_PAYMENT_REQUIRED", BAD_REQUEST, http.StatusPaymentRequired, "errors.payment")
+
+	tests := []struct {
+		name string
+		err  *ErrorResponse
+		want int
+	}{
+		{"nil", nil, http.StatusInternalServerError},
+		{"registered code wins", &ErrorResponse{Code: BAD_REQUEST, ErrorCode: "TEST_PAYMENT_REQUIRED"}, http.StatusPaymentRequired},
+		{"unknown code falls back", &ErrorResponse{Code: NOT_FOUND, ErrorCode: "TEST_UNKNOWN"}, http.StatusNotFound},
+		{"validation", &ErrorResponse{Code: VALIDATION_ERROR}, http.StatusBadRequest},
+		{"too many requests", &ErrorResponse{Code: TOO_MANY_REQUESTS}, http.StatusTooManyRequests},
+		{"raw http status", &ErrorResponse{Code: ResponseCode(http.StatusTeapot)}, http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatusFor(tt.err); got != tt.want {
+			t.Errorf("%s: HTTPStatusFor() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestControllerRendersAppError(t *testing.T) {
+	MustRegisterErrorCode("TEST_ORDER_LOCKED", CONFLICT, http.StatusLocked, "errors.order_locked")
+
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/orders", nil), rec)
+
+	appErr := NewAppError("TEST_ORDER_LOCKED").WithDetails(ErrorDetail{Field: "order_id", Message: "locked"})
+	if err := controller.AppError(c, appErr); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusLocked {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusLocked)
+	}
+	var body ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ErrorCode != "TEST_ORDER_LOCKED" || body.Code != CONFLICT {
+		t.Errorf("body = %+v, want error_code TEST_ORDER_LOCKED and code CONFLICT", body)
+	}
+	if len(body.Details) != 1 || body.Details[0].Field != "order_id" {
+		t.Errorf("details = %+v", body.Details)
+	}
+}
+
+func TestResponseCodeForHTTPStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ResponseCode
+		key    string
+	}{
+		{http.StatusBadRequest, BAD_REQUEST, MsgErrorBadRequest},
+		{http.StatusUnauthorized, UNAUTHORIZED, MsgErrorUnauthorized},
+		{http.StatusNotFound, NOT_FOUND, MsgErrorNotFound},
+		{http.StatusTeapot, ResponseCode(http.StatusTeapot), MsgErrorBadRequest},
+		{http.StatusTooManyRequests, TOO_MANY_REQUESTS, MsgErrorTooManyRequests},
+		{http.StatusBadGateway, ResponseCode(http.StatusBadGateway), MsgErrorInternal},
+		{http.StatusOK, INTERNAL_ERROR, MsgErrorInternal},
+		{http.StatusFound, INTERNAL_ERROR, MsgErrorInternal},
+		{700, INTERNAL_ERROR, MsgErrorInternal},
+	}
+	for _, tt := range tests {
+		got := ResponseCodeForHTTPStatus(tt.status)
+		if got != tt.want {
+			t.Errorf("ResponseCodeForHTTPStatus(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+		if key := MessageKeyForCode(got); key != tt.key {
+			t.Errorf("MessageKeyForCode(%v) = %q, want %q", got, key, tt.key)
+		}
+		if tt.status >= 400 && tt.status < 600 {
+			if status := HTTPStatusFor(&ErrorResponse{Code: got}); status != tt.status {
+				t.Errorf("HTTPStatusFor(%v) = %d, want the round trip to %d", got, status, tt.status)
+			}
+		}
+	}
+}
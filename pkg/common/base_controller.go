@@ -1,11 +1,20 @@
 package common
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 // BaseController is a generic base controller for Echo framework
@@ -20,8 +29,12 @@ type IBaseController[T any] interface {
 	ResponseObject(serviceFunc func(c echo.Context) (T, *ErrorResponse)) echo.HandlerFunc
 	ResponsePointer(serviceFunc func(c echo.Context) (*T, *ErrorResponse)) echo.HandlerFunc
 	ResponseSuccessOnly(serviceFunc func(c echo.Context) *ErrorResponse) echo.HandlerFunc
+	ResponseCount(serviceFunc func(c echo.Context) (int64, *ErrorResponse)) echo.HandlerFunc
 }
 
+// HeaderTotalCount is the response header carrying the total number of items of a list
+const HeaderTotalCount = "X-Total-Count"
+
 // Page represents pagination structure
 type Page[E any] struct {
 	Content []E   `json:"content"`
@@ -31,85 +44,111 @@ type Page[E any] struct {
 // Success returns a success response with i18n support
 func (controller *BaseController[T]) Success(c echo.Context, v any) error {
 	// Get locale from context or header
-	locale := GetLocaleFromHeader(c.Request().Header)
-	ctx := SetLocaleInContext(c.Request().Context(), locale)
+	ctx := LocaleContext(c)
 
 	response := SuccessResponseWithContext(ctx, v, MsgSuccessDefault)
-	_ = ctx // Use context to avoid unused variable error
-	return c.JSON(http.StatusOK, response)
+	return controller.writeSuccess(c, response)
 }
 
 // SuccessWithMessage returns a success response with custom i18n message
 func (controller *BaseController[T]) SuccessWithMessage(c echo.Context, v any, messageKey string) error {
-	locale := GetLocaleFromHeader(c.Request().Header)
-	ctx := SetLocaleInContext(c.Request().Context(), locale)
+	ctx := LocaleContext(c)
 
 	response := SuccessResponseWithContext(ctx, v, messageKey)
-	_ = ctx // Use context to avoid unused variable error
+	return controller.writeSuccess(c, response)
+}
+
+// SuccessWithMeta returns a success response with custom i18n message and auxiliary metadata
+func (controller *BaseController[T]) SuccessWithMeta(c echo.Context, v any, messageKey string, meta map[string]any) error {
+	ctx := LocaleContext(c)
+
+	response := NewSuccessResponse().
+		WithContext(ctx).
+		WithData(v).
+		WithMessageKey(messageKey).
+		WithMeta(meta).
+		Build()
+	return controller.writeSuccess(c, response)
+}
+
+// AddWarning records a warning message key that is flushed into the success response
+func (controller *BaseController[T]) AddWarning(c echo.Context, messageKey string) {
+	AddWarning(c, messageKey)
+}
+
+// writeSuccess completes the success response with trace id and accumulated warnings and writes it
+func (controller *BaseController[T]) writeSuccess(c echo.Context, response BaseResponse) error {
+	response.TraceID = TraceIDFromRequest(c.Request())
+	response.Warnings = append(response.Warnings, GetWarnings(c)...)
+	setDataETag(c, response.Data)
 	return c.JSON(http.StatusOK, response)
 }
 
+// setDataETag sets the ETag of GET and HEAD responses to a hash of data, unlike the whole envelope it
+// does not change with the trace id, timestamp or processing time. An ETag set by the handler is kept
+func setDataETag(c echo.Context, data any) {
+	method := c.Request().Method
+	header := c.Response().Header()
+	if data == nil || (method != http.MethodGet && method != http.MethodHead) || header.Get("ETag") != "" {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	sum := sha1.Sum(payload)
+	header.Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+}
+
 // SuccessWithPagination returns a success response with pagination and i18n
 func (controller *BaseController[T]) SuccessWithPagination(c echo.Context, v any, total int64, page, pageSize int, messageKey string) error {
-	locale := GetLocaleFromHeader(c.Request().Header)
-	ctx := SetLocaleInContext(c.Request().Context(), locale)
+	ctx := LocaleContext(c)
 
-	pagination := CalculatePagination(page, pageSize, total)
-	response := SuccessResponseWithPaginationI18n(v, messageKey, pagination)
-	_ = ctx // Use context to avoid unused variable error
-	return c.JSON(http.StatusOK, response)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
+	setTotalCountHeader(c, total)
+	response := NewSuccessResponse().
+		WithContext(ctx).
+		WithData(v).
+		WithMessageKey(messageKey).
+		WithPagination(pagination).
+		Build()
+	return controller.writeSuccess(c, response)
 }
 
 // Error returns an error response with i18n support
 func (controller *BaseController[T]) Error(c echo.Context, err *ErrorResponse, v any) error {
-	// Map error code to HTTP status code
-	var statusCode int
-	codeValue := int(err.Code)
-	switch {
-	case codeValue == 400: // VALIDATION_ERROR or BAD_REQUEST
-		statusCode = http.StatusBadRequest
-	case err.Code == NOT_FOUND:
-		statusCode = http.StatusNotFound // 404
-	case err.Code == UNAUTHORIZED:
-		statusCode = http.StatusUnauthorized // 401
-	case err.Code == FORBIDDEN:
-		statusCode = http.StatusForbidden // 403
-	case err.Code == INTERNAL_ERROR:
-		statusCode = http.StatusInternalServerError // 500
-	case err.Code == CONFLICT:
-		statusCode = http.StatusConflict // 409
-	default:
-		statusCode = http.StatusInternalServerError // 500
-	}
-
-	// Get locale from header and translate message
-	locale := GetLocaleFromHeader(c.Request().Header)
-	ctx := SetLocaleInContext(c.Request().Context(), locale)
-
-	// Set locale for global i18n manager to translate messages
-	if manager := GetGlobalI18n(); manager != nil {
-		_ = manager.SetLocale(locale)
-	}
-	_ = ctx // Use context to avoid unused variable error
+	// Map error code to HTTP status code (registered error codes take precedence)
+	statusCode := HTTPStatusFor(err)
+
+	// Log the underlying cause, it is never exposed to the client
+	if cause := err.Cause(); cause != nil {
+		logrus.WithError(cause).
+			WithField("code", err.Code).
+			WithField("trace_id", TraceIDFromRequest(c.Request())).
+			Errorf("%s %s failed: %s", c.Request().Method, c.Request().URL.Path, err.Message)
+	}
+
+	// Translate with the request locale, the global i18n manager is shared by all requests
+	ctx := LocaleContext(c)
 
 	// If error has message key, translate it
 	var errorResponse ErrorResponse
 	if err.Message != "" {
 		// Translate the message key to actual message
-		errorResponse = *CreateErrorResponseI18n(err.Code, err.Message, err.Details...)
+		errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, err.Message), err.Details...)
 	} else {
 		// Otherwise, create appropriate error response based on error code
 		switch err.Code {
 		case VALIDATION_ERROR:
-			errorResponse = *ValidationErrorI18n()
+			errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, MsgErrorValidation))
 		case NOT_FOUND:
-			errorResponse = *NotFoundErrorI18n()
+			errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, MsgErrorNotFound))
 		case UNAUTHORIZED:
-			errorResponse = *UnauthorizedErrorI18n()
+			errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, MsgErrorUnauthorized))
 		case INTERNAL_ERROR:
-			errorResponse = *InternalErrorI18n()
+			errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, MsgErrorInternal))
 		case CONFLICT:
-			errorResponse = *ConflictErrorI18n()
+			errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, MsgErrorConflict))
 		default:
 			errorResponse = *err
 		}
@@ -118,39 +157,28 @@ func (controller *BaseController[T]) Error(c echo.Context, err *ErrorResponse, v
 	// Add custom data if provided
 	if v != nil {
 		// Create a new error response with custom data
-		errorResponse = *CreateErrorResponseI18n(err.Code, err.Message, ErrorDetail{})
+		errorResponse = *CreateErrorResponse(err.Code, TWithContext(ctx, err.Message), ErrorDetail{})
 	}
 
+	errorResponse.ErrorCode = err.ErrorCode
+	errorResponse.TraceID = TraceIDFromRequest(c.Request())
 	return c.JSON(statusCode, errorResponse)
 }
 
+// AppError returns an error response for an application error code
+func (controller *BaseController[T]) AppError(c echo.Context, err *AppError) error {
+	return controller.Error(c, err.ToErrorResponse(), nil)
+}
+
 // ErrorWithDetails returns an error response with custom details and i18n
 func (controller *BaseController[T]) ErrorWithDetails(ctx echo.Context, code ResponseCode, messageKey string, details ...ErrorDetail) error {
-	locale := GetLocaleFromHeader(ctx.Request().Header)
-	context := SetLocaleInContext(ctx.Request().Context(), locale)
+	localeCtx := LocaleContext(ctx)
 
 	// Map error code to HTTP status code
-	var statusCode int
-	codeValue := int(code)
-	switch {
-	case codeValue == 400: // VALIDATION_ERROR or BAD_REQUEST
-		statusCode = http.StatusBadRequest
-	case code == NOT_FOUND:
-		statusCode = http.StatusNotFound // 404
-	case code == UNAUTHORIZED:
-		statusCode = http.StatusUnauthorized // 401
-	case code == FORBIDDEN:
-		statusCode = http.StatusForbidden // 403
-	case code == INTERNAL_ERROR:
-		statusCode = http.StatusInternalServerError // 500
-	case code == CONFLICT:
-		statusCode = http.StatusConflict // 409
-	default:
-		statusCode = http.StatusInternalServerError // 500
-	}
-
-	errorResponse := CreateErrorResponseI18n(code, messageKey, details...)
-	_ = context // Use context to avoid unused variable error
+	statusCode := httpStatusForCode(code)
+
+	errorResponse := CreateErrorResponse(code, TWithContext(localeCtx, messageKey), details...)
+	errorResponse.TraceID = TraceIDFromRequest(ctx.Request())
 	return ctx.JSON(statusCode, errorResponse)
 }
 
@@ -218,6 +246,8 @@ func (controller *BaseController[T]) ResponseList(serviceFunc func(c echo.Contex
 			return controller.Error(c, err, nil)
 		}
 
+		setTotalCountHeader(c, total)
+
 		// Create a structured list response
 		listResponse := map[string]interface{}{
 			"data":  body,
@@ -236,6 +266,8 @@ func (controller *BaseController[T]) ResponseListWithMessage(serviceFunc func(c
 			return controller.Error(c, err, nil)
 		}
 
+		setTotalCountHeader(c, total)
+
 		// Create a structured list response
 		listResponse := map[string]interface{}{
 			"data":  body,
@@ -255,21 +287,11 @@ func (controller *BaseController[T]) ResponseListWithPagination(serviceFunc func
 		}
 
 		// Get pagination parameters from query
-		page := 1
-		pageSize := 10
-		if pageStr := c.QueryParam("page"); pageStr != "" {
-			if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-				page = p
-			}
-		}
-		if sizeStr := c.QueryParam("size"); sizeStr != "" {
-			if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-				pageSize = s
-			}
-		}
+		query := controller.listQuery(c, DefaultListPageSize)
+		page, pageSize := query.Page, query.Size
 
 		// Create pagination info
-		pagination := CalculatePagination(page, pageSize, total)
+		pagination := controller.calculatePagination(c, page, pageSize, total)
 
 		// Create a structured list response with pagination
 		listResponse := map[string]interface{}{
@@ -376,18 +398,8 @@ func (controller *BaseController[T]) ResponsePage(serviceFunc func(c echo.Contex
 		}
 
 		// Get pagination parameters from query
-		page := 1
-		pageSize := 10
-		if pageStr := c.QueryParam("page"); pageStr != "" {
-			if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-				page = p
-			}
-		}
-		if sizeStr := c.QueryParam("size"); sizeStr != "" {
-			if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-				pageSize = s
-			}
-		}
+		query := controller.listQuery(c, DefaultListPageSize)
+		page, pageSize := query.Page, query.Size
 
 		return controller.SuccessWithPagination(c, content, total, page, pageSize, MsgSuccessRetrieved)
 	}
@@ -426,6 +438,23 @@ func (controller *BaseController[T]) ResponseSuccessOnly(serviceFunc func(c echo
 	}
 }
 
+// ResponseCount returns a handler function for count-only responses.
+// The envelope data is {"count": n} and the count is also exposed in the X-Total-Count header,
+// which makes the handler suitable for HEAD routes (see RegisterGETWithHEAD)
+func (controller *BaseController[T]) ResponseCount(serviceFunc func(c echo.Context) (int64, *ErrorResponse)) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		count, err := serviceFunc(c)
+		if err != nil {
+			return controller.Error(c, err, nil)
+		}
+
+		setTotalCountHeader(c, count)
+		return controller.Success(c, map[string]interface{}{
+			"count": count,
+		})
+	}
+}
+
 // ResponseArrayWithMessage returns a handler function for array responses with custom message
 func (controller *BaseController[T]) ResponseArrayWithMessage(serviceFunc func(c echo.Context) ([]T, *ErrorResponse), messageKey string) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -471,11 +500,11 @@ func (controller *BaseController[T]) ResponseRetrieved(c echo.Context, v any) er
 // createPaginationResponse automatically creates pagination response
 func (controller *BaseController[T]) createPaginationResponse(c echo.Context, content []T, total int64) error {
 	// Get pagination parameters from query with smart defaults
-	page := controller.getPageFromQuery(c)
-	pageSize := controller.getPageSizeFromQuery(c)
+	query := controller.listQuery(c, DefaultListPageSize)
+	page, pageSize := query.Page, query.Size
 
 	// Create pagination info
-	pagination := CalculatePagination(page, pageSize, total)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
 
 	// Create structured response
 	response := map[string]interface{}{
@@ -491,17 +520,18 @@ func (controller *BaseController[T]) createPaginationResponse(c echo.Context, co
 		},
 	}
 
+	setTotalCountHeader(c, total)
 	return controller.SuccessWithMessage(c, response, MsgSuccessRetrieved)
 }
 
 // createPaginationResponseWithCustomSize creates pagination response with custom default page size
 func (controller *BaseController[T]) createPaginationResponseWithCustomSize(c echo.Context, content []T, total int64, defaultPageSize int) error {
 	// Get pagination parameters from query with custom default
-	page := controller.getPageFromQuery(c)
-	pageSize := controller.getPageSizeFromQueryWithDefault(c, defaultPageSize)
+	query := controller.listQuery(c, defaultPageSize)
+	page, pageSize := query.Page, query.Size
 
 	// Create pagination info
-	pagination := CalculatePagination(page, pageSize, total)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
 
 	// Create structured response
 	response := map[string]interface{}{
@@ -517,25 +547,21 @@ func (controller *BaseController[T]) createPaginationResponseWithCustomSize(c ec
 		},
 	}
 
+	setTotalCountHeader(c, total)
 	return controller.SuccessWithMessage(c, response, MsgSuccessRetrieved)
 }
 
 // createPaginationResponseWithDataSlicing creates pagination response with data slicing
 func (controller *BaseController[T]) createPaginationResponseWithDataSlicing(c echo.Context, content []T, total int64) error {
 	// Get pagination parameters from query with smart defaults
-	page := controller.getPageFromQuery(c)
-	pageSize := controller.getPageSizeFromQuery(c)
+	query := controller.listQuery(c, DefaultListPageSize)
+	page, pageSize := query.Page, query.Size
 
 	// Slice data based on pagination
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if end > int(total) {
-		end = int(total)
-	}
-	paginatedContent := content[start:end]
+	paginatedContent := slicePage(content, page, pageSize)
 
 	// Create pagination info
-	pagination := CalculatePagination(page, pageSize, total)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
 
 	// Create structured response
 	response := map[string]interface{}{
@@ -551,17 +577,18 @@ func (controller *BaseController[T]) createPaginationResponseWithDataSlicing(c e
 		},
 	}
 
+	setTotalCountHeader(c, total)
 	return controller.SuccessWithMessage(c, response, MsgSuccessRetrieved)
 }
 
 // createPaginationResponseFromDB creates pagination response from database-paginated data
 func (controller *BaseController[T]) createPaginationResponseFromDB(c echo.Context, content []*T, total int64) error {
 	// Get pagination parameters from query
-	page := controller.getPageFromQuery(c)
-	pageSize := controller.getPageSizeFromQuery(c)
+	query := controller.listQuery(c, DefaultListPageSize)
+	page, pageSize := query.Page, query.Size
 
 	// Data is already paginated from database, just create response structure
-	pagination := CalculatePagination(page, pageSize, total)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
 
 	// Create structured response
 	response := map[string]interface{}{
@@ -577,18 +604,19 @@ func (controller *BaseController[T]) createPaginationResponseFromDB(c echo.Conte
 		},
 	}
 
+	setTotalCountHeader(c, total)
 	return controller.SuccessWithMessage(c, response, MsgSuccessRetrieved)
 }
 
 // createPaginationResponseFromDBWithSorting creates pagination response from database with sorting info
 func (controller *BaseController[T]) createPaginationResponseFromDBWithSorting(c echo.Context, content []*T, total int64) error {
 	// Get pagination and sorting parameters from query
-	page := controller.getPageFromQuery(c)
-	pageSize := controller.getPageSizeFromQuery(c)
-	sortBy, sortOrder := controller.getSortingFromQuery(c)
+	query := controller.listQuery(c, DefaultListPageSize)
+	page, pageSize := query.Page, query.Size
+	sortBy, sortOrder := query.SortBy, query.sortOrder()
 
 	// Data is already paginated and sorted from database, just create response structure
-	pagination := CalculatePagination(page, pageSize, total)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
 
 	// Create structured response with sorting info
 	response := map[string]interface{}{
@@ -608,41 +636,26 @@ func (controller *BaseController[T]) createPaginationResponseFromDBWithSorting(c
 		},
 	}
 
+	setTotalCountHeader(c, total)
 	return controller.SuccessWithMessage(c, response, MsgSuccessRetrieved)
 }
 
 // createPaginationResponseWithSortingAndSlicing creates pagination response with sorting and data slicing
 func (controller *BaseController[T]) createPaginationResponseWithSortingAndSlicing(c echo.Context, content []T, total int64) error {
 	// Get pagination and sorting parameters from query
-	page := controller.getPageFromQuery(c)
-	pageSize := controller.getPageSizeFromQuery(c)
-	sortBy, sortOrder := controller.getSortingFromQuery(c)
+	query := controller.listQuery(c, DefaultListPageSize)
+	page, pageSize := query.Page, query.Size
+	sortBy, sortOrder := query.SortBy, query.sortOrder()
 
 	// Apply sorting if specified (basic string sorting for demonstration)
 	// In real implementation, you might want to use reflection or custom sorting
 	sortedContent := controller.applyBasicSorting(content)
 
 	// Slice data based on pagination
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if end > int(total) {
-		end = int(total)
-	}
-
-	// Check bounds to prevent panic when content is empty or start exceeds length
-	var paginatedContent []T
-	if start >= len(sortedContent) || len(sortedContent) == 0 {
-		// Return empty slice if start is beyond content length or content is empty
-		paginatedContent = make([]T, 0)
-	} else {
-		if end > len(sortedContent) {
-			end = len(sortedContent)
-		}
-		paginatedContent = sortedContent[start:end]
-	}
+	paginatedContent := slicePage(sortedContent, page, pageSize)
 
 	// Create pagination info
-	pagination := CalculatePagination(page, pageSize, total)
+	pagination := controller.calculatePagination(c, page, pageSize, total)
 
 	// Create structured response with sorting info
 	response := map[string]interface{}{
@@ -662,86 +675,52 @@ func (controller *BaseController[T]) createPaginationResponseWithSortingAndSlici
 		},
 	}
 
+	setTotalCountHeader(c, total)
 	return controller.SuccessWithMessage(c, response, MsgSuccessRetrieved)
 }
 
-// applyBasicSorting applies basic sorting to content (placeholder for demonstration)
-// In real implementation, you would implement proper sorting logic
-func (controller *BaseController[T]) applyBasicSorting(content []T) []T {
-	// For now, return content as-is
-	// In real implementation, you would use reflection or custom sorting logic
-	// based on the sortBy field and sortOrder
-	return content
+// calculatePagination calculates pagination information with navigation links for the current request
+func (controller *BaseController[T]) calculatePagination(c echo.Context, page, pageSize int, total int64) PaginationInfo {
+	pagination := CalculatePagination(page, pageSize, total)
+	pagination.Links = BuildPaginationLinks(c.Request().URL, pagination)
+	return pagination
 }
 
-// getSortingFromQuery extracts sorting parameters from query
-func (controller *BaseController[T]) getSortingFromQuery(c echo.Context) (string, string) {
-	sortBy := c.QueryParam("sort_by")
-
-	// Try to get sort_order first (for compatibility)
-	sortOrder := c.QueryParam("sort_order")
-
-	// If sort_order is not provided, try to get from desc parameter (boolean)
-	if sortOrder == "" {
-		descParam := c.QueryParam("desc")
-		switch descParam {
-		case "true":
-			sortOrder = "desc"
-		case "false":
-			sortOrder = "asc"
-		}
+// slicePage returns the items of the requested page
+// Bounds are checked to prevent panic when content is empty or the page is beyond its length
+func slicePage[E any](content []E, page, pageSize int) []E {
+	if page < 1 {
+		page = 1
 	}
-
-	// Default sorting
-	if sortBy == "" {
-		sortBy = "created_at"
-	}
-	if sortOrder == "" || (sortOrder != "asc" && sortOrder != "desc") {
-		sortOrder = "desc"
+	if pageSize < 1 {
+		pageSize = 1
 	}
 
-	return sortBy, sortOrder
-}
-
-// getPageFromQuery extracts page from query parameters with smart defaults
-func (controller *BaseController[T]) getPageFromQuery(c echo.Context) int {
-	page := 1
-	if pageStr := c.QueryParam("page"); pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	start := (page - 1) * pageSize
+	if start >= len(content) {
+		return make([]E, 0)
+	}
+	end := start + pageSize
+	if end > len(content) {
+		end = len(content)
 	}
-	return page
+	return content[start:end]
 }
 
-// getPageSizeFromQuery extracts page size from query parameters with smart defaults
-func (controller *BaseController[T]) getPageSizeFromQuery(c echo.Context) int {
-	pageSize := 10 // Default page size
-	if sizeStr := c.QueryParam("size"); sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-			// Limit maximum page size to prevent abuse
-			if s > 100 {
-				s = 100
-			}
-			pageSize = s
-		}
-	}
-	return pageSize
+// applyBasicSorting applies basic sorting to content (placeholder for demonstration)
+// In real implementation, you would implement proper sorting logic
+func (controller *BaseController[T]) applyBasicSorting(content []T) []T {
+	// For now, return content as-is
+	// In real implementation, you would use reflection or custom sorting logic
+	// based on the sortBy field and sortOrder
+	return content
 }
 
-// getPageSizeFromQueryWithDefault extracts page size with custom default
-func (controller *BaseController[T]) getPageSizeFromQueryWithDefault(c echo.Context, defaultSize int) int {
-	pageSize := defaultSize
-	if sizeStr := c.QueryParam("size"); sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-			// Limit maximum page size to prevent abuse
-			if s > 100 {
-				s = 100
-			}
-			pageSize = s
-		}
-	}
-	return pageSize
+// listQuery parses the pagination and sorting parameters with parseListQuery, malformed values keep their
+// defaults and any sort_by is reported as requested
+func (controller *BaseController[T]) listQuery(c echo.Context, defaultPageSize int) ListQuery {
+	query, _ := parseListQuery(c, ListQueryConfig{DefaultSize: defaultPageSize, DefaultSort: defaultListSort})
+	return query
 }
 
 // FileResponse returns a file response with proper headers
@@ -765,3 +744,85 @@ func (controller *BaseController[T]) FileDownload(c echo.Context, filePath, file
 	c.Response().Header().Set("Content-Disposition", "attachment; filename="+fileName)
 	return c.File(filePath)
 }
+
+// StreamContent serves content with Range (206 Partial Content) and conditional request support, e.g. to
+// proxy a MinIO object opened with GetObject for video seeking. Empty fileName, contentType or etag are omitted
+func (controller *BaseController[T]) StreamContent(c echo.Context, content io.ReadSeeker, fileName, contentType, etag string, modTime time.Time) error {
+	header := c.Response().Header()
+	if contentType != "" {
+		header.Set(echo.HeaderContentType, contentType)
+	}
+	if etag != "" {
+		header.Set("ETag", strconv.Quote(strings.Trim(etag, `"`)))
+	}
+	if fileName != "" {
+		header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+	}
+	http.ServeContent(c.Response(), c.Request(), fileName, modTime, content)
+	return nil
+}
+
+// setTotalCountHeader exposes the total number of items in the X-Total-Count header
+func setTotalCountHeader(c echo.Context, total int64) {
+	c.Response().Header().Set(HeaderTotalCount, strconv.FormatInt(total, 10))
+}
+
+// RouteRegistrar is implemented by *echo.Echo and *echo.Group
+type RouteRegistrar interface {
+	Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+}
+
+// RegisterGETWithHEAD registers handler for GET and a HEAD route for the same path.
+// The HEAD route runs the GET handler but only returns its headers (X-Total-Count, ETag, ...)
+func RegisterGETWithHEAD(r RouteRegistrar, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) {
+	r.Add(http.MethodGet, path, handler, middleware...)
+	r.Add(http.MethodHead, path, HeadHandler(handler), middleware...)
+}
+
+// HeadHandler wraps a GET handler so that it only writes the response headers.
+// The body produced by the handler is discarded and used to compute the Content-Length, and the ETag
+// when the handler sets none (BaseController responses carry the ETag of their data)
+func HeadHandler(handler echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := c.Response()
+		original := res.Writer
+		buffer := &headResponseWriter{header: original.Header(), status: http.StatusOK}
+		res.Writer = buffer
+
+		err := handler(c)
+		res.Writer = original
+		if err != nil {
+			return err
+		}
+
+		header := original.Header()
+		if buffer.body.Len() > 0 && header.Get(echo.HeaderContentLength) == "" {
+			header.Set(echo.HeaderContentLength, strconv.Itoa(buffer.body.Len()))
+		}
+		if header.Get("ETag") == "" && buffer.body.Len() > 0 {
+			sum := sha1.Sum(buffer.body.Bytes())
+			header.Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		}
+		original.WriteHeader(buffer.status)
+		return nil
+	}
+}
+
+// headResponseWriter buffers the body written by a handler served through HeadHandler
+type headResponseWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *headResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testItem struct {
+	ID string `json:"id"`
+}
+
+func TestResponseCount(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	e.GET("/items/count", controller.ResponseCount(func(c echo.Context) (int64, *ErrorResponse) {
+		return 42, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/count", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get(HeaderTotalCount); got != "42" {
+		t.Errorf("%s = %q, want %q", HeaderTotalCount, got, "42")
+	}
+	var body struct {
+		Data struct {
+			Count int64 `json:"count"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Data.Count != 42 {
+		t.Errorf("data.count = %d, want 42", body.Data.Count)
+	}
+}
+
+func TestResponseCountError(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	e.GET("/items/count", controller.ResponseCount(func(c echo.Context) (int64, *ErrorResponse) {
+		return 0, BadRequestError("bad filter")
+	}))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/count", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if got := rec.Header().Get(HeaderTotalCount); got != "" {
+		t.Errorf("%s = %q on error, want none", HeaderTotalCount, got)
+	}
+}
+
+func TestRegisterGETWithHEAD(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	RegisterGETWithHEAD(e, "/items", controller.ResponseList(func(c echo.Context) ([]*testItem, int64, *ErrorResponse) {
+		return []*testItem{{ID: "a"}, {ID: "b"}}, 7, nil
+	}))
+
+	get := httptest.NewRecorder()
+	e.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/items", nil))
+	head := httptest.NewRecorder()
+	e.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/items", nil))
+
+	if head.Code != http.StatusOK {
+		t.Fatalf("HEAD status = %d, want %d", head.Code, http.StatusOK)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("HEAD body = %q, want empty", head.Body.String())
+	}
+	if got := head.Header().Get(HeaderTotalCount); got != "7" {
+		t.Errorf("HEAD %s = %q, want %q", HeaderTotalCount, got, "7")
+	}
+	if head.Header().Get("ETag") == "" {
+		t.Error("HEAD response has no ETag")
+	}
+	if got := head.Header().Get(echo.HeaderContentLength); got == "" || got == "0" {
+		t.Errorf("HEAD Content-Length = %q, want the GET body length", got)
+	}
+	if get.Body.Len() == 0 {
+		t.Error("GET body is empty")
+	}
+}
+
+func TestRegisterGETWithHEADStableETag(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	RegisterGETWithHEAD(e, "/items", controller.ResponseList(func(c echo.Context) ([]*testItem, int64, *ErrorResponse) {
+		return []*testItem{{ID: "a"}, {ID: "b"}}, 2, nil
+	}))
+	serve := func(method, traceID string) string {
+		req := httptest.NewRequest(method, "/items", nil)
+		req.Header.Set(echo.HeaderXRequestID, traceID)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s status = %d, want 200", method, rec.Code)
+		}
+		return rec.Header().Get("ETag")
+	}
+
+	get := serve(http.MethodGet, "trace-1")
+	if get == "" {
+		t.Fatal("GET response has no ETag")
+	}
+	first, second := serve(http.MethodHead, "trace-2"), serve(http.MethodHead, "trace-3")
+	if first != second {
+		t.Errorf("HEAD ETags = %q, %q, want the same ETag across requests", first, second)
+	}
+	if first != get {
+		t.Errorf("HEAD ETag = %q, want the GET ETag %q", first, get)
+	}
+
+	// A POST response carries no ETag
+	e.POST("/items", controller.ResponseObject(func(c echo.Context) (testItem, *ErrorResponse) {
+		return testItem{ID: "a"}, nil
+	}))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", nil))
+	if got := rec.Header().Get("ETag"); got != "" {
+		t.Errorf("POST ETag = %q, want none", got)
+	}
+}
+
+func TestHeadHandlerKeepsETag(t *testing.T) {
+	e := echo.New()
+	e.HEAD("/file", HeadHandler(func(c echo.Context) error {
+		c.Response().Header().Set("ETag", `"fixed"`)
+		return c.String(http.StatusOK, "content")
+	}))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/file", nil))
+
+	if got := rec.Header().Get("ETag"); got != `"fixed"` {
+		t.Errorf("ETag = %q, want the one set by the handler", got)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", rec.Body.String())
+	}
+}
+
+func TestStreamContent(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.GET("/files/report", func(c echo.Context) error {
+		return controller.StreamContent(c, strings.NewReader("0123456789"), "báo cáo.pdf", "application/pdf", "abc", modTime)
+	})
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/files/report", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	full := serve("", "")
+	if full.Code != http.StatusOK || full.Body.String() != "0123456789" {
+		t.Fatalf("full response = %d %q", full.Code, full.Body.String())
+	}
+	if got := full.Header().Get("ETag"); got != `"abc"` {
+		t.Errorf("ETag = %q, want the quoted etag", got)
+	}
+	if got := full.Header().Get(echo.HeaderContentDisposition); got != `inline; filename*=utf-8''b%C3%A1o%20c%C3%A1o.pdf` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := full.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q", got)
+	}
+
+	partial := serve("Range", "bytes=2-4")
+	if partial.Code != http.StatusPartialContent || partial.Body.String() != "234" {
+		t.Errorf("range response = %d %q, want 206 234", partial.Code, partial.Body.String())
+	}
+	if got := partial.Header().Get("Content-Range"); got != "bytes 2-4/10" {
+		t.Errorf("Content-Range = %q", got)
+	}
+
+	if rec := serve("Range", "bytes=20-30"); rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range status = %d", rec.Code)
+	}
+	if rec := serve("If-None-Match", `"abc"`); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match status = %d, want 304", rec.Code)
+	}
+}
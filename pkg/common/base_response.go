@@ -2,6 +2,9 @@ package common
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -24,6 +27,7 @@ const (
 	REQUEST_TIMEOUT     ResponseCode = 408
 	TOO_MANY_REQUESTS   ResponseCode = 429
 	SERVICE_UNAVAILABLE ResponseCode = 503
+	GATEWAY_TIMEOUT     ResponseCode = 504
 )
 
 // BaseResponse represents the standard response structure
@@ -43,6 +47,13 @@ type BaseResponse struct {
 	// @Description Thông tin phân trang (nếu có)
 	Pagination *PaginationInfo `json:"pagination,omitempty"`
 
+	// @Description Thông tin bổ sung (nếu có)
+	Meta map[string]any `json:"meta,omitempty"`
+
+	// @Description Cảnh báo phát sinh khi xử lý request (nếu có)
+	// @example ["Endpoint này sẽ ngừng hỗ trợ"]
+	Warnings []string `json:"warnings,omitempty" example:"Endpoint này sẽ ngừng hỗ trợ"`
+
 	// @Description Thời gian phản hồi
 	// @example "2024-01-15T10:30:00Z"
 	Timestamp time.Time `json:"timestamp" example:"2024-01-15T10:30:00Z"`
@@ -50,6 +61,10 @@ type BaseResponse struct {
 	// @Description Thời gian xử lý request (milliseconds)
 	// @example 150
 	ProcessingTime int64 `json:"processing_time,omitempty" example:"150"`
+
+	// @Description Trace ID để đối chiếu với hệ thống tracing
+	// @example "4bf92f3577b34da6a3ce929d0e0e4736"
+	TraceID string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
 }
 
 // PaginationInfo represents pagination information
@@ -78,6 +93,33 @@ type PaginationInfo struct {
 	// @Description Có trang trước không
 	// @example false
 	HasPrev bool `json:"has_prev" example:"false"`
+
+	// @Description Liên kết điều hướng giữa các trang (nếu có)
+	Links *PaginationLinks `json:"links,omitempty"`
+}
+
+// PaginationLinks represents navigation links between pages
+// @Description Liên kết phân trang
+type PaginationLinks struct {
+	// @Description Trang hiện tại
+	// @example "/api/users?page=2&size=10"
+	Self string `json:"self,omitempty" example:"/api/users?page=2&size=10"`
+
+	// @Description Trang tiếp theo
+	// @example "/api/users?page=3&size=10"
+	Next string `json:"next,omitempty" example:"/api/users?page=3&size=10"`
+
+	// @Description Trang trước
+	// @example "/api/users?page=1&size=10"
+	Prev string `json:"prev,omitempty" example:"/api/users?page=1&size=10"`
+
+	// @Description Trang đầu tiên
+	// @example "/api/users?page=1&size=10"
+	First string `json:"first,omitempty" example:"/api/users?page=1&size=10"`
+
+	// @Description Trang cuối cùng
+	// @example "/api/users?page=50&size=10"
+	Last string `json:"last,omitempty" example:"/api/users?page=50&size=10"`
 }
 
 // ErrorDetail represents detailed error information
@@ -107,6 +149,10 @@ type ErrorResponse struct {
 	// @example "Dữ liệu không hợp lệ"
 	Message string `json:"message" example:"Dữ liệu không hợp lệ"`
 
+	// @Description Mã lỗi nghiệp vụ (nếu có)
+	// @example "USER_EMAIL_TAKEN"
+	ErrorCode string `json:"error_code,omitempty" example:"USER_EMAIL_TAKEN"`
+
 	// @Description Chi tiết lỗi (nếu có)
 	Details []ErrorDetail `json:"details,omitempty"`
 
@@ -117,57 +163,207 @@ type ErrorResponse struct {
 	// @Description Thời gian xử lý request (milliseconds)
 	// @example 50
 	ProcessingTime int64 `json:"processing_time,omitempty" example:"50"`
+
+	// @Description Trace ID để đối chiếu với hệ thống tracing
+	// @example "4bf92f3577b34da6a3ce929d0e0e4736"
+	TraceID string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+
+	// cause is the underlying error, kept for logging and never serialized
+	cause error
+	// kind tells validation failures from other bad requests, never serialized
+	kind errorKind
 }
 
-// SuccessResponse creates a success response
-func SuccessResponse(data interface{}, message string) BaseResponse {
-	return BaseResponse{
-		Code:      SUCCESS,
-		Message:   message,
-		Data:      data,
-		Timestamp: time.Now(),
+// errorKind tells apart the error responses sharing a response code: VALIDATION_ERROR and BAD_REQUEST are
+// both 400
+type errorKind uint8
+
+const (
+	// errorKindUnknown is the kind of responses built from a bare code, e.g. with CreateErrorResponse
+	errorKindUnknown errorKind = iota
+	errorKindValidation
+	errorKindBadRequest
+)
+
+// Sentinel error responses for errors.Is checks, matched by response code. VALIDATION_ERROR and BAD_REQUEST
+// are both 400: ErrValidationResponse matches the responses of ValidationError and ValidationErrorI18n,
+// ErrBadRequestResponse those of BadRequestError and BadRequestErrorI18n, 400 responses built from the bare
+// code match both
+var (
+	ErrValidationResponse   = &ErrorResponse{Code: VALIDATION_ERROR, kind: errorKindValidation}
+	ErrBadRequestResponse   = &ErrorResponse{Code: BAD_REQUEST, kind: errorKindBadRequest}
+	ErrUnauthorizedResponse = &ErrorResponse{Code: UNAUTHORIZED}
+	ErrForbiddenResponse    = &ErrorResponse{Code: FORBIDDEN}
+	ErrNotFoundResponse     = &ErrorResponse{Code: NOT_FOUND}
+	ErrConflictResponse     = &ErrorResponse{Code: CONFLICT}
+	ErrInternalResponse     = &ErrorResponse{Code: INTERNAL_ERROR}
+)
+
+// Error implements the error interface
+func (e *ErrorResponse) Error() string {
+	message := fmt.Sprintf("%d: %s", e.Code, e.Message)
+	if e.ErrorCode != "" {
+		message = fmt.Sprintf("%d %s: %s", e.Code, e.ErrorCode, e.Message)
+	}
+	if e.cause != nil {
+		message += ": " + e.cause.Error()
 	}
+	return message
 }
 
-// SuccessResponseI18n creates a success response with i18n message
-func SuccessResponseI18n(data interface{}, messageKey string) BaseResponse {
-	return BaseResponse{
-		Code:      SUCCESS,
-		Message:   T(messageKey),
-		Data:      data,
-		Timestamp: time.Now(),
+// Unwrap returns the wrapped cause, if any
+func (e *ErrorResponse) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an error response with the same code.
+// A target without ErrorCode matches any error code, so the sentinels match by response code only, and
+// validation failures do not match ErrBadRequestResponse nor bad requests ErrValidationResponse
+func (e *ErrorResponse) Is(target error) bool {
+	t, ok := target.(*ErrorResponse)
+	if !ok || t == nil {
+		return false
 	}
+	if t.Code != e.Code {
+		return false
+	}
+	if t.kind != errorKindUnknown && e.kind != errorKindUnknown && t.kind != e.kind {
+		return false
+	}
+	return t.ErrorCode == "" || t.ErrorCode == e.ErrorCode
 }
 
-// SuccessResponseWithContext creates a success response with context-based i18n
-func SuccessResponseWithContext(ctx context.Context, data interface{}, messageKey string) BaseResponse {
-	return BaseResponse{
-		Code:      SUCCESS,
-		Message:   TWithContext(ctx, messageKey),
-		Data:      data,
-		Timestamp: time.Now(),
+// Cause returns the wrapped cause, if any
+func (e *ErrorResponse) Cause() error {
+	return e.cause
+}
+
+// withKind sets the kind of the response
+func (e *ErrorResponse) withKind(kind errorKind) *ErrorResponse {
+	e.kind = kind
+	return e
+}
+
+// WithCause sets the underlying cause, kept for logging while Message stays client-facing
+func (e *ErrorResponse) WithCause(cause error) *ErrorResponse {
+	e.cause = cause
+	return e
+}
+
+// SuccessResponseBuilder builds a success BaseResponse step by step.
+// Data, pagination and message are set independently so no constructor can drop one of them
+type SuccessResponseBuilder struct {
+	ctx        context.Context
+	data       interface{}
+	message    string
+	messageKey string
+	pagination *PaginationInfo
+	meta       map[string]any
+	warnings   []string
+}
+
+// NewSuccessResponse creates a new SuccessResponseBuilder
+func NewSuccessResponse() *SuccessResponseBuilder {
+	return &SuccessResponseBuilder{}
+}
+
+// WithData sets the response data
+func (b *SuccessResponseBuilder) WithData(data interface{}) *SuccessResponseBuilder {
+	b.data = data
+	return b
+}
+
+// WithPagination sets the pagination information
+func (b *SuccessResponseBuilder) WithPagination(pagination PaginationInfo) *SuccessResponseBuilder {
+	b.pagination = &pagination
+	return b
+}
+
+// WithMeta merges auxiliary metadata into the response
+func (b *SuccessResponseBuilder) WithMeta(meta map[string]any) *SuccessResponseBuilder {
+	if len(meta) == 0 {
+		return b
+	}
+	if b.meta == nil {
+		b.meta = make(map[string]any, len(meta))
+	}
+	for key, value := range meta {
+		b.meta[key] = value
 	}
+	return b
 }
 
-// SuccessResponseWithPagination creates a success response with pagination
-func SuccessResponseWithPagination(data interface{}, message string, pagination PaginationInfo) BaseResponse {
+// WithWarnings appends already translated warnings, keeping their order
+func (b *SuccessResponseBuilder) WithWarnings(warnings ...string) *SuccessResponseBuilder {
+	b.warnings = append(b.warnings, warnings...)
+	return b
+}
+
+// WithMessage sets an already translated message
+func (b *SuccessResponseBuilder) WithMessage(message string) *SuccessResponseBuilder {
+	b.message = message
+	b.messageKey = ""
+	return b
+}
+
+// WithMessageKey sets an i18n message key, translated when the response is built
+func (b *SuccessResponseBuilder) WithMessageKey(messageKey string) *SuccessResponseBuilder {
+	b.messageKey = messageKey
+	b.message = ""
+	return b
+}
+
+// WithContext sets the context used to resolve the locale of the message key
+func (b *SuccessResponseBuilder) WithContext(ctx context.Context) *SuccessResponseBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Build creates the BaseResponse
+func (b *SuccessResponseBuilder) Build() BaseResponse {
+	message := b.message
+	if b.messageKey != "" {
+		if b.ctx != nil {
+			message = TWithContext(b.ctx, b.messageKey)
+		} else {
+			message = T(b.messageKey)
+		}
+	}
+
 	return BaseResponse{
 		Code:       SUCCESS,
 		Message:    message,
-		Pagination: &pagination,
+		Data:       b.data,
+		Pagination: b.pagination,
+		Meta:       b.meta,
+		Warnings:   b.warnings,
 		Timestamp:  time.Now(),
 	}
 }
 
+// SuccessResponse creates a success response
+func SuccessResponse(data interface{}, message string) BaseResponse {
+	return NewSuccessResponse().WithData(data).WithMessage(message).Build()
+}
+
+// SuccessResponseI18n creates a success response with i18n message
+func SuccessResponseI18n(data interface{}, messageKey string) BaseResponse {
+	return NewSuccessResponse().WithData(data).WithMessageKey(messageKey).Build()
+}
+
+// SuccessResponseWithContext creates a success response with context-based i18n
+func SuccessResponseWithContext(ctx context.Context, data interface{}, messageKey string) BaseResponse {
+	return NewSuccessResponse().WithContext(ctx).WithData(data).WithMessageKey(messageKey).Build()
+}
+
+// SuccessResponseWithPagination creates a success response with pagination
+func SuccessResponseWithPagination(data interface{}, message string, pagination PaginationInfo) BaseResponse {
+	return NewSuccessResponse().WithData(data).WithMessage(message).WithPagination(pagination).Build()
+}
+
 // SuccessResponseWithPaginationI18n creates a success response with pagination and i18n message
 func SuccessResponseWithPaginationI18n(data interface{}, messageKey string, pagination PaginationInfo) BaseResponse {
-	return BaseResponse{
-		Code:       SUCCESS,
-		Message:    T(messageKey),
-		Data:       data,
-		Pagination: &pagination,
-		Timestamp:  time.Now(),
-	}
+	return NewSuccessResponse().WithData(data).WithMessageKey(messageKey).WithPagination(pagination).Build()
 }
 
 // ErrorResponse creates an error response
@@ -190,14 +386,29 @@ func CreateErrorResponseI18n(code ResponseCode, messageKey string, details ...Er
 	}
 }
 
+// CreateErrorResponseI18nWithParams creates an error response with an i18n message interpolated with params
+func CreateErrorResponseI18nWithParams(code ResponseCode, messageKey string, params map[string]any, details ...ErrorDetail) *ErrorResponse {
+	return &ErrorResponse{
+		Code:      code,
+		Message:   Tf(messageKey, params),
+		Details:   details,
+		Timestamp: time.Now(),
+	}
+}
+
+// CreateErrorResponseFrom creates an error response with i18n message wrapping a cause
+func CreateErrorResponseFrom(code ResponseCode, messageKey string, cause error, details ...ErrorDetail) *ErrorResponse {
+	return CreateErrorResponseI18n(code, messageKey, details...).WithCause(cause)
+}
+
 // ValidationError creates a validation error response
 func ValidationError(message string, details ...ErrorDetail) *ErrorResponse {
-	return CreateErrorResponse(VALIDATION_ERROR, message, details...)
+	return CreateErrorResponse(VALIDATION_ERROR, message, details...).withKind(errorKindValidation)
 }
 
 // ValidationErrorI18n creates a validation error response with i18n message
 func ValidationErrorI18n(details ...ErrorDetail) *ErrorResponse {
-	return CreateErrorResponseI18n(VALIDATION_ERROR, "response.error.validation", details...)
+	return CreateErrorResponseI18n(VALIDATION_ERROR, "response.error.validation", details...).withKind(errorKindValidation)
 }
 
 // NotFoundError creates a not found error response
@@ -230,14 +441,19 @@ func InternalErrorI18n() *ErrorResponse {
 	return CreateErrorResponseI18n(INTERNAL_ERROR, "response.error.internal")
 }
 
+// InternalErrorFrom creates an internal error response with i18n message wrapping a cause
+func InternalErrorFrom(err error) *ErrorResponse {
+	return InternalErrorI18n().WithCause(err)
+}
+
 // BadRequestError creates a bad request error response
 func BadRequestError(message string) *ErrorResponse {
-	return CreateErrorResponse(BAD_REQUEST, message)
+	return CreateErrorResponse(BAD_REQUEST, message).withKind(errorKindBadRequest)
 }
 
 // BadRequestErrorI18n creates a bad request error response with i18n message
 func BadRequestErrorI18n() *ErrorResponse {
-	return CreateErrorResponseI18n(BAD_REQUEST, "response.error.bad_request")
+	return CreateErrorResponseI18n(BAD_REQUEST, "response.error.bad_request").withKind(errorKindBadRequest)
 }
 
 // ConflictError creates a conflict error response
@@ -251,7 +467,29 @@ func ConflictErrorI18n() *ErrorResponse {
 }
 
 // CalculatePagination calculates pagination information
+// pageSize and currentPage are clamped to at least 1; an empty result has no pages and no navigation
 func CalculatePagination(currentPage, pageSize int, totalItems int64) PaginationInfo {
+	if pageSize < 1 {
+		pageSize = 1
+	}
+	if currentPage < 1 {
+		currentPage = 1
+	}
+	if totalItems < 0 {
+		totalItems = 0
+	}
+
+	if totalItems == 0 {
+		return PaginationInfo{
+			CurrentPage: currentPage,
+			PageSize:    pageSize,
+			TotalPages:  0,
+			TotalItems:  0,
+			HasNext:     false,
+			HasPrev:     false,
+		}
+	}
+
 	totalPages := int((totalItems + int64(pageSize) - 1) / int64(pageSize))
 
 	return PaginationInfo{
@@ -264,6 +502,44 @@ func CalculatePagination(currentPage, pageSize int, totalItems int64) Pagination
 	}
 }
 
+// BuildPaginationLinks builds navigation links from the current request URL.
+// Existing query parameters are preserved, only page and size are replaced.
+// Links that do not apply (e.g. next on the last page) are left empty
+func BuildPaginationLinks(requestURL *url.URL, pagination PaginationInfo) *PaginationLinks {
+	if requestURL == nil {
+		return nil
+	}
+
+	links := &PaginationLinks{
+		Self: pageURL(requestURL, pagination.CurrentPage, pagination.PageSize),
+	}
+	if pagination.TotalPages > 0 {
+		links.First = pageURL(requestURL, 1, pagination.PageSize)
+		links.Last = pageURL(requestURL, pagination.TotalPages, pagination.PageSize)
+	}
+	if pagination.HasNext {
+		links.Next = pageURL(requestURL, pagination.CurrentPage+1, pagination.PageSize)
+	}
+	if pagination.HasPrev {
+		links.Prev = pageURL(requestURL, pagination.CurrentPage-1, pagination.PageSize)
+	}
+	return links
+}
+
+// pageURL returns the request path and query with page and size replaced
+func pageURL(requestURL *url.URL, page, pageSize int) string {
+	query := requestURL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("size", strconv.Itoa(pageSize))
+
+	target := url.URL{
+		Path:     requestURL.Path,
+		RawPath:  requestURL.RawPath,
+		RawQuery: query.Encode(),
+	}
+	return target.String()
+}
+
 // AuthResponse represents authentication response
 // @Description Phản hồi xác thực
 type AuthResponse struct {
@@ -0,0 +1,158 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSuccessConstructorsIncludeData(t *testing.T) {
+	data := map[string]string{"id": "1"}
+	pagination := CalculatePagination(1, 10, 25)
+
+	tests := []struct {
+		name           string
+		response       BaseResponse
+		wantPagination bool
+	}{
+		{"SuccessResponse", SuccessResponse(data, "ok"), false},
+		{"SuccessResponseI18n", SuccessResponseI18n(data, MsgSuccessDefault), false},
+		{"SuccessResponseWithContext", SuccessResponseWithContext(context.Background(), data, MsgSuccessDefault), false},
+		{"SuccessResponseWithPagination", SuccessResponseWithPagination(data, "ok", pagination), true},
+		{"SuccessResponseWithPaginationI18n", SuccessResponseWithPaginationI18n(data, MsgSuccessDefault, pagination), true},
+		{"builder", NewSuccessResponse().WithData(data).WithPagination(pagination).WithMessageKey(MsgSuccessDefault).Build(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.response.Code != SUCCESS {
+				t.Errorf("code = %v, want %v", tt.response.Code, SUCCESS)
+			}
+			got, ok := tt.response.Data.(map[string]string)
+			if !ok || got["id"] != "1" {
+				t.Errorf("data = %#v, want %#v", tt.response.Data, data)
+			}
+			if (tt.response.Pagination != nil) != tt.wantPagination {
+				t.Errorf("pagination = %v, want present %v", tt.response.Pagination, tt.wantPagination)
+			}
+			if tt.wantPagination && tt.response.Pagination.TotalItems != 25 {
+				t.Errorf("pagination.total_items = %d, want 25", tt.response.Pagination.TotalItems)
+			}
+		})
+	}
+}
+
+func TestSuccessResponseBuilderMessage(t *testing.T) {
+	response := NewSuccessResponse().WithMessageKey(MsgSuccessDefault).WithMessage("done").Build()
+	if response.Message != "done" {
+		t.Errorf("message = %q, want the last message set", response.Message)
+	}
+
+	response = NewSuccessResponse().WithMessage("done").WithMessageKey("missing.key").Build()
+	if response.Message == "done" {
+		t.Error("WithMessageKey did not replace the previous message")
+	}
+}
+
+func TestSuccessWithPaginationIncludesData(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/items?page=2&size=1", nil), rec)
+
+	if err := controller.SuccessWithPagination(c, []testItem{{ID: "b"}}, 3, 2, 1, MsgSuccessRetrieved); err != nil {
+		t.Fatalf("SuccessWithPagination: %v", err)
+	}
+
+	var body struct {
+		Data       []testItem      `json:"data"`
+		Pagination *PaginationInfo `json:"pagination"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if len(body.Data) != 1 || body.Data[0].ID != "b" {
+		t.Errorf("data = %+v, want the page items", body.Data)
+	}
+	if body.Pagination == nil || body.Pagination.CurrentPage != 2 || body.Pagination.TotalItems != 3 {
+		t.Errorf("pagination = %+v, want page 2 of 3 items", body.Pagination)
+	}
+}
+
+func TestErrorResponseImplementsError(t *testing.T) {
+	cause := errors.New("connection refused")
+	errResp := InternalErrorFrom(cause)
+
+	var err error = errResp
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is does not find the cause")
+	}
+	if !errors.Is(err, ErrInternalResponse) {
+		t.Error("errors.Is does not match the internal sentinel")
+	}
+	if errors.Is(err, ErrNotFoundResponse) {
+		t.Error("internal error matches the not found sentinel")
+	}
+	if !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Error() = %q, want the cause included", err.Error())
+	}
+	if strings.Contains(errResp.Message, "connection refused") {
+		t.Errorf("message %q exposes the cause", errResp.Message)
+	}
+}
+
+func TestErrorResponseWrapping(t *testing.T) {
+	wrapped := fmt.Errorf("load order: %w", NotFoundError("order not found"))
+
+	if !errors.Is(wrapped, ErrNotFoundResponse) {
+		t.Error("wrapped not found error does not match its sentinel")
+	}
+	var errResp *ErrorResponse
+	if !errors.As(wrapped, &errResp) || errResp.Message != "order not found" {
+		t.Errorf("errors.As = %+v, want the original error response", errResp)
+	}
+
+	coded := &ErrorResponse{Code: CONFLICT, ErrorCode: "EMAIL_TAKEN"}
+	if !errors.Is(coded, &ErrorResponse{Code: CONFLICT, ErrorCode: "EMAIL_TAKEN"}) {
+		t.Error("same error code does not match")
+	}
+	if errors.Is(coded, &ErrorResponse{Code: CONFLICT, ErrorCode: "PHONE_TAKEN"}) {
+		t.Error("different error code matches")
+	}
+	if !errors.Is(coded, ErrConflictResponse) {
+		t.Error("coded error does not match the code sentinel")
+	}
+}
+
+func TestBadRequestAndValidationSentinels(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantValidation bool
+		wantBadRequest bool
+	}{
+		{"ValidationError", ValidationError("invalid", ErrorDetail{Field: "email"}), true, false},
+		{"ValidationErrorI18n", fmt.Errorf("parse: %w", ValidationErrorI18n()), true, false},
+		{"BadRequestError", BadRequestError("bad"), false, true},
+		{"BadRequestErrorI18n", fmt.Errorf("decode: %w", BadRequestErrorI18n()), false, true},
+		// The intent of a bare 400 is unknown, it matches both
+		{"bare 400", CreateErrorResponse(BAD_REQUEST, "bad"), true, true},
+		{"not found", NotFoundError("missing"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, ErrValidationResponse); got != tt.wantValidation {
+				t.Errorf("errors.Is(err, ErrValidationResponse) = %v, want %v", got, tt.wantValidation)
+			}
+			if got := errors.Is(tt.err, ErrBadRequestResponse); got != tt.wantBadRequest {
+				t.Errorf("errors.Is(err, ErrBadRequestResponse) = %v, want %v", got, tt.wantBadRequest)
+			}
+		})
+	}
+}
@@ -1,14 +1,23 @@
 package common
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 type ctxKey int
 
 const (
 	userIDKey ctxKey = iota
 	sessionIDKey
+	requestIDKey
+	rolesKey
+	tenantIDKey
 )
 
+// claimKey keys the custom claims stored with WithClaim
+type claimKey string
+
 func WithUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
 }
@@ -28,3 +37,61 @@ func SID(ctx context.Context) (string, bool) {
 	id, ok := v.(string)
 	return id, ok
 }
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) (string, bool) {
+	v := ctx.Value(requestIDKey)
+	id, ok := v.(string)
+	return id, ok
+}
+
+func WithRoles(ctx context.Context, roles []string) context.Context {
+	return context.WithValue(ctx, rolesKey, roles)
+}
+
+func Roles(ctx context.Context) ([]string, bool) {
+	v := ctx.Value(rolesKey)
+	roles, ok := v.([]string)
+	return roles, ok
+}
+
+// HasRole reports whether the authenticated principal of ctx has role, pass c.Request().Context() in handlers
+func HasRole(ctx context.Context, role string) bool {
+	roles, _ := Roles(ctx)
+	return slices.Contains(roles, role)
+}
+
+// HasAnyRole reports whether the authenticated principal of ctx has at least one of roles
+func HasAnyRole(ctx context.Context, roles ...string) bool {
+	granted, _ := Roles(ctx)
+	for _, role := range roles {
+		if slices.Contains(granted, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+func TenantID(ctx context.Context) (string, bool) {
+	v := ctx.Value(tenantIDKey)
+	id, ok := v.(string)
+	return id, ok
+}
+
+// WithClaim stores a custom token claim in ctx
+func WithClaim(ctx context.Context, key string, value any) context.Context {
+	return context.WithValue(ctx, claimKey(key), value)
+}
+
+// Claim returns a custom token claim stored by WithClaim
+func Claim(ctx context.Context, key string) (any, bool) {
+	v := ctx.Value(claimKey(key))
+	return v, v != nil
+}
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHasRole(t *testing.T) {
+	ctx := WithRoles(context.Background(), []string{"manager", "viewer"})
+
+	if !HasRole(ctx, "manager") || HasRole(ctx, "admin") {
+		t.Error("HasRole does not match the roles of the context")
+	}
+	if !HasAnyRole(ctx, "admin", "viewer") || HasAnyRole(ctx, "admin", "owner") || HasAnyRole(ctx) {
+		t.Error("HasAnyRole does not match the roles of the context")
+	}
+	if HasRole(context.Background(), "admin") || HasAnyRole(context.Background(), "admin") {
+		t.Error("context without roles has a role")
+	}
+	if roles, ok := Roles(ctx); !ok || len(roles) != 2 {
+		t.Errorf("Roles() = %v, %v", roles, ok)
+	}
+}
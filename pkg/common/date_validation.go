@@ -0,0 +1,157 @@
+package common
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DateLayout is the default layout of date parameters (YYYY-MM-DD)
+const DateLayout = "2006-01-02"
+
+// dateLocation is the location date strings are parsed in when they carry no offset
+var dateLocation atomic.Pointer[time.Location]
+
+// nowFunc is the clock of AgeAtLeast
+var nowFunc = time.Now
+
+// SetDateLocation sets the location used to parse dates and compute ages, UTC by default
+func SetDateLocation(location *time.Location) {
+	dateLocation.Store(location)
+}
+
+// DateLocation returns the location used to parse dates and compute ages
+func DateLocation() *time.Location {
+	if location := dateLocation.Load(); location != nil {
+		return location
+	}
+	return time.UTC
+}
+
+// ParseDate parses value with layout in DateLocation, layouts with an offset keep the offset of value
+func ParseDate(value, layout string) (time.Time, error) {
+	return time.ParseInLocation(layout, strings.TrimSpace(value), DateLocation())
+}
+
+// DateFormat validates that value is a date in layout, the expected format is interpolated as {format}.
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) DateFormat(field string, value string, layout string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if value == "" {
+		return nil
+	}
+	if _, err := ParseDate(value, layout); err != nil {
+		return &ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey, map[string]any{"field": field, "format": displayLayout(layout)}),
+			Value:   v.redact(field, value),
+		}
+	}
+	return nil
+}
+
+// DateAfter validates that value is strictly after bound, interpolated as {after}
+func (v *BaseValidator) DateAfter(field string, value time.Time, bound time.Time, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if !value.After(bound) {
+		return dateError(field, value, messageKey, map[string]any{"after": formatDateBound(bound)})
+	}
+	return nil
+}
+
+// DateBefore validates that value is strictly before bound, interpolated as {before}
+func (v *BaseValidator) DateBefore(field string, value time.Time, bound time.Time, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if !value.Before(bound) {
+		return dateError(field, value, messageKey, map[string]any{"before": formatDateBound(bound)})
+	}
+	return nil
+}
+
+// DateBetween validates that value is within [min, max], interpolated as {min} and {max}
+func (v *BaseValidator) DateBetween(field string, value time.Time, min, max time.Time, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if value.Before(min) || value.After(max) {
+		return dateError(field, value, messageKey, map[string]any{"min": formatDateBound(min), "max": formatDateBound(max)})
+	}
+	return nil
+}
+
+// AgeAtLeast validates that someone born on birthdate is at least years old today in DateLocation,
+// interpolated as {years}. A person born on February 29 turns one year older on March 1 in common years
+func (v *BaseValidator) AgeAtLeast(field string, birthdate time.Time, years int, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if ageAt(birthdate, nowFunc()) < years {
+		return dateError(field, birthdate, messageKey, map[string]any{"years": years})
+	}
+	return nil
+}
+
+// ParseDateParam parses the query parameter name of a request with layout, for controllers.
+// It returns nil without error when the parameter is absent and a VALIDATION_ERROR response when malformed
+func ParseDateParam(c echo.Context, name, layout string) (*time.Time, *ErrorResponse) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := ParseDate(value, layout)
+	if err != nil {
+		ctx := LocaleContext(c)
+		return nil, ValidationErrorI18n(ErrorDetail{
+			Field:   name,
+			Message: TfWithContext(ctx, MsgValidationDateFormat, map[string]any{"field": name, "format": displayLayout(layout)}),
+			Value:   RedactValue(name, value),
+		})
+	}
+	return &parsed, nil
+}
+
+// ageAt returns the age in full years of someone born on birthdate at now, both taken in DateLocation
+func ageAt(birthdate, now time.Time) int {
+	location := DateLocation()
+	birthdate = birthdate.In(location)
+	now = now.In(location)
+
+	age := now.Year() - birthdate.Year()
+	if now.Month() < birthdate.Month() || now.Month() == birthdate.Month() && now.Day() < birthdate.Day() {
+		age--
+	}
+	return age
+}
+
+// dateError builds the error detail of a date rule
+func dateError(field string, value time.Time, messageKey string, params map[string]any) *ErrorDetail {
+	params["field"] = field
+	return &ErrorDetail{
+		Field:   field,
+		Message: Tf(messageKey, params),
+		Value:   RedactValue(field, value.In(DateLocation()).Format(time.RFC3339)),
+	}
+}
+
+// formatDateBound formats a bound for messages, omitting the time of day when it is midnight
+func formatDateBound(bound time.Time) string {
+	bound = bound.In(DateLocation())
+	if bound.Hour() == 0 && bound.Minute() == 0 && bound.Second() == 0 {
+		return bound.Format(DateLayout)
+	}
+	return bound.Format("2006-01-02 15:04:05")
+}
+
+// layoutReplacer translates Go reference layouts to the notation users know, e.g. 2006-01-02 -> YYYY-MM-DD
+var layoutReplacer = strings.NewReplacer(
+	"2006", "YYYY",
+	"01", "MM",
+	"02", "DD",
+	"15", "HH",
+	"04", "mm",
+	"05", "ss",
+)
+
+// displayLayout returns layout in a human readable notation
+func displayLayout(layout string) string {
+	return layoutReplacer.Replace(layout)
+}
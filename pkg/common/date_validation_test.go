@@ -0,0 +1,192 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/labstack/echo/v4"
+)
+
+// useDateLocation sets the date location for the duration of the test
+func useDateLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := dateLocation.Load()
+	SetDateLocation(location)
+	t.Cleanup(func() { dateLocation.Store(previous) })
+	return location
+}
+
+// useNow freezes nowFunc at now for the duration of the test
+func useNow(t *testing.T, now time.Time) {
+	t.Helper()
+	previous := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = previous })
+}
+
+func TestDateFormat(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"date_format": "{field} phải có dạng {format}"},
+	}))
+	v := &BaseValidator{}
+
+	tests := []struct {
+		value  string
+		layout string
+		valid  bool
+	}{
+		{"2024-02-29", DateLayout, true},
+		{"2023-02-29", DateLayout, false},
+		{"2024-13-01", DateLayout, false},
+		{"29/02/2024", "02/01/2006", true},
+		{"2024-02-29", "02/01/2006", false},
+		{"2024-03-10 02:30", "2006-01-02 15:04", true},
+		{"2024-03-10T10:00:00+07:00", time.RFC3339, true},
+		{"", DateLayout, true},
+	}
+	for _, tt := range tests {
+		if got := v.DateFormat("date", tt.value, tt.layout, MsgValidationDateFormat) == nil; got != tt.valid {
+			t.Errorf("DateFormat(%q, %q) valid = %v, want %v", tt.value, tt.layout, got, tt.valid)
+		}
+	}
+
+	detail := v.DateFormat("birthday", "31-12-2000", "02/01/2006 15:04", MsgValidationDateFormat)
+	if detail == nil || detail.Message != "birthday phải có dạng DD/MM/YYYY HH:mm" {
+		t.Errorf("detail = %+v, want the readable format", detail)
+	}
+}
+
+func TestParseDateInLocationAcrossDST(t *testing.T) {
+	newYork := useDateLocation(t, "America/New_York")
+
+	// 2024-03-10 is the spring forward day in New York, 02:30 does not exist
+	before, err := ParseDate("2024-03-10 01:30", "2006-01-02 15:04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseDate("2024-03-10 03:30", "2006-01-02 15:04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Location() != newYork {
+		t.Errorf("location = %v, want the date location", before.Location())
+	}
+	if got := after.Sub(before); got != time.Hour {
+		t.Errorf("01:30 to 03:30 across spring forward = %v, want 1h", got)
+	}
+	if _, offset := before.Zone(); offset != -5*3600 {
+		t.Errorf("offset before DST = %d", offset)
+	}
+	if _, offset := after.Zone(); offset != -4*3600 {
+		t.Errorf("offset after DST = %d", offset)
+	}
+
+	withOffset, err := ParseDate("2024-03-10T12:00:00+07:00", time.RFC3339)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := withOffset.Zone(); offset != 7*3600 {
+		t.Errorf("explicit offset = %d, want it kept", offset)
+	}
+}
+
+func TestDateRangeRules(t *testing.T) {
+	useDateLocation(t, "Asia/Ho_Chi_Minh")
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{
+			"date_after":   "{field} sau {after}",
+			"date_between": "{field} từ {min} đến {max}",
+		},
+	}))
+	v := &BaseValidator{}
+	location := DateLocation()
+	day := func(d int) time.Time { return time.Date(2024, time.May, d, 0, 0, 0, 0, location) }
+
+	if detail := v.DateAfter("end", day(2), day(1), MsgValidationDateAfter); detail != nil {
+		t.Errorf("DateAfter later = %+v", detail)
+	}
+	detail := v.DateAfter("end", day(1), day(1), MsgValidationDateAfter)
+	if detail == nil || detail.Message != "end sau 2024-05-01" {
+		t.Errorf("DateAfter equal = %+v, want strictly after with the bound as a date", detail)
+	}
+	if detail := v.DateBefore("start", day(1), day(2), MsgValidationDateBefore); detail != nil {
+		t.Errorf("DateBefore earlier = %+v", detail)
+	}
+	if detail := v.DateBefore("start", day(2), day(2), MsgValidationDateBefore); detail == nil {
+		t.Error("DateBefore equal passed, want strictly before")
+	}
+
+	if detail := v.DateBetween("at", day(1), day(1), day(3), MsgValidationDateBetween); detail != nil {
+		t.Errorf("DateBetween inclusive min = %+v", detail)
+	}
+	if detail := v.DateBetween("at", day(3), day(1), day(3), MsgValidationDateBetween); detail != nil {
+		t.Errorf("DateBetween inclusive max = %+v", detail)
+	}
+	noon := day(4).Add(12 * time.Hour)
+	detail = v.DateBetween("at", noon, day(1), noon.Add(-time.Second), MsgValidationDateBetween)
+	if detail == nil || detail.Message != "at từ 2024-05-01 đến 2024-05-04 11:59:59" {
+		t.Errorf("DateBetween outside = %+v", detail)
+	}
+}
+
+func TestAgeAtLeast(t *testing.T) {
+	useDateLocation(t, "Asia/Ho_Chi_Minh")
+	location := DateLocation()
+	v := &BaseValidator{}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		birthdate time.Time
+		years     int
+		valid     bool
+	}{
+		{"birthday today", time.Date(2024, 6, 15, 8, 0, 0, 0, location), time.Date(2006, 6, 15, 0, 0, 0, 0, location), 18, true},
+		{"day before birthday", time.Date(2024, 6, 14, 23, 59, 0, 0, location), time.Date(2006, 6, 15, 0, 0, 0, 0, location), 18, false},
+		// 2024-06-14 18:00 UTC is already 2024-06-15 in Vietnam
+		{"birthday in date location", time.Date(2024, 6, 14, 18, 0, 0, 0, time.UTC), time.Date(2006, 6, 15, 0, 0, 0, 0, location), 18, true},
+		{"leap day on Feb 28", time.Date(2022, 2, 28, 12, 0, 0, 0, location), time.Date(2004, 2, 29, 0, 0, 0, 0, location), 18, false},
+		{"leap day on Mar 1", time.Date(2022, 3, 1, 12, 0, 0, 0, location), time.Date(2004, 2, 29, 0, 0, 0, 0, location), 18, true},
+		{"older", time.Date(2024, 1, 1, 0, 0, 0, 0, location), time.Date(1990, 12, 31, 0, 0, 0, 0, location), 18, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useNow(t, tt.now)
+			if got := v.AgeAtLeast("birthdate", tt.birthdate, tt.years, MsgValidationAgeAtLeast) == nil; got != tt.valid {
+				t.Errorf("AgeAtLeast valid = %v, want %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParseDateParam(t *testing.T) {
+	useDateLocation(t, "Asia/Ho_Chi_Minh")
+	e := echo.New()
+	newContext := func(target string) echo.Context {
+		return e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), httptest.NewRecorder())
+	}
+
+	parsed, errResp := ParseDateParam(newContext("/?from=2024-05-01"), "from", DateLayout)
+	if errResp != nil || parsed == nil {
+		t.Fatalf("ParseDateParam = %v, %+v", parsed, errResp)
+	}
+	if want := time.Date(2024, 5, 1, 0, 0, 0, 0, DateLocation()); !parsed.Equal(want) {
+		t.Errorf("parsed = %v, want %v", parsed, want)
+	}
+
+	if parsed, errResp := ParseDateParam(newContext("/"), "from", DateLayout); parsed != nil || errResp != nil {
+		t.Errorf("absent parameter = %v, %+v, want nil without error", parsed, errResp)
+	}
+
+	_, errResp = ParseDateParam(newContext("/?from=01/05/2024"), "from", DateLayout)
+	if errResp == nil || errResp.Code != VALIDATION_ERROR || len(errResp.Details) != 1 || errResp.Details[0].Field != "from" {
+		t.Errorf("malformed parameter = %+v, want a VALIDATION_ERROR on from", errResp)
+	}
+}
@@ -0,0 +1,166 @@
+package common
+
+import (
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// decimalPattern matches plain decimal numbers: optional sign, digits, optional fraction ("-12.50")
+var decimalPattern = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+// ParseDecimal parses a decimal string exactly, without float64 rounding.
+// Exponents, thousands separators and bare dots (".5", "5.") are rejected
+func ParseDecimal(value string) (*big.Rat, bool) {
+	if !decimalPattern.MatchString(value) {
+		return nil, false
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	return rat, ok
+}
+
+// CanonicalDecimal returns the canonical form of a decimal string: no '+' sign, no leading zeros
+// in the integer part and no trailing zeros in the fraction, "+0199.990" -> "199.99"
+func CanonicalDecimal(value string) (string, bool) {
+	if !decimalPattern.MatchString(value) {
+		return "", false
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	intPart, fracPart, _ := strings.Cut(strings.TrimLeft(value, "+-"), ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	canonical := intPart
+	if fracPart != "" {
+		canonical += "." + fracPart
+	}
+	if negative && canonical != "0" {
+		canonical = "-" + canonical
+	}
+	return canonical, true
+}
+
+// decimalDigits returns the number of integer and fraction digits of a canonical decimal
+func decimalDigits(canonical string) (int, int) {
+	intPart, fracPart, _ := strings.Cut(strings.TrimPrefix(canonical, "-"), ".")
+	if intPart == "0" {
+		return 0, len(fracPart)
+	}
+	return len(intPart), len(fracPart)
+}
+
+// NumericString validates that value is a decimal number such as "199.99" or "-5".
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) NumericString(field string, value string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if value == "" {
+		return nil
+	}
+	if _, ok := ParseDecimal(value); !ok {
+		return &ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey, map[string]any{"field": field}),
+			Value:   v.redact(field, value),
+		}
+	}
+	return nil
+}
+
+// DecimalPrecision validates that value is a decimal number with at most maxIntDigits integer digits and
+// maxFracDigits fraction digits (trailing zeros are ignored, "199.990" has 2), interpolated as {int_digits} and
+// {frac_digits}. It returns the canonical value for reuse. An empty value passes, use Required to reject it
+func (v *BaseValidator) DecimalPrecision(field string, value string, maxIntDigits, maxFracDigits int, messageKey string) (string, *ErrorDetail) {
+	field = v.path(field)
+	if value == "" {
+		return "", nil
+	}
+
+	canonical, ok := CanonicalDecimal(value)
+	if ok {
+		intDigits, fracDigits := decimalDigits(canonical)
+		ok = intDigits <= maxIntDigits && fracDigits <= maxFracDigits
+	}
+	if !ok {
+		return "", &ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey, map[string]any{"field": field, "int_digits": maxIntDigits, "frac_digits": maxFracDigits}),
+			Value:   v.redact(field, value),
+		}
+	}
+	return canonical, nil
+}
+
+// DecimalRange validates that value is a decimal number within [min, max] compared exactly,
+// interpolated as {min} and {max}. It returns the canonical value for reuse.
+// min and max must be decimal strings. An empty value passes, use Required to reject it
+func (v *BaseValidator) DecimalRange(field string, value string, min, max string, messageKey string) (string, *ErrorDetail) {
+	field = v.path(field)
+	if value == "" {
+		return "", nil
+	}
+
+	canonical, ok := CanonicalDecimal(value)
+	if ok {
+		ok = decimalInRange(value, min, max)
+	}
+	if !ok {
+		return "", &ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey, map[string]any{"field": field, "min": min, "max": max}),
+			Value:   v.redact(field, value),
+		}
+	}
+	return canonical, nil
+}
+
+// decimalInRange compares value with the decimal bounds, an empty or invalid bound is not checked
+func decimalInRange(value, min, max string) bool {
+	number, ok := ParseDecimal(value)
+	if !ok {
+		return false
+	}
+	if lower, ok := ParseDecimal(min); ok && number.Cmp(lower) < 0 {
+		return false
+	}
+	if upper, ok := ParseDecimal(max); ok && number.Cmp(upper) > 0 {
+		return false
+	}
+	return true
+}
+
+// ruleNumericString accepts decimal number strings (numeric tag)
+func ruleNumericString(value reflect.Value, _ string) (bool, string, map[string]any) {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return true, "", nil
+	}
+	_, ok := ParseDecimal(value.String())
+	return ok, MsgValidationNumericStr, nil
+}
+
+// ruleDecimal checks the precision of decimal strings, decimal=10:2 allows 10 integer and 2 fraction digits
+func ruleDecimal(value reflect.Value, param string) (bool, string, map[string]any) {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return true, "", nil
+	}
+
+	intParam, fracParam, _ := strings.Cut(param, ":")
+	maxIntDigits, err := strconv.Atoi(intParam)
+	if err != nil {
+		return true, "", nil
+	}
+	maxFracDigits, _ := strconv.Atoi(fracParam)
+
+	params := map[string]any{"int_digits": maxIntDigits, "frac_digits": maxFracDigits}
+	canonical, ok := CanonicalDecimal(value.String())
+	if !ok {
+		return false, MsgValidationDecimal, params
+	}
+	intDigits, fracDigits := decimalDigits(canonical)
+	return intDigits <= maxIntDigits && fracDigits <= maxFracDigits, MsgValidationDecimal, params
+}
@@ -0,0 +1,141 @@
+package common
+
+import "testing"
+
+func TestCanonicalDecimal(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{"199.990", "199.99", true},
+		{"+0199.990", "199.99", true},
+		{"-5", "-5", true},
+		{"-0.00", "0", true},
+		{"0.50", "0.5", true},
+		{"000", "0", true},
+		{"12345678901234567890.123456789", "12345678901234567890.123456789", true},
+		{"", "", false},
+		{"1e3", "", false},
+		{"1,000", "", false},
+		{".5", "", false},
+		{"5.", "", false},
+		{"--1", "", false},
+		{" 1", "", false},
+		{"NaN", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := CanonicalDecimal(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("CanonicalDecimal(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+			if _, parsed := ParseDecimal(tt.value); parsed != tt.wantOK {
+				t.Errorf("ParseDecimal(%q) ok = %v, want %v", tt.value, parsed, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNumericString(t *testing.T) {
+	v := &BaseValidator{}
+	for _, value := range []string{"", "0", "-12.50", "+3"} {
+		if detail := v.NumericString("amount", value, MsgValidationNumericStr); detail != nil {
+			t.Errorf("NumericString(%q) = %+v, want valid", value, detail)
+		}
+	}
+	for _, value := range []string{"abc", "1.2.3", "1e5", "١٢"} {
+		if detail := v.NumericString("amount", value, MsgValidationNumericStr); detail == nil || detail.Value != value {
+			t.Errorf("NumericString(%q) = %+v, want an error with the value", value, detail)
+		}
+	}
+}
+
+func TestDecimalPrecision(t *testing.T) {
+	v := &BaseValidator{}
+
+	tests := []struct {
+		name      string
+		value     string
+		want      string
+		wantError bool
+	}{
+		{"empty", "", "", false},
+		{"exact limits", "12345678.99", "12345678.99", false},
+		{"trailing zeros ignored", "199.990", "199.99", false},
+		{"negative", "-99999999.01", "-99999999.01", false},
+		{"only fraction", "0.01", "0.01", false},
+		{"too many fraction digits", "1.001", "", true},
+		{"too many integer digits", "123456789", "", true},
+		{"leading zeros not counted", "000012345678", "12345678", false},
+		{"malformed", "1,5", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, detail := v.DecimalPrecision("amount", tt.value, 8, 2, MsgValidationDecimal)
+			if (detail != nil) != tt.wantError || got != tt.want {
+				t.Errorf("DecimalPrecision(%q) = %q, %+v, want %q, error %v", tt.value, got, detail, tt.want, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDecimalRange(t *testing.T) {
+	v := &BaseValidator{}
+
+	tests := []struct {
+		name      string
+		value     string
+		min, max  string
+		want      string
+		wantError bool
+	}{
+		{"lower bound", "0.01", "0.01", "1000", "0.01", false},
+		{"upper bound", "1000.000", "0.01", "1000", "1000", false},
+		{"just below", "0.009999999999999999999", "0.01", "1000", "", true},
+		{"just above", "1000.0000000000000001", "0.01", "1000", "", true},
+		{"negative range", "-5", "-10", "-1", "-5", false},
+		{"no upper bound", "99999999999999999999", "0", "", "99999999999999999999", false},
+		{"malformed", "ten", "0", "100", "", true},
+		{"empty", "", "0", "100", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, detail := v.DecimalRange("amount", tt.value, tt.min, tt.max, MsgValidationDecimalRange)
+			if (detail != nil) != tt.wantError || got != tt.want {
+				t.Errorf("DecimalRange(%q) = %q, %+v, want %q, error %v", tt.value, got, detail, tt.want, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDecimalTags(t *testing.T) {
+	type request struct {
+		Amount string `json:"amount" validate:"required,decimal=10:2"`
+		Rate   string `json:"rate" validate:"omitempty,numeric"`
+	}
+
+	tests := []struct {
+		name string
+		req  request
+		want map[string]string
+	}{
+		{"valid", request{Amount: "199.990", Rate: "-0.5"}, map[string]string{}},
+		{"precision", request{Amount: "1.999"}, map[string]string{"amount": MsgValidationDecimal}},
+		{"integer digits", request{Amount: "12345678901"}, map[string]string{"amount": MsgValidationDecimal}},
+		{"malformed", request{Amount: "1e2", Rate: "x"}, map[string]string{"amount": MsgValidationDecimal, "rate": MsgValidationNumericStr}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := failedFields(&tt.req)
+			if len(got) != len(tt.want) {
+				t.Fatalf("failures = %v, want %v", got, tt.want)
+			}
+			for field, key := range tt.want {
+				if got[field] != key {
+					t.Errorf("failure of %q = %q, want %q", field, got[field], key)
+				}
+			}
+		})
+	}
+}
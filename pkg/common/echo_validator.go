@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EchoValidator implements echo.Validator with the struct tag validation of ValidateStruct,
+// set it up with e.Validator = common.NewEchoValidator() to use c.Validate(&req) in handlers
+type EchoValidator struct{}
+
+// NewEchoValidator creates the echo.Validator backed by ValidateStruct
+func NewEchoValidator() echo.Validator {
+	return &EchoValidator{}
+}
+
+// Validate validates i, returning a *ValidationErrors when a rule fails
+func (v *EchoValidator) Validate(i any) error {
+	failures := collectFieldFailures(i)
+	if len(failures) == 0 {
+		return nil
+	}
+	return &ValidationErrors{
+		Details:  translateFieldFailures(context.Background(), failures),
+		failures: failures,
+	}
+}
+
+// ValidationErrors is the error returned by EchoValidator, carrying one ErrorDetail per failed rule.
+// ErrorHandlerMiddleware renders it as a VALIDATION_ERROR response in the request locale
+type ValidationErrors struct {
+	// Details are translated with the default locale, use Localize for the request locale
+	Details  []ErrorDetail
+	failures []fieldFailure
+}
+
+// Error implements the error interface
+func (e *ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e.Details))
+	for _, detail := range e.Details {
+		fields = append(fields, detail.Field)
+	}
+	return fmt.Sprintf("validation failed on fields: %s", strings.Join(fields, ", "))
+}
+
+// Localize returns the error details translated with the locale of ctx
+func (e *ValidationErrors) Localize(ctx context.Context) []ErrorDetail {
+	if len(e.failures) == 0 {
+		return e.Details
+	}
+	return translateFieldFailures(ctx, e.failures)
+}
+
+// ToErrorResponse converts the validation errors to a VALIDATION_ERROR response in the locale of ctx
+func (e *ValidationErrors) ToErrorResponse(ctx context.Context) *ErrorResponse {
+	return ValidationError(TWithContext(ctx, MsgErrorValidation), e.Localize(ctx)...).WithCause(e)
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testSignupRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Name  string `json:"name" validate:"required,max=10"`
+}
+
+func TestEchoValidatorThroughContext(t *testing.T) {
+	e := echo.New()
+	e.Validator = NewEchoValidator()
+
+	var validateErr error
+	e.POST("/signup", func(c echo.Context) error {
+		var req testSignupRequest
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		validateErr = c.Validate(&req)
+		return validateErr
+	})
+
+	serve := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(`{"email":"a@example.com","name":"An"}`)
+	if validateErr != nil {
+		t.Fatalf("valid request: %v", validateErr)
+	}
+
+	serve(`{"email":"nope","name":"a very long name"}`)
+	var validationErrors *ValidationErrors
+	if !errors.As(validateErr, &validationErrors) {
+		t.Fatalf("c.Validate() = %v, want *ValidationErrors", validateErr)
+	}
+	if len(validationErrors.Details) != 2 || validationErrors.Details[0].Field != "email" || validationErrors.Details[1].Field != "name" {
+		t.Errorf("details = %+v, want email then name", validationErrors.Details)
+	}
+	if got := validationErrors.Error(); got != "validation failed on fields: email, name" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestValidationErrorsLocalize(t *testing.T) {
+	manager := newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"required": "{field} là bắt buộc"},
+		"response":   map[string]any{"error": map[string]any{"validation": "Dữ liệu không hợp lệ"}},
+	})
+	manager.derived = map[string]*I18nManager{
+		"en": newTestI18n("en", map[string]any{
+			"validation": map[string]any{"required": "{field} is required"},
+			"response":   map[string]any{"error": map[string]any{"validation": "Invalid data"}},
+		}),
+	}
+	useGlobalI18n(t, manager)
+
+	err := NewEchoValidator().Validate(&testSignupRequest{Email: "a@example.com"})
+	var validationErrors *ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if got := validationErrors.Details[0].Message; got != "name là bắt buộc" {
+		t.Errorf("default locale message = %q", got)
+	}
+
+	en := SetLocaleInContext(context.Background(), "en")
+	if got := validationErrors.Localize(en)[0].Message; got != "name is required" {
+		t.Errorf("en message = %q", got)
+	}
+	errResp := validationErrors.ToErrorResponse(en)
+	if errResp.Code != VALIDATION_ERROR || errResp.Message != "Invalid data" || len(errResp.Details) != 1 {
+		t.Errorf("error response = %+v", errResp)
+	}
+	if !errors.Is(errResp, err) {
+		t.Error("error response does not wrap the validation errors")
+	}
+}
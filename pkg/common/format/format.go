@@ -0,0 +1,113 @@
+// Package format provides locale-aware formatting of dates, numbers and currency amounts
+// for message bodies, backed by golang.org/x/text
+package format
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// DateStyle is the length of a formatted date
+type DateStyle string
+
+const (
+	// DateStyleShort e.g. 01/02/2006 (en), 02/01/2006 (vn)
+	DateStyleShort DateStyle = "short"
+	// DateStyleMedium e.g. Jan 2, 2006 (en), 2 thg 1, 2006 (vn)
+	DateStyleMedium DateStyle = "medium"
+	// DateStyleLong e.g. January 2, 2006 (en), ngày 2 tháng 1 năm 2006 (vn)
+	DateStyleLong DateStyle = "long"
+	// DateStyleFull e.g. Monday, January 2, 2006 (en), Thứ Hai, ngày 2 tháng 1 năm 2006 (vn)
+	DateStyleFull DateStyle = "full"
+)
+
+// vietnameseWeekdays are the weekday names indexed by time.Weekday
+var vietnameseWeekdays = [...]string{"Chủ Nhật", "Thứ Hai", "Thứ Ba", "Thứ Tư", "Thứ Năm", "Thứ Sáu", "Thứ Bảy"}
+
+// LanguageTag maps an application locale ("vn", "en", "vi-VN"...) to its language tag, defaulting to English
+func LanguageTag(locale string) language.Tag {
+	switch strings.ToLower(locale) {
+	case "vn", "vi":
+		return language.Vietnamese
+	case "en", "":
+		return language.English
+	}
+	if tag, err := language.Parse(locale); err == nil {
+		return tag
+	}
+	return language.English
+}
+
+// isVietnamese reports whether locale formats as Vietnamese
+func isVietnamese(locale string) bool {
+	base, _ := LanguageTag(locale).Base()
+	return base.String() == "vi"
+}
+
+// FormatDate formats t in the date style of locale
+func FormatDate(locale string, t time.Time, style DateStyle) string {
+	if isVietnamese(locale) {
+		switch style {
+		case DateStyleMedium:
+			return fmt.Sprintf("%d thg %d, %d", t.Day(), int(t.Month()), t.Year())
+		case DateStyleLong:
+			return fmt.Sprintf("ngày %d tháng %d năm %d", t.Day(), int(t.Month()), t.Year())
+		case DateStyleFull:
+			return fmt.Sprintf("%s, ngày %d tháng %d năm %d", vietnameseWeekdays[t.Weekday()], t.Day(), int(t.Month()), t.Year())
+		default:
+			return t.Format("02/01/2006")
+		}
+	}
+
+	switch style {
+	case DateStyleMedium:
+		return t.Format("Jan 2, 2006")
+	case DateStyleLong:
+		return t.Format("January 2, 2006")
+	case DateStyleFull:
+		return t.Format("Monday, January 2, 2006")
+	default:
+		return t.Format("01/02/2006")
+	}
+}
+
+// FormatNumber formats f with the grouping and decimal separators of locale, e.g. 1,234.56 (en), 1.234,56 (vn)
+func FormatNumber(locale string, f float64) string {
+	return message.NewPrinter(LanguageTag(locale)).Sprint(number.Decimal(f))
+}
+
+// FormatCurrency formats amount in the ISO 4217 currency code using the symbol and separators of locale,
+// e.g. $1,234.50 (en, USD), 1.234.567 ₫ (vn, VND). An unknown code is used as the symbol
+func FormatCurrency(locale string, amount float64, currencyCode string) string {
+	printer := message.NewPrinter(LanguageTag(locale))
+
+	symbol := strings.ToUpper(currencyCode)
+	scale := 2
+	if unit, err := currency.ParseISO(currencyCode); err == nil {
+		symbol = printer.Sprint(currency.NarrowSymbol(unit))
+		scale, _ = currency.Standard.Rounding(unit)
+	}
+
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+	value := printer.Sprint(number.Decimal(amount, number.MinFractionDigits(scale), number.MaxFractionDigits(scale)))
+
+	var formatted string
+	if isVietnamese(locale) {
+		formatted = value + " " + symbol
+	} else {
+		formatted = symbol + value
+	}
+	if negative {
+		return "-" + formatted
+	}
+	return formatted
+}
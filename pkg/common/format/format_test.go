@@ -0,0 +1,90 @@
+package format
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale string
+		style  DateStyle
+		want   string
+	}{
+		{"en", DateStyleShort, "03/05/2024"},
+		{"en", DateStyleMedium, "Mar 5, 2024"},
+		{"en", DateStyleLong, "March 5, 2024"},
+		{"en", DateStyleFull, "Tuesday, March 5, 2024"},
+		{"vn", DateStyleShort, "05/03/2024"},
+		{"vn", DateStyleMedium, "5 thg 3, 2024"},
+		{"vn", DateStyleLong, "ngày 5 tháng 3 năm 2024"},
+		{"vn", DateStyleFull, "Thứ Ba, ngày 5 tháng 3 năm 2024"},
+		{"vi-VN", DateStyleShort, "05/03/2024"},
+		{"vn", "", "05/03/2024"},
+	}
+	for _, tt := range tests {
+		if got := FormatDate(tt.locale, date, tt.style); got != tt.want {
+			t.Errorf("FormatDate(%q, %q) = %q, want %q", tt.locale, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		value  float64
+		want   string
+	}{
+		{"en", 1234567.891, "1,234,567.891"},
+		{"en", 1000, "1,000"},
+		{"en", -0.5, "-0.5"},
+		{"vn", 1234567.891, "1.234.567,891"},
+		{"vn", 1000, "1.000"},
+		{"vn", -0.5, "-0,5"},
+	}
+	for _, tt := range tests {
+		if got := FormatNumber(tt.locale, tt.value); got != tt.want {
+			t.Errorf("FormatNumber(%q, %v) = %q, want %q", tt.locale, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		locale   string
+		amount   float64
+		currency string
+		want     string
+	}{
+		{"en", 1234.5, "USD", "$1,234.50"},
+		{"en", 1234567, "VND", "₫1,234,567"},
+		{"en", -12.3, "EUR", "-€12.30"},
+		{"en", 10, "xyz", "XYZ10.00"},
+		{"vn", 1234.5, "USD", "1.234,50 $"},
+		{"vn", 1234567, "VND", "1.234.567 ₫"},
+		{"vn", -12.3, "EUR", "-12,30 €"},
+		{"vn", 10, "xyz", "10,00 XYZ"},
+	}
+	for _, tt := range tests {
+		if got := FormatCurrency(tt.locale, tt.amount, tt.currency); got != tt.want {
+			t.Errorf("FormatCurrency(%q, %v, %q) = %q, want %q", tt.locale, tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestLanguageTag(t *testing.T) {
+	tests := map[string]string{
+		"vn":       "vi",
+		"VI":       "vi",
+		"":         "en",
+		"fr-CA":    "fr-CA",
+		"invalid!": "en",
+	}
+	for locale, want := range tests {
+		if got := LanguageTag(locale).String(); got != want {
+			t.Errorf("LanguageTag(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
@@ -1,20 +1,43 @@
 package common
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
-	"path/filepath"
-	"runtime"
+	"regexp"
 	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
 )
 
 // I18nManager manages internationalization
 type I18nManager struct {
+	mu       sync.RWMutex
 	messages map[string]any
 	locale   string
+
+	// fallbackLocales are consulted in order when a key is missing from the current locale
+	fallbackLocales   []string
+	fallbackMessages  map[string]map[string]any
+	missingKeyHandler MissingKeyHandler
+
+	// derived caches managers for other locales sharing the same fallback chain
+	derived map[string]*I18nManager
+
+	// fsys is the bundle source when set, otherwise bundles are probed on disk
+	fsys fs.FS
+	// bundles holds locales loaded explicitly from an io.Reader
+	bundles map[string]map[string]any
+	// dirs holds the resolved disk directory of each loaded locale, used by Watch
+	dirs map[string]string
 }
 
+// MissingKeyHandler is called when a key is missing from the current locale and its whole fallback chain
+type MissingKeyHandler func(locale string, keyPath string)
+
 // NewI18nManager creates a new I18nManager instance
 func NewI18nManager(locale string) (*I18nManager, error) {
 	manager := &I18nManager{
@@ -30,121 +53,330 @@ func NewI18nManager(locale string) (*I18nManager, error) {
 	return manager, nil
 }
 
-// loadMessages loads messages from JSON file
-func (i *I18nManager) loadMessages(locale string) error {
-	// Try multiple paths to find i18n files
-	possiblePaths := []string{
-		// Path in Docker container (absolute)
-		"/src/i18n",
-		// Path relative to working directory
-		"src/i18n",
+// NewI18nManagerFromFS creates a new I18nManager reading <locale>.json/.yaml/.yml bundles at the root of fsys.
+// Use fs.Sub to point at a sub directory of an embed.FS (e.g. fs.Sub(translations, "i18n"))
+func NewI18nManagerFromFS(fsys fs.FS, locale string) (*I18nManager, error) {
+	manager := &I18nManager{
+		messages: make(map[string]any),
+		locale:   locale,
+		fsys:     fsys,
 	}
 
-	// Try to get path from runtime caller (development mode)
-	_, filename, _, ok := runtime.Caller(1)
-	if ok {
-		i18nDir := filepath.Join(filepath.Dir(filename), "..", "..", "i18n")
-		// Resolve the path to absolute
-		if absPath, err := filepath.Abs(i18nDir); err == nil {
-			possiblePaths = append([]string{absPath}, possiblePaths...)
-		}
+	if err := manager.loadMessages(locale); err != nil {
+		return nil, fmt.Errorf("failed to load messages for locale %s: %w", locale, err)
 	}
 
-	// Check environment variable
-	if envPath := os.Getenv("I18N_DIR"); envPath != "" {
-		possiblePaths = append([]string{envPath}, possiblePaths...)
+	return manager, nil
+}
+
+// LoadLocaleFromReader loads the JSON bundle of locale from r.
+// It replaces the messages of the current or fallback locale and is used for later lookups of that locale
+func (i *I18nManager) LoadLocaleFromReader(locale string, r io.Reader) error {
+	messages, err := parseMessages(fmt.Sprintf("reader for locale %s", locale), r)
+	if err != nil {
+		return err
 	}
 
-	var filePath string
-	var lastErr error
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.bundles == nil {
+		i.bundles = make(map[string]map[string]any)
+	}
+	i.bundles[locale] = messages
+	RegisterLocale(locale)
+	if locale == i.locale {
+		i.messages = messages
+	}
+	if _, ok := i.fallbackMessages[locale]; ok {
+		i.fallbackMessages[locale] = messages
+	}
+	delete(i.derived, locale)
+	return nil
+}
 
-	for _, i18nDir := range possiblePaths {
-		if i18nDir == "" {
-			continue
-		}
-		filePath = filepath.Join(i18nDir, fmt.Sprintf("%s.json", locale))
-
-		// Check if file exists
-		if _, err := os.Stat(filePath); err == nil {
-			// File found, read it
-			data, err := os.ReadFile(filePath)
-			if err != nil {
-				lastErr = fmt.Errorf("failed to read i18n file %s: %w", filePath, err)
-				continue
-			}
+// read reads messages of locale from the bundles loaded explicitly, then from the manager source.
+// The resolved directory is returned for bundles read from disk
+func (i *I18nManager) read(locale string) (map[string]any, string, error) {
+	i.mu.RLock()
+	messages, ok := i.bundles[locale]
+	fsys := i.fsys
+	i.mu.RUnlock()
+	if ok {
+		return messages, "", nil
+	}
 
-			// Parse JSON
-			var messages map[string]any
-			if err := json.Unmarshal(data, &messages); err != nil {
-				lastErr = fmt.Errorf("failed to parse i18n file %s: %w", filePath, err)
-				continue
-			}
+	var files []bundleFile
+	var dir string
+	var err error
+	if fsys != nil {
+		files, err = readBundleFilesFromFS(fsys, locale)
+	} else {
+		files, dir, err = readBundleFiles(locale)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return mergeBundleFiles(files), dir, nil
+}
+
+// loadMessages loads messages of locale as the current messages
+func (i *I18nManager) loadMessages(locale string) error {
+	messages, dir, err := i.read(locale)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	i.messages = messages
+	i.setDir(locale, dir)
+	fsys := i.fsys
+	i.mu.Unlock()
+	RegisterLocale(locale)
+	registerBundleLocales(fsys, dir)
+	return nil
+}
 
-			i.messages = messages
-			return nil
+// registerBundleLocales registers the locales of every bundle of fsys, or of the disk directory dir, so
+// requests can resolve to locales whose bundle is not loaded yet
+func registerBundleLocales(fsys fs.FS, dir string) {
+	if fsys == nil {
+		if dir == "" {
+			return
 		}
-		lastErr = fmt.Errorf("file not found: %s", filePath)
+		fsys = os.DirFS(dir)
+	}
+	locales, err := bundleLocales(fsys)
+	if err != nil {
+		logrus.WithError(err).Warn("i18n: failed to list the bundle locales")
+		return
 	}
+	for _, locale := range locales {
+		RegisterLocale(locale)
+	}
+}
 
-	// If no file found, return error with all tried paths
-	return fmt.Errorf("failed to find i18n file for locale %s. Tried paths: %v. Last error: %v", locale, possiblePaths, lastErr)
+// setDir records the disk directory of a locale bundle, callers must hold the write lock
+func (i *I18nManager) setDir(locale, dir string) {
+	if dir == "" {
+		return
+	}
+	if i.dirs == nil {
+		i.dirs = make(map[string]string)
+	}
+	i.dirs[locale] = dir
 }
 
 // GetMessage retrieves a message by key path (e.g., "response.success.default")
+// Missing keys are looked up in the fallback chain before the key itself is returned
 func (i *I18nManager) GetMessage(keyPath string) string {
+	if message, ok := i.resolve(keyPath); ok {
+		return message
+	}
+	return keyPath
+}
+
+// resolve looks up a key in the current locale then in the fallback chain,
+// reporting it to the missing key handler when no locale defines it
+func (i *I18nManager) resolve(keyPath string) (string, bool) {
+	if message, ok := i.resolveQuiet(keyPath); ok {
+		return message, true
+	}
+
+	i.mu.RLock()
+	handler, locale := i.missingKeyHandler, i.locale
+	i.mu.RUnlock()
+	if handler != nil {
+		handler(locale, keyPath)
+	}
+	return "", false
+}
+
+// resolveQuiet looks up a key in the current locale then in the fallback chain
+func (i *I18nManager) resolveQuiet(keyPath string) (string, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if message, ok := lookupMessage(i.messages, keyPath); ok {
+		return message, true
+	}
+	for _, locale := range i.fallbackLocales {
+		if message, ok := lookupMessage(i.fallbackMessages[locale], keyPath); ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// lookupMessage navigates the nested message structure and returns the string at key path
+func lookupMessage(messages map[string]any, keyPath string) (string, bool) {
 	keys := strings.Split(keyPath, ".")
 	if len(keys) == 0 {
-		return keyPath
+		return "", false
 	}
 
 	// Navigate through the nested structure
-	current := i.messages
+	current := messages
 	for idx, key := range keys {
 		if idx == len(keys)-1 {
 			// Last key, return the value
 			if value, ok := current[key]; ok {
 				if str, ok := value.(string); ok {
-					return str
+					return str, true
 				}
 			}
-			return keyPath
+			return "", false
 		}
 
 		// Navigate deeper
-		if next, ok := current[key]; ok {
-			if nextMap, ok := next.(map[string]any); ok {
-				current = nextMap
-			} else {
-				return keyPath
-			}
-		} else {
-			return keyPath
+		next, ok := current[key]
+		if !ok {
+			return "", false
+		}
+		nextMap, ok := next.(map[string]any)
+		if !ok {
+			return "", false
 		}
+		current = nextMap
+	}
+
+	return "", false
+}
+
+// GetMessageWithParams retrieves a message by key path and replaces its {name} placeholders with params
+func (i *I18nManager) GetMessageWithParams(keyPath string, params map[string]any) string {
+	return interpolate(i.GetLocale(), keyPath, i.GetMessage(keyPath), params)
+}
+
+// GetPluralMessage retrieves the plural form of a message for count and interpolates {count} and params.
+// The form is chosen by the locale plural rule and looked up as a sub-key (e.g. "item.deleted.one"),
+// falling back to "zero" for 0 when defined, then "other", then the key itself when only a single form exists
+func (i *I18nManager) GetPluralMessage(keyPath string, count int, params map[string]any) string {
+	merged := make(map[string]any, len(params)+1)
+	for name, value := range params {
+		merged[name] = value
+	}
+	merged["count"] = count
+
+	candidates := []string{keyPath + "." + PluralRuleFor(i.GetLocale())(count), keyPath + ".other", keyPath}
+	if count == 0 {
+		candidates = append([]string{keyPath + ".zero"}, candidates...)
 	}
 
+	for _, candidate := range candidates[:len(candidates)-1] {
+		if message, ok := i.resolveQuiet(candidate); ok {
+			return interpolate(i.GetLocale(), keyPath, message, merged)
+		}
+	}
+	if message, ok := i.resolve(keyPath); ok {
+		return interpolate(i.GetLocale(), keyPath, message, merged)
+	}
 	return keyPath
 }
 
-// GetMessageWithFallback retrieves a message with fallback to default locale
+// GetMessageWithFallback retrieves a message through the fallback chain, returning fallback instead of the key
 func (i *I18nManager) GetMessageWithFallback(keyPath string, fallback string) string {
-	message := i.GetMessage(keyPath)
-	if message == keyPath {
-		return fallback
+	if message, ok := i.resolve(keyPath); ok {
+		return message
 	}
-	return message
+	return fallback
 }
 
 // SetLocale changes the current locale and reloads messages
 func (i *I18nManager) SetLocale(locale string) error {
+	i.mu.Lock()
 	i.locale = locale
+	i.mu.Unlock()
 	return i.loadMessages(locale)
 }
 
 // GetLocale returns the current locale
 func (i *I18nManager) GetLocale() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	return i.locale
 }
 
+// SetFallbackLocales configures the fallback chain (e.g. vn -> en -> key) and loads its messages
+func (i *I18nManager) SetFallbackLocales(locales ...string) error {
+	fallbackMessages := make(map[string]map[string]any, len(locales))
+	fallbackDirs := make(map[string]string, len(locales))
+	for _, locale := range locales {
+		messages, dir, err := i.read(locale)
+		if err != nil {
+			return fmt.Errorf("failed to load fallback messages for locale %s: %w", locale, err)
+		}
+		fallbackMessages[locale] = messages
+		fallbackDirs[locale] = dir
+		RegisterLocale(locale)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for locale, dir := range fallbackDirs {
+		i.setDir(locale, dir)
+	}
+	i.fallbackLocales = append([]string(nil), locales...)
+	i.fallbackMessages = fallbackMessages
+	i.derived = nil
+	return nil
+}
+
+// FallbackLocales returns the configured fallback chain
+func (i *I18nManager) FallbackLocales() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]string(nil), i.fallbackLocales...)
+}
+
+// SetMissingKeyHandler sets the callback invoked for keys missing from every locale of the chain,
+// e.g. to log untranslated keys in CI
+func (i *I18nManager) SetMissingKeyHandler(handler MissingKeyHandler) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.missingKeyHandler = handler
+	i.derived = nil
+}
+
+// ForLocale returns a manager for locale sharing this manager's fallback chain and missing key handler.
+// Managers for other locales are cached
+func (i *I18nManager) ForLocale(locale string) (*I18nManager, error) {
+	i.mu.RLock()
+	if locale == i.locale {
+		i.mu.RUnlock()
+		return i, nil
+	}
+	if manager, ok := i.derived[locale]; ok {
+		i.mu.RUnlock()
+		return manager, nil
+	}
+	fallbackLocales := i.fallbackLocales
+	fallbackMessages := maps.Clone(i.fallbackMessages)
+	handler := i.missingKeyHandler
+	fsys, bundles := i.fsys, maps.Clone(i.bundles)
+	i.mu.RUnlock()
+
+	manager := &I18nManager{
+		messages: make(map[string]any),
+		locale:   locale,
+		fsys:     fsys,
+		bundles:  bundles,
+	}
+	if err := manager.loadMessages(locale); err != nil {
+		return nil, fmt.Errorf("failed to load messages for locale %s: %w", locale, err)
+	}
+	manager.fallbackLocales = fallbackLocales
+	manager.fallbackMessages = fallbackMessages
+	manager.missingKeyHandler = handler
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.derived == nil {
+		i.derived = make(map[string]*I18nManager)
+	}
+	i.derived[locale] = manager
+	return manager, nil
+}
+
 // Global i18n manager instance
 var globalI18n *I18nManager
 
@@ -155,6 +387,33 @@ func InitGlobalI18n(locale string) error {
 	return err
 }
 
+// InitGlobalI18nWithFallback initializes the global i18n manager with a fallback chain
+// e.g. InitGlobalI18nWithFallback("vn", "en") resolves missing keys vn -> en -> key
+func InitGlobalI18nWithFallback(locale string, fallbackLocales ...string) error {
+	manager, err := NewI18nManager(locale)
+	if err != nil {
+		return err
+	}
+	if err := manager.SetFallbackLocales(fallbackLocales...); err != nil {
+		return err
+	}
+	globalI18n = manager
+	return nil
+}
+
+// InitGlobalI18nFromFS initializes the global i18n manager from bundles in fsys, with an optional fallback chain
+func InitGlobalI18nFromFS(fsys fs.FS, locale string, fallbackLocales ...string) error {
+	manager, err := NewI18nManagerFromFS(fsys, locale)
+	if err != nil {
+		return err
+	}
+	if err := manager.SetFallbackLocales(fallbackLocales...); err != nil {
+		return err
+	}
+	globalI18n = manager
+	return nil
+}
+
 // GetGlobalI18n returns the global i18n manager
 // It always returns a non-nil manager, creating an empty one if initialization fails
 func GetGlobalI18n() *I18nManager {
@@ -188,3 +447,58 @@ func TWithFallback(keyPath string, fallback string) string {
 	}
 	return manager.GetMessageWithFallback(keyPath, fallback)
 }
+
+// Tf is a shorthand for getting a message with named parameters from global i18n manager
+// e.g. Tf("validation.max_length", map[string]any{"max": 50}) with "Tối đa {max} ký tự"
+func Tf(keyPath string, params map[string]any) string {
+	manager := GetGlobalI18n()
+	if manager == nil {
+		return interpolate(DefaultLocale, keyPath, keyPath, params)
+	}
+	return manager.GetMessageWithParams(keyPath, params)
+}
+
+// placeholderPattern matches {name} placeholders in messages, optionally piped to a formatter: {name|formatter:arg}
+var placeholderPattern = regexp.MustCompile(`\{([a-zA-Z0-9_]+)(?:\|([a-zA-Z0-9_]+)(?::([^{}]*))?)?\}`)
+
+// missingParamsLogged remembers the key/placeholder pairs already reported as missing
+var missingParamsLogged sync.Map
+
+// interpolate replaces {name} placeholders in message with the values of params, formatting piped
+// placeholders such as {amount|currency:VND} for locale.
+// Placeholders without a matching param are left as is and logged once per key
+func interpolate(locale, keyPath, message string, params map[string]any) string {
+	if !strings.Contains(message, "{") {
+		return message
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(message, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		name, formatterName, arg := match[1], match[2], match[3]
+		value, ok := params[name]
+		if !ok {
+			if _, logged := missingParamsLogged.LoadOrStore(keyPath+"|"+name, struct{}{}); !logged {
+				logrus.Warnf("i18n: missing param %q for message %q", name, keyPath)
+			}
+			return placeholder
+		}
+		if formatterName != "" {
+			if formatter, ok := lookupMessageFormatter(formatterName); ok {
+				return formatter(locale, value, arg)
+			}
+			if _, logged := missingParamsLogged.LoadOrStore(keyPath+"|"+formatterName, struct{}{}); !logged {
+				logrus.Warnf("i18n: unknown formatter %q for message %q", formatterName, keyPath)
+			}
+		}
+		return fmt.Sprint(value)
+	})
+}
+
+// TPlural is a shorthand for getting a plural message from global i18n manager
+func TPlural(keyPath string, count int, params map[string]any) string {
+	manager := GetGlobalI18n()
+	if manager == nil {
+		return keyPath
+	}
+	return manager.GetPluralMessage(keyPath, count, params)
+}
@@ -0,0 +1,304 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bundleExtensions are the supported translation file formats, in loading order
+var bundleExtensions = []string{".json", ".yaml", ".yml"}
+
+// bundleFile is a parsed translation file of a locale
+type bundleFile struct {
+	name     string
+	messages map[string]any
+}
+
+// readBundleFiles reads the translation files of locale from disk.
+// The first directory containing at least one <locale>.json/.yaml/.yml file is used and returned
+func readBundleFiles(locale string) ([]bundleFile, string, error) {
+	// Try multiple paths to find i18n files
+	possiblePaths := []string{
+		// Path in Docker container (absolute)
+		"/src/i18n",
+		// Path relative to working directory
+		"src/i18n",
+	}
+
+	// Try to get path from runtime caller (development mode)
+	_, filename, _, ok := runtime.Caller(0)
+	if ok {
+		i18nDir := filepath.Join(filepath.Dir(filename), "..", "..", "i18n")
+		// Resolve the path to absolute
+		if absPath, err := filepath.Abs(i18nDir); err == nil {
+			possiblePaths = append([]string{absPath}, possiblePaths...)
+		}
+	}
+
+	// Check environment variable
+	if envPath := os.Getenv("I18N_DIR"); envPath != "" {
+		possiblePaths = append([]string{envPath}, possiblePaths...)
+	}
+
+	var lastErr error
+	for _, i18nDir := range possiblePaths {
+		if i18nDir == "" {
+			continue
+		}
+
+		files, err := readBundleFilesInDir(i18nDir, locale)
+		if err == nil {
+			return files, i18nDir, nil
+		}
+		lastErr = err
+	}
+
+	// If no file found, return error with all tried paths
+	return nil, "", fmt.Errorf("failed to find i18n file for locale %s. Tried paths: %v. Last error: %v", locale, possiblePaths, lastErr)
+}
+
+// readBundleFilesInDir reads every translation file of locale present in dir
+func readBundleFilesInDir(dir, locale string) ([]bundleFile, error) {
+	var files []bundleFile
+	for _, ext := range bundleExtensions {
+		filePath := filepath.Join(dir, locale+ext)
+
+		// Check if file exists
+		if _, err := os.Stat(filePath); err != nil {
+			continue
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read i18n file %s: %w", filePath, err)
+		}
+		messages, err := parseBundle("i18n file "+filePath, filePath, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{name: filePath, messages: messages})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("file not found: %s", filepath.Join(dir, locale+"{"+strings.Join(bundleExtensions, ",")+"}"))
+	}
+	return files, nil
+}
+
+// readBundleFilesFromFS reads the <locale>.json/.yaml/.yml files at the root of fsys
+func readBundleFilesFromFS(fsys fs.FS, locale string) ([]bundleFile, error) {
+	var files []bundleFile
+	var tried []string
+	for _, ext := range bundleExtensions {
+		fileName := locale + ext
+		tried = append(tried, fileName)
+
+		file, err := fsys.Open(fileName)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read fs.FS file %s: %w", fileName, err)
+		}
+		messages, err := parseBundle("fs.FS file "+fileName, fileName, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{name: fileName, messages: messages})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("failed to find i18n file for locale %s. Tried source: fs.FS files %v", locale, tried)
+	}
+	return files, nil
+}
+
+// bundleLocales returns the locales having a translation file at the root of fsys, sorted
+func bundleLocales(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var locales []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := path.Ext(entry.Name())
+		locale := strings.TrimSuffix(entry.Name(), ext)
+		if locale == "" || !slices.Contains(bundleExtensions, strings.ToLower(ext)) || slices.Contains(locales, locale) {
+			continue
+		}
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales, nil
+}
+
+// parseBundle parses a translation file, choosing the format from the extension of name
+func parseBundle(source, name string, r io.Reader) (map[string]any, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		var messages map[string]any
+		if err := yaml.NewDecoder(r).Decode(&messages); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+		}
+		if messages == nil {
+			messages = make(map[string]any)
+		}
+		return messages, nil
+	default:
+		return parseMessages(source, r)
+	}
+}
+
+// parseMessages parses a JSON bundle, source describes where it comes from for error messages
+func parseMessages(source string, r io.Reader) (map[string]any, error) {
+	var messages map[string]any
+	if err := json.NewDecoder(r).Decode(&messages); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	if messages == nil {
+		messages = make(map[string]any)
+	}
+	return messages, nil
+}
+
+// mergeBundleFiles deep merges the translation files of a locale, later files override earlier ones
+func mergeBundleFiles(files []bundleFile) map[string]any {
+	if len(files) == 1 {
+		return files[0].messages
+	}
+
+	merged := make(map[string]any)
+	for _, file := range files {
+		mergeMessages(merged, file.messages)
+	}
+	return merged
+}
+
+// mergeMessages deep merges src into dst
+func mergeMessages(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeMessages(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			copied := make(map[string]any, len(srcMap))
+			mergeMessages(copied, srcMap)
+			dst[key] = copied
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// ValidateBundle validates a locale bundle of the global i18n manager, see I18nManager.ValidateBundle
+func ValidateBundle(locale string) ([]string, error) {
+	return GetGlobalI18n().ValidateBundle(locale)
+}
+
+// ValidateBundle checks the translation files of locale and returns the problems found:
+// non-string leaves, keys defined in several files and keys of the default locale missing from locale.
+// The error is only returned when the bundle cannot be read, so it is suitable for tests and CI
+func (i *I18nManager) ValidateBundle(locale string) ([]string, error) {
+	files, err := i.bundleFiles(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	definedIn := make(map[string][]string)
+	for _, file := range files {
+		leaves := make(map[string]any)
+		collectLeaves("", file.messages, leaves)
+		for _, key := range sortedKeys(leaves) {
+			if _, ok := leaves[key].(string); !ok {
+				problems = append(problems, fmt.Sprintf("%s: key %q must be a string or an object, got %T", file.name, key, leaves[key]))
+			}
+			definedIn[key] = append(definedIn[key], file.name)
+		}
+	}
+
+	for _, key := range sortedKeys(definedIn) {
+		if len(definedIn[key]) > 1 {
+			problems = append(problems, fmt.Sprintf("key %q is defined in several files: %s", key, strings.Join(definedIn[key], ", ")))
+		}
+	}
+
+	if locale != DefaultLocale {
+		defaultFiles, err := i.bundleFiles(DefaultLocale)
+		if err != nil {
+			return nil, err
+		}
+		defaultLeaves := make(map[string]any)
+		collectLeaves("", mergeBundleFiles(defaultFiles), defaultLeaves)
+		for _, key := range sortedKeys(defaultLeaves) {
+			if _, ok := definedIn[key]; !ok {
+				problems = append(problems, fmt.Sprintf("key %q of default locale %s is missing in locale %s", key, DefaultLocale, locale))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// bundleFiles returns the translation files of locale from the manager source
+func (i *I18nManager) bundleFiles(locale string) ([]bundleFile, error) {
+	i.mu.RLock()
+	messages, ok := i.bundles[locale]
+	fsys := i.fsys
+	i.mu.RUnlock()
+	if ok {
+		return []bundleFile{{name: "reader for locale " + locale, messages: messages}}, nil
+	}
+
+	if fsys != nil {
+		return readBundleFilesFromFS(fsys, locale)
+	}
+	files, _, err := readBundleFiles(locale)
+	return files, err
+}
+
+// collectLeaves flattens the message tree into dotted key paths
+func collectLeaves(prefix string, node map[string]any, leaves map[string]any) {
+	for key, value := range node {
+		keyPath := key
+		if prefix != "" {
+			keyPath = prefix + "." + key
+		}
+		if child, ok := value.(map[string]any); ok {
+			collectLeaves(keyPath, child, leaves)
+			continue
+		}
+		leaves[keyPath] = value
+	}
+}
+
+// sortedKeys returns the keys of m in a stable order for reporting
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
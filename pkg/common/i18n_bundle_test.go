@@ -0,0 +1,191 @@
+package common
+
+import (
+	"embed"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/i18n
+var testBundles embed.FS
+
+// testBundleFS returns the translation fixtures rooted at their directory
+func testBundleFS(t *testing.T) fs.FS {
+	t.Helper()
+	fsys, err := fs.Sub(testBundles, "testdata/i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+func TestNewI18nManagerFromFS(t *testing.T) {
+	manager, err := NewI18nManagerFromFS(testBundleFS(t), "vn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetFallbackLocales("en"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := manager.GetMessage(MsgSuccessDefault); got != "Thành công" {
+		t.Errorf("vn message = %q", got)
+	}
+	if got := manager.GetMessage(MsgErrorConflict); got != "Conflict" {
+		t.Errorf("key only in en = %q, want the embedded en message", got)
+	}
+
+	en, err := manager.ForLocale("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := en.GetMessage(MsgSuccessDefault); got != "Success" {
+		t.Errorf("derived en message = %q, want it read from the same fs.FS", got)
+	}
+}
+
+func TestNewI18nManagerFromFSMissingLocale(t *testing.T) {
+	_, err := NewI18nManagerFromFS(testBundleFS(t), "de")
+	if err == nil {
+		t.Fatal("missing locale loaded")
+	}
+	if !strings.Contains(err.Error(), "fs.FS") || !strings.Contains(err.Error(), "de.json") {
+		t.Errorf("error = %q, want the attempted source listed", err)
+	}
+}
+
+func TestLoadLocaleFromReader(t *testing.T) {
+	manager, err := NewI18nManagerFromFS(testBundleFS(t), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.LoadLocaleFromReader("en", strings.NewReader(`{"response":{"success":{"default":"Done"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := manager.GetMessage(MsgSuccessDefault); got != "Done" {
+		t.Errorf("message = %q, want the reader bundle to replace the current locale", got)
+	}
+
+	if err := manager.LoadLocaleFromReader("ko", strings.NewReader(`{"hello":"안녕하세요"}`)); err != nil {
+		t.Fatal(err)
+	}
+	ko, err := manager.ForLocale("ko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ko.GetMessage("hello"); got != "안녕하세요" {
+		t.Errorf("ko message = %q, want the reader bundle used for later lookups", got)
+	}
+
+	if err := manager.LoadLocaleFromReader("en", strings.NewReader(`{broken`)); err == nil {
+		t.Error("invalid JSON loaded")
+	}
+	if got := manager.GetMessage(MsgSuccessDefault); got != "Done" {
+		t.Errorf("message = %q after a failed load, want the previous bundle kept", got)
+	}
+}
+
+func TestInitGlobalI18nFromFS(t *testing.T) {
+	useGlobalI18n(t, nil)
+	if err := InitGlobalI18nFromFS(testBundleFS(t), "vn", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if got := T(MsgErrorConflict); got != "Conflict" {
+		t.Errorf("T() = %q, want the en fallback", got)
+	}
+}
+
+func TestYAMLBundles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vn.yaml": {Data: []byte("response:\n  success:\n    default: Thành công\n  error:\n    conflict: Xung đột\n")},
+		"vn.json": {Data: []byte(`{"response":{"success":{"default":"Thành công (json)"}}}`)},
+		"en.yml":  {Data: []byte("response:\n  success:\n    default: Success\n")},
+	}
+
+	vn, err := NewI18nManagerFromFS(fsys, "vn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vn.GetMessage(MsgErrorConflict); got != "Xung đột" {
+		t.Errorf("yaml key = %q", got)
+	}
+	if got := vn.GetMessage(MsgSuccessDefault); got != "Thành công" {
+		t.Errorf("key in json and yaml = %q, want the yaml file loaded later to win", got)
+	}
+
+	en, err := NewI18nManagerFromFS(fsys, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := en.GetMessage(MsgSuccessDefault); got != "Success" {
+		t.Errorf(".yml key = %q", got)
+	}
+}
+
+func TestValidateBundle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vn.json": {Data: []byte(`{"greeting":"Xin chào","farewell":"Tạm biệt","nested":{"a":"A","b":"B"}}`)},
+		"en.json": {Data: []byte(`{"greeting":"Hello","nested":{"a":["not","a","string"]}}`)},
+		"en.yaml": {Data: []byte("greeting: Hi\ncount: 3\n")},
+	}
+	manager, err := NewI18nManagerFromFS(fsys, DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := manager.ValidateBundle(DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("default locale problems = %v, want none", problems)
+	}
+
+	problems, err = manager.ValidateBundle("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantProblems := []string{
+		`en.json: key "nested.a" must be a string`,
+		`en.yaml: key "count" must be a string`,
+		`key "greeting" is defined in several files`,
+		`key "farewell" of default locale vn is missing in locale en`,
+		`key "nested.b" of default locale vn is missing in locale en`,
+	}
+	if len(problems) != len(wantProblems) {
+		t.Errorf("problems = %q, want %d", problems, len(wantProblems))
+	}
+	for _, want := range wantProblems {
+		found := false
+		for _, problem := range problems {
+			if strings.Contains(problem, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("problems = %q, want one containing %q", problems, want)
+		}
+	}
+
+	if _, err := manager.ValidateBundle("de"); err == nil {
+		t.Error("ValidateBundle of an unreadable locale returned no error")
+	}
+}
+
+func TestCorruptedBundle(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"json": {"vn.json": {Data: []byte(`{"greeting": "Xin chào",}`)}},
+		"yaml": {"vn.yaml": {Data: []byte("greeting: [unclosed\n")}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewI18nManagerFromFS(fsys, "vn"); err == nil {
+				t.Error("corrupted bundle loaded")
+			}
+		})
+	}
+}
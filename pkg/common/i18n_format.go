@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/thanhthanh221/msa-core/pkg/common/format"
+)
+
+// MessageFormatter formats a placeholder value for locale, arg is the text after ':' in {name|formatter:arg}
+type MessageFormatter func(locale string, value any, arg string) string
+
+var (
+	messageFormattersMu sync.RWMutex
+	// messageFormatters are the pipes usable in messages, e.g. {amount|currency:VND}, {n|number}, {at|date:long}
+	messageFormatters = map[string]MessageFormatter{
+		"number": func(locale string, value any, _ string) string {
+			if f, ok := toFloat(value); ok {
+				return format.FormatNumber(locale, f)
+			}
+			return fmt.Sprint(value)
+		},
+		"currency": func(locale string, value any, arg string) string {
+			if f, ok := toFloat(value); ok {
+				return format.FormatCurrency(locale, f, arg)
+			}
+			return fmt.Sprint(value)
+		},
+		"date": func(locale string, value any, arg string) string {
+			style := format.DateStyle(arg)
+			switch t := value.(type) {
+			case time.Time:
+				return format.FormatDate(locale, t, style)
+			case *time.Time:
+				if t != nil {
+					return format.FormatDate(locale, *t, style)
+				}
+			}
+			return fmt.Sprint(value)
+		},
+	}
+)
+
+// RegisterMessageFormatter registers a pipe usable in interpolated messages as {name|formatter:arg}
+func RegisterMessageFormatter(name string, formatter MessageFormatter) {
+	messageFormattersMu.Lock()
+	defer messageFormattersMu.Unlock()
+	messageFormatters[name] = formatter
+}
+
+// lookupMessageFormatter returns the formatter registered under name
+func lookupMessageFormatter(name string) (MessageFormatter, bool) {
+	messageFormattersMu.RLock()
+	defer messageFormattersMu.RUnlock()
+	formatter, ok := messageFormatters[name]
+	return formatter, ok
+}
+
+// toFloat converts numeric values and numeric strings to float64
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -3,7 +3,6 @@ package common
 import (
 	"context"
 	"net/http"
-	"strings"
 )
 
 // I18nContextKey is the key used to store locale in context
@@ -11,12 +10,15 @@ type contextKey string
 
 const I18nContextKey contextKey = "locale"
 
+// DefaultLocale is the locale used when the request does not specify a supported one
+const DefaultLocale = "vn"
+
 // GetLocaleFromContext extracts locale from context
 func GetLocaleFromContext(ctx context.Context) string {
 	if locale, ok := ctx.Value(I18nContextKey).(string); ok {
 		return locale
 	}
-	return "vn" // default locale (Vietnamese)
+	return DefaultLocale // default locale (Vietnamese)
 }
 
 // SetLocaleInContext sets locale in context
@@ -27,48 +29,12 @@ func SetLocaleInContext(ctx context.Context, locale string) context.Context {
 // GetLocaleFromHeader extracts locale from Accept-Language header
 // Supports quality values: "en-US,vi;q=0.9,en;q=0.8"
 func GetLocaleFromHeader(header http.Header) string {
-	acceptLang := header.Get("Accept-Language")
-	if acceptLang == "" {
-		return "vn" // default locale (Vietnamese)
-	}
-
-	// Parse Accept-Language header with quality values
-	// Format: "en-US,vi;q=0.9,en;q=0.8,fr-FR;q=0.7,fr;q=0.6"
-	// Split by comma to get all language preferences
-	langs := strings.Split(acceptLang, ",")
-
-	// Supported locales in the system
-	supportedLocales := map[string]string{
-		"en": "en",
-		"vn": "vn",
-		"vi": "vn",
-	}
-
-	// Try to find first supported locale
-	for _, lang := range langs {
-		// Remove whitespace
-		lang = strings.TrimSpace(lang)
-
-		// Split by semicolon to get language and quality value
-		parts := strings.Split(lang, ";")
-		langCode := strings.TrimSpace(parts[0])
-
-		// Remove region code if present (e.g., "vi-VN" -> "vi", "en-US" -> "en")
-		if idx := strings.Index(langCode, "-"); idx > 0 {
-			langCode = langCode[:idx]
-		}
-
-		// Normalize to lowercase
-		langCode = strings.ToLower(langCode)
-
-		// Check if this locale is supported
-		if mappedLocale, ok := supportedLocales[langCode]; ok {
-			return mappedLocale
-		}
+	if locale, ok := localeFromHeader(header); ok {
+		return locale
 	}
 
 	// If no supported locale found, return default
-	return "vn" // default locale (Vietnamese)
+	return DefaultLocale // default locale (Vietnamese)
 }
 
 // TWithContext gets a message using locale from context
@@ -76,8 +42,8 @@ func TWithContext(ctx context.Context, keyPath string) string {
 	locale := GetLocaleFromContext(ctx)
 	globalI18n := GetGlobalI18n()
 	if globalI18n != nil && locale != globalI18n.GetLocale() {
-		// Create a new i18n manager for this locale
-		if manager, err := NewI18nManager(locale); err == nil {
+		// Use the i18n manager of this locale, sharing the fallback chain of the global one
+		if manager, err := globalI18n.ForLocale(locale); err == nil {
 			return manager.GetMessage(keyPath)
 		}
 	}
@@ -88,13 +54,39 @@ func TWithContext(ctx context.Context, keyPath string) string {
 	return keyPath
 }
 
+// TfWithContext gets a message with named parameters using locale from context
+func TfWithContext(ctx context.Context, keyPath string, params map[string]any) string {
+	locale := GetLocaleFromContext(ctx)
+	globalI18n := GetGlobalI18n()
+	if globalI18n != nil && locale != globalI18n.GetLocale() {
+		// Use the i18n manager of this locale, sharing the fallback chain of the global one
+		if manager, err := globalI18n.ForLocale(locale); err == nil {
+			return manager.GetMessageWithParams(keyPath, params)
+		}
+	}
+	return Tf(keyPath, params)
+}
+
+// TPluralWithContext gets a plural message using locale from context
+func TPluralWithContext(ctx context.Context, keyPath string, count int, params map[string]any) string {
+	locale := GetLocaleFromContext(ctx)
+	globalI18n := GetGlobalI18n()
+	if globalI18n != nil && locale != globalI18n.GetLocale() {
+		// Use the i18n manager of this locale, sharing the fallback chain of the global one
+		if manager, err := globalI18n.ForLocale(locale); err == nil {
+			return manager.GetPluralMessage(keyPath, count, params)
+		}
+	}
+	return TPlural(keyPath, count, params)
+}
+
 // TWithContextAndFallback gets a message using locale from context with fallback
 func TWithContextAndFallback(ctx context.Context, keyPath string, fallback string) string {
 	locale := GetLocaleFromContext(ctx)
 	globalI18n := GetGlobalI18n()
 	if globalI18n != nil && locale != globalI18n.GetLocale() {
-		// Create a new i18n manager for this locale
-		if manager, err := NewI18nManager(locale); err == nil {
+		// Use the i18n manager of this locale, sharing the fallback chain of the global one
+		if manager, err := globalI18n.ForLocale(locale); err == nil {
 			return manager.GetMessageWithFallback(keyPath, fallback)
 		}
 	}
@@ -126,4 +118,20 @@ const (
 	MsgErrorRequestTimeout     = "response.error.request_timeout"
 	MsgErrorTooManyRequests    = "response.error.too_many_requests"
 	MsgErrorServiceUnavailable = "response.error.service_unavailable"
+	MsgErrorGatewayTimeout     = "response.error.gateway_timeout"
+	MsgErrorTokenRevoked       = "response.error.token_revoked"
+	MsgErrorInsufficientScope  = "response.error.insufficient_scope"
+	MsgErrorInsufficientRole   = "response.error.insufficient_role"
+	MsgErrorInvalidAPIKey      = "response.error.invalid_api_key"
+	MsgErrorInvalidSignature   = "response.error.invalid_signature"
+	MsgErrorSignatureExpired   = "response.error.signature_expired"
+	MsgErrorMaintenance        = "response.error.maintenance"
+	MsgErrorIPForbidden        = "response.error.ip_forbidden"
+	MsgErrorLoginLocked        = "response.error.login_locked"
+	MsgErrorInvalidCredentials = "response.error.invalid_credentials"
+	MsgErrorInvalidRefresh     = "response.error.invalid_refresh_token"
+	// MsgErrorIdempotencyInProgress is answered to requests retried while the first attempt runs
+	MsgErrorIdempotencyInProgress = "response.error.idempotency_in_progress"
+	// MsgErrorIdempotencyKeyReused is answered to requests reusing an Idempotency-Key with another body
+	MsgErrorIdempotencyKeyReused = "response.error.idempotency_key_reused"
 )
@@ -0,0 +1,55 @@
+package common
+
+import "sync"
+
+// Plural forms returned by plural rules
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// PluralRule returns the plural form of count for a locale (one of the Plural* constants)
+type PluralRule func(count int) string
+
+var (
+	pluralRules = map[string]PluralRule{
+		"en": EnglishPluralRule,
+		"vn": VietnamesePluralRule,
+		"vi": VietnamesePluralRule,
+	}
+	pluralRulesMu sync.RWMutex
+)
+
+// EnglishPluralRule distinguishes singular ("one") from plural ("other")
+func EnglishPluralRule(count int) string {
+	if count == 1 || count == -1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+// VietnamesePluralRule always returns "other", Vietnamese nouns do not inflect for number
+func VietnamesePluralRule(count int) string {
+	return PluralOther
+}
+
+// RegisterPluralRule registers or replaces the plural rule of a locale
+func RegisterPluralRule(locale string, rule PluralRule) {
+	pluralRulesMu.Lock()
+	defer pluralRulesMu.Unlock()
+	pluralRules[locale] = rule
+}
+
+// PluralRuleFor returns the plural rule of a locale, defaulting to the English rule
+func PluralRuleFor(locale string) PluralRule {
+	pluralRulesMu.RLock()
+	defer pluralRulesMu.RUnlock()
+	if rule, ok := pluralRules[locale]; ok && rule != nil {
+		return rule
+	}
+	return EnglishPluralRule
+}
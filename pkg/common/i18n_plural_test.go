@@ -0,0 +1,96 @@
+package common
+
+import "testing"
+
+func TestGetPluralMessage(t *testing.T) {
+	en := newTestI18n("en", map[string]any{
+		"item": map[string]any{
+			"deleted": map[string]any{
+				"one":   "{count} item deleted",
+				"other": "{count} items deleted",
+			},
+			"found": map[string]any{
+				"zero":  "No {name} found",
+				"one":   "One {name} found",
+				"other": "{count} {name}s found",
+			},
+			"single": "{count} record",
+		},
+	})
+	vn := newTestI18n("vn", map[string]any{
+		"item": map[string]any{
+			"deleted": map[string]any{
+				"one":   "không dùng",
+				"other": "Đã xoá {count} mục",
+			},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		manager *I18nManager
+		key     string
+		count   int
+		params  map[string]any
+		want    string
+	}{
+		{"en zero", en, "item.deleted", 0, nil, "0 items deleted"},
+		{"en one", en, "item.deleted", 1, nil, "1 item deleted"},
+		{"en many", en, "item.deleted", 5, nil, "5 items deleted"},
+		{"en zero form", en, "item.found", 0, map[string]any{"name": "user"}, "No user found"},
+		{"en one with params", en, "item.found", 1, map[string]any{"name": "user"}, "One user found"},
+		{"en many with params", en, "item.found", 3, map[string]any{"name": "user"}, "3 users found"},
+		{"single form", en, "item.single", 7, nil, "7 record"},
+		{"missing key", en, "item.unknown", 2, nil, "item.unknown"},
+		{"vn zero", vn, "item.deleted", 0, nil, "Đã xoá 0 mục"},
+		{"vn one", vn, "item.deleted", 1, nil, "Đã xoá 1 mục"},
+		{"vn many", vn, "item.deleted", 5, nil, "Đã xoá 5 mục"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.manager.GetPluralMessage(tt.key, tt.count, tt.params); got != tt.want {
+				t.Errorf("GetPluralMessage(%q, %d) = %q, want %q", tt.key, tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterPluralRule(t *testing.T) {
+	RegisterPluralRule("test-dual", func(count int) string {
+		if count == 2 {
+			return PluralTwo
+		}
+		return EnglishPluralRule(count)
+	})
+	t.Cleanup(func() {
+		pluralRulesMu.Lock()
+		delete(pluralRules, "test-dual")
+		pluralRulesMu.Unlock()
+	})
+
+	manager := newTestI18n("test-dual", map[string]any{
+		"pair": map[string]any{"two": "a pair", "other": "{count} things"},
+	})
+	if got := manager.GetPluralMessage("pair", 2, nil); got != "a pair" {
+		t.Errorf("count 2 = %q, want the registered form", got)
+	}
+	if got := manager.GetPluralMessage("pair", 3, nil); got != "3 things" {
+		t.Errorf("count 3 = %q", got)
+	}
+	if got := PluralRuleFor("unknown-locale")(1); got != PluralOne {
+		t.Errorf("unknown locale rule = %q, want the English rule", got)
+	}
+}
+
+func TestTPlural(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("en", map[string]any{
+		"files": map[string]any{"one": "{count} file", "other": "{count} files"},
+	}))
+
+	if got := TPlural("files", 1, nil); got != "1 file" {
+		t.Errorf("TPlural(1) = %q", got)
+	}
+	if got := TPlural("files", 2, nil); got != "2 files" {
+		t.Errorf("TPlural(2) = %q", got)
+	}
+}
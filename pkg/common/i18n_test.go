@@ -0,0 +1,228 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestI18n returns a manager of locale holding messages, without reading any bundle
+func newTestI18n(locale string, messages map[string]any) *I18nManager {
+	return &I18nManager{messages: messages, locale: locale}
+}
+
+// useGlobalI18n installs manager as the global i18n manager for the duration of the test
+func useGlobalI18n(t *testing.T, manager *I18nManager) {
+	t.Helper()
+	previous := globalI18n
+	globalI18n = manager
+	t.Cleanup(func() { globalI18n = previous })
+}
+
+func TestGetMessageWithParams(t *testing.T) {
+	manager := newTestI18n("vn", map[string]any{
+		"validation": map[string]any{
+			"max_length": "{field} tối đa {max} ký tự",
+			"range":      "{field} từ {min} đến {max}",
+			"static":     "Không có tham số",
+		},
+	})
+
+	tests := []struct {
+		name   string
+		key    string
+		params map[string]any
+		want   string
+	}{
+		{"substitution", "validation.max_length", map[string]any{"field": "name", "max": 50}, "name tối đa 50 ký tự"},
+		{"missing param kept", "validation.range", map[string]any{"field": "age", "min": 18}, "age từ 18 đến {max}"},
+		{"no params", "validation.range", nil, "{field} từ {min} đến {max}"},
+		{"non-string values", "validation.range", map[string]any{"field": 1.5, "min": int64(-1), "max": true}, "1.5 từ -1 đến true"},
+		{"extra params ignored", "validation.static", map[string]any{"unused": 1}, "Không có tham số"},
+		{"missing key", "validation.unknown", map[string]any{"max": 1}, "validation.unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := manager.GetMessageWithParams(tt.key, tt.params); got != tt.want {
+				t.Errorf("GetMessageWithParams(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTfWithContext(t *testing.T) {
+	manager := newTestI18n("vn", map[string]any{"limit": "Tối đa {max}"})
+	manager.derived = map[string]*I18nManager{
+		"en": newTestI18n("en", map[string]any{"limit": "At most {max}"}),
+	}
+	useGlobalI18n(t, manager)
+
+	params := map[string]any{"max": 3}
+	if got := Tf("limit", params); got != "Tối đa 3" {
+		t.Errorf("Tf() = %q", got)
+	}
+	if got := TfWithContext(SetLocaleInContext(context.Background(), "en"), "limit", params); got != "At most 3" {
+		t.Errorf("TfWithContext(en) = %q", got)
+	}
+	if got := TfWithContext(context.Background(), "limit", params); got != "Tối đa 3" {
+		t.Errorf("TfWithContext(default) = %q", got)
+	}
+}
+
+func TestValidatorMessagesIncludeParams(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"max_length": "{field} tối đa {max} ký tự"},
+	}))
+
+	v := &BaseValidator{}
+	detail := v.MaxLength("name", "too long", 5, MsgValidationMaxLength)
+	if detail == nil || detail.Message != "name tối đa 5 ký tự" {
+		t.Errorf("MaxLength detail = %+v, want the limit in the message", detail)
+	}
+
+	errResp := CreateErrorResponseI18nWithParams(VALIDATION_ERROR, "validation.max_length", map[string]any{"field": "name", "max": 5})
+	if errResp.Message != "name tối đa 5 ký tự" {
+		t.Errorf("message = %q", errResp.Message)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	manager := &I18nManager{
+		locale:   "vn",
+		messages: map[string]any{"greeting": "Xin chào"},
+		bundles: map[string]map[string]any{
+			"en": {"greeting": "Hello", "response": map[string]any{"error": map[string]any{"conflict": "Conflict"}}},
+			"fr": {"only_fr": "Seulement"},
+		},
+	}
+	if err := manager.SetFallbackLocales("en", "fr"); err != nil {
+		t.Fatal(err)
+	}
+
+	var missing []string
+	manager.SetMissingKeyHandler(func(locale, keyPath string) {
+		missing = append(missing, locale+":"+keyPath)
+	})
+
+	if got := manager.GetMessage("greeting"); got != "Xin chào" {
+		t.Errorf("current locale key = %q", got)
+	}
+	if got := manager.GetMessage("response.error.conflict"); got != "Conflict" {
+		t.Errorf("key only in en = %q, want the en message", got)
+	}
+	if got := manager.GetMessage("only_fr"); got != "Seulement" {
+		t.Errorf("key only in the second fallback = %q", got)
+	}
+	if len(missing) != 0 {
+		t.Errorf("missing key handler called for keys found in the chain: %v", missing)
+	}
+
+	if got := manager.GetMessage("nowhere"); got != "nowhere" {
+		t.Errorf("missing key = %q, want the key", got)
+	}
+	if got := manager.GetMessageWithFallback("nowhere.either", "default"); got != "default" {
+		t.Errorf("GetMessageWithFallback = %q, want the fallback", got)
+	}
+	if got := manager.GetMessageWithFallback("response.error.conflict", "default"); got != "Conflict" {
+		t.Errorf("GetMessageWithFallback = %q, want the chain to win over the fallback", got)
+	}
+	want := []string{"vn:nowhere", "vn:nowhere.either"}
+	if len(missing) != len(want) || missing[0] != want[0] || missing[1] != want[1] {
+		t.Errorf("missing keys = %v, want %v", missing, want)
+	}
+
+	if chain := manager.FallbackLocales(); len(chain) != 2 || chain[0] != "en" {
+		t.Errorf("FallbackLocales() = %v", chain)
+	}
+}
+
+func TestForLocaleSharesChain(t *testing.T) {
+	manager := &I18nManager{
+		locale:   "vn",
+		messages: map[string]any{},
+		bundles: map[string]map[string]any{
+			"en": {"shared": "From en"},
+			"ja": {"local": "ローカル"},
+		},
+	}
+	if err := manager.SetFallbackLocales("en"); err != nil {
+		t.Fatal(err)
+	}
+
+	ja, err := manager.ForLocale("ja")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ja.GetMessage("local"); got != "ローカル" {
+		t.Errorf("ja key = %q", got)
+	}
+	if got := ja.GetMessage("shared"); got != "From en" {
+		t.Errorf("ja missing key = %q, want the shared en fallback", got)
+	}
+	if again, _ := manager.ForLocale("ja"); again != ja {
+		t.Error("derived manager not cached")
+	}
+	if _, err := manager.ForLocale("xx"); err == nil {
+		t.Error("ForLocale of a locale without bundle succeeded")
+	}
+}
+
+func TestSetFallbackLocalesUnknown(t *testing.T) {
+	manager := &I18nManager{locale: "vn", messages: map[string]any{}, bundles: map[string]map[string]any{}}
+	if err := manager.SetFallbackLocales("zz-missing"); err == nil {
+		t.Error("fallback to a locale without bundle succeeded")
+	}
+}
+
+func TestInterpolateFormatters(t *testing.T) {
+	messages := map[string]any{
+		"order": map[string]any{
+			"total":   "Tổng {amount|currency:VND} cho {items|number} sản phẩm",
+			"placed":  "Đặt ngày {at|date:long}",
+			"unknown": "Giá trị {value|missing}",
+		},
+	}
+	vn := newTestI18n("vn", messages)
+	en := newTestI18n("en", messages)
+	at := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		manager *I18nManager
+		key     string
+		params  map[string]any
+		want    string
+	}{
+		{"vn currency and number", vn, "order.total", map[string]any{"amount": 1234567, "items": 1200}, "Tổng 1.234.567 ₫ cho 1.200 sản phẩm"},
+		{"en currency and number", en, "order.total", map[string]any{"amount": 1234567, "items": 1200}, "Tổng ₫1,234,567 cho 1,200 sản phẩm"},
+		{"numeric string", vn, "order.total", map[string]any{"amount": "1500", "items": "x"}, "Tổng 1.500 ₫ cho x sản phẩm"},
+		{"date", vn, "order.placed", map[string]any{"at": at}, "Đặt ngày ngày 5 tháng 3 năm 2024"},
+		{"date pointer", en, "order.placed", map[string]any{"at": &at}, "Đặt ngày March 5, 2024"},
+		{"unknown formatter", vn, "order.unknown", map[string]any{"value": 42}, "Giá trị 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.manager.GetMessageWithParams(tt.key, tt.params); got != tt.want {
+				t.Errorf("GetMessageWithParams(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterMessageFormatter(t *testing.T) {
+	RegisterMessageFormatter("test_upper", func(locale string, value any, arg string) string {
+		return strings.ToUpper(fmt.Sprint(value)) + arg
+	})
+	t.Cleanup(func() {
+		messageFormattersMu.Lock()
+		delete(messageFormatters, "test_upper")
+		messageFormattersMu.Unlock()
+	})
+
+	manager := newTestI18n("en", map[string]any{"shout": "{word|test_upper:!}"})
+	if got := manager.GetMessageWithParams("shout", map[string]any{"word": "hi"}); got != "HI!" {
+		t.Errorf("custom formatter = %q", got)
+	}
+}
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultI18nWatchInterval is the polling interval used by I18nManager.Watch
+const DefaultI18nWatchInterval = 2 * time.Second
+
+// Watch reloads translation files when they change on disk, polling their modification time.
+// It returns once watching has started and stops when ctx is done
+func (i *I18nManager) Watch(ctx context.Context) error {
+	return i.WatchWithInterval(ctx, DefaultI18nWatchInterval)
+}
+
+// WatchWithInterval is like Watch with a custom polling interval. Every bundle of the directories of the
+// loaded locales is watched, including the locales loaded later by ForLocale.
+// A file that fails to parse is logged and the previous good version is kept
+func (i *I18nManager) WatchWithInterval(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultI18nWatchInterval
+	}
+
+	dirs := i.watchedDirs()
+	if len(dirs) == 0 {
+		return fmt.Errorf("no translation file on disk to watch")
+	}
+	// modTimes holds the fingerprint of each locale by directory
+	modTimes := make(map[string]map[string]string, len(dirs))
+	for _, dir := range dirs {
+		modTimes[dir] = dirModTimes(dir)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, dir := range i.watchedDirs() {
+					current := dirModTimes(dir)
+					previous, seen := modTimes[dir]
+					modTimes[dir] = current
+					if !seen {
+						// Directory of a locale loaded since the last tick, its bundles were just read
+						continue
+					}
+					for locale, fingerprint := range current {
+						if fingerprint != previous[locale] {
+							i.reloadLocale(locale, dir)
+						}
+					}
+					for locale := range previous {
+						if _, ok := current[locale]; !ok {
+							i.reloadLocale(locale, dir)
+						}
+					}
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// watchedDirs returns the disk directories of the locales loaded by i and by its ForLocale managers
+func (i *I18nManager) watchedDirs() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var dirs []string
+	add := func(locales map[string]string) {
+		for _, dir := range locales {
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	add(i.dirs)
+	for _, manager := range i.derived {
+		manager.mu.RLock()
+		add(manager.dirs)
+		manager.mu.RUnlock()
+	}
+	return dirs
+}
+
+// dirModTimes returns the bundleModTimes fingerprint of every locale with a bundle in dir
+func dirModTimes(dir string) map[string]string {
+	locales, err := bundleLocales(os.DirFS(dir))
+	if err != nil {
+		return nil
+	}
+	fingerprints := make(map[string]string, len(locales))
+	for _, locale := range locales {
+		fingerprints[locale] = bundleModTimes(dir, locale)
+	}
+	return fingerprints
+}
+
+// bundleModTimes returns a fingerprint of the modification times of every translation file of locale in dir,
+// so adding or removing a .json/.yaml/.yml file is detected as well
+func bundleModTimes(dir, locale string) string {
+	var fingerprint string
+	for _, ext := range bundleExtensions {
+		if info, err := os.Stat(filepath.Join(dir, locale+ext)); err == nil {
+			fingerprint += ext + "=" + info.ModTime().String() + ";"
+		}
+	}
+	return fingerprint
+}
+
+// reloadLocale parses the changed translation files of a locale and atomically swaps its messages, the
+// ForLocale managers are dropped so they are loaded again with the new messages
+func (i *I18nManager) reloadLocale(locale, dir string) {
+	files, err := readBundleFilesInDir(dir, locale)
+	if err != nil {
+		logrus.Errorf("i18n: keeping previous messages for locale %s: %v", locale, err)
+		return
+	}
+	messages := mergeBundleFiles(files)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if locale == i.locale {
+		i.messages = messages
+	}
+	if _, ok := i.fallbackMessages[locale]; ok {
+		i.fallbackMessages[locale] = messages
+	}
+	i.derived = nil
+	logrus.Infof("i18n: reloaded messages for locale %s from %s", locale, dir)
+}
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBundle writes a translation file and moves its modification time forward so polling sees the change
+func writeBundle(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForMessage polls manager until key resolves to want or the deadline passes
+func waitForMessage(t *testing.T, manager *I18nManager, key, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if manager.GetMessage(key) == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("GetMessage(%q) = %q, want %q", key, manager.GetMessage(key), want)
+}
+
+func TestWatchReloadsChangedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "en.json")
+	start := time.Now().Add(-time.Hour)
+	writeBundle(t, path, `{"greeting":"Hello"}`, start)
+	t.Setenv("I18N_DIR", dir)
+
+	manager, err := NewI18nManager("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.WatchWithInterval(ctx, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent readers exercise the swap under the race detector
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				manager.GetMessage("greeting")
+			}
+		}
+	}()
+
+	writeBundle(t, path, `{"greeting":"Hi there"}`, start.Add(time.Minute))
+	waitForMessage(t, manager, "greeting", "Hi there")
+
+	// A broken file keeps the previous good version
+	writeBundle(t, path, `{"greeting":`, start.Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if got := manager.GetMessage("greeting"); got != "Hi there" {
+		t.Errorf("after a broken write = %q, want the previous message", got)
+	}
+
+	writeBundle(t, path, `{"greeting":"Fixed"}`, start.Add(3*time.Minute))
+	waitForMessage(t, manager, "greeting", "Fixed")
+}
+
+func TestWatchReloadsDerivedLocale(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeBundle(t, filepath.Join(dir, "en.json"), `{"greeting":"Hello"}`, start)
+	writeBundle(t, filepath.Join(dir, "vn.json"), `{"greeting":"Xin chào"}`, start)
+	t.Setenv("I18N_DIR", dir)
+
+	manager, err := NewI18nManager("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.WatchWithInterval(ctx, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	vn, err := manager.ForLocale("vn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vn.GetMessage("greeting"); got != "Xin chào" {
+		t.Fatalf("vn greeting = %q", got)
+	}
+
+	// vn is only loaded by ForLocale, its file is watched all the same
+	writeBundle(t, filepath.Join(dir, "vn.json"), `{"greeting":"Chào bạn"}`, start.Add(time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		vn, err := manager.ForLocale("vn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vn.GetMessage("greeting") == "Chào bạn" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("vn greeting = %q after the edit, want the new message", vn.GetMessage("greeting"))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := manager.GetMessage("greeting"); got != "Hello" {
+		t.Errorf("en greeting = %q, want it unchanged", got)
+	}
+}
+
+func TestWatchWithoutDiskBundle(t *testing.T) {
+	manager := newTestI18n("en", map[string]any{})
+	if err := manager.Watch(context.Background()); err == nil {
+		t.Error("Watch started without any translation file on disk")
+	}
+}
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// List query defaults of the BaseController pagination handlers
+const (
+	DefaultListPageSize    = 10
+	DefaultListMaxPageSize = 100
+)
+
+// defaultListSort is the sort_by the BaseController pagination handlers report without one
+const defaultListSort = "created_at"
+
+// ListQueryConfig configures ParseListQuery, empty fields get defaults
+type ListQueryConfig struct {
+	// DefaultSize is the page size without a size parameter, DefaultListPageSize by default
+	DefaultSize int
+	// MaxSize clamps larger size parameters, DefaultListMaxPageSize by default
+	MaxSize int
+	// SortableColumns whitelists the sort_by values, mapping API field names to DB columns, e.g.
+	// {"createdAt": "created_at"}. Other values are rejected, nothing is sortable when empty
+	SortableColumns map[string]string
+	// DefaultSort is the API field sorted by without a sort_by parameter, unsorted when empty
+	DefaultSort string
+	// DefaultAsc sorts ascending without a sort_order or desc parameter, descending by default
+	DefaultAsc bool
+}
+
+// withDefaults fills the empty fields of cfg
+func (cfg ListQueryConfig) withDefaults() ListQueryConfig {
+	if cfg.MaxSize < 1 {
+		cfg.MaxSize = DefaultListMaxPageSize
+	}
+	if cfg.DefaultSize < 1 {
+		cfg.DefaultSize = DefaultListPageSize
+	}
+	cfg.DefaultSize = min(cfg.DefaultSize, cfg.MaxSize)
+	return cfg
+}
+
+// ListQuery is the validated page and sort of a list request, see ParseListQuery
+type ListQuery struct {
+	// Page is the page number from 1
+	Page int
+	// Size is the page size, clamped to ListQueryConfig.MaxSize
+	Size int
+	// SortBy is the API field sorted by, empty when unsorted
+	SortBy string
+	// Desc sorts descending
+	Desc bool
+	// column is the DB column of SortBy
+	column string
+}
+
+// Limit returns the number of rows of the page, for repositories
+func (q ListQuery) Limit() int {
+	return q.Size
+}
+
+// Offset returns the number of rows before the page, for repositories
+func (q ListQuery) Offset() int {
+	return (q.Page - 1) * q.Size
+}
+
+// OrderBy returns the ORDER BY clause of the query, e.g. "created_at DESC", empty when unsorted. The column
+// comes from ListQueryConfig.SortableColumns, never from the request
+func (q ListQuery) OrderBy() string {
+	if q.column == "" {
+		return ""
+	}
+	if q.Desc {
+		return q.column + " DESC"
+	}
+	return q.column + " ASC"
+}
+
+// Pagination returns the pagination info of the page for total items
+func (q ListQuery) Pagination(total int64) PaginationInfo {
+	return CalculatePagination(q.Page, q.Size, total)
+}
+
+// sortOrder returns the sort_order value of the query
+func (q ListQuery) sortOrder() string {
+	if q.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// ParseListQuery parses the page, size, sort_by and sort_order (or desc=true|false) query parameters of a
+// list request, the parameters of the BaseController pagination handlers. Sizes above cfg.MaxSize are
+// clamped; malformed pages or sizes, unknown sort fields and sort orders other than asc and desc return a
+// VALIDATION_ERROR response. The result feeds Repository.GetWhereWithOrder:
+//
+//	query, errResp := common.ParseListQuery(c, cfg)
+//	err := repo.GetWhereWithOrder(ctx, &items, "1 = 1", query.OrderBy(), query.Limit(), query.Offset(), nil)
+func ParseListQuery(c echo.Context, cfg ListQueryConfig) (ListQuery, *ErrorResponse) {
+	query, details := parseListQuery(c, cfg)
+	if len(details) > 0 {
+		return ListQuery{}, ValidationErrorI18n(details...)
+	}
+	return query, nil
+}
+
+// parseListQuery parses the list parameters of c, malformed parameters keep their defaults and are
+// reported in the details. An unknown sort_by is kept without a column
+func parseListQuery(c echo.Context, cfg ListQueryConfig) (ListQuery, []ErrorDetail) {
+	cfg = cfg.withDefaults()
+	ctx := LocaleContext(c)
+	query := ListQuery{Page: 1, Size: cfg.DefaultSize, SortBy: cfg.DefaultSort, Desc: !cfg.DefaultAsc}
+
+	var details []ErrorDetail
+	invalid := func(name, value, messageKey string, params map[string]any) {
+		params["field"] = name
+		details = append(details, ErrorDetail{
+			Field:   name,
+			Message: TfWithContext(ctx, messageKey, params),
+			Value:   RedactValue(name, value),
+		})
+	}
+
+	if value := c.QueryParam("size"); value != "" {
+		size, err := parseListInt(value)
+		switch {
+		case err != nil:
+			invalid("size", value, MsgValidationNumeric, map[string]any{})
+		case size < 1:
+			invalid("size", value, MsgValidationMinValue, map[string]any{"min": 1})
+		default:
+			query.Size = min(size, cfg.MaxSize)
+		}
+	}
+
+	if value := c.QueryParam("page"); value != "" {
+		// The offset of the last page must fit an int
+		maxPage := math.MaxInt/query.Size + 1
+		page, err := parseListInt(value)
+		switch {
+		case err != nil:
+			invalid("page", value, MsgValidationNumeric, map[string]any{})
+		case page < 1:
+			invalid("page", value, MsgValidationMinValue, map[string]any{"min": 1})
+		case page > maxPage:
+			invalid("page", value, MsgValidationMaxValue, map[string]any{"max": maxPage})
+		default:
+			query.Page = page
+		}
+	}
+
+	if value := c.QueryParam("sort_by"); value != "" {
+		query.SortBy = value
+	}
+	if query.SortBy != "" {
+		column, ok := cfg.SortableColumns[query.SortBy]
+		if ok && column != "" {
+			query.column = column
+		} else {
+			fields := slices.Sorted(maps.Keys(cfg.SortableColumns))
+			invalid("sort_by", query.SortBy, MsgValidationOneOf, map[string]any{"values": strings.Join(fields, ", ")})
+		}
+	}
+
+	if value := c.QueryParam("sort_order"); value != "" {
+		switch strings.ToLower(value) {
+		case "asc":
+			query.Desc = false
+		case "desc":
+			query.Desc = true
+		default:
+			invalid("sort_order", value, MsgValidationOneOf, map[string]any{"values": "asc, desc"})
+		}
+	} else if value := c.QueryParam("desc"); value != "" {
+		if desc, err := strconv.ParseBool(value); err == nil {
+			query.Desc = desc
+		} else {
+			invalid("desc", value, MsgValidationOneOf, map[string]any{"values": "true, false"})
+		}
+	}
+
+	return query, details
+}
+
+// parseListInt parses an integer parameter, saturating values out of the int range so they are clamped or
+// rejected by their bounds rather than reported as malformed
+func parseListInt(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if errors.Is(err, strconv.ErrRange) {
+		return n, nil
+	}
+	return n, err
+}
@@ -0,0 +1,210 @@
+package common
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// listQueryContext returns an echo context of a GET request with query
+func listQueryContext(query string) echo.Context {
+	return echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/items?"+query, nil), httptest.NewRecorder())
+}
+
+func TestParseListQuery(t *testing.T) {
+	sortable := map[string]string{"createdAt": "created_at", "name": "users.name"}
+	tests := []struct {
+		name        string
+		query       string
+		cfg         ListQueryConfig
+		wantPage    int
+		wantSize    int
+		wantOrderBy string
+	}{
+		{name: "defaults", wantPage: 1, wantSize: DefaultListPageSize},
+		{name: "configured defaults", cfg: ListQueryConfig{DefaultSize: 25, SortableColumns: sortable, DefaultSort: "createdAt"},
+			wantPage: 1, wantSize: 25, wantOrderBy: "created_at DESC"},
+		{name: "default ascending", cfg: ListQueryConfig{SortableColumns: sortable, DefaultSort: "name", DefaultAsc: true},
+			wantPage: 1, wantSize: 10, wantOrderBy: "users.name ASC"},
+		{name: "default size above max", cfg: ListQueryConfig{DefaultSize: 50, MaxSize: 20}, wantPage: 1, wantSize: 20},
+		{name: "overrides", query: "page=3&size=20&sort_by=name&sort_order=ASC", cfg: ListQueryConfig{SortableColumns: sortable, DefaultSort: "createdAt"},
+			wantPage: 3, wantSize: 20, wantOrderBy: "users.name ASC"},
+		{name: "desc parameter", query: "sort_by=name&desc=false", cfg: ListQueryConfig{SortableColumns: sortable},
+			wantPage: 1, wantSize: 10, wantOrderBy: "users.name ASC"},
+		{name: "sort_order wins over desc", query: "sort_by=name&sort_order=desc&desc=false", cfg: ListQueryConfig{SortableColumns: sortable},
+			wantPage: 1, wantSize: 10, wantOrderBy: "users.name DESC"},
+		{name: "max size clamp", query: "size=500", wantPage: 1, wantSize: DefaultListMaxPageSize},
+		{name: "configured max size clamp", query: "size=30", cfg: ListQueryConfig{MaxSize: 25}, wantPage: 1, wantSize: 25},
+		{name: "size out of int range clamped", query: "size=99999999999999999999", wantPage: 1, wantSize: DefaultListMaxPageSize},
+		{name: "largest page", query: "size=100&page=" + strconv.Itoa(math.MaxInt/100+1), wantPage: math.MaxInt/100 + 1, wantSize: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, errResp := ParseListQuery(listQueryContext(tt.query), tt.cfg)
+			if errResp != nil {
+				t.Fatalf("ParseListQuery() error = %+v", errResp)
+			}
+			if query.Page != tt.wantPage || query.Size != tt.wantSize || query.OrderBy() != tt.wantOrderBy {
+				t.Errorf("ParseListQuery() = page %d, size %d, order %q, want %d, %d, %q",
+					query.Page, query.Size, query.OrderBy(), tt.wantPage, tt.wantSize, tt.wantOrderBy)
+			}
+			if query.Limit() != query.Size || query.Offset() != (query.Page-1)*query.Size {
+				t.Errorf("Limit() = %d, Offset() = %d, want the size and the rows before page %d", query.Limit(), query.Offset(), query.Page)
+			}
+		})
+	}
+}
+
+func TestParseListQueryInvalid(t *testing.T) {
+	sortable := map[string]string{"createdAt": "created_at", "name": "name"}
+	tests := []struct {
+		name       string
+		query      string
+		cfg        ListQueryConfig
+		wantFields []string
+	}{
+		{name: "malformed size", query: "size=ten", wantFields: []string{"size"}},
+		{name: "zero size", query: "size=0", wantFields: []string{"size"}},
+		{name: "negative page", query: "page=-1", wantFields: []string{"page"}},
+		{name: "page overflowing the offset", query: "size=100&page=" + strconv.Itoa(math.MaxInt/100+2), wantFields: []string{"page"}},
+		{name: "unknown sort field", query: "sort_by=password", cfg: ListQueryConfig{SortableColumns: sortable}, wantFields: []string{"sort_by"}},
+		{name: "column name instead of field", query: "sort_by=created_at", cfg: ListQueryConfig{SortableColumns: sortable}, wantFields: []string{"sort_by"}},
+		{name: "nothing sortable", query: "sort_by=name", wantFields: []string{"sort_by"}},
+		{name: "unknown default sort", cfg: ListQueryConfig{SortableColumns: sortable, DefaultSort: "id"}, wantFields: []string{"sort_by"}},
+		{name: "sort order", query: "sort_by=name&sort_order=random", cfg: ListQueryConfig{SortableColumns: sortable}, wantFields: []string{"sort_order"}},
+		{name: "desc", query: "desc=maybe", wantFields: []string{"desc"}},
+		{name: "several", query: "page=x&size=-5&sort_by=id%3B%20DROP%20TABLE%20users", cfg: ListQueryConfig{SortableColumns: sortable},
+			wantFields: []string{"size", "page", "sort_by"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, errResp := ParseListQuery(listQueryContext(tt.query), tt.cfg)
+			if errResp == nil || errResp.Code != VALIDATION_ERROR {
+				t.Fatalf("ParseListQuery() = %+v, %+v, want a VALIDATION_ERROR", query, errResp)
+			}
+			if query != (ListQuery{}) {
+				t.Errorf("query = %+v, want zero on error", query)
+			}
+			if len(errResp.Details) != len(tt.wantFields) {
+				t.Fatalf("details = %+v, want fields %v", errResp.Details, tt.wantFields)
+			}
+			for i, detail := range errResp.Details {
+				if detail.Field != tt.wantFields[i] || detail.Message == "" {
+					t.Errorf("detail %d = %+v, want field %s with a message", i, detail, tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListQueryPagination(t *testing.T) {
+	query, errResp := ParseListQuery(listQueryContext("page=2&size=10"), ListQueryConfig{})
+	if errResp != nil {
+		t.Fatal(errResp)
+	}
+	if got := query.Pagination(35); got != CalculatePagination(2, 10, 35) {
+		t.Errorf("Pagination() = %+v", got)
+	}
+}
+
+func TestControllerListQueryLenient(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantSize  int
+		wantSort  string
+		wantOrder string
+	}{
+		{"", 1, 10, "created_at", "desc"},
+		{"page=2&size=25&sort_by=name&sort_order=asc", 2, 25, "name", "asc"},
+		{"size=500", 1, 100, "created_at", "desc"},
+		{"page=0&size=ten&sort_order=sideways", 1, 10, "created_at", "desc"},
+		{"page=-3&size=-1&desc=maybe", 1, 10, "created_at", "desc"},
+		{"sort_by=anything&desc=false", 1, 10, "anything", "asc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			e := echo.New()
+			controller := &BaseController[testItem]{}
+			e.GET("/items", controller.ResponseListWithPaginationAndSorting(func(c echo.Context) ([]testItem, int64, *ErrorResponse) {
+				return []testItem{{ID: "a"}}, 1, nil
+			}))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			var resp struct {
+				Data struct {
+					Sorting struct {
+						SortBy    string `json:"sort_by"`
+						SortOrder string `json:"sort_order"`
+					} `json:"sorting"`
+					Meta struct {
+						CurrentPage int `json:"current_page"`
+						PageSize    int `json:"page_size"`
+					} `json:"meta"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			got := resp.Data
+			if got.Meta.CurrentPage != tt.wantPage || got.Meta.PageSize != tt.wantSize ||
+				got.Sorting.SortBy != tt.wantSort || got.Sorting.SortOrder != tt.wantOrder {
+				t.Errorf("got page %d, size %d, sort %s %s, want %d, %d, %s %s", got.Meta.CurrentPage, got.Meta.PageSize,
+					got.Sorting.SortBy, got.Sorting.SortOrder, tt.wantPage, tt.wantSize, tt.wantSort, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestControllerPageHandlersUseListQuery(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	serviceFunc := func(c echo.Context) ([]testItem, int64, *ErrorResponse) {
+		return []testItem{{ID: "a"}}, 1000, nil
+	}
+	handlers := map[string]echo.HandlerFunc{
+		"ResponsePage":               controller.ResponsePage(serviceFunc),
+		"ResponseListWithPagination": controller.ResponseListWithPagination(serviceFunc),
+	}
+	tests := []struct {
+		query    string
+		wantPage int
+		wantSize int
+	}{
+		{"", 1, 10},
+		{"page=3&size=25", 3, 25},
+		{"size=500", 1, 100},
+		{"page=zero&size=-1", 1, 10},
+	}
+	for name, handler := range handlers {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.query, func(t *testing.T) {
+				e := echo.New()
+				e.GET("/items", handler)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+
+				var resp struct {
+					Pagination PaginationInfo `json:"pagination"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if got := resp.Pagination; got.CurrentPage != tt.wantPage || got.PageSize != tt.wantSize {
+					t.Errorf("pagination = page %d, size %d, want %d, %d", got.CurrentPage, got.PageSize, tt.wantPage, tt.wantSize)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LocaleSource is a place of the request where the locale can be read from
+type LocaleSource string
+
+const (
+	LocaleSourceQuery  LocaleSource = "query"
+	LocaleSourceCookie LocaleSource = "cookie"
+	LocaleSourceHeader LocaleSource = "header"
+)
+
+// LocaleResolverConfig configures how ResolveLocale reads the locale of a request
+type LocaleResolverConfig struct {
+	// Order is the list of sources checked in order, the first supported locale wins
+	Order []LocaleSource
+	// QueryParam is the query parameter holding the locale, e.g. ?lang=en
+	QueryParam string
+	// CookieName is the cookie holding the locale
+	CookieName string
+}
+
+// DefaultLocaleResolverConfig checks ?lang=, then the lang cookie, then Accept-Language
+var DefaultLocaleResolverConfig = LocaleResolverConfig{
+	Order:      []LocaleSource{LocaleSourceQuery, LocaleSourceCookie, LocaleSourceHeader},
+	QueryParam: "lang",
+	CookieName: "lang",
+}
+
+var (
+	localeMu       sync.RWMutex
+	localeResolver = DefaultLocaleResolverConfig
+	// supportedLocales maps a language code or alias to the locale of its bundle, filled from the bundles found
+	// by the i18n managers
+	supportedLocales = map[string]string{}
+	// localeAliases are the other codes of a locale, registered with it when its bundle is loaded
+	localeAliases = map[string][]string{
+		"vn": {"vi"},
+	}
+)
+
+// SetLocaleResolverConfig replaces the configuration used by ResolveLocale, empty fields keep their default
+func SetLocaleResolverConfig(config LocaleResolverConfig) {
+	config = withLocaleResolverDefaults(config)
+
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	localeResolver = config
+}
+
+// withLocaleResolverDefaults fills the empty fields of config with DefaultLocaleResolverConfig
+func withLocaleResolverDefaults(config LocaleResolverConfig) LocaleResolverConfig {
+	if len(config.Order) == 0 {
+		config.Order = DefaultLocaleResolverConfig.Order
+	}
+	if config.QueryParam == "" {
+		config.QueryParam = DefaultLocaleResolverConfig.QueryParam
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultLocaleResolverConfig.CookieName
+	}
+	return config
+}
+
+// RegisterLocale adds locale to the supported locales, aliases are other codes resolving to it (e.g. "vi" for "vn").
+// Creating an i18n manager registers every locale having a bundle in its directory or fs.FS, loaded or not
+func RegisterLocale(locale string, aliases ...string) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if locale == "" {
+		return
+	}
+
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	supportedLocales[locale] = locale
+	for _, alias := range append(localeAliases[locale], aliases...) {
+		if alias = strings.ToLower(strings.TrimSpace(alias)); alias != "" {
+			supportedLocales[alias] = locale
+		}
+	}
+}
+
+// ListLocales returns the supported locales, sorted, e.g. for a language settings endpoint
+func ListLocales() []string {
+	localeMu.RLock()
+	defer localeMu.RUnlock()
+
+	seen := make(map[string]bool, len(supportedLocales))
+	locales := make([]string, 0, len(supportedLocales))
+	for _, locale := range supportedLocales {
+		if !seen[locale] {
+			seen[locale] = true
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// NormalizeLocale maps a language tag such as "vi-VN" or "EN" to a supported locale
+func NormalizeLocale(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	// Remove region code if present (e.g., "vi-VN" -> "vi", "en_US" -> "en")
+	if idx := strings.IndexAny(code, "-_"); idx > 0 {
+		code = code[:idx]
+	}
+	if code == "" {
+		return "", false
+	}
+
+	localeMu.RLock()
+	defer localeMu.RUnlock()
+	locale, ok := supportedLocales[code]
+	return locale, ok
+}
+
+// ContextKeyLocale is the echo context key of the locale resolved once per request by the locale middleware
+const ContextKeyLocale = "locale"
+
+// ResolveLocale returns the locale of the request: the one resolved by the locale middleware when present,
+// otherwise the first supported locale found in the sources of SetLocaleResolverConfig, or DefaultLocale
+func ResolveLocale(c echo.Context) string {
+	if locale, ok := c.Get(ContextKeyLocale).(string); ok && locale != "" {
+		return locale
+	}
+
+	localeMu.RLock()
+	config := localeResolver
+	localeMu.RUnlock()
+	return ResolveLocaleWith(c, config)
+}
+
+// ResolveLocaleWith reads the locale of the request from the sources of config, empty fields keep their default
+func ResolveLocaleWith(c echo.Context, config LocaleResolverConfig) string {
+	config = withLocaleResolverDefaults(config)
+
+	req := c.Request()
+	for _, source := range config.Order {
+		switch source {
+		case LocaleSourceQuery:
+			if locale, ok := NormalizeLocale(c.QueryParam(config.QueryParam)); ok {
+				return locale
+			}
+		case LocaleSourceCookie:
+			if cookie, err := req.Cookie(config.CookieName); err == nil {
+				if locale, ok := NormalizeLocale(cookie.Value); ok {
+					return locale
+				}
+			}
+		case LocaleSourceHeader:
+			if locale, ok := localeFromHeader(req.Header); ok {
+				return locale
+			}
+		}
+	}
+	return DefaultLocale
+}
+
+// LocaleContext returns the request context carrying the request locale, for the *WithContext translations
+func LocaleContext(c echo.Context) context.Context {
+	ctx := c.Request().Context()
+	locale := ResolveLocale(c)
+	if current, ok := ctx.Value(I18nContextKey).(string); ok && current == locale {
+		return ctx
+	}
+	return SetLocaleInContext(ctx, locale)
+}
+
+// localeFromHeader returns the first supported locale of the Accept-Language header
+// Supports quality values: "en-US,vi;q=0.9,en;q=0.8"
+func localeFromHeader(header http.Header) (string, bool) {
+	acceptLang := header.Get("Accept-Language")
+	if acceptLang == "" {
+		return "", false
+	}
+
+	// Split by comma to get all language preferences
+	for _, lang := range strings.Split(acceptLang, ",") {
+		// Split by semicolon to get language and quality value
+		langCode := strings.Split(lang, ";")[0]
+		if locale, ok := NormalizeLocale(langCode); ok {
+			return locale, true
+		}
+	}
+	return "", false
+}
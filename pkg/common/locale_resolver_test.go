@@ -0,0 +1,183 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/labstack/echo/v4"
+)
+
+// useLocales replaces the supported locales for the duration of the test
+func useLocales(t *testing.T, locales ...string) {
+	t.Helper()
+	localeMu.Lock()
+	previous := supportedLocales
+	supportedLocales = map[string]string{}
+	localeMu.Unlock()
+	t.Cleanup(func() {
+		localeMu.Lock()
+		supportedLocales = previous
+		localeMu.Unlock()
+	})
+
+	for _, locale := range locales {
+		RegisterLocale(locale)
+	}
+}
+
+func TestSupportedLocalesComeFromBundles(t *testing.T) {
+	useLocales(t)
+	if locales := ListLocales(); len(locales) != 0 {
+		t.Fatalf("ListLocales() = %v before any manager was created, want none", locales)
+	}
+
+	// Every bundle of the source is supported, not only the loaded one
+	if _, err := NewI18nManagerFromFS(testBundleFS(t), "vn"); err != nil {
+		t.Fatal(err)
+	}
+	if locales := ListLocales(); !slices.Equal(locales, []string{"en", "vn"}) {
+		t.Errorf("ListLocales() = %v, want every bundle of the fs.FS", locales)
+	}
+	if locale, ok := NormalizeLocale("vi-VN"); !ok || locale != "vn" {
+		t.Errorf("NormalizeLocale(vi-VN) = %q, %v, want the vi alias of the vn bundle", locale, ok)
+	}
+	if locale, ok := NormalizeLocale("en-US"); !ok || locale != "en" {
+		t.Errorf("NormalizeLocale(en-US) = %q, %v, want the en bundle", locale, ok)
+	}
+	if _, ok := NormalizeLocale("fr"); ok {
+		t.Error("fr supported without a bundle")
+	}
+
+	manager, err := NewI18nManagerFromFS(testBundleFS(t), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.LoadLocaleFromReader("ko", strings.NewReader("{broken")); err == nil {
+		t.Fatal("invalid bundle loaded")
+	}
+	if err := manager.LoadLocaleFromReader("ja", strings.NewReader(`{"response": {}}`)); err != nil {
+		t.Fatal(err)
+	}
+	RegisterLocale("pt", "br")
+	if locales := ListLocales(); !slices.Equal(locales, []string{"en", "ja", "pt", "vn"}) {
+		t.Errorf("ListLocales() = %v", locales)
+	}
+	if locale, _ := NormalizeLocale("BR"); locale != "pt" {
+		t.Errorf("NormalizeLocale(BR) = %q, want the registered alias", locale)
+	}
+}
+
+func TestBundleLocales(t *testing.T) {
+	fsys := fstest.MapFS{
+		"en.json":        {Data: []byte("{}")},
+		"en.yaml":        {Data: []byte("")},
+		"vn.YML":         {Data: []byte("")},
+		"README.md":      {Data: []byte("")},
+		".json":          {Data: []byte("{}")},
+		"nested/fr.json": {Data: []byte("{}")},
+	}
+	locales, err := bundleLocales(fsys)
+	if err != nil || !slices.Equal(locales, []string{"en", "vn"}) {
+		t.Errorf("bundleLocales() = %v, %v, want en and vn", locales, err)
+	}
+}
+
+func TestResolveLocaleToUnloadedDiskBundle(t *testing.T) {
+	useLocales(t)
+	t.Setenv("I18N_DIR", "testdata/i18n")
+	manager, err := NewI18nManager("vn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	useGlobalI18n(t, manager)
+
+	if locales := ListLocales(); !slices.Equal(locales, []string{"en", "vn"}) {
+		t.Errorf("ListLocales() = %v, want the bundles on disk", locales)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en-GB,vi;q=0.8")
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	if got := ResolveLocale(c); got != "en" {
+		t.Fatalf("ResolveLocale() = %q, want en from its bundle on disk", got)
+	}
+	if got := GetLocaleFromHeader(req.Header); got != "en" {
+		t.Errorf("GetLocaleFromHeader() = %q, want en", got)
+	}
+	// The en bundle is loaded on first use
+	if got := TWithContext(LocaleContext(c), MsgSuccessDefault); got != "Success" {
+		t.Errorf("TWithContext() = %q, want the en message", got)
+	}
+}
+
+func TestResolveLocalePrecedence(t *testing.T) {
+	useLocales(t, "en", "vn", "fr")
+
+	tests := []struct {
+		name   string
+		target string
+		cookie string
+		header string
+		config *LocaleResolverConfig
+		want   string
+	}{
+		{name: "query wins", target: "/?lang=fr", cookie: "en", header: "vi", want: "fr"},
+		{name: "cookie before header", target: "/", cookie: "en", header: "fr", want: "en"},
+		{name: "header", target: "/", header: "fr-FR,en;q=0.8", want: "fr"},
+		{name: "header quality order", target: "/", header: "de,en;q=0.8", want: "en"},
+		{name: "unknown query skipped", target: "/?lang=de", cookie: "fr", want: "fr"},
+		{name: "all unknown", target: "/?lang=de", cookie: "ja", header: "ko", want: DefaultLocale},
+		{name: "nothing", target: "/", want: DefaultLocale},
+		{
+			name: "custom order", target: "/?locale=en", cookie: "fr", header: "vi",
+			config: &LocaleResolverConfig{Order: []LocaleSource{LocaleSourceHeader, LocaleSourceQuery}, QueryParam: "locale"},
+			want:   "vn",
+		},
+		{
+			name: "custom query param", target: "/?lang=fr&locale=en",
+			config: &LocaleResolverConfig{QueryParam: "locale"},
+			want:   "en",
+		},
+	}
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var got string
+			if tt.config != nil {
+				got = ResolveLocaleWith(c, *tt.config)
+			} else {
+				got = ResolveLocale(c)
+			}
+			if got != tt.want {
+				t.Errorf("locale = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLocaleUsesMiddlewareValue(t *testing.T) {
+	useLocales(t, "en", "vn")
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/?lang=en", nil), httptest.NewRecorder())
+	c.Set(ContextKeyLocale, "vn")
+
+	if got := ResolveLocale(c); got != "vn" {
+		t.Errorf("ResolveLocale() = %q, want the locale resolved by the middleware", got)
+	}
+	if got := GetLocaleFromContext(LocaleContext(c)); got != "vn" {
+		t.Errorf("LocaleContext locale = %q", got)
+	}
+}
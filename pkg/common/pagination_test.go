@@ -0,0 +1,208 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBuildPaginationLinks(t *testing.T) {
+	requestURL, err := url.Parse("/api/users?filter=a%20b&tag=x&tag=y&page=2&size=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := BuildPaginationLinks(requestURL, CalculatePagination(2, 10, 35))
+	if links == nil {
+		t.Fatal("links = nil")
+	}
+
+	want := map[string]string{
+		"self":  "2",
+		"first": "1",
+		"last":  "4",
+		"next":  "3",
+		"prev":  "1",
+	}
+	got := map[string]string{
+		"self":  links.Self,
+		"first": links.First,
+		"last":  links.Last,
+		"next":  links.Next,
+		"prev":  links.Prev,
+	}
+	for name, page := range want {
+		link, err := url.Parse(got[name])
+		if err != nil {
+			t.Fatalf("%s = %q: %v", name, got[name], err)
+		}
+		if link.Path != "/api/users" {
+			t.Errorf("%s path = %q, want /api/users", name, link.Path)
+		}
+		query := link.Query()
+		if query.Get("page") != page || query.Get("size") != "10" {
+			t.Errorf("%s = %q, want page %s and size 10", name, got[name], page)
+		}
+		if query.Get("filter") != "a b" {
+			t.Errorf("%s filter = %q, want the decoded original %q", name, query.Get("filter"), "a b")
+		}
+		if tags := query["tag"]; len(tags) != 2 || tags[0] != "x" || tags[1] != "y" {
+			t.Errorf("%s tag = %v, want the repeated parameter preserved", name, tags)
+		}
+		if len(query["page"]) != 1 {
+			t.Errorf("%s has %d page parameters, want 1", name, len(query["page"]))
+		}
+	}
+}
+
+func TestBuildPaginationLinksOmitsInapplicable(t *testing.T) {
+	requestURL := &url.URL{Path: "/items"}
+
+	tests := []struct {
+		name                              string
+		pagination                        PaginationInfo
+		wantNext, wantPrev, wantFirstLast bool
+	}{
+		{"first page", CalculatePagination(1, 10, 25), true, false, true},
+		{"last page", CalculatePagination(3, 10, 25), false, true, true},
+		{"single page", CalculatePagination(1, 10, 5), false, false, true},
+		{"empty", CalculatePagination(1, 10, 0), false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := BuildPaginationLinks(requestURL, tt.pagination)
+			if links.Self == "" {
+				t.Error("self link missing")
+			}
+			if (links.Next != "") != tt.wantNext {
+				t.Errorf("next = %q, want present %v", links.Next, tt.wantNext)
+			}
+			if (links.Prev != "") != tt.wantPrev {
+				t.Errorf("prev = %q, want present %v", links.Prev, tt.wantPrev)
+			}
+			if (links.First != "" && links.Last != "") != tt.wantFirstLast {
+				t.Errorf("first = %q, last = %q, want present %v", links.First, links.Last, tt.wantFirstLast)
+			}
+		})
+	}
+
+	if BuildPaginationLinks(nil, CalculatePagination(1, 10, 5)) != nil {
+		t.Error("links built without a request URL")
+	}
+}
+
+func TestPaginationLinksInJSON(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/items?page=3&size=1&q=%C3%A9", nil), rec)
+
+	if err := controller.SuccessWithPagination(c, []testItem{{ID: "c"}}, 3, 3, 1, MsgSuccessRetrieved); err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Pagination struct {
+			Links map[string]string `json:"links"`
+		} `json:"pagination"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	links := body.Pagination.Links
+	if _, ok := links["next"]; ok {
+		t.Errorf("links = %v, want no next on the last page", links)
+	}
+	prev, err := url.Parse(links["prev"])
+	if err != nil || prev.Query().Get("page") != "2" || prev.Query().Get("q") != "é" {
+		t.Errorf("prev = %q, want page 2 keeping q", links["prev"])
+	}
+}
+
+func TestCalculatePagination(t *testing.T) {
+	tests := []struct {
+		name               string
+		page, size         int
+		total              int64
+		wantPage, wantSize int
+		wantPages          int
+		wantNext, wantPrev bool
+	}{
+		{"first of many", 1, 10, 25, 1, 10, 3, true, false},
+		{"middle", 2, 10, 25, 2, 10, 3, true, true},
+		{"last", 3, 10, 25, 3, 10, 3, false, true},
+		{"beyond last", 5, 10, 25, 5, 10, 3, false, true},
+		{"exact multiple", 2, 5, 10, 2, 5, 2, false, true},
+		{"zero size", 1, 0, 3, 1, 1, 3, true, false},
+		{"negative size", 2, -5, 3, 2, 1, 3, true, true},
+		{"zero page", 0, 10, 25, 1, 10, 3, true, false},
+		{"negative page", -3, 10, 25, 1, 10, 3, true, false},
+		{"empty", 1, 10, 0, 1, 10, 0, false, false},
+		{"empty on later page", 4, 10, 0, 4, 10, 0, false, false},
+		{"negative total", 1, 10, -1, 1, 10, 0, false, false},
+		{"zero everything", 0, 0, 0, 1, 1, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculatePagination(tt.page, tt.size, tt.total)
+			if got.CurrentPage != tt.wantPage || got.PageSize != tt.wantSize || got.TotalPages != tt.wantPages {
+				t.Errorf("page/size/pages = %d/%d/%d, want %d/%d/%d",
+					got.CurrentPage, got.PageSize, got.TotalPages, tt.wantPage, tt.wantSize, tt.wantPages)
+			}
+			if got.HasNext != tt.wantNext || got.HasPrev != tt.wantPrev {
+				t.Errorf("has_next/has_prev = %v/%v, want %v/%v", got.HasNext, got.HasPrev, tt.wantNext, tt.wantPrev)
+			}
+			if got.TotalItems < 0 {
+				t.Errorf("total_items = %d, want non-negative", got.TotalItems)
+			}
+		})
+	}
+}
+
+func TestSlicePage(t *testing.T) {
+	content := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name       string
+		content    []int
+		page, size int
+		want       []int
+	}{
+		{"first", content, 1, 2, []int{1, 2}},
+		{"partial last", content, 3, 2, []int{5}},
+		{"beyond", content, 4, 2, []int{}},
+		{"empty content", nil, 1, 10, []int{}},
+		{"zero page and size", content, 0, 0, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slicePage(tt.content, tt.page, tt.size)
+			if got == nil || len(got) != len(tt.want) {
+				t.Fatalf("slicePage() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("slicePage() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSuccessWithPaginationZeroPageSize(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/items", nil), rec)
+
+	if err := controller.SuccessWithPagination(c, []testItem{}, 3, 0, 0, MsgSuccessRetrieved); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,138 @@
+package common
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonPasswordsList is the embedded denylist of common passwords, one per line
+//
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// PasswordPolicy configures PasswordStrength
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DenyList holds extra passwords to reject, compared case insensitively
+	DenyList []string
+	// DenyCommon rejects the passwords of the embedded common passwords list
+	DenyCommon bool
+	// AllowWhitespace accepts spaces and other whitespace characters
+	AllowWhitespace bool
+}
+
+// DefaultPasswordPolicy requires 8 characters with upper, lower and digit and rejects common passwords
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    8,
+	RequireUpper: true,
+	RequireLower: true,
+	RequireDigit: true,
+	DenyCommon:   true,
+}
+
+// Password requirement suffixes appended to the message key of PasswordStrength
+const (
+	PasswordRuleMinLength  = "min_length"
+	PasswordRuleUpper      = "upper"
+	PasswordRuleLower      = "lower"
+	PasswordRuleDigit      = "digit"
+	PasswordRuleSymbol     = "symbol"
+	PasswordRuleWhitespace = "whitespace"
+	PasswordRuleCommon     = "common"
+)
+
+// PasswordStrength validates value against policy and returns one ErrorDetail per unmet requirement so
+// clients can render a checklist. Each message uses messageKey + "." + requirement (e.g. "validation.password.upper"),
+// with {min} interpolated for the length. The password itself is never echoed back in Value
+func (v *BaseValidator) PasswordStrength(field string, value string, policy PasswordPolicy, messageKey string) []ErrorDetail {
+	field = v.path(field)
+	var hasUpper, hasLower, hasDigit, hasSymbol, hasSpace bool
+	for _, r := range value {
+		switch {
+		case unicode.IsSpace(r):
+			hasSpace = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var errors []ErrorDetail
+	fail := func(rule string, params map[string]any) {
+		params["field"] = field
+		errors = append(errors, ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey+"."+rule, params),
+		})
+	}
+
+	if utf8.RuneCountInString(value) < policy.MinLength {
+		fail(PasswordRuleMinLength, map[string]any{"min": policy.MinLength})
+	}
+	if policy.RequireUpper && !hasUpper {
+		fail(PasswordRuleUpper, map[string]any{})
+	}
+	if policy.RequireLower && !hasLower {
+		fail(PasswordRuleLower, map[string]any{})
+	}
+	if policy.RequireDigit && !hasDigit {
+		fail(PasswordRuleDigit, map[string]any{})
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		fail(PasswordRuleSymbol, map[string]any{})
+	}
+	if !policy.AllowWhitespace && hasSpace {
+		fail(PasswordRuleWhitespace, map[string]any{})
+	}
+	if isDeniedPassword(value, policy) {
+		fail(PasswordRuleCommon, map[string]any{})
+	}
+
+	return errors
+}
+
+// IsCommonPassword reports whether password is in the embedded common passwords list
+func IsCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		lines := strings.Split(commonPasswordsList, "\n")
+		commonPasswords = make(map[string]struct{}, len(lines))
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				commonPasswords[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	})
+
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+// isDeniedPassword checks the policy deny list and, when enabled, the common passwords list
+func isDeniedPassword(password string, policy PasswordPolicy) bool {
+	if password == "" {
+		return false
+	}
+	for _, denied := range policy.DenyList {
+		if strings.EqualFold(password, denied) {
+			return true
+		}
+	}
+	return policy.DenyCommon && IsCommonPassword(password)
+}
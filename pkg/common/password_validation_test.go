@@ -0,0 +1,68 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+// passwordRules returns the requirement suffix of each detail
+func passwordRules(t *testing.T, details []ErrorDetail) []string {
+	t.Helper()
+	rules := make([]string, 0, len(details))
+	for _, detail := range details {
+		if detail.Value != "" {
+			t.Errorf("detail %+v echoes the password", detail)
+		}
+		rules = append(rules, detail.Message[len(MsgValidationPassword)+1:])
+	}
+	return rules
+}
+
+func TestPasswordStrength(t *testing.T) {
+	v := &BaseValidator{}
+	strict := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true, DenyList: []string{"Company2024!"}}
+
+	tests := []struct {
+		name     string
+		password string
+		policy   PasswordPolicy
+		want     []string
+	}{
+		{"strong default", "Str0ngPass", DefaultPasswordPolicy, []string{}},
+		{"empty", "", DefaultPasswordPolicy, []string{PasswordRuleMinLength, PasswordRuleUpper, PasswordRuleLower, PasswordRuleDigit}},
+		{"short lowercase", "xqzk", DefaultPasswordPolicy, []string{PasswordRuleMinLength, PasswordRuleUpper, PasswordRuleDigit}},
+		{"common password", "Password1", DefaultPasswordPolicy, []string{PasswordRuleCommon}},
+		{"whitespace", "Str0ng Pass", DefaultPasswordPolicy, []string{PasswordRuleWhitespace}},
+		{"whitespace allowed", "Str0ng Pass", PasswordPolicy{MinLength: 8, AllowWhitespace: true}, []string{}},
+		{"length in characters", "Mậtkhẩu9", DefaultPasswordPolicy, []string{}},
+		{"symbol required", "Str0ngPassword", strict, []string{PasswordRuleSymbol}},
+		{"deny list case insensitive", "company2024!", strict, []string{PasswordRuleUpper, PasswordRuleCommon}},
+		{"common list disabled", "password", PasswordPolicy{MinLength: 8}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := passwordRules(t, v.PasswordStrength("password", tt.password, tt.policy, MsgValidationPassword))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("unmet requirements = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordStrengthMessages(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"password": map[string]any{"min_length": "{field} cần ít nhất {min} ký tự"}},
+	}))
+	details := (&BaseValidator{}).Prefix("user").PasswordStrength("password", "Ab1", DefaultPasswordPolicy, MsgValidationPassword)
+	if len(details) != 1 || details[0].Field != "user.password" || details[0].Message != "user.password cần ít nhất 8 ký tự" {
+		t.Errorf("details = %+v", details)
+	}
+}
+
+func TestIsCommonPassword(t *testing.T) {
+	for password, want := range map[string]bool{"123456": true, "PASSWORD": true, "qwerty": true, "x7#Vq!m2Lp": false, "": false} {
+		if got := IsCommonPassword(password); got != want {
+			t.Errorf("IsCommonPassword(%q) = %v, want %v", password, got, want)
+		}
+	}
+}
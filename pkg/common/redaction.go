@@ -0,0 +1,167 @@
+package common
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// RedactedValue replaces the value of sensitive fields in ErrorDetail
+const RedactedValue = "***"
+
+var (
+	sensitiveFieldsMu sync.RWMutex
+	// sensitiveFields are normalized names (lowercase, without '_' and '-') of fields whose value is never echoed back
+	sensitiveFields = map[string]struct{}{
+		"password":             {},
+		"passwordconfirmation": {},
+		"passwordconfirm":      {},
+		"passwd":               {},
+		"token":                {},
+		"secret":               {},
+		"cardnumber":           {},
+		"cvv":                  {},
+		"apikey":               {},
+		"privatekey":           {},
+	}
+)
+
+// RegisterSensitiveField adds field names whose value is redacted in validation error details.
+// Names match case insensitively the trailing words of the last path segment, words being separated by
+// '_', '-' or a camelCase boundary: "token" covers "access_token" and "refreshToken" but not "token_type"
+func RegisterSensitiveField(names ...string) {
+	sensitiveFieldsMu.Lock()
+	defer sensitiveFieldsMu.Unlock()
+	for _, name := range names {
+		if normalized := normalizeFieldName(name); normalized != "" {
+			sensitiveFields[normalized] = struct{}{}
+		}
+	}
+}
+
+// IsSensitiveField reports whether the value of field (a name or a path like "users[0].password") must be redacted
+func IsSensitiveField(field string) bool {
+	sensitiveFieldsMu.RLock()
+	defer sensitiveFieldsMu.RUnlock()
+	for _, suffix := range wordSuffixes(lastPathSegment(field)) {
+		if _, ok := sensitiveFields[suffix]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue returns RedactedValue for sensitive fields and value otherwise
+func RedactValue(field, value string) string {
+	if value != "" && IsSensitiveField(field) {
+		return RedactedValue
+	}
+	return value
+}
+
+// RedactErrorDetails redacts the values of sensitive fields in details, e.g. before logging them
+func RedactErrorDetails(details []ErrorDetail) []ErrorDetail {
+	for index := range details {
+		details[index].Value = RedactValue(details[index].Field, details[index].Value)
+	}
+	return details
+}
+
+// matchesSensitiveName reports whether field matches a single sensitive name
+func matchesSensitiveName(field, name string) bool {
+	name = normalizeFieldName(name)
+	if name == "" {
+		return false
+	}
+	for _, suffix := range wordSuffixes(lastPathSegment(field)) {
+		if suffix == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wordSuffixes returns the normalized trailing word sequences of a field name,
+// "new_accessToken" -> "token", "accesstoken", "newaccesstoken"
+func wordSuffixes(field string) []string {
+	words := fieldWords(field)
+	suffixes := make([]string, 0, len(words))
+	suffix := ""
+	for index := len(words) - 1; index >= 0; index-- {
+		suffix = words[index] + suffix
+		suffixes = append(suffixes, suffix)
+	}
+	return suffixes
+}
+
+// fieldWords splits a field name into lowercase words at '_', '-' and camelCase boundaries
+func fieldWords(field string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(strings.TrimSpace(field))
+	for index, r := range runes {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && index > 0:
+			previous := runes[index-1]
+			nextIsLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+			// "cardNumber" and "APIKey" split before the upper case letter starting a word
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || unicode.IsUpper(previous) && nextIsLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// lastPathSegment returns the field name of a path, "items[0].card_number" -> "card_number"
+func lastPathSegment(field string) string {
+	if index := strings.LastIndex(field, "."); index >= 0 {
+		field = field[index+1:]
+	}
+	if index := strings.Index(field, "["); index >= 0 {
+		field = field[:index]
+	}
+	return field
+}
+
+// normalizeFieldName lowercases name and removes '_' and '-' so card_number, cardNumber and card-number match
+func normalizeFieldName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// RedactJSON returns a copy of a decoded JSON value with the values of sensitive object keys replaced by
+// RedactedValue, at any depth
+func RedactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, item := range v {
+			if IsSensitiveField(key) {
+				redacted[key] = RedactedValue
+				continue
+			}
+			redacted[key] = RedactJSON(item)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = RedactJSON(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsSensitiveField(t *testing.T) {
+	tests := map[string]bool{
+		"password":              true,
+		"new_password":          true,
+		"password_confirmation": true,
+		"passwordConfirmation":  true,
+		"access_token":          true,
+		"refreshToken":          true,
+		"X-Api-Key":             true,
+		"APIKey":                true,
+		"api_key":               true,
+		"card_number":           true,
+		"cardNumber":            true,
+		"CardNumber":            true,
+		"client_secret":         true,
+		"users[0].password":     true,
+		"payment.card_number":   true,
+		"token_type":            false,
+		"tokenType":             false,
+		"tokens_used":           false,
+		"password_hint.length":  false,
+		"keyboard":              false,
+		"secretary":             false,
+		"username":              false,
+		"card_holder":           false,
+		"":                      false,
+	}
+	for field, want := range tests {
+		if got := IsSensitiveField(field); got != want {
+			t.Errorf("IsSensitiveField(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestFieldWords(t *testing.T) {
+	tests := map[string][]string{
+		"card_number":   {"card", "number"},
+		"cardNumber":    {"card", "number"},
+		"APIKey":        {"api", "key"},
+		"X-Api-Key":     {"x", "api", "key"},
+		"already_lower": {"already", "lower"},
+		"__leading__":   {"leading"},
+	}
+	for field, want := range tests {
+		if got := fieldWords(field); !slices.Equal(got, want) {
+			t.Errorf("fieldWords(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestRegisterSensitiveField(t *testing.T) {
+	RegisterSensitiveField("national_id")
+	t.Cleanup(func() {
+		sensitiveFieldsMu.Lock()
+		delete(sensitiveFields, "nationalid")
+		sensitiveFieldsMu.Unlock()
+	})
+
+	for field, want := range map[string]bool{"national_id": true, "nationalId": true, "owner_national_id": true, "national_id_type": false} {
+		if got := IsSensitiveField(field); got != want {
+			t.Errorf("IsSensitiveField(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestRedactionInValidators(t *testing.T) {
+	v := &BaseValidator{SensitiveFields: []string{"pin"}}
+
+	if detail := v.MinLength("password", "abc", 8, MsgValidationMinLength); detail == nil || detail.Value != RedactedValue {
+		t.Errorf("registered sensitive field = %+v, want redacted", detail)
+	}
+	if detail := v.MinLength("pin", "12", 4, MsgValidationMinLength); detail == nil || detail.Value != RedactedValue {
+		t.Errorf("validator sensitive field = %+v, want redacted", detail)
+	}
+	if detail := v.MinLength("pinned", "ab", 4, MsgValidationMinLength); detail == nil || detail.Value != "ab" {
+		t.Errorf("field containing a sensitive name = %+v, want the value kept", detail)
+	}
+	if detail := v.OneOf("token_type", "mac", []string{"bearer"}, MsgValidationOneOf); detail == nil || detail.Value != "mac" {
+		t.Errorf("token_type = %+v, want the value kept", detail)
+	}
+}
+
+func TestRedactionInStructTags(t *testing.T) {
+	type request struct {
+		Password  string `json:"password" validate:"min=8"`
+		TokenType string `json:"token_type" validate:"oneof=bearer"`
+		Pin       string `json:"pin" validate:"min=4" sensitive:"true"`
+	}
+
+	failures := collectFieldFailures(&request{Password: "short", TokenType: "mac", Pin: "12"})
+	values := make(map[string]string)
+	for _, failure := range failures {
+		values[failure.field] = failure.value
+	}
+	want := map[string]string{"password": RedactedValue, "token_type": "mac", "pin": RedactedValue}
+	for field, value := range want {
+		if values[field] != value {
+			t.Errorf("value of %q = %q, want %q", field, values[field], value)
+		}
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	redacted := RedactJSON(map[string]any{
+		"user":       map[string]any{"password": "p", "name": "An"},
+		"token_type": "bearer",
+		"items":      []any{map[string]any{"card_number": "4111"}},
+	}).(map[string]any)
+
+	if redacted["user"].(map[string]any)["password"] != RedactedValue || redacted["user"].(map[string]any)["name"] != "An" {
+		t.Errorf("user = %v", redacted["user"])
+	}
+	if redacted["token_type"] != "bearer" {
+		t.Errorf("token_type = %v, want kept", redacted["token_type"])
+	}
+	if redacted["items"].([]any)[0].(map[string]any)["card_number"] != RedactedValue {
+		t.Errorf("items = %v", redacted["items"])
+	}
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	scopeImplicationsMu sync.RWMutex
+	// scopeImplications maps a scope or action to the scopes it directly implies
+	scopeImplications = map[string][]string{
+		"admin": {"write"},
+		"write": {"read"},
+	}
+)
+
+// DefineScopeImplication registers that scope grants implied as well. Implications are transitive and
+// an implication between actions also applies to resource-prefixed scopes, so with the default
+// admin ⊃ write ⊃ read hierarchy a granted "orders:admin" satisfies "orders:read". Register them at
+// startup, ScopeSets built earlier keep the implications they were built with
+func DefineScopeImplication(scope string, implied ...string) {
+	scopeImplicationsMu.Lock()
+	defer scopeImplicationsMu.Unlock()
+	for _, child := range implied {
+		if child != scope && !slices.Contains(scopeImplications[scope], child) {
+			scopeImplications[scope] = append(scopeImplications[scope], child)
+		}
+	}
+}
+
+// ResetScopeImplications removes every registered implication, including the default hierarchy, so
+// scopes are only satisfied by themselves and wildcards
+func ResetScopeImplications() {
+	scopeImplicationsMu.Lock()
+	defer scopeImplicationsMu.Unlock()
+	scopeImplications = map[string][]string{}
+}
+
+// ScopeMatches reports whether a granted scope satisfies a required one without implications. "*"
+// grants every scope and a granted "orders:*" grants "orders:read" as well as "orders:*"
+func ScopeMatches(granted, required string) bool {
+	if granted == required || granted == "*" {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(granted, "*")
+	return ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(required, prefix)
+}
+
+// ScopeSet is a set of granted scopes expanded with the registered implications
+type ScopeSet struct {
+	granted []string
+	scopes  map[string]struct{}
+}
+
+// NewScopeSet builds the ScopeSet of the granted scopes
+func NewScopeSet(granted ...string) ScopeSet {
+	set := ScopeSet{granted: NormalizeScopes(granted), scopes: make(map[string]struct{})}
+
+	scopeImplicationsMu.RLock()
+	defer scopeImplicationsMu.RUnlock()
+
+	queue := slices.Clone(set.granted)
+	for len(queue) > 0 {
+		scope := queue[0]
+		queue = queue[1:]
+		if _, seen := set.scopes[scope]; seen {
+			continue
+		}
+		set.scopes[scope] = struct{}{}
+
+		queue = append(queue, scopeImplications[scope]...)
+		if resource, action, ok := strings.Cut(scope, ":"); ok && action != "*" {
+			for _, implied := range scopeImplications[action] {
+				queue = append(queue, resource+":"+implied)
+			}
+		}
+	}
+	return set
+}
+
+// Has reports whether the set satisfies required, directly, through an implication or a wildcard
+func (s ScopeSet) Has(required string) bool {
+	if _, ok := s.scopes[required]; ok {
+		return true
+	}
+	for scope := range s.scopes {
+		if ScopeMatches(scope, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the required scopes the set does not satisfy, in order
+func (s ScopeSet) Missing(required ...string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !s.Has(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// Scopes returns the granted scopes, normalized and without the implied ones
+func (s ScopeSet) Scopes() []string {
+	return slices.Clone(s.granted)
+}
+
+// NormalizeScopes trims, dedupes and sorts scopes, dropping empty ones. It returns nil for no scopes
+func NormalizeScopes(scopes []string) []string {
+	var normalized []string
+	for _, scope := range scopes {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			normalized = append(normalized, scope)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
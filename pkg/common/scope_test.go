@@ -0,0 +1,112 @@
+package common
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// restoreScopeImplications restores the registered implications after the test
+func restoreScopeImplications(t *testing.T) {
+	t.Helper()
+	scopeImplicationsMu.Lock()
+	saved := maps.Clone(scopeImplications)
+	scopeImplicationsMu.Unlock()
+	t.Cleanup(func() {
+		scopeImplicationsMu.Lock()
+		defer scopeImplicationsMu.Unlock()
+		scopeImplications = saved
+	})
+}
+
+func TestScopeSetHas(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required string
+		want     bool
+	}{
+		{"exact", []string{"profile"}, "profile", true},
+		{"none granted", nil, "read", false},
+		{"admin implies write", []string{"admin"}, "write", true},
+		{"admin implies read transitively", []string{"admin"}, "read", true},
+		{"write implies read", []string{"write"}, "read", true},
+		{"read does not imply write", []string{"read"}, "write", false},
+		{"write does not imply admin", []string{"write"}, "admin", false},
+		{"prefixed admin implies prefixed read", []string{"orders:admin"}, "orders:read", true},
+		{"prefixed write implies prefixed read", []string{"orders:write"}, "orders:read", true},
+		{"prefixed read does not imply prefixed write", []string{"orders:read"}, "orders:write", false},
+		{"prefixed scope stays in its resource", []string{"orders:admin"}, "billing:read", false},
+		{"prefixed scope does not imply the bare action", []string{"orders:write"}, "read", false},
+		{"bare action does not imply prefixed scopes", []string{"admin"}, "orders:read", false},
+		{"resource wildcard", []string{"orders:*"}, "orders:admin", true},
+		{"resource wildcard stays in its resource", []string{"orders:*"}, "billing:read", false},
+		{"global wildcard", []string{"*"}, "billing:admin", true},
+		{"untrimmed grant", []string{" write "}, "read", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewScopeSet(tt.granted...).Has(tt.required); got != tt.want {
+				t.Errorf("NewScopeSet(%q).Has(%q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefineScopeImplication(t *testing.T) {
+	restoreScopeImplications(t)
+	before := NewScopeSet("owner")
+
+	DefineScopeImplication("owner", "admin", "billing")
+	DefineScopeImplication("owner", "admin", "owner")
+	if got := scopeImplications["owner"]; !slices.Equal(got, []string{"admin", "billing"}) {
+		t.Errorf("owner implications = %v, want admin and billing once, without a self implication", got)
+	}
+
+	set := NewScopeSet("owner", "reports:owner")
+	for _, scope := range []string{"owner", "admin", "write", "read", "billing", "reports:admin", "reports:read", "reports:billing"} {
+		if !set.Has(scope) {
+			t.Errorf("owner does not imply %q", scope)
+		}
+	}
+	if before.Has("admin") {
+		t.Error("a ScopeSet built before the registration got the new implication")
+	}
+
+	DefineScopeImplication("read", "admin")
+	if !NewScopeSet("read").Has("admin") || !NewScopeSet("admin").Has("read") {
+		t.Error("cyclic implications are not followed both ways")
+	}
+}
+
+func TestResetScopeImplications(t *testing.T) {
+	restoreScopeImplications(t)
+	ResetScopeImplications()
+
+	set := NewScopeSet("admin", "orders:*")
+	if set.Has("read") || !set.Has("admin") || !set.Has("orders:read") {
+		t.Error("after ResetScopeImplications scopes must only match themselves and wildcards")
+	}
+}
+
+func TestScopeSetMissing(t *testing.T) {
+	set := NewScopeSet("orders:write", "profile")
+	if got := set.Missing("orders:read", "admin", "profile", "orders:admin"); !slices.Equal(got, []string{"admin", "orders:admin"}) {
+		t.Errorf("Missing = %v, want the unsatisfied scopes in order", got)
+	}
+	if got := set.Missing("orders:read"); got != nil {
+		t.Errorf("Missing = %v, want nil", got)
+	}
+	if got := set.Scopes(); !slices.Equal(got, []string{"orders:write", "profile"}) {
+		t.Errorf("Scopes = %v, want the granted scopes without the implied ones", got)
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	if got := NormalizeScopes([]string{"write", " read", "", "write ", "admin", "  "}); !slices.Equal(got, []string{"admin", "read", "write"}) {
+		t.Errorf("NormalizeScopes = %v, want trimmed, deduped and sorted scopes", got)
+	}
+	if got := NormalizeScopes([]string{"", " "}); got != nil {
+		t.Errorf("NormalizeScopes of empty scopes = %v, want nil", got)
+	}
+}
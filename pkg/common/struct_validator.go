@@ -0,0 +1,654 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ValidateTagName is the struct tag read by ValidateStruct, e.g. `validate:"required,min=3,max=50"`
+const ValidateTagName = "validate"
+
+// tagRuleFunc checks value against the parameter of a tag rule (the text after '=').
+// It returns whether the value passes, and otherwise the i18n message key and params of the error
+type tagRuleFunc func(value reflect.Value, param string) (bool, string, map[string]any)
+
+// tagRules are the rules usable in validate tags
+var tagRules = map[string]tagRuleFunc{
+	"required": ruleRequired,
+	"min":      ruleMin,
+	"max":      ruleMax,
+	"email":    ruleEmail,
+	"url":      ruleURL,
+	"uuid":     ruleUUID,
+	"oneof":    ruleOneOf,
+	"oneofci":  ruleOneOfIgnoreCase,
+	"alpha":    ruleCharset(isAlpha, MsgValidationAlpha),
+	"alphanum": ruleCharset(isAlphaNumeric, MsgValidationAlphaNum),
+	"digits":   ruleCharset(isNumeric, MsgValidationNumeric),
+	"numeric":  ruleNumericString,
+	"decimal":  ruleDecimal,
+}
+
+var (
+	customRulesMu sync.RWMutex
+	// customRules are the rules added with RegisterValidation
+	customRules = map[string]tagRuleFunc{}
+)
+
+// RegisterValidation adds a custom tag rule, e.g. RegisterValidation("slug", isSlug, "validation.slug") for
+// `validate:"slug=strict"`. fn receives the field value (pointers dereferenced, nil pointers are skipped)
+// and the rule parameter, interpolated as {param} in the messageKey message. Built-in and already
+// registered names are rejected. It is safe to call concurrently with ValidateStruct
+func RegisterValidation(name string, fn func(value any, param string) bool, messageKey string) error {
+	if name == "" || strings.ContainsAny(name, ",= ") {
+		return fmt.Errorf("invalid validation rule name %q", name)
+	}
+	if fn == nil {
+		return errors.New("validation rule function must not be nil")
+	}
+	if _, ok := tagRules[name]; ok || isReservedRule(name) {
+		return fmt.Errorf("validation rule %q is built in", name)
+	}
+
+	customRulesMu.Lock()
+	defer customRulesMu.Unlock()
+	if _, ok := customRules[name]; ok {
+		return fmt.Errorf("validation rule %q is already registered", name)
+	}
+	customRules[name] = func(value reflect.Value, param string) (bool, string, map[string]any) {
+		if !value.CanInterface() {
+			return true, "", nil
+		}
+		return fn(value.Interface(), param), messageKey, map[string]any{"param": param}
+	}
+	return nil
+}
+
+// MustRegisterValidation is like RegisterValidation but panics on error, for package initialization
+func MustRegisterValidation(name string, fn func(value any, param string) bool, messageKey string) {
+	if err := RegisterValidation(name, fn, messageKey); err != nil {
+		panic(err)
+	}
+}
+
+// lookupTagRule returns the built-in or custom rule registered under name
+func lookupTagRule(name string) (tagRuleFunc, bool) {
+	if rule, ok := tagRules[name]; ok {
+		return rule, true
+	}
+
+	customRulesMu.RLock()
+	defer customRulesMu.RUnlock()
+	rule, ok := customRules[name]
+	return rule, ok
+}
+
+// isReservedRule reports whether name is handled by the engine itself rather than the rule tables
+func isReservedRule(name string) bool {
+	if name == "omitempty" {
+		return true
+	}
+	if _, ok := crossFieldRules[name]; ok {
+		return true
+	}
+	_, ok := conditionalRules[name]
+	return ok
+}
+
+// fieldScope is the struct holding the validated field, used by cross-field rules to read its siblings
+type fieldScope struct {
+	parent reflect.Value
+	prefix string
+}
+
+// crossFieldRuleFunc checks value against sibling fields named in the rule parameter
+type crossFieldRuleFunc func(value reflect.Value, scope fieldScope, param string) (bool, string, map[string]any)
+
+// conditionalRuleFunc reports whether the field is required, with the i18n message key and params used when it is missing
+type conditionalRuleFunc func(scope fieldScope, param string) (bool, string, map[string]any)
+
+// crossFieldRules are the tag rules comparing a field with a sibling field
+var crossFieldRules = map[string]crossFieldRuleFunc{
+	"eqfield": ruleEqualsField,
+}
+
+// conditionalRules are the tag rules making a field required depending on its siblings.
+// They act like required when their condition holds; otherwise an empty field skips its remaining rules
+var conditionalRules = map[string]conditionalRuleFunc{
+	"required_if":      ruleRequiredIf,
+	"required_without": ruleRequiredWithout,
+}
+
+// fieldSpec is a parsed struct field of a validated type
+type fieldSpec struct {
+	index  int
+	name   string
+	goName string
+	rules  []tagRuleSpec
+	// sensitive is set by the sensitive:"true" tag, the value is then redacted in error details
+	sensitive bool
+	// inline is set for embedded structs without json name, whose fields are validated at the parent level
+	inline bool
+}
+
+// tagRuleSpec is a rule of a validate tag with its parameter
+type tagRuleSpec struct {
+	name  string
+	param string
+}
+
+var (
+	// structSpecs caches the parsed fields of each validated struct type
+	structSpecs sync.Map
+	// unknownRulesLogged remembers the unknown rules already reported
+	unknownRulesLogged sync.Map
+	timeType           = reflect.TypeOf(time.Time{})
+)
+
+// ValidateStruct validates s using its validate struct tags and returns one ErrorDetail per failed rule.
+// Field names come from the json tag, nested structs and slices of structs are reported with dotted
+// and indexed paths (e.g. "items[0].name"), and messages are translated with the locale of ctx.
+// Supported rules: required, omitempty, min, max, email, url, uuid, oneof and oneofci (space separated values),
+// alpha, alphanum, digits, numeric (decimal string), decimal=10:2 (precision), and the cross-field rules
+// eqfield=other, required_if=other value and required_without=other... (field names as in json)
+func ValidateStruct(ctx context.Context, s any) ValidationResult {
+	details := translateFieldFailures(ctx, collectFieldFailures(s))
+	return ValidationResult{
+		IsValid: len(details) == 0,
+		Errors:  details,
+	}
+}
+
+// fieldFailure is a failed rule of a field, kept untranslated so it can be rendered in any locale
+type fieldFailure struct {
+	field      string
+	value      string
+	messageKey string
+	params     map[string]any
+}
+
+// collectFieldFailures runs the validate tags of s and returns the failed rules
+func collectFieldFailures(s any) []fieldFailure {
+	var failures []fieldFailure
+	validateNested(reflect.ValueOf(s), "", make(map[visitedPointer]bool), &failures)
+	return failures
+}
+
+// visitedPointer identifies a pointer being traversed, the type is kept because a struct and
+// its first field share the same address
+type visitedPointer struct {
+	address uintptr
+	typ     reflect.Type
+}
+
+// translateFieldFailures renders failed rules as error details in the locale of ctx
+func translateFieldFailures(ctx context.Context, failures []fieldFailure) []ErrorDetail {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	details := make([]ErrorDetail, 0, len(failures))
+	for _, failure := range failures {
+		details = append(details, ErrorDetail{
+			Field:   failure.field,
+			Message: TfWithContext(ctx, failure.messageKey, failure.params),
+			Value:   failure.value,
+		})
+	}
+	return details
+}
+
+// validateStructValue validates the fields of a struct value, prefix is the path of the struct itself.
+// ancestors holds the pointers being traversed, so a pointer back to one of them (e.g. Self *Req) is not walked again
+func validateStructValue(value reflect.Value, prefix string, ancestors map[visitedPointer]bool, failures *[]fieldFailure) {
+	for _, spec := range fieldSpecsOf(value.Type()) {
+		field := value.Field(spec.index)
+		if spec.inline {
+			if field.Kind() != reflect.Ptr {
+				validateStructValue(field, prefix, ancestors, failures)
+				continue
+			}
+			pointer := visitedPointer{address: field.Pointer(), typ: field.Type()}
+			if field.IsNil() || ancestors[pointer] {
+				continue
+			}
+			ancestors[pointer] = true
+			validateStructValue(field.Elem(), prefix, ancestors, failures)
+			delete(ancestors, pointer)
+			continue
+		}
+
+		path := joinFieldPath(prefix, spec.name)
+		if !validateField(field, path, fieldScope{parent: value, prefix: prefix}, spec, failures) {
+			continue
+		}
+		validateNested(field, path, ancestors, failures)
+	}
+}
+
+// validateField applies the rules of a field in tag order, returning false when validation of the field stopped early.
+// required and conditional rules stop at their failure, so they should come first in the tag
+func validateField(field reflect.Value, path string, scope fieldScope, spec fieldSpec, failures *[]fieldFailure) bool {
+	addFailure := func(messageKey string, params map[string]any) {
+		if params == nil {
+			params = make(map[string]any, 1)
+		}
+		params["field"] = path
+		value := RedactValue(path, fieldValueString(field))
+		if spec.sensitive && value != "" {
+			value = RedactedValue
+		}
+		*failures = append(*failures, fieldFailure{
+			field:      path,
+			value:      value,
+			messageKey: messageKey,
+			params:     params,
+		})
+	}
+
+	for _, rule := range spec.rules {
+		if rule.name == "omitempty" {
+			if isZeroValue(field) {
+				return false
+			}
+			continue
+		}
+
+		if condition, ok := conditionalRules[rule.name]; ok {
+			required, messageKey, params := condition(scope, rule.param)
+			if !isZeroValue(field) {
+				continue
+			}
+			if required {
+				addFailure(messageKey, params)
+			}
+			return false
+		}
+
+		if check, ok := crossFieldRules[rule.name]; ok {
+			if target := indirectValue(field); target.IsValid() {
+				if passed, messageKey, params := check(target, scope, rule.param); !passed {
+					addFailure(messageKey, params)
+				}
+			}
+			continue
+		}
+
+		check, ok := lookupTagRule(rule.name)
+		if !ok {
+			if _, logged := unknownRulesLogged.LoadOrStore(rule.name, struct{}{}); !logged {
+				logrus.Warnf("validation: unknown rule %q on field %q", rule.name, path)
+			}
+			continue
+		}
+
+		target := field
+		if rule.name != "required" {
+			target = indirectValue(field)
+			if !target.IsValid() {
+				// Only required applies to nil pointers
+				continue
+			}
+		}
+
+		if passed, messageKey, params := check(target, rule.param); !passed {
+			addFailure(messageKey, params)
+			if rule.name == "required" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateNested traverses struct and slice values of a field, skipping pointers already being traversed
+func validateNested(field reflect.Value, path string, ancestors map[visitedPointer]bool, failures *[]fieldFailure) {
+	for field.Kind() == reflect.Interface && !field.IsNil() {
+		field = field.Elem()
+	}
+	if field.Kind() == reflect.Ptr && !field.IsNil() {
+		pointer := visitedPointer{address: field.Pointer(), typ: field.Type()}
+		if ancestors[pointer] {
+			return
+		}
+		ancestors[pointer] = true
+		defer delete(ancestors, pointer)
+	}
+
+	field = indirectValue(field)
+	if !field.IsValid() {
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.Struct:
+		if field.Type() != timeType {
+			validateStructValue(field, path, ancestors, failures)
+		}
+	case reflect.Slice, reflect.Array:
+		validateSliceValue(field, path, ancestors, failures)
+	}
+}
+
+// validateSliceValue validates the struct elements of a slice, reported as path[index]
+func validateSliceValue(value reflect.Value, path string, ancestors map[visitedPointer]bool, failures *[]fieldFailure) {
+	for index := 0; index < value.Len(); index++ {
+		validateNested(value.Index(index), fmt.Sprintf("%s[%d]", path, index), ancestors, failures)
+	}
+}
+
+// fieldSpecsOf parses and caches the validated fields of a struct type
+func fieldSpecsOf(structType reflect.Type) []fieldSpec {
+	if cached, ok := structSpecs.Load(structType); ok {
+		return cached.([]fieldSpec)
+	}
+
+	var specs []fieldSpec
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, hasJSONName := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && !hasJSONName && fieldType.Kind() == reflect.Struct {
+			specs = append(specs, fieldSpec{index: index, inline: true})
+			continue
+		}
+
+		specs = append(specs, fieldSpec{
+			index:     index,
+			name:      name,
+			goName:    field.Name,
+			rules:     parseValidateTag(field.Tag.Get(ValidateTagName)),
+			sensitive: field.Tag.Get("sensitive") == "true",
+		})
+	}
+
+	structSpecs.Store(structType, specs)
+	return specs
+}
+
+// jsonFieldName returns the json name of a field, or its Go name when the json tag has none
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "-", true
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, false
+}
+
+// parseValidateTag splits a validate tag into its rules, e.g. "required,min=3" or "oneof=a b"
+func parseValidateTag(tag string) []tagRuleSpec {
+	if tag == "" || tag == "-" {
+		return nil
+	}
+
+	var rules []tagRuleSpec
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(part, "=")
+		rules = append(rules, tagRuleSpec{name: strings.TrimSpace(name), param: strings.TrimSpace(param)})
+	}
+	return rules
+}
+
+// joinFieldPath appends a field name to the path of its parent
+func joinFieldPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// indirectValue dereferences pointers and interfaces, returning an invalid value for nil
+func indirectValue(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// isZeroValue reports whether a field is empty for required and omitempty: blank strings,
+// empty collections, nil pointers and zero values
+func isZeroValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.Invalid:
+		return true
+	default:
+		return value.IsZero()
+	}
+}
+
+// fieldValueString returns the value reported in ErrorDetail, empty for composite values
+func fieldValueString(value reflect.Value) string {
+	value = indirectValue(value)
+	if !value.IsValid() {
+		return ""
+	}
+
+	switch value.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Map, reflect.Array:
+		if value.Type() == timeType {
+			return value.Interface().(time.Time).Format(time.RFC3339)
+		}
+		return ""
+	default:
+		if !value.CanInterface() {
+			return ""
+		}
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// numericValue returns the value of numeric kinds as float64
+func numericValue(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+// lengthValue returns the length of strings (in characters) and collections
+func lengthValue(value reflect.Value) (int, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len(), true
+	}
+	return 0, false
+}
+
+func ruleRequired(value reflect.Value, _ string) (bool, string, map[string]any) {
+	return !isZeroValue(value), MsgValidationRequired, nil
+}
+
+func ruleMin(value reflect.Value, param string) (bool, string, map[string]any) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return true, "", nil
+	}
+	if length, ok := lengthValue(value); ok {
+		return float64(length) >= limit, MsgValidationMinLength, map[string]any{"min": param}
+	}
+	if number, ok := numericValue(value); ok {
+		return number >= limit, MsgValidationMinValue, map[string]any{"min": param}
+	}
+	return true, "", nil
+}
+
+func ruleMax(value reflect.Value, param string) (bool, string, map[string]any) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return true, "", nil
+	}
+	if length, ok := lengthValue(value); ok {
+		return float64(length) <= limit, MsgValidationMaxLength, map[string]any{"max": param}
+	}
+	if number, ok := numericValue(value); ok {
+		return number <= limit, MsgValidationMaxValue, map[string]any{"max": param}
+	}
+	return true, "", nil
+}
+
+func ruleEmail(value reflect.Value, _ string) (bool, string, map[string]any) {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return true, "", nil
+	}
+	return IsValidEmail(value.String()), MsgValidationEmail, nil
+}
+
+// ruleURL accepts http and https URLs, or the space separated schemes of its parameter (url=ftp sftp)
+func ruleURL(value reflect.Value, param string) (bool, string, map[string]any) {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return true, "", nil
+	}
+	schemes := DefaultURLSchemes
+	if param != "" {
+		schemes = strings.Fields(param)
+	}
+	return IsValidURL(value.String(), schemes...), MsgValidationURL, map[string]any{"schemes": strings.Join(schemes, ", ")}
+}
+
+func ruleUUID(value reflect.Value, _ string) (bool, string, map[string]any) {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return true, "", nil
+	}
+	return IsValidUUID(value.String()), MsgValidationUUID, nil
+}
+
+func ruleOneOf(value reflect.Value, param string) (bool, string, map[string]any) {
+	return checkOneOf(value, param, false)
+}
+
+func ruleOneOfIgnoreCase(value reflect.Value, param string) (bool, string, map[string]any) {
+	return checkOneOf(value, param, true)
+}
+
+// checkOneOf compares the value with the space separated options of param
+func checkOneOf(value reflect.Value, param string, ignoreCase bool) (bool, string, map[string]any) {
+	options := strings.Fields(param)
+	if len(options) == 0 || !value.CanInterface() {
+		return true, "", nil
+	}
+	return isOneOf(fmt.Sprint(value.Interface()), options, ignoreCase), MsgValidationOneOf, map[string]any{"values": strings.Join(options, ", ")}
+}
+
+// ruleCharset builds a rule checking every character of a string, empty strings pass
+func ruleCharset(allowed func(string) bool, messageKey string) tagRuleFunc {
+	return func(value reflect.Value, _ string) (bool, string, map[string]any) {
+		if value.Kind() != reflect.String || value.String() == "" {
+			return true, "", nil
+		}
+		return allowed(value.String()), messageKey, nil
+	}
+}
+
+// siblingField finds a field of the scope struct by json or Go name, looking into inline embedded structs
+func siblingField(parent reflect.Value, name string) (reflect.Value, bool) {
+	for _, spec := range fieldSpecsOf(parent.Type()) {
+		field := parent.Field(spec.index)
+		if spec.inline {
+			if field = indirectValue(field); field.IsValid() {
+				if found, ok := siblingField(field, name); ok {
+					return found, true
+				}
+			}
+			continue
+		}
+		if spec.name == name || spec.goName == name {
+			return field, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// ruleEqualsField requires the value to equal the sibling named by param
+func ruleEqualsField(value reflect.Value, scope fieldScope, param string) (bool, string, map[string]any) {
+	other, ok := siblingField(scope.parent, param)
+	if !ok {
+		logrus.Warnf("validation: eqfield references unknown field %q", param)
+		return true, "", nil
+	}
+
+	params := map[string]any{"other": joinFieldPath(scope.prefix, param)}
+	other = indirectValue(other)
+	if !other.IsValid() || !value.CanInterface() || !other.CanInterface() {
+		return false, MsgValidationEqualsField, params
+	}
+	return reflect.DeepEqual(value.Interface(), other.Interface()), MsgValidationEqualsField, params
+}
+
+// ruleRequiredIf requires the field when every "other value" pair of param matches, e.g. required_if=type business
+func ruleRequiredIf(scope fieldScope, param string) (bool, string, map[string]any) {
+	parts := strings.Fields(param)
+	if len(parts) == 0 || len(parts)%2 != 0 {
+		logrus.Warnf("validation: required_if expects field/value pairs, got %q", param)
+		return false, "", nil
+	}
+
+	var others []string
+	for index := 0; index < len(parts); index += 2 {
+		other, ok := siblingField(scope.parent, parts[index])
+		if !ok {
+			return false, "", nil
+		}
+		other = indirectValue(other)
+		if !other.IsValid() || !other.CanInterface() || fmt.Sprint(other.Interface()) != parts[index+1] {
+			return false, "", nil
+		}
+		others = append(others, joinFieldPath(scope.prefix, parts[index]))
+	}
+	return true, MsgValidationRequiredIf, map[string]any{"other": strings.Join(others, ", "), "value": parts[1]}
+}
+
+// ruleRequiredWithout requires the field when all the siblings named in param are empty, e.g. required_without=phone
+func ruleRequiredWithout(scope fieldScope, param string) (bool, string, map[string]any) {
+	names := strings.Fields(param)
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		if other, ok := siblingField(scope.parent, name); ok && !isZeroValue(other) {
+			return false, "", nil
+		}
+		fields = append(fields, joinFieldPath(scope.prefix, name))
+	}
+	return len(names) > 0, MsgValidationRequiredOne, map[string]any{"fields": strings.Join(fields, ", ")}
+}
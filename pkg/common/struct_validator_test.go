@@ -0,0 +1,393 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testOrderItem struct {
+	ProductID string `json:"product_id" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"min=1,max=100"`
+}
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testOrderRequest struct {
+	Email    string          `json:"email" validate:"required,email"`
+	Note     string          `json:"note" validate:"omitempty,max=5"`
+	Type     string          `json:"type" validate:"oneof=retail wholesale"`
+	Items    []testOrderItem `json:"items" validate:"min=1"`
+	Address  *testAddress    `json:"address"`
+	Website  string          `json:"website,omitempty" validate:"omitempty,url"`
+	NoJSON   string          `validate:"required"`
+	Ignored  string          `json:"-" validate:"required"`
+	internal string          `validate:"required"`
+	At       time.Time       `json:"at"`
+}
+
+// failedFields returns the field and message key of each failure, in order
+func failedFields(s any) map[string]string {
+	fields := make(map[string]string)
+	for _, failure := range collectFieldFailures(s) {
+		fields[failure.field] = failure.messageKey
+	}
+	return fields
+}
+
+func TestValidateStructRules(t *testing.T) {
+	valid := func() testOrderRequest {
+		return testOrderRequest{
+			Email:  "buyer@example.com",
+			Type:   "retail",
+			Items:  []testOrderItem{{ProductID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Quantity: 1}},
+			NoJSON: "set",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*testOrderRequest)
+		want   map[string]string
+	}{
+		{"valid", func(r *testOrderRequest) {}, map[string]string{}},
+		{"required", func(r *testOrderRequest) { r.Email = "  " }, map[string]string{"email": MsgValidationRequired}},
+		{"email", func(r *testOrderRequest) { r.Email = "not-an-email" }, map[string]string{"email": MsgValidationEmail}},
+		{"omitempty skips empty", func(r *testOrderRequest) { r.Note = "" }, map[string]string{}},
+		{"max length in runes", func(r *testOrderRequest) { r.Note = "ẫẫẫẫẫ" }, map[string]string{}},
+		{"max length", func(r *testOrderRequest) { r.Note = "toolong" }, map[string]string{"note": MsgValidationMaxLength}},
+		{"oneof", func(r *testOrderRequest) { r.Type = "vip" }, map[string]string{"type": MsgValidationOneOf}},
+		{"min collection", func(r *testOrderRequest) { r.Items = nil }, map[string]string{"items": MsgValidationMinLength}},
+		{"go name without json tag", func(r *testOrderRequest) { r.NoJSON = "" }, map[string]string{"NoJSON": MsgValidationRequired}},
+		{"url", func(r *testOrderRequest) { r.Website = "notaurl" }, map[string]string{"website": MsgValidationURL}},
+		{
+			"indexed slice paths",
+			func(r *testOrderRequest) {
+				r.Items = append(r.Items, testOrderItem{ProductID: "x", Quantity: 0}, testOrderItem{Quantity: 101})
+			},
+			map[string]string{
+				"items[1].product_id": MsgValidationUUID,
+				"items[1].quantity":   MsgValidationMinValue,
+				"items[2].product_id": MsgValidationRequired,
+				"items[2].quantity":   MsgValidationMaxValue,
+			},
+		},
+		{"nested pointer", func(r *testOrderRequest) { r.Address = &testAddress{} }, map[string]string{"address.city": MsgValidationRequired}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			got := failedFields(&req)
+			if len(got) != len(tt.want) {
+				t.Fatalf("failures = %v, want %v", got, tt.want)
+			}
+			for field, key := range tt.want {
+				if got[field] != key {
+					t.Errorf("failure of %q = %q, want %q (all: %v)", field, got[field], key, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateStructTranslatesMessages(t *testing.T) {
+	manager := newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"max_length": "{field} tối đa {max} ký tự"},
+	})
+	manager.derived = map[string]*I18nManager{
+		"en": newTestI18n("en", map[string]any{
+			"validation": map[string]any{"max_length": "{field} must be at most {max} characters"},
+		}),
+	}
+	useGlobalI18n(t, manager)
+
+	req := testOrderRequest{Email: "a@example.com", Type: "retail", NoJSON: "x", Note: "toolong",
+		Items: []testOrderItem{{ProductID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Quantity: 1}}}
+
+	result := ValidateStruct(context.Background(), &req)
+	if result.IsValid || len(result.Errors) != 1 {
+		t.Fatalf("result = %+v, want one error", result)
+	}
+	detail := result.Errors[0]
+	if detail.Field != "note" || detail.Message != "note tối đa 5 ký tự" || detail.Value != "toolong" {
+		t.Errorf("detail = %+v", detail)
+	}
+
+	result = ValidateStruct(SetLocaleInContext(context.Background(), "en"), &req)
+	if got := result.Errors[0].Message; got != "note must be at most 5 characters" {
+		t.Errorf("en message = %q", got)
+	}
+}
+
+func TestValidateStructInputs(t *testing.T) {
+	if result := ValidateStruct(context.Background(), nil); !result.IsValid {
+		t.Errorf("nil = %+v, want valid", result)
+	}
+	var nilRequest *testOrderRequest
+	if result := ValidateStruct(context.Background(), nilRequest); !result.IsValid {
+		t.Errorf("nil pointer = %+v, want valid", result)
+	}
+	if result := ValidateStruct(context.Background(), "text"); !result.IsValid {
+		t.Errorf("non-struct = %+v, want valid", result)
+	}
+
+	items := []testOrderItem{{ProductID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Quantity: 1}, {Quantity: 1}}
+	if got := failedFields(items); len(got) != 1 || got["[1].product_id"] != MsgValidationRequired {
+		t.Errorf("top-level slice failures = %v", got)
+	}
+}
+
+type testTreeNode struct {
+	Name     string          `json:"name" validate:"required"`
+	Self     *testTreeNode   `json:"self"`
+	Parent   *testTreeNode   `json:"parent"`
+	Children []*testTreeNode `json:"children"`
+	Any      any             `json:"any"`
+}
+
+func TestValidateStructCycles(t *testing.T) {
+	root := &testTreeNode{Name: "root"}
+	root.Self = root
+	child := &testTreeNode{Parent: root}
+	child.Any = child
+	root.Children = []*testTreeNode{child, child}
+
+	got := failedFields(root)
+	want := map[string]string{
+		"children[0].name": MsgValidationRequired,
+		"children[1].name": MsgValidationRequired,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("failures = %v, want %v", got, want)
+	}
+	for field := range want {
+		if _, ok := got[field]; !ok {
+			t.Errorf("failures = %v, want %q reported", got, field)
+		}
+	}
+
+	// The embedded type must be exported to be walked
+	type Embedded struct {
+		*Embedded
+		Name string `json:"name" validate:"required"`
+	}
+	embedded := &Embedded{}
+	embedded.Embedded = embedded
+	if got := failedFields(embedded); len(got) != 1 || got["name"] != MsgValidationRequired {
+		t.Errorf("embedded cycle failures = %v", got)
+	}
+}
+
+type testBookingRequest struct {
+	Type        string     `json:"type"`
+	CompanyName string     `json:"company_name" validate:"required_if=type business,max=5"`
+	StartDate   *time.Time `json:"start_date"`
+	EndDate     *time.Time `json:"end_date" validate:"required_without=start_date"`
+	Password    string     `json:"password"`
+	Confirm     string     `json:"password_confirmation" validate:"eqfield=password"`
+	Email       string     `json:"email" validate:"required_without=phone"`
+	Phone       string     `json:"phone"`
+}
+
+func TestCrossFieldTags(t *testing.T) {
+	now := time.Now()
+	valid := func() testBookingRequest {
+		return testBookingRequest{Type: "personal", StartDate: &now, Password: "p", Confirm: "p", Phone: "0901"}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*testBookingRequest)
+		want   map[string]string
+	}{
+		{"valid", func(r *testBookingRequest) {}, map[string]string{}},
+		{"required_if holds", func(r *testBookingRequest) { r.Type = "business" }, map[string]string{"company_name": MsgValidationRequiredIf}},
+		{"required_if holds and set", func(r *testBookingRequest) { r.Type = "business"; r.CompanyName = "Acme" }, map[string]string{}},
+		{"rules after required_if still run", func(r *testBookingRequest) { r.Type = "business"; r.CompanyName = "Acme Corp" }, map[string]string{"company_name": MsgValidationMaxLength}},
+		{"required_if does not hold", func(r *testBookingRequest) { r.CompanyName = "" }, map[string]string{}},
+		{"required_without missing both", func(r *testBookingRequest) { r.StartDate = nil }, map[string]string{"end_date": MsgValidationRequiredOne}},
+		{"required_without other set", func(r *testBookingRequest) { r.StartDate = nil; r.EndDate = &now }, map[string]string{}},
+		{"eqfield mismatch", func(r *testBookingRequest) { r.Confirm = "q" }, map[string]string{"password_confirmation": MsgValidationEqualsField}},
+		{"eqfield both empty", func(r *testBookingRequest) { r.Password = ""; r.Confirm = "" }, map[string]string{}},
+		{"email or phone", func(r *testBookingRequest) { r.Phone = "" }, map[string]string{"email": MsgValidationRequiredOne}},
+		{"email instead of phone", func(r *testBookingRequest) { r.Phone = ""; r.Email = "a@example.com" }, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			got := failedFields(&req)
+			if len(got) != len(tt.want) {
+				t.Fatalf("failures = %v, want %v", got, tt.want)
+			}
+			for field, key := range tt.want {
+				if got[field] != key {
+					t.Errorf("failure of %q = %q, want %q", field, got[field], key)
+				}
+			}
+		})
+	}
+}
+
+func TestCrossFieldTagsNested(t *testing.T) {
+	type account struct {
+		Password string `json:"password"`
+		Confirm  string `json:"confirm" validate:"eqfield=password"`
+	}
+	type request struct {
+		Accounts []account `json:"accounts"`
+	}
+
+	failures := collectFieldFailures(&request{Accounts: []account{{"a", "a"}, {"a", "b"}}})
+	if len(failures) != 1 || failures[0].field != "accounts[1].confirm" || failures[0].params["other"] != "accounts[1].password" {
+		t.Errorf("failures = %+v, want the sibling resolved under the same element", failures)
+	}
+}
+
+func TestValidateStructTwoLevelPaths(t *testing.T) {
+	type shipment struct {
+		Address testAddress     `json:"address"`
+		Items   []testOrderItem `json:"items"`
+	}
+	type request struct {
+		Shipments []shipment `json:"shipments"`
+	}
+
+	req := request{Shipments: []shipment{
+		{Address: testAddress{City: "Hà Nội"}, Items: []testOrderItem{{ProductID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Quantity: 1}}},
+		{Items: []testOrderItem{{ProductID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Quantity: 1}, {ProductID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"}}},
+	}}
+
+	got := failedFields(&req)
+	want := map[string]string{
+		"shipments[1].address.city":      MsgValidationRequired,
+		"shipments[1].items[1].quantity": MsgValidationMinValue,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("failures = %v, want %v", got, want)
+	}
+	for field, key := range want {
+		if got[field] != key {
+			t.Errorf("failure of %q = %q, want %q", field, got[field], key)
+		}
+	}
+}
+
+// useValidation registers a custom rule for the duration of the test
+func useValidation(t *testing.T, name string, fn func(value any, param string) bool, messageKey string) {
+	t.Helper()
+	if err := RegisterValidation(name, fn, messageKey); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		customRulesMu.Lock()
+		delete(customRules, name)
+		customRulesMu.Unlock()
+	})
+}
+
+func TestRegisterValidation(t *testing.T) {
+	var params []string
+	useValidation(t, "test_slug", func(value any, param string) bool {
+		params = append(params, param)
+		s, _ := value.(string)
+		if param == "strict" {
+			return s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyz-") == ""
+		}
+		return !strings.Contains(s, " ")
+	}, "validation.test_slug")
+	useGlobalI18n(t, newTestI18n("en", map[string]any{
+		"validation": map[string]any{"test_slug": "{field} must be a {param} slug"},
+	}))
+
+	type request struct {
+		Slug  string  `json:"slug" validate:"test_slug=strict"`
+		Loose *string `json:"loose" validate:"test_slug"`
+	}
+
+	loose := "Has Space"
+	result := ValidateStruct(context.Background(), &request{Slug: "Not_Slug", Loose: &loose})
+	if len(result.Errors) != 2 {
+		t.Fatalf("errors = %+v, want both custom rules to fail", result.Errors)
+	}
+	if detail := result.Errors[0]; detail.Field != "slug" || detail.Message != "slug must be a strict slug" {
+		t.Errorf("slug detail = %+v, want the param interpolated", detail)
+	}
+	if result.Errors[1].Field != "loose" {
+		t.Errorf("loose detail = %+v, want the dereferenced pointer validated", result.Errors[1])
+	}
+	if len(params) != 2 || params[0] != "strict" || params[1] != "" {
+		t.Errorf("params = %q, want the tag params passed through", params)
+	}
+
+	params = nil
+	if result := ValidateStruct(context.Background(), &request{Slug: "good-slug"}); !result.IsValid {
+		t.Errorf("valid request failed: %+v", result.Errors)
+	}
+	if len(params) != 1 {
+		t.Errorf("rule called %d times, want nil pointers skipped", len(params))
+	}
+}
+
+func TestRegisterValidationRejects(t *testing.T) {
+	useValidation(t, "test_taken", func(any, string) bool { return true }, "validation.test")
+	ok := func(any, string) bool { return true }
+
+	tests := []struct {
+		name string
+		rule string
+		fn   func(any, string) bool
+	}{
+		{"built in", "email", ok},
+		{"reserved", "omitempty", ok},
+		{"cross field", "eqfield", ok},
+		{"already registered", "test_taken", ok},
+		{"empty name", "", ok},
+		{"name with separator", "a,b", ok},
+		{"nil function", "test_nil", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RegisterValidation(tt.rule, tt.fn, "validation.test"); err == nil {
+				t.Errorf("RegisterValidation(%q) succeeded", tt.rule)
+			}
+		})
+	}
+}
+
+func TestRegisterValidationConcurrent(t *testing.T) {
+	type request struct {
+		Code string `json:"code" validate:"test_concurrent_0"`
+	}
+	useGlobalI18n(t, newTestI18n("en", map[string]any{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("test_concurrent_%d", i)
+		t.Cleanup(func() {
+			customRulesMu.Lock()
+			delete(customRules, name)
+			customRulesMu.Unlock()
+		})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = RegisterValidation(name, func(any, string) bool { return false }, "validation.test")
+		}()
+		go func() {
+			defer wg.Done()
+			ValidateStruct(context.Background(), &request{Code: "x"})
+		}()
+	}
+	wg.Wait()
+
+	if result := ValidateStruct(context.Background(), &request{Code: "x"}); result.IsValid {
+		t.Error("rule registered concurrently does not fire")
+	}
+}
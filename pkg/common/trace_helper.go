@@ -0,0 +1,62 @@
+package common
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderTraceID is the header carrying the trace id when no span is active
+const HeaderTraceID = "X-Trace-Id"
+
+// HeaderRequestID is the header carrying the request id set by the RequestID middleware
+const HeaderRequestID = "X-Request-Id"
+
+// TraceIDFromContext returns the trace id of the active span in context, or "" if there is none
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// TraceIDFromRequest returns the trace id of the active span in the request context, the id every log and
+// response uses. It falls back to the X-Trace-Id then X-Request-Id headers when no span exists, then to the
+// request id of the context set by the RequestID middleware. Header ids must pass IsValidRequestID
+func TraceIDFromRequest(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	if traceID := TraceIDFromContext(req.Context()); traceID != "" {
+		return traceID
+	}
+	for _, header := range []string{HeaderTraceID, HeaderRequestID} {
+		if id := req.Header.Get(header); IsValidRequestID(id) {
+			return id
+		}
+	}
+	requestID, _ := RequestID(req.Context())
+	return requestID
+}
+
+// maxRequestIDLength bounds the ids accepted from clients
+const maxRequestIDLength = 128
+
+// IsValidRequestID accepts non empty printable ASCII ids of at most 128 bytes, so client ids cannot inject
+// into logs
+func IsValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
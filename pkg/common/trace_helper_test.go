@@ -0,0 +1,135 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// withTestSpan returns ctx carrying a sampled remote span context of testTraceID
+func withTestSpan(t *testing.T, ctx context.Context) context.Context {
+	t.Helper()
+	traceID, err := trace.TraceIDFromHex(testTraceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+func TestTraceIDFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		span    bool
+		headers map[string]string
+		reqID   string
+		want    string
+	}{
+		{name: "span", span: true, headers: map[string]string{HeaderTraceID: "from-header"}, want: testTraceID},
+		{name: "trace header without span", headers: map[string]string{HeaderTraceID: "from-header"}, want: "from-header"},
+		{name: "request id header", headers: map[string]string{HeaderRequestID: "req-1"}, want: "req-1"},
+		{name: "invalid header skipped", headers: map[string]string{HeaderTraceID: "bad id\n", HeaderRequestID: "req-2"}, want: "req-2"},
+		{name: "context request id", reqID: "ctx-req", want: "ctx-req"},
+		{name: "nothing", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			ctx := req.Context()
+			if tt.span {
+				ctx = withTestSpan(t, ctx)
+			}
+			if tt.reqID != "" {
+				ctx = WithRequestID(ctx, tt.reqID)
+			}
+			if got := TraceIDFromRequest(req.WithContext(ctx)); got != tt.want {
+				t.Errorf("TraceIDFromRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceIDFromContext(t *testing.T) {
+	if got := TraceIDFromContext(context.Background()); got != "" {
+		t.Errorf("without span = %q, want empty", got)
+	}
+	if got := TraceIDFromContext(withTestSpan(t, context.Background())); got != testTraceID {
+		t.Errorf("with span = %q, want %q", got, testTraceID)
+	}
+}
+
+func TestIsValidRequestID(t *testing.T) {
+	long := make([]byte, maxRequestIDLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	tests := map[string]bool{
+		"abc-123":                         true,
+		"":                                false,
+		"with space":                      false,
+		"line\nbreak":                     false,
+		string(long[:maxRequestIDLength]): true,
+		string(long):                      false,
+	}
+	for id, want := range tests {
+		if got := IsValidRequestID(id); got != want {
+			t.Errorf("IsValidRequestID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestControllerResponsesCarryTraceID(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+
+	for _, span := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		want := "header-trace"
+		req.Header.Set(HeaderTraceID, want)
+		if span {
+			req = req.WithContext(withTestSpan(t, req.Context()))
+			want = testTraceID
+		}
+
+		rec := httptest.NewRecorder()
+		if err := controller.Success(e.NewContext(req, rec), "ok"); err != nil {
+			t.Fatal(err)
+		}
+		var success BaseResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &success); err != nil {
+			t.Fatal(err)
+		}
+		if success.TraceID != want {
+			t.Errorf("span=%v: success trace_id = %q, want %q", span, success.TraceID, want)
+		}
+
+		rec = httptest.NewRecorder()
+		if err := controller.Error(e.NewContext(req, rec), NotFoundError("missing"), nil); err != nil {
+			t.Fatal(err)
+		}
+		var failure ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil {
+			t.Fatal(err)
+		}
+		if failure.TraceID != want {
+			t.Errorf("span=%v: error trace_id = %q, want %q", span, failure.TraceID, want)
+		}
+	}
+}
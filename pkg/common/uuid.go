@@ -0,0 +1,105 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// NilUUID is the all-zero UUID
+const NilUUID = "00000000-0000-0000-0000-000000000000"
+
+// maxUUID is the all-ones UUID of RFC 9562, exempt from the version checks like NilUUID
+const maxUUID = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+
+// ErrInvalidUUID is returned by ParseUUID for malformed UUIDs
+var ErrInvalidUUID = errors.New("invalid uuid")
+
+// UUIDOptions relaxes or tightens ParseUUID
+type UUIDOptions struct {
+	// AllowWrapped normalizes the "{...}" and "urn:uuid:..." forms instead of rejecting them
+	AllowWrapped bool
+	// RequireRFC4122 rejects UUIDs without the RFC 4122 variant or with a version other than 1 to 8,
+	// NilUUID and the max UUID excepted
+	RequireRFC4122 bool
+}
+
+// ParseUUID validates a UUID in the 8-4-4-4-12 hexadecimal form, any case, and returns it in the canonical
+// lower-case form Postgres uses. opts, at most one, accept wrapped forms or check the version and variant
+func ParseUUID(s string, opts ...UUIDOptions) (string, error) {
+	var options UUIDOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	value := s
+	if options.AllowWrapped {
+		if len(value) > len("urn:uuid:") && strings.EqualFold(value[:len("urn:uuid:")], "urn:uuid:") {
+			value = value[len("urn:uuid:"):]
+		} else if len(value) > 2 && value[0] == '{' && value[len(value)-1] == '}' {
+			value = value[1 : len(value)-1]
+		}
+	}
+
+	if len(value) != len(NilUUID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+	}
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return "", fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+			}
+		default:
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return "", fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+			}
+		}
+	}
+
+	canonical := strings.ToLower(value)
+	if options.RequireRFC4122 && canonical != NilUUID && canonical != maxUUID {
+		version, variant := canonical[14], canonical[19]
+		if version < '1' || version > '8' || !strings.ContainsRune("89ab", rune(variant)) {
+			return "", fmt.Errorf("%w: unsupported version or variant: %q", ErrInvalidUUID, s)
+		}
+	}
+	return canonical, nil
+}
+
+// IsValidUUID reports whether s is a UUID in the 8-4-4-4-12 hexadecimal form, see ParseUUID
+func IsValidUUID(s string) bool {
+	_, err := ParseUUID(s)
+	return err == nil
+}
+
+// IsNilUUID reports whether s is a valid UUID equal to NilUUID
+func IsNilUUID(s string) bool {
+	canonical, err := ParseUUID(s)
+	return err == nil && canonical == NilUUID
+}
+
+// MustNewUUID returns a random (version 4) UUID in the canonical form, it panics when the system random
+// source fails
+func MustNewUUID() string {
+	return uuid.Must(uuid.NewRandom()).String()
+}
+
+// ParamUUID returns the path parameter name of a request as a canonical UUID, for controllers.
+// Missing or malformed ids return a VALIDATION_ERROR response before they reach the database
+func ParamUUID(c echo.Context, name string) (string, *ErrorResponse) {
+	value := c.Param(name)
+	canonical, err := ParseUUID(value)
+	if err != nil {
+		ctx := LocaleContext(c)
+		return "", ValidationErrorI18n(ErrorDetail{
+			Field:   name,
+			Message: TfWithContext(ctx, MsgValidationUUID, map[string]any{"field": name}),
+			Value:   RedactValue(name, value),
+		})
+	}
+	return canonical, nil
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestParseUUID(t *testing.T) {
+	const canonical = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+	wrapped := UUIDOptions{AllowWrapped: true}
+	strict := UUIDOptions{RequireRFC4122: true}
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []UUIDOptions
+		want  string
+	}{
+		{"canonical", canonical, nil, canonical},
+		{"upper case", "7C9E6679-7425-40DE-944B-E07FC1F90AE7", nil, canonical},
+		{"mixed case", "7c9E6679-7425-40dE-944b-E07fc1f90ae7", nil, canonical},
+		{"all z", "zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz", nil, ""},
+		{"one non-hex digit", "7c9e6679-7425-40de-944b-e07fc1f90aeg", nil, ""},
+		{"misplaced hyphen", "7c9e667-97425-40de-944b-e07fc1f90ae7", nil, ""},
+		{"no hyphens", "7c9e6679742540de944be07fc1f90ae7", nil, ""},
+		{"too long", canonical + "0", nil, ""},
+		{"empty", "", nil, ""},
+		{"surrounding spaces", " " + canonical + " ", nil, ""},
+		{"braces rejected", "{" + canonical + "}", nil, ""},
+		{"urn rejected", "urn:uuid:" + canonical, nil, ""},
+		{"braces normalized", "{" + canonical + "}", []UUIDOptions{wrapped}, canonical},
+		{"urn normalized", "URN:UUID:7C9E6679-7425-40DE-944B-E07FC1F90AE7", []UUIDOptions{wrapped}, canonical},
+		{"unbalanced braces", "{" + canonical, []UUIDOptions{wrapped}, ""},
+		{"empty braces", "{}", []UUIDOptions{wrapped}, ""},
+		{"invalid wrapped", "{zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz}", []UUIDOptions{wrapped}, ""},
+		{"version 4 when strict", canonical, []UUIDOptions{strict}, canonical},
+		{"version 7 when strict", "01890a5d-ac96-774b-bcce-b302099a8057", []UUIDOptions{strict}, "01890a5d-ac96-774b-bcce-b302099a8057"},
+		{"version 0 when strict", "7c9e6679-7425-00de-944b-e07fc1f90ae7", []UUIDOptions{strict}, ""},
+		{"version 9 when strict", "7c9e6679-7425-90de-944b-e07fc1f90ae7", []UUIDOptions{strict}, ""},
+		{"NCS variant when strict", "7c9e6679-7425-40de-744b-e07fc1f90ae7", []UUIDOptions{strict}, ""},
+		{"NCS variant", "7c9e6679-7425-40de-744b-e07fc1f90ae7", nil, "7c9e6679-7425-40de-744b-e07fc1f90ae7"},
+		{"nil when strict", NilUUID, []UUIDOptions{strict}, NilUUID},
+		{"max when strict", "FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF", []UUIDOptions{strict}, maxUUID},
+		{"wrapped and strict", "{01890A5D-AC96-774B-BCCE-B302099A8057}", []UUIDOptions{{AllowWrapped: true, RequireRFC4122: true}}, "01890a5d-ac96-774b-bcce-b302099a8057"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUUID(tt.input, tt.opts...)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidUUID) || got != "" {
+					t.Errorf("ParseUUID(%q) = %q, %v, want ErrInvalidUUID", tt.input, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseUUID(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUUIDHelpers(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+		isNil bool
+	}{
+		{"7c9e6679-7425-40de-944b-e07fc1f90ae7", true, false},
+		{"7C9E6679-7425-40DE-944B-E07FC1F90AE7", true, false},
+		{NilUUID, true, true},
+		{"zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz", false, false},
+		{"{00000000-0000-0000-0000-000000000000}", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if got := IsValidUUID(tt.input); got != tt.valid {
+			t.Errorf("IsValidUUID(%q) = %v, want %v", tt.input, got, tt.valid)
+		}
+		if got := IsNilUUID(tt.input); got != tt.isNil {
+			t.Errorf("IsNilUUID(%q) = %v, want %v", tt.input, got, tt.isNil)
+		}
+	}
+
+	seen := map[string]bool{}
+	for range 100 {
+		id := MustNewUUID()
+		if canonical, err := ParseUUID(id, UUIDOptions{RequireRFC4122: true}); err != nil || canonical != id || id[14] != '4' {
+			t.Fatalf("MustNewUUID = %q, want a canonical version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("MustNewUUID repeated %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestParamUUID(t *testing.T) {
+	e := echo.New()
+	newContext := func(id string) echo.Context {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/orders/"+id, nil), httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		return c
+	}
+
+	got, errResp := ParamUUID(newContext("7C9E6679-7425-40DE-944B-E07FC1F90AE7"), "id")
+	if errResp != nil || got != "7c9e6679-7425-40de-944b-e07fc1f90ae7" {
+		t.Errorf("ParamUUID = %q, %v, want the canonical form", got, errResp)
+	}
+
+	for _, id := range []string{"zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz", "42", ""} {
+		got, errResp := ParamUUID(newContext(id), "id")
+		if got != "" || errResp == nil || errResp.Code != VALIDATION_ERROR || len(errResp.Details) != 1 ||
+			errResp.Details[0].Field != "id" || errResp.Details[0].Value != id || errResp.Details[0].Message == "" {
+			t.Errorf("ParamUUID(%q) = %q, %+v, want a VALIDATION_ERROR on id", id, got, errResp)
+		}
+	}
+}
+
+func TestUUIDValidationTag(t *testing.T) {
+	tests := []struct {
+		productID string
+		want      string
+	}{
+		{"7c9e6679-7425-40de-944b-e07fc1f90ae7", ""},
+		{"7C9E6679-7425-40DE-944B-E07FC1F90AE7", ""},
+		{"zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz", MsgValidationUUID},
+		{"{7c9e6679-7425-40de-944b-e07fc1f90ae7}", MsgValidationUUID},
+	}
+	for _, tt := range tests {
+		got := failedFields(&testOrderItem{ProductID: tt.productID, Quantity: 1})
+		if got["product_id"] != tt.want {
+			t.Errorf("product_id %q failed with %q, want %q", tt.productID, got["product_id"], tt.want)
+		}
+	}
+}
@@ -1,9 +1,24 @@
 package common
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/idna"
 )
 
 // ValidationRule represents a validation rule
@@ -26,39 +41,83 @@ type Validator interface {
 }
 
 // BaseValidator provides common validation functionality
-type BaseValidator struct{}
+type BaseValidator struct {
+	// CheckEmailMX makes Email also require an MX (or A) record for the domain, this performs a DNS lookup
+	CheckEmailMX bool
+	// SensitiveFields are redacted in ErrorDetail.Value in addition to the registered sensitive fields
+	SensitiveFields []string
+	// prefix is the path of the sub-object validated, see Prefix
+	prefix string
+}
+
+// Prefix returns a validator reporting fields under prefix, to validate sub-objects with dotted and
+// indexed paths, e.g. v.Prefix(IndexedField("items", 2)).MinValue("quantity", ...) reports "items[2].quantity"
+func (v *BaseValidator) Prefix(prefix string) *BaseValidator {
+	prefixed := *v
+	prefixed.prefix = v.path(prefix)
+	return &prefixed
+}
+
+// IndexedField returns the path of a slice element, IndexedField("items", 2) -> "items[2]"
+func IndexedField(field string, index int) string {
+	return field + "[" + strconv.Itoa(index) + "]"
+}
+
+// path returns field under the validator prefix
+func (v *BaseValidator) path(field string) string {
+	if v.prefix == "" {
+		return field
+	}
+	if field == "" || strings.HasPrefix(field, "[") {
+		return v.prefix + field
+	}
+	return v.prefix + "." + field
+}
+
+// redact hides value when field is sensitive for this validator
+func (v *BaseValidator) redact(field string, value string) string {
+	for _, name := range v.SensitiveFields {
+		if matchesSensitiveName(field, name) {
+			return RedactedValue
+		}
+	}
+	return RedactValue(field, value)
+}
 
 // Required validates that a field is not empty
 func (v *BaseValidator) Required(field string, value interface{}, messageKey string) *ErrorDetail {
+	field = v.path(field)
 	if isEmpty(value) {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   toString(value),
+			Message: Tf(messageKey, map[string]any{"field": field}),
+			Value:   v.redact(field, toString(value)),
 		}
 	}
 	return nil
 }
 
-// MinLength validates minimum length for strings
+// MinLength validates minimum length for strings, counted in characters
 func (v *BaseValidator) MinLength(field string, value string, minLength int, messageKey string) *ErrorDetail {
-	if len(value) < minLength {
+	field = v.path(field)
+	if utf8.RuneCountInString(value) < minLength {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   value,
+			Message: Tf(messageKey, map[string]any{"field": field, "min": minLength}),
+			Value:   v.redact(field, value),
 		}
 	}
 	return nil
 }
 
-// MaxLength validates maximum length for strings
+// MaxLength validates maximum length for strings, counted in characters
 func (v *BaseValidator) MaxLength(field string, value string, maxLength int, messageKey string) *ErrorDetail {
-	if len(value) > maxLength {
+	field = v.path(field)
+	if utf8.RuneCountInString(value) > maxLength {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   value,
+			Message: Tf(messageKey, map[string]any{"field": field, "max": maxLength}),
+			Value:   v.redact(field, value),
 		}
 	}
 	return nil
@@ -66,11 +125,12 @@ func (v *BaseValidator) MaxLength(field string, value string, maxLength int, mes
 
 // MinValue validates minimum value for numbers
 func (v *BaseValidator) MinValue(field string, value float64, minValue float64, messageKey string) *ErrorDetail {
+	field = v.path(field)
 	if value < minValue {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   strconv.FormatFloat(value, 'f', -1, 64),
+			Message: Tf(messageKey, map[string]any{"field": field, "min": minValue}),
+			Value:   v.redact(field, strconv.FormatFloat(value, 'f', -1, 64)),
 		}
 	}
 	return nil
@@ -78,11 +138,12 @@ func (v *BaseValidator) MinValue(field string, value float64, minValue float64,
 
 // MaxValue validates maximum value for numbers
 func (v *BaseValidator) MaxValue(field string, value float64, maxValue float64, messageKey string) *ErrorDetail {
+	field = v.path(field)
 	if value > maxValue {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   strconv.FormatFloat(value, 'f', -1, 64),
+			Message: Tf(messageKey, map[string]any{"field": field, "max": maxValue}),
+			Value:   v.redact(field, strconv.FormatFloat(value, 'f', -1, 64)),
 		}
 	}
 	return nil
@@ -90,35 +151,210 @@ func (v *BaseValidator) MaxValue(field string, value float64, maxValue float64,
 
 // Email validates email format
 func (v *BaseValidator) Email(field string, value string, messageKey string) *ErrorDetail {
-	if !isValidEmail(value) {
+	field = v.path(field)
+	valid := IsValidEmail(value)
+	if valid && v.CheckEmailMX {
+		valid = IsValidEmailMX(context.Background(), value)
+	}
+	if !valid {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   value,
+			Message: Tf(messageKey, map[string]any{"field": field}),
+			Value:   v.redact(field, value),
 		}
 	}
 	return nil
 }
 
-// URL validates URL format
+// URL validates URL format, only http and https URLs are accepted
 func (v *BaseValidator) URL(field string, value string, messageKey string) *ErrorDetail {
-	if !isValidURL(value) {
+	return v.URLWithSchemes(field, value, DefaultURLSchemes, messageKey)
+}
+
+// URLWithSchemes validates URL format restricted to schemes, any scheme is accepted when schemes is empty
+func (v *BaseValidator) URLWithSchemes(field string, value string, schemes []string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if !IsValidURL(value, schemes...) {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   value,
+			Message: Tf(messageKey, map[string]any{"field": field, "schemes": strings.Join(schemes, ", ")}),
+			Value:   v.redact(field, value),
 		}
 	}
 	return nil
 }
 
+// Regex validates that value matches pattern, compiled patterns are cached.
+// An empty value passes, use Required to reject it. An invalid pattern is logged and fails the rule for
+// every value, empty or not, so a broken pattern is found on its first use
+func (v *BaseValidator) Regex(field string, value string, pattern string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	re, err := compileCachedPattern(pattern)
+	if err == nil && value == "" {
+		return nil
+	}
+
+	if err != nil || !re.MatchString(value) {
+		return &ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey, map[string]any{"field": field, "pattern": pattern}),
+			Value:   v.redact(field, value),
+		}
+	}
+	return nil
+}
+
+// LengthBetween validates that value has between minLength and maxLength characters (inclusive).
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) LengthBetween(field string, value string, minLength, maxLength int, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if value == "" {
+		return nil
+	}
+
+	length := utf8.RuneCountInString(value)
+	if length < minLength || length > maxLength {
+		return &ErrorDetail{
+			Field:   field,
+			Message: Tf(messageKey, map[string]any{"field": field, "min": minLength, "max": maxLength}),
+			Value:   v.redact(field, value),
+		}
+	}
+	return nil
+}
+
+// Alpha validates that value only contains letters, including accented letters such as Vietnamese ones.
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) Alpha(field string, value string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	return v.charset(field, value, isAlpha, messageKey)
+}
+
+// AlphaNumeric validates that value only contains letters and digits 0-9.
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) AlphaNumeric(field string, value string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	return v.charset(field, value, isAlphaNumeric, messageKey)
+}
+
+// Numeric validates that value only contains digits 0-9.
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) Numeric(field string, value string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	return v.charset(field, value, isNumeric, messageKey)
+}
+
+// charset validates that every character of value satisfies allowed
+func (v *BaseValidator) charset(field string, value string, allowed func(string) bool, messageKey string) *ErrorDetail {
+	if value == "" || allowed(value) {
+		return nil
+	}
+	return &ErrorDetail{
+		Field:   field,
+		Message: Tf(messageKey, map[string]any{"field": field}),
+		Value:   v.redact(field, value),
+	}
+}
+
+// OneOf validates that value is one of allowed, the list is interpolated as {values}.
+// An empty value passes, use Required to reject it
+func (v *BaseValidator) OneOf(field string, value string, allowed []string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if value == "" || isOneOf(value, allowed, false) {
+		return nil
+	}
+	return v.oneOfError(field, value, allowed, messageKey)
+}
+
+// OneOfIgnoreCase is like OneOf comparing values case insensitively
+func (v *BaseValidator) OneOfIgnoreCase(field string, value string, allowed []string, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if value == "" || isOneOf(value, allowed, true) {
+		return nil
+	}
+	return v.oneOfError(field, value, allowed, messageKey)
+}
+
+// OneOfTyped validates that value is one of allowed for any comparable type, e.g. a string based enum.
+// Methods cannot have type parameters so it takes the validator first, applying its prefix and SensitiveFields
+func OneOfTyped[T comparable](v *BaseValidator, field string, value T, allowed []T, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if slices.Contains(allowed, value) {
+		return nil
+	}
+
+	names := make([]string, 0, len(allowed))
+	for _, option := range allowed {
+		names = append(names, fmt.Sprint(option))
+	}
+	return v.oneOfError(field, fmt.Sprint(value), names, messageKey)
+}
+
+// In validates that a numeric value is one of allowed, the list is interpolated as {values}
+func (v *BaseValidator) In(field string, value float64, allowed []float64, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if slices.Contains(allowed, value) {
+		return nil
+	}
+	return v.oneOfError(field, strconv.FormatFloat(value, 'f', -1, 64), formatFloats(allowed), messageKey)
+}
+
+// NotIn validates that a numeric value is none of denied, the list is interpolated as {values}
+func (v *BaseValidator) NotIn(field string, value float64, denied []float64, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if !slices.Contains(denied, value) {
+		return nil
+	}
+	return v.oneOfError(field, strconv.FormatFloat(value, 'f', -1, 64), formatFloats(denied), messageKey)
+}
+
+// RequiredIf validates that value is not empty when condition holds, e.g. end_date when start_date is set
+func (v *BaseValidator) RequiredIf(field string, value interface{}, condition bool, messageKey string) *ErrorDetail {
+	if condition {
+		return v.Required(field, value, messageKey)
+	}
+	return nil
+}
+
+// EqualsField validates that value equals the value of otherField, interpolated as {other},
+// e.g. password_confirmation and password
+func (v *BaseValidator) EqualsField(field string, value interface{}, otherField string, otherValue interface{}, messageKey string) *ErrorDetail {
+	field = v.path(field)
+	if reflect.DeepEqual(value, otherValue) {
+		return nil
+	}
+	return &ErrorDetail{
+		Field:   field,
+		Message: Tf(messageKey, map[string]any{"field": field, "other": otherField}),
+	}
+}
+
+// RequiredWithout validates that at least one of fields (name to value) is not empty, e.g. email or phone.
+// The error names all the fields, comma separated in Field and interpolated as {fields}
+func (v *BaseValidator) RequiredWithout(fields map[string]any, messageKey string) *ErrorDetail {
+	names := make([]string, 0, len(fields))
+	for name, value := range fields {
+		if !isEmpty(value) {
+			return nil
+		}
+		names = append(names, v.path(name))
+	}
+	sort.Strings(names)
+
+	return &ErrorDetail{
+		Field:   strings.Join(names, ","),
+		Message: Tf(messageKey, map[string]any{"field": strings.Join(names, ", "), "fields": strings.Join(names, ", ")}),
+	}
+}
+
 // Custom validates with custom validation function
 func (v *BaseValidator) Custom(field string, value interface{}, validator func(interface{}) bool, messageKey string) *ErrorDetail {
+	field = v.path(field)
 	if !validator(value) {
 		return &ErrorDetail{
 			Field:   field,
-			Message: T(messageKey),
-			Value:   toString(value),
+			Message: Tf(messageKey, map[string]any{"field": field}),
+			Value:   v.redact(field, toString(value)),
 		}
 	}
 	return nil
@@ -168,24 +404,224 @@ func toString(value interface{}) string {
 	return strings.TrimSpace(reflect.ValueOf(value).String())
 }
 
-func isValidEmail(email string) bool {
-	// Basic email validation
-	return strings.Contains(email, "@") && strings.Contains(email, ".")
+// patternCache holds the compiled regular expressions of Regex, or the error of an invalid pattern
+var patternCache sync.Map
+
+// compileCachedPattern compiles pattern once and returns the cached regexp afterwards. An invalid pattern
+// is logged on its first use
+func compileCachedPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := patternCache.Load(pattern); ok {
+		if err, ok := cached.(error); ok {
+			return nil, err
+		}
+		return cached.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		err = fmt.Errorf("validation: invalid pattern %q: %w", pattern, err)
+		logrus.WithError(err).Error("validation: Regex rule failed")
+		patternCache.Store(pattern, err)
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+func isAlpha(value string) bool {
+	for _, r := range value {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphaNumeric(value string) bool {
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !(r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isOneOf reports whether value is in allowed, shared by OneOf and the oneof tag rule
+func isOneOf(value string, allowed []string, ignoreCase bool) bool {
+	for _, option := range allowed {
+		if value == option || ignoreCase && strings.EqualFold(value, option) {
+			return true
+		}
+	}
+	return false
 }
 
-func isValidURL(url string) bool {
-	// Basic URL validation
-	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+// oneOfError builds the error detail of the enum rules
+func (v *BaseValidator) oneOfError(field string, value string, allowed []string, messageKey string) *ErrorDetail {
+	return &ErrorDetail{
+		Field:   field,
+		Message: Tf(messageKey, map[string]any{"field": field, "values": strings.Join(allowed, ", ")}),
+		Value:   v.redact(field, value),
+	}
+}
+
+func formatFloats(values []float64) []string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return formatted
+}
+
+// DefaultURLSchemes are the schemes accepted by BaseValidator.URL and the url tag rule
+var DefaultURLSchemes = []string{"http", "https"}
+
+// lookupMX is the resolver of the MX records checked by IsValidEmailMX
+var lookupMX = net.DefaultResolver.LookupMX
+
+// lookupHost is the resolver of the A/AAAA fallback of IsValidEmailMX for domains without MX records
+var lookupHost = net.DefaultResolver.LookupHost
+
+// emailLookupTimeout bounds the DNS lookups of IsValidEmailMX
+const emailLookupTimeout = 3 * time.Second
+
+// IsValidEmail validates an email address following RFC 5322 (quoted local parts are accepted)
+// with a sanity check of the domain: at least two labels, valid label characters and a non numeric TLD.
+// Unicode domains are checked in their punycode form. Display names ("John <a@b.com>") are rejected
+func IsValidEmail(email string) bool {
+	if email == "" || len(email) > 254 {
+		return false
+	}
+
+	if strings.TrimSpace(email) != email || strings.HasSuffix(email, ">") {
+		return false
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" {
+		return false
+	}
+
+	at := strings.LastIndex(address.Address, "@")
+	if at <= 0 || at > 64 {
+		return false
+	}
+	return isValidDomain(address.Address[at+1:])
+}
+
+// IsValidEmailMX validates an email address like IsValidEmail and checks that its domain accepts mail,
+// i.e. has an MX record or, as the RFC 5321 fallback, an address record
+func IsValidEmailMX(ctx context.Context, email string) bool {
+	if !IsValidEmail(email) {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, emailLookupTimeout)
+	defer cancel()
+
+	domain, err := idna.Lookup.ToASCII(email[strings.LastIndex(email, "@")+1:])
+	if err != nil {
+		return false
+	}
+	if records, err := lookupMX(ctx, domain); err == nil && len(records) > 0 {
+		return true
+	}
+	addresses, err := lookupHost(ctx, domain)
+	return err == nil && len(addresses) > 0
+}
+
+// isValidDomain checks that domain is a fully qualified host name with a TLD
+func isValidDomain(domain string) bool {
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || ascii == "" || len(ascii) > 253 {
+		return false
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 {
+		return false
+	}
+	if _, err := strconv.Atoi(tld); err == nil {
+		return false
+	}
+	return true
+}
+
+// IsValidURL validates an absolute URL with a scheme and a host.
+// When schemes are given the URL scheme must be one of them (case insensitive), so javascript: or
+// data: URLs can be rejected, e.g. IsValidURL(value, "https")
+func IsValidURL(rawURL string, schemes ...string) bool {
+	if rawURL == "" || strings.ContainsAny(rawURL, " \t\r\n") {
+		return false
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" || parsed.Hostname() == "" {
+		return false
+	}
+	if len(schemes) == 0 {
+		return true
+	}
+
+	for _, scheme := range schemes {
+		if strings.EqualFold(parsed.Scheme, scheme) {
+			return true
+		}
+	}
+	return false
 }
 
 // Common validation message keys
 const (
-	MsgValidationRequired  = "validation.required"
-	MsgValidationMinLength = "validation.min_length"
-	MsgValidationMaxLength = "validation.max_length"
-	MsgValidationMinValue  = "validation.min_value"
-	MsgValidationMaxValue  = "validation.max_value"
-	MsgValidationEmail     = "validation.email"
-	MsgValidationURL       = "validation.url"
-	MsgValidationInvalid   = "validation.invalid"
+	MsgValidationRequired     = "validation.required"
+	MsgValidationRequiredIf   = "validation.required_if"
+	MsgValidationRequiredOne  = "validation.required_without"
+	MsgValidationEqualsField  = "validation.equals_field"
+	MsgValidationMinLength    = "validation.min_length"
+	MsgValidationMaxLength    = "validation.max_length"
+	MsgValidationMinValue     = "validation.min_value"
+	MsgValidationMaxValue     = "validation.max_value"
+	MsgValidationEmail        = "validation.email"
+	MsgValidationURL          = "validation.url"
+	MsgValidationUUID         = "validation.uuid"
+	MsgValidationOneOf        = "validation.one_of"
+	MsgValidationNotIn        = "validation.not_in"
+	MsgValidationPattern      = "validation.pattern"
+	MsgValidationLength       = "validation.length_between"
+	MsgValidationAlpha        = "validation.alpha"
+	MsgValidationAlphaNum     = "validation.alpha_numeric"
+	MsgValidationNumeric      = "validation.numeric"
+	MsgValidationNumericStr   = "validation.numeric_string"
+	MsgValidationDecimal      = "validation.decimal_precision"
+	MsgValidationDecimalRange = "validation.decimal_range"
+	MsgValidationDateFormat   = "validation.date_format"
+	MsgValidationDateAfter    = "validation.date_after"
+	MsgValidationDateBefore   = "validation.date_before"
+	MsgValidationDateBetween  = "validation.date_between"
+	MsgValidationAgeAtLeast   = "validation.age_at_least"
+	MsgValidationPassword     = "validation.password"
+	MsgValidationInvalid      = "validation.invalid"
 )
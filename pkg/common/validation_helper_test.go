@@ -0,0 +1,408 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestIsValidEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"user@example.com", true},
+		{"first.last+tag@sub.example.co.uk", true},
+		{`"john doe"@example.com`, true},
+		{"user@bücher.de", true},
+		{"người.dùng@example.vn", true},
+		{"a@b.", false},
+		{"@@.", false},
+		{"user@localhost", false},
+		{"user@example", false},
+		{"user@example.c", false},
+		{"user@example.123", false},
+		{"user@-example.com", false},
+		{"user@exa_mple.com", false},
+		{"user@.example.com", false},
+		{"John <user@example.com>", false},
+		{"<user@example.com>", false},
+		{" user@example.com", false},
+		{"userexample.com", false},
+		{"", false},
+		{strings.Repeat("a", 65) + "@example.com", false},
+		{"user@" + strings.Repeat("a", 64) + ".com", false},
+	}
+	for _, tt := range tests {
+		if got := IsValidEmail(tt.email); got != tt.want {
+			t.Errorf("IsValidEmail(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
+
+// stubEmailLookups replaces the DNS lookups of IsValidEmailMX for the duration of the test
+func stubEmailLookups(t *testing.T, mx map[string]bool, hosts map[string]bool) {
+	t.Helper()
+	previousMX, previousHost := lookupMX, lookupHost
+	t.Cleanup(func() { lookupMX, lookupHost = previousMX, previousHost })
+
+	lookupMX = func(ctx context.Context, domain string) ([]*net.MX, error) {
+		if mx[domain] {
+			return []*net.MX{{Host: "mail." + domain, Pref: 10}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	lookupHost = func(ctx context.Context, domain string) ([]string, error) {
+		if hosts[domain] {
+			return []string{"192.0.2.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+}
+
+func TestIsValidEmailMX(t *testing.T) {
+	stubEmailLookups(t,
+		map[string]bool{"mail.example": true, "xn--bcher-kva.de": true},
+		map[string]bool{"host-only.example": true})
+
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"user@mail.example", true},
+		{"user@host-only.example", true},
+		{"user@bücher.de", true},
+		{"user@nothing.example", false},
+		{"not-an-email", false},
+	}
+	for _, tt := range tests {
+		if got := IsValidEmailMX(context.Background(), tt.email); got != tt.want {
+			t.Errorf("IsValidEmailMX(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+
+	v := &BaseValidator{CheckEmailMX: true}
+	if detail := v.Email("email", "user@nothing.example", MsgValidationEmail); detail == nil {
+		t.Error("Email with CheckEmailMX accepted a domain without MX or address records")
+	}
+	if detail := v.Email("email", "user@mail.example", MsgValidationEmail); detail != nil {
+		t.Errorf("Email with CheckEmailMX = %+v, want valid", detail)
+	}
+}
+
+func TestIsValidURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		schemes []string
+		want    bool
+	}{
+		{"https://example.com/path?q=1", nil, true},
+		{"http://localhost:8080", nil, true},
+		{"ftp://files.example.com", nil, true},
+		{"https://bücher.de", nil, true},
+		{"HTTPS://example.com", []string{"https"}, true},
+		{"ftp://files.example.com", DefaultURLSchemes, false},
+		{"javascript:alert(1)", nil, false},
+		{"javascript://example.com/%0Aalert(1)", DefaultURLSchemes, false},
+		{"data:text/html;base64,PHNjcmlwdD4=", nil, false},
+		{"example.com", nil, false},
+		{"/relative/path", nil, false},
+		{"https://", nil, false},
+		{"https://exa mple.com", nil, false},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsValidURL(tt.url, tt.schemes...); got != tt.want {
+			t.Errorf("IsValidURL(%q, %v) = %v, want %v", tt.url, tt.schemes, got, tt.want)
+		}
+	}
+}
+
+func TestBaseValidatorEmailAndURL(t *testing.T) {
+	v := &BaseValidator{}
+	if detail := v.Email("email", "a@b.", MsgValidationEmail); detail == nil || detail.Field != "email" {
+		t.Errorf("Email(a@b.) = %+v, want an error on email", detail)
+	}
+	if detail := v.URL("site", "javascript:alert(1)", MsgValidationURL); detail == nil {
+		t.Error("URL accepted a javascript: URL")
+	}
+	if detail := v.URLWithSchemes("feed", "ftp://files.example.com", []string{"ftp"}, MsgValidationURL); detail != nil {
+		t.Errorf("URLWithSchemes(ftp) = %+v, want valid", detail)
+	}
+}
+
+func TestBaseValidatorLengthRules(t *testing.T) {
+	v := &BaseValidator{}
+
+	tests := []struct {
+		name    string
+		detail  *ErrorDetail
+		wantErr bool
+	}{
+		{"min length counts characters", v.MinLength("name", "Đặng", 4, MsgValidationMinLength), false},
+		{"min length too short", v.MinLength("name", "Đặn", 4, MsgValidationMinLength), true},
+		{"max length counts characters", v.MaxLength("name", "Nguyễn", 6, MsgValidationMaxLength), false},
+		{"max length too long", v.MaxLength("name", "Nguyễnn", 6, MsgValidationMaxLength), true},
+		{"between accented", v.LengthBetween("name", "Hà", 2, 3, MsgValidationLength), false},
+		{"between too short", v.LengthBetween("name", "H", 2, 3, MsgValidationLength), true},
+		{"between too long", v.LengthBetween("name", "Hà Nội", 2, 3, MsgValidationLength), true},
+		{"between empty passes", v.LengthBetween("name", "", 2, 3, MsgValidationLength), false},
+	}
+	for _, tt := range tests {
+		if (tt.detail != nil) != tt.wantErr {
+			t.Errorf("%s: detail = %+v, want error %v", tt.name, tt.detail, tt.wantErr)
+		}
+	}
+}
+
+func TestBaseValidatorCharsetRules(t *testing.T) {
+	v := &BaseValidator{}
+
+	tests := []struct {
+		name  string
+		rule  func(field, value, messageKey string) *ErrorDetail
+		value string
+		want  bool
+	}{
+		{"alpha accented", v.Alpha, "Tiếng", true},
+		{"alpha digit", v.Alpha, "abc1", false},
+		{"alpha space", v.Alpha, "a b", false},
+		{"alpha empty", v.Alpha, "", true},
+		{"alphanumeric", v.AlphaNumeric, "Đơn123", true},
+		{"alphanumeric symbol", v.AlphaNumeric, "abc-1", false},
+		{"alphanumeric empty", v.AlphaNumeric, "", true},
+		{"numeric", v.Numeric, "0123", true},
+		{"numeric sign", v.Numeric, "-1", false},
+		{"numeric unicode digit", v.Numeric, "١٢", false},
+		{"numeric empty", v.Numeric, "", true},
+	}
+	for _, tt := range tests {
+		if got := tt.rule("code", tt.value, MsgValidationAlpha) == nil; got != tt.want {
+			t.Errorf("%s(%q) valid = %v, want %v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBaseValidatorRegex(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"pattern": "{field} không khớp {pattern}"},
+	}))
+	v := &BaseValidator{}
+	pattern := `^[A-Z]{3}-\d{3}$`
+
+	if detail := v.Regex("code", "ABC-123", pattern, MsgValidationPattern); detail != nil {
+		t.Errorf("matching value = %+v", detail)
+	}
+	if detail := v.Regex("code", "", pattern, MsgValidationPattern); detail != nil {
+		t.Errorf("empty value = %+v, want it left to Required", detail)
+	}
+	detail := v.Regex("code", "abc-123", pattern, MsgValidationPattern)
+	if detail == nil || detail.Message != `code không khớp `+pattern {
+		t.Errorf("non matching value = %+v, want the pattern in the message", detail)
+	}
+	if _, ok := patternCache.Load(pattern); !ok {
+		t.Error("compiled pattern not cached")
+	}
+}
+
+func TestBaseValidatorRegexInvalidPattern(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"pattern": "{field} không khớp {pattern}"},
+	}))
+	logger := logrus.StandardLogger()
+	hooks, output := logger.ReplaceHooks(logrus.LevelHooks{}), logger.Out
+	logger.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		logger.ReplaceHooks(hooks)
+		logger.SetOutput(output)
+	})
+	hook := test.NewLocal(logger)
+	v := &BaseValidator{}
+	pattern := `([a-z`
+
+	for _, value := range []string{"value", ""} {
+		detail := v.Regex("code", value, pattern, MsgValidationPattern)
+		if detail == nil || detail.Field != "code" || detail.Message != `code không khớp `+pattern {
+			t.Errorf("Regex(%q) with an invalid pattern = %+v, want the rule failed", value, detail)
+		}
+	}
+	if entries := hook.AllEntries(); len(entries) != 1 || entries[0].Level != logrus.ErrorLevel {
+		t.Errorf("logged %d entries, want the invalid pattern logged once", len(entries))
+	}
+}
+
+type testStatus string
+
+func TestOneOfRules(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{"one_of": "{field} phải là một trong {values}"},
+	}))
+	v := &BaseValidator{}
+	allowed := []string{"draft", "published", "archived"}
+
+	if detail := v.OneOf("status", "draft", allowed, MsgValidationOneOf); detail != nil {
+		t.Errorf("allowed value = %+v", detail)
+	}
+	if detail := v.OneOf("status", "", allowed, MsgValidationOneOf); detail != nil {
+		t.Errorf("empty value = %+v, want it left to Required", detail)
+	}
+	detail := v.OneOf("status", "Draft", allowed, MsgValidationOneOf)
+	if detail == nil || detail.Message != "status phải là một trong draft, published, archived" || detail.Value != "Draft" {
+		t.Errorf("case sensitive mismatch = %+v", detail)
+	}
+	if detail := v.OneOfIgnoreCase("status", "Draft", allowed, MsgValidationOneOf); detail != nil {
+		t.Errorf("OneOfIgnoreCase = %+v", detail)
+	}
+
+	if detail := v.In("rating", 5, []float64{1, 3, 5}, MsgValidationOneOf); detail != nil {
+		t.Errorf("In allowed = %+v", detail)
+	}
+	if detail := v.In("rating", 2.5, []float64{1, 3, 5}, MsgValidationOneOf); detail == nil || detail.Value != "2.5" {
+		t.Errorf("In not allowed = %+v", detail)
+	}
+	if detail := v.NotIn("port", 22, []float64{22, 23}, MsgValidationNotIn); detail == nil {
+		t.Error("NotIn denied value passed")
+	}
+	if detail := v.NotIn("port", 8080, []float64{22, 23}, MsgValidationNotIn); detail != nil {
+		t.Errorf("NotIn other value = %+v", detail)
+	}
+}
+
+func TestOneOfTyped(t *testing.T) {
+	v := &BaseValidator{SensitiveFields: []string{"secret_kind"}}
+	allowed := []testStatus{"draft", "published"}
+
+	if detail := OneOfTyped(v, "status", testStatus("draft"), allowed, MsgValidationOneOf); detail != nil {
+		t.Errorf("allowed value = %+v", detail)
+	}
+	if detail := OneOfTyped(v, "level", 4, []int{1, 2, 3}, MsgValidationOneOf); detail == nil || detail.Value != "4" {
+		t.Errorf("int value = %+v", detail)
+	}
+
+	detail := OneOfTyped(v.Prefix(IndexedField("posts", 1)), "status", testStatus("deleted"), allowed, MsgValidationOneOf)
+	if detail == nil || detail.Field != "posts[1].status" {
+		t.Errorf("prefixed detail = %+v, want the field under the prefix", detail)
+	}
+
+	detail = OneOfTyped(v, "secret_kind", testStatus("deleted"), allowed, MsgValidationOneOf)
+	if detail == nil || detail.Value != RedactedValue {
+		t.Errorf("sensitive detail = %+v, want the value redacted", detail)
+	}
+	if detail := v.OneOf("secret_kind", "deleted", []string{"draft"}, MsgValidationOneOf); detail == nil || detail.Value != RedactedValue {
+		t.Errorf("OneOf sensitive detail = %+v, want the value redacted", detail)
+	}
+}
+
+func TestOneOfTagSharesImplementation(t *testing.T) {
+	type request struct {
+		Status string `json:"status" validate:"oneof=draft published"`
+		Kind   string `json:"kind" validate:"oneofci=a b"`
+		Level  int    `json:"level" validate:"oneof=1 2"`
+	}
+
+	got := failedFields(&request{Status: "Draft", Kind: "A", Level: 3})
+	if len(got) != 2 || got["status"] != MsgValidationOneOf || got["level"] != MsgValidationOneOf {
+		t.Errorf("failures = %v, want status and level", got)
+	}
+}
+
+func TestCrossFieldMethods(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("vn", map[string]any{
+		"validation": map[string]any{
+			"equals_field":     "{field} phải trùng {other}",
+			"required_without": "Cần ít nhất một trong {fields}",
+		},
+	}))
+	v := &BaseValidator{}
+
+	if detail := v.RequiredIf("end_date", "", true, MsgValidationRequired); detail == nil || detail.Field != "end_date" {
+		t.Errorf("RequiredIf with condition = %+v", detail)
+	}
+	if detail := v.RequiredIf("end_date", "", false, MsgValidationRequired); detail != nil {
+		t.Errorf("RequiredIf without condition = %+v", detail)
+	}
+	if detail := v.RequiredIf("end_date", "2024-01-02", true, MsgValidationRequired); detail != nil {
+		t.Errorf("RequiredIf with value = %+v", detail)
+	}
+
+	detail := v.EqualsField("password_confirmation", "secret1", "password", "secret2", MsgValidationEqualsField)
+	if detail == nil || detail.Message != "password_confirmation phải trùng password" || detail.Value != "" {
+		t.Errorf("EqualsField mismatch = %+v, want no value reported", detail)
+	}
+	if detail := v.EqualsField("password_confirmation", "secret", "password", "secret", MsgValidationEqualsField); detail != nil {
+		t.Errorf("EqualsField match = %+v", detail)
+	}
+
+	detail = v.Prefix("contact").RequiredWithout(map[string]any{"phone": "", "email": nil}, MsgValidationRequiredOne)
+	if detail == nil || detail.Field != "contact.email,contact.phone" || detail.Message != "Cần ít nhất một trong contact.email, contact.phone" {
+		t.Errorf("RequiredWithout none = %+v, want both fields named", detail)
+	}
+	if detail := v.RequiredWithout(map[string]any{"phone": "0901", "email": ""}, MsgValidationRequiredOne); detail != nil {
+		t.Errorf("RequiredWithout one = %+v", detail)
+	}
+}
+
+func TestPrefixPaths(t *testing.T) {
+	v := &BaseValidator{}
+	items := v.Prefix("items")
+	item := v.Prefix(IndexedField("items", 2))
+
+	tests := []struct {
+		name   string
+		detail *ErrorDetail
+		want   string
+	}{
+		{"unprefixed", v.Required("email", "", MsgValidationRequired), "email"},
+		{"dotted", v.Prefix("address").Required("city", "", MsgValidationRequired), "address.city"},
+		{"indexed element", item.MinValue("quantity", 0, 1, MsgValidationMinValue), "items[2].quantity"},
+		{"index under prefix", items.Required(IndexedField("", 0), "", MsgValidationRequired), "items[0]"},
+		{"two levels", item.Prefix("address").Required("city", "", MsgValidationRequired), "items[2].address.city"},
+		{"date rule", item.DateFormat("at", "x", "2006-01-02", MsgValidationDateFormat), "items[2].at"},
+		{"decimal rule", item.NumericString("price", "1,5", MsgValidationNumericStr), "items[2].price"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.detail == nil || tt.detail.Field != tt.want {
+				t.Errorf("detail = %+v, want field %q", tt.detail, tt.want)
+			}
+		})
+	}
+
+	if v.Required("email", "", MsgValidationRequired).Field != "email" {
+		t.Error("Prefix modified the parent validator")
+	}
+}
+
+func TestHandleValidationKeepsPaths(t *testing.T) {
+	v := &BaseValidator{}
+	item := v.Prefix(IndexedField("items", 1))
+	result := v.ValidateMultiple(
+		v.Required("email", "", MsgValidationRequired),
+		item.MinValue("quantity", 0, 1, MsgValidationMinValue),
+	)
+
+	controller := &BaseController[testItem]{}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/orders", nil), rec)
+	if err := controller.HandleValidation(c, result, MsgErrorValidation); err != nil {
+		t.Fatal(err)
+	}
+
+	var body ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Details) != 2 || body.Details[0].Field != "email" || body.Details[1].Field != "items[1].quantity" {
+		t.Errorf("details = %+v, want the validated paths unchanged", body.Details)
+	}
+}
@@ -0,0 +1,52 @@
+package common
+
+// NewValidationResult creates an empty, valid result to add errors to
+func NewValidationResult() ValidationResult {
+	return ValidationResult{IsValid: true}
+}
+
+// Valid reports whether the result has no errors, it does not rely on IsValid being up to date
+func (r ValidationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Merge appends the errors of other after the errors of r, e.g. request shape then business rules
+func (r *ValidationResult) Merge(other ValidationResult) *ValidationResult {
+	r.Errors = append(r.Errors, other.Errors...)
+	r.IsValid = r.Valid()
+	return r
+}
+
+// Add appends the non nil details returned by BaseValidator rules
+func (r *ValidationResult) Add(details ...*ErrorDetail) *ValidationResult {
+	for _, detail := range details {
+		if detail != nil {
+			r.Errors = append(r.Errors, *detail)
+		}
+	}
+	r.IsValid = r.Valid()
+	return r
+}
+
+// AddError appends an error for field with the i18n message of messageKey, {field} is interpolated.
+// The value is redacted for sensitive fields
+func (r *ValidationResult) AddError(field, messageKey string, value string) *ValidationResult {
+	return r.AddErrorf(field, messageKey, value, nil)
+}
+
+// AddErrorf is like AddError with extra params interpolated into the message
+func (r *ValidationResult) AddErrorf(field, messageKey string, value string, params map[string]any) *ValidationResult {
+	merged := make(map[string]any, len(params)+1)
+	for name, param := range params {
+		merged[name] = param
+	}
+	merged["field"] = field
+
+	r.Errors = append(r.Errors, ErrorDetail{
+		Field:   field,
+		Message: Tf(messageKey, merged),
+		Value:   RedactValue(field, value),
+	})
+	r.IsValid = false
+	return r
+}
@@ -0,0 +1,76 @@
+package common
+
+import "testing"
+
+func TestValidationResultBuilder(t *testing.T) {
+	useGlobalI18n(t, newTestI18n("en", map[string]any{
+		"validation": map[string]any{
+			"required": "{field} is required",
+			"taken":    "{field} {value} is taken",
+		},
+	}))
+
+	result := NewValidationResult()
+	if !result.IsValid || !result.Valid() {
+		t.Fatal("new result is not valid")
+	}
+
+	result.Add(nil, nil)
+	if !result.IsValid {
+		t.Error("adding nil details made the result invalid")
+	}
+
+	v := &BaseValidator{}
+	result.Add(v.Required("email", "", "validation.required"))
+	result.AddErrorf("username", "validation.taken", "bob", map[string]any{"value": "bob", "field": "ignored"})
+	result.AddError("password", "validation.required", "hunter2")
+
+	if result.IsValid || result.Valid() {
+		t.Error("result with errors is valid")
+	}
+	want := []ErrorDetail{
+		{Field: "email", Message: "email is required"},
+		{Field: "username", Message: "username bob is taken", Value: "bob"},
+		{Field: "password", Message: "password is required", Value: RedactedValue},
+	}
+	if len(result.Errors) != len(want) {
+		t.Fatalf("errors = %+v, want %+v", result.Errors, want)
+	}
+	for i := range want {
+		if result.Errors[i] != want[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, result.Errors[i], want[i])
+		}
+	}
+}
+
+func TestValidationResultMerge(t *testing.T) {
+	shape := NewValidationResult()
+	shape.AddError("email", "validation.required", "")
+	rules := NewValidationResult()
+	rules.AddError("total", "validation.min_value", "0")
+	rules.AddError("items", "validation.min_length", "")
+
+	merged := NewValidationResult()
+	merged.Merge(shape).Merge(NewValidationResult()).Merge(rules)
+
+	if merged.IsValid {
+		t.Error("merged result with errors is valid")
+	}
+	fields := make([]string, 0, len(merged.Errors))
+	for _, detail := range merged.Errors {
+		fields = append(fields, detail.Field)
+	}
+	if len(fields) != 3 || fields[0] != "email" || fields[1] != "total" || fields[2] != "items" {
+		t.Errorf("merged fields = %v, want the errors in merge order", fields)
+	}
+
+	// IsValid is recomputed, even from a stale value
+	stale := ValidationResult{IsValid: false}
+	stale.Merge(NewValidationResult())
+	if !stale.IsValid {
+		t.Error("merging valid results kept a stale IsValid")
+	}
+	if len(shape.Errors) != 1 {
+		t.Errorf("merge modified its argument: %+v", shape.Errors)
+	}
+}
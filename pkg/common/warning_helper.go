@@ -0,0 +1,30 @@
+package common
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// ContextKeyWarnings is the echo context key holding the warning message keys of the request
+const ContextKeyWarnings = "responseWarnings"
+
+// AddWarning records a warning message key for the current request.
+// Warnings are translated and flushed into the success response, in the order they were added
+func AddWarning(c echo.Context, messageKey string) {
+	keys, _ := c.Get(ContextKeyWarnings).([]string)
+	c.Set(ContextKeyWarnings, append(keys, messageKey))
+}
+
+// GetWarnings returns the warnings recorded for the current request, translated with the request locale
+func GetWarnings(c echo.Context) []string {
+	keys, _ := c.Get(ContextKeyWarnings).([]string)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ctx := LocaleContext(c)
+	warnings := make([]string, 0, len(keys))
+	for _, key := range keys {
+		warnings = append(warnings, TWithContext(ctx, key))
+	}
+	return warnings
+}
@@ -0,0 +1,103 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestAddWarningKeepsOrder(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	if warnings := GetWarnings(c); warnings != nil {
+		t.Errorf("warnings = %v before any was added, want nil", warnings)
+	}
+
+	AddWarning(c, "test.warning.first")
+	AddWarning(c, "test.warning.second")
+	AddWarning(c, "test.warning.first")
+
+	got := GetWarnings(c)
+	want := []string{"test.warning.first", "test.warning.second", "test.warning.first"}
+	if len(got) != len(want) {
+		t.Fatalf("warnings = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("warnings = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSuccessWithMetaAndWarnings(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	controller.AddWarning(c, "test.warning.deprecated")
+	controller.AddWarning(c, "test.warning.partial")
+	meta := map[string]any{"rate_limit_remaining": 9}
+	if err := controller.SuccessWithMeta(c, testItem{ID: "a"}, MsgSuccessRetrieved, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Data     testItem       `json:"data"`
+		Meta     map[string]any `json:"meta"`
+		Warnings []string       `json:"warnings"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.ID != "a" {
+		t.Errorf("data = %+v", body.Data)
+	}
+	if body.Meta["rate_limit_remaining"] != float64(9) {
+		t.Errorf("meta = %v", body.Meta)
+	}
+	if len(body.Warnings) != 2 || body.Warnings[0] != "test.warning.deprecated" || body.Warnings[1] != "test.warning.partial" {
+		t.Errorf("warnings = %v, want both in order", body.Warnings)
+	}
+}
+
+func TestSuccessOmitsEmptyMetaAndWarnings(t *testing.T) {
+	controller := &BaseController[testItem]{}
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	if err := controller.SuccessWithMeta(c, testItem{ID: "a"}, MsgSuccessRetrieved, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"meta", "warnings"} {
+		if _, ok := body[field]; ok {
+			t.Errorf("%s = %s, want omitted when empty", field, body[field])
+		}
+	}
+}
+
+func TestSuccessResponseBuilderMeta(t *testing.T) {
+	response := NewSuccessResponse().
+		WithMeta(map[string]any{"a": 1, "b": 1}).
+		WithMeta(map[string]any{"b": 2}).
+		WithWarnings("first").
+		WithWarnings("second").
+		Build()
+
+	if response.Meta["a"] != 1 || response.Meta["b"] != 2 {
+		t.Errorf("meta = %v, want later values to win", response.Meta)
+	}
+	if len(response.Warnings) != 2 || response.Warnings[0] != "first" {
+		t.Errorf("warnings = %v", response.Warnings)
+	}
+}
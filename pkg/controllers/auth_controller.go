@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// Error codes of the auth endpoints
+const (
+	ErrorCodeInvalidCredentials  = "invalid_credentials"
+	ErrorCodeInvalidRefreshToken = "invalid_refresh_token"
+)
+
+// ErrInvalidCredentials is returned by UserLookup.FindByCredentials for unknown users and wrong passwords
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// UserLookup finds the users of AuthController
+type UserLookup interface {
+	// FindByCredentials returns the user of email and password, ErrInvalidCredentials when they do not match
+	FindByCredentials(ctx context.Context, email, password string) (*models.OAuthUser, error)
+	// FindByID returns the user id, used to reissue access tokens on refresh
+	FindByID(ctx context.Context, id string) (*models.OAuthUser, error)
+}
+
+// LoginRequest is the body of POST /login
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email" example:"john.doe@example.com"`
+	Password string `json:"password" validate:"required" example:"secret"`
+}
+
+// AuthControllerConfig configures AuthController, empty fields get defaults
+type AuthControllerConfig struct {
+	// Issuer is the issuer of the tokens, "msa-core" by default
+	Issuer string
+	// AccessTokenTTL is the lifetime of access tokens, 15 minutes by default
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is the lifetime of refresh tokens and sessions, 7 days by default
+	RefreshTokenTTL time.Duration
+	// Scopes returns the scopes of the tokens of user, none by default
+	Scopes func(user models.OAuthUser) []string
+}
+
+// AuthController exposes the login, refresh, logout and me endpoints of a JWTService
+type AuthController struct {
+	common.BaseController[models.OAuthUser]
+	jwtService services.JWTService
+	users      UserLookup
+	cfg        AuthControllerConfig
+}
+
+// NewAuthController creates an AuthController authenticating users with users
+func NewAuthController(jwtService services.JWTService, users UserLookup, cfg AuthControllerConfig) *AuthController {
+	if cfg.Issuer == "" {
+		cfg.Issuer = "msa-core"
+	}
+	if cfg.AccessTokenTTL <= 0 {
+		cfg.AccessTokenTTL = 15 * time.Minute
+	}
+	if cfg.RefreshTokenTTL <= 0 {
+		cfg.RefreshTokenTTL = 7 * 24 * time.Hour
+	}
+	if cfg.Scopes == nil {
+		cfg.Scopes = func(models.OAuthUser) []string { return nil }
+	}
+	return &AuthController{jwtService: jwtService, users: users, cfg: cfg}
+}
+
+// RegisterRoutes registers POST /login, POST /refresh, POST /logout and GET /me on g. Protect /login
+// against credential stuffing, e.g. with middleware.LoginThrottle
+func (ctl *AuthController) RegisterRoutes(g *echo.Group, loginMiddlewares ...echo.MiddlewareFunc) {
+	g.POST("/login", ctl.Login, loginMiddlewares...)
+	g.POST("/refresh", ctl.Refresh)
+	g.POST("/logout", ctl.Logout)
+	g.GET("/me", ctl.Me)
+}
+
+// Login authenticates email and password and answers an AuthResponse with a new session
+func (ctl *AuthController) Login(c echo.Context) error {
+	var req LoginRequest
+	if err := c.Bind(&req); err != nil {
+		return ctl.HandleBindError(c, err, common.MsgErrorValidation)
+	}
+	if err := ctl.HandleValidation(c, common.ValidateStruct(common.LocaleContext(c), req), common.MsgErrorValidation); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	user, err := ctl.users.FindByCredentials(ctx, req.Email, req.Password)
+	if errors.Is(err, ErrInvalidCredentials) || err == nil && user == nil {
+		errorResp := common.UnauthorizedError(common.MsgErrorInvalidCredentials)
+		errorResp.ErrorCode = ErrorCodeInvalidCredentials
+		return ctl.Error(c, errorResp, nil)
+	}
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+
+	sid := uuid.NewString()
+	if err := ctl.jwtService.CreateSession(ctx, sid, user.ID, ctl.cfg.RefreshTokenTTL); err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	response, err := ctl.issueTokens(*user, sid)
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return ctl.Success(c, response)
+}
+
+// Refresh rotates a refresh token: the token is revoked and a new access and refresh token pair of
+// the same session is answered
+func (ctl *AuthController) Refresh(c echo.Context) error {
+	var req common.RefreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return ctl.HandleBindError(c, err, common.MsgErrorValidation)
+	}
+	if err := ctl.HandleValidation(c, common.ValidateStruct(common.LocaleContext(c), req), common.MsgErrorValidation); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userID, sid, err := ctl.jwtService.ValidateRefreshToken(req.RefreshToken)
+	if errors.Is(err, services.ErrTokenRevoked) {
+		return ctl.tokenRevoked(c)
+	}
+	if err != nil {
+		return ctl.invalidRefreshToken(c)
+	}
+	// Single use refresh tokens: the token is consumed before anything is issued, so a stolen token
+	// replayed after or during the rotation is rejected
+	consumed, err := ctl.consumeRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	if !consumed {
+		return ctl.tokenRevoked(c)
+	}
+	if err := ctl.jwtService.ExtendSession(ctx, sid, ctl.cfg.RefreshTokenTTL); errors.Is(err, services.ErrSessionNotFound) {
+		return ctl.invalidRefreshToken(c)
+	} else if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+
+	user, err := ctl.users.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ctl.invalidRefreshToken(c)
+	}
+	response, err := ctl.issueTokens(*user, sid)
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return ctl.Success(c, response)
+}
+
+// consumeRefreshToken blacklists the refresh token and reports whether it was not blacklisted yet, atomically
+// when the JWT service implements services.TokenConsumer
+func (ctl *AuthController) consumeRefreshToken(ctx context.Context, refreshToken string) (bool, error) {
+	if consumer, ok := ctl.jwtService.(services.TokenConsumer); ok {
+		return consumer.ConsumeToken(ctx, refreshToken)
+	}
+	revoked, err := ctl.jwtService.IsTokenBlacklisted(ctx, refreshToken)
+	if err != nil || revoked {
+		return false, err
+	}
+	return true, ctl.jwtService.BlacklistToken(ctx, refreshToken)
+}
+
+// Logout blacklists the bearer access token and the refresh token of the body when present, and ends
+// the session
+func (ctl *AuthController) Logout(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return ctl.Error(c, common.UnauthorizedError(common.MsgErrorUnauthorized), nil)
+	}
+	ctx := c.Request().Context()
+	claims, err := ctl.jwtService.ValidateToken(token)
+	if err != nil {
+		return ctl.Error(c, common.UnauthorizedError(common.MsgErrorUnauthorized), nil)
+	}
+
+	var req common.RefreshTokenRequest
+	_ = c.Bind(&req)
+
+	if err := ctl.jwtService.BlacklistToken(ctx, token); err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	if req.RefreshToken != "" {
+		if err := ctl.jwtService.BlacklistToken(ctx, req.RefreshToken); err != nil {
+			return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+		}
+	}
+	if err := ctl.jwtService.DeleteSession(ctx, claims.SID); err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	return ctl.Success(c, common.LogoutResponse{Message: "Logged out successfully"})
+}
+
+// Me answers the user of the bearer token after full validation
+func (ctl *AuthController) Me(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return ctl.Error(c, common.UnauthorizedError(common.MsgErrorUnauthorized), nil)
+	}
+	user, err := ctl.jwtService.ExtractUser(c.Request().Context(), token)
+	if errors.Is(err, services.ErrTokenRevoked) {
+		return ctl.tokenRevoked(c)
+	}
+	if err != nil {
+		return ctl.Error(c, common.UnauthorizedError(common.MsgErrorUnauthorized), nil)
+	}
+	return ctl.Success(c, user)
+}
+
+// issueTokens issues the access and refresh tokens of user for the session sid
+func (ctl *AuthController) issueTokens(user models.OAuthUser, sid string) (common.AuthResponse, error) {
+	accessToken, err := ctl.jwtService.GenerateToken(user, ctl.cfg.Scopes(user), ctl.cfg.Issuer, sid, ctl.cfg.AccessTokenTTL)
+	if err != nil {
+		return common.AuthResponse{}, err
+	}
+	refreshToken, err := ctl.jwtService.GenerateRefreshToken(user, ctl.cfg.Issuer, sid, ctl.cfg.RefreshTokenTTL)
+	if err != nil {
+		return common.AuthResponse{}, err
+	}
+	return common.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(ctl.cfg.AccessTokenTTL.Seconds()),
+		JWTToken:     accessToken,
+		User:         user,
+	}, nil
+}
+
+// tokenRevoked writes the 401 token_revoked error response
+func (ctl *AuthController) tokenRevoked(c echo.Context) error {
+	errorResp := common.UnauthorizedError(common.MsgErrorTokenRevoked)
+	errorResp.ErrorCode = ErrorCodeTokenRevoked
+	return ctl.Error(c, errorResp, nil)
+}
+
+// invalidRefreshToken writes the 401 invalid_refresh_token error response of expired or ended sessions
+func (ctl *AuthController) invalidRefreshToken(c echo.Context) error {
+	errorResp := common.UnauthorizedError(common.MsgErrorInvalidRefresh)
+	errorResp.ErrorCode = ErrorCodeInvalidRefreshToken
+	return ctl.Error(c, errorResp, nil)
+}
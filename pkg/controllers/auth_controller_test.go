@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// fakeUsers is a UserLookup of a single user with the password "s3cret"
+type fakeUsers struct {
+	user models.OAuthUser
+}
+
+func (f fakeUsers) FindByCredentials(_ context.Context, email, password string) (*models.OAuthUser, error) {
+	if email != f.user.Email || password != "s3cret" {
+		return nil, ErrInvalidCredentials
+	}
+	user := f.user
+	return &user, nil
+}
+
+func (f fakeUsers) FindByID(_ context.Context, id string) (*models.OAuthUser, error) {
+	if id != f.user.ID {
+		return nil, ErrInvalidCredentials
+	}
+	user := f.user
+	return &user, nil
+}
+
+// authFlow serves the AuthController routes
+type authFlow struct {
+	t          *testing.T
+	e          *echo.Echo
+	jwtService services.JWTService
+}
+
+func newAuthFlow(t *testing.T) *authFlow {
+	return newAuthFlowWith(t, services.NewJWTService("test-secret", nil))
+}
+
+// newAuthFlowWith serves the AuthController routes on jwtService
+func newAuthFlowWith(t *testing.T, jwtService services.JWTService) *authFlow {
+	e := echo.New()
+	NewAuthController(jwtService, fakeUsers{user: models.OAuthUser{ID: "u1", Email: "u1@example.com"}}, AuthControllerConfig{}).
+		RegisterRoutes(e.Group(""))
+	return &authFlow{t: t, e: e, jwtService: jwtService}
+}
+
+// do serves a request with a JSON body and an optional bearer token
+func (f *authFlow) do(method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	f.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// tokens decodes the AuthResponse of a successful login or refresh
+func (f *authFlow) tokens(rec *httptest.ResponseRecorder) common.AuthResponse {
+	f.t.Helper()
+	var body struct {
+		Data common.AuthResponse `json:"data"`
+	}
+	if rec.Code != http.StatusOK {
+		f.t.Fatalf("response = %d %s, want 200", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		f.t.Fatal(err)
+	}
+	return body.Data
+}
+
+// login logs u1 in
+func (f *authFlow) login() common.AuthResponse {
+	f.t.Helper()
+	return f.tokens(f.do(http.MethodPost, "/login", `{"email":"u1@example.com","password":"s3cret"}`, ""))
+}
+
+// refresh posts refreshToken to /refresh
+func (f *authFlow) refresh(refreshToken string) *httptest.ResponseRecorder {
+	return f.do(http.MethodPost, "/refresh", `{"refresh_token":"`+refreshToken+`"}`, "")
+}
+
+// expectError checks the status and error code of an error response
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != status || body.ErrorCode != code {
+		t.Errorf("response = %d %s, want %d with error code %q", rec.Code, rec.Body.String(), status, code)
+	}
+}
+
+func TestAuthFlow(t *testing.T) {
+	f := newAuthFlow(t)
+
+	login := f.login()
+	if login.AccessToken == "" || login.RefreshToken == "" || login.TokenType != "Bearer" || login.ExpiresIn != 900 || login.User == nil {
+		t.Fatalf("login = %+v, want a 15 minutes token pair of u1", login)
+	}
+	if rec := f.do(http.MethodGet, "/me", "", login.AccessToken); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"u1"`) {
+		t.Errorf("me = %d %s, want u1", rec.Code, rec.Body.String())
+	}
+
+	// Rotation: the refresh token is single use
+	rotated := f.tokens(f.refresh(login.RefreshToken))
+	if rotated.RefreshToken == login.RefreshToken || rotated.AccessToken == "" {
+		t.Fatalf("refresh = %+v, want a new token pair", rotated)
+	}
+	expectError(t, f.refresh(login.RefreshToken), http.StatusUnauthorized, ErrorCodeTokenRevoked)
+
+	// Logout revokes the access token and the refresh token of the body
+	rec := f.do(http.MethodPost, "/logout", `{"refresh_token":"`+rotated.RefreshToken+`"}`, rotated.AccessToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout = %d %s, want 200", rec.Code, rec.Body.String())
+	}
+	expectError(t, f.do(http.MethodGet, "/me", "", rotated.AccessToken), http.StatusUnauthorized, ErrorCodeTokenRevoked)
+	expectError(t, f.refresh(rotated.RefreshToken), http.StatusUnauthorized, ErrorCodeTokenRevoked)
+}
+
+// slowBlacklistStore is a blacklist store without AddIfAbsent whose lookups are slow enough to interleave
+// concurrent refreshes
+type slowBlacklistStore struct {
+	services.TokenBlacklistStore
+}
+
+func (s slowBlacklistStore) Exists(ctx context.Context, key string) (bool, error) {
+	time.Sleep(time.Millisecond)
+	return s.TokenBlacklistStore.Exists(ctx, key)
+}
+
+func TestAuthRefreshConcurrent(t *testing.T) {
+	tests := []struct {
+		name       string
+		jwtService services.JWTService
+	}{
+		{"atomic store", services.NewJWTService("test-secret", nil)},
+		{"store without AddIfAbsent", services.NewJWTService("test-secret", nil,
+			services.WithTokenBlacklistStore(slowBlacklistStore{services.NewMemoryTokenBlacklistStore()}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFlowWith(t, tt.jwtService)
+			login := f.login()
+
+			const n = 20
+			recs := make([]*httptest.ResponseRecorder, n)
+			var wg sync.WaitGroup
+			for i := range recs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					recs[i] = f.refresh(login.RefreshToken)
+				}()
+			}
+			wg.Wait()
+
+			rotated := 0
+			for _, rec := range recs {
+				if rec.Code == http.StatusOK {
+					rotated++
+					continue
+				}
+				expectError(t, rec, http.StatusUnauthorized, ErrorCodeTokenRevoked)
+			}
+			if rotated != 1 {
+				t.Errorf("%d of %d concurrent refreshes rotated the token, want exactly 1", rotated, n)
+			}
+		})
+	}
+}
+
+func TestAuthLoginErrors(t *testing.T) {
+	f := newAuthFlow(t)
+
+	expectError(t, f.do(http.MethodPost, "/login", `{"email":"u1@example.com","password":"wrong"}`, ""), http.StatusUnauthorized, ErrorCodeInvalidCredentials)
+	expectError(t, f.do(http.MethodPost, "/login", `{"email":"other@example.com","password":"s3cret"}`, ""), http.StatusUnauthorized, ErrorCodeInvalidCredentials)
+	if rec := f.do(http.MethodPost, "/login", `{"email":"not-an-email"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid login body = %d, want 400", rec.Code)
+	}
+}
+
+func TestAuthRefreshErrors(t *testing.T) {
+	f := newAuthFlow(t)
+	login := f.login()
+	expired, err := f.jwtService.GenerateRefreshToken(models.OAuthUser{ID: "u1"}, "msa-core", "sid-1", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, f.refresh(expired), http.StatusUnauthorized, ErrorCodeInvalidRefreshToken)
+	expectError(t, f.refresh("garbage"), http.StatusUnauthorized, ErrorCodeInvalidRefreshToken)
+	// An access token is not a refresh token, nor is a refresh token an access token
+	expectError(t, f.refresh(login.AccessToken), http.StatusUnauthorized, ErrorCodeInvalidRefreshToken)
+	if rec := f.do(http.MethodGet, "/me", "", login.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("me with a refresh token = %d, want 401", rec.Code)
+	}
+	if rec := f.do(http.MethodPost, "/logout", "", login.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("logout with a refresh token = %d, want 401", rec.Code)
+	}
+	if rec := f.do(http.MethodGet, "/me", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("me without token = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// OAuth2 error codes of the token endpoint (RFC 6749 5.2)
+const (
+	ErrorCodeInvalidClient        = "invalid_client"
+	ErrorCodeInvalidScope         = "invalid_scope"
+	ErrorCodeUnsupportedGrantType = "unsupported_grant_type"
+)
+
+// RegisterClientRequest is the body of client registrations
+type RegisterClientRequest struct {
+	Name         string   `json:"name" validate:"required" example:"billing-worker"`
+	Scopes       []string `json:"scopes" example:"[\"orders:read\"]"`
+	RedirectURIs []string `json:"redirect_uris" example:"[\"https://billing.example.com/callback\"]"`
+}
+
+// OAuthClientController exposes the client_credentials token endpoint and the management of OAuth clients
+type OAuthClientController struct {
+	common.BaseController[models.OAuthClient]
+	clientService services.OAuthClientService
+}
+
+// NewOAuthClientController creates an OAuthClientController
+func NewOAuthClientController(clientService services.OAuthClientService) *OAuthClientController {
+	return &OAuthClientController{clientService: clientService}
+}
+
+// RegisterRoutes registers POST /oauth/token on g, and GET /oauth/clients, POST /oauth/clients and
+// DELETE /oauth/clients/:id behind adminMiddlewares, which must restrict them to administrators
+func (ctl *OAuthClientController) RegisterRoutes(g *echo.Group, adminMiddlewares ...echo.MiddlewareFunc) {
+	g.POST("/oauth/token", ctl.Token)
+	g.GET("/oauth/clients", ctl.ListClients, adminMiddlewares...)
+	g.POST("/oauth/clients", ctl.RegisterClient, adminMiddlewares...)
+	g.DELETE("/oauth/clients/:id", ctl.RevokeClient, adminMiddlewares...)
+}
+
+// Token issues an access token for the client_credentials grant. Clients authenticate with HTTP Basic
+// or the client_id and client_secret form parameters, and may narrow their scopes with "scope"
+func (ctl *OAuthClientController) Token(c echo.Context) error {
+	if grantType := c.FormValue("grant_type"); grantType != "client_credentials" {
+		errorResp := common.BadRequestError(common.MsgErrorBadRequest)
+		errorResp.ErrorCode = ErrorCodeUnsupportedGrantType
+		return ctl.Error(c, errorResp, nil)
+	}
+
+	clientID, clientSecret, ok := c.Request().BasicAuth()
+	if !ok {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+
+	ctx := c.Request().Context()
+	client, err := ctl.clientService.ValidateClientCredentials(ctx, clientID, clientSecret)
+	if errors.Is(err, services.ErrInvalidClientCredentials) {
+		if ok {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+		}
+		errorResp := common.UnauthorizedError(common.MsgErrorUnauthorized)
+		errorResp.ErrorCode = ErrorCodeInvalidClient
+		return ctl.Error(c, errorResp, nil)
+	}
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+
+	response, err := ctl.clientService.IssueClientToken(ctx, client, strings.Fields(c.FormValue("scope")))
+	if errors.Is(err, services.ErrInvalidClientScope) {
+		errorResp := common.BadRequestError(common.MsgErrorInsufficientScope)
+		errorResp.ErrorCode = ErrorCodeInvalidScope
+		return ctl.Error(c, errorResp, nil)
+	}
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, response)
+}
+
+// ListClients answers the registered clients, without their secrets
+func (ctl *OAuthClientController) ListClients(c echo.Context) error {
+	clients, err := ctl.clientService.ListClients(c.Request().Context())
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+
+	response := common.ClientListResponse{Clients: make([]any, 0, len(clients))}
+	for _, client := range clients {
+		response.Clients = append(response.Clients, client)
+	}
+	return ctl.ResponseRetrieved(c, response)
+}
+
+// RegisterClient registers a client, the response is the only one carrying its secret
+func (ctl *OAuthClientController) RegisterClient(c echo.Context) error {
+	var req RegisterClientRequest
+	if err := c.Bind(&req); err != nil {
+		return ctl.HandleBindError(c, err, common.MsgErrorValidation)
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return ctl.ValidationError(c, common.ErrorDetail{Field: "name", Message: "name is required"})
+	}
+
+	credentials, err := ctl.clientService.RegisterClient(c.Request().Context(), req.Name, req.Scopes, req.RedirectURIs)
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return ctl.ResponseCreated(c, common.ClientCreateResponse{
+		Message: "Client registered successfully",
+		Client:  credentials,
+	})
+}
+
+// RevokeClient revokes a client and the tokens it was issued
+func (ctl *OAuthClientController) RevokeClient(c echo.Context) error {
+	err := ctl.clientService.RevokeClient(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, services.ErrOAuthClientNotFound) {
+		return ctl.Error(c, common.NotFoundError(common.MsgErrorNotFound), nil)
+	}
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	return ctl.ResponseDeleted(c)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// newClientServer registers the OAuthClientController routes over an in-memory client store
+func newClientServer(t *testing.T) (*echo.Echo, services.JWTService, *models.ClientCredentials) {
+	t.Helper()
+	jwtService := services.NewJWTService("test-secret", nil)
+	clients := services.NewOAuthClientService(services.NewMemoryOAuthClientStore(), jwtService, nil)
+	credentials, err := clients.RegisterClient(context.Background(), "billing-worker", []string{"orders:write", "profile"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	NewOAuthClientController(clients).RegisterRoutes(e.Group(""))
+	return e, jwtService, credentials
+}
+
+// requestToken posts form to /oauth/token, with HTTP Basic credentials when id is set
+func requestToken(e *echo.Echo, form url.Values, id, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if id != "" {
+		req.SetBasicAuth(id, secret)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// tokenErrorCode decodes the error code of a token endpoint error response
+func tokenErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return body.ErrorCode
+}
+
+func TestTokenClientCredentials(t *testing.T) {
+	e, jwtService, credentials := newClientServer(t)
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"basic auth": requestToken(e, url.Values{"grant_type": {"client_credentials"}}, credentials.ClientID, credentials.ClientSecret),
+		"form": requestToken(e, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {credentials.ClientID},
+			"client_secret": {credentials.ClientSecret},
+		}, "", ""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderCacheControl) != "no-store" {
+				t.Fatalf("response = %d %s, want 200 no-store", rec.Code, rec.Body.String())
+			}
+			var response models.ClientTokenResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			claims, err := jwtService.ValidateToken(response.AccessToken)
+			if err != nil || claims.User.ID != credentials.ClientID || response.Scope != "orders:write profile" {
+				t.Errorf("token = %+v, %v, want a client token with the client scopes", response, err)
+			}
+		})
+	}
+}
+
+func TestTokenErrors(t *testing.T) {
+	e, _, credentials := newClientServer(t)
+	grant := url.Values{"grant_type": {"client_credentials"}}
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		id, secret string
+		wantStatus int
+		wantCode   string
+	}{
+		{"unsupported grant", url.Values{"grant_type": {"password"}}, credentials.ClientID, credentials.ClientSecret, http.StatusBadRequest, ErrorCodeUnsupportedGrantType},
+		{"wrong secret", grant, credentials.ClientID, "wrong", http.StatusUnauthorized, ErrorCodeInvalidClient},
+		{"no credentials", grant, "", "", http.StatusUnauthorized, ErrorCodeInvalidClient},
+		{"scope beyond the client", url.Values{"grant_type": {"client_credentials"}, "scope": {"orders:read admin"}}, credentials.ClientID, credentials.ClientSecret, http.StatusBadRequest, ErrorCodeInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := requestToken(e, tt.form, tt.id, tt.secret)
+			if rec.Code != tt.wantStatus || tokenErrorCode(t, rec) != tt.wantCode {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+
+	rec := requestToken(e, grant, credentials.ClientID, "wrong")
+	if rec.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+		t.Error("Basic authentication failure without WWW-Authenticate")
+	}
+}
+
+func TestClientManagement(t *testing.T) {
+	e, jwtService, credentials := newClientServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/clients", strings.NewReader(`{"name":"reports","scopes":["reports:read"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "client_secret") {
+		t.Fatalf("register = %d %s, want 200 with the secret", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/oauth/clients", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret") || !strings.Contains(rec.Body.String(), "reports") {
+		t.Errorf("list = %d %s, want the clients without secrets", rec.Code, rec.Body.String())
+	}
+
+	token := requestToken(e, url.Values{"grant_type": {"client_credentials"}}, credentials.ClientID, credentials.ClientSecret)
+	var response models.ClientTokenResponse
+	if err := json.Unmarshal(token.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/oauth/clients/"+credentials.ClientID, nil))
+	if rec.Code >= 300 {
+		t.Fatalf("revoke = %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := jwtService.ValidateToken(response.AccessToken); err == nil {
+		t.Error("token of a revoked client is still valid")
+	}
+	if rec := requestToken(e, url.Values{"grant_type": {"client_credentials"}}, credentials.ClientID, credentials.ClientSecret); rec.Code != http.StatusUnauthorized {
+		t.Errorf("token of a revoked client = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/oauth/clients/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("revoke of an unknown client = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// ErrorCodeTokenRevoked is the error code of responses rejecting a revoked token
+const ErrorCodeTokenRevoked = "token_revoked"
+
+// OAuthController exposes the token introspection and userinfo endpoints of a JWTService
+type OAuthController struct {
+	common.BaseController[models.OAuthUser]
+	jwtService services.JWTService
+}
+
+// NewOAuthController creates an OAuthController
+func NewOAuthController(jwtService services.JWTService) *OAuthController {
+	return &OAuthController{jwtService: jwtService}
+}
+
+// RegisterRoutes registers POST /oauth/introspect and GET /oauth/userinfo on g. Introspection
+// reveals token contents, protect it with client authentication (e.g. APIKeyAuth)
+func (ctl *OAuthController) RegisterRoutes(g *echo.Group, introspectMiddlewares ...echo.MiddlewareFunc) {
+	g.POST("/oauth/introspect", ctl.Introspect, introspectMiddlewares...)
+	g.GET("/oauth/userinfo", ctl.UserInfo)
+}
+
+// Introspect answers RFC 7662 introspection of the "token" form parameter, inactive tokens are a
+// 200 response with active=false
+func (ctl *OAuthController) Introspect(c echo.Context) error {
+	token := c.FormValue("token")
+	if token == "" {
+		return ctl.ValidationError(c, common.ErrorDetail{Field: "token", Message: "token is required"})
+	}
+
+	response, err := ctl.jwtService.Introspect(c.Request().Context(), token)
+	if err != nil {
+		return ctl.Error(c, common.InternalError(common.MsgErrorInternal).WithCause(err), nil)
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, response)
+}
+
+// UserInfo answers the user of the bearer token, only after full validation of the token
+func (ctl *OAuthController) UserInfo(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return ctl.Error(c, common.UnauthorizedError(common.MsgErrorUnauthorized), nil)
+	}
+
+	user, err := ctl.jwtService.UserInfo(c.Request().Context(), token)
+	if errors.Is(err, services.ErrTokenRevoked) {
+		errorResp := common.UnauthorizedError(common.MsgErrorTokenRevoked)
+		errorResp.ErrorCode = ErrorCodeTokenRevoked
+		return ctl.Error(c, errorResp, nil)
+	}
+	if err != nil {
+		return ctl.Error(c, common.UnauthorizedError(common.MsgErrorUnauthorized), nil)
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, user)
+}
+
+// bearerToken returns the token of the Authorization header
+func bearerToken(c echo.Context) (string, bool) {
+	token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return token, ok && token != ""
+}
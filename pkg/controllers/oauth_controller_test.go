@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// newOAuthServer registers the OAuthController routes of jwtService
+func newOAuthServer(jwtService services.JWTService) *echo.Echo {
+	e := echo.New()
+	NewOAuthController(jwtService).RegisterRoutes(e.Group(""))
+	return e
+}
+
+// issueOAuthToken returns an access token of user u1 expiring after expiresIn
+func issueOAuthToken(t *testing.T, jwtService services.JWTService, expiresIn time.Duration) string {
+	t.Helper()
+	token, err := jwtService.IssueToken(services.TokenRequest{
+		User:      models.OAuthUser{ID: "u1", Email: "u1@example.com"},
+		Scopes:    []string{"profile"},
+		Issuer:    "test",
+		SID:       "sid-1",
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// introspect posts token to /oauth/introspect
+func introspect(e *echo.Echo, token string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	if token != "" {
+		form.Set("token", token)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// userInfo requests /oauth/userinfo with token as bearer token
+func userInfo(e *echo.Echo, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/oauth/userinfo", nil)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIntrospectHandler(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil)
+	e := newOAuthServer(jwtService)
+	revoked := issueOAuthToken(t, jwtService, time.Hour)
+	if err := jwtService.BlacklistToken(context.Background(), revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantActive bool
+	}{
+		{"valid", issueOAuthToken(t, jwtService, time.Hour), true},
+		{"expired", issueOAuthToken(t, jwtService, -time.Minute), false},
+		{"revoked", revoked, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := introspect(e, tt.token)
+			if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderCacheControl) != "no-store" {
+				t.Fatalf("response = %d, Cache-Control %q, want 200 no-store", rec.Code, rec.Header().Get(echo.HeaderCacheControl))
+			}
+			var response models.IntrospectionResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Active != tt.wantActive {
+				t.Errorf("active = %v, want %v", response.Active, tt.wantActive)
+			}
+			if tt.wantActive && response.Sub != "u1" {
+				t.Errorf("sub = %q, want u1", response.Sub)
+			}
+		})
+	}
+
+	if rec := introspect(e, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing token = %d, want 400", rec.Code)
+	}
+}
+
+func TestUserInfoHandler(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil)
+	e := newOAuthServer(jwtService)
+	revoked := issueOAuthToken(t, jwtService, time.Hour)
+	if err := jwtService.BlacklistToken(context.Background(), revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := userInfo(e, issueOAuthToken(t, jwtService, time.Hour))
+	var user models.OAuthUser
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil || rec.Code != http.StatusOK || user.ID != "u1" {
+		t.Errorf("valid token = %d %s, want the user", rec.Code, rec.Body.String())
+	}
+
+	for name, token := range map[string]string{"expired": issueOAuthToken(t, jwtService, -time.Minute), "revoked": revoked, "missing": ""} {
+		rec := userInfo(e, token)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s token = %d, want 401", name, rec.Code)
+		}
+		if name == "revoked" {
+			var body common.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.ErrorCode != ErrorCodeTokenRevoked {
+				t.Errorf("revoked token body = %s, want the %s error code", rec.Body.String(), ErrorCodeTokenRevoked)
+			}
+		}
+	}
+}
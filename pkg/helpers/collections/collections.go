@@ -0,0 +1,139 @@
+// Package collections provides generic slice and map helpers for assembling responses and batch inputs.
+// Results are freshly allocated with their final capacity when it is known, inputs are never modified.
+// Slice results are nil for nil inputs and empty for empty inputs unless documented otherwise
+package collections
+
+// Chunk splits s into consecutive chunks of size elements, the last one shorter, e.g. for batch inserts.
+// Chunks share the backing array of s, with their capacity clipped so appending to one does not overwrite
+// the next. It returns nil for empty inputs and panics when size is less than 1
+func Chunk[T any](s []T, size int) [][]T {
+	if size < 1 {
+		panic("collections: chunk size must be at least 1")
+	}
+	if len(s) == 0 {
+		return nil
+	}
+
+	chunks := make([][]T, 0, (len(s)+size-1)/size)
+	for start := 0; start < len(s); start += size {
+		end := min(start+size, len(s))
+		chunks = append(chunks, s[start:end:end])
+	}
+	return chunks
+}
+
+// Unique returns the distinct elements of s in the order of their first occurrence
+func Unique[T comparable](s []T) []T {
+	return UniqueBy(s, func(v T) T { return v })
+}
+
+// UniqueBy returns the elements of s with distinct keys, keeping the first element of each key
+func UniqueBy[T any, K comparable](s []T, key func(T) K) []T {
+	if s == nil {
+		return nil
+	}
+
+	seen := make(map[K]struct{}, len(s))
+	unique := make([]T, 0, len(s))
+	for _, v := range s {
+		k := key(v)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		unique = append(unique, v)
+	}
+	return unique
+}
+
+// Map returns the results of f applied to each element of s
+func Map[T, U any](s []T, f func(T) U) []U {
+	if s == nil {
+		return nil
+	}
+
+	mapped := make([]U, len(s))
+	for i, v := range s {
+		mapped[i] = f(v)
+	}
+	return mapped
+}
+
+// Filter returns the elements of s for which keep returns true, in order
+func Filter[T any](s []T, keep func(T) bool) []T {
+	if s == nil {
+		return nil
+	}
+
+	filtered := make([]T, 0, len(s))
+	for _, v := range s {
+		if keep(v) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// GroupBy groups the elements of s by key, each group in the order of s. It returns an empty map, never
+// nil, for empty inputs
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for _, v := range s {
+		k := key(v)
+		groups[k] = append(groups[k], v)
+	}
+	return groups
+}
+
+// Difference returns the distinct elements of a that are not in b, in the order of a
+func Difference[T comparable](a, b []T) []T {
+	if a == nil {
+		return nil
+	}
+
+	exclude := make(map[T]struct{}, len(b)+len(a))
+	for _, v := range b {
+		exclude[v] = struct{}{}
+	}
+	difference := make([]T, 0, len(a))
+	for _, v := range a {
+		if _, ok := exclude[v]; ok {
+			continue
+		}
+		// Added to the set so duplicates of a are returned once
+		exclude[v] = struct{}{}
+		difference = append(difference, v)
+	}
+	return difference
+}
+
+// Intersect returns the distinct elements of a that are also in b, in the order of a
+func Intersect[T comparable](a, b []T) []T {
+	if a == nil {
+		return nil
+	}
+
+	include := make(map[T]bool, len(b))
+	for _, v := range b {
+		include[v] = true
+	}
+	intersection := make([]T, 0, min(len(a), len(include)))
+	for _, v := range a {
+		if include[v] {
+			// Cleared so duplicates of a are returned once
+			include[v] = false
+			intersection = append(intersection, v)
+		}
+	}
+	return intersection
+}
+
+// ToMap indexes the elements of s by key, later elements replace earlier ones with the same key. It
+// returns an empty map, never nil, for empty inputs
+func ToMap[T any, K comparable](s []T, key func(T) K) map[K]T {
+	indexed := make(map[K]T, len(s))
+	for _, v := range s {
+		indexed[key(v)] = v
+	}
+	return indexed
+}
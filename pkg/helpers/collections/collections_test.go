@@ -0,0 +1,230 @@
+package collections
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	ID    int
+	Group string
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+		size int
+		want [][]int
+	}{
+		{"nil", nil, 2, nil},
+		{"empty", []int{}, 2, nil},
+		{"exact", []int{1, 2, 3, 4}, 2, [][]int{{1, 2}, {3, 4}}},
+		{"shorter last chunk", []int{1, 2, 3, 4, 5}, 2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{"size above length", []int{1, 2}, 5, [][]int{{1, 2}}},
+		{"size one", []int{1, 2, 3}, 1, [][]int{{1}, {2}, {3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Chunk(tt.in, tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chunk(%v, %d) = %v, want %v", tt.in, tt.size, got, tt.want)
+			}
+			if cap(got) != len(got) {
+				t.Errorf("cap = %d, want %d", cap(got), len(got))
+			}
+		})
+	}
+
+	t.Run("appending to a chunk keeps the next one", func(t *testing.T) {
+		s := []int{1, 2, 3, 4}
+		chunks := Chunk(s, 2)
+		_ = append(chunks[0], 99)
+		if !reflect.DeepEqual(chunks[1], []int{3, 4}) || !reflect.DeepEqual(s, []int{1, 2, 3, 4}) {
+			t.Errorf("append overwrote the input: chunks %v, input %v", chunks, s)
+		}
+	})
+
+	for _, size := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Chunk(size %d) did not panic", size)
+				}
+			}()
+			Chunk([]int{1}, size)
+		}()
+	}
+}
+
+func TestUnique(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"empty", []string{}, []string{}},
+		{"first occurrence order", []string{"b", "a", "b", "c", "a"}, []string{"b", "a", "c"}},
+		{"already unique", []string{"a", "b"}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unique(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unique(%#v) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueBy(t *testing.T) {
+	in := []item{{1, "a"}, {2, "b"}, {3, "A"}, {4, "a"}}
+	got := UniqueBy(in, func(v item) string { return strings.ToLower(v.Group) })
+	if want := []item{{1, "a"}, {2, "b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UniqueBy = %v, want %v", got, want)
+	}
+	if got := UniqueBy(nil, func(v item) int { return v.ID }); got != nil {
+		t.Errorf("UniqueBy(nil) = %#v, want nil", got)
+	}
+}
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"empty", []int{}, []string{}},
+		{"values", []int{1, 2, 3}, []string{"x", "xx", "xxx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Map(tt.in, func(n int) string { return strings.Repeat("x", n) })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map(%#v) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	even := func(n int) bool { return n%2 == 0 }
+	tests := []struct {
+		name string
+		in   []int
+		want []int
+	}{
+		{"nil", nil, nil},
+		{"empty", []int{}, []int{}},
+		{"none kept", []int{1, 3}, []int{}},
+		{"order kept", []int{4, 1, 2, 3, 6}, []int{4, 2, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Filter(tt.in, even); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter(%#v) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	byGroup := func(v item) string { return v.Group }
+	tests := []struct {
+		name string
+		in   []item
+		want map[string][]item
+	}{
+		{"nil", nil, map[string][]item{}},
+		{"empty", []item{}, map[string][]item{}},
+		{"groups in order", []item{{1, "a"}, {2, "b"}, {3, "a"}}, map[string][]item{
+			"a": {{1, "a"}, {3, "a"}},
+			"b": {{2, "b"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupBy(tt.in, byGroup)
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupBy(%v) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDifferenceAndIntersect(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       []int
+		difference []int
+		intersect  []int
+	}{
+		{"nil a", nil, []int{1}, nil, nil},
+		{"empty a", []int{}, []int{1}, []int{}, []int{}},
+		{"nil b", []int{1, 2, 1}, nil, []int{1, 2}, []int{}},
+		{"overlap", []int{5, 1, 2, 3, 2, 5}, []int{2, 5, 7}, []int{1, 3}, []int{5, 2}},
+		{"disjoint", []int{1, 2}, []int{3, 4}, []int{1, 2}, []int{}},
+		{"same", []int{1, 2}, []int{2, 1}, []int{}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Difference(tt.a, tt.b); !reflect.DeepEqual(got, tt.difference) {
+				t.Errorf("Difference(%#v, %#v) = %#v, want %#v", tt.a, tt.b, got, tt.difference)
+			}
+			if got := Intersect(tt.a, tt.b); !reflect.DeepEqual(got, tt.intersect) {
+				t.Errorf("Intersect(%#v, %#v) = %#v, want %#v", tt.a, tt.b, got, tt.intersect)
+			}
+		})
+	}
+}
+
+func TestToMap(t *testing.T) {
+	byGroup := func(v item) string { return v.Group }
+	tests := []struct {
+		name string
+		in   []item
+		want map[string]item
+	}{
+		{"nil", nil, map[string]item{}},
+		{"later replaces earlier", []item{{1, "a"}, {2, "b"}, {3, "a"}}, map[string]item{"a": {3, "a"}, "b": {2, "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToMap(tt.in, byGroup)
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToMap(%v) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputsNotModified(t *testing.T) {
+	in := []int{3, 1, 3, 2}
+	Unique(in)
+	Filter(in, func(n int) bool { return n > 1 })
+	Difference(in, []int{3})
+	Intersect(in, []int{1})
+	Map(in, func(n int) int { return n * 2 })
+	if !reflect.DeepEqual(in, []int{3, 1, 3, 2}) {
+		t.Errorf("input modified to %v", in)
+	}
+}
+
+func TestPreallocatedResults(t *testing.T) {
+	s := make([]int, 1000)
+	for i := range s {
+		s[i] = i
+	}
+	allocs := map[string]func(){
+		"Chunk":  func() { Chunk(s, 100) },
+		"Map":    func() { Map(s, func(n int) int { return n + 1 }) },
+		"Filter": func() { Filter(s, func(n int) bool { return n%2 == 0 }) },
+	}
+	for name, f := range allocs {
+		if n := testing.AllocsPerRun(10, f); n != 1 {
+			t.Errorf("%s allocated %v times, want 1", name, n)
+		}
+	}
+}
@@ -3,72 +3,336 @@ package helpers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
+// DefaultCurlMaxBodyBytes caps the bodies of generated curl commands when CurlOptions.MaxBodyBytes is 0
+const DefaultCurlMaxBodyBytes = 64 << 10
+
+// Placeholders of generated curl commands
+const (
+	CurlRedacted        = "<redacted>"
+	CurlTruncated       = "...<truncated>"
+	CurlBodyNotCaptured = "<body not captured>"
+)
+
+// curlMultipartTruncated ends the commands of truncated multipart bodies, as a shell comment
+const curlMultipartTruncated = "# multipart body truncated"
+
+// DefaultRedactHeaders are the credential headers redacted when CurlOptions.RedactHeaders is nil
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// CurlOptions configures the curl generators, commands are meant for logs so credentials are redacted and
+// bodies capped by default
+type CurlOptions struct {
+	// RedactHeaders are rendered as "<redacted>", case-insensitively. nil redacts DefaultRedactHeaders, an
+	// empty slice nothing
+	RedactHeaders []string
+	// MaxBodyBytes truncates bodies with an ellipsis marker, only that much of a request body is buffered.
+	// 0 caps at DefaultCurlMaxBodyBytes, negative values disable the cap
+	MaxBodyBytes int
+	// DisableBodyCapture leaves bodies unread, for streaming requests
+	DisableBodyCapture bool
+}
+
+// curlOptions returns the options of a generator call, defaults applied
+func curlOptions(opts []CurlOptions) CurlOptions {
+	var options CurlOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.RedactHeaders == nil {
+		options.RedactHeaders = DefaultRedactHeaders
+	}
+	if options.MaxBodyBytes == 0 {
+		options.MaxBodyBytes = DefaultCurlMaxBodyBytes
+	}
+	return options
+}
+
+// headerValue returns value, or the redaction placeholder for redacted headers
+func (o CurlOptions) headerValue(key, value string) string {
+	for _, redacted := range o.RedactHeaders {
+		if strings.EqualFold(key, redacted) {
+			return CurlRedacted
+		}
+	}
+	return value
+}
+
+// body returns body truncated to MaxBodyBytes on a rune boundary
+func (o CurlOptions) body(body []byte, truncated bool) string {
+	if o.MaxBodyBytes >= 0 && len(body) > o.MaxBodyBytes {
+		body, truncated = body[:o.MaxBodyBytes], true
+	}
+	if !truncated {
+		return string(body)
+	}
+	for end := len(body); end > 0 && end > len(body)-utf8.UTFMax; end-- {
+		if utf8.RuneStart(body[end-1]) {
+			if !utf8.FullRune(body[end-1:]) {
+				body = body[:end-1]
+			}
+			break
+		}
+	}
+	return string(body) + CurlTruncated
+}
+
+// CurlFormField is a multipart/form-data field of GenerateCurlCommand, pass a []CurlFormField body.
+// Fields with a FileName are rendered as "@FileName" placeholders, the content is not part of the command
+type CurlFormField struct {
+	Name        string
+	Value       string
+	FileName    string
+	ContentType string
+}
+
 // GenerateCurlCommand generates a cURL command string from request details.
-func GenerateCurlCommand(method, url string, headers map[string]string, body interface{}) (string, error) {
+// GET bodies must be flat JSON objects, they are URL-encoded and appended to the query string.
+// []CurlFormField bodies are rendered as form flags, curl then sets the multipart Content-Type itself.
+// opts, at most one, redact headers and cap the body
+func GenerateCurlCommand(method, url string, headers map[string]string, body interface{}, opts ...CurlOptions) (string, error) {
+	options := curlOptions(opts)
+	fields, multipartBody := body.([]CurlFormField)
+	captureBody := body != nil && !options.DisableBodyCapture
+	if captureBody && !multipartBody && method == http.MethodGet {
+		var err error
+		if url, err = appendQueryBody(url, body); err != nil {
+			return "", err
+		}
+	}
+
 	var cmd []string
-	cmd = append(cmd, "curl", "-X", method, fmt.Sprintf("'%s'", url))
+	cmd = append(cmd, "curl", "-X", method, shellQuote(url))
 
-	for key, value := range headers {
-		cmd = append(cmd, "-H", fmt.Sprintf("'%s: %s'", key, value))
+	// Sorted so the same request always gives the same command
+	for _, key := range slices.Sorted(maps.Keys(headers)) {
+		if multipartBody && strings.EqualFold(key, "Content-Type") {
+			continue
+		}
+		cmd = append(cmd, "-H", shellQuote(key+": "+options.headerValue(key, headers[key])))
 	}
 
-	if body != nil {
+	switch {
+	case body != nil && !captureBody:
+		cmd = append(cmd, "-d", shellQuote(CurlBodyNotCaptured))
+	case multipartBody:
+		for _, field := range fields {
+			cmd = append(cmd, formFieldFlag(field)...)
+		}
+	case body != nil && method != http.MethodGet:
+		// For other methods like POST, PUT, DELETE
 		bodyBytes, err := json.Marshal(body)
 		if err != nil {
 			return "", fmt.Errorf("failed to marshal body: %w", err)
 		}
-
-		// For GET request, append body as query parameters
-		if method == http.MethodGet {
-			// This is a simplified version. A full implementation would need to handle URL encoding
-			// and merging with existing query parameters.
-			if len(bodyBytes) > 0 {
-				// Assuming body is a JSON object that can be converted to query params
-				// This part might need to be more sophisticated depending on the actual body structure.
-			}
-		} else {
-			// For other methods like POST, PUT, DELETE
-			cmd = append(cmd, "-d", fmt.Sprintf("'%s'", string(bodyBytes)))
-		}
+		cmd = append(cmd, "-d", shellQuote(options.body(bodyBytes, false)))
 	}
 
 	return strings.Join(cmd, " "), nil
 }
 
 // GenerateCurlCommandFromRequest generates a cURL command string from an http.Request.
-func GenerateCurlCommandFromRequest(req *http.Request) (string, error) {
+// multipart/form-data bodies are rendered as form flags with "@filename" placeholders for file parts.
+// opts, at most one, redact headers and cap the body, at most MaxBodyBytes of it is buffered
+func GenerateCurlCommandFromRequest(req *http.Request, opts ...CurlOptions) (string, error) {
+	options := curlOptions(opts)
 	var cmd []string
 
+	// Body
+	var bodyBytes []byte
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	truncated := false
+	if hasBody && !options.DisableBodyCapture {
+		var err error
+		if bodyBytes, truncated, err = readRequestBody(req, options.MaxBodyBytes); err != nil {
+			return "", fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	var fields []CurlFormField
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" && len(bodyBytes) > 0 {
+		var err error
+		// Parts cut by the cap are dropped, the command is marked truncated
+		if fields, err = parseFormFields(bodyBytes, params["boundary"]); err != nil && !truncated {
+			return "", fmt.Errorf("failed to parse multipart body: %w", err)
+		}
+	}
+
 	// Method and URL
-	cmd = append(cmd, "curl", "-X", req.Method, fmt.Sprintf("'%s'", req.URL.String()))
+	cmd = append(cmd, "curl", "-X", req.Method, shellQuote(req.URL.String()))
 
 	// Headers
-	for key, values := range req.Header {
+	for _, key := range slices.Sorted(maps.Keys(req.Header)) {
+		if fields != nil && key == "Content-Type" {
+			continue
+		}
+		for _, value := range req.Header[key] {
+			cmd = append(cmd, "-H", shellQuote(key+": "+options.headerValue(key, value)))
+		}
+	}
+
+	switch {
+	case hasBody && options.DisableBodyCapture:
+		cmd = append(cmd, "-d", shellQuote(CurlBodyNotCaptured))
+	case fields != nil:
+		for _, field := range fields {
+			cmd = append(cmd, formFieldFlag(field)...)
+		}
+		if truncated {
+			cmd = append(cmd, curlMultipartTruncated)
+		}
+	case len(bodyBytes) > 0:
+		cmd = append(cmd, "-d", shellQuote(options.body(bodyBytes, truncated)))
+	}
+
+	return strings.Join(cmd, " "), nil
+}
+
+// readRequestBody reads up to maxBytes of the body of req, all of it when negative, and restores the body
+// so it can be read again. The rest of a truncated body stays unread
+func readRequestBody(req *http.Request, maxBytes int) ([]byte, bool, error) {
+	reader := io.Reader(req.Body)
+	if maxBytes >= 0 {
+		reader = io.LimitReader(req.Body, int64(maxBytes)+1)
+	}
+	bodyBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false, err
+	}
+	// Restore the body so it can be read again
+	req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(bodyBytes), req.Body), Closer: req.Body}
+
+	if maxBytes >= 0 && len(bodyBytes) > maxBytes {
+		return bodyBytes[:maxBytes], true, nil
+	}
+	return bodyBytes, false, nil
+}
+
+// readCloser closes the original body of a request whose start was read
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// shellQuote quotes s for POSIX shells, single quotes are closed, escaped and reopened
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// appendQueryBody URL-encodes the fields of a flat JSON object body after the query string of rawURL,
+// arrays become repeated parameters
+func appendQueryBody(rawURL string, body interface{}) (string, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal body: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &fields); err != nil {
+		return "", fmt.Errorf("GET body must be a JSON object: %w", err)
+	}
+
+	query := url.Values{}
+	for key, raw := range fields {
+		// Only arrays are expanded, null would unmarshal to no values and drop the field
+		values := []json.RawMessage{raw}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return "", fmt.Errorf("GET body field %q: %w", key, err)
+			}
+		}
 		for _, value := range values {
-			cmd = append(cmd, "-H", fmt.Sprintf("'%s: %s'", key, value))
+			param, err := queryValue(value)
+			if err != nil {
+				return "", fmt.Errorf("GET body field %q: %w", key, err)
+			}
+			query.Add(key, param)
 		}
 	}
+	if len(query) == 0 {
+		return rawURL, nil
+	}
 
-	// Body
-	if req.Body != nil {
-		bodyBytes := new(bytes.Buffer)
-		_, err := bodyBytes.ReadFrom(req.Body)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	// Existing parameters keep their order and encoding, body fields are appended sorted by key
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += query.Encode()
+	return u.String(), nil
+}
+
+// queryValue renders a scalar JSON value as a query parameter, null as an empty value
+func queryValue(raw json.RawMessage) (string, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", errors.New("nested objects cannot be encoded as query parameters")
+}
+
+// parseFormFields reads the parts of a multipart/form-data body
+func parseFormFields(body []byte, boundary string) ([]CurlFormField, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	fields := []CurlFormField{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return fields, nil
+		}
 		if err != nil {
-			return "", fmt.Errorf("failed to read request body: %w", err)
+			return nil, err
 		}
-		// Restore the body so it can be read again
-		req.Body = io.NopCloser(bytes.NewReader(bodyBytes.Bytes()))
 
-		if bodyBytes.Len() > 0 {
-			cmd = append(cmd, "-d", fmt.Sprintf("'%s'", bodyBytes.String()))
+		field := CurlFormField{Name: part.FormName(), FileName: part.FileName()}
+		if field.FileName != "" {
+			field.ContentType = part.Header.Get("Content-Type")
+		} else {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			field.Value = string(value)
 		}
+		fields = append(fields, field)
 	}
+}
 
-	return strings.Join(cmd, " "), nil
+// formFieldFlag returns the curl flag of a field, --form-string for values so a leading @ or < is not read
+// as a file, -F "name=@file;type=..." for files
+func formFieldFlag(field CurlFormField) []string {
+	if field.FileName == "" {
+		return []string{"--form-string", shellQuote(field.Name + "=" + field.Value)}
+	}
+	argument := field.Name + "=@" + field.FileName
+	if field.ContentType != "" {
+		argument += ";type=" + field.ContentType
+	}
+	return []string{"-F", shellQuote(argument)}
 }
@@ -0,0 +1,300 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCurlCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		headers map[string]string
+		body    interface{}
+		opts    []CurlOptions
+		want    string
+	}{
+		{
+			name:   "GET with body",
+			method: http.MethodGet,
+			url:    "https://api.example.com/orders?status=open",
+			body:   map[string]interface{}{"page": 2, "tags": []string{"a", "b"}, "q": "it's", "deleted": false, "cursor": nil},
+			want:   `curl -X GET 'https://api.example.com/orders?status=open&cursor=&deleted=false&page=2&q=it%27s&tags=a&tags=b'`,
+		},
+		{
+			name:   "GET with a struct body",
+			method: http.MethodGet,
+			url:    "https://api.example.com/orders",
+			body:   struct{ Page int }{Page: 1},
+			want:   `curl -X GET 'https://api.example.com/orders?Page=1'`,
+		},
+		{
+			name:   "GET with an empty body",
+			method: http.MethodGet,
+			url:    "https://api.example.com/orders?status=open",
+			body:   map[string]interface{}{},
+			want:   `curl -X GET 'https://api.example.com/orders?status=open'`,
+		},
+		{
+			name:    "POST JSON",
+			method:  http.MethodPost,
+			url:     "https://api.example.com/users",
+			headers: map[string]string{"Content-Type": "application/json", "Authorization": "Bearer secret", "X-Note": "it's"},
+			body:    map[string]string{"name": "O'Neil"},
+			want:    `curl -X POST 'https://api.example.com/users' -H 'Authorization: <redacted>' -H 'Content-Type: application/json' -H 'X-Note: it'\''s' -d '{"name":"O'\''Neil"}'`,
+		},
+		{
+			name:    "multipart",
+			method:  http.MethodPost,
+			url:     "https://api.example.com/uploads",
+			headers: map[string]string{"Content-Type": "multipart/form-data; boundary=x", "X-Request-Id": "r1"},
+			body: []CurlFormField{
+				{Name: "title", Value: "@notes it's"},
+				{Name: "file", FileName: "report.pdf", ContentType: "application/pdf"},
+				{Name: "avatar", FileName: "me.png"},
+			},
+			want: `curl -X POST 'https://api.example.com/uploads' -H 'X-Request-Id: r1' --form-string 'title=@notes it'\''s' -F 'file=@report.pdf;type=application/pdf' -F 'avatar=@me.png'`,
+		},
+		{
+			name:   "truncated body",
+			method: http.MethodPut,
+			url:    "https://api.example.com/notes/1",
+			body:   "héllo",
+			opts:   []CurlOptions{{MaxBodyBytes: 3}},
+			want:   `curl -X PUT 'https://api.example.com/notes/1' -d '"h...<truncated>'`,
+		},
+		{
+			name:    "body not captured",
+			method:  http.MethodPost,
+			url:     "https://api.example.com/stream",
+			headers: map[string]string{"Authorization": "Bearer secret"},
+			body:    map[string]string{"a": "b"},
+			opts:    []CurlOptions{{DisableBodyCapture: true, RedactHeaders: []string{}}},
+			want:    `curl -X POST 'https://api.example.com/stream' -H 'Authorization: Bearer secret' -d '<body not captured>'`,
+		},
+		{
+			name:   "no body",
+			method: http.MethodDelete,
+			url:    "https://api.example.com/users/1",
+			want:   `curl -X DELETE 'https://api.example.com/users/1'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCurlCommand(tt.method, tt.url, tt.headers, tt.body, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateCurlCommand =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateCurlCommandInvalidGETBody(t *testing.T) {
+	bodies := map[string]interface{}{
+		"array":         []int{1, 2},
+		"scalar":        "page=2",
+		"nested object": map[string]interface{}{"filter": map[string]string{"status": "open"}},
+		"nested array":  map[string]interface{}{"ids": [][]int{{1}}},
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			if _, err := GenerateCurlCommand(http.MethodGet, "https://api.example.com/orders", nil, body); err == nil {
+				t.Error("GenerateCurlCommand accepted a GET body that is not a flat object")
+			}
+		})
+	}
+}
+
+func TestGenerateCurlCommandFromRequest(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/users?invite=true", strings.NewReader(`{"name":"O'Neil"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cookie", "session=abc")
+
+		got, err := GenerateCurlCommandFromRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `curl -X POST 'https://api.example.com/users?invite=true' -H 'Content-Type: application/json' -H 'Cookie: <redacted>' -d '{"name":"O'\''Neil"}'`
+		if got != want {
+			t.Errorf("GenerateCurlCommandFromRequest =\n%s\nwant\n%s", got, want)
+		}
+		// The body can still be read by the handler
+		if body, _ := io.ReadAll(req.Body); string(body) != `{"name":"O'Neil"}` {
+			t.Errorf("body after generation = %q", body)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("title", "Q3 report")
+		part, _ := writer.CreateFormFile("file", "report.pdf")
+		part.Write([]byte("%PDF-1.4 \x00\x01 binary"))
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/uploads", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		got, err := GenerateCurlCommandFromRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `curl -X POST 'https://api.example.com/uploads' --form-string 'title=Q3 report' -F 'file=@report.pdf;type=application/octet-stream'`
+		if got != want {
+			t.Errorf("GenerateCurlCommandFromRequest =\n%s\nwant\n%s", got, want)
+		}
+		if rest, _ := io.ReadAll(req.Body); !bytes.Equal(rest, body.Bytes()) {
+			t.Error("multipart body was not restored")
+		}
+
+		// A cap cutting the file part keeps the fields before it
+		req = httptest.NewRequest(http.MethodPost, "https://api.example.com/uploads", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		got, err = GenerateCurlCommandFromRequest(req, CurlOptions{MaxBodyBytes: body.Len() - 60})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(got, `--form-string 'title=Q3 report'`) || !strings.HasSuffix(got, " "+curlMultipartTruncated) {
+			t.Errorf("truncated multipart command = %s", got)
+		}
+		if rest, _ := io.ReadAll(req.Body); !bytes.Equal(rest, body.Bytes()) {
+			t.Error("truncated multipart body was not restored")
+		}
+	})
+}
+
+// TestShellQuote runs quoted values through a shell to check they come back unchanged
+func TestShellQuote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell available")
+	}
+	for _, value := range []string{"plain", "it's", "''", `a"b$c\d`, "x'; rm -rf / #", "new\nline", "`id`"} {
+		out, err := exec.Command(sh, "-c", "printf %s "+shellQuote(value)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != value {
+			t.Errorf("shell read %q back as %q", value, out)
+		}
+	}
+}
+
+func TestCurlHeaderRedaction(t *testing.T) {
+	headers := map[string]string{"authorization": "Bearer secret", "X-API-KEY": "k1", "Cookie": "session=abc", "X-Trace": "t1"}
+	tests := []struct {
+		name string
+		opts []CurlOptions
+		want string
+	}{
+		{"defaults, case-insensitive", nil, `curl -X GET 'https://api.example.com/' -H 'Cookie: <redacted>' -H 'X-API-KEY: <redacted>' -H 'X-Trace: t1' -H 'authorization: <redacted>'`},
+		{"custom list", []CurlOptions{{RedactHeaders: []string{"x-trace"}}}, `curl -X GET 'https://api.example.com/' -H 'Cookie: session=abc' -H 'X-API-KEY: k1' -H 'X-Trace: <redacted>' -H 'authorization: Bearer secret'`},
+		{"nothing redacted", []CurlOptions{{RedactHeaders: []string{}}}, `curl -X GET 'https://api.example.com/' -H 'Cookie: session=abc' -H 'X-API-KEY: k1' -H 'X-Trace: t1' -H 'authorization: Bearer secret'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCurlCommand(http.MethodGet, "https://api.example.com/", headers, nil, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateCurlCommand =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+	req.Header.Add("Set-Cookie", "a=1")
+	req.Header.Add("Set-Cookie", "b=2")
+	req.Header.Set("Proxy-Authorization", "Basic eDp5")
+	got, err := GenerateCurlCommandFromRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `curl -X GET 'https://api.example.com/' -H 'Proxy-Authorization: <redacted>' -H 'Set-Cookie: <redacted>' -H 'Set-Cookie: <redacted>'`; got != want {
+		t.Errorf("GenerateCurlCommandFromRequest =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCurlBodyTruncation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		max  int
+		want string
+	}{
+		{"under the cap", "hello", 10, "hello"},
+		{"at the cap", "hello", 5, "hello"},
+		{"over the cap", "hello world", 5, "hello" + CurlTruncated},
+		{"inside a rune", "héllo", 2, "h" + CurlTruncated},
+		{"after a rune", "héllo", 3, "hé" + CurlTruncated},
+		{"inside a 4 byte rune", "a😀b", 3, "a" + CurlTruncated},
+		{"no cap", strings.Repeat("x", DefaultCurlMaxBodyBytes+1), -1, strings.Repeat("x", DefaultCurlMaxBodyBytes+1)},
+		{"default cap", strings.Repeat("x", DefaultCurlMaxBodyBytes+1), 0, strings.Repeat("x", DefaultCurlMaxBodyBytes) + CurlTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://api.example.com/", strings.NewReader(tt.body))
+			got, err := GenerateCurlCommandFromRequest(req, CurlOptions{MaxBodyBytes: tt.max})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `curl -X POST 'https://api.example.com/' -d ` + shellQuote(tt.want); got != want {
+				t.Errorf("GenerateCurlCommandFromRequest = %.200s, want %.200s", got, want)
+			}
+			if body, _ := io.ReadAll(req.Body); string(body) != tt.body {
+				t.Errorf("body after generation has %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+// zeroBody is a body of n zero bytes counting the bytes read
+type zeroBody struct {
+	n    int
+	read int
+}
+
+func (r *zeroBody) Read(p []byte) (int, error) {
+	if r.read >= r.n {
+		return 0, io.EOF
+	}
+	n := min(len(p), r.n-r.read)
+	clear(p[:n])
+	r.read += n
+	return n, nil
+}
+
+func TestCurlBodyCaptureBuffersAtMostTheCap(t *testing.T) {
+	body := &zeroBody{n: 8 << 20}
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/", io.NopCloser(body))
+	if _, err := GenerateCurlCommandFromRequest(req, CurlOptions{MaxBodyBytes: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	if body.read > 1024+512 {
+		t.Errorf("read %d bytes of an 8 MiB body with a 1 KiB cap", body.read)
+	}
+	if n, _ := io.Copy(io.Discard, req.Body); n != 8<<20 {
+		t.Errorf("handler read %d bytes, want the whole body", n)
+	}
+
+	body = &zeroBody{n: 1024}
+	req = httptest.NewRequest(http.MethodPost, "https://api.example.com/", io.NopCloser(body))
+	got, err := GenerateCurlCommandFromRequest(req, CurlOptions{DisableBodyCapture: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.read != 0 || !strings.HasSuffix(got, `-d '<body not captured>'`) {
+		t.Errorf("DisableBodyCapture read %d bytes, command %s", body.read, got)
+	}
+}
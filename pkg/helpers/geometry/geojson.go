@@ -0,0 +1,242 @@
+// Package geometry parses, validates and converts GeoJSON (RFC 7946) geometries to the [][][]float64
+// polygons of the helpers package, rings of [lon, lat] positions with holes after the exterior ring
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thanhthanh221/msa-core/pkg/helpers"
+)
+
+// GeoJSON types
+const (
+	TypePoint             = "Point"
+	TypePolygon           = "Polygon"
+	TypeMultiPolygon      = "MultiPolygon"
+	TypeFeature           = "Feature"
+	TypeFeatureCollection = "FeatureCollection"
+)
+
+// Geometry is a Point, Polygon or MultiPolygon geometry, only the coordinates of its Type are set
+type Geometry struct {
+	Type         string
+	Point        []float64
+	Polygon      [][][]float64
+	MultiPolygon [][][][]float64
+}
+
+// NewPolygon returns the Polygon geometry of polygon
+func NewPolygon(polygon [][][]float64) *Geometry {
+	return &Geometry{Type: TypePolygon, Polygon: polygon}
+}
+
+// NewMultiPolygon returns the MultiPolygon geometry of polygons
+func NewMultiPolygon(polygons [][][][]float64) *Geometry {
+	return &Geometry{Type: TypeMultiPolygon, MultiPolygon: polygons}
+}
+
+// Polygons returns the polygons of the geometry, one for a Polygon and each part of a MultiPolygon, none
+// for points
+func (g *Geometry) Polygons() [][][][]float64 {
+	switch g.Type {
+	case TypePolygon:
+		return [][][][]float64{g.Polygon}
+	case TypeMultiPolygon:
+		return g.MultiPolygon
+	}
+	return nil
+}
+
+// NormalizeOrientation rewinds the rings of the geometry with helpers.NormalizePolygonOrientation:
+// exterior rings counter-clockwise, holes clockwise, consecutive duplicate positions removed
+func (g *Geometry) NormalizeOrientation() {
+	switch g.Type {
+	case TypePolygon:
+		g.Polygon = helpers.NormalizePolygonOrientation(g.Polygon)
+	case TypeMultiPolygon:
+		for i, polygon := range g.MultiPolygon {
+			g.MultiPolygon[i] = helpers.NormalizePolygonOrientation(polygon)
+		}
+	}
+}
+
+// Validate checks the coordinates of the geometry with ValidatePolygon
+func (g *Geometry) Validate() error {
+	switch g.Type {
+	case TypePoint:
+		if err := validatePosition(g.Point); err != nil {
+			return &ValidationError{Err: err, Ring: -1, Position: -1}
+		}
+		return nil
+	case TypePolygon:
+		return ValidatePolygon(g.Polygon)
+	case TypeMultiPolygon:
+		if len(g.MultiPolygon) == 0 {
+			return &ValidationError{Err: ErrEmptyPolygon, Ring: -1, Position: -1}
+		}
+		for i, polygon := range g.MultiPolygon {
+			if err := ValidatePolygon(polygon); err != nil {
+				return fmt.Errorf("polygon %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	return &ValidationError{Err: fmt.Errorf("%w: %q", ErrInvalidType, g.Type), Ring: -1, Position: -1}
+}
+
+type geometryJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	var coordinates any
+	switch g.Type {
+	case TypePoint:
+		coordinates = g.Point
+	case TypePolygon:
+		coordinates = g.Polygon
+	case TypeMultiPolygon:
+		coordinates = g.MultiPolygon
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidType, g.Type)
+	}
+	raw, err := json.Marshal(coordinates)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(geometryJSON{Type: g.Type, Coordinates: raw})
+}
+
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var raw geometryJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*g = Geometry{Type: raw.Type}
+	switch raw.Type {
+	case TypePoint:
+		return json.Unmarshal(raw.Coordinates, &g.Point)
+	case TypePolygon:
+		return json.Unmarshal(raw.Coordinates, &g.Polygon)
+	case TypeMultiPolygon:
+		return json.Unmarshal(raw.Coordinates, &g.MultiPolygon)
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidType, raw.Type)
+}
+
+// Feature is a GeoJSON feature
+type Feature struct {
+	Type       string         `json:"type"`
+	ID         any            `json:"id,omitempty"`
+	Geometry   *Geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// NewFeature returns a feature of geometry
+func NewFeature(geometry *Geometry, properties map[string]any) Feature {
+	return Feature{Type: TypeFeature, Geometry: geometry, Properties: properties}
+}
+
+// FeatureCollection is a GeoJSON feature collection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewFeatureCollection returns a collection of features
+func NewFeatureCollection(features ...Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: TypeFeatureCollection, Features: features}
+}
+
+// Polygons returns the polygons of every feature, MultiPolygons flattened
+func (fc FeatureCollection) Polygons() [][][][]float64 {
+	var polygons [][][][]float64
+	for _, feature := range fc.Features {
+		if feature.Geometry != nil {
+			polygons = append(polygons, feature.Geometry.Polygons()...)
+		}
+	}
+	return polygons
+}
+
+// ParseOption configures ParseGeoJSON
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	normalizeOrientation bool
+}
+
+// WithNormalizeOrientation normalizes the rings of every geometry with Geometry.NormalizeOrientation
+// before validating them, for frontends sending reversed rings. Validation errors then locate positions in
+// the normalized rings
+func WithNormalizeOrientation() ParseOption {
+	return func(o *parseOptions) {
+		o.normalizeOrientation = true
+	}
+}
+
+// ParseGeoJSON parses and validates a geometry, feature or feature collection. Geometries and features
+// are returned as a collection of one feature. Features without geometry are rejected with
+// ErrMissingGeometry, invalid geometries with a *ValidationError
+func ParseGeoJSON(data []byte, opts ...ParseOption) (*FeatureCollection, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+
+	var fc FeatureCollection
+	switch probe.Type {
+	case TypeFeatureCollection:
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return nil, err
+		}
+	case TypeFeature:
+		var feature Feature
+		if err := json.Unmarshal(data, &feature); err != nil {
+			return nil, err
+		}
+		fc = NewFeatureCollection(feature)
+	case TypePoint, TypePolygon, TypeMultiPolygon:
+		var geometry Geometry
+		if err := json.Unmarshal(data, &geometry); err != nil {
+			return nil, err
+		}
+		fc = NewFeatureCollection(NewFeature(&geometry, nil))
+	default:
+		return nil, &ValidationError{Err: fmt.Errorf("%w: %q", ErrInvalidType, probe.Type), Ring: -1, Position: -1}
+	}
+
+	for i, feature := range fc.Features {
+		if feature.Geometry == nil {
+			return nil, fmt.Errorf("feature %d: %w", i, ErrMissingGeometry)
+		}
+		if options.normalizeOrientation {
+			feature.Geometry.NormalizeOrientation()
+		}
+		if err := feature.Geometry.Validate(); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	return &fc, nil
+}
+
+// ParsePolygons parses GeoJSON with ParseGeoJSON and returns its polygons, MultiPolygons flattened
+func ParsePolygons(data []byte, opts ...ParseOption) ([][][][]float64, error) {
+	fc, err := ParseGeoJSON(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fc.Polygons(), nil
+}
@@ -0,0 +1,208 @@
+package geometry
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const featureCollectionJSON = `{
+	"type": "FeatureCollection",
+	"features": [
+		{
+			"type": "Feature",
+			"id": "district-1",
+			"geometry": {"type": "Polygon", "coordinates": [[[105.8, 21.0], [105.9, 21.0], [105.9, 21.1], [105.8, 21.1], [105.8, 21.0]]]},
+			"properties": {"name": "Hoan Kiem"}
+		},
+		{
+			"type": "Feature",
+			"geometry": {"type": "MultiPolygon", "coordinates": [
+				[[[0, 0], [1, 0], [1, 1], [0, 0]]],
+				[[[2, 2], [3, 2], [3, 3], [2, 2]], [[2.5, 2.2], [2.9, 2.8], [2.9, 2.2], [2.5, 2.2]]]
+			]},
+			"properties": null
+		},
+		{
+			"type": "Feature",
+			"geometry": {"type": "Point", "coordinates": [106.6, 10.8]},
+			"properties": {}
+		}
+	]
+}`
+
+func TestParseGeoJSONFeatureCollection(t *testing.T) {
+	fc, err := ParseGeoJSON([]byte(featureCollectionJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 3 || fc.Features[0].ID != "district-1" || fc.Features[0].Properties["name"] != "Hoan Kiem" {
+		t.Fatalf("features = %+v", fc.Features)
+	}
+	if point := fc.Features[2].Geometry; point.Type != TypePoint || !reflect.DeepEqual(point.Point, []float64{106.6, 10.8}) {
+		t.Errorf("point = %+v", point)
+	}
+
+	// MultiPolygons are flattened and points skipped
+	polygons := fc.Polygons()
+	if len(polygons) != 3 || len(polygons[2]) != 2 || !reflect.DeepEqual(polygons[1], [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}) {
+		t.Errorf("Polygons = %v", polygons)
+	}
+	if parsed, err := ParsePolygons([]byte(featureCollectionJSON)); err != nil || !reflect.DeepEqual(parsed, polygons) {
+		t.Errorf("ParsePolygons = %v, %v", parsed, err)
+	}
+}
+
+func TestParseGeoJSONSingleGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		polygons int
+	}{
+		{"polygon", `{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}`, 1},
+		{"multipolygon", `{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 0]]], [[[2, 2], [3, 2], [3, 3], [2, 2]]]]}`, 2},
+		{"point", `{"type": "Point", "coordinates": [1, 2]}`, 0},
+		{"feature", `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}, "properties": {}}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, err := ParseGeoJSON([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fc.Type != TypeFeatureCollection || len(fc.Features) != 1 || len(fc.Polygons()) != tt.polygons {
+				t.Errorf("ParseGeoJSON = %+v, want one feature with %d polygons", fc, tt.polygons)
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"unsupported type", `{"type": "LineString", "coordinates": [[0, 0], [1, 1]]}`, ErrInvalidType},
+		{"unsupported geometry in a feature", `{"type": "Feature", "geometry": {"type": "Circle", "coordinates": [0, 0]}}`, ErrInvalidType},
+		{"missing geometry", `{"type": "Feature", "geometry": null, "properties": {}}`, ErrMissingGeometry},
+		{"open ring", `{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}`, ErrRingNotClosed},
+		{"too few positions", `{"type": "Polygon", "coordinates": [[[0, 0], [1, 1], [0, 0]]]}`, ErrTooFewPoints},
+		{"empty polygon", `{"type": "Polygon", "coordinates": []}`, ErrEmptyPolygon},
+		{"empty multipolygon", `{"type": "MultiPolygon", "coordinates": []}`, ErrEmptyPolygon},
+		{"latitude first", `{"type": "Polygon", "coordinates": [[[21.0, 105.8], [21.0, 105.9], [21.1, 105.9], [21.0, 105.8]]]}`, ErrLatitudeRange},
+		{"point out of range", `{"type": "Point", "coordinates": [190, 0]}`, ErrLongitudeRange},
+		{"point without latitude", `{"type": "Point", "coordinates": [1]}`, ErrInvalidPosition},
+		{"invalid part of a multipolygon", `{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 0]]], [[[0, 0], [1, 0], [1, 1]]]]}`, ErrTooFewPoints},
+		{"invalid second feature", `{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 0]}}, {"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 95]}}]}`, ErrLatitudeRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseGeoJSON([]byte(tt.data)); !errors.Is(err, tt.err) {
+				t.Errorf("ParseGeoJSON = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// Malformed JSON keeps the decoding error
+	var syntaxErr *json.SyntaxError
+	if _, err := ParseGeoJSON([]byte(`{"type": "Polygon",`)); !errors.As(err, &syntaxErr) {
+		t.Errorf("ParseGeoJSON of malformed JSON = %v, want a *json.SyntaxError", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if _, err := ParseGeoJSON([]byte(`{"type": "Polygon", "coordinates": [["0,0"]]}`)); !errors.As(err, &typeErr) {
+		t.Errorf("ParseGeoJSON of string coordinates = %v, want a *json.UnmarshalTypeError", err)
+	}
+}
+
+func TestParseGeoJSONNormalizeOrientation(t *testing.T) {
+	// A clockwise exterior ring with a duplicated position
+	data := []byte(`{"type": "Polygon", "coordinates": [[[0, 0], [0, 1], [0, 1], [1, 1], [1, 0], [0, 0]]]}`)
+
+	fc, err := ParseGeoJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring := fc.Features[0].Geometry.Polygon[0]; len(ring) != 6 {
+		t.Errorf("ring = %v, want it unchanged without the option", ring)
+	}
+
+	fc, err = ParseGeoJSON(data, WithNormalizeOrientation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	if ring := fc.Features[0].Geometry.Polygon[0]; !reflect.DeepEqual(ring, want) {
+		t.Errorf("normalized ring = %v, want %v", ring, want)
+	}
+}
+
+func TestGeometryNormalizeOrientation(t *testing.T) {
+	clockwise := [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}
+	counterClockwise := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+
+	multi := NewMultiPolygon([][][][]float64{{clockwise}, {counterClockwise}})
+	multi.NormalizeOrientation()
+	for i, polygon := range multi.MultiPolygon {
+		if !reflect.DeepEqual(polygon, [][][]float64{counterClockwise}) {
+			t.Errorf("polygon %d = %v, want %v", i, polygon, counterClockwise)
+		}
+	}
+
+	point := &Geometry{Type: TypePoint, Point: []float64{1, 2}}
+	point.NormalizeOrientation()
+	if !reflect.DeepEqual(point.Point, []float64{1, 2}) {
+		t.Errorf("point = %v", point.Point)
+	}
+}
+
+func TestGeoJSONRoundTrip(t *testing.T) {
+	polygon := [][][]float64{
+		{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}},
+		{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}},
+	}
+	multiPolygon := [][][][]float64{polygon, {{{10, 10}, {11, 10}, {11, 11}, {10, 10}}}}
+	fc := NewFeatureCollection(
+		NewFeature(NewPolygon(polygon), map[string]any{"name": "zone"}),
+		NewFeature(NewMultiPolygon(multiPolygon), nil),
+		NewFeature(&Geometry{Type: TypePoint, Point: []float64{1.5, 2.5}}, map[string]any{}),
+	)
+
+	data, err := json.Marshal(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spec-compliant members: a geometry has only type and coordinates
+	var raw struct {
+		Features []struct {
+			Geometry map[string]json.RawMessage `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for i, feature := range raw.Features {
+		if len(feature.Geometry) != 2 || feature.Geometry["type"] == nil || feature.Geometry["coordinates"] == nil {
+			t.Errorf("feature %d geometry members = %v", i, feature.Geometry)
+		}
+	}
+
+	parsed, err := ParseGeoJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Features[0].Geometry.Polygon, polygon) ||
+		!reflect.DeepEqual(parsed.Features[1].Geometry.MultiPolygon, multiPolygon) ||
+		!reflect.DeepEqual(parsed.Features[2].Geometry.Point, []float64{1.5, 2.5}) ||
+		parsed.Features[0].Properties["name"] != "zone" {
+		t.Errorf("round trip = %s", data)
+	}
+
+	if data, err := json.Marshal(NewFeatureCollection()); err != nil || string(data) != `{"type":"FeatureCollection","features":[]}` {
+		t.Errorf("empty collection = %s, %v", data, err)
+	}
+	if _, err := json.Marshal(Geometry{Type: "LineString"}); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("Marshal of an unsupported type = %v, want ErrInvalidType", err)
+	}
+}
@@ -0,0 +1,88 @@
+package geometry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Violations of ValidatePolygon and ParseGeoJSON, wrapped by *ValidationError
+var (
+	ErrInvalidType       = errors.New("unsupported geojson type")
+	ErrEmptyPolygon      = errors.New("polygon has no rings")
+	ErrTooFewPoints      = errors.New("ring has fewer than 4 positions")
+	ErrRingNotClosed     = errors.New("ring is not closed")
+	ErrInvalidPosition   = errors.New("position needs a longitude and a latitude")
+	ErrLongitudeRange    = errors.New("longitude is not between -180 and 180")
+	ErrLatitudeRange     = errors.New("latitude is not between -90 and 90")
+	ErrMissingGeometry   = errors.New("feature has no geometry")
+	ErrInvalidCoordinate = errors.New("coordinate is not a finite number")
+)
+
+// minRingPositions is the smallest closed ring, a triangle and its closing position (RFC 7946 3.1.6)
+const minRingPositions = 4
+
+// ValidationError is a geometry violation. Err is one of the Err* violations, Ring and Position locate it
+// (-1 when not applicable)
+type ValidationError struct {
+	Err      error
+	Ring     int
+	Position int
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.Position >= 0:
+		return fmt.Sprintf("ring %d, position %d: %v", e.Ring, e.Position, e.Err)
+	case e.Ring >= 0:
+		return fmt.Sprintf("ring %d: %v", e.Ring, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidatePolygon checks a polygon of [lon, lat] rings: at least one ring, 4 positions per ring, closed
+// rings and coordinates within range. Violations are *ValidationError values
+func ValidatePolygon(polygon [][][]float64) error {
+	if len(polygon) == 0 {
+		return &ValidationError{Err: ErrEmptyPolygon, Ring: -1, Position: -1}
+	}
+
+	for i, ring := range polygon {
+		if len(ring) < minRingPositions {
+			return &ValidationError{Err: ErrTooFewPoints, Ring: i, Position: -1}
+		}
+		for j, position := range ring {
+			if err := validatePosition(position); err != nil {
+				return &ValidationError{Err: err, Ring: i, Position: j}
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return &ValidationError{Err: ErrRingNotClosed, Ring: i, Position: -1}
+		}
+	}
+	return nil
+}
+
+// validatePosition checks a [lon, lat] position, extra coordinates such as altitude are allowed
+func validatePosition(position []float64) error {
+	if len(position) < 2 {
+		return ErrInvalidPosition
+	}
+	for _, coordinate := range position {
+		if math.IsNaN(coordinate) || math.IsInf(coordinate, 0) {
+			return ErrInvalidCoordinate
+		}
+	}
+	if position[0] < -180 || position[0] > 180 {
+		return ErrLongitudeRange
+	}
+	if position[1] < -90 || position[1] > 90 {
+		return ErrLatitudeRange
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package geometry
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestValidatePolygon(t *testing.T) {
+	square := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	tests := []struct {
+		name     string
+		polygon  [][][]float64
+		err      error
+		ring     int
+		position int
+	}{
+		{"valid", [][][]float64{square}, nil, 0, 0},
+		{"valid with a hole and altitudes", [][][]float64{square, {{0.2, 0.2, 10}, {0.2, 0.8, 10}, {0.8, 0.8, 10}, {0.2, 0.2, 10}}}, nil, 0, 0},
+		{"no rings", nil, ErrEmptyPolygon, -1, -1},
+		{"too few positions", [][][]float64{{{0, 0}, {1, 1}, {0, 0}}}, ErrTooFewPoints, 0, -1},
+		{"not closed", [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}, ErrRingNotClosed, 0, -1},
+		{"hole not closed", [][][]float64{square, {{0.2, 0.2}, {0.2, 0.8}, {0.8, 0.8}, {0.8, 0.2}}}, ErrRingNotClosed, 1, -1},
+		{"missing latitude", [][][]float64{{{0, 0}, {1}, {1, 1}, {0, 0}}}, ErrInvalidPosition, 0, 1},
+		{"longitude out of range", [][][]float64{{{0, 0}, {181, 0}, {1, 1}, {0, 0}}}, ErrLongitudeRange, 0, 1},
+		{"latitude out of range", [][][]float64{{{0, 0}, {1, 0}, {1, -91}, {0, 0}}}, ErrLatitudeRange, 0, 2},
+		{"swapped coordinates", [][][]float64{{{10, 100}, {11, 100}, {11, 101}, {10, 100}}}, ErrLatitudeRange, 0, 0},
+		{"NaN", [][][]float64{{{0, 0}, {math.NaN(), 0}, {1, 1}, {0, 0}}}, ErrInvalidCoordinate, 0, 1},
+		{"infinite altitude", [][][]float64{{{0, 0, math.Inf(1)}, {1, 0}, {1, 1}, {0, 0}}}, ErrInvalidCoordinate, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolygon(tt.polygon)
+			if tt.err == nil {
+				if err != nil {
+					t.Errorf("ValidatePolygon = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.Is(err, tt.err) || !errors.As(err, &validationErr) {
+				t.Fatalf("ValidatePolygon = %v, want a *ValidationError wrapping %v", err, tt.err)
+			}
+			if validationErr.Ring != tt.ring || validationErr.Position != tt.position {
+				t.Errorf("located at ring %d, position %d, want ring %d, position %d", validationErr.Ring, validationErr.Position, tt.ring, tt.position)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  *ValidationError
+		want string
+	}{
+		{&ValidationError{Err: ErrLatitudeRange, Ring: 1, Position: 3}, "ring 1, position 3: latitude is not between -90 and 90"},
+		{&ValidationError{Err: ErrRingNotClosed, Ring: 0, Position: -1}, "ring 0: ring is not closed"},
+		{&ValidationError{Err: ErrEmptyPolygon, Ring: -1, Position: -1}, "polygon has no rings"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
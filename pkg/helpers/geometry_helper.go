@@ -1,6 +1,9 @@
 package helpers
 
-import "math"
+import (
+	"math"
+	"slices"
+)
 
 func CalculatePolygonArea(polygon [][][]float64) float64 {
 	if len(polygon) == 0 {
@@ -65,6 +68,11 @@ func calculateRingSignedArea(ring [][]float64) float64 {
 // For production use, consider using a proper projection library like PROJ
 //
 // Note: This uses a simple approximation. For accurate results, use proper coordinate transformation
+//
+// Error bounds: the spherical 111,320 m degree ignores the ellipsoid, small polygons are off by up to
+// 0.7% near the equator and -0.3% at 60°N. The single average latitude adds an error growing with the
+// latitude span (about 1% for a 5° cell at 45°N, several percent for large provinces), and results are
+// meaningless for polygons crossing the antimeridian. Prefer CalculateGeodesicAreaSquareMeters
 func CalculatePolygonAreaInSquareMeters(polygon [][][]float64) float64 {
 	areaInSquareDegrees := CalculatePolygonArea(polygon)
 
@@ -82,6 +90,70 @@ func CalculatePolygonAreaInSquareMeters(polygon [][][]float64) float64 {
 	return areaInSquareDegrees * latMeters * lonMeters
 }
 
+// WGS84 ellipsoid
+const (
+	wgs84SemiMajorAxis  = 6378137.0
+	wgs84Flattening     = 1 / 298.257223563
+	wgs84Eccentricity2  = wgs84Flattening * (2 - wgs84Flattening)
+	wgs84AuthalicRadius = 6371007.180918475
+)
+
+// CalculateGeodesicAreaSquareMeters calculates the area of a GeoJSON polygon ([lon, lat] in degrees)
+// in square meters. Latitudes are mapped to the authalic sphere of WGS84, which preserves areas, and the
+// spherical excess of each ring is summed edge by edge, so large polygons and wide latitude ranges stay
+// accurate (within 0.01% for 1° cells). Holes are subtracted. Longitudes are compared modulo 360°,
+// polygons crossing the antimeridian are supported, edges longer than 180° of longitude are not
+func CalculateGeodesicAreaSquareMeters(polygon [][][]float64) float64 {
+	if len(polygon) == 0 {
+		return 0
+	}
+
+	area := math.Abs(ringGeodesicArea(polygon[0]))
+	for i := 1; i < len(polygon); i++ {
+		area -= math.Abs(ringGeodesicArea(polygon[i]))
+	}
+	if area < 0 {
+		return 0
+	}
+	return area
+}
+
+// ringGeodesicArea returns the signed area of a ring in square meters, positive for counter-clockwise
+// rings. Each edge adds the spherical excess of the triangle it forms with the north pole
+func ringGeodesicArea(ring [][]float64) float64 {
+	if len(ring) < 3 {
+		return 0
+	}
+
+	var excess float64
+	n := len(ring)
+	for i := range n {
+		j := (i + 1) % n
+		if len(ring[i]) < 2 || len(ring[j]) < 2 {
+			continue
+		}
+
+		// Wrap the longitude difference into [-180°, 180°] so edges crossing the antimeridian stay short
+		deltaLon := math.Remainder(ring[j][0]-ring[i][0], 360) * math.Pi / 180
+		t1 := math.Tan(authalicLatitude(ring[i][1]) / 2)
+		t2 := math.Tan(authalicLatitude(ring[j][1]) / 2)
+		excess += 2 * math.Atan2(math.Tan(deltaLon/2)*(t1+t2), 1+t1*t2)
+	}
+
+	return excess * wgs84AuthalicRadius * wgs84AuthalicRadius
+}
+
+// authalicLatitude converts a geodetic latitude in degrees to the authalic latitude in radians
+func authalicLatitude(lat float64) float64 {
+	q := func(sinLat float64) float64 {
+		e := math.Sqrt(wgs84Eccentricity2)
+		return (1 - wgs84Eccentricity2) * (sinLat/(1-wgs84Eccentricity2*sinLat*sinLat) -
+			math.Log((1-e*sinLat)/(1+e*sinLat))/(2*e))
+	}
+	ratio := q(math.Sin(lat*math.Pi/180)) / q(1)
+	return math.Asin(math.Max(-1, math.Min(1, ratio)))
+}
+
 // getAverageLatitude calculates the average latitude of all points in the polygon
 func getAverageLatitude(polygon [][][]float64) float64 {
 	if len(polygon) == 0 || len(polygon[0]) == 0 {
@@ -109,6 +181,9 @@ func getAverageLatitude(polygon [][][]float64) float64 {
 
 // ValidatePolygon validates that a polygon has valid structure
 // Returns true if polygon is valid, false otherwise
+//
+// Deprecated: use geometry.ValidatePolygon, which also checks ring closure and coordinate ranges and
+// reports the violation
 func ValidatePolygon(polygon [][][]float64) bool {
 	if len(polygon) == 0 {
 		return false
@@ -185,3 +260,736 @@ func isPointInRing(px, py float64, ring [][]float64) bool {
 
 	return inside
 }
+
+// earthMeanRadiusMeters is the mean radius of the WGS84 ellipsoid used for distances
+const earthMeanRadiusMeters = 6371008.8
+
+// HaversineDistance returns the great-circle distance in meters between two points in degrees
+func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	return angularDistance(lat1, lon1, lat2, lon2) * earthMeanRadiusMeters
+}
+
+// DistanceToSegment returns the distance in meters from a point to the great-circle segment between
+// (lat1, lon1) and (lat2, lon2), the distance to the nearest end when the point projects outside it
+func DistanceToSegment(lat, lon, lat1, lon1, lat2, lon2 float64) float64 {
+	segment := angularDistance(lat1, lon1, lat2, lon2)
+	toPoint := angularDistance(lat1, lon1, lat, lon)
+	if segment == 0 || toPoint == 0 {
+		return toPoint * earthMeanRadiusMeters
+	}
+
+	// Cross-track and along-track distances of the point relative to the segment great circle
+	bearingDiff := initialBearing(lat1, lon1, lat, lon) - initialBearing(lat1, lon1, lat2, lon2)
+	crossTrack := math.Asin(math.Max(-1, math.Min(1, math.Sin(toPoint)*math.Sin(bearingDiff))))
+	alongTrack := math.Acos(math.Max(-1, math.Min(1, math.Cos(toPoint)/math.Cos(crossTrack))))
+	if math.Cos(bearingDiff) < 0 {
+		alongTrack = -alongTrack
+	}
+
+	switch {
+	case alongTrack <= 0:
+		return toPoint * earthMeanRadiusMeters
+	case alongTrack >= segment:
+		return HaversineDistance(lat, lon, lat2, lon2)
+	default:
+		return math.Abs(crossTrack) * earthMeanRadiusMeters
+	}
+}
+
+// DistanceToPolygon returns the distance in meters from a point to a GeoJSON polygon: 0 inside it,
+// otherwise the distance to the nearest edge of any ring, so a point inside a hole measures the distance
+// to the hole boundary. Polygons without points return +Inf
+func DistanceToPolygon(lat, lon float64, polygon [][][]float64) float64 {
+	if IsPointInPolygon(lat, lon, polygon) {
+		return 0
+	}
+
+	distance := math.Inf(1)
+	for _, ring := range polygon {
+		n := len(ring)
+		for i := range n {
+			j := (i + 1) % n
+			if len(ring[i]) < 2 || len(ring[j]) < 2 {
+				continue
+			}
+			distance = math.Min(distance, DistanceToSegment(lat, lon, ring[i][1], ring[i][0], ring[j][1], ring[j][0]))
+		}
+	}
+	return distance
+}
+
+// angularDistance returns the haversine central angle in radians between two points in degrees
+func angularDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	deltaPhi := phi2 - phi1
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	h := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// initialBearing returns the initial great-circle bearing in radians from the first point to the second
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(deltaLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(deltaLambda)
+	return math.Atan2(y, x)
+}
+
+// CalculatePolygonCentroid returns the area-weighted centroid of a GeoJSON polygon, holes subtracted,
+// computed in the longitude/latitude plane. The centroid of a concave polygon (e.g. C-shaped) may lie
+// outside it. Polygons without area return 0, 0, false
+func CalculatePolygonCentroid(polygon [][][]float64) (lat, lon float64, ok bool) {
+	var area, sumLon, sumLat float64
+	for i, ring := range polygon {
+		ringArea, ringLon, ringLat := ringCentroid(ring)
+		weight := math.Abs(ringArea)
+		if i > 0 {
+			weight = -weight // Holes are subtracted
+		}
+		area += weight
+		sumLon += weight * ringLon
+		sumLat += weight * ringLat
+	}
+	if math.Abs(area) < 1e-15 {
+		return 0, 0, false
+	}
+	return sumLat / area, sumLon / area, true
+}
+
+// ringCentroid returns the signed area and centroid of a ring with the shoelace formula
+func ringCentroid(ring [][]float64) (area, lon, lat float64) {
+	if len(ring) < 3 {
+		return 0, 0, 0
+	}
+
+	n := len(ring)
+	for i := range n {
+		j := (i + 1) % n
+		if len(ring[i]) < 2 || len(ring[j]) < 2 {
+			continue
+		}
+		cross := ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+		area += cross
+		lon += (ring[i][0] + ring[j][0]) * cross
+		lat += (ring[i][1] + ring[j][1]) * cross
+	}
+	area /= 2
+	if area == 0 {
+		return 0, 0, 0
+	}
+	return area, lon / (6 * area), lat / (6 * area)
+}
+
+// BoundingBox is a latitude/longitude rectangle in degrees
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// Contains reports whether a point lies in the box, edges included
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// CalculateBoundingBox returns the bounds of the points of a GeoJSON polygon. Polygons without points
+// return zero bounds and false
+func CalculateBoundingBox(polygon [][][]float64) (minLat, minLon, maxLat, maxLon float64, ok bool) {
+	box, ok := polygonBoundingBox(polygon)
+	return box.MinLat, box.MinLon, box.MaxLat, box.MaxLon, ok
+}
+
+// BoundingBoxIntersects reports whether two boxes overlap or touch, a cheap pre-filter before
+// IsPointInPolygon or polygon intersection tests
+func BoundingBoxIntersects(a, b BoundingBox) bool {
+	return a.MinLat <= b.MaxLat && b.MinLat <= a.MaxLat && a.MinLon <= b.MaxLon && b.MinLon <= a.MaxLon
+}
+
+// polygonBoundingBox returns the bounding box of the points of polygon
+func polygonBoundingBox(polygon [][][]float64) (BoundingBox, bool) {
+	box := BoundingBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	found := false
+	for _, ring := range polygon {
+		for _, point := range ring {
+			if len(point) < 2 {
+				continue
+			}
+			box.MinLon = math.Min(box.MinLon, point[0])
+			box.MaxLon = math.Max(box.MaxLon, point[0])
+			box.MinLat = math.Min(box.MinLat, point[1])
+			box.MaxLat = math.Max(box.MaxLat, point[1])
+			found = true
+		}
+	}
+	if !found {
+		return BoundingBox{}, false
+	}
+	return box, true
+}
+
+// RingOrientation reports whether a ring of [lon, lat] positions is clockwise, GeoJSON exterior rings are
+// counter-clockwise and holes clockwise (RFC 7946 3.1.6). Rings without area are reported counter-clockwise
+func RingOrientation(ring [][]float64) (clockwise bool) {
+	return calculateRingSignedArea(ring) < 0
+}
+
+// NormalizePolygonOrientation returns a copy of polygon with consecutive duplicate positions removed, the
+// exterior ring counter-clockwise and holes clockwise. polygon is not modified
+func NormalizePolygonOrientation(polygon [][][]float64) [][][]float64 {
+	if polygon == nil {
+		return nil
+	}
+
+	normalized := make([][][]float64, len(polygon))
+	for i, ring := range polygon {
+		ring = dedupeRing(ring)
+		if wantClockwise := i > 0; RingOrientation(ring) != wantClockwise && calculateRingSignedArea(ring) != 0 {
+			slices.Reverse(ring)
+		}
+		normalized[i] = ring
+	}
+	return normalized
+}
+
+// dedupeRing returns a copy of ring without positions equal to the previous one
+func dedupeRing(ring [][]float64) [][]float64 {
+	deduped := make([][]float64, 0, len(ring))
+	for _, position := range ring {
+		if n := len(deduped); n > 0 && len(position) >= 2 && len(deduped[n-1]) >= 2 &&
+			position[0] == deduped[n-1][0] && position[1] == deduped[n-1][1] {
+			continue
+		}
+		deduped = append(deduped, position)
+	}
+	return deduped
+}
+
+// SimplifyOption configures SimplifyPolygon
+type SimplifyOption func(*simplifyOptions)
+
+type simplifyOptions struct {
+	dropCollapsedHoles bool
+}
+
+// WithDropCollapsedHoles drops the holes smaller than the tolerance instead of keeping them as triangles
+func WithDropCollapsedHoles() SimplifyOption {
+	return func(o *simplifyOptions) {
+		o.dropCollapsedHoles = true
+	}
+}
+
+// SimplifyPolygon returns a copy of polygon simplified with Douglas–Peucker, removing the vertices closer
+// than toleranceMeters to the simplified boundary. Distances are measured on an equirectangular projection
+// at the mean latitude of each ring. Rings stay closed with at least 4 positions: rings smaller than the
+// tolerance collapse to a triangle, holes are dropped instead with WithDropCollapsedHoles. Unclosed rings
+// and rings of fewer than 4 positions are copied unchanged
+func SimplifyPolygon(polygon [][][]float64, toleranceMeters float64, opts ...SimplifyOption) [][][]float64 {
+	if polygon == nil {
+		return nil
+	}
+
+	var options simplifyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	simplified := make([][][]float64, 0, len(polygon))
+	for i, ring := range polygon {
+		ring, collapsed := simplifyRing(ring, toleranceMeters)
+		if collapsed && i > 0 && options.dropCollapsedHoles {
+			continue
+		}
+		simplified = append(simplified, ring)
+	}
+	return simplified
+}
+
+// simplifyRing simplifies a closed ring and reports whether it collapsed below 3 distinct vertices and was
+// kept as a triangle
+func simplifyRing(ring [][]float64, toleranceMeters float64) ([][]float64, bool) {
+	n := len(ring) - 1
+	if n < 3 || toleranceMeters <= 0 || !isClosedRing(ring) {
+		return slices.Clone(ring), false
+	}
+
+	// Project to meters, index n is the closing position so the last segment ends at the first vertex
+	var sumLat float64
+	for _, point := range ring[:n] {
+		sumLat += point[1]
+	}
+	metersPerDegree := earthMeanRadiusMeters * math.Pi / 180
+	lonScale := metersPerDegree * math.Cos(sumLat/float64(n)*math.Pi/180)
+	xs := make([]float64, n+1)
+	ys := make([]float64, n+1)
+	for i, point := range ring {
+		xs[i] = point[0] * lonScale
+		ys[i] = point[1] * metersPerDegree
+	}
+	// farthest returns the vertex between from and to farthest from the segment from a to b
+	farthest := func(from, to, a, b int) (int, float64) {
+		index, maxDistance := -1, -1.0
+		for k := from + 1; k < to; k++ {
+			if d := planarSegmentDistance(xs[k], ys[k], xs[a], ys[a], xs[b], ys[b]); d > maxDistance {
+				index, maxDistance = k, d
+			}
+		}
+		return index, maxDistance
+	}
+
+	// A closed ring is split at the vertex farthest from the first one, then each half is simplified
+	split := 1
+	for k := 2; k < n; k++ {
+		if math.Hypot(xs[k]-xs[0], ys[k]-ys[0]) > math.Hypot(xs[split]-xs[0], ys[split]-ys[0]) {
+			split = k
+		}
+	}
+	keep := make([]bool, n)
+	keep[0], keep[split] = true, true
+	kept := 2
+	stack := [][2]int{{0, split}, {split, n}}
+	for len(stack) > 0 {
+		segment := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if k, d := farthest(segment[0], segment[1], segment[0], segment[1]); k >= 0 && d > toleranceMeters {
+			keep[k] = true
+			kept++
+			stack = append(stack, [2]int{segment[0], k}, [2]int{k, segment[1]})
+		}
+	}
+
+	collapsed := kept < 3
+	if collapsed {
+		k, _ := farthest(0, n, 0, split)
+		keep[k] = true
+	}
+
+	simplified := make([][]float64, 0, kept+2)
+	for i, point := range ring[:n] {
+		if keep[i] {
+			simplified = append(simplified, point)
+		}
+	}
+	return append(simplified, ring[0]), collapsed
+}
+
+// isClosedRing reports whether the first and last positions of ring are equal
+func isClosedRing(ring [][]float64) bool {
+	for _, point := range ring {
+		if len(point) < 2 {
+			return false
+		}
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	return first[0] == last[0] && first[1] == last[1]
+}
+
+// planarSegmentDistance returns the distance from (px, py) to the segment from (ax, ay) to (bx, by)
+func planarSegmentDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return math.Hypot(px-ax, py-ay)
+	}
+	t := math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/lengthSquared))
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+// boundaryToleranceDegrees is the distance under which a position is on a boundary, about 0.1 mm
+const boundaryToleranceDegrees = 1e-9
+
+// PolygonsIntersect reports whether two polygons share any point, boundaries included: an edge of one
+// crosses or touches an edge of the other, or one lies inside the other. Holes are respected, a polygon
+// inside a hole of the other does not intersect it. Use OverlapAreaSquareMeters to ignore touching zones
+func PolygonsIntersect(a, b [][][]float64) bool {
+	boxA, okA := polygonBoundingBox(a)
+	boxB, okB := polygonBoundingBox(b)
+	if !okA || !okB || !BoundingBoxIntersects(boxA, boxB) {
+		return false
+	}
+	if polygonEdgesIntersect(a, b, false) {
+		return true
+	}
+
+	// Without crossing edges the polygons are disjoint or one contains the other
+	return ringVertexInPolygon(a[0], b) || ringVertexInPolygon(b[0], a)
+}
+
+// IsPolygonInsidePolygon reports whether inner lies within outer, boundaries may touch. Holes are
+// respected: inner must not cover a hole of outer unless inner has a hole around it
+func IsPolygonInsidePolygon(inner, outer [][][]float64) bool {
+	innerBox, okInner := polygonBoundingBox(inner)
+	outerBox, okOuter := polygonBoundingBox(outer)
+	if !okInner || !okOuter || !outerBox.Contains(innerBox.MinLat, innerBox.MinLon) ||
+		!outerBox.Contains(innerBox.MaxLat, innerBox.MaxLon) {
+		return false
+	}
+	if polygonEdgesIntersect(inner[:1], outer, true) {
+		return false
+	}
+
+	// Vertices and edge midpoints of the exterior ring catch edges leaving outer between two boundary points
+	exterior := inner[0]
+	for i := range exterior {
+		j := (i + 1) % len(exterior)
+		if len(exterior[i]) < 2 || len(exterior[j]) < 2 {
+			continue
+		}
+		midLon, midLat := (exterior[i][0]+exterior[j][0])/2, (exterior[i][1]+exterior[j][1])/2
+		if !isPointInOrOnPolygon(exterior[i][0], exterior[i][1], outer) || !isPointInOrOnPolygon(midLon, midLat, outer) {
+			return false
+		}
+	}
+
+	for _, hole := range outer[1:] {
+		for _, point := range hole {
+			if len(point) >= 2 && IsPointInPolygon(point[1], point[0], inner) && !isPointOnPolygonBoundary(point[0], point[1], inner) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// OverlapAreaSquareMeters returns the area in square meters shared by two polygons, holes subtracted.
+// Rings are clipped with Sutherland–Hodgman in [lon, lat] space, which is exact when one ring of each pair
+// is convex and approximate when both are concave, close enough for administrative shapes. Touching
+// polygons overlap by 0. Self-intersecting rings give meaningless results, reject them with validation
+func OverlapAreaSquareMeters(a, b [][][]float64) float64 {
+	boxA, okA := polygonBoundingBox(a)
+	boxB, okB := polygonBoundingBox(b)
+	if !okA || !okB || !BoundingBoxIntersects(boxA, boxB) {
+		return 0
+	}
+
+	// Inclusion-exclusion over the holes, which lie inside their exterior ring
+	area := ringOverlapArea(a[0], b[0])
+	for _, holeA := range a[1:] {
+		area -= ringOverlapArea(holeA, b[0])
+	}
+	for _, holeB := range b[1:] {
+		area -= ringOverlapArea(a[0], holeB)
+		for _, holeA := range a[1:] {
+			area += ringOverlapArea(holeA, holeB)
+		}
+	}
+	return math.Max(area, 0)
+}
+
+// ringOverlapArea returns the area shared by two rings, the convex one is used as the clip ring
+func ringOverlapArea(a, b [][]float64) float64 {
+	subject, clip := a, b
+	if !isConvexRing(clip) && isConvexRing(subject) {
+		subject, clip = clip, subject
+	}
+	return math.Abs(ringGeodesicArea(clipRing(subject, clip)))
+}
+
+// clipRing clips subject by each edge of clip (Sutherland–Hodgman) and returns the remaining ring, nil
+// when they do not overlap
+func clipRing(subject, clip [][]float64) [][]float64 {
+	output := openRing(subject)
+	clipPoints := openRing(clip)
+	if len(output) < 3 || len(clipPoints) < 3 {
+		return nil
+	}
+
+	// Points left of counter-clockwise edges are inside, right of clockwise ones
+	side := 1.0
+	if calculateRingSignedArea(clip) < 0 {
+		side = -1
+	}
+	for i := range clipPoints {
+		a, b := clipPoints[i], clipPoints[(i+1)%len(clipPoints)]
+		inside := func(p []float64) bool {
+			return side*cross(a, b, p) >= 0
+		}
+		intersection := func(p, q []float64) []float64 {
+			t := cross(a, b, p) / (cross(a, b, p) - cross(a, b, q))
+			return []float64{p[0] + t*(q[0]-p[0]), p[1] + t*(q[1]-p[1])}
+		}
+
+		input := output
+		output = make([][]float64, 0, len(input)+1)
+		for j, current := range input {
+			previous := input[(j+len(input)-1)%len(input)]
+			switch {
+			case inside(current) && !inside(previous):
+				output = append(output, intersection(previous, current), current)
+			case inside(current):
+				output = append(output, current)
+			case inside(previous):
+				output = append(output, intersection(previous, current))
+			}
+		}
+		if len(output) < 3 {
+			return nil
+		}
+	}
+	return append(output, output[0])
+}
+
+// openRing returns the positions of ring without the closing one
+func openRing(ring [][]float64) [][]float64 {
+	points := make([][]float64, 0, len(ring))
+	for _, point := range ring {
+		if len(point) >= 2 {
+			points = append(points, point)
+		}
+	}
+	if n := len(points); n > 1 && points[0][0] == points[n-1][0] && points[0][1] == points[n-1][1] {
+		points = points[:n-1]
+	}
+	return points
+}
+
+// isConvexRing reports whether every turn of ring is in the same direction, collinear positions ignored
+func isConvexRing(ring [][]float64) bool {
+	points := openRing(ring)
+	if len(points) < 3 {
+		return false
+	}
+
+	var sign float64
+	for i := range points {
+		turn := cross(points[i], points[(i+1)%len(points)], points[(i+2)%len(points)])
+		if turn == 0 {
+			continue
+		}
+		if sign != 0 && (turn > 0) != (sign > 0) {
+			return false
+		}
+		sign = turn
+	}
+	return true
+}
+
+// cross returns the cross product of (b - a) and (c - a), positive when c is left of a→b
+func cross(a, b, c []float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// polygonEdgesIntersect reports whether an edge of a intersects an edge of b, only proper crossings when
+// proper is set
+func polygonEdgesIntersect(a, b [][][]float64, proper bool) bool {
+	for _, ringA := range a {
+		for i := 0; i+1 < len(ringA); i++ {
+			for _, ringB := range b {
+				for j := 0; j+1 < len(ringB); j++ {
+					if segmentsIntersect(ringA[i], ringA[i+1], ringB[j], ringB[j+1], proper) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect reports whether the segments p1→p2 and q1→q2 share a point. Proper intersections cross
+// at a single point interior to both segments
+func segmentsIntersect(p1, p2, q1, q2 []float64, proper bool) bool {
+	if len(p1) < 2 || len(p2) < 2 || len(q1) < 2 || len(q2) < 2 {
+		return false
+	}
+
+	d1, d2 := cross(q1, q2, p1), cross(q1, q2, p2)
+	d3, d4 := cross(p1, p2, q1), cross(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	if proper {
+		return false
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// onSegment reports whether p, collinear with a→b, lies between a and b
+func onSegment(a, b, p []float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
+
+// ringVertexInPolygon reports whether the first vertex of ring is inside polygon
+func ringVertexInPolygon(ring [][]float64, polygon [][][]float64) bool {
+	for _, point := range ring {
+		if len(point) >= 2 {
+			return IsPointInPolygon(point[1], point[0], polygon)
+		}
+	}
+	return false
+}
+
+// isPointInOrOnPolygon reports whether a [lon, lat] position is inside polygon or on its boundary
+func isPointInOrOnPolygon(lon, lat float64, polygon [][][]float64) bool {
+	return isPointOnPolygonBoundary(lon, lat, polygon) || IsPointInPolygon(lat, lon, polygon)
+}
+
+// isPointOnPolygonBoundary reports whether a [lon, lat] position is within boundaryToleranceDegrees of an
+// edge of polygon
+func isPointOnPolygonBoundary(lon, lat float64, polygon [][][]float64) bool {
+	for _, ring := range polygon {
+		for i := 0; i+1 < len(ring); i++ {
+			if len(ring[i]) < 2 || len(ring[i+1]) < 2 {
+				continue
+			}
+			if planarSegmentDistance(lon, lat, ring[i][0], ring[i][1], ring[i+1][0], ring[i+1][1]) <= boundaryToleranceDegrees {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxIndexBands caps the latitude bands of a ring index
+const maxIndexBands = 4096
+
+// indexBoundsMarginDegrees widens the longitude bounds of an index beyond the rounding of ray
+// intersections, so bounding box rejections agree with IsPointInPolygon
+const indexBoundsMarginDegrees = 1e-9
+
+// IndexedPolygon is a polygon prepared by BuildPolygonIndex for repeated point-in-polygon tests
+type IndexedPolygon struct {
+	polygon [][][]float64
+	box     BoundingBox
+	rings   []ringIndex
+	indexed bool
+}
+
+// ringIndex buckets the edges of a ring by latitude band, a ray cast only visits the edges of its band
+type ringIndex struct {
+	minY, maxY, bandHeight float64
+	bands                  [][][4]float64
+}
+
+// BuildPolygonIndex indexes polygon for Contains and ClassifyPoints: the bounding box of its exterior ring
+// rejects most misses and the edges of each ring are bucketed by latitude band. Build it once per zone and
+// reuse it, polygon must not be modified afterwards
+func BuildPolygonIndex(polygon [][][]float64) IndexedPolygon {
+	index := IndexedPolygon{polygon: polygon}
+	if len(polygon) == 0 || len(polygon[0]) < 3 {
+		return index
+	}
+	for _, ring := range polygon {
+		for _, point := range ring {
+			// IsPointInPolygon skips the edges of short positions and compares non-finite ones, the index
+			// falls back to it
+			if len(point) < 2 || math.IsNaN(point[0]+point[1]) || math.IsInf(point[0]+point[1], 0) {
+				return index
+			}
+		}
+	}
+
+	index.box, _ = polygonBoundingBox(polygon[:1])
+	index.rings = make([]ringIndex, len(polygon))
+	for i, ring := range polygon {
+		index.rings[i] = buildRingIndex(ring)
+	}
+	index.indexed = true
+	return index
+}
+
+func buildRingIndex(ring [][]float64) ringIndex {
+	if len(ring) < 3 {
+		return ringIndex{}
+	}
+
+	r := ringIndex{minY: math.Inf(1), maxY: math.Inf(-1)}
+	for _, point := range ring {
+		r.minY = math.Min(r.minY, point[1])
+		r.maxY = math.Max(r.maxY, point[1])
+	}
+	bandCount := max(1, min(len(ring)/4, maxIndexBands))
+	r.bandHeight = (r.maxY - r.minY) / float64(bandCount)
+	r.bands = make([][][4]float64, bandCount)
+
+	// Edges keep the direction and wrap-around of isPointInRing so ray casts round identically
+	n := len(ring)
+	for i := range n {
+		j := (i + 1) % n
+		yi, yj := ring[i][1], ring[j][1]
+		if yi == yj {
+			continue
+		}
+		edge := [4]float64{ring[i][0], yi, ring[j][0], yj}
+		for band := r.band(math.Min(yi, yj)); band <= r.band(math.Max(yi, yj)); band++ {
+			r.bands[band] = append(r.bands[band], edge)
+		}
+	}
+	return r
+}
+
+// band returns the band of latitude y, monotonic in y so an edge spanning y is always in its band
+func (r ringIndex) band(y float64) int {
+	if r.bandHeight <= 0 {
+		return 0
+	}
+	return max(0, min(int((y-r.minY)/r.bandHeight), len(r.bands)-1))
+}
+
+// contains casts a ray like isPointInRing over the edges of the band of py
+func (r ringIndex) contains(px, py float64) bool {
+	if len(r.bands) == 0 || !(py >= r.minY && py < r.maxY) {
+		return false
+	}
+
+	inside := false
+	for _, edge := range r.bands[r.band(py)] {
+		xi, yi, xj, yj := edge[0], edge[1], edge[2], edge[3]
+		if (yi > py) != (yj > py) {
+			intersectX := xi + (py-yi)*(xj-xi)/(yj-yi)
+			if px < intersectX {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Polygon returns the indexed polygon
+func (p IndexedPolygon) Polygon() [][][]float64 {
+	return p.polygon
+}
+
+// Contains reports whether a point is inside the polygon, with the same result as IsPointInPolygon
+func (p IndexedPolygon) Contains(lat, lon float64) bool {
+	if !p.indexed {
+		return IsPointInPolygon(lat, lon, p.polygon)
+	}
+	// Rays of points left of the bounding box cross the exterior ring an even number of times
+	if !(lat >= p.box.MinLat && lat < p.box.MaxLat) ||
+		!(lon >= p.box.MinLon-indexBoundsMarginDegrees && lon <= p.box.MaxLon+indexBoundsMarginDegrees) {
+		return false
+	}
+
+	if !p.rings[0].contains(lon, lat) {
+		return false
+	}
+	for _, hole := range p.rings[1:] {
+		if hole.contains(lon, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClassifyPoints returns for each [lon, lat] point the index of the first zone containing it, -1 when
+// none does. Results match IsPointInPolygon over the zones in order
+func ClassifyPoints(points [][2]float64, zones []IndexedPolygon) []int {
+	classes := make([]int, len(points))
+	for i, point := range points {
+		classes[i] = -1
+		for j := range zones {
+			if zones[j].Contains(point[1], point[0]) {
+				classes[i] = j
+				break
+			}
+		}
+	}
+	return classes
+}
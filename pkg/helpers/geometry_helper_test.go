@@ -0,0 +1,798 @@
+package helpers
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// cell returns a counter-clockwise lon/lat rectangle, with a vertex every step degrees along the
+// parallels so edges follow them
+func cell(minLon, minLat, maxLon, maxLat, step float64) [][][]float64 {
+	var ring [][]float64
+	width := math.Remainder(maxLon-minLon, 360)
+	if width <= 0 {
+		width += 360
+	}
+	n := max(int(math.Ceil(width/step)), 1)
+	for i := 0; i <= n; i++ {
+		ring = append(ring, []float64{minLon + width*float64(i)/float64(n), minLat})
+	}
+	for i := n; i >= 0; i-- {
+		ring = append(ring, []float64{minLon + width*float64(i)/float64(n), maxLat})
+	}
+	ring = append(ring, ring[0])
+	return [][][]float64{ring}
+}
+
+// withinRatio reports whether got is within ratio of want
+func withinRatio(got, want, ratio float64) bool {
+	return math.Abs(got-want) <= math.Abs(want)*ratio
+}
+
+func TestCalculateGeodesicAreaReferenceAreas(t *testing.T) {
+	// Reference areas of the WGS84 ellipsoid between two parallels, from the closed form
+	// b²/2·Δλ·[sinφ/(1-e²sin²φ) + artanh(e·sinφ)/e] evaluated at both latitudes
+	tests := []struct {
+		name    string
+		polygon [][][]float64
+		want    float64
+	}{
+		{"1x1 at the equator", cell(0, 0, 1, 1, 1), 12308463894},
+		{"1x1 at 60N", cell(10, 60, 11, 61, 1), 6123140879},
+		{"1x1 at 45S", cell(-70, -45, -69, -44, 1), 8837369526},
+		{"10x10 province", cell(100, 10, 110, 20, 0.1), 1188551885148},
+		{"1x1 across the antimeridian", cell(179.5, 0, -179.5, 1, 1), 12308463894},
+		{"1x1 past 180", cell(179.5, 0, 180.5, 1, 1), 12308463894},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateGeodesicAreaSquareMeters(tt.polygon); !withinRatio(got, tt.want, 0.0001) {
+				t.Errorf("CalculateGeodesicAreaSquareMeters = %.0f, want %.0f within 0.01%%", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculatePolygonAreaInSquareMetersErrorBounds(t *testing.T) {
+	// The flat approximation stays within its documented bounds for 1° cells
+	tests := []struct {
+		name    string
+		polygon [][][]float64
+		want    float64
+		bound   float64
+	}{
+		{"1x1 at the equator", cell(0, 0, 1, 1, 1), 12308463894, 0.007},
+		{"1x1 at 60N", cell(10, 60, 11, 61, 1), 6123140879, 0.005},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculatePolygonAreaInSquareMeters(tt.polygon); !withinRatio(got, tt.want, tt.bound) {
+				t.Errorf("CalculatePolygonAreaInSquareMeters = %.0f, want %.0f within %.1f%%", got, tt.want, tt.bound*100)
+			}
+		})
+	}
+}
+
+func TestCalculateGeodesicAreaSquareMeters(t *testing.T) {
+	outer := cell(0, 0, 1, 1, 1)
+	hole := cell(0.25, 0.25, 0.75, 0.75, 1)[0]
+	clockwise := [][][]float64{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}}
+	cellArea := CalculateGeodesicAreaSquareMeters(outer)
+
+	tests := []struct {
+		name    string
+		polygon [][][]float64
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"degenerate ring", [][][]float64{{{0, 0}, {1, 1}}}, 0},
+		{"clockwise ring", clockwise, cellArea},
+		{"unclosed ring", [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}, cellArea},
+		{"hole", [][][]float64{outer[0], hole}, cellArea - CalculateGeodesicAreaSquareMeters([][][]float64{hole})},
+		{"hole larger than the outer ring", [][][]float64{hole, outer[0]}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateGeodesicAreaSquareMeters(tt.polygon); !withinRatio(got, tt.want, 1e-9) {
+				t.Errorf("CalculateGeodesicAreaSquareMeters = %.0f, want %.0f", got, tt.want)
+			}
+		})
+	}
+
+	// The hole covers about a quarter of the cell
+	withHole := CalculateGeodesicAreaSquareMeters([][][]float64{outer[0], hole})
+	if !withinRatio(withHole, cellArea*0.75, 0.001) {
+		t.Errorf("area with hole = %.0f, want about %.0f", withHole, cellArea*0.75)
+	}
+}
+
+// Reference distances below are computed independently with unit vectors on a sphere of
+// earthMeanRadiusMeters: central angles from atan2(|a×b|, a·b), cross-track distances from the
+// normal of the segment great circle
+
+func TestHaversineDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 21.0285, 105.8542, 21.0285, 105.8542, 0},
+		{"Hanoi to Ho Chi Minh City", 21.0285, 105.8542, 10.8231, 106.6297, 1137805.9},
+		{"London to Paris", 51.5074, -0.1278, 48.8566, 2.3522, 343556.5},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111195.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HaversineDistance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("HaversineDistance = %.1f, want %.1f", got, tt.want)
+			}
+			if back := HaversineDistance(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-6 {
+				t.Errorf("HaversineDistance is not symmetric: %.3f and %.3f", got, back)
+			}
+		})
+	}
+}
+
+func TestDistanceToSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		segment  [4]float64
+		want     float64
+	}{
+		{"beside the segment", 1, 0.5, [4]float64{0, 0, 0, 1}, 111195.080},
+		{"before the start", 0, -1, [4]float64{0, 0, 0, 1}, 111195.080},
+		{"past the end", 0, 2, [4]float64{0, 0, 0, 1}, 111195.080},
+		{"on the segment", 0, 0.5, [4]float64{0, 0, 0, 1}, 0},
+		{"north of a parallel edge", 1.5, 0.5, [4]float64{1, 0, 1, 1}, 55593.307},
+		{"Ho Chi Minh City to Hanoi-Da Nang", 10.8231, 106.6297, [4]float64{21.0285, 105.8542, 16.0544, 108.2022}, 606026.292},
+		{"zero length segment", 0, 1, [4]float64{0, 0, 0, 0}, 111195.080},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.segment
+			if got := DistanceToSegment(tt.lat, tt.lon, s[0], s[1], s[2], s[3]); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("DistanceToSegment = %.3f, want %.3f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistanceToPolygon(t *testing.T) {
+	square := cell(0, 0, 1, 1, 1)
+	withHole := [][][]float64{square[0], cell(0.25, 0.25, 0.75, 0.75, 1)[0]}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		polygon  [][][]float64
+		want     float64
+	}{
+		{"inside", 0.5, 0.5, square, 0},
+		{"inside outside the hole", 0.1, 0.1, withHole, 0},
+		{"north of the square", 1.5, 0.5, square, 55593.307},
+		{"west of the square", 0.5, -1, square, 111190.846},
+		{"center of the hole", 0.5, 0.5, withHole, 27797.712},
+		{"single point", 0, 1, [][][]float64{{{0, 0}}}, 111195.080},
+		{"empty", 0, 0, nil, math.Inf(1)},
+		{"empty ring", 0, 0, [][][]float64{{}}, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceToPolygon(tt.lat, tt.lon, tt.polygon)
+			if math.IsInf(tt.want, 1) {
+				if !math.IsInf(got, 1) {
+					t.Errorf("DistanceToPolygon = %v, want +Inf", got)
+				}
+				return
+			}
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("DistanceToPolygon = %.3f, want %.3f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculatePolygonCentroid(t *testing.T) {
+	// A 3x3 square with a 2x1 notch cut from its right side
+	cShape := [][][]float64{{{0, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 2}, {3, 2}, {3, 3}, {0, 3}, {0, 0}}}
+	withHole := [][][]float64{
+		{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}},
+		{{2, 2}, {2, 3}, {3, 3}, {3, 2}, {2, 2}},
+	}
+	// The same rings listed clockwise and counter-clockwise
+	reversedHole := [][][]float64{
+		{{0, 0}, {0, 4}, {4, 4}, {4, 0}, {0, 0}},
+		{{2, 2}, {3, 2}, {3, 3}, {2, 3}, {2, 2}},
+	}
+
+	tests := []struct {
+		name     string
+		polygon  [][][]float64
+		lat, lon float64
+		ok       bool
+	}{
+		{"square", [][][]float64{{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {0, 0}}}, 1, 1, true},
+		{"C-shaped", cShape, 1.5, 9.5 / 7, true},
+		{"hole", withHole, 29.5 / 15, 29.5 / 15, true},
+		{"hole with reversed orientations", reversedHole, 29.5 / 15, 29.5 / 15, true},
+		{"empty", nil, 0, 0, false},
+		{"two points", [][][]float64{{{0, 0}, {1, 1}}}, 0, 0, false},
+		{"collinear", [][][]float64{{{0, 0}, {1, 1}, {2, 2}, {0, 0}}}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, ok := CalculatePolygonCentroid(tt.polygon)
+			if ok != tt.ok || math.Abs(lat-tt.lat) > 1e-9 || math.Abs(lon-tt.lon) > 1e-9 {
+				t.Errorf("CalculatePolygonCentroid = %v, %v, %v, want %v, %v, %v", lat, lon, ok, tt.lat, tt.lon, tt.ok)
+			}
+		})
+	}
+
+	// The centroid of the C falls in its notch, outside the polygon
+	lat, lon, _ := CalculatePolygonCentroid(cShape)
+	if IsPointInPolygon(lat, lon, cShape) {
+		t.Errorf("centroid %v, %v of the C-shaped polygon is inside it", lat, lon)
+	}
+}
+
+func TestCalculateBoundingBox(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon [][][]float64
+		want    BoundingBox
+		ok      bool
+	}{
+		{"C-shaped", [][][]float64{{{0, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 2}, {3, 2}, {3, 3}, {0, 3}, {0, 0}}}, BoundingBox{0, 0, 3, 3}, true},
+		{"negative coordinates", [][][]float64{{{-70, -45}, {-69, -45}, {-69, -44}, {-70, -45}}}, BoundingBox{-45, -70, -44, -69}, true},
+		{"holes inside the outer ring", [][][]float64{{{100, 10}, {110, 10}, {110, 20}, {100, 10}}, {{105, 12}, {106, 12}, {106, 13}, {105, 12}}}, BoundingBox{10, 100, 20, 110}, true},
+		{"short positions skipped", [][][]float64{{{5}, {1, 2}, {3, 4}}}, BoundingBox{2, 1, 4, 3}, true},
+		{"empty", nil, BoundingBox{}, false},
+		{"no positions", [][][]float64{{}, {{1}}}, BoundingBox{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minLat, minLon, maxLat, maxLon, ok := CalculateBoundingBox(tt.polygon)
+			if got := (BoundingBox{minLat, minLon, maxLat, maxLon}); got != tt.want || ok != tt.ok {
+				t.Errorf("CalculateBoundingBox = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBoundingBoxIntersects(t *testing.T) {
+	box := BoundingBox{MinLat: 0, MinLon: 0, MaxLat: 1, MaxLon: 1}
+	tests := []struct {
+		name  string
+		other BoundingBox
+		want  bool
+	}{
+		{"overlapping", BoundingBox{0.5, 0.5, 2, 2}, true},
+		{"contained", BoundingBox{0.25, 0.25, 0.75, 0.75}, true},
+		{"touching edge", BoundingBox{1, 0, 2, 1}, true},
+		{"touching corner", BoundingBox{1, 1, 2, 2}, true},
+		{"north", BoundingBox{1.1, 0, 2, 1}, false},
+		{"east", BoundingBox{0, 1.1, 1, 2}, false},
+		{"same latitudes west", BoundingBox{0, -2, 1, -0.1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BoundingBoxIntersects(box, tt.other); got != tt.want {
+				t.Errorf("BoundingBoxIntersects = %v, want %v", got, tt.want)
+			}
+			if got := BoundingBoxIntersects(tt.other, box); got != tt.want {
+				t.Errorf("BoundingBoxIntersects reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !box.Contains(1, 0) || !box.Contains(0.5, 0.5) || box.Contains(1.01, 0.5) || box.Contains(0.5, -0.01) {
+		t.Error("Contains does not include exactly the box and its edges")
+	}
+}
+
+func TestRingOrientation(t *testing.T) {
+	tests := []struct {
+		name      string
+		ring      [][]float64
+		clockwise bool
+	}{
+		{"counter-clockwise", [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}, false},
+		{"clockwise", [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}, true},
+		{"unclosed clockwise", [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}}, true},
+		{"collinear", [][]float64{{0, 0}, {1, 1}, {2, 2}, {0, 0}}, false},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RingOrientation(tt.ring); got != tt.clockwise {
+				t.Errorf("RingOrientation = %v, want %v", got, tt.clockwise)
+			}
+		})
+	}
+}
+
+func TestNormalizePolygonOrientation(t *testing.T) {
+	exterior := [][]float64{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}
+	hole := [][]float64{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}
+	normalized := [][][]float64{exterior, hole}
+
+	tests := []struct {
+		name    string
+		polygon [][][]float64
+	}{
+		{"already normalized", [][][]float64{exterior, hole}},
+		{"reversed exterior", [][][]float64{reversedRing(exterior), hole}},
+		{"reversed hole", [][][]float64{exterior, reversedRing(hole)}},
+		{"both reversed", [][][]float64{reversedRing(exterior), reversedRing(hole)}},
+		{"duplicate positions", [][][]float64{{{0, 0}, {0, 0}, {4, 0}, {4, 4}, {4, 4}, {4, 4}, {0, 4}, {0, 0}}, {{1, 1}, {1, 2}, {2, 2}, {2, 2}, {2, 1}, {1, 1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := clonePolygon(tt.polygon)
+			got := NormalizePolygonOrientation(tt.polygon)
+			if !reflect.DeepEqual(got, normalized) {
+				t.Errorf("NormalizePolygonOrientation = %v, want %v", got, normalized)
+			}
+			if !reflect.DeepEqual(tt.polygon, input) {
+				t.Errorf("input modified to %v", tt.polygon)
+			}
+			// Areas and containment do not depend on the orientation
+			if before, after := CalculatePolygonArea(tt.polygon), CalculatePolygonArea(got); before != after || after != 15 {
+				t.Errorf("CalculatePolygonArea before %v, after %v, want 15", before, after)
+			}
+			if before, after := CalculateGeodesicAreaSquareMeters(tt.polygon), CalculateGeodesicAreaSquareMeters(got); !withinRatio(before, after, 1e-12) {
+				t.Errorf("CalculateGeodesicAreaSquareMeters before %.0f, after %.0f", before, after)
+			}
+			if IsPointInPolygon(1.5, 1.5, got) || !IsPointInPolygon(3, 3, got) {
+				t.Error("normalized polygon contains the hole or misses the exterior")
+			}
+		})
+	}
+
+	if got := NormalizePolygonOrientation(nil); got != nil {
+		t.Errorf("NormalizePolygonOrientation(nil) = %v, want nil", got)
+	}
+	// Rings without area are kept as they are, minus duplicates
+	collinear := [][][]float64{{{2, 2}, {1, 1}, {1, 1}, {0, 0}, {2, 2}}}
+	if got, want := NormalizePolygonOrientation(collinear), [][][]float64{{{2, 2}, {1, 1}, {0, 0}, {2, 2}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizePolygonOrientation of a collinear ring = %v, want %v", got, want)
+	}
+}
+
+func reversedRing(ring [][]float64) [][]float64 {
+	reversed := slices.Clone(ring)
+	slices.Reverse(reversed)
+	return reversed
+}
+
+func clonePolygon(polygon [][][]float64) [][][]float64 {
+	clone := make([][][]float64, len(polygon))
+	for i, ring := range polygon {
+		clone[i] = slices.Clone(ring)
+	}
+	return clone
+}
+
+// fieldBoundary returns a field-drawn boundary: a closed ring of n vertices around (lat, lon) with a radius
+// of radiusMeters and a deterministic jitter of up to jitterMeters, counter-clockwise unless clockwise
+func fieldBoundary(lat, lon, radiusMeters, jitterMeters float64, n int, clockwise bool, seed uint64) [][]float64 {
+	random := rand.New(rand.NewPCG(seed, seed))
+	metersPerDegree := earthMeanRadiusMeters * math.Pi / 180
+	ring := make([][]float64, 0, n+1)
+	for i := range n {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		if clockwise {
+			angle = -angle
+		}
+		r := radiusMeters + (random.Float64()*2-1)*jitterMeters
+		ring = append(ring, []float64{
+			lon + r*math.Cos(angle)/(metersPerDegree*math.Cos(lat*math.Pi/180)),
+			lat + r*math.Sin(angle)/metersPerDegree,
+		})
+	}
+	return append(ring, ring[0])
+}
+
+// fieldPolygon is a 20k vertex boundary with a 5k vertex hole
+func fieldPolygon() [][][]float64 {
+	return [][][]float64{
+		fieldBoundary(21.0, 105.8, 1000, 2, 20000, false, 1),
+		fieldBoundary(21.0, 105.8, 300, 2, 5000, true, 2),
+	}
+}
+
+// distanceToRing returns the distance in meters from a point to the nearest edge of ring
+func distanceToRing(lat, lon float64, ring [][]float64) float64 {
+	distance := math.Inf(1)
+	for i := 0; i+1 < len(ring); i++ {
+		distance = math.Min(distance, DistanceToSegment(lat, lon, ring[i][1], ring[i][0], ring[i+1][1], ring[i+1][0]))
+	}
+	return distance
+}
+
+func TestSimplifyPolygonFieldBoundary(t *testing.T) {
+	polygon := fieldPolygon()
+	input := clonePolygon(polygon)
+	const tolerance = 5.0
+
+	simplified := SimplifyPolygon(polygon, tolerance)
+	if !reflect.DeepEqual(polygon, input) {
+		t.Fatal("SimplifyPolygon modified its input")
+	}
+	if len(simplified) != 2 {
+		t.Fatalf("SimplifyPolygon kept %d rings, want 2", len(simplified))
+	}
+	for i, ring := range simplified {
+		if len(ring) < 4 || len(ring) > len(polygon[i])/10 || !isClosedRing(ring) {
+			t.Errorf("ring %d has %d positions (from %d), closed %v", i, len(ring), len(polygon[i]), isClosedRing(ring))
+		}
+		if RingOrientation(ring) != RingOrientation(polygon[i]) {
+			t.Errorf("ring %d changed orientation", i)
+		}
+		// Every original vertex stays within the tolerance, allowing for the planar projection
+		for _, point := range polygon[i] {
+			if d := distanceToRing(point[1], point[0], ring); d > tolerance*1.01 {
+				t.Fatalf("ring %d vertex %v is %.2f m from the simplified ring, want at most %.0f m", i, point, d, tolerance)
+			}
+		}
+	}
+
+	before, after := CalculateGeodesicAreaSquareMeters(polygon), CalculateGeodesicAreaSquareMeters(simplified)
+	if !withinRatio(after, before, 0.005) {
+		t.Errorf("simplified area %.0f m², original %.0f m², want within 0.5%%", after, before)
+	}
+}
+
+func TestSimplifyPolygonCollapsedRings(t *testing.T) {
+	exterior := fieldBoundary(21.0, 105.8, 1000, 0, 400, false, 1)
+	// A hole of about 1 m collapses with a 10 m tolerance
+	tinyHole := fieldBoundary(21.0, 105.8, 0.5, 0, 40, true, 2)
+
+	simplified := SimplifyPolygon([][][]float64{exterior, tinyHole}, 10)
+	if len(simplified) != 2 || len(simplified[1]) != 4 || !isClosedRing(simplified[1]) {
+		t.Errorf("collapsed hole = %v, want a closed triangle", simplified[1:])
+	}
+	if dropped := SimplifyPolygon([][][]float64{exterior, tinyHole}, 10, WithDropCollapsedHoles()); len(dropped) != 1 {
+		t.Errorf("SimplifyPolygon with WithDropCollapsedHoles kept %d rings, want the exterior only", len(dropped))
+	}
+
+	// An exterior ring is never dropped, even with the option
+	if tiny := SimplifyPolygon([][][]float64{tinyHole}, 10, WithDropCollapsedHoles()); len(tiny) != 1 || len(tiny[0]) != 4 {
+		t.Errorf("collapsed exterior = %v, want a closed triangle", tiny)
+	}
+}
+
+func TestSimplifyPolygonUnchanged(t *testing.T) {
+	square := [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}
+	tests := []struct {
+		name      string
+		polygon   [][][]float64
+		tolerance float64
+	}{
+		{"zero tolerance", fieldPolygon(), 0},
+		{"negative tolerance", square, -1},
+		{"square above the tolerance", square, 10},
+		{"unclosed ring", [][][]float64{{{0, 0}, {0.5, 0.0000001}, {1, 0}, {1, 1}}}, 10},
+		{"triangle", [][][]float64{{{0, 0}, {1, 0}, {0, 1}}}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SimplifyPolygon(tt.polygon, tt.tolerance); !reflect.DeepEqual(got, tt.polygon) {
+				t.Errorf("SimplifyPolygon changed the polygon to %v", got)
+			}
+		})
+	}
+	if got := SimplifyPolygon(nil, 10); got != nil {
+		t.Errorf("SimplifyPolygon(nil) = %v, want nil", got)
+	}
+}
+
+func BenchmarkSimplifyPolygon(b *testing.B) {
+	polygon := fieldPolygon()
+	b.ResetTimer()
+	for range b.N {
+		SimplifyPolygon(polygon, 5)
+	}
+}
+
+// BenchmarkIsPointInPolygonSimplified compares point-in-polygon checks against the field boundary before
+// and after simplification
+func BenchmarkIsPointInPolygonSimplified(b *testing.B) {
+	original := fieldPolygon()
+	polygons := []struct {
+		name    string
+		polygon [][][]float64
+	}{
+		{"original", original},
+		{"simplified", SimplifyPolygon(original, 5)},
+	}
+	random := rand.New(rand.NewPCG(3, 3))
+	points := make([][2]float64, 1024)
+	for i := range points {
+		points[i] = [2]float64{21.0 + (random.Float64()*2-1)*0.01, 105.8 + (random.Float64()*2-1)*0.01}
+	}
+
+	for _, p := range polygons {
+		b.Run(p.name, func(b *testing.B) {
+			for i := range b.N {
+				point := points[i%len(points)]
+				IsPointInPolygon(point[0], point[1], p.polygon)
+			}
+		})
+	}
+}
+
+// rect returns a counter-clockwise lon/lat rectangle ring
+func rect(minLon, minLat, maxLon, maxLat float64) [][]float64 {
+	return [][]float64{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+}
+
+func TestPolygonsIntersect(t *testing.T) {
+	unit := [][][]float64{rect(0, 0, 1, 1)}
+	withHole := [][][]float64{rect(0, 0, 4, 4), reversedRing(rect(1, 1, 3, 3))}
+
+	tests := []struct {
+		name string
+		a, b [][][]float64
+		want bool
+	}{
+		{"disjoint", unit, [][][]float64{rect(2, 2, 3, 3)}, false},
+		{"overlapping boxes only", [][][]float64{{{0, 0}, {2, 0}, {0, 2}, {0, 0}}}, [][][]float64{rect(1.5, 1.5, 2.5, 2.5)}, false},
+		{"touching edge", unit, [][][]float64{rect(1, 0, 2, 1)}, true},
+		{"touching corner", unit, [][][]float64{rect(1, 1, 2, 2)}, true},
+		{"partially overlapping", unit, [][][]float64{rect(0.5, 0.5, 1.5, 1.5)}, true},
+		{"fully contained", unit, [][][]float64{rect(0.25, 0.25, 0.75, 0.75)}, true},
+		{"crossing without vertices inside", unit, [][][]float64{rect(-1, 0.25, 2, 0.75)}, true},
+		{"inside a hole", withHole, [][][]float64{rect(1.5, 1.5, 2.5, 2.5)}, false},
+		{"across a hole boundary", withHole, [][][]float64{rect(2.5, 2.5, 3.5, 3.5)}, true},
+		{"empty", unit, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PolygonsIntersect(tt.a, tt.b); got != tt.want {
+				t.Errorf("PolygonsIntersect(a, b) = %v, want %v", got, tt.want)
+			}
+			if got := PolygonsIntersect(tt.b, tt.a); got != tt.want {
+				t.Errorf("PolygonsIntersect(b, a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPolygonInsidePolygon(t *testing.T) {
+	unit := [][][]float64{rect(0, 0, 1, 1)}
+	// A 3x3 square with a 2x1 notch cut from its right side
+	cShape := [][][]float64{{{0, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 2}, {3, 2}, {3, 3}, {0, 3}, {0, 0}}}
+	withHole := [][][]float64{rect(0, 0, 4, 4), reversedRing(rect(1, 1, 2, 2))}
+
+	tests := []struct {
+		name         string
+		inner, outer [][][]float64
+		want         bool
+	}{
+		{"contained", [][][]float64{rect(0.25, 0.25, 0.75, 0.75)}, unit, true},
+		{"container inside contained", unit, [][][]float64{rect(0.25, 0.25, 0.75, 0.75)}, false},
+		{"same polygon", unit, unit, true},
+		{"sharing an edge from inside", [][][]float64{rect(0, 0, 0.5, 1)}, unit, true},
+		{"partially overlapping", [][][]float64{rect(0.5, 0.5, 1.5, 1.5)}, unit, false},
+		{"disjoint", [][][]float64{rect(2, 2, 3, 3)}, unit, false},
+		{"in the arm of a C", [][][]float64{rect(0.5, 0.2, 2.5, 0.8)}, cShape, true},
+		{"across the notch of a C", [][][]float64{rect(0.5, 0.5, 2.5, 2.5)}, cShape, false},
+		{"spanning the notch with vertices in the arms", [][][]float64{rect(2, 0.5, 2.5, 2.5)}, cShape, false},
+		{"covering a hole", [][][]float64{rect(0.5, 0.5, 3, 3)}, withHole, false},
+		{"with a hole around the hole", [][][]float64{rect(0.5, 0.5, 3, 3), reversedRing(rect(1, 1, 2, 2))}, withHole, true},
+		{"inside the hole", [][][]float64{rect(1.25, 1.25, 1.75, 1.75)}, withHole, false},
+		{"empty", nil, unit, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPolygonInsidePolygon(tt.inner, tt.outer); got != tt.want {
+				t.Errorf("IsPolygonInsidePolygon = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlapAreaSquareMeters(t *testing.T) {
+	unit := [][][]float64{rect(0, 0, 1, 1)}
+	withHole := [][][]float64{rect(0, 0, 4, 4), reversedRing(rect(1, 1, 2, 2))}
+	cShape := [][][]float64{{{0, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 2}, {3, 2}, {3, 3}, {0, 3}, {0, 0}}}
+	// area returns the geodesic area of outer minus its holes
+	area := func(outer [][]float64, holes ...[][]float64) float64 {
+		return CalculateGeodesicAreaSquareMeters(append([][][]float64{outer}, holes...))
+	}
+
+	tests := []struct {
+		name string
+		a, b [][][]float64
+		want float64
+	}{
+		{"disjoint", unit, [][][]float64{rect(2, 2, 3, 3)}, 0},
+		{"touching edge", unit, [][][]float64{rect(1, 0, 2, 1)}, 0},
+		{"partially overlapping", unit, [][][]float64{rect(0.5, 0.5, 1.5, 1.5)}, area(rect(0.5, 0.5, 1, 1))},
+		{"fully contained", unit, [][][]float64{rect(0.25, 0.25, 0.75, 0.75)}, area(rect(0.25, 0.25, 0.75, 0.75))},
+		{"same polygon", unit, unit, area(rect(0, 0, 1, 1))},
+		{"inside a hole", withHole, [][][]float64{rect(1.25, 1.25, 1.75, 1.75)}, 0},
+		{"over part of a hole", withHole, [][][]float64{rect(1.5, 0.5, 2.5, 1.5)}, area(rect(1.5, 0.5, 2.5, 1.5), rect(1.5, 1, 2, 1.5))},
+		{"both with the same hole", withHole, withHole, area(rect(0, 0, 4, 4), rect(1, 1, 2, 2))},
+		{"concave and convex", cShape, [][][]float64{rect(0, 0, 3, 3)}, area(rect(0, 0, 3, 3), rect(1, 1, 3, 2))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, got := range []float64{OverlapAreaSquareMeters(tt.a, tt.b), OverlapAreaSquareMeters(tt.b, tt.a)} {
+				if tt.want == 0 && got != 0 || !withinRatio(got, tt.want, 0.001) {
+					t.Errorf("OverlapAreaSquareMeters = %.0f, want %.0f", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// naiveClassifyPoints is the nested loop ClassifyPoints replaces
+func naiveClassifyPoints(points [][2]float64, zones [][][][]float64) []int {
+	classes := make([]int, len(points))
+	for i, point := range points {
+		classes[i] = -1
+		for j, zone := range zones {
+			if IsPointInPolygon(point[1], point[0], zone) {
+				classes[i] = j
+				break
+			}
+		}
+	}
+	return classes
+}
+
+// randomZone returns a star-shaped ring around a random center, with a hole half the time. Coordinates
+// are rounded to a coarse grid so points land exactly on vertices and horizontal edges
+func randomZone(random *rand.Rand) [][][]float64 {
+	grid := func(v float64) float64 { return math.Round(v*8) / 8 }
+	centerLon, centerLat := grid(random.Float64()*20), grid(random.Float64()*20)
+	ring := func(radius float64, n int, clockwise bool) [][]float64 {
+		var points [][]float64
+		for i := range n {
+			angle := 2 * math.Pi * float64(i) / float64(n)
+			if clockwise {
+				angle = -angle
+			}
+			r := radius * (0.3 + 0.7*random.Float64())
+			points = append(points, []float64{grid(centerLon + r*math.Cos(angle)), grid(centerLat + r*math.Sin(angle))})
+		}
+		// Some rings are left unclosed, IsPointInPolygon wraps around either way
+		if random.IntN(4) > 0 {
+			points = append(points, points[0])
+		}
+		return points
+	}
+
+	polygon := [][][]float64{ring(1+random.Float64()*4, 3+random.IntN(200), random.IntN(2) == 0)}
+	if random.IntN(2) == 0 {
+		polygon = append(polygon, ring(0.5, 3+random.IntN(20), true))
+	}
+	return polygon
+}
+
+// randomPoints returns points spread over the zones, on their vertices and on their bounding boxes
+func randomPoints(random *rand.Rand, zones [][][][]float64, n int) [][2]float64 {
+	points := make([][2]float64, 0, n)
+	for len(points) < n {
+		zone := zones[random.IntN(len(zones))]
+		ring := zone[random.IntN(len(zone))]
+		vertex := ring[random.IntN(len(ring))]
+		box, _ := polygonBoundingBox(zone)
+		switch random.IntN(4) {
+		case 0:
+			points = append(points, [2]float64{vertex[0], vertex[1]})
+		case 1:
+			points = append(points, [2]float64{box.MinLon + random.Float64()*(box.MaxLon-box.MinLon), box.MaxLat})
+		case 2:
+			points = append(points, [2]float64{math.Round(vertex[0]*16) / 16, math.Round(vertex[1]*16) / 16})
+		default:
+			points = append(points, [2]float64{random.Float64()*30 - 5, random.Float64()*30 - 5})
+		}
+	}
+	return points
+}
+
+func TestClassifyPointsMatchesNaive(t *testing.T) {
+	hits := 0
+	for seed := range uint64(100) {
+		random := rand.New(rand.NewPCG(seed, 14))
+		zones := make([][][][]float64, 1+random.IntN(30))
+		for i := range zones {
+			zones[i] = randomZone(random)
+		}
+		indexed := make([]IndexedPolygon, len(zones))
+		for i, zone := range zones {
+			indexed[i] = BuildPolygonIndex(zone)
+		}
+		points := randomPoints(random, zones, 2000)
+
+		got, want := ClassifyPoints(points, indexed), naiveClassifyPoints(points, zones)
+		for i := range points {
+			if got[i] != want[i] {
+				t.Fatalf("seed %d: point %v classified %d, IsPointInPolygon gives %d", seed, points[i], got[i], want[i])
+			}
+			if got[i] >= 0 {
+				hits++
+			}
+		}
+	}
+	// The generated points must exercise both outcomes
+	if hits == 0 || hits == 100*2000 {
+		t.Errorf("%d of %d points inside a zone", hits, 100*2000)
+	}
+}
+
+func TestClassifyPointsDegenerateZones(t *testing.T) {
+	zones := [][][][]float64{
+		nil,
+		{{}},
+		{{{0, 0}, {1, 1}}},
+		{{{0, 0}, {1}, {1, 1}, {0, 1}, {0, 0}}},
+		{{{0, 0}, {math.NaN(), 0}, {1, 1}, {0, 1}, {0, 0}}},
+		{{{0, 0}, {1, 0}, {2, 0}, {0, 0}}},
+		{rect(0, 0, 1, 1)},
+	}
+	indexed := make([]IndexedPolygon, len(zones))
+	for i, zone := range zones {
+		indexed[i] = BuildPolygonIndex(zone)
+		if !reflect.DeepEqual(indexed[i].Polygon(), zone) {
+			t.Errorf("zone %d: Polygon() = %v, want %v", i, indexed[i].Polygon(), zone)
+		}
+	}
+	points := [][2]float64{{0.5, 0.5}, {0, 0}, {1, 1}, {0.5, 0}, {0.5, 1}, {0, 0.5}, {2, 2}, {-1, 0.5}}
+
+	if got, want := ClassifyPoints(points, indexed), naiveClassifyPoints(points, zones); !slices.Equal(got, want) {
+		t.Errorf("ClassifyPoints = %v, IsPointInPolygon gives %v", got, want)
+	}
+	if got := ClassifyPoints(points, nil); !slices.Equal(got, []int{-1, -1, -1, -1, -1, -1, -1, -1}) {
+		t.Errorf("ClassifyPoints without zones = %v", got)
+	}
+	if got := ClassifyPoints(nil, indexed); len(got) != 0 {
+		t.Errorf("ClassifyPoints without points = %v", got)
+	}
+}
+
+// districtZones returns a grid of 300 adjacent districts of about 3 km with 400 vertex boundaries
+func districtZones() [][][][]float64 {
+	var zones [][][][]float64
+	for row := range 15 {
+		for col := range 20 {
+			lat, lon := 20.9+float64(row)*0.03, 105.7+float64(col)*0.03
+			zones = append(zones, [][][]float64{fieldBoundary(lat, lon, 1500, 20, 400, false, uint64(row*20+col))})
+		}
+	}
+	return zones
+}
+
+// BenchmarkClassifyPoints classifies 10k GPS points against 300 districts, most points miss most zones
+func BenchmarkClassifyPoints(b *testing.B) {
+	zones := districtZones()
+	random := rand.New(rand.NewPCG(4, 4))
+	points := make([][2]float64, 10000)
+	for i := range points {
+		points[i] = [2]float64{105.68 + random.Float64()*0.62, 20.88 + random.Float64()*0.47}
+	}
+
+	b.Run("naive", func(b *testing.B) {
+		for range b.N {
+			naiveClassifyPoints(points, zones)
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		indexed := make([]IndexedPolygon, len(zones))
+		for i, zone := range zones {
+			indexed[i] = BuildPolygonIndex(zone)
+		}
+		b.ResetTimer()
+		for range b.N {
+			ClassifyPoints(points, indexed)
+		}
+	})
+}
@@ -1,17 +1,179 @@
 package helpers
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashAlgorithm is a password hashing scheme
+type HashAlgorithm string
+
+const (
+	HashBcrypt   HashAlgorithm = "bcrypt"
+	HashArgon2id HashAlgorithm = "argon2id"
+)
+
+// ErrInvalidHash is returned for hashes of an unknown scheme or with malformed parameters
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// HashOptions configures HashPassword, empty fields get defaults
+type HashOptions struct {
+	// Algorithm is HashBcrypt by default
+	Algorithm HashAlgorithm
+	// BcryptCost is bcrypt.DefaultCost (10) by default
+	BcryptCost int
+	// Argon2Memory is the argon2id memory in KiB, 64 MiB by default
+	Argon2Memory uint32
+	// Argon2Iterations is the argon2id number of passes, 3 by default
+	Argon2Iterations uint32
+	// Argon2Parallelism is the argon2id number of threads, 2 by default
+	Argon2Parallelism uint8
+	// Argon2SaltLength is 16 bytes by default
+	Argon2SaltLength uint32
+	// Argon2KeyLength is 32 bytes by default
+	Argon2KeyLength uint32
+}
+
+// withDefaults fills the empty fields of opts
+func (opts HashOptions) withDefaults() HashOptions {
+	if opts.Algorithm == "" {
+		opts.Algorithm = HashBcrypt
+	}
+	if opts.BcryptCost == 0 {
+		opts.BcryptCost = bcrypt.DefaultCost
+	}
+	if opts.Argon2Memory == 0 {
+		opts.Argon2Memory = 64 * 1024
+	}
+	if opts.Argon2Iterations == 0 {
+		opts.Argon2Iterations = 3
+	}
+	if opts.Argon2Parallelism == 0 {
+		opts.Argon2Parallelism = 2
+	}
+	if opts.Argon2SaltLength == 0 {
+		opts.Argon2SaltLength = 16
+	}
+	if opts.Argon2KeyLength == 0 {
+		opts.Argon2KeyLength = 32
+	}
+	return opts
+}
+
+// HashPassword hashes p, encoding the algorithm and its parameters in the result: bcrypt's "$2a$<cost>$..."
+// or the PHC string "$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>"
+func HashPassword(p string, opts HashOptions) (string, error) {
+	opts = opts.withDefaults()
+	switch opts.Algorithm {
+	case HashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(p), opts.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	case HashArgon2id:
+		salt := make([]byte, opts.Argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(p), salt, opts.Argon2Iterations, opts.Argon2Memory, opts.Argon2Parallelism, opts.Argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+			opts.Argon2Memory, opts.Argon2Iterations, opts.Argon2Parallelism,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm %q", opts.Algorithm)
+	}
+}
+
+// VerifyPassword checks p against hash, detecting its scheme. needsRehash reports a matching password
+// whose hash uses another algorithm or weaker parameters than opts, so it can be upgraded on login
+func VerifyPassword(hash, p string, opts HashOptions) (match, needsRehash bool, err error) {
+	opts = opts.withDefaults()
+
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(p), salt, params.Argon2Iterations, params.Argon2Memory, params.Argon2Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+		needsRehash = opts.Algorithm != HashArgon2id ||
+			params.Argon2Memory < opts.Argon2Memory ||
+			params.Argon2Iterations < opts.Argon2Iterations ||
+			params.Argon2Parallelism < opts.Argon2Parallelism ||
+			uint32(len(key)) < opts.Argon2KeyLength
+		return true, needsRehash, nil
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(p)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	return true, opts.Algorithm != HashBcrypt || cost < opts.BcryptCost, nil
+}
+
+// decodeArgon2id parses a PHC encoded argon2id hash
+func decodeArgon2id(hash string) (HashOptions, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return HashOptions{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return HashOptions{}, nil, nil, ErrInvalidHash
+	}
+	var params HashOptions
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Iterations, &params.Argon2Parallelism); err != nil ||
+		params.Argon2Iterations == 0 || params.Argon2Parallelism == 0 {
+		return HashOptions{}, nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return HashOptions{}, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return HashOptions{}, nil, nil, ErrInvalidHash
+	}
+	params.Algorithm = HashArgon2id
+	return params, salt, key, nil
+}
+
+// HashPass hashes p with bcrypt at the default cost, it returns nil on failure
+//
+// Deprecated: use HashPassword, which reports errors
 func HashPass(p string) []byte {
-	salt := 8
-	password := []byte(p)
-	hash, _ := bcrypt.GenerateFromPassword(password, salt)
-	return hash
+	hash, err := HashPassword(p, HashOptions{})
+	if err != nil {
+		return nil
+	}
+	return []byte(hash)
 }
 
+// ComparePass reports whether p matches the bcrypt or argon2id hash h, an empty hash never matches
+//
+// Deprecated: use VerifyPassword, which also reports whether the hash should be upgraded
 func ComparePass(h, p []byte) bool {
-	hash, pass := []byte(h), []byte(p)
-
-	err := bcrypt.CompareHashAndPassword(hash, pass)
-	return err == nil
+	if len(h) == 0 {
+		return false
+	}
+	match, _, err := VerifyPassword(string(h), string(p), HashOptions{})
+	return err == nil && match
 }
@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2 keeps the argon2id tests fast
+var fastArgon2 = HashOptions{Algorithm: HashArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}
+
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   HashOptions
+		prefix string
+	}{
+		{"bcrypt", HashOptions{BcryptCost: bcrypt.MinCost}, "$2a$04$"},
+		{"argon2id", fastArgon2, "$argon2id$v=19$m=1024,t=1,p=1$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := HashPassword("s3cret", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash = %q, want the %q prefix", hash, tt.prefix)
+			}
+			if other, _ := HashPassword("s3cret", tt.opts); other == hash {
+				t.Error("two hashes of the same password are equal, want a random salt")
+			}
+
+			match, needsRehash, err := VerifyPassword(hash, "s3cret", tt.opts)
+			if err != nil || !match || needsRehash {
+				t.Errorf("VerifyPassword(right password) = %v, %v, %v, want a match without rehash", match, needsRehash, err)
+			}
+			match, needsRehash, err = VerifyPassword(hash, "wrong", tt.opts)
+			if err != nil || match || needsRehash {
+				t.Errorf("VerifyPassword(wrong password) = %v, %v, %v, want no match", match, needsRehash, err)
+			}
+		})
+	}
+
+	if _, err := HashPassword("s3cret", HashOptions{Algorithm: "md5"}); err == nil {
+		t.Error("HashPassword with an unknown algorithm succeeded")
+	}
+	if _, err := HashPassword("s3cret", HashOptions{BcryptCost: bcrypt.MaxCost + 1}); err == nil {
+		t.Error("HashPassword with an invalid bcrypt cost succeeded")
+	}
+}
+
+func TestVerifyPasswordNeedsRehash(t *testing.T) {
+	weakBcrypt, err := HashPassword("s3cret", HashOptions{BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2Hash, err := HashPassword("s3cret", fastArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strongerArgon2 := fastArgon2
+	strongerArgon2.Argon2Iterations = 2
+
+	tests := []struct {
+		name string
+		hash string
+		opts HashOptions
+		want bool
+	}{
+		{"bcrypt below the cost", weakBcrypt, HashOptions{BcryptCost: bcrypt.MinCost + 1}, true},
+		{"bcrypt above the cost", weakBcrypt, HashOptions{BcryptCost: bcrypt.MinCost - 1}, false},
+		{"bcrypt to argon2id", weakBcrypt, fastArgon2, true},
+		{"argon2id to bcrypt", argon2Hash, HashOptions{}, true},
+		{"argon2id with more iterations", argon2Hash, strongerArgon2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, needsRehash, err := VerifyPassword(tt.hash, "s3cret", tt.opts)
+			if err != nil || !match || needsRehash != tt.want {
+				t.Errorf("VerifyPassword = %v, %v, %v, want a match with needsRehash %v", match, needsRehash, err, tt.want)
+			}
+			if _, needsRehash, _ := VerifyPassword(tt.hash, "wrong", tt.opts); needsRehash {
+				t.Error("wrong password reports needsRehash")
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain-text",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	} {
+		if match, _, err := VerifyPassword(hash, "s3cret", HashOptions{}); match || !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("VerifyPassword(%q) = %v, %v, want ErrInvalidHash", hash, match, err)
+		}
+	}
+}
+
+func TestDeprecatedHashPass(t *testing.T) {
+	hash := HashPass("s3cret")
+	if cost, err := bcrypt.Cost(hash); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("HashPass cost = %d, %v, want %d", cost, err, bcrypt.DefaultCost)
+	}
+	if !ComparePass(hash, []byte("s3cret")) || ComparePass(hash, []byte("wrong")) {
+		t.Error("ComparePass does not match the HashPass hash")
+	}
+	if ComparePass(nil, []byte("")) || ComparePass([]byte(""), []byte("")) {
+		t.Error("ComparePass matches an empty hash")
+	}
+	argon2Hash, err := HashPassword("s3cret", fastArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ComparePass([]byte(argon2Hash), []byte("s3cret")) {
+		t.Error("ComparePass does not match an argon2id hash")
+	}
+}
@@ -0,0 +1,202 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register the GIF decoder for image.DecodeConfig
+	_ "image/jpeg" // Register the JPEG decoder for image.DecodeConfig
+	_ "image/png"  // Register the PNG decoder for image.DecodeConfig
+	"io"
+	"slices"
+)
+
+// Image formats detected by ValidateImage
+const (
+	ImageFormatPNG  = "png"
+	ImageFormatJPEG = "jpeg"
+	ImageFormatGIF  = "gif"
+	ImageFormatWebP = "webp"
+	ImageFormatSVG  = "svg"
+)
+
+// ErrInvalidImage is wrapped by every ValidateImage rejection
+var ErrInvalidImage = errors.New("invalid image")
+
+// sniffSize is the number of leading bytes used to detect the format
+const sniffSize = 512
+
+// ImagePolicy restricts the images accepted by ValidateImage, zero fields are not checked
+type ImagePolicy struct {
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+	// MaxMegapixels bounds width x height, guarding against decompression bombs
+	MaxMegapixels float64
+	// MinAspectRatio and MaxAspectRatio bound width / height, e.g. 1 and 1 for square avatars
+	MinAspectRatio float64
+	MaxAspectRatio float64
+	// AllowedFormats are the accepted ImageFormat* values, PNG, JPEG, GIF and WebP when empty
+	AllowedFormats []string
+	// AllowSVG accepts SVG images, which can carry scripts. Their dimensions are not checked
+	AllowSVG bool
+	// MaxBytes is the largest accepted file, checked by reading the whole image
+	MaxBytes int64
+}
+
+// ImageInfo describes a validated image, e.g. for storage metadata
+type ImageInfo struct {
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Bytes is the file size, only measured when the policy has a MaxBytes
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// ValidateImage detects the format of r from its magic bytes and checks it against policy, decoding
+// only the image header. The file extension and client Content-Type are not trusted. r is consumed
+func ValidateImage(r io.Reader, policy ImagePolicy) (ImageInfo, error) {
+	counter := &countingReader{r: r}
+	source := io.Reader(counter)
+	if policy.MaxBytes > 0 {
+		source = io.LimitReader(counter, policy.MaxBytes+1)
+	}
+	buffered := bufio.NewReaderSize(source, sniffSize)
+	head, err := buffered.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ImageInfo{}, err
+	}
+
+	info := ImageInfo{Format: detectImageFormat(head)}
+	if info.Format == "" {
+		return ImageInfo{}, fmt.Errorf("%w: unrecognized image format", ErrInvalidImage)
+	}
+	if !imageFormatAllowed(info.Format, policy) {
+		return ImageInfo{}, fmt.Errorf("%w: format %s is not allowed", ErrInvalidImage, info.Format)
+	}
+
+	err = nil
+	switch info.Format {
+	case ImageFormatSVG:
+	case ImageFormatWebP:
+		info.Width, info.Height, err = webpSize(head)
+	default:
+		var config image.Config
+		config, _, err = image.DecodeConfig(buffered)
+		info.Width, info.Height = config.Width, config.Height
+	}
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if info.Format != ImageFormatSVG {
+		if err := checkImageDimensions(info, policy); err != nil {
+			return ImageInfo{}, err
+		}
+	}
+
+	if policy.MaxBytes > 0 {
+		if _, err := io.Copy(io.Discard, buffered); err != nil {
+			return ImageInfo{}, err
+		}
+		if counter.n > policy.MaxBytes {
+			return ImageInfo{}, fmt.Errorf("%w: file exceeds %d bytes", ErrInvalidImage, policy.MaxBytes)
+		}
+		info.Bytes = counter.n
+	}
+	return info, nil
+}
+
+// detectImageFormat returns the format of the magic bytes of head, empty when unknown
+func detectImageFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return ImageFormatPNG
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return ImageFormatJPEG
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return ImageFormatGIF
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return ImageFormatWebP
+	}
+
+	text := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if bytes.HasPrefix(text, []byte("<?xml")) || bytes.HasPrefix(text, []byte("<svg")) || bytes.HasPrefix(text, []byte("<!DOCTYPE svg")) {
+		if bytes.Contains(text, []byte("<svg")) {
+			return ImageFormatSVG
+		}
+	}
+	return ""
+}
+
+func imageFormatAllowed(format string, policy ImagePolicy) bool {
+	if format == ImageFormatSVG {
+		return policy.AllowSVG
+	}
+	if len(policy.AllowedFormats) == 0 {
+		return true
+	}
+	return slices.Contains(policy.AllowedFormats, format)
+}
+
+func checkImageDimensions(info ImageInfo, policy ImagePolicy) error {
+	width, height := info.Width, info.Height
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%w: empty image", ErrInvalidImage)
+	}
+	switch {
+	case policy.MinWidth > 0 && width < policy.MinWidth, policy.MinHeight > 0 && height < policy.MinHeight:
+		return fmt.Errorf("%w: %dx%d is smaller than %dx%d", ErrInvalidImage, width, height, policy.MinWidth, policy.MinHeight)
+	case policy.MaxWidth > 0 && width > policy.MaxWidth, policy.MaxHeight > 0 && height > policy.MaxHeight:
+		return fmt.Errorf("%w: %dx%d is larger than %dx%d", ErrInvalidImage, width, height, policy.MaxWidth, policy.MaxHeight)
+	}
+	if megapixels := float64(width) * float64(height) / 1e6; policy.MaxMegapixels > 0 && megapixels > policy.MaxMegapixels {
+		return fmt.Errorf("%w: %g megapixels exceeds %g", ErrInvalidImage, megapixels, policy.MaxMegapixels)
+	}
+	ratio := float64(width) / float64(height)
+	if policy.MinAspectRatio > 0 && ratio < policy.MinAspectRatio || policy.MaxAspectRatio > 0 && ratio > policy.MaxAspectRatio {
+		return fmt.Errorf("%w: aspect ratio %.2f is not allowed", ErrInvalidImage, ratio)
+	}
+	return nil
+}
+
+// webpSize reads the canvas size from the first chunk of a WebP file
+func webpSize(head []byte) (int, int, error) {
+	if len(head) < 30 {
+		return 0, 0, errors.New("truncated webp header")
+	}
+	chunk := head[20:]
+	switch string(head[12:16]) {
+	case "VP8X":
+		width := int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16
+		height := int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16
+		return width + 1, height + 1, nil
+	case "VP8L":
+		if chunk[0] != 0x2F {
+			return 0, 0, errors.New("invalid webp lossless signature")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, nil
+	case "VP8 ":
+		if !bytes.Equal(chunk[3:6], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0, errors.New("invalid webp start code")
+		}
+		return int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3FFF), int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3FFF), nil
+	}
+	return 0, 0, errors.New("unknown webp chunk")
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"testing"
+)
+
+// fixture returns a file of testdata
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// pngHeader returns the signature and IHDR chunk of a width x height PNG, enough for image.DecodeConfig
+func pngHeader(width, height uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32([]byte("IHDR"), width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
+
+// webpHeader returns a WebP header with a first chunk of kind and payload
+func webpHeader(kind string, payload []byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP" + kind + "\x00\x00\x00\x00")
+	return append(data, append(payload, make([]byte, 16)...)...)
+}
+
+func TestValidateImageFixtures(t *testing.T) {
+	tests := []struct {
+		file          string
+		format        string
+		width, height int
+	}{
+		{"tiny.png", ImageFormatPNG, 3, 2},
+		{"tiny.jpg", ImageFormatJPEG, 4, 4},
+		{"tiny.gif", ImageFormatGIF, 2, 2},
+		{"tiny.webp", ImageFormatWebP, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := fixture(t, tt.file)
+			info, err := ValidateImage(bytes.NewReader(data), ImagePolicy{MaxBytes: 4096})
+			want := ImageInfo{Format: tt.format, Width: tt.width, Height: tt.height, Bytes: int64(len(data))}
+			if err != nil || info != want {
+				t.Errorf("ValidateImage = %+v, %v, want %+v", info, err, want)
+			}
+		})
+	}
+}
+
+func TestValidateImageRejectsDisguisedFiles(t *testing.T) {
+	for _, file := range []string{"fake.jpg", "icon.svg"} {
+		if _, err := ValidateImage(bytes.NewReader(fixture(t, file)), ImagePolicy{}); !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("ValidateImage(%s) = %v, want ErrInvalidImage", file, err)
+		}
+	}
+	if _, err := ValidateImage(bytes.NewReader(nil), ImagePolicy{}); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("ValidateImage(empty) = %v, want ErrInvalidImage", err)
+	}
+	truncated := fixture(t, "tiny.png")[:20]
+	if _, err := ValidateImage(bytes.NewReader(truncated), ImagePolicy{}); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("ValidateImage(truncated png) = %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestValidateImageSVG(t *testing.T) {
+	info, err := ValidateImage(bytes.NewReader(fixture(t, "icon.svg")), ImagePolicy{AllowSVG: true, MinWidth: 100})
+	if err != nil || info.Format != ImageFormatSVG {
+		t.Errorf("ValidateImage(svg) = %+v, %v, want an SVG without dimension checks", info, err)
+	}
+	bom := append([]byte("\xEF\xBB\xBF  \n"), fixture(t, "icon.svg")...)
+	if info, err := ValidateImage(bytes.NewReader(bom), ImagePolicy{AllowSVG: true}); err != nil || info.Format != ImageFormatSVG {
+		t.Errorf("ValidateImage(svg with BOM) = %+v, %v", info, err)
+	}
+	xml := []byte(`<?xml version="1.0"?><note>not an image</note>`)
+	if _, err := ValidateImage(bytes.NewReader(xml), ImagePolicy{AllowSVG: true}); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("ValidateImage(xml) = %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestValidateImagePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		policy ImagePolicy
+		ok     bool
+	}{
+		{name: "within bounds", data: pngHeader(800, 600), policy: ImagePolicy{MinWidth: 100, MinHeight: 100, MaxWidth: 1000, MaxHeight: 1000}, ok: true},
+		{name: "too narrow", data: pngHeader(99, 600), policy: ImagePolicy{MinWidth: 100}},
+		{name: "too short", data: pngHeader(800, 50), policy: ImagePolicy{MinHeight: 100}},
+		{name: "too wide", data: pngHeader(1001, 600), policy: ImagePolicy{MaxWidth: 1000}},
+		{name: "too tall", data: pngHeader(800, 1001), policy: ImagePolicy{MaxHeight: 1000}},
+		{name: "decompression bomb", data: pngHeader(50000, 50000), policy: ImagePolicy{MaxMegapixels: 40}},
+		{name: "megapixels limit", data: pngHeader(4000, 3000), policy: ImagePolicy{MaxMegapixels: 12}, ok: true},
+		{name: "square avatar", data: pngHeader(512, 512), policy: ImagePolicy{MinAspectRatio: 1, MaxAspectRatio: 1}, ok: true},
+		{name: "not square", data: pngHeader(512, 511), policy: ImagePolicy{MinAspectRatio: 1, MaxAspectRatio: 1}},
+		{name: "banner too tall", data: pngHeader(1200, 800), policy: ImagePolicy{MinAspectRatio: 3}},
+		{name: "allowed format", data: pngHeader(10, 10), policy: ImagePolicy{AllowedFormats: []string{ImageFormatPNG}}, ok: true},
+		{name: "format not allowed", data: pngHeader(10, 10), policy: ImagePolicy{AllowedFormats: []string{ImageFormatJPEG}}},
+		{name: "svg not in allowed formats", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), policy: ImagePolicy{AllowedFormats: []string{ImageFormatSVG}}},
+		{name: "too large", data: append(pngHeader(10, 10), make([]byte, 100)...), policy: ImagePolicy{MaxBytes: 100}},
+		{name: "zero size", data: pngHeader(0, 10), policy: ImagePolicy{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateImage(bytes.NewReader(tt.data), tt.policy)
+			if tt.ok && err != nil || !tt.ok && !errors.Is(err, ErrInvalidImage) {
+				t.Errorf("ValidateImage = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateImageWebPChunks(t *testing.T) {
+	vp8x := webpHeader("VP8X", []byte{0, 0, 0, 0, 0x7F, 0x02, 0x00, 0xDF, 0x01, 0x00})
+	lossy := webpHeader("VP8 ", []byte{0, 0, 0, 0x9D, 0x01, 0x2A, 0x40, 0x01, 0xF0, 0x00})
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+		ok            bool
+	}{
+		{name: "extended", data: vp8x, width: 640, height: 480, ok: true},
+		{name: "lossy", data: lossy, width: 320, height: 240, ok: true},
+		{name: "bad start code", data: webpHeader("VP8 ", make([]byte, 10))},
+		{name: "bad lossless signature", data: webpHeader("VP8L", make([]byte, 10))},
+		{name: "unknown chunk", data: webpHeader("ALPH", make([]byte, 10))},
+		{name: "truncated", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8X")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ValidateImage(bytes.NewReader(tt.data), ImagePolicy{})
+			if tt.ok != (err == nil) || tt.ok && (info.Width != tt.width || info.Height != tt.height) {
+				t.Errorf("ValidateImage = %+v, %v, want %dx%d (ok %v)", info, err, tt.width, tt.height, tt.ok)
+			}
+		})
+	}
+}
@@ -3,39 +3,121 @@ package helpers
 import (
 	"errors"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
 )
 
+// helperTokenTTL is the lifetime of tokens issued by GenerateToken
+const helperTokenTTL = 24 * time.Hour
+
+// helperTokenIssuer is the issuer of tokens issued by GenerateToken
+const helperTokenIssuer = "msa-core"
+
+// errSignIn is returned by VerifyToken for missing or invalid tokens
+var errSignIn = errors.New("sign in to proceed")
+
+var (
+	jwtServiceMu sync.Mutex
+	jwtService   services.JWTService
+	// jwtServiceConfigured is set once jwtService was set or created from API_SECRET
+	jwtServiceConfigured bool
+)
+
+// SetJWTService sets the JWTService used by the JWT helpers, by default a stateless HS256 service
+// created once from the API_SECRET environment variable
+func SetJWTService(service services.JWTService) {
+	jwtServiceMu.Lock()
+	defer jwtServiceMu.Unlock()
+	jwtService = service
+	jwtServiceConfigured = true
+}
+
+// helperJWTService returns the JWTService of the helpers, creating the default one on first use
+func helperJWTService() (services.JWTService, error) {
+	jwtServiceMu.Lock()
+	defer jwtServiceMu.Unlock()
+	if !jwtServiceConfigured {
+		if secret := os.Getenv("API_SECRET"); secret != "" {
+			jwtService = services.NewJWTService(secret, nil)
+		}
+		jwtServiceConfigured = true
+	}
+	if jwtService == nil {
+		return nil, errors.New("jwt helper: API_SECRET is not set")
+	}
+	return jwtService, nil
+}
+
+// GenerateToken issues a token for the user id and email, or an empty string on failure
+//
+// Deprecated: use GenerateTokenE, which reports the error, or services.JWTService
 func GenerateToken(id uint, email string) string {
-	claims := jwt.MapClaims{}
-	claims["id"] = id
-	claims["email"] = email
+	token, _ := GenerateTokenE(id, email)
+	return token
+}
 
-	parseToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, _ := parseToken.SignedString([]byte(os.Getenv("API_SECRET")))
-	return signedToken
+// GenerateTokenE issues a token for the user id and email, valid for 24 hours
+func GenerateTokenE(id uint, email string) (string, error) {
+	service, err := helperJWTService()
+	if err != nil {
+		return "", err
+	}
+	return service.IssueToken(services.TokenRequest{
+		User:      models.OAuthUser{ID: strconv.FormatUint(uint64(id), 10), Email: email},
+		Issuer:    helperTokenIssuer,
+		ExpiresIn: helperTokenTTL,
+	})
 }
 
+// VerifyToken validates the bearer token of the request and returns its "id", "email", "sub", "exp" and
+// "iat" claims. The id is a float64, as it was when decoded from the token
+//
+// Deprecated: use VerifyTokenE, which returns the typed claims
 func VerifyToken(c echo.Context) (interface{}, error) {
-	errResponse := errors.New("sign in to proceed")
-	headerToken := c.Request().Header.Get("Authorization")
-	bearer := strings.HasPrefix(headerToken, "Bearer")
-	if !bearer {
-		return nil, errResponse
+	claims, err := VerifyTokenE(c)
+	if err != nil {
+		return nil, err
 	}
 
-	stringToken := strings.Split(headerToken, " ")[1]
-	token, _ := jwt.Parse(stringToken, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errResponse
-		}
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
-	if _, ok := token.Claims.(jwt.MapClaims); !ok && !token.Valid {
-		return nil, errResponse
+	mapClaims := jwt.MapClaims{
+		"email": claims.User.Email,
+		"sub":   claims.Subject,
+	}
+	if id, err := strconv.ParseUint(claims.User.ID, 10, 64); err == nil {
+		mapClaims["id"] = float64(id)
+	} else {
+		mapClaims["id"] = claims.User.ID
+	}
+	if claims.ExpiresAt != nil {
+		mapClaims["exp"] = float64(claims.ExpiresAt.Unix())
+	}
+	if claims.IssuedAt != nil {
+		mapClaims["iat"] = float64(claims.IssuedAt.Unix())
+	}
+	return mapClaims, nil
+}
+
+// VerifyTokenE validates the bearer token of the request with the helpers JWTService
+func VerifyTokenE(c echo.Context) (*models.JWTClaims, error) {
+	token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return nil, errSignIn
+	}
+
+	service, err := helperJWTService()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := service.ValidateToken(strings.TrimSpace(token))
+	if err != nil {
+		return nil, errors.Join(errSignIn, err)
 	}
-	return token.Claims.(jwt.MapClaims), nil
+	return claims, nil
 }
@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// resetJWTService makes the helpers create their JWTService from API_SECRET again, now and after the test
+func resetJWTService(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		jwtServiceMu.Lock()
+		defer jwtServiceMu.Unlock()
+		jwtService, jwtServiceConfigured = nil, false
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// bearerContext returns an echo context of a request with the authorization header
+func bearerContext(authorization string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestGenerateTokenE(t *testing.T) {
+	resetJWTService(t)
+	t.Setenv("API_SECRET", "test-secret")
+
+	token, err := GenerateTokenE(42, "u42@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := VerifyTokenE(bearerContext("Bearer " + token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.User.ID != "42" || claims.User.Email != "u42@example.com" {
+		t.Errorf("user = %+v, want 42 u42@example.com", claims.User)
+	}
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		t.Fatal("token without exp or iat")
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != helperTokenTTL {
+		t.Errorf("token lifetime = %s, want %s", ttl, helperTokenTTL)
+	}
+
+	legacy, err := VerifyToken(bearerContext("Bearer " + token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapClaims := legacy.(jwt.MapClaims)
+	if mapClaims["id"] != float64(42) || mapClaims["email"] != "u42@example.com" || mapClaims["exp"] == nil {
+		t.Errorf("VerifyToken claims = %v, want the legacy id, email and exp", mapClaims)
+	}
+}
+
+func TestGenerateTokenWithoutSecret(t *testing.T) {
+	resetJWTService(t)
+	t.Setenv("API_SECRET", "")
+
+	if _, err := GenerateTokenE(1, "u1@example.com"); err == nil {
+		t.Error("GenerateTokenE without API_SECRET succeeded")
+	}
+	if token := GenerateToken(1, "u1@example.com"); token != "" {
+		t.Errorf("GenerateToken without API_SECRET = %q, want an empty token", token)
+	}
+}
+
+func TestVerifyTokenInvalid(t *testing.T) {
+	resetJWTService(t)
+	t.Setenv("API_SECRET", "test-secret")
+	expired, err := services.NewJWTService("test-secret", nil).GenerateToken(
+		models.OAuthUser{ID: "1"}, nil, helperTokenIssuer, "", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := services.NewJWTService("other-secret", nil).GenerateToken(
+		models.OAuthUser{ID: "1"}, nil, helperTokenIssuer, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// These used to dereference a nil token and panic
+	for name, authorization := range map[string]string{
+		"missing":      "",
+		"not bearer":   "Basic abc",
+		"empty bearer": "Bearer  ",
+		"garbage":      "Bearer not-a-jwt",
+		"expired":      "Bearer " + expired,
+		"other secret": "Bearer " + other,
+	} {
+		t.Run(name, func(t *testing.T) {
+			claims, err := VerifyToken(bearerContext(authorization))
+			if !errors.Is(err, errSignIn) || claims != nil {
+				t.Errorf("VerifyToken = %v, %v, want errSignIn", claims, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// List query defaults, matching the BaseController pagination handlers
+const (
+	DefaultListPageSize    = common.DefaultListPageSize
+	DefaultListMaxPageSize = common.DefaultListMaxPageSize
+)
+
+// ListQueryConfig configures ParseListQuery, see common.ListQueryConfig
+type ListQueryConfig = common.ListQueryConfig
+
+// ListQuery is the validated page and sort of a list request, see common.ListQuery
+type ListQuery = common.ListQuery
+
+// ParseListQuery parses the pagination and sort parameters of a list request with common.ParseListQuery,
+// the parsing of the BaseController pagination handlers
+func ParseListQuery(c echo.Context, cfg ListQueryConfig) (ListQuery, *common.ErrorResponse) {
+	return common.ParseListQuery(c, cfg)
+}
@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+func TestParseListQuery(t *testing.T) {
+	cfg := ListQueryConfig{SortableColumns: map[string]string{"createdAt": "created_at"}, DefaultSort: "createdAt"}
+	tests := []struct {
+		query       string
+		wantOffset  int
+		wantOrderBy string
+		wantErr     bool
+	}{
+		{query: "", wantOffset: 0, wantOrderBy: "created_at DESC"},
+		{query: "page=3&size=20&sort_order=asc", wantOffset: 40, wantOrderBy: "created_at ASC"},
+		{query: "sort_by=password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil), httptest.NewRecorder())
+			query, errResp := ParseListQuery(c, cfg)
+			if tt.wantErr {
+				if errResp == nil || errResp.Code != common.VALIDATION_ERROR {
+					t.Errorf("ParseListQuery() error = %+v, want a VALIDATION_ERROR", errResp)
+				}
+				return
+			}
+			if errResp != nil || query.Offset() != tt.wantOffset || query.OrderBy() != tt.wantOrderBy {
+				t.Errorf("ParseListQuery() = offset %d, order %q, %+v, want %d, %q",
+					query.Offset(), query.OrderBy(), errResp, tt.wantOffset, tt.wantOrderBy)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MapStruct errors
+var (
+	ErrUnmappedField    = errors.New("destination field has no source field")
+	ErrIncompatibleType = errors.New("source type cannot be mapped to destination type")
+)
+
+// MapOptions configures MapStruct and MapSlice
+type MapOptions struct {
+	// Strict fails with ErrUnmappedField when a destination field has no source field, so tests catch DTOs
+	// drifting from their entities
+	Strict bool
+}
+
+// mapKey identifies a cached mapping plan
+type mapKey struct {
+	dst, src reflect.Type
+	strict   bool
+}
+
+// mapPlan copies a source value into a settable destination value. Plans are built once per type pair
+type mapPlan struct {
+	copy func(dst, src reflect.Value)
+}
+
+// mapPlans caches the plans by mapKey
+var mapPlans sync.Map
+
+// MapStruct copies src into a new D, e.g. a gorm entity into its response DTO. Fields are matched by name
+// or by their map:"name" tag (map:"-" skips a field), including the fields promoted from embedded
+// structs. Pointers and values are converted both ways (nil pointers give zero values), nested structs,
+// slices and maps are mapped recursively, values of identical types such as time.Time are copied as is and
+// named basic types convert to their underlying kind. Unmatched destination fields stay zero unless
+// opts.Strict is set; matched fields of incompatible types fail with ErrIncompatibleType. The reflection
+// plan is cached per type pair, opts at most one
+func MapStruct[S, D any](src S, opts ...MapOptions) (D, error) {
+	var dst D
+	plan, err := mapPlanFor(reflect.TypeFor[D](), reflect.TypeFor[S](), mapStrict(opts))
+	if err != nil {
+		return dst, err
+	}
+	plan.copy(reflect.ValueOf(&dst).Elem(), reflect.ValueOf(&src).Elem())
+	return dst, nil
+}
+
+// MapSlice maps each element of src with MapStruct, nil for a nil src
+func MapSlice[S, D any](src []S, opts ...MapOptions) ([]D, error) {
+	if src == nil {
+		return nil, nil
+	}
+	plan, err := mapPlanFor(reflect.TypeFor[D](), reflect.TypeFor[S](), mapStrict(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	dst := make([]D, len(src))
+	for i := range src {
+		plan.copy(reflect.ValueOf(&dst[i]).Elem(), reflect.ValueOf(&src[i]).Elem())
+	}
+	return dst, nil
+}
+
+func mapStrict(opts []MapOptions) bool {
+	return len(opts) > 0 && opts[0].Strict
+}
+
+// mapPlanFor returns the cached plan of a type pair, building it and its nested plans on first use
+func mapPlanFor(dst, src reflect.Type, strict bool) (*mapPlan, error) {
+	key := mapKey{dst: dst, src: src, strict: strict}
+	if plan, ok := mapPlans.Load(key); ok {
+		return plan.(*mapPlan), nil
+	}
+
+	building := make(map[mapKey]*mapPlan)
+	plan, err := buildMapPlan(key, building)
+	if err != nil {
+		return nil, err
+	}
+	// Nested plans are only published once the whole tree built, failed builds are not cached
+	for k, p := range building {
+		mapPlans.LoadOrStore(k, p)
+	}
+	return plan, nil
+}
+
+// buildMapPlan builds the plan of key. Plans being built are registered first so recursive types refer to
+// them before they are complete
+func buildMapPlan(key mapKey, building map[mapKey]*mapPlan) (*mapPlan, error) {
+	if plan, ok := mapPlans.Load(key); ok {
+		return plan.(*mapPlan), nil
+	}
+	if plan, ok := building[key]; ok {
+		return plan, nil
+	}
+
+	plan := &mapPlan{}
+	building[key] = plan
+	copyFunc, err := buildMapCopy(key, building)
+	if err != nil {
+		return nil, err
+	}
+	plan.copy = copyFunc
+	return plan, nil
+}
+
+func buildMapCopy(key mapKey, building map[mapKey]*mapPlan) (func(dst, src reflect.Value), error) {
+	dstType, srcType := key.dst, key.src
+	nested := func(dst, src reflect.Type) (*mapPlan, error) {
+		return buildMapPlan(mapKey{dst: dst, src: src, strict: key.strict}, building)
+	}
+
+	switch {
+	case srcType == dstType || srcType.AssignableTo(dstType) && dstType.Kind() == reflect.Interface:
+		return func(dst, src reflect.Value) {
+			dst.Set(src)
+		}, nil
+
+	case dstType.Kind() == reflect.Pointer:
+		srcIsPointer := srcType.Kind() == reflect.Pointer
+		elemSrc := srcType
+		if srcIsPointer {
+			elemSrc = srcType.Elem()
+		}
+		elem, err := nested(dstType.Elem(), elemSrc)
+		if err != nil {
+			return nil, err
+		}
+		return func(dst, src reflect.Value) {
+			if srcIsPointer {
+				if src.IsNil() {
+					dst.SetZero()
+					return
+				}
+				src = src.Elem()
+			}
+			value := reflect.New(dstType.Elem())
+			elem.copy(value.Elem(), src)
+			dst.Set(value)
+		}, nil
+
+	case srcType.Kind() == reflect.Pointer:
+		elem, err := nested(dstType, srcType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(dst, src reflect.Value) {
+			if src.IsNil() {
+				dst.SetZero()
+				return
+			}
+			elem.copy(dst, src.Elem())
+		}, nil
+
+	case srcType.Kind() == reflect.Struct && dstType.Kind() == reflect.Struct:
+		return buildStructCopy(key, nested)
+
+	case srcType.Kind() == reflect.Slice && dstType.Kind() == reflect.Slice:
+		elem, err := nested(dstType.Elem(), srcType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(dst, src reflect.Value) {
+			if src.IsNil() {
+				dst.SetZero()
+				return
+			}
+			slice := reflect.MakeSlice(dstType, src.Len(), src.Len())
+			for i := range src.Len() {
+				elem.copy(slice.Index(i), src.Index(i))
+			}
+			dst.Set(slice)
+		}, nil
+
+	case srcType.Kind() == reflect.Map && dstType.Kind() == reflect.Map:
+		mapKeyPlan, err := nested(dstType.Key(), srcType.Key())
+		if err != nil {
+			return nil, err
+		}
+		mapValuePlan, err := nested(dstType.Elem(), srcType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(dst, src reflect.Value) {
+			if src.IsNil() {
+				dst.SetZero()
+				return
+			}
+			m := reflect.MakeMapWithSize(dstType, src.Len())
+			k := reflect.New(dstType.Key()).Elem()
+			v := reflect.New(dstType.Elem()).Elem()
+			for iter := src.MapRange(); iter.Next(); {
+				// Reset so fields left unset by a plan do not carry over from the previous entry
+				k.SetZero()
+				v.SetZero()
+				mapKeyPlan.copy(k, iter.Key())
+				mapValuePlan.copy(v, iter.Value())
+				m.SetMapIndex(k, v)
+			}
+			dst.Set(m)
+		}, nil
+
+	case srcType.Kind() == dstType.Kind() && isBasicKind(srcType.Kind()):
+		return func(dst, src reflect.Value) {
+			dst.Set(src.Convert(dstType))
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s to %s", ErrIncompatibleType, srcType, dstType)
+}
+
+// structFieldMapping copies the source field at src into the destination field at dst
+type structFieldMapping struct {
+	dst, src []int
+	plan     *mapPlan
+}
+
+func buildStructCopy(key mapKey, nested func(dst, src reflect.Type) (*mapPlan, error)) (func(dst, src reflect.Value), error) {
+	srcFields := make(map[string]reflect.StructField)
+	for _, field := range mappableFields(key.src) {
+		srcFields[mappedFieldName(field)] = field
+	}
+
+	var mappings []structFieldMapping
+	for _, field := range mappableFields(key.dst) {
+		name := mappedFieldName(field)
+		srcField, ok := srcFields[name]
+		if !ok {
+			if key.strict {
+				return nil, fmt.Errorf("%w: %s.%s", ErrUnmappedField, key.dst, field.Name)
+			}
+			continue
+		}
+		plan, err := nested(field.Type, srcField.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", key.dst, field.Name, err)
+		}
+		mappings = append(mappings, structFieldMapping{dst: field.Index, src: srcField.Index, plan: plan})
+	}
+
+	return func(dst, src reflect.Value) {
+		for _, mapping := range mappings {
+			srcValue, ok := sourceField(src, mapping.src)
+			if !ok {
+				continue
+			}
+			mapping.plan.copy(destinationField(dst, mapping.dst), srcValue)
+		}
+	}, nil
+}
+
+// mappableFields returns the exported fields of t, promoted fields included and embedded structs
+// excluded, skipping map:"-" fields and fields promoted through unexported embedded structs
+func mappableFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Tag.Get("map") == "-" || !exportedPath(t, field.Index) {
+			continue
+		}
+		if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// mappedFieldName returns the map tag of field, or its name
+func mappedFieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("map"), ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// exportedPath reports whether the embedded structs leading to the field at index are exported, reflect
+// cannot set fields promoted through unexported ones
+func exportedPath(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		field := t.Field(i)
+		if !field.IsExported() {
+			return false
+		}
+		t = indirectType(field.Type)
+	}
+	return true
+}
+
+// sourceField returns the field at index of v, false when an embedded pointer on the way is nil
+func sourceField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fieldIndex := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v, true
+}
+
+// destinationField returns the field at index of v, allocating nil embedded pointers on the way
+func destinationField(v reflect.Value, index []int) reflect.Value {
+	for i, fieldIndex := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// isBasicKind reports whether values of kind convert to named types of the same kind
+func isBasicKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,388 @@
+package helpers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type MapAudit struct {
+	CreatedAt time.Time
+	CreatedBy string
+}
+
+type MapOwner struct {
+	Name string
+}
+
+type orderStatus string
+
+type mapEntity struct {
+	MapAudit
+	*MapOwner
+	hidden    string
+	ID        int64
+	Code      string `map:"order_code"`
+	Status    orderStatus
+	Total     *float64
+	Note      string
+	Secret    string `map:"-"`
+	Lines     []mapEntityLine
+	Tags      map[string]*mapEntityLine
+	Delivered *time.Time
+}
+
+type mapEntityLine struct {
+	SKU      string
+	Quantity int
+	Extra    string
+}
+
+type mapDTO struct {
+	ID        int64
+	OrderCode string `map:"order_code"`
+	Status    string
+	Total     float64
+	Note      *string
+	Secret    string
+	CreatedAt time.Time
+	CreatedBy string
+	Name      string
+	Lines     []*mapDTOLine
+	Tags      map[string]mapDTOLine
+	Delivered *time.Time
+}
+
+type mapDTOLine struct {
+	SKU      string
+	Quantity int
+}
+
+func TestMapStruct(t *testing.T) {
+	created := time.Date(2026, 10, 14, 9, 30, 0, 0, time.FixedZone("ICT", 7*3600))
+	total := 12.5
+	src := mapEntity{
+		MapAudit: MapAudit{CreatedAt: created, CreatedBy: "admin"},
+		MapOwner: &MapOwner{Name: "Lan"},
+		hidden:   "internal",
+		ID:       42,
+		Code:     "ORD-42",
+		Status:   "paid",
+		Total:    &total,
+		Note:     "leave at door",
+		Secret:   "token",
+		Lines:    []mapEntityLine{{SKU: "A", Quantity: 2, Extra: "x"}, {SKU: "B", Quantity: 1}},
+		Tags:     map[string]*mapEntityLine{"gift": {SKU: "G", Quantity: 1}, "none": nil},
+	}
+
+	got, err := MapStruct[mapEntity, mapDTO](src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mapDTO{
+		ID:        42,
+		OrderCode: "ORD-42",
+		Status:    "paid",
+		Total:     12.5,
+		Note:      &src.Note,
+		CreatedAt: created,
+		CreatedBy: "admin",
+		Name:      "Lan",
+		Lines:     []*mapDTOLine{{SKU: "A", Quantity: 2}, {SKU: "B", Quantity: 1}},
+		Tags:      map[string]mapDTOLine{"gift": {SKU: "G", Quantity: 1}, "none": {}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapStruct() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got.Note == &src.Note {
+		t.Error("Note points into the source")
+	}
+	if got.CreatedAt.Location() != created.Location() {
+		t.Errorf("CreatedAt location = %v, want it copied as is", got.CreatedAt.Location())
+	}
+}
+
+func TestMapStructNilPointers(t *testing.T) {
+	got, err := MapStruct[mapEntity, mapDTO](mapEntity{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "" || got.Total != 0 || got.Lines != nil || got.Tags != nil || got.Delivered != nil {
+		t.Errorf("nil source pointers mapped to %+v, want zero values", got)
+	}
+	// The value field is converted to a pointer even when empty
+	if got.Note == nil || *got.Note != "" {
+		t.Errorf("Note = %v, want a pointer to an empty string", got.Note)
+	}
+
+	ptr, err := MapStruct[*mapEntity, *mapDTO](nil)
+	if err != nil || ptr != nil {
+		t.Errorf("MapStruct(nil pointer) = %v, %v, want nil", ptr, err)
+	}
+	ptr, err = MapStruct[*mapEntity, *mapDTO](&mapEntity{ID: 7})
+	if err != nil || ptr == nil || ptr.ID != 7 {
+		t.Errorf("MapStruct(pointer) = %+v, %v, want ID 7", ptr, err)
+	}
+}
+
+func TestMapStructEmbeddedDestination(t *testing.T) {
+	type flat struct {
+		CreatedBy string
+		Name      string
+		ID        int64
+	}
+	type embedded struct {
+		MapAudit
+		*MapOwner
+		ID int64
+	}
+
+	got, err := MapStruct[flat, embedded](flat{CreatedBy: "admin", Name: "Lan", ID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CreatedBy != "admin" || got.MapOwner == nil || got.Name != "Lan" || got.ID != 3 {
+		t.Errorf("MapStruct() = %+v, want the promoted fields set and the embedded pointer allocated", got)
+	}
+
+	back, err := MapStruct[embedded, flat](embedded{ID: 1})
+	if err != nil || back != (flat{ID: 1}) {
+		t.Errorf("nil embedded source = %+v, %v, want its fields skipped", back, err)
+	}
+}
+
+func TestMapStructUnexportedEmbedded(t *testing.T) {
+	type audit struct {
+		CreatedBy string
+	}
+	type withUnexported struct {
+		audit
+		hidden string
+		ID     int64
+	}
+	type flat struct {
+		CreatedBy string
+		ID        int64
+	}
+
+	// reflect can neither read nor set fields promoted through unexported embedded structs
+	got, err := MapStruct[withUnexported, flat](withUnexported{audit: audit{CreatedBy: "admin"}, hidden: "x", ID: 1})
+	if err != nil || got != (flat{ID: 1}) {
+		t.Errorf("MapStruct() = %+v, %v, want only ID", got, err)
+	}
+	back, err := MapStruct[flat, withUnexported](flat{CreatedBy: "admin", ID: 1})
+	if err != nil || back != (withUnexported{ID: 1}) {
+		t.Errorf("MapStruct() = %+v, %v, want only ID", back, err)
+	}
+	if _, err := MapStruct[flat, withUnexported](flat{}, MapOptions{Strict: true}); err != nil {
+		t.Errorf("strict = %v, want unexported fields ignored", err)
+	}
+}
+
+func TestMapStructRecursive(t *testing.T) {
+	type category struct {
+		Name     string
+		Children []*category
+	}
+	type categoryDTO struct {
+		Name     string
+		Children []categoryDTO
+	}
+
+	src := category{Name: "root", Children: []*category{{Name: "a", Children: []*category{{Name: "a1"}}}, nil}}
+	got, err := MapStruct[category, categoryDTO](src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := categoryDTO{Name: "root", Children: []categoryDTO{{Name: "a", Children: []categoryDTO{{Name: "a1"}}}, {}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapStruct() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMapStructErrors(t *testing.T) {
+	type source struct {
+		ID   int64
+		Name string
+	}
+	type extra struct {
+		ID      int64
+		Name    string
+		Missing string
+	}
+	type mismatch struct {
+		ID string
+	}
+	type nestedMismatch struct {
+		Lines []mismatch
+	}
+	type nestedSource struct {
+		Lines []source
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"unmapped field ignored", func() error { _, err := MapStruct[source, extra](source{}); return err }, nil},
+		{"strict unmapped field", func() error {
+			_, err := MapStruct[source, extra](source{}, MapOptions{Strict: true})
+			return err
+		}, ErrUnmappedField},
+		{"strict complete", func() error {
+			_, err := MapStruct[extra, source](extra{}, MapOptions{Strict: true})
+			return err
+		}, nil},
+		{"incompatible field", func() error { _, err := MapStruct[source, mismatch](source{}); return err }, ErrIncompatibleType},
+		{"incompatible nested field", func() error {
+			_, err := MapStruct[nestedSource, nestedMismatch](nestedSource{Lines: []source{{}}})
+			return err
+		}, ErrIncompatibleType},
+		{"incompatible slice", func() error { _, err := MapSlice[source, mismatch]([]source{{}}); return err }, ErrIncompatibleType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Strict and lenient plans are cached apart, failures are not cached
+	if _, err := MapStruct[source, extra](source{}); err != nil {
+		t.Errorf("lenient after strict = %v", err)
+	}
+	if _, err := MapStruct[source, extra](source{}, MapOptions{Strict: true}); !errors.Is(err, ErrUnmappedField) {
+		t.Errorf("strict again = %v, want ErrUnmappedField", err)
+	}
+}
+
+func TestMapSlice(t *testing.T) {
+	got, err := MapSlice[mapEntityLine, mapDTOLine]([]mapEntityLine{{SKU: "A", Quantity: 1}, {SKU: "B", Quantity: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []mapDTOLine{{"A", 1}, {"B", 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MapSlice() = %+v, want %+v", got, want)
+	}
+
+	if got, err := MapSlice[mapEntityLine, mapDTOLine](nil); got != nil || err != nil {
+		t.Errorf("MapSlice(nil) = %#v, %v, want nil", got, err)
+	}
+	if got, err := MapSlice[mapEntityLine, mapDTOLine]([]mapEntityLine{}); got == nil || len(got) != 0 || err != nil {
+		t.Errorf("MapSlice(empty) = %#v, %v, want an empty slice", got, err)
+	}
+}
+
+// benchmarkEntity is a typical gorm entity mapped to benchmarkDTO
+type benchmarkEntity struct {
+	MapAudit
+	ID       int64
+	Code     string `map:"order_code"`
+	Status   orderStatus
+	Total    *float64
+	Customer string
+	Email    string
+	Phone    string
+	Address  string
+	Lines    []mapEntityLine
+}
+
+type benchmarkDTO struct {
+	ID        int64
+	OrderCode string `map:"order_code"`
+	Status    string
+	Total     float64
+	Customer  string
+	Email     string
+	Phone     string
+	Address   string
+	CreatedAt time.Time
+	CreatedBy string
+	Lines     []mapDTOLine
+}
+
+func newBenchmarkEntity() benchmarkEntity {
+	total := 99.5
+	return benchmarkEntity{
+		MapAudit: MapAudit{CreatedAt: time.Now(), CreatedBy: "admin"},
+		ID:       1, Code: "ORD-1", Status: "paid", Total: &total,
+		Customer: "Lan", Email: "lan@example.com", Phone: "0900000000", Address: "Hanoi",
+		Lines: []mapEntityLine{{SKU: "A", Quantity: 1}, {SKU: "B", Quantity: 2}, {SKU: "C", Quantity: 3}},
+	}
+}
+
+// mapBenchmarkByHand is the copy function MapStruct replaces
+func mapBenchmarkByHand(src benchmarkEntity) benchmarkDTO {
+	dst := benchmarkDTO{
+		ID: src.ID, OrderCode: src.Code, Status: string(src.Status),
+		Customer: src.Customer, Email: src.Email, Phone: src.Phone, Address: src.Address,
+		CreatedAt: src.CreatedAt, CreatedBy: src.CreatedBy,
+	}
+	if src.Total != nil {
+		dst.Total = *src.Total
+	}
+	if src.Lines != nil {
+		dst.Lines = make([]mapDTOLine, len(src.Lines))
+		for i, line := range src.Lines {
+			dst.Lines[i] = mapDTOLine{SKU: line.SKU, Quantity: line.Quantity}
+		}
+	}
+	return dst
+}
+
+func TestMapBenchmarkTypes(t *testing.T) {
+	src := newBenchmarkEntity()
+	got, err := MapStruct[benchmarkEntity, benchmarkDTO](src, MapOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mapBenchmarkByHand(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("MapStruct() = %+v, want %+v", got, want)
+	}
+}
+
+// BenchmarkMapStruct compares the cached plan with the hand-written copy and with building the plan on
+// every call, which the cache avoids
+func BenchmarkMapStruct(b *testing.B) {
+	src := newBenchmarkEntity()
+
+	b.Run("by hand", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			_ = mapBenchmarkByHand(src)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if _, err := MapStruct[benchmarkEntity, benchmarkDTO](src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			mapPlans.Clear()
+			if _, err := MapStruct[benchmarkEntity, benchmarkDTO](src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMapSlice(b *testing.B) {
+	src := make([]benchmarkEntity, 100)
+	for i := range src {
+		src[i] = newBenchmarkEntity()
+	}
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := MapSlice[benchmarkEntity, benchmarkDTO](src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
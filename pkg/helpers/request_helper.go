@@ -2,11 +2,32 @@ package helpers
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
 )
 
-func GetTraceId(c echo.Context) string {
+// GetTraceID returns the trace id of the request with common.TraceIDFromRequest, the one logs and responses
+// carry: the active OpenTelemetry span, then the X-Trace-Id and X-Request-Id headers, then the request id
+// set by the RequestID middleware
+func GetTraceID(c echo.Context) string {
 	if c == nil || c.Request() == nil {
 		return ""
 	}
-	return c.Request().Header.Get("X-Trace-Id")
+	return common.TraceIDFromRequest(c.Request())
+}
+
+// GetTraceId returns the trace id of the request
+//
+// Deprecated: use GetTraceID, which prefers the active span over the X-Trace-Id header
+func GetTraceId(c echo.Context) string {
+	return GetTraceID(c)
+}
+
+// SetTraceIDHeader echoes the trace id of the request in the X-Trace-Id response header and returns it,
+// nothing is set when the request has none
+func SetTraceIDHeader(c echo.Context) string {
+	traceID := GetTraceID(c)
+	if traceID != "" {
+		c.Response().Header().Set(common.HeaderTraceID, traceID)
+	}
+	return traceID
 }
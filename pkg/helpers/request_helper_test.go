@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestGetTraceID(t *testing.T) {
+	const spanTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceID, _ := trace.TraceIDFromHex(spanTraceID)
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+
+	tests := []struct {
+		name      string
+		span      bool
+		headers   map[string]string
+		requestID string
+		want      string
+	}{
+		{name: "span wins over headers", span: true, headers: map[string]string{common.HeaderTraceID: "header-trace", common.HeaderRequestID: "header-req"}, requestID: "ctx-req", want: spanTraceID},
+		{name: "trace header without span", headers: map[string]string{common.HeaderTraceID: "header-trace", common.HeaderRequestID: "header-req"}, want: "header-trace"},
+		{name: "request id header", headers: map[string]string{common.HeaderRequestID: "header-req"}, requestID: "ctx-req", want: "header-req"},
+		{name: "malformed headers skipped", headers: map[string]string{common.HeaderTraceID: "bad id"}, requestID: "ctx-req", want: "ctx-req"},
+		{name: "request id middleware", requestID: "ctx-req", want: "ctx-req"},
+		{name: "nothing", want: ""},
+	}
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			ctx := req.Context()
+			if tt.span {
+				ctx = trace.ContextWithSpanContext(ctx, spanContext)
+			}
+			if tt.requestID != "" {
+				ctx = common.WithRequestID(ctx, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req.WithContext(ctx), rec)
+
+			if got := GetTraceID(c); got != tt.want {
+				t.Errorf("GetTraceID() = %q, want %q", got, tt.want)
+			}
+			if got := GetTraceId(c); got != tt.want {
+				t.Errorf("GetTraceId() = %q, want %q", got, tt.want)
+			}
+			if got := SetTraceIDHeader(c); got != tt.want {
+				t.Errorf("SetTraceIDHeader() = %q, want %q", got, tt.want)
+			}
+			if values := rec.Header().Values(common.HeaderTraceID); tt.want == "" && len(values) != 0 ||
+				tt.want != "" && (len(values) != 1 || values[0] != tt.want) {
+				t.Errorf("%s response header = %q, want %q", common.HeaderTraceID, values, tt.want)
+			}
+		})
+	}
+
+	if got := GetTraceID(nil); got != "" {
+		t.Errorf("GetTraceID(nil) = %q, want empty", got)
+	}
+}
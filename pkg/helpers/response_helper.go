@@ -40,6 +40,7 @@ func (h *ResponseHelper) Error(c echo.Context, statusCode int, errorResp common.
 			errorResp.ProcessingTime = processingTime
 		}
 	}
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
 	return c.JSON(statusCode, errorResp)
 }
 
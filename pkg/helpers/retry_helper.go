@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures Retry, empty fields get defaults
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 3 by default
+	MaxAttempts int
+	// InitialInterval is the delay before the first retry, 100ms by default
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between attempts, 5 seconds by default
+	MaxInterval time.Duration
+	// Multiplier grows the delay after each retry, 2 by default
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it, between 0 and 1. 0 disables it, 0.2 spreads
+	// retries of concurrent callers
+	Jitter float64
+	// RetryIf reports whether an error is worth retrying, by default any error except context errors
+	RetryIf func(err error) bool
+	// AllErrors returns a *MultiError of every attempt instead of the last error
+	AllErrors bool
+	// Sleep waits between attempts and returns early with the error of ctx, a timer by default
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// withDefaults fills the empty fields of p
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = 100 * time.Millisecond
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = 5 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	if p.RetryIf == nil {
+		p.RetryIf = func(err error) bool {
+			return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+		}
+	}
+	if p.Sleep == nil {
+		p.Sleep = sleepContext
+	}
+	return p
+}
+
+// interval returns the delay before retry n (from 1)
+func (p RetryPolicy) interval(n int) time.Duration {
+	delay := float64(p.InitialInterval)
+	for i := 1; i < n && delay < float64(p.MaxInterval); i++ {
+		delay *= p.Multiplier
+	}
+	delay = min(delay, float64(p.MaxInterval))
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// sleepContext waits for d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// MultiError holds the errors of every attempt of Retry, in order
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = fmt.Sprintf("attempt %d: %v", i+1, err)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors of the attempts for errors.Is and errors.As
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// Retry calls fn until it succeeds, policy.RetryIf rejects its error or policy.MaxAttempts is reached,
+// waiting with exponential backoff in between. It stops as soon as ctx is done, returning the error of ctx
+// wrapped with the last error. Failures return the last error, or a *MultiError with policy.AllErrors
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	_, err := RetryWithResult(ctx, policy, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// RetryWithResult is Retry for functions returning a value, the value of the successful attempt
+func RetryWithResult[T any](ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	policy = policy.withDefaults()
+
+	var zero T
+	var errs []error
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return zero, retryContextError(err, errs)
+		}
+
+		result, err := fn(ctx)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, err)
+		if attempt >= policy.MaxAttempts || !policy.RetryIf(err) {
+			break
+		}
+
+		if err := policy.Sleep(ctx, policy.interval(attempt)); err != nil {
+			return zero, retryContextError(err, errs)
+		}
+	}
+
+	if policy.AllErrors {
+		return zero, &MultiError{Errors: errs}
+	}
+	return zero, errs[len(errs)-1]
+}
+
+// retryContextError reports the end of ctx after the attempts that failed
+func retryContextError(ctxErr error, errs []error) error {
+	if len(errs) == 0 {
+		return ctxErr
+	}
+	return fmt.Errorf("%w after %d attempts: %w", ctxErr, len(errs), errs[len(errs)-1])
+}
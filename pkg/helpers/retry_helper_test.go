@@ -0,0 +1,221 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSleeper records the delays of Retry without waiting, calling before each one when set
+type recordingSleeper struct {
+	delays []time.Duration
+	before func()
+}
+
+func (s *recordingSleeper) sleep(ctx context.Context, d time.Duration) error {
+	if s.before != nil {
+		s.before()
+	}
+	s.delays = append(s.delays, d)
+	return ctx.Err()
+}
+
+// failingN returns a function failing with errs in turn, then succeeding, and the count of its calls
+func failingN(errs ...error) (func(ctx context.Context) error, *int) {
+	calls := 0
+	return func(ctx context.Context) error {
+		calls++
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return nil
+	}, &calls
+}
+
+var (
+	errFirst  = errors.New("first")
+	errSecond = errors.New("second")
+	errThird  = errors.New("third")
+)
+
+func TestRetry(t *testing.T) {
+	permanent := errors.New("permanent")
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		errs       []error
+		wantErr    error
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{
+			name:      "first attempt succeeds",
+			wantCalls: 1,
+		},
+		{
+			name:       "succeeds after retries",
+			policy:     RetryPolicy{MaxAttempts: 5},
+			errs:       []error{errFirst, errSecond},
+			wantCalls:  3,
+			wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:       "last error after max attempts",
+			errs:       []error{errFirst, errSecond, errThird, errFirst},
+			wantErr:    errThird,
+			wantCalls:  3,
+			wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:       "exponential backoff capped",
+			policy:     RetryPolicy{MaxAttempts: 6, InitialInterval: time.Second, MaxInterval: 5 * time.Second, Multiplier: 3},
+			errs:       []error{errFirst, errFirst, errFirst, errFirst, errFirst, errFirst},
+			wantErr:    errFirst,
+			wantCalls:  6,
+			wantDelays: []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:      "RetryIf rejects",
+			policy:    RetryPolicy{RetryIf: func(err error) bool { return !errors.Is(err, permanent) }},
+			errs:      []error{permanent},
+			wantErr:   permanent,
+			wantCalls: 1,
+		},
+		{
+			name:      "context errors not retried by default",
+			errs:      []error{context.DeadlineExceeded},
+			wantErr:   context.DeadlineExceeded,
+			wantCalls: 1,
+		},
+		{
+			name:      "single attempt",
+			policy:    RetryPolicy{MaxAttempts: 1},
+			errs:      []error{errFirst},
+			wantErr:   errFirst,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sleeper := &recordingSleeper{}
+			tt.policy.Sleep = sleeper.sleep
+			fn, calls := failingN(tt.errs...)
+
+			err := Retry(context.Background(), tt.policy, fn)
+			if err != tt.wantErr {
+				t.Errorf("Retry() = %v, want %v", err, tt.wantErr)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if len(sleeper.delays) != len(tt.wantDelays) {
+				t.Fatalf("delays = %v, want %v", sleeper.delays, tt.wantDelays)
+			}
+			for i, d := range sleeper.delays {
+				if d != tt.wantDelays[i] {
+					t.Errorf("delays = %v, want %v", sleeper.delays, tt.wantDelays)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	sleeper := &recordingSleeper{}
+	policy := RetryPolicy{MaxAttempts: 200, InitialInterval: time.Second, MaxInterval: time.Second, Jitter: 0.2, Sleep: sleeper.sleep}
+	Retry(context.Background(), policy, func(ctx context.Context) error { return errFirst })
+
+	distinct := map[time.Duration]bool{}
+	for _, d := range sleeper.delays {
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("delay %v outside 1s±20%%", d)
+		}
+		distinct[d] = true
+	}
+	if len(sleeper.delays) != 199 || len(distinct) < 100 {
+		t.Errorf("%d delays with %d distinct values, want 199 spread delays", len(sleeper.delays), len(distinct))
+	}
+}
+
+func TestRetryAllErrors(t *testing.T) {
+	sleeper := &recordingSleeper{}
+	fn, _ := failingN(errFirst, errSecond, errThird)
+	err := Retry(context.Background(), RetryPolicy{AllErrors: true, Sleep: sleeper.sleep}, fn)
+
+	var multi *MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 3 {
+		t.Fatalf("Retry() = %v, want a MultiError of 3 attempts", err)
+	}
+	for _, target := range []error{errFirst, errSecond, errThird} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) = false", err, target)
+		}
+	}
+	if want := "attempt 1: first; attempt 2: second; attempt 3: third"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	t.Run("done before the first attempt", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		fn, calls := failingN()
+		if err := Retry(ctx, RetryPolicy{}, fn); err != context.Canceled || *calls != 0 {
+			t.Errorf("Retry() = %v after %d calls, want context.Canceled without calls", err, *calls)
+		}
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sleeper := &recordingSleeper{before: cancel}
+		fn, calls := failingN(errFirst, errSecond)
+
+		err := Retry(ctx, RetryPolicy{MaxAttempts: 5, Sleep: sleeper.sleep}, fn)
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errFirst) || *calls != 1 {
+			t.Fatalf("Retry() = %v after %d calls, want context.Canceled wrapping the first error", err, *calls)
+		}
+		if !strings.Contains(err.Error(), "after 1 attempts") {
+			t.Errorf("Error() = %q, want the attempt count", err.Error())
+		}
+	})
+
+	t.Run("default sleeper returns on cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := Retry(ctx, RetryPolicy{InitialInterval: time.Hour}, func(ctx context.Context) error { return errFirst })
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errFirst) {
+			t.Errorf("Retry() = %v, want context.DeadlineExceeded wrapping the error", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Retry() waited %v after the deadline", elapsed)
+		}
+	})
+}
+
+func TestRetryWithResult(t *testing.T) {
+	sleeper := &recordingSleeper{}
+	calls := 0
+	got, err := RetryWithResult(context.Background(), RetryPolicy{Sleep: sleeper.sleep}, func(ctx context.Context) (string, error) {
+		calls++
+		if calls < 3 {
+			return "partial", errFirst
+		}
+		return "done", nil
+	})
+	if err != nil || got != "done" || calls != 3 {
+		t.Errorf("RetryWithResult() = %q, %v after %d calls, want done after 3", got, err, calls)
+	}
+
+	got, err = RetryWithResult(context.Background(), RetryPolicy{MaxAttempts: 2, Sleep: sleeper.sleep}, func(ctx context.Context) (string, error) {
+		return "partial", errFirst
+	})
+	if err != errFirst || got != "" {
+		t.Errorf("RetryWithResult() = %q, %v, want the zero value and the last error", got, err)
+	}
+}
@@ -0,0 +1,204 @@
+package helpers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// Vietnamese date layouts, days first. ParseVNDate also accepts days and months without a leading zero
+const (
+	VNDateLayout     = "02/01/2006"
+	VNDateTimeLayout = "02/01/2006 15:04"
+)
+
+// vnDateParseLayout accepts one or two digit days and months
+const vnDateParseLayout = "2/1/2006"
+
+// vnLocation loads Asia/Ho_Chi_Minh, falling back to its fixed UTC+7 offset without a tz database.
+// Vietnam has no daylight saving time so both agree on current dates
+var vnLocation = sync.OnceValue(func() *time.Location {
+	if location, err := time.LoadLocation("Asia/Ho_Chi_Minh"); err == nil {
+		return location
+	}
+	return time.FixedZone("ICT", 7*60*60)
+})
+
+// VNLocation returns the Asia/Ho_Chi_Minh location
+func VNLocation() *time.Location {
+	return vnLocation()
+}
+
+// locationOrVN returns the location passed to a VN helper, VNLocation by default
+func locationOrVN(location []*time.Location) *time.Location {
+	if len(location) > 0 && location[0] != nil {
+		return location[0]
+	}
+	return VNLocation()
+}
+
+// InVNTime returns t in Vietnam time for display, the instant is unchanged. location, at most one,
+// replaces VNLocation as for every VN helper
+func InVNTime(t time.Time, location ...*time.Location) time.Time {
+	return t.In(locationOrVN(location))
+}
+
+// StartOfDayVN returns midnight of the Vietnam day of t, e.g. 17:00 UTC of the previous day
+func StartOfDayVN(t time.Time, location ...*time.Location) time.Time {
+	loc := locationOrVN(location)
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// EndOfDayVN returns the last nanosecond of the Vietnam day of t. Databases truncating to microseconds
+// round it up to the next day, prefer the next StartOfDayVN as an exclusive bound in queries
+func EndOfDayVN(t time.Time, location ...*time.Location) time.Time {
+	return nextDay(StartOfDayVN(t, location...)).Add(-time.Nanosecond)
+}
+
+// StartOfWeekVN returns midnight of the Monday of the Vietnam week of t
+func StartOfWeekVN(t time.Time, location ...*time.Location) time.Time {
+	start := StartOfDayVN(t, location...)
+	daysSinceMonday := (int(start.Weekday()) + 6) % 7
+	return start.AddDate(0, 0, -daysSinceMonday)
+}
+
+// StartOfMonthVN returns midnight of the first day of the Vietnam month of t
+func StartOfMonthVN(t time.Time, location ...*time.Location) time.Time {
+	loc := locationOrVN(location)
+	year, month, _ := t.In(loc).Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+}
+
+// nextDay returns midnight of the day after the midnight start, by calendar rather than by 24 hours
+func nextDay(start time.Time) time.Time {
+	return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+}
+
+// ParseVNDate parses a dd/MM/yyyy date as users type it, "5/3/2024" included, at midnight Vietnam time
+func ParseVNDate(s string, location ...*time.Location) (time.Time, error) {
+	return time.ParseInLocation(vnDateParseLayout, strings.TrimSpace(s), locationOrVN(location))
+}
+
+// FormatVNDate formats t as dd/MM/yyyy in Vietnam time
+func FormatVNDate(t time.Time, location ...*time.Location) string {
+	return InVNTime(t, location...).Format(VNDateLayout)
+}
+
+// FormatVNDateTime formats t as dd/MM/yyyy HH:mm in Vietnam time
+func FormatVNDateTime(t time.Time, location ...*time.Location) string {
+	return InVNTime(t, location...).Format(VNDateTimeLayout)
+}
+
+// DateRange is a range of days as UTC instants, From inclusive and To exclusive, for queries such as
+// "created_at >= ? AND created_at < ?". Bounds are zero when their parameter is absent
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether t is within the range, absent bounds excluded
+func (r DateRange) Contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// DateRangeFromQuery parses the fromParam and toParam query parameters of a request, dd/MM/yyyy or
+// YYYY-MM-DD days in Vietnam time, into UTC bounds covering both days entirely. Malformed days or a to day
+// before the from day return a VALIDATION_ERROR response
+func DateRangeFromQuery(c echo.Context, fromParam, toParam string, location ...*time.Location) (DateRange, *common.ErrorResponse) {
+	loc := locationOrVN(location)
+	ctx := common.LocaleContext(c)
+
+	var rng DateRange
+	var details []common.ErrorDetail
+	fromValue, toValue := c.QueryParam(fromParam), c.QueryParam(toParam)
+	if fromValue != "" {
+		if day, err := parseQueryDay(fromValue, loc); err != nil {
+			details = append(details, dateParamError(ctx, fromParam, fromValue))
+		} else {
+			rng.From = day.UTC()
+		}
+	}
+	if toValue != "" {
+		if day, err := parseQueryDay(toValue, loc); err != nil {
+			details = append(details, dateParamError(ctx, toParam, toValue))
+		} else {
+			rng.To = nextDay(day).UTC()
+		}
+	}
+
+	if len(details) == 0 && !rng.From.IsZero() && !rng.To.IsZero() && !rng.From.Before(rng.To) {
+		details = append(details, common.ErrorDetail{
+			Field:   toParam,
+			Message: common.TfWithContext(ctx, common.MsgValidationDateAfter, map[string]any{"field": toParam, "after": fromValue}),
+			Value:   common.RedactValue(toParam, toValue),
+		})
+	}
+	if len(details) > 0 {
+		return DateRange{}, common.ValidationErrorI18n(details...)
+	}
+	return rng, nil
+}
+
+// parseQueryDay parses a dd/MM/yyyy or YYYY-MM-DD day at midnight in location
+func parseQueryDay(value string, location *time.Location) (time.Time, error) {
+	if day, err := ParseVNDate(value, location); err == nil {
+		return day, nil
+	}
+	return time.ParseInLocation(common.DateLayout, strings.TrimSpace(value), location)
+}
+
+// dateParamError builds the error detail of a malformed day parameter
+func dateParamError(ctx context.Context, name, value string) common.ErrorDetail {
+	return common.ErrorDetail{
+		Field:   name,
+		Message: common.TfWithContext(ctx, common.MsgValidationDateFormat, map[string]any{"field": name, "format": "dd/MM/yyyy"}),
+		Value:   common.RedactValue(name, value),
+	}
+}
+
+// BusinessDaysBetween counts the Monday to Friday days from the Vietnam day of start, included, to the
+// Vietnam day of end, excluded, skipping the days of holidays. It is negative when end is before start
+func BusinessDaysBetween(start, end time.Time, holidays []time.Time, location ...*time.Location) int {
+	loc := locationOrVN(location)
+	first, last := civilDay(start, loc), civilDay(end, loc)
+	sign := 1
+	if last < first {
+		first, last, sign = last, first, -1
+	}
+
+	weeks := (last - first) / 7
+	count := weeks * 5
+	for day := first + weeks*7; day < last; day++ {
+		if isWeekday(day) {
+			count++
+		}
+	}
+
+	skipped := make(map[int64]struct{}, len(holidays))
+	for _, holiday := range holidays {
+		day := civilDay(holiday, loc)
+		if _, ok := skipped[day]; ok || day < first || day >= last || !isWeekday(day) {
+			continue
+		}
+		skipped[day] = struct{}{}
+		count--
+	}
+	return sign * int(count)
+}
+
+// civilDay returns the number of days since 1970-01-01 of the day of t in location
+func civilDay(t time.Time, location *time.Location) int64 {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+}
+
+// isWeekday reports whether the civil day falls from Monday to Friday, 1970-01-01 was a Thursday
+func isWeekday(day int64) bool {
+	weekday := time.Weekday(((day % 7) + 7 + 4) % 7)
+	return weekday != time.Saturday && weekday != time.Sunday
+}
@@ -0,0 +1,296 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// loadLocation loads a tz database location
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+// utc returns a UTC instant
+func utc(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestVNLocation(t *testing.T) {
+	at := utc(2024, time.June, 1, 12, 0)
+	if _, offset := at.In(VNLocation()).Zone(); offset != 7*60*60 {
+		t.Errorf("VNLocation offset = %d, want UTC+7", offset)
+	}
+	if got := InVNTime(at); got.Hour() != 19 || !got.Equal(at) {
+		t.Errorf("InVNTime() = %v, want 19:00 at the same instant", got)
+	}
+}
+
+func TestStartAndEndOfDayVN(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantStart time.Time
+	}{
+		{"VN midnight", utc(2024, time.March, 4, 17, 0), utc(2024, time.March, 4, 17, 0)},
+		{"just before VN midnight", utc(2024, time.March, 4, 16, 59), utc(2024, time.March, 3, 17, 0)},
+		{"UTC midnight is VN morning", utc(2024, time.March, 5, 0, 0), utc(2024, time.March, 4, 17, 0)},
+		{"last nanosecond of the VN day", utc(2024, time.March, 5, 17, 0).Add(-time.Nanosecond), utc(2024, time.March, 4, 17, 0)},
+		{"new year in VN before UTC", utc(2023, time.December, 31, 18, 0), utc(2023, time.December, 31, 17, 0)},
+		{"leap day", utc(2024, time.February, 29, 10, 0), utc(2024, time.February, 28, 17, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := StartOfDayVN(tt.at)
+			if !start.Equal(tt.wantStart) || start.Location() != VNLocation() {
+				t.Errorf("StartOfDayVN(%v) = %v, want %v in VN time", tt.at, start, tt.wantStart)
+			}
+			end := EndOfDayVN(tt.at)
+			if want := tt.wantStart.Add(24*time.Hour - time.Nanosecond); !end.Equal(want) {
+				t.Errorf("EndOfDayVN(%v) = %v, want %v", tt.at, end, want)
+			}
+			if StartOfDayVN(start) != start || StartOfDayVN(end) != start {
+				t.Errorf("the bounds of the day are not on the same day")
+			}
+		})
+	}
+}
+
+func TestStartOfWeekAndMonthVN(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantWeek  time.Time
+		wantMonth time.Time
+	}{
+		// Sunday 2024-03-10 22:00 UTC is Monday 05:00 in Vietnam
+		{"UTC Sunday is VN Monday", utc(2024, time.March, 10, 22, 0), utc(2024, time.March, 10, 17, 0), utc(2024, time.February, 29, 17, 0)},
+		{"VN Sunday", utc(2024, time.March, 10, 10, 0), utc(2024, time.March, 3, 17, 0), utc(2024, time.February, 29, 17, 0)},
+		// 2024-02-29 18:00 UTC is already March 1st in Vietnam
+		{"month end crossing in VN", utc(2024, time.February, 29, 18, 0), utc(2024, time.February, 25, 17, 0), utc(2024, time.February, 29, 17, 0)},
+		{"last VN minute of February", utc(2024, time.February, 29, 16, 59), utc(2024, time.February, 25, 17, 0), utc(2024, time.January, 31, 17, 0)},
+		{"week spanning new year", utc(2025, time.January, 1, 3, 0), utc(2024, time.December, 29, 17, 0), utc(2024, time.December, 31, 17, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			week := StartOfWeekVN(tt.at)
+			if !week.Equal(tt.wantWeek) || week.Weekday() != time.Monday {
+				t.Errorf("StartOfWeekVN(%v) = %v, want Monday %v", tt.at, week, tt.wantWeek)
+			}
+			if month := StartOfMonthVN(tt.at); !month.Equal(tt.wantMonth) || month.Day() != 1 {
+				t.Errorf("StartOfMonthVN(%v) = %v, want %v", tt.at, month, tt.wantMonth)
+			}
+		})
+	}
+}
+
+func TestVNHelpersWithLocation(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+
+	// 2024-03-10 is 23 hours long in New York, 2024-11-03 is 25 hours long
+	tests := []struct {
+		at      time.Time
+		wantDay time.Duration
+	}{
+		{time.Date(2024, time.March, 10, 12, 0, 0, 0, newYork), 23 * time.Hour},
+		{time.Date(2024, time.November, 3, 12, 0, 0, 0, newYork), 25 * time.Hour},
+		{time.Date(2024, time.November, 4, 12, 0, 0, 0, newYork), 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		start, end := StartOfDayVN(tt.at, newYork), EndOfDayVN(tt.at, newYork)
+		if start.Hour() != 0 || start.Day() != tt.at.Day() {
+			t.Errorf("StartOfDayVN(%v) = %v, want local midnight", tt.at, start)
+		}
+		if got := end.Sub(start) + time.Nanosecond; got != tt.wantDay {
+			t.Errorf("day of %v lasts %v, want %v", tt.at, got, tt.wantDay)
+		}
+	}
+
+	if got := InVNTime(utc(2024, time.June, 1, 12, 0), newYork); got.Hour() != 8 {
+		t.Errorf("InVNTime(New York) = %v, want 08:00", got)
+	}
+	if got := FormatVNDateTime(utc(2024, time.June, 1, 3, 5), newYork); got != "31/05/2024 23:05" {
+		t.Errorf("FormatVNDateTime(New York) = %q", got)
+	}
+	day, err := ParseVNDate("1/6/2024", newYork)
+	if err != nil || !day.Equal(utc(2024, time.June, 1, 4, 0)) {
+		t.Errorf("ParseVNDate(New York) = %v, %v, want 04:00 UTC", day, err)
+	}
+	// A nil location keeps the default
+	if got := InVNTime(utc(2024, time.June, 1, 12, 0), nil); got.Location() != VNLocation() {
+		t.Errorf("InVNTime(nil) location = %v, want VN", got.Location())
+	}
+}
+
+func TestParseVNDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		err   bool
+	}{
+		{value: "05/03/2024", want: utc(2024, time.March, 4, 17, 0)},
+		{value: "5/3/2024", want: utc(2024, time.March, 4, 17, 0)},
+		{value: " 31/12/2024 ", want: utc(2024, time.December, 30, 17, 0)},
+		{value: "29/02/2024", want: utc(2024, time.February, 28, 17, 0)},
+		{value: "29/02/2023", err: true},
+		{value: "31/04/2024", err: true},
+		{value: "2024-03-05", err: true},
+		{value: "03/13/2024", err: true},
+		{value: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVNDate(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseVNDate(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseVNDate(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatVN(t *testing.T) {
+	tests := []struct {
+		at           time.Time
+		wantDate     string
+		wantDateTime string
+	}{
+		{utc(2024, time.March, 4, 16, 59), "04/03/2024", "04/03/2024 23:59"},
+		{utc(2024, time.March, 4, 17, 0), "05/03/2024", "05/03/2024 00:00"},
+		{utc(2024, time.December, 31, 17, 30), "01/01/2025", "01/01/2025 00:30"},
+	}
+	for _, tt := range tests {
+		if got := FormatVNDate(tt.at); got != tt.wantDate {
+			t.Errorf("FormatVNDate(%v) = %q, want %q", tt.at, got, tt.wantDate)
+		}
+		if got := FormatVNDateTime(tt.at); got != tt.wantDateTime {
+			t.Errorf("FormatVNDateTime(%v) = %q, want %q", tt.at, got, tt.wantDateTime)
+		}
+	}
+}
+
+func TestDateRangeFromQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		want       DateRange
+		wantFields []string
+	}{
+		{name: "none", want: DateRange{}},
+		{name: "single day", query: "from=05/03/2024&to=05/03/2024", want: DateRange{utc(2024, time.March, 4, 17, 0), utc(2024, time.March, 5, 17, 0)}},
+		{name: "ISO days", query: "from=2024-02-01&to=2024-02-29", want: DateRange{utc(2024, time.January, 31, 17, 0), utc(2024, time.February, 29, 17, 0)}},
+		{name: "month end", query: "from=1/1/2024&to=31/1/2024", want: DateRange{utc(2023, time.December, 31, 17, 0), utc(2024, time.January, 31, 17, 0)}},
+		{name: "year end", query: "to=31/12/2024", want: DateRange{To: utc(2024, time.December, 31, 17, 0)}},
+		{name: "from only", query: "from=01/03/2024", want: DateRange{From: utc(2024, time.February, 29, 17, 0)}},
+		{name: "malformed", query: "from=32/01/2024&to=yesterday", wantFields: []string{"from", "to"}},
+		{name: "to before from", query: "from=02/03/2024&to=01/03/2024", wantFields: []string{"to"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil), httptest.NewRecorder())
+			got, errResp := DateRangeFromQuery(c, "from", "to")
+			if tt.wantFields != nil {
+				if errResp == nil || errResp.Code != common.VALIDATION_ERROR || len(errResp.Details) != len(tt.wantFields) {
+					t.Fatalf("DateRangeFromQuery() = %v, %+v, want errors on %v", got, errResp, tt.wantFields)
+				}
+				for i, detail := range errResp.Details {
+					if detail.Field != tt.wantFields[i] || detail.Message == "" {
+						t.Errorf("detail %d = %+v, want field %s", i, detail, tt.wantFields[i])
+					}
+				}
+				return
+			}
+			if errResp != nil || !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) {
+				t.Errorf("DateRangeFromQuery() = %v, %+v, want %v", got, errResp, tt.want)
+			}
+			if !got.From.IsZero() && got.From.Location() != time.UTC || !got.To.IsZero() && got.To.Location() != time.UTC {
+				t.Errorf("bounds %v are not UTC", got)
+			}
+		})
+	}
+}
+
+func TestDateRangeContains(t *testing.T) {
+	rng := DateRange{From: utc(2024, time.March, 4, 17, 0), To: utc(2024, time.March, 5, 17, 0)}
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{rng.From, true},
+		{rng.From.Add(-time.Nanosecond), false},
+		{rng.To.Add(-time.Nanosecond), true},
+		{rng.To, false},
+	}
+	for _, tt := range tests {
+		if got := rng.Contains(tt.at); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+	if !(DateRange{}).Contains(time.Time{}) || !(DateRange{To: rng.To}).Contains(utc(1990, time.January, 1, 0, 0)) {
+		t.Error("absent bounds should not exclude")
+	}
+}
+
+func TestBusinessDaysBetween(t *testing.T) {
+	vn := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 0, 0, 0, VNLocation())
+	}
+	tests := []struct {
+		name       string
+		start, end time.Time
+		holidays   []time.Time
+		want       int
+	}{
+		{"same day", vn(2024, time.March, 4), vn(2024, time.March, 4), nil, 0},
+		{"monday to monday", vn(2024, time.March, 4), vn(2024, time.March, 11), nil, 5},
+		{"friday to monday", vn(2024, time.March, 8), vn(2024, time.March, 11), nil, 1},
+		{"saturday to monday", vn(2024, time.March, 9), vn(2024, time.March, 11), nil, 0},
+		{"reversed", vn(2024, time.March, 11), vn(2024, time.March, 4), nil, -5},
+		{"month end", vn(2024, time.January, 29), vn(2024, time.February, 2), nil, 4},
+		{"leap february", vn(2024, time.February, 1), vn(2024, time.March, 1), nil, 21},
+		{"long range", vn(2024, time.January, 1), vn(2025, time.January, 1), nil, 262},
+		{"holidays", vn(2024, time.April, 29), vn(2024, time.May, 6), []time.Time{
+			vn(2024, time.April, 30), vn(2024, time.May, 1),
+			// Duplicates, weekends and days out of range are ignored
+			vn(2024, time.May, 1), vn(2024, time.May, 4), vn(2024, time.May, 6),
+		}, 3},
+		// 2024-03-04 18:00 UTC is Tuesday in Vietnam, neither bound counts the Monday
+		{"UTC instants on VN days", utc(2024, time.March, 4, 18, 0), utc(2024, time.March, 11, 16, 0), nil, 4},
+		{"holiday as a UTC instant", vn(2024, time.September, 2), vn(2024, time.September, 4),
+			[]time.Time{utc(2024, time.September, 2, 17, 30)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BusinessDaysBetween(tt.start, tt.end, tt.holidays); got != tt.want {
+				t.Errorf("BusinessDaysBetween(%v, %v) = %d, want %d", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+
+	// The brute force count agrees over every start weekday and length of up to four weeks
+	for start := vn(2024, time.March, 4); start.Before(vn(2024, time.March, 11)); start = start.AddDate(0, 0, 1) {
+		want := 0
+		for length := 0; length <= 28; length++ {
+			end := start.AddDate(0, 0, length)
+			if got := BusinessDaysBetween(start, end, nil); got != want {
+				t.Errorf("BusinessDaysBetween(%v, %v) = %d, want %d", start, end, got, want)
+			}
+			if weekday := end.Weekday(); weekday != time.Saturday && weekday != time.Sunday {
+				want++
+			}
+		}
+	}
+}
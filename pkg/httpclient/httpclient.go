@@ -0,0 +1,288 @@
+// Package httpclient provides an outbound http.Client with tracing, trace context propagation, retries and
+// curl logging
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.22.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderIdempotencyKey makes POST and PATCH requests retryable, the server replays the first response
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// maxErrorBodyBytes caps the body kept by StatusError
+const maxErrorBodyBytes = 4 << 10
+
+// Options configures NewTracedHTTPClient, empty fields get defaults
+type Options struct {
+	// Transport sends the requests, http.DefaultTransport by default
+	Transport http.RoundTripper
+	// TracerProvider records a client span per attempt, the global provider by default
+	TracerProvider trace.TracerProvider
+	// Propagator injects the trace context headers, the global W3C Trace Context propagator by default
+	Propagator propagation.TextMapPropagator
+	// Logger logs failed attempts and, with LogCurl, the curl command of each request at debug level
+	Logger *logrus.Logger
+	// LogCurl logs requests with helpers.GenerateCurlCommandFromRequest, redacted with CurlOptions
+	LogCurl     bool
+	CurlOptions helpers.CurlOptions
+	// Timeout bounds each attempt, reading the response body included. 0 disables it
+	Timeout time.Duration
+	// Retry retries failed idempotent requests, no retries by default
+	Retry RetryPolicy
+}
+
+// RetryPolicy retries requests with GET, HEAD, OPTIONS, TRACE, PUT and DELETE methods, or with an
+// Idempotency-Key header, whose body can be replayed (http.NewRequest sets GetBody)
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 1 or less disables retries
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, doubled for each next one, 100ms by default
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay, 5 seconds by default
+	MaxBackoff time.Duration
+	// RetryOn reports whether an attempt failed, by default on connection errors and 5xx responses
+	RetryOn func(resp *http.Response, err error) bool
+}
+
+// withDefaults fills the empty fields of p
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+	if p.RetryOn == nil {
+		p.RetryOn = DefaultRetryOn
+	}
+	return p
+}
+
+// backoff returns the delay before retry n (from 1), exponential with jitter in its upper half
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < n && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxBackoff)
+	return delay/2 + time.Duration(rand.Int64N(int64(delay/2)+1))
+}
+
+// DefaultRetryOn retries connection errors and 5xx responses, not cancelled requests
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type tracedTransport struct {
+	next       http.RoundTripper
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	logger     *logrus.Logger
+	opts       Options
+	retry      RetryPolicy
+}
+
+// NewTracedHTTPClient creates an http.Client propagating the trace context of request contexts, tracing
+// each attempt and retrying idempotent requests with Options.Retry
+func NewTracedHTTPClient(opts Options) *http.Client {
+	t := &tracedTransport{
+		next:       opts.Transport,
+		propagator: opts.Propagator,
+		logger:     opts.Logger,
+		opts:       opts,
+		retry:      opts.Retry.withDefaults(),
+	}
+	if t.next == nil {
+		t.next = http.DefaultTransport
+	}
+	tracerProvider := opts.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	t.tracer = tracerProvider.Tracer("http.client")
+	if t.propagator == nil {
+		t.propagator = otel.GetTextMapPropagator() // W3C Trace Context propagator
+	}
+	if t.logger == nil {
+		t.logger = logrus.StandardLogger()
+	}
+	return &http.Client{Transport: t}
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if t.retryable(req) {
+		attempts = t.retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.roundTrip(req, attempt)
+		if attempt >= attempts || !t.retry.RetryOn(resp, err) {
+			return resp, err
+		}
+
+		delay := t.retry.backoff(attempt)
+		if err != nil {
+			t.logger.Warnf("HTTP %s %s failed, retrying in %s: %v", req.Method, req.URL.Redacted(), delay, err)
+		} else {
+			t.logger.Warnf("HTTP %s %s returned %d, retrying in %s", req.Method, req.URL.Redacted(), resp.StatusCode, delay)
+			// Drain the body so the connection is reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodyBytes))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether req is idempotent and its body can be sent again
+func (t *tracedTransport) retryable(req *http.Request) bool {
+	if t.retry.MaxAttempts <= 1 {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+// roundTrip sends one attempt in its own span
+func (t *tracedTransport) roundTrip(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method, trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(
+		semconv.HTTPMethodKey.String(req.Method),
+		semconv.HTTPURLKey.String(req.URL.Redacted()),
+		attribute.Int("http.attempt", attempt),
+	)
+
+	cancel := context.CancelFunc(func() {})
+	if t.opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.opts.Timeout)
+	}
+	// The transport must not modify the request of the caller
+	outgoing := req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(outgoing.Header))
+
+	if t.opts.LogCurl && attempt == 1 && t.logger.IsLevelEnabled(logrus.DebugLevel) {
+		if curl, err := helpers.GenerateCurlCommandFromRequest(outgoing, t.opts.CurlOptions); err == nil {
+			t.logger.Debugf("HTTP request: %s", curl)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(outgoing)
+	if err != nil {
+		cancel()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+
+	span.SetAttributes(
+		semconv.HTTPStatusCodeKey.Int(resp.StatusCode),
+		semconv.HTTPResponseContentLengthKey.Int64(resp.ContentLength),
+	)
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	} else {
+		span.SetStatus(codes.Ok, "Request sent successfully")
+	}
+	span.End()
+
+	// The attempt timeout covers reading the body, it is released once the body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout of an attempt when its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// StatusError is returned by DoJSON for responses with a 4xx or 5xx status
+type StatusError struct {
+	StatusCode int
+	// Body is the start of the response body, kept for error messages
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// DoJSON sends req with ctx and decodes the JSON response into a T. Responses with a 4xx or 5xx status
+// return a *StatusError, empty bodies (204) a zero T
+func DoJSON[T any](ctx context.Context, client *http.Client, req *http.Request) (*T, error) {
+	req = req.Clone(ctx)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	result := new(T)
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,485 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingTracer records the spans of its tracers, spans keep the span context of their parent so the
+// trace context still propagates
+type recordingTracer struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (r *recordingTracer) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return spanRecorder{recorder: r}
+}
+
+// recorded returns the spans started so far
+func (r *recordingTracer) recorded() []*recordingSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*recordingSpan(nil), r.spans...)
+}
+
+// spanRecorder is the trace.Tracer of a recordingTracer
+type spanRecorder struct {
+	noop.Tracer
+	recorder *recordingTracer
+}
+
+func (t spanRecorder) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name, parent: trace.SpanContextFromContext(ctx), attributes: map[attribute.Key]attribute.Value{}}
+	t.recorder.mu.Lock()
+	t.recorder.spans = append(t.recorder.spans, span)
+	t.recorder.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// recordingSpan is a span keeping its attributes and status
+type recordingSpan struct {
+	noop.Span
+	name       string
+	parent     trace.SpanContext
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+	ended      bool
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.parent }
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordingSpan) End(...trace.SpanEndOption) { s.ended = true }
+
+// roundTripperFunc is an http.RoundTripper function
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// flakyServer fails the first failures requests with status, then answers 200 with body. It records the
+// requests it receives
+type flakyServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	status   int
+	body     string
+	received []*http.Request
+	bodies   []string
+}
+
+func newFlakyServer(t *testing.T, failures, status int, body string) *flakyServer {
+	t.Helper()
+	s := &flakyServer{failures: failures, status: status, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBody, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.received = append(s.received, r)
+		s.bodies = append(s.bodies, string(requestBody))
+		fail := len(s.received) <= s.failures
+		s.mu.Unlock()
+		if fail {
+			http.Error(w, "try again", s.status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests returns the number of requests received
+func (s *flakyServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.received)
+}
+
+func testOptions() Options {
+	logger, _ := test.NewNullLogger()
+	return Options{
+		TracerProvider: noop.NewTracerProvider(),
+		Propagator:     propagation.TraceContext{},
+		Logger:         logger,
+		Retry:          RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+	}
+}
+
+// remoteContext returns a context carrying a sampled remote span context
+func remoteContext(t *testing.T) (context.Context, trace.SpanContext) {
+	t.Helper()
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled, Remote: true})
+	return trace.ContextWithRemoteSpanContext(context.Background(), sc), sc
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	server := newFlakyServer(t, 0, 0, `{}`)
+	client := NewTracedHTTPClient(testOptions())
+	ctx, sc := remoteContext(t)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if got := server.received[0].Header.Get("traceparent"); got != want {
+		t.Errorf("traceparent = %q, want %q", got, want)
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("the request of the caller was modified")
+	}
+}
+
+func TestSpansPerAttempt(t *testing.T) {
+	server := newFlakyServer(t, 1, http.StatusBadGateway, `{}`)
+	tracer := &recordingTracer{}
+	opts := testOptions()
+	opts.TracerProvider = tracer
+	client := NewTracedHTTPClient(opts)
+
+	resp, err := client.Get(server.URL + "/orders?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := tracer.recorded()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want one per attempt", len(spans))
+	}
+	for i, span := range spans {
+		wantStatus, wantCode := []int64{http.StatusBadGateway, http.StatusOK}[i], []codes.Code{codes.Error, codes.Ok}[i]
+		if span.name != "HTTP GET" || !span.ended || span.status != wantCode ||
+			span.attributes["http.method"].AsString() != http.MethodGet ||
+			span.attributes["http.url"].AsString() != server.URL+"/orders?token=secret" ||
+			span.attributes["http.status_code"].AsInt64() != wantStatus ||
+			span.attributes["http.attempt"].AsInt64() != int64(i+1) {
+			t.Errorf("span %d = %+v", i, span)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("idempotent request retried on 5xx", func(t *testing.T) {
+		server := newFlakyServer(t, 2, http.StatusServiceUnavailable, `{"ok":true}`)
+		resp, err := NewTracedHTTPClient(testOptions()).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `{"ok":true}` || server.requests() != 3 {
+			t.Errorf("status %d, body %q after %d requests", resp.StatusCode, body, server.requests())
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		server := newFlakyServer(t, 5, http.StatusInternalServerError, `{}`)
+		resp, err := NewTracedHTTPClient(testOptions()).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError || server.requests() != 3 {
+			t.Errorf("status %d after %d requests, want the last 500 after 3", resp.StatusCode, server.requests())
+		}
+	})
+
+	t.Run("4xx not retried", func(t *testing.T) {
+		server := newFlakyServer(t, 5, http.StatusNotFound, `{}`)
+		resp, err := NewTracedHTTPClient(testOptions()).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if server.requests() != 1 {
+			t.Errorf("%d requests, want 1", server.requests())
+		}
+	})
+
+	t.Run("POST not retried", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, `{}`)
+		resp, err := NewTracedHTTPClient(testOptions()).Post(server.URL, "application/json", strings.NewReader(`{"a":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || server.requests() != 1 {
+			t.Errorf("status %d after %d requests, want the 503 without retry", resp.StatusCode, server.requests())
+		}
+	})
+
+	t.Run("POST with an idempotency key replays its body", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, `{}`)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"a":1}`))
+		req.Header.Set(HeaderIdempotencyKey, "order-1")
+		resp, err := NewTracedHTTPClient(testOptions()).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(server.bodies) != 2 || server.bodies[0] != `{"a":1}` || server.bodies[1] != `{"a":1}` {
+			t.Errorf("status %d, bodies %q", resp.StatusCode, server.bodies)
+		}
+	})
+
+	t.Run("body without GetBody not retried", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, `{}`)
+		req, _ := http.NewRequest(http.MethodPut, server.URL, io.NopCloser(strings.NewReader(`{"a":1}`)))
+		resp, err := NewTracedHTTPClient(testOptions()).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if server.requests() != 1 {
+			t.Errorf("%d requests, want 1", server.requests())
+		}
+	})
+
+	t.Run("connection errors retried", func(t *testing.T) {
+		server := newFlakyServer(t, 0, 0, `{}`)
+		attempts := 0
+		opts := testOptions()
+		opts.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if attempts++; attempts == 1 {
+				return nil, errors.New("connection reset by peer")
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		resp, err := NewTracedHTTPClient(opts).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if attempts != 2 || server.requests() != 1 {
+			t.Errorf("%d attempts, %d requests", attempts, server.requests())
+		}
+	})
+
+	t.Run("no retries by default", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, `{}`)
+		opts := testOptions()
+		opts.Retry = RetryPolicy{}
+		resp, err := NewTracedHTTPClient(opts).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if server.requests() != 1 {
+			t.Errorf("%d requests, want 1", server.requests())
+		}
+	})
+
+	t.Run("cancelled during backoff", func(t *testing.T) {
+		server := newFlakyServer(t, 5, http.StatusServiceUnavailable, `{}`)
+		opts := testOptions()
+		opts.Retry.InitialBackoff, opts.Retry.MaxBackoff = time.Hour, time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		start := time.Now()
+		if _, err := NewTracedHTTPClient(opts).Do(req); !errors.Is(err, context.Canceled) {
+			t.Errorf("Do = %v, want context.Canceled", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second || server.requests() != 1 {
+			t.Errorf("returned after %s and %d requests", elapsed, server.requests())
+		}
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{30, time.Second},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if delay := policy.backoff(tt.retry); delay < tt.max/2 || delay > tt.max {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.retry, delay, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	defaults := RetryPolicy{}.withDefaults()
+	if defaults.MaxAttempts != 1 || defaults.InitialBackoff != 100*time.Millisecond || defaults.MaxBackoff != 5*time.Second || defaults.RetryOn == nil {
+		t.Errorf("defaults = %+v", defaults)
+	}
+	if DefaultRetryOn(nil, context.Canceled) || !DefaultRetryOn(nil, context.DeadlineExceeded) ||
+		!DefaultRetryOn(&http.Response{StatusCode: http.StatusBadGateway}, nil) || DefaultRetryOn(&http.Response{StatusCode: http.StatusTooManyRequests}, nil) {
+		t.Error("DefaultRetryOn does not retry exactly connection errors and 5xx responses")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	opts := testOptions()
+	opts.Timeout = 50 * time.Millisecond
+	opts.Retry = RetryPolicy{}
+	client := NewTracedHTTPClient(opts)
+
+	if _, err := client.Get(server.URL + "/slow-headers"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The timeout covers reading the body
+	resp, err := client.Get(server.URL + "/slow-body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("reading the body = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCurlLogging(t *testing.T) {
+	server := newFlakyServer(t, 1, http.StatusServiceUnavailable, `{}`)
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	opts := testOptions()
+	opts.Logger, opts.LogCurl = logger, true
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := NewTracedHTTPClient(opts).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var curls []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.DebugLevel {
+			curls = append(curls, entry.Message)
+		}
+	}
+	if len(curls) != 1 || !strings.HasPrefix(curls[0], "HTTP request: curl -X GET '"+server.URL+"/orders'") ||
+		!strings.Contains(curls[0], "Authorization: <redacted>") || strings.Contains(curls[0], "secret") {
+		t.Errorf("debug entries = %q, want one redacted curl command", curls)
+	}
+
+	// Without debug level nothing is generated
+	hook.Reset()
+	logger.SetLevel(logrus.InfoLevel)
+	resp, err = NewTracedHTTPClient(opts).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for _, entry := range hook.AllEntries() {
+		if strings.Contains(entry.Message, "curl") {
+			t.Errorf("logged %q at info level", entry.Message)
+		}
+	}
+}
+
+type order struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+func TestDoJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders/1":
+			if r.Header.Get("Accept") != "application/json" {
+				http.Error(w, "missing accept", http.StatusNotAcceptable)
+				return
+			}
+			io.WriteString(w, `{"id":"1","total":250}`)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/invalid":
+			io.WriteString(w, `{"id":`)
+		default:
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := NewTracedHTTPClient(testOptions())
+	get := func(path string) (*order, error) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		return DoJSON[order](context.Background(), client, req)
+	}
+
+	if got, err := get("/orders/1"); err != nil || *got != (order{ID: "1", Total: 250}) {
+		t.Errorf("DoJSON = %+v, %v", got, err)
+	}
+	if got, err := get("/empty"); err != nil || *got != (order{}) {
+		t.Errorf("DoJSON of 204 = %+v, %v, want a zero value", got, err)
+	}
+	if _, err := get("/invalid"); err == nil || !strings.Contains(err.Error(), "failed to decode response") {
+		t.Errorf("DoJSON of invalid JSON = %v", err)
+	}
+
+	_, err := get("/missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound || strings.TrimSpace(string(statusErr.Body)) != `{"error":"not found"}` {
+		t.Fatalf("DoJSON of 404 = %v", err)
+	}
+	if statusErr.Error() != "unexpected status 404: {\"error\":\"not found\"}\n" {
+		t.Errorf("Error() = %q", statusErr.Error())
+	}
+	if (&StatusError{StatusCode: http.StatusBadGateway}).Error() != "unexpected status 502" {
+		t.Error("StatusError without body")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders/1", nil)
+	if _, err := DoJSON[order](ctx, client, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("DoJSON with a cancelled context = %v", err)
+	}
+}
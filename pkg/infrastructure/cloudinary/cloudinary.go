@@ -2,10 +2,15 @@ package cloudinary
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
-	"path/filepath"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
@@ -16,9 +21,40 @@ import (
 )
 
 type CloudinaryService interface {
-	UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, folder string) (string, error)
+	// UploadFile uploads a file into folder under a public ID chosen by opts, unique by default
+	UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, folder string, opts UploadOptions) (UploadResult, error)
+	// UploadReader uploads generated content, e.g. a thumbnail rendered in memory
+	UploadReader(ctx context.Context, r io.Reader, opts UploadOptions) (UploadResult, error)
+	// UploadFromURL makes Cloudinary fetch and store a remote HTTP(S) file
+	UploadFromURL(ctx context.Context, remoteURL string, opts UploadOptions) (UploadResult, error)
+	// DeleteFile deletes an asset by public ID or delivery URL, ErrAssetNotFound when it does not exist
 	DeleteFile(ctx context.Context, publicID string) error
+	// DeleteByURL deletes the asset of a delivery URL
+	DeleteByURL(ctx context.Context, assetURL string) error
+	// DeleteMany deletes assets by public ID or delivery URL and returns the ones that were not deleted
+	// with a *DeleteError
+	DeleteMany(ctx context.Context, publicIDs []string) ([]string, error)
+	// ListTemporaryAssets returns the delivery URLs of the Temporary uploads that expired before expiredBefore
+	ListTemporaryAssets(ctx context.Context, expiredBefore time.Time) ([]string, error)
+	// MarkPermanent removes the temporary tag of an asset by public ID or delivery URL, call it when the
+	// owning entity is saved
+	MarkPermanent(ctx context.Context, publicID string) error
+	// GetImageURL returns the delivery URL of an image with transformations applied. Unknown keys and
+	// invalid values are logged and skipped, the other transformations still apply. Use BuildImageURL to
+	// reject them instead
 	GetImageURL(publicID string, transformations map[string]interface{}) string
+	// BuildImageURL returns the delivery URL of an image with chained transformations, rejecting unknown ones
+	BuildImageURL(publicID string, chain ...Transformation) (string, error)
+	// ThumbnailURL returns the URL of a width x height thumbnail cropped around the subject
+	ThumbnailURL(publicID string, width, height int) string
+	// ResponsiveSrcSet returns an <img srcset> value with one URL per width
+	ResponsiveSrcSet(publicID string, widths []int) string
+	// SignedURL returns a signed delivery URL of a Private image. Cloudinary rejects it after expiresAt,
+	// which requires the auth token key of the client (Config.AuthToken.Key). A zero expiresAt
+	// signs a URL that does not expire
+	SignedURL(publicID string, transformations map[string]interface{}, expiresAt time.Time) (string, error)
+	// SignedDownloadURL returns an API download URL of a Private raw file (PDF, archive...) valid until expiresAt
+	SignedDownloadURL(publicID, format string, expiresAt time.Time) (string, error)
 }
 
 type cloudinaryService struct {
@@ -42,7 +78,7 @@ func (s *cloudinaryService) trace(ctx context.Context, name string) (context.Con
 	return tracer.Start(ctx, name)
 }
 
-func (s *cloudinaryService) UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, folder string) (string, error) {
+func (s *cloudinaryService) UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, folder string, opts UploadOptions) (UploadResult, error) {
 	ctx, span := s.trace(ctx, "cloudinary.upload-file")
 	defer span.End()
 
@@ -59,32 +95,79 @@ func (s *cloudinaryService) UploadFile(ctx context.Context, fileHeader *multipar
 		s.logger.Errorf("Failed to open uploaded file: %v", err)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return "", err
+		return UploadResult{}, err
 	}
 	defer file.Close()
 
-	// Generate public ID from filename (without extension)
-	ext := filepath.Ext(fileHeader.Filename)
-	publicID := strings.TrimSuffix(fileHeader.Filename, ext)
-
-	// Prepare upload options
-	overwrite := true
-	invalidate := true
-	uploadOptions := &uploader.UploadParams{
-		PublicID:     publicID,
-		Folder:       folder,
-		ResourceType: "auto", // Auto-detect resource type
-		Overwrite:    &overwrite,
-		Invalidate:   &invalidate,
+	if folder != "" {
+		opts.Folder = folder
 	}
+	if opts.Filename == "" {
+		opts.Filename = fileHeader.Filename
+	}
+	return s.upload(ctx, span, file, opts)
+}
 
-	// Upload to Cloudinary
-	result, err := s.client.Upload.Upload(ctx, file, *uploadOptions)
+func (s *cloudinaryService) UploadReader(ctx context.Context, r io.Reader, opts UploadOptions) (UploadResult, error) {
+	ctx, span := s.trace(ctx, "cloudinary.upload-reader")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("cloudinary.filename", opts.Filename),
+		attribute.String("cloudinary.folder", opts.Folder),
+	)
+	return s.upload(ctx, span, r, opts)
+}
+
+func (s *cloudinaryService) UploadFromURL(ctx context.Context, remoteURL string, opts UploadOptions) (UploadResult, error) {
+	ctx, span := s.trace(ctx, "cloudinary.upload-from-url")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("cloudinary.remote_url", remoteURL),
+		attribute.String("cloudinary.folder", opts.Folder),
+	)
+	parsed, err := url.Parse(remoteURL)
+	if err == nil && (parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "") {
+		err = fmt.Errorf("invalid remote url %q", remoteURL)
+	}
 	if err != nil {
-		s.logger.Errorf("Failed to upload file to Cloudinary: %v", err)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return "", err
+		return UploadResult{}, err
+	}
+	if opts.Filename == "" {
+		opts.Filename = path.Base(parsed.Path)
+	}
+	// The SDK uploads strings that are URLs by reference
+	return s.upload(ctx, span, remoteURL, opts)
+}
+
+// upload stores file, a reader or a remote URL, with opts
+func (s *cloudinaryService) upload(ctx context.Context, span trace.Span, file interface{}, opts UploadOptions) (UploadResult, error) {
+	span.SetAttributes(
+		attribute.Bool("cloudinary.overwrite", opts.Overwrite),
+		attribute.Bool("cloudinary.private", opts.Private),
+	)
+	uploadParams, err := opts.uploadParams(opts.Filename, time.Now())
+	if err != nil {
+		return UploadResult{}, s.uploadError(span, err)
+	}
+	span.SetAttributes(
+		attribute.String("cloudinary.requested_public_id", uploadParams.PublicID),
+		attribute.String("cloudinary.requested_resource_type", uploadParams.ResourceType),
+	)
+
+	// Upload to Cloudinary
+	result, err := s.client.Upload.Upload(ctx, file, uploadParams)
+	if err == nil && result.Error.Message != "" {
+		err = errors.New(result.Error.Message)
+	}
+	if err == nil && !opts.Overwrite && existingAsset(result) {
+		err = fmt.Errorf("%w: %s", ErrPublicIDExists, result.PublicID)
+	}
+	if err != nil {
+		return UploadResult{}, s.uploadError(span, err)
 	}
 
 	// Set success attributes
@@ -96,7 +179,15 @@ func (s *cloudinaryService) UploadFile(ctx context.Context, fileHeader *multipar
 	span.SetStatus(codes.Ok, "File uploaded successfully")
 
 	s.logger.Infof("Successfully uploaded file to Cloudinary: %s", result.PublicID)
-	return result.SecureURL, nil
+	return newUploadResult(result), nil
+}
+
+// uploadError records a failed upload on span
+func (s *cloudinaryService) uploadError(span trace.Span, err error) error {
+	s.logger.Errorf("Failed to upload file to Cloudinary: %v", err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
 }
 
 func (s *cloudinaryService) DeleteFile(ctx context.Context, publicID string) error {
@@ -104,28 +195,36 @@ func (s *cloudinaryService) DeleteFile(ctx context.Context, publicID string) err
 	defer span.End()
 
 	// Extract public ID from URL if it's a full URL
-	originalPublicID := publicID
-	if strings.Contains(publicID, "cloudinary.com") {
-		// Extract public ID from Cloudinary URL
-		parts := strings.Split(publicID, "/")
-		if len(parts) > 0 {
-			lastPart := parts[len(parts)-1]
-			// Remove file extension
-			ext := filepath.Ext(lastPart)
-			publicID = strings.TrimSuffix(lastPart, ext)
-		}
+	asset, err := ParseAssetRef(publicID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
 
 	// Set span attributes
 	span.SetAttributes(
-		attribute.String("cloudinary.original_public_id", originalPublicID),
-		attribute.String("cloudinary.public_id", publicID),
+		attribute.String("cloudinary.original_public_id", publicID),
+		attribute.String("cloudinary.public_id", asset.PublicID),
+		attribute.String("cloudinary.resource_type", asset.ResourceType),
 	)
 
 	// Delete from Cloudinary
 	result, err := s.client.Upload.Destroy(ctx, uploader.DestroyParams{
-		PublicID: publicID,
+		PublicID:     asset.PublicID,
+		Type:         asset.DeliveryType,
+		ResourceType: asset.ResourceType,
 	})
+	if err == nil && result.Error.Message != "" {
+		err = errors.New(result.Error.Message)
+	}
+	if err == nil && result.Result != "ok" {
+		if result.Result == "not found" {
+			err = fmt.Errorf("%w: %s", ErrAssetNotFound, asset.PublicID)
+		} else {
+			err = fmt.Errorf("cloudinary delete of %s failed: %s", asset.PublicID, result.Result)
+		}
+	}
 	if err != nil {
 		s.logger.Errorf("Failed to delete file from Cloudinary: %v", err)
 		span.RecordError(err)
@@ -139,7 +238,7 @@ func (s *cloudinaryService) DeleteFile(ctx context.Context, publicID string) err
 	)
 	span.SetStatus(codes.Ok, "File deleted successfully")
 
-	s.logger.Infof("Successfully deleted file from Cloudinary: %s, Result: %s", publicID, result.Result)
+	s.logger.Infof("Successfully deleted file from Cloudinary: %s", asset.PublicID)
 	return nil
 }
 
@@ -158,8 +257,12 @@ func (s *cloudinaryService) GetImageURL(publicID string, transformations map[str
 		)
 	}
 
-	// Generate Cloudinary URL directly using string formatting
-	url := fmt.Sprintf("https://res.cloudinary.com/%s/image/upload/%s", s.cloudName, publicID)
+	segment, skipped := Transformation(transformations).renderValid()
+	if len(skipped) > 0 {
+		s.logger.Warnf("Ignoring invalid Cloudinary transformations %v of %s, use BuildImageURL to reject them", skipped, publicID)
+		span.SetAttributes(attribute.StringSlice("cloudinary.skipped_transformations", skipped))
+	}
+	url := s.imageURL(publicID, segment)
 
 	// Set success attributes
 	span.SetAttributes(
@@ -169,3 +272,36 @@ func (s *cloudinaryService) GetImageURL(publicID string, transformations map[str
 
 	return url
 }
+
+func (s *cloudinaryService) BuildImageURL(publicID string, chain ...Transformation) (string, error) {
+	segment, err := renderChain(chain)
+	if err != nil {
+		return "", err
+	}
+	return s.imageURL(publicID, segment), nil
+}
+
+func (s *cloudinaryService) ThumbnailURL(publicID string, width, height int) string {
+	segment, _ := Transformation{"width": width, "height": height, "crop": "fill", "gravity": "auto", "quality": "auto", "format": "auto"}.Render()
+	return s.imageURL(publicID, segment)
+}
+
+func (s *cloudinaryService) ResponsiveSrcSet(publicID string, widths []int) string {
+	candidates := make([]string, 0, len(widths))
+	for _, width := range widths {
+		if width <= 0 {
+			continue
+		}
+		segment, _ := Transformation{"width": width, "crop": "scale", "quality": "auto", "format": "auto"}.Render()
+		candidates = append(candidates, s.imageURL(publicID, segment)+" "+strconv.Itoa(width)+"w")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// imageURL returns the delivery URL of an image, with the transformation segment when not empty
+func (s *cloudinaryService) imageURL(publicID, segment string) string {
+	if segment != "" {
+		return fmt.Sprintf("https://res.cloudinary.com/%s/image/upload/%s/%s", s.cloudName, segment, publicID)
+	}
+	return fmt.Sprintf("https://res.cloudinary.com/%s/image/upload/%s", s.cloudName, publicID)
+}
@@ -0,0 +1,211 @@
+package cloudinary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ErrAssetNotFound is returned when Cloudinary has no asset with the public ID to delete
+var ErrAssetNotFound = errors.New("cloudinary asset not found")
+
+// deleteBatchSize is the largest number of public IDs of one delete resources call
+const deleteBatchSize = 100
+
+// DeleteError is returned by DeleteMany when some assets could not be deleted
+type DeleteError struct {
+	// Failed are the public IDs or URLs that were not deleted
+	Failed []string
+	// Err is the first error
+	Err error
+}
+
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("failed to delete %d cloudinary assets: %v", len(e.Failed), e.Err)
+}
+
+func (e *DeleteError) Unwrap() error {
+	return e.Err
+}
+
+// AssetRef identifies a stored asset
+type AssetRef struct {
+	PublicID string
+	// ResourceType is "image", "video" or "raw"
+	ResourceType string
+	// DeliveryType is "upload", "authenticated", "private"...
+	DeliveryType string
+}
+
+var (
+	versionSegment   = regexp.MustCompile(`^v\d+$`)
+	signatureSegment = regexp.MustCompile(`^s--[A-Za-z0-9_-]+--$`)
+)
+
+// ParseAssetRef returns the asset of a delivery URL such as
+// "https://res.cloudinary.com/demo/image/upload/c_fill,w_200/v1699999999/avatars/user-123/photo.jpg"
+// (public ID "avatars/user-123/photo"). Other values are returned as image public IDs
+func ParseAssetRef(ref string) (AssetRef, error) {
+	if !strings.Contains(ref, "://") {
+		if ref == "" {
+			return AssetRef{}, errors.New("empty cloudinary public id")
+		}
+		return AssetRef{PublicID: ref, ResourceType: string(api.Image), DeliveryType: string(api.Upload)}, nil
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return AssetRef{}, err
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	// Shared host URLs start with the cloud name, private CDN and CNAME URLs do not
+	for i := 0; i+2 < len(segments); i++ {
+		switch segments[i] {
+		case string(api.Image), api.Video, api.File:
+		default:
+			continue
+		}
+		asset := AssetRef{ResourceType: segments[i], DeliveryType: segments[i+1]}
+		rest := segments[i+2:]
+		if len(rest) > 0 && signatureSegment.MatchString(rest[0]) {
+			rest = rest[1:]
+		}
+		rest = stripTransformations(rest)
+		if len(rest) == 0 {
+			break
+		}
+		publicID, err := url.PathUnescape(strings.Join(rest, "/"))
+		if err != nil {
+			return AssetRef{}, err
+		}
+		// Raw public IDs keep their extension, image and video URLs add the delivery format
+		if asset.ResourceType != api.File {
+			publicID = strings.TrimSuffix(publicID, path.Ext(publicID))
+		}
+		asset.PublicID = publicID
+		return asset, nil
+	}
+	return AssetRef{}, fmt.Errorf("not a cloudinary delivery url: %s", ref)
+}
+
+// stripTransformations drops the transformation and version segments before a public ID. With a
+// version everything after it is the public ID, otherwise leading segments made of the supported
+// transformation parameters are dropped, so folders such as "my_folder" are kept
+func stripTransformations(segments []string) []string {
+	for i, segment := range segments {
+		if versionSegment.MatchString(segment) {
+			return segments[i+1:]
+		}
+	}
+	for len(segments) > 1 && isTransformationSegment(segments[0]) {
+		segments = segments[1:]
+	}
+	return segments
+}
+
+// isTransformationSegment reports whether every part of segment is a supported parameter in its URL
+// form, e.g. "w_200,c_fill"
+func isTransformationSegment(segment string) bool {
+	for _, part := range strings.Split(segment, ",") {
+		abbr, value, ok := strings.Cut(part, "_")
+		if !ok || value == "" {
+			return false
+		}
+		if param, known := lookupTransformationParam(abbr); !known || param.abbr != abbr {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *cloudinaryService) DeleteByURL(ctx context.Context, assetURL string) error {
+	if !strings.Contains(assetURL, "://") {
+		return fmt.Errorf("not a cloudinary delivery url: %s", assetURL)
+	}
+	return s.DeleteFile(ctx, assetURL)
+}
+
+func (s *cloudinaryService) DeleteMany(ctx context.Context, refs []string) ([]string, error) {
+	ctx, span := s.trace(ctx, "cloudinary.delete-many")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("cloudinary.attempted", len(refs)))
+
+	// Delete resources calls are per resource and delivery type
+	type assetGroup struct {
+		resourceType string
+		deliveryType string
+	}
+	groups := make(map[assetGroup][]AssetRef)
+	var order []assetGroup
+	refOf := make(map[AssetRef]string, len(refs))
+	var failed []string
+	var firstErr error
+	for _, ref := range refs {
+		asset, err := ParseAssetRef(ref)
+		if err != nil {
+			failed = append(failed, ref)
+			firstErr = errors.Join(firstErr, err)
+			continue
+		}
+		group := assetGroup{asset.ResourceType, asset.DeliveryType}
+		if _, ok := groups[group]; !ok {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], asset)
+		refOf[asset] = ref
+	}
+
+	for _, group := range order {
+		assets := groups[group]
+		for start := 0; start < len(assets); start += deleteBatchSize {
+			batch := assets[start:min(start+deleteBatchSize, len(assets))]
+			publicIDs := make([]string, len(batch))
+			for i, asset := range batch {
+				publicIDs[i] = asset.PublicID
+			}
+
+			result, err := s.client.Admin.DeleteAssets(ctx, admin.DeleteAssetsParams{
+				AssetType:    api.AssetType(group.resourceType),
+				DeliveryType: api.DeliveryType(group.deliveryType),
+				PublicIDs:    publicIDs,
+			})
+			if err == nil && result.Error.Message != "" {
+				err = errors.New(result.Error.Message)
+			}
+			for _, asset := range batch {
+				if err == nil && result.Deleted[asset.PublicID] == "deleted" {
+					continue
+				}
+				failed = append(failed, refOf[asset])
+				if firstErr == nil {
+					firstErr = err
+					if err == nil {
+						firstErr = fmt.Errorf("%w: %s", ErrAssetNotFound, asset.PublicID)
+					}
+				}
+			}
+		}
+	}
+
+	span.SetAttributes(attribute.Int("cloudinary.failed", len(failed)))
+	if len(failed) > 0 {
+		deleteErr := &DeleteError{Failed: failed, Err: firstErr}
+		s.logger.Errorf("Failed to delete files from Cloudinary: %v", deleteErr)
+		span.RecordError(deleteErr)
+		span.SetStatus(codes.Error, deleteErr.Error())
+		return failed, deleteErr
+	}
+	span.SetStatus(codes.Ok, "Files deleted successfully")
+	s.logger.Infof("Successfully deleted %d files from Cloudinary", len(refs))
+	return nil, nil
+}
@@ -0,0 +1,128 @@
+package cloudinary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestParseAssetRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    AssetRef
+		wantErr bool
+	}{
+		{name: "bare id", ref: "avatars/user-123/photo", want: AssetRef{"avatars/user-123/photo", "image", "upload"}},
+		{name: "plain", ref: "https://res.cloudinary.com/demo/image/upload/sample.jpg", want: AssetRef{"sample", "image", "upload"}},
+		{name: "foldered", ref: "https://res.cloudinary.com/demo/image/upload/avatars/user-123/photo.jpg", want: AssetRef{"avatars/user-123/photo", "image", "upload"}},
+		{name: "underscore folder", ref: "https://res.cloudinary.com/demo/image/upload/my_folder/photo.jpg", want: AssetRef{"my_folder/photo", "image", "upload"}},
+		{name: "short underscore folder", ref: "https://res.cloudinary.com/demo/image/upload/ab_c/photo.jpg", want: AssetRef{"ab_c/photo", "image", "upload"}},
+		{name: "underscore folder after transformation", ref: "https://res.cloudinary.com/demo/image/upload/w_200/my_folder/photo.jpg", want: AssetRef{"my_folder/photo", "image", "upload"}},
+		{name: "versioned", ref: "https://res.cloudinary.com/demo/image/upload/v1699999999/avatars/user-123/photo.jpg", want: AssetRef{"avatars/user-123/photo", "image", "upload"}},
+		{
+			name: "transformed and versioned",
+			ref:  "https://res.cloudinary.com/demo/image/upload/c_fill,w_200/v1699999999/avatars/user-123/photo.jpg",
+			want: AssetRef{"avatars/user-123/photo", "image", "upload"},
+		},
+		{
+			name: "chained transformations",
+			ref:  "https://res.cloudinary.com/demo/image/upload/c_fill,g_auto,h_200,w_200/q_auto,f_auto/avatars/photo.png",
+			want: AssetRef{"avatars/photo", "image", "upload"},
+		},
+		{name: "version-like folder after version", ref: "https://res.cloudinary.com/demo/image/upload/v1/w_200/photo.jpg", want: AssetRef{"w_200/photo", "image", "upload"}},
+		{
+			name: "signed authenticated",
+			ref:  "https://res.cloudinary.com/demo/image/authenticated/s--AbCd1234--/w_200/v1/contracts/scan.jpg",
+			want: AssetRef{"contracts/scan", "image", "authenticated"},
+		},
+		{name: "video", ref: "https://res.cloudinary.com/demo/video/upload/so_2,f_jpg/v3/clips/intro.mp4", want: AssetRef{"clips/intro", "video", "upload"}},
+		{name: "raw keeps extension", ref: "https://res.cloudinary.com/demo/raw/upload/v1/docs/report.pdf", want: AssetRef{"docs/report.pdf", "raw", "upload"}},
+		{name: "escaped", ref: "https://res.cloudinary.com/demo/image/upload/v1/h%E1%BB%93%20s%C6%A1/a.jpg", want: AssetRef{"hồ sơ/a", "image", "upload"}},
+		{name: "private cdn", ref: "https://demo-res.cloudinary.com/image/upload/v1/avatars/photo.jpg", want: AssetRef{"avatars/photo", "image", "upload"}},
+		{name: "public id only transformation-like", ref: "https://res.cloudinary.com/demo/image/upload/w_200.jpg", want: AssetRef{"w_200", "image", "upload"}},
+		{name: "empty", ref: "", wantErr: true},
+		{name: "not cloudinary", ref: "https://example.com/photo.jpg", wantErr: true},
+		{name: "no public id", ref: "https://res.cloudinary.com/demo/image/upload/v1/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAssetRef(tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseAssetRef(%q) = %+v, %v, want %+v (error %v)", tt.ref, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteFile(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+	fake.put(ResourceImage, "upload", "avatars/user-123/photo")
+	fake.put(ResourceRaw, "upload", "docs/report.pdf")
+
+	if err := s.DeleteFile(context.Background(), "https://res.cloudinary.com/demo/image/upload/c_fill,w_200/v1699999999/avatars/user-123/photo.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteByURL(context.Background(), "https://res.cloudinary.com/demo/raw/upload/v1/docs/report.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if ids := fake.publicIDs(); len(ids) != 0 {
+		t.Errorf("assets = %v, want all deleted", ids)
+	}
+
+	if err := s.DeleteFile(context.Background(), "avatars/user-123/photo"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("DeleteFile of a missing asset = %v, want ErrAssetNotFound", err)
+	}
+	if err := s.DeleteByURL(context.Background(), "avatars/user-123/photo"); err == nil {
+		t.Error("DeleteByURL accepted a public id")
+	}
+	if err := s.DeleteFile(context.Background(), ""); err == nil {
+		t.Error("DeleteFile accepted an empty public id")
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+	var refs []string
+	for i := range 150 {
+		publicID := fmt.Sprintf("gallery/%03d", i)
+		fake.put(ResourceImage, "upload", publicID)
+		refs = append(refs, publicID)
+	}
+	fake.put(ResourceVideo, "upload", "clips/intro")
+	fake.put(ResourceImage, "authenticated", "contracts/scan")
+	fake.failDelete["gallery/007"] = true
+	refs = append(refs,
+		"https://res.cloudinary.com/demo/video/upload/v1/clips/intro.mp4",
+		"https://res.cloudinary.com/demo/image/authenticated/s--AbCd1234--/v1/contracts/scan.jpg",
+		"gallery/missing",
+		"https://example.com/not-cloudinary.jpg",
+	)
+
+	failed, err := s.DeleteMany(context.Background(), refs)
+	wantFailed := []string{"https://example.com/not-cloudinary.jpg", "gallery/007", "gallery/missing"}
+	var deleteErr *DeleteError
+	if !errors.As(err, &deleteErr) || !slices.Equal(failed, wantFailed) || !slices.Equal(deleteErr.Failed, wantFailed) {
+		t.Fatalf("DeleteMany = %v, %v, want failures %v", failed, err, wantFailed)
+	}
+	if ids := fake.publicIDs(); !slices.Equal(ids, []string{"gallery/007"}) {
+		t.Errorf("assets = %v, want only the failed one left", ids)
+	}
+	var sizes []int
+	for _, batch := range fake.deleteBatches {
+		sizes = append(sizes, len(batch))
+	}
+	if !slices.Equal(sizes, []int{100, 51, 1, 1}) {
+		t.Errorf("delete batches = %v, want batches of at most 100 per resource and delivery type", sizes)
+	}
+
+	if failed, err := s.DeleteMany(context.Background(), []string{"gallery/007"}); err == nil || len(failed) != 1 {
+		t.Errorf("DeleteMany = %v, %v, want the failure reported", failed, err)
+	}
+	delete(fake.failDelete, "gallery/007")
+	if failed, err := s.DeleteMany(context.Background(), []string{"gallery/007"}); err != nil || failed != nil {
+		t.Errorf("DeleteMany = %v, %v", failed, err)
+	}
+}
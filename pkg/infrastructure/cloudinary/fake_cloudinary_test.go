@@ -0,0 +1,386 @@
+package cloudinary
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeAsset is an asset stored by fakeCloudinary
+type fakeAsset struct {
+	publicID     string
+	resourceType string
+	deliveryType string
+	data         []byte
+	tags         []string
+}
+
+// fakeCloudinary is an in-memory Cloudinary upload and admin API, serving the calls of cloudinaryService
+type fakeCloudinary struct {
+	mu sync.Mutex
+	// assets are keyed by "<resource type>/<delivery type>/<public id>"
+	assets map[string]*fakeAsset
+	// uploads are the parameters of the upload requests
+	uploads []map[string]string
+	// deleteBatches are the public ids of the Admin API delete requests
+	deleteBatches [][]string
+	// failDelete makes deletes of these public ids fail
+	failDelete map[string]bool
+	// uploadError makes uploads fail with this message
+	uploadError string
+}
+
+// newFakeCloudinary returns a fake and a service using it
+func newFakeCloudinary(t *testing.T) (*fakeCloudinary, *cloudinaryService) {
+	t.Helper()
+	fake := &fakeCloudinary{assets: map[string]*fakeAsset{}, failDelete: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := cloudinary.NewFromParams("demo", "key", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Config.API.UploadPrefix = server.URL
+	client.Upload.Config.API.UploadPrefix = server.URL
+	client.Admin.Config.API.UploadPrefix = server.URL
+	logger, _ := test.NewNullLogger()
+	return fake, NewCloudinaryService(client, "demo", logger, noop.NewTracerProvider()).(*cloudinaryService)
+}
+
+func assetKey(resourceType, deliveryType, publicID string) string {
+	return resourceType + "/" + deliveryType + "/" + publicID
+}
+
+// put stores an asset
+func (f *fakeCloudinary) put(resourceType, deliveryType, publicID string, tags ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.assets[assetKey(resourceType, deliveryType, publicID)] = &fakeAsset{
+		publicID: publicID, resourceType: resourceType, deliveryType: deliveryType, tags: tags,
+	}
+}
+
+// asset returns a stored asset
+func (f *fakeCloudinary) asset(resourceType, deliveryType, publicID string) (*fakeAsset, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	asset, ok := f.assets[assetKey(resourceType, deliveryType, publicID)]
+	return asset, ok
+}
+
+// publicIDs returns the sorted public ids of the stored assets
+func (f *fakeCloudinary) publicIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for _, asset := range f.assets {
+		ids = append(ids, asset.publicID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// lastUpload returns the parameters of the last upload request
+func (f *fakeCloudinary) lastUpload() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.uploads) == 0 {
+		return nil
+	}
+	return f.uploads[len(f.uploads)-1]
+}
+
+func (f *fakeCloudinary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v1_1/demo/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(rest, "/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "upload":
+		f.upload(w, r)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "destroy":
+		f.destroy(w, r, parts[0])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "tags":
+		f.removeTag(w, r, parts[0])
+	case r.Method == http.MethodDelete && len(parts) == 3 && parts[0] == "resources":
+		f.deleteAssets(w, r, parts[1], parts[2])
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "resources" && parts[2] == "tags":
+		f.assetsByTag(w, r, parts[1], parts[3])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// upload stores the file of a multipart or form upload request. f.mu must be held
+func (f *fakeCloudinary) upload(w http.ResponseWriter, r *http.Request) {
+	params := map[string]string{}
+	var data []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(10 << 20); err == nil {
+			for name, values := range r.MultipartForm.Value {
+				params[name] = values[0]
+			}
+			if file, _, err := r.FormFile("file"); err == nil {
+				data, _ = io.ReadAll(file)
+				file.Close()
+			}
+		}
+	} else {
+		for name, values := range formValues(r) {
+			params[name] = values[0]
+		}
+	}
+	f.uploads = append(f.uploads, params)
+	if f.uploadError != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": f.uploadError}})
+		return
+	}
+	if data == nil {
+		data = []byte(params["file"])
+	}
+
+	resourceType := params["resource_type"]
+	if resourceType == "" || resourceType == ResourceAuto {
+		resourceType = detectResourceType(params["file"], data)
+	}
+	deliveryType := params["type"]
+	if deliveryType == "" {
+		deliveryType = "upload"
+	}
+	publicID := params["public_id"]
+	if publicID == "" {
+		publicID = strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+	}
+	if params["folder"] != "" {
+		publicID = params["folder"] + "/" + publicID
+	}
+
+	key := assetKey(resourceType, deliveryType, publicID)
+	if existing, ok := f.assets[key]; ok && params["overwrite"] == "false" {
+		response := assetResponse(existing)
+		response["existing"] = true
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+	asset := &fakeAsset{publicID: publicID, resourceType: resourceType, deliveryType: deliveryType, data: data}
+	if params["tags"] != "" {
+		asset.tags = strings.Split(params["tags"], ",")
+	}
+	f.assets[key] = asset
+
+	response := assetResponse(asset)
+	response["existing"] = false
+	if resourceType == ResourceVideo {
+		response["duration"] = 12.5
+	}
+	if params["eager"] != "" {
+		var eager []map[string]string
+		for _, transformation := range strings.Split(params["eager"], "|") {
+			eager = append(eager, map[string]string{
+				"transformation": transformation,
+				"secure_url":     deliveryURL(resourceType, deliveryType, transformation+"/"+publicID),
+			})
+		}
+		response["eager"] = eager
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// formValues parses a URL encoded body, the SDK sends it without Content-Type
+func formValues(r *http.Request) url.Values {
+	body, _ := io.ReadAll(r.Body)
+	values, _ := url.ParseQuery(string(body))
+	return values
+}
+
+// detectResourceType guesses the resource type of an "auto" upload from the URL or content
+func detectResourceType(file string, data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF")) || strings.HasSuffix(file, ".pdf"):
+		return ResourceRaw
+	case strings.HasSuffix(file, ".mp4"):
+		return ResourceVideo
+	}
+	return ResourceImage
+}
+
+// destroy deletes one asset. f.mu must be held
+func (f *fakeCloudinary) destroy(w http.ResponseWriter, r *http.Request, resourceType string) {
+	form := formValues(r)
+	deliveryType := form.Get("type")
+	if deliveryType == "" {
+		deliveryType = "upload"
+	}
+	key := assetKey(resourceType, deliveryType, form.Get("public_id"))
+	if _, ok := f.assets[key]; !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"result": "not found"})
+		return
+	}
+	delete(f.assets, key)
+	writeJSON(w, http.StatusOK, map[string]string{"result": "ok"})
+}
+
+// removeTag removes a tag from assets. f.mu must be held
+func (f *fakeCloudinary) removeTag(w http.ResponseWriter, r *http.Request, resourceType string) {
+	form := formValues(r)
+	if form.Get("command") != "remove" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": "unsupported tag command"}})
+		return
+	}
+	deliveryType := form.Get("type")
+	if deliveryType == "" {
+		deliveryType = "upload"
+	}
+	tag := form.Get("tag")
+	var updated []string
+	for i := 0; form.Has("public_ids[" + strconv.Itoa(i) + "]"); i++ {
+		publicID := form.Get("public_ids[" + strconv.Itoa(i) + "]")
+		if asset, ok := f.assets[assetKey(resourceType, deliveryType, publicID)]; ok {
+			asset.tags = slices.DeleteFunc(asset.tags, func(t string) bool { return t == tag })
+			updated = append(updated, publicID)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"public_ids": updated})
+}
+
+// deleteAssets deletes up to 100 assets of a resource and delivery type. f.mu must be held
+func (f *fakeCloudinary) deleteAssets(w http.ResponseWriter, r *http.Request, resourceType, deliveryType string) {
+	var body struct {
+		PublicIDs string `json:"public_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": err.Error()}})
+		return
+	}
+	publicIDs := strings.Split(body.PublicIDs, ",")
+	f.deleteBatches = append(f.deleteBatches, publicIDs)
+	if len(publicIDs) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": "too many public ids"}})
+		return
+	}
+	deleted := map[string]string{}
+	for _, publicID := range publicIDs {
+		key := assetKey(resourceType, deliveryType, publicID)
+		_, ok := f.assets[key]
+		switch {
+		case f.failDelete[publicID]:
+			deleted[publicID] = "error"
+		case !ok:
+			deleted[publicID] = "not_found"
+		default:
+			delete(f.assets, key)
+			deleted[publicID] = "deleted"
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": deleted})
+}
+
+// assetsByTag lists the assets of a resource type with a tag, max_results per page. f.mu must be held
+func (f *fakeCloudinary) assetsByTag(w http.ResponseWriter, r *http.Request, resourceType, tag string) {
+	var matching []*fakeAsset
+	for _, asset := range f.assets {
+		if asset.resourceType == resourceType && slices.Contains(asset.tags, tag) {
+			matching = append(matching, asset)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].publicID < matching[j].publicID })
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("next_cursor"))
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("max_results"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+	end := min(start+pageSize, len(matching))
+	resources := []map[string]any{}
+	for _, asset := range matching[start:end] {
+		resources = append(resources, assetResponse(asset))
+	}
+	nextCursor := ""
+	if end < len(matching) {
+		nextCursor = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"resources": resources, "next_cursor": nextCursor})
+}
+
+// assetResponse returns the JSON of an asset in upload and admin responses
+func assetResponse(asset *fakeAsset) map[string]any {
+	format := "jpg"
+	switch asset.resourceType {
+	case ResourceRaw:
+		format = ""
+	case ResourceVideo:
+		format = "mp4"
+	}
+	name := asset.publicID
+	if format != "" {
+		name += "." + format
+	}
+	return map[string]any{
+		"public_id":     asset.publicID,
+		"resource_type": asset.resourceType,
+		"type":          asset.deliveryType,
+		"format":        format,
+		"bytes":         len(asset.data),
+		"width":         640,
+		"height":        480,
+		"tags":          asset.tags,
+		"secure_url":    deliveryURL(asset.resourceType, asset.deliveryType, "v1/"+name),
+	}
+}
+
+// deliveryURL returns a delivery URL of the demo cloud
+func deliveryURL(resourceType, deliveryType, rest string) string {
+	return "https://res.cloudinary.com/demo/" + path.Join(resourceType, deliveryType, rest)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// fileHeader returns the multipart.FileHeader of an uploaded file
+func fileHeader(t *testing.T, filename string, data []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	header.Set("Content-Type", "application/octet-stream")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := req.ParseMultipartForm(10 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return req.MultipartForm.File["file"][0]
+}
@@ -0,0 +1,141 @@
+package cloudinary
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokenKey is the hex auth token key of the signed URL tests
+const tokenKey = "00112233445566778899aabbccddeeff"
+
+// urlSignature is the "s--<signature>--" component Cloudinary checks for toSign, the transformation
+// and public ID of the URL
+func urlSignature(toSign, secret string) string {
+	sum := sha1.Sum([]byte(toSign + secret))
+	return "s--" + base64.RawURLEncoding.EncodeToString(sum[:])[:8] + "--"
+}
+
+func TestPrivateUpload(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+
+	result, err := s.UploadReader(context.Background(), bytes.NewReader([]byte("%PDF-1.4")), UploadOptions{
+		Folder: "contracts", Filename: "contract.pdf", ResourceType: ResourceRaw, Private: true, UniqueID: UniqueIDNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params := fake.lastUpload(); params["type"] != "authenticated" {
+		t.Errorf("upload params = %v, want type authenticated", params)
+	}
+	if _, ok := fake.asset(ResourceRaw, "authenticated", result.PublicID); !ok {
+		t.Errorf("asset %q not stored as authenticated", result.PublicID)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+
+	raw, err := s.SignedURL("contracts/scan", map[string]interface{}{"width": 200}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := urlSignature("w_200/contracts/scan", "secret")
+	want := regexp.MustCompile(`^https://res\.cloudinary\.com/demo/image/authenticated/` + regexp.QuoteMeta(signature) + `/w_200/(v1/)?contracts/scan$`)
+	if !want.MatchString(raw) {
+		t.Errorf("SignedURL = %q, want signature %s", raw, signature)
+	}
+
+	// The signature covers the transformation, clients cannot request another size with it
+	other, err := s.SignedURL("contracts/scan", map[string]interface{}{"width": 2000}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(other, signature) {
+		t.Errorf("SignedURL of another transformation reused signature %s", signature)
+	}
+
+	if _, err := s.SignedURL("contracts/scan", map[string]interface{}{"size": "xl"}, time.Time{}); err == nil {
+		t.Error("SignedURL accepted an unknown transformation")
+	}
+}
+
+func TestSignedURLExpiry(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+	expiresAt := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+
+	if _, err := s.SignedURL("contracts/scan", nil, expiresAt); !errors.Is(err, ErrAuthTokenKeyRequired) {
+		t.Fatalf("SignedURL without token key = %v, want ErrAuthTokenKeyRequired", err)
+	}
+
+	s.client.Config.AuthToken.Key = tokenKey
+	raw, err := s.SignedURL("contracts/scan", map[string]interface{}{"width": 200}, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(parsed.Path, "/s--") {
+		t.Errorf("SignedURL = %q, want the auth token instead of the URL signature", raw)
+	}
+	token, ok := strings.CutPrefix(parsed.RawQuery, "__cld_token__=")
+	if !ok {
+		t.Fatalf("SignedURL = %q, want a __cld_token__ query", raw)
+	}
+	exp := "exp=" + strconv.FormatInt(expiresAt.Unix(), 10)
+	key, _ := hex.DecodeString(tokenKey)
+	mac := hmac.New(sha256.New, key)
+	// The token signs the path with "/" percent-encoded in lower case
+	mac.Write([]byte(exp + "~url=" + strings.ReplaceAll(parsed.Path, "/", "%2f")))
+	if want := exp + "~hmac=" + hex.EncodeToString(mac.Sum(nil)); token != want {
+		t.Errorf("token = %q, want %q", token, want)
+	}
+
+	if _, err := s.SignedURL("contracts/scan", nil, time.Now().Add(-time.Minute)); err == nil {
+		t.Error("SignedURL accepted an expiry in the past")
+	}
+}
+
+func TestSignedDownloadURL(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+	expiresAt := time.Unix(1893456000, 0)
+
+	raw, err := s.SignedDownloadURL("contracts/contract.pdf", "pdf", expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(parsed.Path, "/v1_1/demo/raw/download") {
+		t.Errorf("path = %q, want the raw download API", parsed.Path)
+	}
+	query := parsed.Query()
+	toSign := "expires_at=1893456000&format=pdf&public_id=contracts/contract.pdf&timestamp=" + query.Get("timestamp") + "&type=authenticated"
+	sum := sha1.Sum([]byte(toSign + "secret"))
+	if query.Get("signature") != hex.EncodeToString(sum[:]) || query.Get("api_key") != "key" {
+		t.Errorf("query = %v, want the signature of %q", query, toSign)
+	}
+
+	if _, err := s.SignedDownloadURL("contracts/contract.pdf", "pdf", time.Time{}); err == nil {
+		t.Error("SignedDownloadURL accepted a zero expiry")
+	}
+	s.client.Config.Cloud.APISecret = ""
+	if _, err := s.SignedDownloadURL("contracts/contract.pdf", "pdf", expiresAt); err == nil {
+		t.Error("SignedDownloadURL signed without an api secret")
+	}
+}
@@ -0,0 +1,154 @@
+package cloudinary
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TemporaryTag is the tag of Temporary uploads, their expiry is a second "temporary_expires_<unix time>" tag
+const TemporaryTag = "temporary"
+
+// DefaultTemporaryTTL is the lifetime of Temporary uploads
+const DefaultTemporaryTTL = 24 * time.Hour
+
+const temporaryExpiresTagPrefix = "temporary_expires_"
+
+// listTemporaryPageSize is the largest page of the Admin API
+const listTemporaryPageSize = 500
+
+// temporaryTags returns the tags of a Temporary upload made at now
+func temporaryTags(now time.Time, ttl time.Duration) api.CldAPIArray {
+	if ttl <= 0 {
+		ttl = DefaultTemporaryTTL
+	}
+	return api.CldAPIArray{TemporaryTag, temporaryExpiresTagPrefix + strconv.FormatInt(now.Add(ttl).Unix(), 10)}
+}
+
+// temporaryExpiry returns the expiry of the tags of a Temporary asset
+func temporaryExpiry(tags []string) (time.Time, bool) {
+	for _, tag := range tags {
+		if unix, ok := strings.CutPrefix(tag, temporaryExpiresTagPrefix); ok {
+			if seconds, err := strconv.ParseInt(unix, 10, 64); err == nil {
+				return time.Unix(seconds, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *cloudinaryService) ListTemporaryAssets(ctx context.Context, expiredBefore time.Time) ([]string, error) {
+	ctx, span := s.trace(ctx, "cloudinary.list-temporary-assets")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cloudinary.expired_before", expiredBefore.UTC().Format(time.RFC3339)))
+
+	withTags := true
+	expired := []string{}
+	var err error
+	for _, resourceType := range []string{ResourceImage, ResourceVideo, ResourceRaw} {
+		nextCursor := ""
+		for {
+			var result *admin.AssetsResult
+			result, err = s.client.Admin.AssetsByTag(ctx, admin.AssetsByTagParams{
+				AssetType:  api.AssetType(resourceType),
+				Tag:        TemporaryTag,
+				Tags:       &withTags,
+				MaxResults: listTemporaryPageSize,
+				NextCursor: nextCursor,
+			})
+			if err == nil && result.Error.Message != "" {
+				err = errors.New(result.Error.Message)
+			}
+			if err != nil {
+				break
+			}
+			for _, asset := range result.Assets {
+				if expiresAt, ok := temporaryExpiry(asset.Tags); ok && expiresAt.Before(expiredBefore) {
+					// Delivery URLs keep the resource and delivery type DeleteMany needs
+					expired = append(expired, asset.SecureURL)
+				}
+			}
+			if nextCursor = result.NextCursor; nextCursor == "" {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to list temporary files from Cloudinary: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("cloudinary.count", len(expired)))
+	span.SetStatus(codes.Ok, "Temporary files listed successfully")
+	return expired, nil
+}
+
+func (s *cloudinaryService) MarkPermanent(ctx context.Context, publicID string) error {
+	ctx, span := s.trace(ctx, "cloudinary.mark-permanent")
+	defer span.End()
+
+	asset, err := ParseAssetRef(publicID)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("cloudinary.public_id", asset.PublicID),
+			attribute.String("cloudinary.resource_type", asset.ResourceType),
+		)
+		var result *uploader.RemoveTagResult
+		result, err = s.client.Upload.RemoveTag(ctx, uploader.RemoveTagParams{
+			Tag:          TemporaryTag,
+			PublicIDs:    []string{asset.PublicID},
+			Type:         asset.DeliveryType,
+			ResourceType: asset.ResourceType,
+		})
+		if err == nil && result.Error.Message != "" {
+			err = errors.New(result.Error.Message)
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to mark file permanent in Cloudinary: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "File marked permanent successfully")
+	s.logger.Infof("Successfully marked file permanent in Cloudinary: %s", asset.PublicID)
+	return nil
+}
+
+type janitorStorage struct {
+	service CloudinaryService
+}
+
+// NewJanitorStorage returns the storage.FileStorage of the Temporary uploads of service, for
+// storage.NewJanitor
+func NewJanitorStorage(service CloudinaryService) storage.FileStorage {
+	return &janitorStorage{service: service}
+}
+
+func (j *janitorStorage) ListExpired(ctx context.Context, before time.Time) ([]string, error) {
+	return j.service.ListTemporaryAssets(ctx, before)
+}
+
+func (j *janitorStorage) Delete(ctx context.Context, refs []string) ([]string, error) {
+	return j.service.DeleteMany(ctx, refs)
+}
+
+func (j *janitorStorage) MarkPermanent(ctx context.Context, publicID string) error {
+	return j.service.MarkPermanent(ctx, publicID)
+}
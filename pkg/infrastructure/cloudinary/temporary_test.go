@@ -0,0 +1,99 @@
+package cloudinary
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/storage"
+)
+
+func TestTemporaryTags(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tags := temporaryTags(now, 0)
+	if len(tags) != 2 || tags[0] != TemporaryTag {
+		t.Fatalf("temporaryTags = %v", tags)
+	}
+	if expiresAt, ok := temporaryExpiry(tags); !ok || !expiresAt.Equal(now.Add(DefaultTemporaryTTL)) {
+		t.Errorf("temporaryExpiry = %s, %v, want %s", expiresAt, ok, now.Add(DefaultTemporaryTTL))
+	}
+	if expiresAt, ok := temporaryExpiry(temporaryTags(now, time.Hour)); !ok || !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("temporaryExpiry with a ttl = %s, %v", expiresAt, ok)
+	}
+	for _, tags := range [][]string{nil, {TemporaryTag}, {TemporaryTag, "temporary_expires_soon"}} {
+		if _, ok := temporaryExpiry(tags); ok {
+			t.Errorf("temporaryExpiry(%v) found an expiry", tags)
+		}
+	}
+}
+
+func TestJanitorCleansUpExpiredTemporaryAssets(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+	ctx := context.Background()
+	start := time.Now()
+
+	upload := func(name string, data []byte, opts UploadOptions) UploadResult {
+		t.Helper()
+		opts.Folder, opts.Filename, opts.UniqueID = "uploads", name, UniqueIDNone
+		result, err := s.UploadReader(ctx, bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	upload("orphan.png", smallPNG(t), UploadOptions{Temporary: true, TemporaryTTL: time.Hour})
+	if tags := fake.lastUpload()["tags"]; tags != TemporaryTag+",temporary_expires_"+strconv.FormatInt(start.Add(time.Hour).Unix(), 10) &&
+		tags != TemporaryTag+",temporary_expires_"+strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) {
+		t.Errorf("upload tags = %q", tags)
+	}
+	upload("orphan.pdf", []byte("%PDF-1.4 draft"), UploadOptions{Temporary: true, TemporaryTTL: time.Hour})
+	attached := upload("attached.png", smallPNG(t), UploadOptions{Temporary: true, TemporaryTTL: 48 * time.Hour})
+	upload("permanent.png", smallPNG(t), UploadOptions{})
+	// More expired assets than an Admin API page
+	expiredTag := "temporary_expires_" + strconv.FormatInt(start.Add(-time.Hour).Unix(), 10)
+	for i := range listTemporaryPageSize + 1 {
+		fake.put(ResourceImage, "upload", fmt.Sprintf("bulk/%03d", i), TemporaryTag, expiredTag)
+	}
+
+	now := start.Add(2 * time.Hour)
+	logger, _ := test.NewNullLogger()
+	janitor := storage.NewJanitor(NewJanitorStorage(s), logger, storage.WithClock(func() time.Time { return now }))
+
+	deleted, err := janitor.RunCleanup(ctx, 0)
+	if err != nil || deleted != listTemporaryPageSize+3 {
+		t.Fatalf("RunCleanup = %d, %v, want %d deleted", deleted, err, listTemporaryPageSize+3)
+	}
+	if got, want := fake.publicIDs(), []string{"uploads/attached", "uploads/permanent"}; !slices.Equal(got, want) {
+		t.Errorf("assets = %v, want %v", got, want)
+	}
+	for _, batch := range fake.deleteBatches {
+		if len(batch) > 100 {
+			t.Errorf("delete batch of %d assets, want at most 100", len(batch))
+		}
+	}
+
+	if err := janitor.MarkPermanent(ctx, attached.SecureURL); err != nil {
+		t.Fatal(err)
+	}
+	if asset, _ := fake.asset(ResourceImage, "upload", "uploads/attached"); slices.Contains(asset.tags, TemporaryTag) {
+		t.Errorf("tags = %v, want the temporary tag removed", asset.tags)
+	}
+
+	now = start.Add(72 * time.Hour)
+	if deleted, err := janitor.RunCleanup(ctx, 0); err != nil || deleted != 0 {
+		t.Errorf("RunCleanup after MarkPermanent = %d, %v, want nothing deleted", deleted, err)
+	}
+}
+
+func TestMarkPermanentInvalidRef(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+	if err := s.MarkPermanent(context.Background(), ""); err == nil {
+		t.Error("MarkPermanent of an empty public ID succeeded")
+	}
+}
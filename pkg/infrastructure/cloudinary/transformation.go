@@ -0,0 +1,149 @@
+package cloudinary
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Transformation is one step of a Cloudinary transformation chain, e.g.
+// {"width": 200, "height": 200, "crop": "fill", "quality": "auto", "format": "auto"}. Keys are the
+// parameter names or their URL abbreviations ("w", "h", "c"...)
+type Transformation map[string]interface{}
+
+// transformationParam is a supported transformation parameter
+type transformationParam struct {
+	name string
+	abbr string
+}
+
+// transformationParams are the supported parameters, in their rendering order
+var transformationParams = []transformationParam{
+	{"width", "w"},
+	{"height", "h"},
+	{"aspect_ratio", "ar"},
+	{"crop", "c"},
+	{"gravity", "g"},
+	{"start_offset", "so"},
+	{"quality", "q"},
+	{"format", "f"},
+	{"dpr", "dpr"},
+	{"radius", "r"},
+	{"angle", "a"},
+	{"effect", "e"},
+	{"opacity", "o"},
+	{"background", "b"},
+}
+
+// Render returns the URL path segment of t, e.g. "w_200,h_200,c_fill,q_auto,f_auto"
+func (t Transformation) Render() (string, error) {
+	values := make(map[string]string, len(t))
+	for key, value := range t {
+		param, ok := lookupTransformationParam(key)
+		if !ok {
+			return "", fmt.Errorf("unknown cloudinary transformation %q, supported: %s", key, strings.Join(supportedTransformations(), ", "))
+		}
+		rendered, err := renderTransformationValue(value)
+		if err != nil {
+			return "", fmt.Errorf("cloudinary transformation %q: %w", key, err)
+		}
+		if _, duplicate := values[param.abbr]; duplicate {
+			return "", fmt.Errorf("cloudinary transformation %q is set twice", param.name)
+		}
+		values[param.abbr] = rendered
+	}
+
+	parts := make([]string, 0, len(values))
+	for _, param := range transformationParams {
+		if value := values[param.abbr]; value != "" {
+			parts = append(parts, param.abbr+"_"+value)
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// renderValid renders the valid parameters of t and returns the skipped keys: unknown keys, invalid values
+// and parameters set again by abbreviation
+func (t Transformation) renderValid() (string, []string) {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	valid := make(Transformation, len(t))
+	seen := make(map[string]bool, len(t))
+	var skipped []string
+	for _, key := range keys {
+		param, ok := lookupTransformationParam(key)
+		if ok {
+			_, err := renderTransformationValue(t[key])
+			ok = err == nil && !seen[param.abbr]
+		}
+		if !ok {
+			skipped = append(skipped, key)
+			continue
+		}
+		seen[param.abbr] = true
+		valid[key] = t[key]
+	}
+	segment, _ := valid.Render()
+	return segment, skipped
+}
+
+// renderChain renders chained transformations separated by "/", skipping empty steps
+func renderChain(chain []Transformation) (string, error) {
+	segments := make([]string, 0, len(chain))
+	for _, transformation := range chain {
+		segment, err := transformation.Render()
+		if err != nil {
+			return "", err
+		}
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+func lookupTransformationParam(key string) (transformationParam, bool) {
+	for _, param := range transformationParams {
+		if key == param.name || key == param.abbr {
+			return param, true
+		}
+	}
+	return transformationParam{}, false
+}
+
+func renderTransformationValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.ContainsAny(v, ",/ ") {
+			return "", fmt.Errorf("invalid value %q", v)
+		}
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// supportedTransformations lists the accepted parameter names
+func supportedTransformations() []string {
+	names := make([]string, 0, len(transformationParams))
+	for _, param := range transformationParams {
+		names = append(names, param.name)
+	}
+	sort.Strings(names)
+	return names
+}
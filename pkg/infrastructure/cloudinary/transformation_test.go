@@ -0,0 +1,93 @@
+package cloudinary
+
+import (
+	"testing"
+)
+
+const imageBase = "https://res.cloudinary.com/demo/image/upload/"
+
+func TestTransformationRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       Transformation
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", t: Transformation{}, want: ""},
+		{name: "thumbnail", t: Transformation{"format": "auto", "quality": "auto", "crop": "fill", "height": 200, "width": 200}, want: "w_200,h_200,c_fill,q_auto,f_auto"},
+		{name: "abbreviations", t: Transformation{"w": 300, "c": "scale", "g": "face"}, want: "w_300,c_scale,g_face"},
+		{name: "numbers", t: Transformation{"aspect_ratio": 1.5, "dpr": float32(2), "radius": int64(10), "so": 2}, want: "ar_1.5,so_2,dpr_2,r_10"},
+		{name: "nil value", t: Transformation{"width": 100, "height": nil}, want: "w_100"},
+		{name: "effect", t: Transformation{"effect": "blur:300", "opacity": 50, "background": "rgb:ffffff", "angle": 90}, want: "a_90,e_blur:300,o_50,b_rgb:ffffff"},
+		{name: "unknown key", t: Transformation{"width": 100, "size": 3}, wantErr: true},
+		{name: "separator in value", t: Transformation{"crop": "fill,w_1"}, wantErr: true},
+		{name: "path in value", t: Transformation{"crop": "../x"}, wantErr: true},
+		{name: "unsupported type", t: Transformation{"width": []int{1}}, wantErr: true},
+		{name: "name and abbreviation", t: Transformation{"width": 100, "w": 200}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.t.Render()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Render = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetImageURL(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+	tests := []struct {
+		name            string
+		transformations map[string]interface{}
+		want            string
+	}{
+		{name: "none", want: imageBase + "avatars/u1"},
+		{name: "resize", transformations: map[string]interface{}{"width": 200, "height": 200, "crop": "fill"}, want: imageBase + "w_200,h_200,c_fill/avatars/u1"},
+		{name: "unknown key skipped", transformations: map[string]interface{}{"width": 200, "size": "xl"}, want: imageBase + "w_200/avatars/u1"},
+		{name: "invalid value skipped", transformations: map[string]interface{}{"width": 200, "crop": "fill/x"}, want: imageBase + "w_200/avatars/u1"},
+		{name: "abbreviation duplicate skipped", transformations: map[string]interface{}{"w": 100, "width": 200}, want: imageBase + "w_100/avatars/u1"},
+		{name: "all invalid", transformations: map[string]interface{}{"size": "xl"}, want: imageBase + "avatars/u1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.GetImageURL("avatars/u1", tt.transformations); got != tt.want {
+				t.Errorf("GetImageURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildImageURL(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+
+	got, err := s.BuildImageURL("avatars/u1",
+		Transformation{"width": 400, "crop": "scale"},
+		Transformation{},
+		Transformation{"radius": "max", "format": "png"},
+	)
+	if err != nil || got != imageBase+"w_400,c_scale/f_png,r_max/avatars/u1" {
+		t.Errorf("BuildImageURL = %q, %v", got, err)
+	}
+	if got, err := s.BuildImageURL("avatars/u1"); err != nil || got != imageBase+"avatars/u1" {
+		t.Errorf("BuildImageURL without chain = %q, %v", got, err)
+	}
+	if _, err := s.BuildImageURL("avatars/u1", Transformation{"width": 100}, Transformation{"size": "xl"}); err == nil {
+		t.Error("BuildImageURL accepted an unknown transformation")
+	}
+}
+
+func TestThumbnailAndSrcSet(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+
+	if got, want := s.ThumbnailURL("avatars/u1", 64, 48), imageBase+"w_64,h_48,c_fill,g_auto,q_auto,f_auto/avatars/u1"; got != want {
+		t.Errorf("ThumbnailURL = %q, want %q", got, want)
+	}
+	want := imageBase + "w_320,c_scale,q_auto,f_auto/avatars/u1 320w, " + imageBase + "w_640,c_scale,q_auto,f_auto/avatars/u1 640w"
+	if got := s.ResponsiveSrcSet("avatars/u1", []int{320, 0, 640, -1}); got != want {
+		t.Errorf("ResponsiveSrcSet = %q, want %q", got, want)
+	}
+	if got := s.ResponsiveSrcSet("avatars/u1", nil); got != "" {
+		t.Errorf("ResponsiveSrcSet without widths = %q", got)
+	}
+}
@@ -0,0 +1,208 @@
+package cloudinary
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/google/uuid"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrPublicIDExists is returned when an upload without Overwrite targets the public ID of an existing asset
+var ErrPublicIDExists = errors.New("cloudinary asset with this public id already exists")
+
+// UniqueIDMode selects the suffix making public IDs unique
+type UniqueIDMode string
+
+const (
+	// UniqueIDUUID appends a random UUID, the default
+	UniqueIDUUID UniqueIDMode = "uuid"
+	// UniqueIDTimestamp appends the upload time (yyyymmddhhmmss), uploads of the same name within a
+	// second collide
+	UniqueIDTimestamp UniqueIDMode = "timestamp"
+	// UniqueIDNone uses the sanitized filename as is
+	UniqueIDNone UniqueIDMode = "none"
+)
+
+// Resource types of uploads
+const (
+	ResourceAuto  = "auto"
+	ResourceImage = "image"
+	ResourceVideo = "video"
+	ResourceRaw   = "raw"
+)
+
+// UploadOptions controls where and how uploads are stored
+type UploadOptions struct {
+	// Folder is the folder of the asset. The folder argument of UploadFile takes precedence when set
+	Folder string
+	// Filename names the asset with KeepOriginalFilename and UniqueIDNone. UploadFile uses the name of the
+	// uploaded file and UploadFromURL the last path segment of the URL when empty
+	Filename string
+	// ResourceType is one of the Resource* constants, ResourceAuto when empty. PDFs and other documents
+	// are raw resources
+	ResourceType string
+	// Eager are transformations generated at upload time, e.g. a video poster
+	// Transformation{"start_offset": 2, "format": "jpg"}
+	Eager []Transformation
+	// UniqueID is the suffix of public IDs, UniqueIDUUID when empty
+	UniqueID UniqueIDMode
+	// KeepOriginalFilename starts public IDs with the sanitized filename, "<name>-<suffix>". Otherwise the
+	// public ID is the suffix alone, or the filename with UniqueIDNone
+	KeepOriginalFilename bool
+	// Overwrite replaces an existing asset with the same public ID. Without it such uploads fail with
+	// ErrPublicIDExists
+	Overwrite bool
+	// Private stores the asset as "authenticated", delivered only through SignedURL and SignedDownloadURL
+	Private bool
+	// Temporary tags the asset as temporary, a storage janitor deletes it after TemporaryTTL unless
+	// MarkPermanent is called
+	Temporary bool
+	// TemporaryTTL is the lifetime of Temporary assets, DefaultTemporaryTTL when zero
+	TemporaryTTL time.Duration
+}
+
+// UploadResult is a stored asset. Store PublicID to delete or transform the asset later
+type UploadResult struct {
+	PublicID     string `json:"public_id"`
+	SecureURL    string `json:"secure_url"`
+	ResourceType string `json:"resource_type"`
+	Bytes        int    `json:"bytes"`
+	Format       string `json:"format,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	// Duration is the length of videos and audio in seconds
+	Duration float64 `json:"duration,omitempty"`
+	// EagerURLs are the secure URLs of the Eager transformations
+	EagerURLs []string `json:"eager_urls,omitempty"`
+}
+
+// SanitizePublicID turns a filename into a public ID part: directories and extension are dropped,
+// accents removed and runs of characters other than ASCII letters, digits and "_" replaced by "-"
+func SanitizePublicID(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.TrimSuffix(filename, path.Ext(filename))
+	filename = strings.NewReplacer("đ", "d", "Đ", "D").Replace(filename)
+	if ascii, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), filename); err == nil {
+		filename = ascii
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range filename {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	publicID := strings.Trim(b.String(), "-")
+	if publicID == "" {
+		return "file"
+	}
+	return publicID
+}
+
+// publicID returns the public ID of an upload of filename, without folder
+func (o UploadOptions) publicID(filename string, now time.Time) string {
+	var suffix string
+	switch o.UniqueID {
+	case UniqueIDNone:
+		return SanitizePublicID(filename)
+	case UniqueIDTimestamp:
+		suffix = now.UTC().Format("20060102150405")
+	default:
+		suffix = uuid.NewString()
+	}
+	if !o.KeepOriginalFilename {
+		return suffix
+	}
+	return SanitizePublicID(filename) + "-" + suffix
+}
+
+// uploadParams returns the Cloudinary parameters of an upload of filename
+func (o UploadOptions) uploadParams(filename string, now time.Time) (uploader.UploadParams, error) {
+	eager, err := renderEager(o.Eager)
+	if err != nil {
+		return uploader.UploadParams{}, err
+	}
+	resourceType := o.ResourceType
+	if resourceType == "" {
+		resourceType = ResourceAuto // Auto-detect resource type
+	}
+	overwrite := o.Overwrite
+	params := uploader.UploadParams{
+		PublicID:     o.publicID(filename, now),
+		Folder:       o.Folder,
+		ResourceType: resourceType,
+		Overwrite:    &overwrite,
+		Eager:        eager,
+	}
+	if o.Private {
+		params.Type = api.Authenticated
+	}
+	if o.Temporary {
+		params.Tags = temporaryTags(now, o.TemporaryTTL)
+	}
+	if overwrite {
+		// Purge CDN copies of the replaced asset
+		invalidate := true
+		params.Invalidate = &invalidate
+	}
+	return params, nil
+}
+
+// renderEager renders eager transformations separated by "|"
+func renderEager(eager []Transformation) (string, error) {
+	rendered := make([]string, 0, len(eager))
+	for _, transformation := range eager {
+		segment, err := transformation.Render()
+		if err != nil {
+			return "", err
+		}
+		if segment != "" {
+			rendered = append(rendered, segment)
+		}
+	}
+	return strings.Join(rendered, "|"), nil
+}
+
+// newUploadResult converts the SDK result
+func newUploadResult(result *uploader.UploadResult) UploadResult {
+	uploaded := UploadResult{
+		PublicID:     result.PublicID,
+		SecureURL:    result.SecureURL,
+		ResourceType: result.ResourceType,
+		Bytes:        result.Bytes,
+		Format:       result.Format,
+		Width:        result.Width,
+		Height:       result.Height,
+	}
+	if response, ok := result.Response.(*map[string]interface{}); ok && response != nil {
+		uploaded.Duration, _ = (*response)["duration"].(float64)
+	}
+	for _, eager := range result.Eager {
+		uploaded.EagerURLs = append(uploaded.EagerURLs, eager.SecureURL)
+	}
+	return uploaded
+}
+
+// existingAsset reports whether Cloudinary kept an existing asset instead of storing the upload
+func existingAsset(result *uploader.UploadResult) bool {
+	// The SDK stores the raw response as a pointer to the decoded JSON
+	response, ok := result.Response.(*map[string]interface{})
+	if !ok || response == nil {
+		return false
+	}
+	existing, _ := (*response)["existing"].(bool)
+	return existing
+}
@@ -0,0 +1,127 @@
+package cloudinary
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var uuidPattern = `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
+
+func TestUploadFileUniqueIDs(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+	ctx := context.Background()
+
+	first, err := s.UploadFile(ctx, fileHeader(t, "avatar.png", []byte("first")), "avatars", UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.UploadFile(ctx, fileHeader(t, "avatar.png", []byte("second")), "avatars", UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := regexp.MustCompile(`^avatars/` + uuidPattern + `$`)
+	if !pattern.MatchString(first.PublicID) || !pattern.MatchString(second.PublicID) || first.PublicID == second.PublicID {
+		t.Fatalf("public ids = %q, %q, want distinct UUIDs", first.PublicID, second.PublicID)
+	}
+	for _, result := range []UploadResult{first, second} {
+		asset, ok := fake.asset(ResourceImage, "upload", result.PublicID)
+		if !ok || result.SecureURL != "https://res.cloudinary.com/demo/image/upload/v1/"+result.PublicID+".jpg" {
+			t.Errorf("result = %+v, stored %v", result, ok)
+		}
+		if ok && result.Bytes != len(asset.data) {
+			t.Errorf("bytes = %d, want %d", result.Bytes, len(asset.data))
+		}
+	}
+	if params := fake.lastUpload(); params["overwrite"] != "false" || params["resource_type"] != ResourceAuto {
+		t.Errorf("upload params = %v, want no overwrite and auto resource type", params)
+	}
+}
+
+func TestUploadKeepOriginalFilename(t *testing.T) {
+	_, s := newFakeCloudinary(t)
+
+	result, err := s.UploadFile(context.Background(), fileHeader(t, "../../Ảnh đại diện (1).PNG", []byte("png")), "avatars",
+		UploadOptions{KeepOriginalFilename: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^avatars/Anh-dai-dien-1-` + uuidPattern + `$`).MatchString(result.PublicID) {
+		t.Errorf("public id = %q, want the sanitized filename with a UUID", result.PublicID)
+	}
+}
+
+func TestUploadCollisions(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+	ctx := context.Background()
+	opts := UploadOptions{Folder: "avatars", Filename: "avatar.png", UniqueID: UniqueIDNone}
+
+	if _, err := s.UploadReader(ctx, bytes.NewReader([]byte("first")), opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UploadReader(ctx, bytes.NewReader([]byte("second")), opts); !errors.Is(err, ErrPublicIDExists) {
+		t.Fatalf("second upload = %v, want ErrPublicIDExists", err)
+	}
+	if asset, _ := fake.asset(ResourceImage, "upload", "avatars/avatar"); string(asset.data) != "first" {
+		t.Errorf("stored %q, want the first upload kept", asset.data)
+	}
+
+	opts.Overwrite = true
+	if _, err := s.UploadReader(ctx, bytes.NewReader([]byte("third")), opts); err != nil {
+		t.Fatal(err)
+	}
+	if asset, _ := fake.asset(ResourceImage, "upload", "avatars/avatar"); string(asset.data) != "third" {
+		t.Errorf("stored %q, want the overwrite", asset.data)
+	}
+	if params := fake.lastUpload(); params["overwrite"] != "true" || params["invalidate"] != "true" {
+		t.Errorf("upload params = %v, want overwrite with CDN invalidation", params)
+	}
+
+	fake.uploadError = "Invalid image file"
+	if _, err := s.UploadReader(ctx, bytes.NewReader([]byte("bad")), UploadOptions{}); err == nil || err.Error() != "Invalid image file" {
+		t.Errorf("upload = %v, want the Cloudinary error", err)
+	}
+}
+
+func TestUploadOptionsPublicID(t *testing.T) {
+	now := time.Date(2024, 3, 5, 7, 8, 9, 0, time.FixedZone("ICT", 7*3600))
+	tests := []struct {
+		name string
+		opts UploadOptions
+		want string
+	}{
+		{name: "uuid", opts: UploadOptions{}, want: `^` + uuidPattern + `$`},
+		{name: "uuid with filename", opts: UploadOptions{KeepOriginalFilename: true}, want: `^my-photo-` + uuidPattern + `$`},
+		{name: "timestamp", opts: UploadOptions{UniqueID: UniqueIDTimestamp}, want: `^20240305000809$`},
+		{name: "timestamp with filename", opts: UploadOptions{UniqueID: UniqueIDTimestamp, KeepOriginalFilename: true}, want: `^my-photo-20240305000809$`},
+		{name: "none", opts: UploadOptions{UniqueID: UniqueIDNone}, want: `^my-photo$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.publicID("dir/my photo.jpg", now); !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("publicID = %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizePublicID(t *testing.T) {
+	tests := map[string]string{
+		"avatar.png":                  "avatar",
+		"../../etc/passwd":            "passwd",
+		`C:\Users\me\hồ sơ.pdf`:       "ho-so",
+		"Đặng Thị Ngọc Hân.JPG":       "Dang-Thi-Ngoc-Han",
+		"  report  (final) v2 .docx ": "report-final-v2",
+		"snake_case_name.txt":         "snake_case_name",
+		"文件.png":                      "file",
+		"":                            "file",
+	}
+	for in, want := range tests {
+		if got := SanitizePublicID(in); got != want {
+			t.Errorf("SanitizePublicID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package cloudinary
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+// smallPNG returns a 4x4 PNG image
+func smallPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadReader(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+	data := smallPNG(t)
+
+	result, err := s.UploadReader(context.Background(), bytes.NewReader(data), UploadOptions{
+		Folder: "thumbnails", Filename: "thumb.png", ResourceType: ResourceImage, UniqueID: UniqueIDNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UploadResult{
+		PublicID:     "thumbnails/thumb",
+		SecureURL:    "https://res.cloudinary.com/demo/image/upload/v1/thumbnails/thumb.jpg",
+		ResourceType: ResourceImage,
+		Bytes:        len(data),
+		Format:       "jpg",
+		Width:        640,
+		Height:       480,
+	}
+	if !uploadResultEqual(result, want) {
+		t.Errorf("UploadReader = %+v, want %+v", result, want)
+	}
+	if asset, ok := fake.asset(ResourceImage, "upload", "thumbnails/thumb"); !ok || !bytes.Equal(asset.data, data) {
+		t.Errorf("stored asset = %v, want the PNG bytes", asset)
+	}
+	if params := fake.lastUpload(); params["resource_type"] != ResourceImage || params["folder"] != "thumbnails" || params["public_id"] != "thumb" {
+		t.Errorf("upload params = %v", params)
+	}
+}
+
+func TestUploadRawDocument(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+
+	result, err := s.UploadReader(context.Background(), bytes.NewReader([]byte("%PDF-1.4 report")), UploadOptions{
+		Folder: "reports", Filename: "report.pdf", UniqueID: UniqueIDNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ResourceType != ResourceRaw || fake.lastUpload()["resource_type"] != ResourceAuto {
+		t.Errorf("result = %+v, want an auto upload stored as raw", result)
+	}
+}
+
+func TestUploadFromURL(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+
+	result, err := s.UploadFromURL(context.Background(), "https://cdn.example.com/media/intro.mp4?token=1", UploadOptions{
+		Folder:       "videos",
+		ResourceType: ResourceVideo,
+		UniqueID:     UniqueIDNone,
+		Eager:        []Transformation{{"start_offset": 2, "format": "jpg"}, {}, {"width": 320, "crop": "scale"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := fake.lastUpload()
+	if params["file"] != "https://cdn.example.com/media/intro.mp4?token=1" || params["public_id"] != "intro" || params["eager"] != "so_2,f_jpg|w_320,c_scale" {
+		t.Errorf("upload params = %v, want the remote URL fetched with eager transformations", params)
+	}
+	if result.PublicID != "videos/intro" || result.ResourceType != ResourceVideo || result.Duration != 12.5 {
+		t.Errorf("result = %+v, want the video with its duration", result)
+	}
+	wantEager := []string{
+		"https://res.cloudinary.com/demo/video/upload/so_2,f_jpg/videos/intro",
+		"https://res.cloudinary.com/demo/video/upload/w_320,c_scale/videos/intro",
+	}
+	if !slices.Equal(result.EagerURLs, wantEager) {
+		t.Errorf("eager urls = %v, want %v", result.EagerURLs, wantEager)
+	}
+}
+
+func TestUploadFromURLRejectsInvalidURLs(t *testing.T) {
+	fake, s := newFakeCloudinary(t)
+
+	for _, remoteURL := range []string{"ftp://example.com/a.png", "https:///a.png", "/etc/passwd", "file:///etc/passwd", "://bad"} {
+		if _, err := s.UploadFromURL(context.Background(), remoteURL, UploadOptions{}); err == nil {
+			t.Errorf("UploadFromURL(%q) succeeded", remoteURL)
+		}
+	}
+	if _, err := s.UploadFromURL(context.Background(), "https://example.com/a.png", UploadOptions{Eager: []Transformation{{"size": 1}}}); err == nil {
+		t.Error("UploadFromURL accepted an unknown eager transformation")
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("%d upload requests were sent for invalid uploads", len(fake.uploads))
+	}
+}
+
+func uploadResultEqual(a, b UploadResult) bool {
+	return a.PublicID == b.PublicID && a.SecureURL == b.SecureURL && a.ResourceType == b.ResourceType && a.Bytes == b.Bytes &&
+		a.Format == b.Format && a.Width == b.Width && a.Height == b.Height && a.Duration == b.Duration && slices.Equal(a.EagerURLs, b.EagerURLs)
+}
@@ -0,0 +1,82 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (s *minioService) CopyFile(ctx context.Context, srcObject, dstObject string) error {
+	ctx, span := s.trace(ctx, "minio.copy-file")
+	defer span.End()
+
+	if err := s.copyObject(ctx, span, srcObject, dstObject); err != nil {
+		return err
+	}
+	span.SetStatus(codes.Ok, "File copied successfully")
+	s.logger.Infof("Successfully copied file in MinIO: %s -> %s", srcObject, dstObject)
+	return nil
+}
+
+func (s *minioService) MoveFile(ctx context.Context, srcObject, dstObject string) error {
+	ctx, span := s.trace(ctx, "minio.move-file")
+	defer span.End()
+
+	if srcObject == dstObject {
+		return nil
+	}
+	if err := s.copyObject(ctx, span, srcObject, dstObject); err != nil {
+		return err
+	}
+
+	if err := s.minioClient.RemoveObject(ctx, s.bucketName, srcObject, minio.RemoveObjectOptions{}); err != nil {
+		// Roll back the copy so the object is not left in both places
+		if cleanupErr := s.minioClient.RemoveObject(ctx, s.bucketName, dstObject, minio.RemoveObjectOptions{}); cleanupErr != nil {
+			s.logger.Errorf("Failed to remove copied object %s after failed move: %v", dstObject, cleanupErr)
+			err = errors.Join(err, cleanupErr)
+		}
+		s.logger.Errorf("Failed to remove source of moved file from MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "File moved successfully")
+	s.logger.Infof("Successfully moved file in MinIO: %s -> %s", srcObject, dstObject)
+	return nil
+}
+
+func (s *minioService) RenameFile(ctx context.Context, folder, fileID, newFileID string) error {
+	if newFileID == "" || strings.Contains(newFileID, "/") {
+		return fmt.Errorf("invalid file name %q", newFileID)
+	}
+	return s.MoveFile(ctx, folder+"/"+fileID, folder+"/"+newFileID)
+}
+
+// copyObject copies srcObject to dstObject server-side, keeping its content type and user metadata
+func (s *minioService) copyObject(ctx context.Context, span trace.Span, srcObject, dstObject string) error {
+	bucket := s.bucketName
+	span.SetAttributes(
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.src_object", srcObject),
+		attribute.String("minio.dst_object", dstObject),
+	)
+
+	_, err := s.minioClient.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: dstObject},
+		minio.CopySrcOptions{Bucket: bucket, Object: srcObject},
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to copy file in MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package minio
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// putWithMetadata stores an object with a user metadata entry
+func putWithMetadata(fake *fakeS3, key string) {
+	fake.put(key, "application/pdf", []byte("%PDF "+key))
+	fake.mu.Lock()
+	fake.objects[key].header.Set("X-Amz-Meta-Owner", "u1")
+	fake.mu.Unlock()
+}
+
+func TestMoveFilePromotesFromTmp(t *testing.T) {
+	fake, s := newFakeS3(t)
+	putWithMetadata(fake, "tmp/20240101000000-invoice.pdf")
+
+	if err := s.MoveFile(context.Background(), "tmp/20240101000000-invoice.pdf", "invoices/42/20240101000000-invoice.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := fake.keys(); !slices.Equal(keys, []string{"invoices/42/20240101000000-invoice.pdf"}) {
+		t.Fatalf("objects = %v, want the promoted object only", keys)
+	}
+	object, _ := fake.object("invoices/42/20240101000000-invoice.pdf")
+	if string(object.data) != "%PDF tmp/20240101000000-invoice.pdf" || object.header.Get("Content-Type") != "application/pdf" ||
+		object.header.Get("X-Amz-Meta-Owner") != "u1" {
+		t.Errorf("promoted object = %q %v, want the content, content type and user metadata kept", object.data, object.header)
+	}
+}
+
+func TestMoveFileRollsBack(t *testing.T) {
+	fake, s := newFakeS3(t)
+	putWithMetadata(fake, "tmp/a.pdf")
+	fake.failDelete["tmp/a.pdf"] = true
+
+	if err := s.MoveFile(context.Background(), "tmp/a.pdf", "docs/a.pdf"); err == nil {
+		t.Fatal("MoveFile succeeded although the source could not be removed")
+	}
+	if keys := fake.keys(); !slices.Equal(keys, []string{"tmp/a.pdf"}) {
+		t.Errorf("objects = %v, want the copy rolled back", keys)
+	}
+
+	if err := s.MoveFile(context.Background(), "tmp/missing.pdf", "docs/missing.pdf"); err == nil {
+		t.Error("MoveFile of a missing object succeeded")
+	}
+}
+
+func TestCopyAndRenameFile(t *testing.T) {
+	fake, s := newFakeS3(t)
+	putWithMetadata(fake, "docs/a.pdf")
+
+	if err := s.CopyFile(context.Background(), "docs/a.pdf", "archive/a.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RenameFile(context.Background(), "docs", "a.pdf", "b.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := fake.keys(); !slices.Equal(keys, []string{"archive/a.pdf", "docs/b.pdf"}) {
+		t.Errorf("objects = %v, want the copy and the renamed object", keys)
+	}
+	if object, _ := fake.object("archive/a.pdf"); object.header.Get("X-Amz-Meta-Owner") != "u1" {
+		t.Errorf("copy headers = %v, want the user metadata", object.header)
+	}
+
+	for _, name := range []string{"", "other/b.pdf"} {
+		if err := s.RenameFile(context.Background(), "docs", "b.pdf", name); err == nil {
+			t.Errorf("RenameFile to %q succeeded, want it to stay in the folder", name)
+		}
+	}
+	if err := s.MoveFile(context.Background(), "docs/b.pdf", "docs/b.pdf"); err != nil {
+		t.Errorf("MoveFile onto itself = %v", err)
+	}
+	if _, ok := fake.object("docs/b.pdf"); !ok {
+		t.Error("MoveFile onto itself removed the object")
+	}
+}
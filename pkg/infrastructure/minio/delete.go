@@ -0,0 +1,147 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DeleteError is returned by DeleteFiles and DeleteFolder when some objects could not be deleted
+type DeleteError struct {
+	// Failed are the file ids of DeleteFiles, or the object names of DeleteFolder, that were not deleted
+	Failed []string
+	// Err is the first removal error
+	Err error
+}
+
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("failed to delete %d objects: %v", len(e.Failed), e.Err)
+}
+
+func (e *DeleteError) Unwrap() error {
+	return e.Err
+}
+
+func (s *minioService) DeleteFiles(ctx context.Context, folder string, fileIDs []string) ([]string, error) {
+	ctx, span := s.trace(ctx, "minio.delete-files")
+	defer span.End()
+
+	bucket := s.bucketName
+	prefix := folderPrefix(folder)
+	span.SetAttributes(
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.Int("minio.attempted", len(fileIDs)),
+	)
+
+	objectsCh := make(chan minio.ObjectInfo, len(fileIDs))
+	for _, fileID := range fileIDs {
+		objectsCh <- minio.ObjectInfo{Key: prefix + fileID}
+	}
+	close(objectsCh)
+
+	failed, err := s.removeObjects(ctx, bucket, objectsCh)
+	for i := range failed {
+		failed[i] = strings.TrimPrefix(failed[i], prefix)
+	}
+	if err = s.deleteResult(span, failed, err); err != nil {
+		return failed, err
+	}
+	s.logger.Infof("Successfully deleted %d files from MinIO folder: %s", len(fileIDs), folder)
+	return nil, nil
+}
+
+func (s *minioService) DeleteFolder(ctx context.Context, folder string) (int, error) {
+	ctx, span := s.trace(ctx, "minio.delete-folder")
+	defer span.End()
+
+	bucket := s.bucketName
+	prefix := folderPrefix(folder)
+	span.SetAttributes(
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+	)
+	if prefix == "" {
+		err := errors.New("refusing to delete the whole bucket, folder is required")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	// Objects are removed while they are listed, in batches of 1000 by RemoveObjects
+	attempted := 0
+	var listErr error
+	listed := make(chan struct{})
+	objectsCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(listed)
+		defer close(objectsCh)
+		for object := range s.minioClient.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			select {
+			case objectsCh <- object:
+				attempted++
+			case <-ctx.Done():
+				listErr = ctx.Err()
+				return
+			}
+		}
+	}()
+
+	failed, err := s.removeObjects(ctx, bucket, objectsCh)
+	<-listed
+	span.SetAttributes(attribute.Int("minio.attempted", attempted))
+	deleted := attempted - len(failed)
+	if listErr != nil {
+		s.logger.Errorf("Failed to list MinIO folder %s for deletion: %v", folder, listErr)
+		span.SetAttributes(attribute.Int("minio.failed", len(failed)))
+		span.RecordError(listErr)
+		span.SetStatus(codes.Error, listErr.Error())
+		if err != nil {
+			return deleted, errors.Join(listErr, &DeleteError{Failed: failed, Err: err})
+		}
+		return deleted, listErr
+	}
+	if err = s.deleteResult(span, failed, err); err != nil {
+		return deleted, err
+	}
+	s.logger.Infof("Successfully deleted %d files from MinIO folder: %s", deleted, folder)
+	return deleted, nil
+}
+
+// removeObjects removes the objects of objectsCh and returns the names of the failed ones with the
+// first error
+func (s *minioService) removeObjects(ctx context.Context, bucket string, objectsCh <-chan minio.ObjectInfo) ([]string, error) {
+	var failed []string
+	var firstErr error
+	for result := range s.minioClient.RemoveObjects(ctx, bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		failed = append(failed, result.ObjectName)
+		if firstErr == nil {
+			firstErr = result.Err
+		}
+	}
+	return failed, firstErr
+}
+
+// deleteResult records the failed count on span and returns the DeleteError of failed
+func (s *minioService) deleteResult(span trace.Span, failed []string, err error) error {
+	span.SetAttributes(attribute.Int("minio.failed", len(failed)))
+	if len(failed) == 0 {
+		span.SetStatus(codes.Ok, "Files deleted successfully")
+		return nil
+	}
+	deleteErr := &DeleteError{Failed: failed, Err: err}
+	s.logger.Errorf("Failed to delete files from MinIO: %v", deleteErr)
+	span.RecordError(deleteErr)
+	span.SetStatus(codes.Error, deleteErr.Error())
+	return deleteErr
+}
@@ -0,0 +1,77 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestDeleteFiles(t *testing.T) {
+	fake, s := newFakeS3(t)
+	tracer := &recordingTracer{}
+	s.tracer = tracer
+	for _, key := range []string{"orders/1/a.pdf", "orders/1/b.pdf", "orders/1/c.pdf", "orders/2/a.pdf"} {
+		fake.put(key, "application/pdf", []byte(key))
+	}
+	fake.failDelete["orders/1/b.pdf"] = true
+
+	failed, err := s.DeleteFiles(context.Background(), "orders/1", []string{"a.pdf", "b.pdf", "c.pdf"})
+	var deleteErr *DeleteError
+	if !errors.As(err, &deleteErr) || !slices.Equal(deleteErr.Failed, []string{"b.pdf"}) {
+		t.Fatalf("DeleteFiles error = %v, want a DeleteError for b.pdf", err)
+	}
+	if !slices.Equal(failed, []string{"b.pdf"}) {
+		t.Errorf("failed = %v, want the file ids that were not deleted", failed)
+	}
+	if keys := fake.keys(); !slices.Equal(keys, []string{"orders/1/b.pdf", "orders/2/a.pdf"}) {
+		t.Errorf("objects = %v", keys)
+	}
+	span := tracer.span("minio.delete-files")
+	if span.attributes["minio.attempted"].AsInt64() != 3 || span.attributes["minio.failed"].AsInt64() != 1 || span.status != codes.Error {
+		t.Errorf("span = %v %v", span.attributes, span.status)
+	}
+
+	delete(fake.failDelete, "orders/1/b.pdf")
+	if failed, err := s.DeleteFiles(context.Background(), "orders/1", []string{"b.pdf", "missing.pdf"}); err != nil || failed != nil {
+		t.Errorf("DeleteFiles = %v, %v, want missing objects to count as deleted", failed, err)
+	}
+}
+
+func TestDeleteFolder(t *testing.T) {
+	fake, s := newFakeS3(t)
+	tracer := &recordingTracer{}
+	s.tracer = tracer
+	// More than one ListObjects page and one RemoveObjects batch
+	for i := range 1200 {
+		fake.put(fmt.Sprintf("orders/1/%04d.pdf", i), "application/pdf", nil)
+	}
+	fake.put("orders/10/a.pdf", "application/pdf", nil)
+	fake.failDelete["orders/1/0007.pdf"] = true
+
+	deleted, err := s.DeleteFolder(context.Background(), "orders/1")
+	var deleteErr *DeleteError
+	if !errors.As(err, &deleteErr) || !slices.Equal(deleteErr.Failed, []string{"orders/1/0007.pdf"}) {
+		t.Fatalf("DeleteFolder error = %v, want a DeleteError for the failed object", err)
+	}
+	if deleted != 1199 {
+		t.Errorf("deleted = %d, want 1199", deleted)
+	}
+	if keys := fake.keys(); !slices.Equal(keys, []string{"orders/1/0007.pdf", "orders/10/a.pdf"}) {
+		t.Errorf("objects = %v, want the failed object and the sibling folder kept", keys)
+	}
+	span := tracer.span("minio.delete-folder")
+	if span.attributes["minio.attempted"].AsInt64() != 1200 || span.attributes["minio.failed"].AsInt64() != 1 {
+		t.Errorf("span attributes = %v", span.attributes)
+	}
+
+	if _, err := s.DeleteFolder(context.Background(), ""); err == nil {
+		t.Error("DeleteFolder of the bucket root succeeded")
+	}
+	if _, err := s.DeleteFolder(context.Background(), "/"); err == nil {
+		t.Error("DeleteFolder of / succeeded")
+	}
+}
@@ -0,0 +1,519 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeObject is an object stored by fakeS3
+type fakeObject struct {
+	data         []byte
+	header       http.Header
+	tags         string
+	lastModified time.Time
+}
+
+func (o fakeObject) etag() string {
+	sum := md5.Sum(o.data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// fakeUpload is a multipart upload in progress
+type fakeUpload struct {
+	key    string
+	header http.Header
+	parts  map[int][]byte
+}
+
+// fakeS3 is an in-memory S3 server covering the API used by minioService. It serves TLS so the client
+// sends plain payloads instead of streaming signatures
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string]fakeObject
+	uploads map[string]*fakeUpload
+	// failDelete makes individual deletes of these keys fail
+	failDelete map[string]bool
+	nextID     int
+	requests   []string
+	// client trusts the TLS certificate of the server
+	client *http.Client
+}
+
+// newFakeS3 starts a fake S3 server and returns a minioService of its "test" bucket
+func newFakeS3(t *testing.T, opts ...MinioOption) (*fakeS3, *minioService) {
+	t.Helper()
+	fake := &fakeS3{
+		buckets:    map[string]bool{},
+		objects:    map[string]fakeObject{},
+		uploads:    map[string]*fakeUpload{},
+		failDelete: map[string]bool{},
+	}
+	server := httptest.NewTLSServer(fake)
+	t.Cleanup(server.Close)
+	fake.client = server.Client()
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "https://"), &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		Secure:       true,
+		Region:       "us-east-1",
+		Transport:    server.Client().Transport,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, _ := test.NewNullLogger()
+	return fake, NewMinioService(client, "test", "us-east-1", logger, noop.NewTracerProvider(), opts...).(*minioService)
+}
+
+// put stores an object directly, bypassing the service
+func (f *fakeS3) put(key, contentType string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buckets["test"] = true
+	f.objects[key] = fakeObject{data: data, header: http.Header{"Content-Type": {contentType}}, lastModified: time.Now().UTC()}
+}
+
+// object returns a stored object
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[key]
+	return object, ok
+}
+
+// keys returns the stored object keys, sorted
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if key == "" {
+		f.serveBucket(w, r, bucket, query)
+		return
+	}
+	if !f.buckets[bucket] {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := "upload-" + strconv.Itoa(f.nextID)
+		f.uploads[id] = &fakeUpload{key: key, header: objectHeader(r.Header), parts: map[int][]byte{}}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Bucket: bucket, Key: key, UploadID: id})
+	case query.Has("uploadId"):
+		f.serveUpload(w, r, bucket, key, query)
+	case query.Has("tagging"):
+		object, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			object.tags = string(body)
+			f.objects[key] = object
+		case http.MethodDelete:
+			object.tags = ""
+			f.objects[key] = object
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if object.tags == "" {
+				writeXML(w, struct {
+					XMLName xml.Name `xml:"Tagging"`
+					TagSet  struct{}
+				}{})
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, object.tags)
+		}
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		_, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+		object, ok := f.objects[sourceKey]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			object.header = objectHeader(r.Header)
+		}
+		object.lastModified = time.Now().UTC()
+		f.objects[key] = object
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: object.etag(), LastModified: object.lastModified.Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		object := fakeObject{data: data, header: objectHeader(r.Header), lastModified: time.Now().UTC()}
+		f.objects[key] = object
+		w.Header().Set("ETag", object.etag())
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for name, values := range object.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", object.etag())
+		http.ServeContent(w, r, "", object.lastModified, bytes.NewReader(object.data))
+	case r.Method == http.MethodDelete:
+		if f.failDelete[key] {
+			writeS3Error(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// serveBucket serves the bucket requests
+func (f *fakeS3) serveBucket(w http.ResponseWriter, r *http.Request, bucket string, query url.Values) {
+	switch {
+	case r.Method == http.MethodHead:
+		if !f.buckets[bucket] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut:
+		f.buckets[bucket] = true
+	case r.Method == http.MethodPost && query.Has("delete"):
+		var request struct {
+			Objects []struct {
+				Key string
+			} `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		type deleted struct {
+			Key string
+		}
+		type deleteError struct {
+			Key     string
+			Code    string
+			Message string
+		}
+		var result struct {
+			XMLName xml.Name      `xml:"DeleteResult"`
+			Deleted []deleted     `xml:"Deleted"`
+			Errors  []deleteError `xml:"Error"`
+		}
+		for _, object := range request.Objects {
+			if f.failDelete[object.Key] {
+				result.Errors = append(result.Errors, deleteError{Key: object.Key, Code: "AccessDenied", Message: "Access Denied"})
+				continue
+			}
+			delete(f.objects, object.Key)
+			result.Deleted = append(result.Deleted, deleted{Key: object.Key})
+		}
+		writeXML(w, result)
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		f.serveList(w, bucket, query)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// serveList serves ListObjectsV2 pages
+func (f *fakeS3) serveList(w http.ResponseWriter, bucket string, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	startAfter := max(query.Get("start-after"), query.Get("continuation-token"))
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	type metadataEntry struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	}
+	type userMetadata struct {
+		Entries []metadataEntry `xml:",any"`
+	}
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+		UserMetadata *userMetadata `xml:",omitempty"`
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	var result struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Name                  string
+		Prefix                string
+		KeyCount              int
+		MaxKeys               int
+		IsTruncated           bool
+		NextContinuationToken string         `xml:",omitempty"`
+		Contents              []content      `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+	}
+	result.Name, result.Prefix, result.MaxKeys = bucket, prefix, maxKeys
+
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	seenPrefixes := map[string]bool{}
+	last := ""
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= startAfter {
+			continue
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = last
+			break
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				folder := key[:len(prefix)+i+len(delimiter)]
+				if !seenPrefixes[folder] {
+					seenPrefixes[folder] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: folder})
+					result.KeyCount++
+				}
+				last = key
+				continue
+			}
+		}
+		object := f.objects[key]
+		item := content{Key: key, LastModified: object.lastModified.Format(time.RFC3339), ETag: object.etag(), Size: int64(len(object.data))}
+		// MinIO lists the user metadata, with its header prefix, when asked to
+		if query.Get("metadata") == "true" {
+			item.UserMetadata = &userMetadata{}
+			for _, name := range slices.Sorted(maps.Keys(object.header)) {
+				if strings.HasPrefix(name, amzMetaPrefix) {
+					item.UserMetadata.Entries = append(item.UserMetadata.Entries, metadataEntry{XMLName: xml.Name{Local: name}, Value: object.header.Get(name)})
+				}
+			}
+		}
+		result.Contents = append(result.Contents, item)
+		result.KeyCount++
+		last = key
+	}
+	writeXML(w, result)
+}
+
+// serveUpload serves the part, list, complete and abort requests of a multipart upload
+func (f *fakeS3) serveUpload(w http.ResponseWriter, r *http.Request, bucket, key string, query url.Values) {
+	id := query.Get("uploadId")
+	upload, ok := f.uploads[id]
+	if !ok || upload.key != key {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		upload.parts[partNumber] = data
+		w.Header().Set("ETag", fakeObject{data: data}.etag())
+	case http.MethodGet:
+		type part struct {
+			PartNumber   int
+			ETag         string
+			Size         int64
+			LastModified string
+		}
+		var result struct {
+			XMLName     xml.Name `xml:"ListPartsResult"`
+			Bucket      string
+			Key         string
+			UploadID    string `xml:"UploadId"`
+			IsTruncated bool
+			Parts       []part `xml:"Part"`
+		}
+		result.Bucket, result.Key, result.UploadID = bucket, key, id
+		for _, number := range sortedParts(upload) {
+			data := upload.parts[number]
+			result.Parts = append(result.Parts, part{PartNumber: number, ETag: fakeObject{data: data}.etag(), Size: int64(len(data)), LastModified: time.Now().UTC().Format(time.RFC3339)})
+		}
+		writeXML(w, result)
+	case http.MethodPost:
+		var request struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		for _, part := range request.Parts {
+			partData, ok := upload.parts[part.PartNumber]
+			if !ok || (fakeObject{data: partData}).etag() != `"`+strings.Trim(part.ETag, `"`)+`"` {
+				writeS3Error(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			data = append(data, partData...)
+		}
+		object := fakeObject{data: data, header: upload.header, lastModified: time.Now().UTC()}
+		f.objects[key] = object
+		delete(f.uploads, id)
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucket, Key: key, ETag: object.etag()})
+	case http.MethodDelete:
+		delete(f.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// sortedParts returns the part numbers of upload in order
+func sortedParts(upload *fakeUpload) []int {
+	var numbers []int
+	for number := range upload.parts {
+		numbers = append(numbers, number)
+	}
+	slices.Sort(numbers)
+	return numbers
+}
+
+// objectHeader keeps the request headers stored with an object
+func objectHeader(h http.Header) http.Header {
+	header := http.Header{}
+	for name, values := range h {
+		if name == "Content-Type" || name == "Cache-Control" || name == "Content-Disposition" || strings.HasPrefix(name, "X-Amz-Meta-") {
+			header[name] = values
+		}
+	}
+	return header
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		panic(fmt.Sprintf("fakeS3: encode %T: %v", v, err))
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// recordingTracer records the names and attributes of the spans started by the service
+type recordingTracer struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (r *recordingTracer) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return spanRecorder{recorder: r}
+}
+
+// span returns the last span named name
+func (r *recordingTracer) span(name string) *recordingSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.spans) - 1; i >= 0; i-- {
+		if r.spans[i].name == name {
+			return r.spans[i]
+		}
+	}
+	return nil
+}
+
+// spanRecorder is the trace.Tracer of a recordingTracer
+type spanRecorder struct {
+	noop.Tracer
+	recorder *recordingTracer
+}
+
+func (t spanRecorder) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name, attributes: map[attribute.Key]attribute.Value{}}
+	t.recorder.mu.Lock()
+	t.recorder.spans = append(t.recorder.spans, span)
+	t.recorder.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// recordingSpan is a span keeping its attributes and status
+type recordingSpan struct {
+	noop.Span
+	name       string
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordingSpan) End(...trace.SpanEndOption) { s.ended = true }
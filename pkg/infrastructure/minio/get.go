@@ -0,0 +1,107 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (s *minioService) GetObject(ctx context.Context, fileID, folder string) (io.ReadSeekCloser, FileInfo, error) {
+	ctx, span := s.trace(ctx, "minio.get-object")
+	defer span.End()
+
+	return s.getObject(ctx, span, fileID, folder, minio.GetObjectOptions{})
+}
+
+func (s *minioService) GetObjectRange(ctx context.Context, fileID, folder string, offset, length int64) (io.ReadCloser, FileInfo, error) {
+	ctx, span := s.trace(ctx, "minio.get-object-range")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("minio.offset", offset),
+		attribute.Int64("minio.length", length),
+	)
+	opts := minio.GetObjectOptions{}
+	var err error
+	switch {
+	case offset < 0:
+		err = fmt.Errorf("invalid range offset %d", offset)
+	case length > 0:
+		err = opts.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = opts.SetRange(offset, 0)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, FileInfo{}, err
+	}
+	return s.getObject(ctx, span, fileID, folder, opts)
+}
+
+func (s *minioService) DownloadTo(ctx context.Context, fileID, folder string, w io.Writer) (int64, error) {
+	ctx, span := s.trace(ctx, "minio.download-to")
+	defer span.End()
+
+	object, _, err := s.getObject(ctx, span, fileID, folder, minio.GetObjectOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer object.Close()
+
+	written, err := io.Copy(w, object)
+	span.SetAttributes(attribute.Int64("minio.written", written))
+	if err != nil {
+		s.logger.Errorf("Failed to download file from MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return written, err
+	}
+	span.SetStatus(codes.Ok, "File downloaded successfully")
+	return written, nil
+}
+
+// getObject opens an object and stats it, so missing objects fail with ErrObjectNotFound before reading.
+// The FileInfo of ranged reads has the size of the whole object
+func (s *minioService) getObject(ctx context.Context, span trace.Span, fileID, folder string, opts minio.GetObjectOptions) (*minio.Object, FileInfo, error) {
+	bucket := s.bucketName
+	objectName := folder + "/" + fileID
+	span.SetAttributes(
+		attribute.String("minio.file_id", fileID),
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.object_name", objectName),
+	)
+
+	object, err := s.minioClient.GetObject(ctx, bucket, objectName, opts)
+	if err == nil {
+		var stat minio.ObjectInfo
+		// Object.Stat drops the Range of opts, so ranged reads are stated separately
+		if opts.Header().Get("Range") != "" {
+			stat, err = s.minioClient.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+		} else {
+			stat, err = object.Stat()
+		}
+		if err == nil {
+			span.SetAttributes(attribute.Int64("minio.size", stat.Size))
+			return object, fileInfoFromObject(stat), nil
+		}
+		object.Close()
+	}
+
+	if isNotFound(err) {
+		err = fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+	}
+	if !errors.Is(err, ErrObjectNotFound) {
+		s.logger.Errorf("Failed to get file from MinIO: %v", err)
+		span.RecordError(err)
+	}
+	span.SetStatus(codes.Error, err.Error())
+	return nil, FileInfo{}, err
+}
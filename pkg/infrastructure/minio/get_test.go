@@ -0,0 +1,128 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+const videoData = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func TestGetObject(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.put("videos/clip.mp4", "video/mp4", []byte(videoData))
+
+	object, info, err := s.GetObject(context.Background(), "clip.mp4", "videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer object.Close()
+	if info.Size != int64(len(videoData)) || info.ContentType != "video/mp4" {
+		t.Errorf("FileInfo = %+v", info)
+	}
+	data, err := io.ReadAll(object)
+	if err != nil || string(data) != videoData {
+		t.Fatalf("read %q, %v", data, err)
+	}
+	if _, err := object.Seek(30, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(object); string(data) != "uvwxyz" {
+		t.Errorf("read after seek = %q", data)
+	}
+
+	if _, _, err := s.GetObject(context.Background(), "missing.mp4", "videos"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObject of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestGetObjectRange(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.put("videos/clip.mp4", "video/mp4", []byte(videoData))
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           string
+	}{
+		{name: "whole object", want: videoData},
+		{name: "middle", offset: 10, length: 5, want: "abcde"},
+		{name: "to the end", offset: 30, want: "uvwxyz"},
+		{name: "first byte", length: 1, want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, info, err := s.GetObjectRange(context.Background(), "clip.mp4", "videos", tt.offset, tt.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer object.Close()
+			if info.Size != int64(len(videoData)) {
+				t.Errorf("FileInfo size = %d, want the whole object size", info.Size)
+			}
+			if data, err := io.ReadAll(object); err != nil || string(data) != tt.want {
+				t.Errorf("read %q, %v, want %q", data, err, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := s.GetObjectRange(context.Background(), "clip.mp4", "videos", -1, 5); err == nil {
+		t.Error("GetObjectRange accepted a negative offset")
+	}
+	if _, _, err := s.GetObjectRange(context.Background(), "missing.mp4", "videos", 0, 5); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObjectRange of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestDownloadTo(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.put("videos/clip.mp4", "video/mp4", []byte(videoData))
+
+	var buf bytes.Buffer
+	written, err := s.DownloadTo(context.Background(), "clip.mp4", "videos", &buf)
+	if err != nil || written != int64(len(videoData)) || buf.String() != videoData {
+		t.Errorf("DownloadTo = %d, %v, wrote %q", written, err, buf.String())
+	}
+
+	if _, err := s.DownloadTo(context.Background(), "missing.mp4", "videos", &buf); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("DownloadTo of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestProxyObjectRange(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.put("videos/clip.mp4", "video/mp4", []byte(videoData))
+
+	controller := &common.BaseController[struct{}]{}
+	e := echo.New()
+	e.GET("/videos/:id", func(c echo.Context) error {
+		object, info, err := s.GetObject(c.Request().Context(), c.Param("id"), "videos")
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		defer object.Close()
+		return controller.StreamContent(c, object, c.Param("id"), info.ContentType, info.ETag, info.LastModified)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/videos/clip.mp4", nil)
+	req.Header.Set("Range", "bytes=10-14")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "abcde" {
+		t.Fatalf("response = %d %q, want 206 abcde", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 10-14/36" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); got != "video/mp4" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+package minio
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// FileInfo describes a stored object
+type FileInfo struct {
+	// Name is the full object name, e.g. "attachments/20240101120000-invoice.pdf"
+	Name         string            `json:"name"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"content_type,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	UserMetadata map[string]string `json:"user_metadata,omitempty"`
+	// IsFolder is set for the sub folders of a non-recursive listing
+	IsFolder bool `json:"is_folder,omitempty"`
+}
+
+// ListOptions configures ListFiles
+type ListOptions struct {
+	// MaxKeys is the page size, 1000 by default
+	MaxKeys int
+	// ContinuationToken is the NextContinuationToken of the previous page
+	ContinuationToken string
+	// Recursive lists the objects of sub folders too, otherwise sub folders are listed as folders
+	Recursive bool
+	// WithMetadata returns the content type and user metadata, only supported by MinIO servers
+	WithMetadata bool
+}
+
+// ListResult is a page of ListFiles
+type ListResult struct {
+	Files []FileInfo `json:"files"`
+	// NextContinuationToken fetches the next page, empty on the last page
+	NextContinuationToken string `json:"next_continuation_token,omitempty"`
+}
+
+// defaultListMaxKeys is the page size of ListFiles
+const defaultListMaxKeys = 1000
+
+func (s *minioService) ListFiles(ctx context.Context, folder string, opts ListOptions) (ListResult, error) {
+	ctx, span := s.trace(ctx, "minio.list-files")
+	defer span.End()
+
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = defaultListMaxKeys
+	}
+	bucket := s.bucketName
+	prefix := folderPrefix(folder)
+	span.SetAttributes(
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.prefix", prefix),
+		attribute.Bool("minio.recursive", opts.Recursive),
+		attribute.Int("minio.max_keys", opts.MaxKeys),
+	)
+
+	// Cancelling stops the listing once the page is full, the channel is drained before returning
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objects := s.minioClient.ListObjects(listCtx, bucket, minio.ListObjectsOptions{
+		Prefix:       prefix,
+		Recursive:    opts.Recursive,
+		StartAfter:   opts.ContinuationToken,
+		WithMetadata: opts.WithMetadata,
+		MaxKeys:      min(opts.MaxKeys+1, defaultListMaxKeys),
+	})
+
+	result := ListResult{Files: []FileInfo{}}
+	var listErr error
+	for object := range objects {
+		if listErr != nil || result.NextContinuationToken != "" {
+			continue
+		}
+		if object.Err != nil {
+			listErr = object.Err
+			cancel()
+			continue
+		}
+		if len(result.Files) == opts.MaxKeys {
+			// One more object exists, the last returned one is where the next page starts
+			result.NextContinuationToken = result.Files[len(result.Files)-1].Name
+			cancel()
+			continue
+		}
+		result.Files = append(result.Files, fileInfoFromObject(object))
+	}
+	if listErr != nil {
+		s.logger.Errorf("Failed to list files from MinIO: %v", listErr)
+		span.RecordError(listErr)
+		span.SetStatus(codes.Error, listErr.Error())
+		return ListResult{}, listErr
+	}
+
+	span.SetAttributes(
+		attribute.Int("minio.count", len(result.Files)),
+		attribute.Bool("minio.truncated", result.NextContinuationToken != ""),
+	)
+	span.SetStatus(codes.Ok, "Files listed successfully")
+	return result, nil
+}
+
+// folderPrefix returns the listing prefix of folder, "" lists the whole bucket
+func folderPrefix(folder string) string {
+	folder = strings.Trim(folder, "/")
+	if folder == "" {
+		return ""
+	}
+	return folder + "/"
+}
+
+// fileInfoFromObject converts a listed or stat object
+func fileInfoFromObject(object minio.ObjectInfo) FileInfo {
+	return FileInfo{
+		Name:         object.Key,
+		Size:         object.Size,
+		ContentType:  object.ContentType,
+		ETag:         object.ETag,
+		LastModified: object.LastModified,
+		UserMetadata: object.UserMetadata,
+		IsFolder:     strings.HasSuffix(object.Key, "/") && object.Size == 0 && object.ETag == "",
+	}
+}
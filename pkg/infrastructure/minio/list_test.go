@@ -0,0 +1,93 @@
+package minio
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// fileNames returns the names of files
+func fileNames(files []FileInfo) []string {
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names
+}
+
+func TestListFilesPages(t *testing.T) {
+	fake, s := newFakeS3(t)
+	tracer := &recordingTracer{}
+	s.tracer = tracer
+	for _, key := range []string{"attachments/a.pdf", "attachments/b.pdf", "attachments/c.pdf", "attachments/d.pdf", "attachmentsx/e.pdf", "other/f.pdf"} {
+		fake.put(key, "application/pdf", []byte(key))
+	}
+
+	var pages [][]string
+	opts := ListOptions{MaxKeys: 3}
+	for {
+		result, err := s.ListFiles(context.Background(), "attachments", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, fileNames(result.Files))
+		if result.NextContinuationToken == "" {
+			break
+		}
+		opts.ContinuationToken = result.NextContinuationToken
+	}
+	if len(pages) != 2 || !slices.Equal(pages[0], []string{"attachments/a.pdf", "attachments/b.pdf", "attachments/c.pdf"}) ||
+		!slices.Equal(pages[1], []string{"attachments/d.pdf"}) {
+		t.Errorf("pages = %v, want 3 then 1 files of the folder only", pages)
+	}
+
+	span := tracer.span("minio.list-files")
+	if span == nil || span.attributes["minio.prefix"].AsString() != "attachments/" || span.attributes["minio.count"].AsInt64() != 1 {
+		t.Errorf("span = %+v, want the prefix and returned count", span)
+	}
+}
+
+func TestListFilesFolders(t *testing.T) {
+	fake, s := newFakeS3(t)
+	for _, key := range []string{"entity/1/a.pdf", "entity/1/sub/b.pdf", "entity/1/sub/c.pdf"} {
+		fake.put(key, "application/pdf", []byte("%PDF"))
+	}
+
+	result, err := s.ListFiles(context.Background(), "/entity/1/", ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fileNames(result.Files), []string{"entity/1/a.pdf", "entity/1/sub/"}) || len(result.Files) != 2 ||
+		result.Files[0].IsFolder || !result.Files[1].IsFolder || result.Files[0].Size != 4 || result.Files[0].LastModified.IsZero() {
+		t.Errorf("files = %+v, want a.pdf with its size and date, then the sub folder", result.Files)
+	}
+
+	result, err = s.ListFiles(context.Background(), "entity/1", ListOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fileNames(result.Files), []string{"entity/1/a.pdf", "entity/1/sub/b.pdf", "entity/1/sub/c.pdf"}) {
+		t.Errorf("recursive files = %v, want every object", fileNames(result.Files))
+	}
+}
+
+func TestListFilesEmpty(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.put("other/a.pdf", "application/pdf", nil)
+
+	result, err := s.ListFiles(context.Background(), "entity/2", ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files == nil || len(result.Files) != 0 || result.NextContinuationToken != "" {
+		t.Errorf("result = %+v, want an empty slice", result)
+	}
+}
+
+func TestFolderPrefix(t *testing.T) {
+	for folder, want := range map[string]string{"": "", "/": "", "docs": "docs/", "/docs/": "docs/", "a/b": "a/b/"} {
+		if got := folderPrefix(folder); got != want {
+			t.Errorf("folderPrefix(%q) = %q, want %q", folder, got, want)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package minio
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"mime/multipart"
 	"time"
 
@@ -14,8 +17,99 @@ import (
 
 type MinioService interface {
 	UploadFile(ctx context.Context, file *multipart.FileHeader, folder string) (string, error)
-	DownloadFile(ctx context.Context, fileID string, folder string) (string, error)
+	// UploadReader uploads r under folder like UploadFile, size -1 streams r when its size is unknown
+	UploadReader(ctx context.Context, folder, objectName string, r io.Reader, size int64, contentType string, opts ...UploadOption) (string, error)
+	// UploadBytes uploads data under folder like UploadFile
+	UploadBytes(ctx context.Context, folder, objectName string, data []byte, contentType string, opts ...UploadOption) (string, error)
+	// DownloadFile returns a download URL of the object, valid for one hour unless WithDownloadExpiry is used
+	DownloadFile(ctx context.Context, fileID string, folder string, opts ...DownloadOption) (string, error)
+	// GetObject opens an object for reading server-side, ErrObjectNotFound when it does not exist. The
+	// reader is seekable, e.g. for http.ServeContent
+	GetObject(ctx context.Context, fileID, folder string) (io.ReadSeekCloser, FileInfo, error)
+	// GetObjectRange opens length bytes of an object from offset, to its end when length is 0. The
+	// FileInfo has the size of the whole object
+	GetObjectRange(ctx context.Context, fileID, folder string, offset, length int64) (io.ReadCloser, FileInfo, error)
+	// DownloadTo copies an object to w and returns the number of bytes written
+	DownloadTo(ctx context.Context, fileID, folder string, w io.Writer) (int64, error)
+	// PresignedUploadURL lets clients upload "<folder>/<timestamp>-<filename>" straight to MinIO until expiry
+	PresignedUploadURL(ctx context.Context, folder, filename string, expiry time.Duration, constraints UploadConstraints) (PresignedUpload, error)
+	// PresignedDownloadURL returns a download URL of the object valid until expiry, one hour when zero
+	PresignedDownloadURL(ctx context.Context, fileID, folder string, expiry time.Duration) (string, error)
+	// DeleteFile removes an object. Missing objects are not an error unless WithStrictDelete is used
 	DeleteFile(ctx context.Context, fileID string, folder string) error
+	// DeleteFiles removes objects of folder in batches and returns the file ids that could not be deleted
+	// with a *DeleteError. Missing objects are not an error
+	DeleteFiles(ctx context.Context, folder string, fileIDs []string) ([]string, error)
+	// DeleteFolder removes every object under folder and returns the number deleted. Objects that could
+	// not be deleted are reported by a *DeleteError
+	DeleteFolder(ctx context.Context, folder string) (int, error)
+	// StatFile returns the size, content type, ETag, last modified time and user metadata of an object,
+	// ErrObjectNotFound when it does not exist
+	StatFile(ctx context.Context, fileID, folder string) (FileInfo, error)
+	// FileExists reports whether an object exists
+	FileExists(ctx context.Context, fileID, folder string) (bool, error)
+	// CopyFile copies an object server-side, object names include their folder ("tmp/a.pdf")
+	CopyFile(ctx context.Context, srcObject, dstObject string) error
+	// MoveFile copies an object server-side then removes the source, e.g. to promote uploads from tmp/
+	MoveFile(ctx context.Context, srcObject, dstObject string) error
+	// RenameFile renames an object within its folder
+	RenameFile(ctx context.Context, folder, fileID, newFileID string) error
+	// ListFiles lists a page of the objects under folder, an empty folder has no files
+	ListFiles(ctx context.Context, folder string, opts ListOptions) (ListResult, error)
+	// StartMultipartUpload starts a resumable upload of "<folder>/<timestamp>-<filename>". Parts of at
+	// least MinPartSize, except the last, are sent with UploadPart or presigned part URLs
+	StartMultipartUpload(ctx context.Context, folder, filename, contentType string) (UploadSession, error)
+	// UploadPart uploads part partNumber (1 to MaxPartNumber) of session, uploading it again replaces it
+	UploadPart(ctx context.Context, session UploadSession, partNumber int, r io.Reader, size int64) (PartInfo, error)
+	// ListUploadedParts lists the parts MinIO received, to resume a session after reconnecting
+	ListUploadedParts(ctx context.Context, session UploadSession) ([]PartInfo, error)
+	// CompleteMultipartUpload assembles the parts of session, listed from MinIO when session.Parts is empty,
+	// and returns the object name
+	CompleteMultipartUpload(ctx context.Context, session UploadSession) (string, error)
+	// AbortMultipartUpload discards session and its uploaded parts
+	AbortMultipartUpload(ctx context.Context, session UploadSession) error
+	// PresignedPartURL lets browsers PUT part partNumber of session straight to MinIO until expiry, the
+	// ETag response header of each part is needed to complete the upload
+	PresignedPartURL(ctx context.Context, session UploadSession, partNumber int, expiry time.Duration) (string, error)
+	// ListTemporaryFiles lists the WithTemporary uploads under folder that expired before expiredBefore.
+	// Listing metadata requires a MinIO server
+	ListTemporaryFiles(ctx context.Context, folder string, expiredBefore time.Time) ([]FileInfo, error)
+	// MarkPermanent removes the temporary tag of an object ("<folder>/<file id>"), call it when the owning
+	// entity is saved
+	MarkPermanent(ctx context.Context, objectName string) error
+}
+
+// UploadOption configures UploadReader and UploadBytes
+type UploadOption func(*uploadOptions)
+
+type uploadOptions struct {
+	userMetadata       map[string]string
+	cacheControl       string
+	contentDisposition string
+	policy             *UploadPolicy
+	// temporaryTTL tags the upload as temporary when positive
+	temporaryTTL time.Duration
+}
+
+// WithUserMetadata stores metadata with the object (x-amz-meta-* headers)
+func WithUserMetadata(metadata map[string]string) UploadOption {
+	return func(o *uploadOptions) {
+		o.userMetadata = metadata
+	}
+}
+
+// WithCacheControl sets the Cache-Control header served with the object
+func WithCacheControl(cacheControl string) UploadOption {
+	return func(o *uploadOptions) {
+		o.cacheControl = cacheControl
+	}
+}
+
+// WithContentDisposition sets the Content-Disposition header served with the object
+func WithContentDisposition(contentDisposition string) UploadOption {
+	return func(o *uploadOptions) {
+		o.contentDisposition = contentDisposition
+	}
 }
 
 type minioService struct {
@@ -24,16 +118,35 @@ type minioService struct {
 	bucketRegion string
 	logger       *logrus.Logger
 	tracer       trace.TracerProvider
+	// strictDelete makes DeleteFile return ErrObjectNotFound for missing objects
+	strictDelete bool
+	uploadPolicy *UploadPolicy
+	scanner      Scanner
+}
+
+// MinioOption configures NewMinioService
+type MinioOption func(*minioService)
+
+// WithStrictDelete makes DeleteFile return ErrObjectNotFound when the object does not exist, at the cost
+// of a stat request. By default deleting a missing object succeeds
+func WithStrictDelete() MinioOption {
+	return func(s *minioService) {
+		s.strictDelete = true
+	}
 }
 
-func NewMinioService(minioClient *minio.Client, bucketName string, bucketRegion string, logger *logrus.Logger, tracer trace.TracerProvider) MinioService {
-	return &minioService{
+func NewMinioService(minioClient *minio.Client, bucketName string, bucketRegion string, logger *logrus.Logger, tracer trace.TracerProvider, opts ...MinioOption) MinioService {
+	s := &minioService{
 		minioClient:  minioClient,
 		bucketName:   bucketName,
 		bucketRegion: bucketRegion,
 		logger:       logger,
 		tracer:       tracer,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *minioService) trace(ctx context.Context, name string) (context.Context, trace.Span) {
@@ -45,16 +158,63 @@ func (s *minioService) UploadFile(ctx context.Context, file *multipart.FileHeade
 	ctx, span := s.trace(ctx, "minio.upload-file")
 	defer span.End()
 
+	src, err := file.Open()
+	if err != nil {
+		s.logger.Errorf("Failed to open file: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", err
+	}
+	defer src.Close()
+
+	return s.upload(ctx, span, folder, file.Filename, src, file.Size, file.Header.Get("Content-Type"), nil)
+}
+
+func (s *minioService) UploadReader(ctx context.Context, folder, objectName string, r io.Reader, size int64, contentType string, opts ...UploadOption) (string, error) {
+	ctx, span := s.trace(ctx, "minio.upload-reader")
+	defer span.End()
+
+	return s.upload(ctx, span, folder, objectName, r, size, contentType, opts)
+}
+
+func (s *minioService) UploadBytes(ctx context.Context, folder, objectName string, data []byte, contentType string, opts ...UploadOption) (string, error) {
+	ctx, span := s.trace(ctx, "minio.upload-bytes")
+	defer span.End()
+
+	return s.upload(ctx, span, folder, objectName, bytes.NewReader(data), int64(len(data)), contentType, opts)
+}
+
+// upload stores r as "<folder>/<timestamp>-<filename>" in the bucket, a negative size streams r with a
+// multipart upload
+func (s *minioService) upload(ctx context.Context, span trace.Span, folder, filename string, r io.Reader, size int64, contentType string, opts []UploadOption) (string, error) {
 	bucket := s.bucketName
+	options := uploadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
 
 	// Set span attributes
 	span.SetAttributes(
-		attribute.String("minio.filename", file.Filename),
+		attribute.String("minio.filename", filename),
 		attribute.String("minio.folder", folder),
 		attribute.String("minio.bucket", bucket),
-		attribute.Int64("minio.size", file.Size),
+		attribute.Int64("minio.size", size),
 	)
 
+	policy := s.uploadPolicy
+	if options.policy != nil {
+		policy = options.policy
+	}
+	filename = SanitizeFilename(filename)
+	r, sniffed, cleanup, err := s.checkUpload(ctx, policy, filename, r, size)
+	defer cleanup()
+	if err != nil {
+		return "", s.uploadError(span, err)
+	}
+	if sniffed != "" {
+		contentType = sniffed
+	}
+
 	if err := s.ensureBucket(ctx, bucket); err != nil {
 		s.logger.Errorf("Failed to ensure bucket: %v", err)
 		span.RecordError(err)
@@ -62,26 +222,20 @@ func (s *minioService) UploadFile(ctx context.Context, file *multipart.FileHeade
 		return "", err
 	}
 
-	src, err := file.Open()
-	if err != nil {
-		s.logger.Errorf("Failed to open file: %v", err)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return "", err
+	now := time.Now()
+	objectName := buildObjectName(folder, filename, now)
+	if size < 0 {
+		size = -1
 	}
-	defer src.Close()
-
-	objectName := folder + "/" + time.Now().Format("20060102150405") + "-" + file.Filename
-	contentType := file.Header.Get("Content-Type")
 
-	_, err = s.minioClient.PutObject(ctx, bucket, objectName, src, file.Size, minio.PutObjectOptions{
-		ContentType: contentType,
+	_, err = s.minioClient.PutObject(ctx, bucket, objectName, r, size, minio.PutObjectOptions{
+		ContentType:        contentType,
+		UserMetadata:       options.metadata(now),
+		CacheControl:       options.cacheControl,
+		ContentDisposition: options.contentDisposition,
 	})
 	if err != nil {
-		s.logger.Errorf("Failed to upload file to MinIO: %v", err)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return "", err
+		return "", s.uploadError(span, err)
 	}
 
 	// Set success attributes
@@ -95,14 +249,24 @@ func (s *minioService) UploadFile(ctx context.Context, file *multipart.FileHeade
 	return objectName, nil
 }
 
-func (s *minioService) DownloadFile(ctx context.Context, fileID string, folder string) (string, error) {
-	bucket := s.bucketName
-
-	url, err := s.minioClient.PresignedGetObject(ctx, bucket, folder+"/"+fileID, time.Hour, nil)
-	if err != nil {
-		return "", err
+// uploadError records a failed upload, policy violations are logged as warnings
+func (s *minioService) uploadError(span trace.Span, err error) error {
+	var policyErr *UploadPolicyError
+	if errors.As(err, &policyErr) {
+		s.logger.Warnf("Upload to MinIO rejected: %v", policyErr)
+		span.SetAttributes(attribute.String("minio.rejected_rule", policyErr.Rule))
+		span.SetStatus(codes.Error, policyErr.Error())
+		return policyErr
 	}
-	return url.String(), nil
+	s.logger.Errorf("Failed to upload file to MinIO: %v", err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// buildObjectName returns the object name of an upload, "<folder>/<yyyyMMddHHmmss>-<filename>"
+func buildObjectName(folder, filename string, now time.Time) string {
+	return folder + "/" + now.Format("20060102150405") + "-" + filename
 }
 
 func (s *minioService) DeleteFile(ctx context.Context, fileID string, folder string) error {
@@ -120,6 +284,14 @@ func (s *minioService) DeleteFile(ctx context.Context, fileID string, folder str
 		attribute.String("minio.object_name", objectName),
 	)
 
+	if s.strictDelete {
+		if _, err := s.statObject(ctx, bucket, objectName); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+
 	err := s.minioClient.RemoveObject(ctx, bucket, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		s.logger.Errorf("Failed to delete file from MinIO: %v", err)
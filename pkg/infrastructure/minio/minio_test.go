@@ -0,0 +1,113 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// objectNamePattern matches "<folder>/<yyyyMMddHHmmss>-<filename>"
+func objectNamePattern(folder, filename string) *regexp.Regexp {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(folder) + `/\d{14}-` + regexp.QuoteMeta(filename) + "$")
+}
+
+func TestUploadReader(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("%PDF-1.7 generated report")
+
+	tests := []struct {
+		name   string
+		upload func(s *minioService) (string, error)
+	}{
+		{"bytes", func(s *minioService) (string, error) {
+			return s.UploadBytes(ctx, "reports", "report.pdf", data, "application/pdf")
+		}},
+		{"reader with size", func(s *minioService) (string, error) {
+			return s.UploadReader(ctx, "reports", "report.pdf", bytes.NewReader(data), int64(len(data)), "application/pdf")
+		}},
+		{"reader of unknown size", func(s *minioService) (string, error) {
+			return s.UploadReader(ctx, "reports", "report.pdf", io.MultiReader(bytes.NewReader(data)), -1, "application/pdf")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, s := newFakeS3(t)
+			objectName, err := tt.upload(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !objectNamePattern("reports", "report.pdf").MatchString(objectName) {
+				t.Errorf("object name = %q, want reports/<timestamp>-report.pdf", objectName)
+			}
+			object, ok := fake.object(objectName)
+			if !ok || !bytes.Equal(object.data, data) || object.header.Get("Content-Type") != "application/pdf" {
+				t.Errorf("stored object = %q %v, want the uploaded PDF", object.data, object.header)
+			}
+			if !fake.buckets["test"] {
+				t.Error("bucket not created")
+			}
+		})
+	}
+}
+
+func TestUploadOptions(t *testing.T) {
+	fake, s := newFakeS3(t)
+	objectName, err := s.UploadBytes(context.Background(), "invoices", "../../etc/inv 1.pdf", []byte("%PDF"), "application/pdf",
+		WithUserMetadata(map[string]string{"owner": "u1"}),
+		WithCacheControl("private, max-age=60"),
+		WithContentDisposition(`attachment; filename="invoice.pdf"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !objectNamePattern("invoices", "inv 1.pdf").MatchString(objectName) {
+		t.Errorf("object name = %q, want the sanitized filename", objectName)
+	}
+	object, _ := fake.object(objectName)
+	if object.header.Get("X-Amz-Meta-Owner") != "u1" || object.header.Get("Cache-Control") != "private, max-age=60" ||
+		object.header.Get("Content-Disposition") != `attachment; filename="invoice.pdf"` {
+		t.Errorf("stored headers = %v, want the metadata, Cache-Control and Content-Disposition", object.header)
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	fake, s := newFakeS3(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "avatar.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("\x89PNG\r\n\x1a\n"))
+	writer.Close()
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	_, file, err := req.FormFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectName, err := s.UploadFile(context.Background(), file, "avatars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !objectNamePattern("avatars", "avatar.png").MatchString(objectName) {
+		t.Errorf("object name = %q, want avatars/<timestamp>-avatar.png", objectName)
+	}
+	if object, ok := fake.object(objectName); !ok || !strings.HasPrefix(string(object.data), "\x89PNG") {
+		t.Errorf("stored object = %q, want the form file", object.data)
+	}
+}
+
+func TestBuildObjectName(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := buildObjectName("docs", "a.pdf", now); got != "docs/20240102030405-a.pdf" {
+		t.Errorf("buildObjectName = %q", got)
+	}
+}
@@ -0,0 +1,326 @@
+package minio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Part limits of S3 multipart uploads
+const (
+	// MinPartSize is the smallest accepted part, except for the last one
+	MinPartSize = 5 << 20
+	// MaxPartNumber is the largest part number
+	MaxPartNumber = 10000
+)
+
+// DefaultUploadSessionTTL is how long NewRedisUploadSessionStore keeps sessions
+const DefaultUploadSessionTTL = 24 * time.Hour
+
+// ErrUploadSessionNotFound is returned for unknown, completed or aborted upload sessions
+var ErrUploadSessionNotFound = errors.New("upload session not found")
+
+// UploadSession is a multipart upload in progress. It serializes to JSON so clients can resume it after
+// reconnecting, e.g. through an UploadSessionStore
+type UploadSession struct {
+	UploadID    string    `json:"upload_id"`
+	ObjectName  string    `json:"object_name"`
+	ContentType string    `json:"content_type,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Parts are the uploaded parts, CompleteMultipartUpload lists them from MinIO when empty
+	Parts []PartInfo `json:"parts,omitempty"`
+}
+
+// PartInfo is an uploaded part of an UploadSession
+type PartInfo struct {
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+func (s *minioService) StartMultipartUpload(ctx context.Context, folder, filename, contentType string) (UploadSession, error) {
+	ctx, span := s.trace(ctx, "minio.start-multipart-upload")
+	defer span.End()
+
+	filename = SanitizeFilename(filename)
+	bucket := s.bucketName
+	objectName := buildObjectName(folder, filename, time.Now())
+	span.SetAttributes(
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.object_name", objectName),
+		attribute.String("minio.content_type", contentType),
+	)
+
+	// Parts are not sniffed nor scanned, the extension is checked here and the size on completion
+	if s.uploadPolicy != nil && len(s.uploadPolicy.AllowedExtensions) > 0 {
+		extension := strings.ToLower(path.Ext(filename))
+		if !slices.Contains(s.uploadPolicy.AllowedExtensions, extension) {
+			err := &UploadPolicyError{Rule: RuleExtension, Message: fmt.Sprintf("extension %q is not allowed", extension)}
+			return UploadSession{}, s.uploadError(span, err)
+		}
+	}
+
+	uploadID, err := minio.Core{Client: s.minioClient}.NewMultipartUpload(ctx, bucket, objectName, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		s.logger.Errorf("Failed to start multipart upload to MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return UploadSession{}, err
+	}
+
+	span.SetAttributes(attribute.String("minio.upload_id", uploadID))
+	span.SetStatus(codes.Ok, "Multipart upload started successfully")
+	s.logger.Infof("Successfully started multipart upload to MinIO: %s", objectName)
+	return UploadSession{
+		UploadID:    uploadID,
+		ObjectName:  objectName,
+		ContentType: contentType,
+		CreatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+func (s *minioService) UploadPart(ctx context.Context, session UploadSession, partNumber int, r io.Reader, size int64) (PartInfo, error) {
+	ctx, span := s.trace(ctx, "minio.upload-part")
+	defer span.End()
+
+	s.sessionAttributes(span, session)
+	span.SetAttributes(
+		attribute.Int("minio.part_number", partNumber),
+		attribute.Int64("minio.size", size),
+	)
+
+	var part minio.ObjectPart
+	err := validatePartNumber(partNumber)
+	if err == nil {
+		part, err = minio.Core{Client: s.minioClient}.PutObjectPart(ctx, s.bucketName, session.ObjectName, session.UploadID, partNumber, r, size, minio.PutObjectPartOptions{})
+	}
+	if err != nil {
+		return PartInfo{}, s.multipartError(span, "upload part to MinIO", err)
+	}
+
+	span.SetStatus(codes.Ok, "Part uploaded successfully")
+	return PartInfo{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size}, nil
+}
+
+func (s *minioService) ListUploadedParts(ctx context.Context, session UploadSession) ([]PartInfo, error) {
+	ctx, span := s.trace(ctx, "minio.list-uploaded-parts")
+	defer span.End()
+
+	s.sessionAttributes(span, session)
+	parts, err := s.listParts(ctx, session)
+	if err != nil {
+		return nil, s.multipartError(span, "list uploaded parts from MinIO", err)
+	}
+
+	span.SetAttributes(attribute.Int("minio.parts", len(parts)))
+	span.SetStatus(codes.Ok, "Uploaded parts listed successfully")
+	return parts, nil
+}
+
+func (s *minioService) CompleteMultipartUpload(ctx context.Context, session UploadSession) (string, error) {
+	ctx, span := s.trace(ctx, "minio.complete-multipart-upload")
+	defer span.End()
+
+	s.sessionAttributes(span, session)
+	parts := session.Parts
+	var err error
+	if len(parts) == 0 {
+		if parts, err = s.listParts(ctx, session); err != nil {
+			return "", s.multipartError(span, "complete multipart upload to MinIO", err)
+		}
+	}
+	if len(parts) == 0 {
+		return "", s.multipartError(span, "complete multipart upload to MinIO", errors.New("multipart upload has no parts"))
+	}
+
+	var size int64
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		size += part.Size
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	slices.SortFunc(completeParts, func(a, b minio.CompletePart) int { return a.PartNumber - b.PartNumber })
+	span.SetAttributes(
+		attribute.Int("minio.parts", len(parts)),
+		attribute.Int64("minio.size", size),
+	)
+
+	core := minio.Core{Client: s.minioClient}
+	if s.uploadPolicy != nil && s.uploadPolicy.MaxSize > 0 && size > s.uploadPolicy.MaxSize {
+		if abortErr := core.AbortMultipartUpload(ctx, s.bucketName, session.ObjectName, session.UploadID); abortErr != nil {
+			s.logger.Warnf("Failed to abort rejected multipart upload to MinIO: %v", abortErr)
+		}
+		return "", s.uploadError(span, maxSizeError(s.uploadPolicy.MaxSize))
+	}
+
+	_, err = core.CompleteMultipartUpload(ctx, s.bucketName, session.ObjectName, session.UploadID, completeParts, minio.PutObjectOptions{ContentType: session.ContentType})
+	if err != nil {
+		return "", s.multipartError(span, "complete multipart upload to MinIO", err)
+	}
+
+	span.SetStatus(codes.Ok, "Multipart upload completed successfully")
+	s.logger.Infof("Successfully completed multipart upload to MinIO: %s", session.ObjectName)
+	return session.ObjectName, nil
+}
+
+func (s *minioService) AbortMultipartUpload(ctx context.Context, session UploadSession) error {
+	ctx, span := s.trace(ctx, "minio.abort-multipart-upload")
+	defer span.End()
+
+	s.sessionAttributes(span, session)
+	if err := (minio.Core{Client: s.minioClient}).AbortMultipartUpload(ctx, s.bucketName, session.ObjectName, session.UploadID); err != nil {
+		return s.multipartError(span, "abort multipart upload to MinIO", err)
+	}
+
+	span.SetStatus(codes.Ok, "Multipart upload aborted successfully")
+	s.logger.Infof("Successfully aborted multipart upload to MinIO: %s", session.ObjectName)
+	return nil
+}
+
+func (s *minioService) PresignedPartURL(ctx context.Context, session UploadSession, partNumber int, expiry time.Duration) (string, error) {
+	ctx, span := s.trace(ctx, "minio.presigned-part-url")
+	defer span.End()
+
+	if expiry <= 0 {
+		expiry = DefaultUploadURLExpiry
+	}
+	s.sessionAttributes(span, session)
+	span.SetAttributes(
+		attribute.Int("minio.part_number", partNumber),
+		attribute.String("minio.expiry", expiry.String()),
+	)
+
+	var presigned *url.URL
+	err := validatePartNumber(partNumber)
+	if err == nil {
+		params := url.Values{}
+		params.Set("partNumber", strconv.Itoa(partNumber))
+		params.Set("uploadId", session.UploadID)
+		presigned, err = s.minioClient.Presign(ctx, http.MethodPut, s.bucketName, session.ObjectName, expiry, params)
+	}
+	if err != nil {
+		return "", s.multipartError(span, "presign part upload to MinIO", err)
+	}
+
+	span.SetStatus(codes.Ok, "Part URL presigned successfully")
+	return presigned.String(), nil
+}
+
+// listParts lists every uploaded part of session
+func (s *minioService) listParts(ctx context.Context, session UploadSession) ([]PartInfo, error) {
+	core := minio.Core{Client: s.minioClient}
+	var parts []PartInfo
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(ctx, s.bucketName, session.ObjectName, session.UploadID, marker, MaxPartNumber)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, PartInfo{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// sessionAttributes sets the span attributes of session
+func (s *minioService) sessionAttributes(span trace.Span, session UploadSession) {
+	span.SetAttributes(
+		attribute.String("minio.bucket", s.bucketName),
+		attribute.String("minio.object_name", session.ObjectName),
+		attribute.String("minio.upload_id", session.UploadID),
+	)
+}
+
+// multipartError records a failed multipart operation, unknown uploads become ErrUploadSessionNotFound
+func (s *minioService) multipartError(span trace.Span, operation string, err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+		err = fmt.Errorf("%w: %v", ErrUploadSessionNotFound, err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	s.logger.Errorf("Failed to %s: %v", operation, err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+func validatePartNumber(partNumber int) error {
+	if partNumber < 1 || partNumber > MaxPartNumber {
+		return fmt.Errorf("part number %d is not between 1 and %d", partNumber, MaxPartNumber)
+	}
+	return nil
+}
+
+// UploadSessionStore keeps upload sessions between the requests of a client
+type UploadSessionStore interface {
+	Save(ctx context.Context, session UploadSession) error
+	// Get returns ErrUploadSessionNotFound for unknown or expired sessions
+	Get(ctx context.Context, uploadID string) (UploadSession, error)
+	Delete(ctx context.Context, uploadID string) error
+}
+
+type redisUploadSessionStore struct {
+	redis redis.RedisClient
+	ttl   time.Duration
+}
+
+// NewRedisUploadSessionStore returns an UploadSessionStore keeping sessions as JSON in Redis for ttl,
+// DefaultUploadSessionTTL when zero. Each Save restarts the ttl
+func NewRedisUploadSessionStore(redisClient redis.RedisClient, ttl time.Duration) UploadSessionStore {
+	if ttl <= 0 {
+		ttl = DefaultUploadSessionTTL
+	}
+	return &redisUploadSessionStore{redis: redisClient, ttl: ttl}
+}
+
+func (s *redisUploadSessionStore) Save(ctx context.Context, session UploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, uploadSessionRedisKey(session.UploadID), string(data), s.ttl)
+}
+
+func (s *redisUploadSessionStore) Get(ctx context.Context, uploadID string) (UploadSession, error) {
+	data, err := s.redis.Get(ctx, uploadSessionRedisKey(uploadID))
+	if errors.Is(err, goredis.Nil) {
+		return UploadSession{}, ErrUploadSessionNotFound
+	}
+	if err != nil {
+		return UploadSession{}, err
+	}
+	var session UploadSession
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return UploadSession{}, err
+	}
+	return session, nil
+}
+
+func (s *redisUploadSessionStore) Delete(ctx context.Context, uploadID string) error {
+	return s.redis.Del(ctx, uploadSessionRedisKey(uploadID))
+}
+
+func uploadSessionRedisKey(uploadID string) string {
+	return "minio:upload-session:" + uploadID
+}
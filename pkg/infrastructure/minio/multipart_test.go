@@ -0,0 +1,244 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// memoryRedis keeps the values of Set, Get and Del in memory, other methods panic through the nil
+// embedded interface
+type memoryRedis struct {
+	redis.RedisClient
+	values map[string]string
+	ttls   map[string]time.Duration
+}
+
+func (m *memoryRedis) Set(ctx context.Context, key string, val any, exp time.Duration) error {
+	m.values[key] = val.(string)
+	m.ttls[key] = exp
+	return nil
+}
+
+func (m *memoryRedis) Get(ctx context.Context, key string) (string, error) {
+	value, ok := m.values[key]
+	if !ok {
+		return "", goredis.Nil
+	}
+	return value, nil
+}
+
+func (m *memoryRedis) Del(ctx context.Context, key string) error {
+	delete(m.values, key)
+	return nil
+}
+
+func TestMultipartUploadResume(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.buckets["test"] = true
+	ctx := context.Background()
+
+	session, err := s.StartMultipartUpload(ctx, "surveys", "../survey video.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !objectNamePattern("surveys", "survey video.mp4").MatchString(session.ObjectName) || session.UploadID == "" {
+		t.Fatalf("session = %+v", session)
+	}
+
+	chunks := []string{"first-chunk|", "second-chunk|", "last"}
+	if _, err := s.UploadPart(ctx, session, 3, strings.NewReader(chunks[2]), int64(len(chunks[2]))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UploadPart(ctx, session, 1, strings.NewReader("stale"), 5); err != nil {
+		t.Fatal(err)
+	}
+	// Uploading a part again replaces it
+	part, err := s.UploadPart(ctx, session, 1, strings.NewReader(chunks[0]), int64(len(chunks[0])))
+	if err != nil || part.PartNumber != 1 || part.Size != int64(len(chunks[0])) || part.ETag == "" {
+		t.Fatalf("UploadPart = %+v, %v", part, err)
+	}
+
+	// The client reconnects with its serialized session and resumes with the missing part
+	data, err := json.Marshal(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resumed UploadSession
+	if err := json.Unmarshal(data, &resumed); err != nil {
+		t.Fatal(err)
+	}
+	parts, err := s.ListUploadedParts(ctx, resumed)
+	if err != nil || len(parts) != 2 || parts[0].PartNumber != 1 || parts[1].PartNumber != 3 {
+		t.Fatalf("ListUploadedParts = %+v, %v, want parts 1 and 3", parts, err)
+	}
+	if _, err := s.UploadPart(ctx, resumed, 2, strings.NewReader(chunks[1]), int64(len(chunks[1]))); err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := s.CompleteMultipartUpload(ctx, resumed)
+	if err != nil || name != session.ObjectName {
+		t.Fatalf("CompleteMultipartUpload = %q, %v", name, err)
+	}
+	object, ok := fake.object(name)
+	if !ok || string(object.data) != strings.Join(chunks, "") || object.header.Get("Content-Type") != "video/mp4" {
+		t.Errorf("object = %q %v, want the parts assembled in order", object.data, object.header)
+	}
+	if _, err := s.UploadPart(ctx, resumed, 4, strings.NewReader("late"), 4); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("UploadPart after completion = %v, want ErrUploadSessionNotFound", err)
+	}
+}
+
+func TestMultipartUploadAbort(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.buckets["test"] = true
+	ctx := context.Background()
+
+	session, err := s.StartMultipartUpload(ctx, "surveys", "video.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UploadPart(ctx, session, 1, strings.NewReader("part one"), 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AbortMultipartUpload(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.UploadPart(ctx, session, 2, strings.NewReader("part two"), 8); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("UploadPart after abort = %v, want ErrUploadSessionNotFound", err)
+	}
+	if _, err := s.CompleteMultipartUpload(ctx, session); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("CompleteMultipartUpload after abort = %v, want ErrUploadSessionNotFound", err)
+	}
+	if err := s.AbortMultipartUpload(ctx, session); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("AbortMultipartUpload twice = %v, want ErrUploadSessionNotFound", err)
+	}
+	if keys := fake.keys(); len(keys) != 0 {
+		t.Errorf("objects = %v, want none", keys)
+	}
+}
+
+func TestMultipartUploadValidation(t *testing.T) {
+	fake, s := newFakeS3(t, WithUploadPolicy(UploadPolicy{AllowedExtensions: []string{".mp4"}, MaxSize: 10}))
+	fake.buckets["test"] = true
+	ctx := context.Background()
+
+	if _, err := s.StartMultipartUpload(ctx, "surveys", "payload.exe", "video/mp4"); policyRule(err) != RuleExtension {
+		t.Errorf("StartMultipartUpload = %v, want the extension rule", err)
+	}
+
+	session, err := s.StartMultipartUpload(ctx, "surveys", "video.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, number := range []int{0, MaxPartNumber + 1} {
+		if _, err := s.UploadPart(ctx, session, number, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("UploadPart accepted part number %d", number)
+		}
+		if _, err := s.PresignedPartURL(ctx, session, number, 0); err == nil {
+			t.Errorf("PresignedPartURL accepted part number %d", number)
+		}
+	}
+	if _, err := s.CompleteMultipartUpload(ctx, session); err == nil {
+		t.Error("CompleteMultipartUpload without parts succeeded")
+	}
+
+	for i := 1; i <= 2; i++ {
+		if _, err := s.UploadPart(ctx, session, i, strings.NewReader("123456"), 6); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CompleteMultipartUpload(ctx, session); policyRule(err) != RuleMaxSize {
+		t.Errorf("CompleteMultipartUpload = %v, want the max size rule", err)
+	}
+	if _, err := s.ListUploadedParts(ctx, session); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("ListUploadedParts of the rejected upload = %v, want it aborted", err)
+	}
+	if keys := fake.keys(); len(keys) != 0 {
+		t.Errorf("objects = %v, want none", keys)
+	}
+}
+
+func TestPresignedPartURL(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.buckets["test"] = true
+	ctx := context.Background()
+
+	session, err := s.StartMultipartUpload(ctx, "surveys", "video.mp4", "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, chunk := range []string{"browser-", "parts"} {
+		raw, err := s.PresignedPartURL(ctx, session, i+1, 10*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := parseURL(t, raw).Query()
+		if query.Get("partNumber") != strconv.Itoa(i+1) || query.Get("uploadId") != session.UploadID || query.Get("X-Amz-Expires") != "600" {
+			t.Fatalf("URL = %s", raw)
+		}
+
+		req, _ := http.NewRequest(http.MethodPut, raw, bytes.NewReader([]byte(chunk)))
+		resp, err := fake.client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "" {
+			t.Fatalf("part upload = %d, ETag %q", resp.StatusCode, resp.Header.Get("ETag"))
+		}
+		session.Parts = append(session.Parts, PartInfo{PartNumber: i + 1, ETag: resp.Header.Get("ETag"), Size: int64(len(chunk))})
+	}
+
+	name, err := s.CompleteMultipartUpload(ctx, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := fake.object(name); string(object.data) != "browser-parts" {
+		t.Errorf("object = %q", object.data)
+	}
+}
+
+func TestRedisUploadSessionStore(t *testing.T) {
+	client := &memoryRedis{values: map[string]string{}, ttls: map[string]time.Duration{}}
+	store := NewRedisUploadSessionStore(client, 0)
+	ctx := context.Background()
+	session := UploadSession{
+		UploadID:    "upload-1",
+		ObjectName:  "surveys/20240101000000-video.mp4",
+		ContentType: "video/mp4",
+		CreatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Parts:       []PartInfo{{PartNumber: 1, ETag: "abc", Size: MinPartSize}},
+	}
+
+	if err := store.Save(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := client.ttls["minio:upload-session:upload-1"]; ttl != DefaultUploadSessionTTL {
+		t.Errorf("ttl = %s, want %s", ttl, DefaultUploadSessionTTL)
+	}
+	got, err := store.Get(ctx, "upload-1")
+	if err != nil || got.ObjectName != session.ObjectName || !got.CreatedAt.Equal(session.CreatedAt) || len(got.Parts) != 1 || got.Parts[0] != session.Parts[0] {
+		t.Errorf("Get = %+v, %v, want %+v", got, err, session)
+	}
+	if err := store.Delete(ctx, "upload-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "upload-1"); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrUploadSessionNotFound", err)
+	}
+
+	if err := NewRedisUploadSessionStore(client, time.Hour).Save(ctx, session); err != nil || client.ttls["minio:upload-session:upload-1"] != time.Hour {
+		t.Errorf("Save with ttl = %v, ttl %s", err, client.ttls["minio:upload-session:upload-1"])
+	}
+}
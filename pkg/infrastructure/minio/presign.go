@@ -0,0 +1,201 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default expiries of presigned URLs
+const (
+	DefaultUploadURLExpiry   = 15 * time.Minute
+	DefaultDownloadURLExpiry = time.Hour
+)
+
+// UploadConstraints restricts presigned uploads. A MaxSize switches to a POST policy since presigned
+// PUT URLs cannot limit the size
+type UploadConstraints struct {
+	// ContentType is the required content type, a "image/*" prefix accepts any image with a POST policy
+	ContentType string
+	// MaxSize is the largest accepted upload in bytes, 0 for no limit
+	MaxSize int64
+}
+
+// PresignedUpload describes how a client uploads straight to MinIO
+type PresignedUpload struct {
+	// URL receives the upload
+	URL string `json:"url"`
+	// Method is PUT for presigned PUT URLs, POST for POST policies (multipart/form-data)
+	Method string `json:"method"`
+	// Headers must be sent with a PUT upload
+	Headers map[string]string `json:"headers,omitempty"`
+	// FormData are the fields of a POST upload, the file goes in a last "file" field
+	FormData map[string]string `json:"form_data,omitempty"`
+	// ObjectName is the name of the stored object
+	ObjectName string `json:"object_name"`
+	// ExpiresAt is the expiry of the URL
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *minioService) PresignedUploadURL(ctx context.Context, folder, filename string, expiry time.Duration, constraints UploadConstraints) (PresignedUpload, error) {
+	ctx, span := s.trace(ctx, "minio.presigned-upload-url")
+	defer span.End()
+
+	if expiry <= 0 {
+		expiry = DefaultUploadURLExpiry
+	}
+	bucket := s.bucketName
+	objectName := buildObjectName(folder, filename, time.Now())
+	span.SetAttributes(
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.object_name", objectName),
+		attribute.String("minio.content_type", constraints.ContentType),
+		attribute.Int64("minio.max_size", constraints.MaxSize),
+		attribute.String("minio.expiry", expiry.String()),
+	)
+
+	upload := PresignedUpload{ObjectName: objectName, ExpiresAt: time.Now().Add(expiry)}
+	var err error
+	contentTypePrefix, isPrefix := strings.CutSuffix(constraints.ContentType, "*")
+	if constraints.MaxSize > 0 || isPrefix {
+		upload.Method = http.MethodPost
+		upload.URL, upload.FormData, err = s.presignPostPolicy(ctx, bucket, objectName, upload.ExpiresAt, constraints, contentTypePrefix, isPrefix)
+	} else {
+		upload.Method = http.MethodPut
+		headers := http.Header{}
+		if constraints.ContentType != "" {
+			headers.Set("Content-Type", constraints.ContentType)
+			upload.Headers = map[string]string{"Content-Type": constraints.ContentType}
+		}
+		var presigned *url.URL
+		if presigned, err = s.minioClient.PresignHeader(ctx, http.MethodPut, bucket, objectName, expiry, nil, headers); err == nil {
+			upload.URL = presigned.String()
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to presign upload to MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return PresignedUpload{}, err
+	}
+
+	span.SetAttributes(attribute.String("minio.method", upload.Method))
+	span.SetStatus(codes.Ok, "Upload URL presigned successfully")
+	return upload, nil
+}
+
+// presignPostPolicy presigns a POST policy upload of objectName
+func (s *minioService) presignPostPolicy(ctx context.Context, bucket, objectName string, expiresAt time.Time, constraints UploadConstraints, contentTypePrefix string, isPrefix bool) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	err := errors.Join(
+		policy.SetBucket(bucket),
+		policy.SetKey(objectName),
+		policy.SetExpires(expiresAt.UTC()),
+	)
+	switch {
+	case isPrefix:
+		err = errors.Join(err, policy.SetContentTypeStartsWith(contentTypePrefix))
+	case constraints.ContentType != "":
+		err = errors.Join(err, policy.SetContentType(constraints.ContentType))
+	}
+	if constraints.MaxSize > 0 {
+		err = errors.Join(err, policy.SetContentLengthRange(0, constraints.MaxSize))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	presigned, formData, err := s.minioClient.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return "", nil, err
+	}
+	return presigned.String(), formData, nil
+}
+
+// DownloadOption configures DownloadFile
+type DownloadOption func(*downloadOptions)
+
+type downloadOptions struct {
+	expiry             time.Duration
+	contentDisposition string
+}
+
+// WithDownloadExpiry sets the validity of the download URL, DefaultDownloadURLExpiry by default
+func WithDownloadExpiry(expiry time.Duration) DownloadOption {
+	return func(o *downloadOptions) {
+		o.expiry = expiry
+	}
+}
+
+// WithDownloadFilename makes browsers save the download as filename instead of the object name
+func WithDownloadFilename(filename string) DownloadOption {
+	return WithResponseContentDisposition(mime.FormatMediaType("attachment", map[string]string{"filename": SanitizeFilename(filename)}))
+}
+
+// WithResponseContentDisposition overrides the Content-Disposition header of the download, e.g. "inline"
+func WithResponseContentDisposition(disposition string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.contentDisposition = disposition
+	}
+}
+
+func (s *minioService) DownloadFile(ctx context.Context, fileID string, folder string, opts ...DownloadOption) (string, error) {
+	ctx, span := s.trace(ctx, "minio.download-file")
+	defer span.End()
+
+	options := downloadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return s.presignDownload(ctx, span, fileID, folder, options)
+}
+
+func (s *minioService) PresignedDownloadURL(ctx context.Context, fileID, folder string, expiry time.Duration) (string, error) {
+	ctx, span := s.trace(ctx, "minio.presigned-download-url")
+	defer span.End()
+
+	return s.presignDownload(ctx, span, fileID, folder, downloadOptions{expiry: expiry})
+}
+
+// presignDownload presigns a GET of the object with the response overrides of options
+func (s *minioService) presignDownload(ctx context.Context, span trace.Span, fileID, folder string, options downloadOptions) (string, error) {
+	expiry := options.expiry
+	if expiry <= 0 {
+		expiry = DefaultDownloadURLExpiry
+	}
+	bucket := s.bucketName
+	objectName := folder + "/" + fileID
+	span.SetAttributes(
+		attribute.String("minio.file_id", fileID),
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.object_name", objectName),
+		attribute.String("minio.expiry", expiry.String()),
+	)
+
+	var params url.Values
+	if options.contentDisposition != "" {
+		params = url.Values{"response-content-disposition": {options.contentDisposition}}
+		span.SetAttributes(attribute.String("minio.content_disposition", options.contentDisposition))
+	}
+	presigned, err := s.minioClient.PresignedGetObject(ctx, bucket, objectName, expiry, params)
+	if err != nil {
+		s.logger.Errorf("Failed to presign download from MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", err
+	}
+
+	span.SetStatus(codes.Ok, "Download URL presigned successfully")
+	return presigned.String(), nil
+}
@@ -0,0 +1,192 @@
+package minio
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// parseURL parses a presigned URL
+func parseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestPresignedUploadURLPut(t *testing.T) {
+	fake, s := newFakeS3(t)
+	tracer := &recordingTracer{}
+	s.tracer = tracer
+
+	before := time.Now()
+	upload, err := s.PresignedUploadURL(context.Background(), "uploads", "photo.jpg", 0, UploadConstraints{ContentType: "image/jpeg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.Method != http.MethodPut || upload.Headers["Content-Type"] != "image/jpeg" || upload.FormData != nil {
+		t.Errorf("upload = %+v, want a PUT with the Content-Type header", upload)
+	}
+	if !objectNamePattern("uploads", "photo.jpg").MatchString(upload.ObjectName) {
+		t.Errorf("object name = %q, want uploads/<timestamp>-photo.jpg", upload.ObjectName)
+	}
+	if expiry := upload.ExpiresAt.Sub(before); expiry < DefaultUploadURLExpiry || expiry > DefaultUploadURLExpiry+time.Minute {
+		t.Errorf("expires in %s, want the default %s", expiry, DefaultUploadURLExpiry)
+	}
+
+	presigned := parseURL(t, upload.URL)
+	query := presigned.Query()
+	if presigned.Path != "/test/"+upload.ObjectName || query.Get("X-Amz-Expires") != "900" ||
+		!strings.Contains(query.Get("X-Amz-SignedHeaders"), "content-type") || query.Get("X-Amz-Signature") == "" {
+		t.Errorf("presigned URL = %s, want a signed PUT of the object for 900s including the content type", upload.URL)
+	}
+
+	// The client uploads straight to the server with the URL
+	fake.buckets["test"] = true
+	req, _ := http.NewRequest(upload.Method, upload.URL, strings.NewReader("jpeg"))
+	req.Header.Set("Content-Type", "image/jpeg")
+	if resp, err := fake.client.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload with the presigned URL = %v, %v", resp, err)
+	}
+	if _, ok := fake.object(upload.ObjectName); !ok {
+		t.Error("object not stored by the presigned upload")
+	}
+
+	span := tracer.span("minio.presigned-upload-url")
+	if span == nil || !span.ended || span.status != codes.Ok || span.attributes["minio.method"].AsString() != http.MethodPut {
+		t.Errorf("span = %+v, want an ended OK span with the method", span)
+	}
+}
+
+func TestPresignedUploadURLPostPolicy(t *testing.T) {
+	_, s := newFakeS3(t)
+
+	tests := []struct {
+		name        string
+		constraints UploadConstraints
+		condition   string
+	}{
+		{"max size", UploadConstraints{ContentType: "application/pdf", MaxSize: 1 << 20}, `["content-length-range", 0, 1048576]`},
+		{"content type prefix", UploadConstraints{ContentType: "image/*"}, `["starts-with","$Content-Type","image/"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upload, err := s.PresignedUploadURL(context.Background(), "uploads", "file.bin", time.Minute, tt.constraints)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upload.Method != http.MethodPost || upload.Headers != nil || upload.FormData["key"] != upload.ObjectName {
+				t.Fatalf("upload = %+v, want a POST policy of the object", upload)
+			}
+			if parseURL(t, upload.URL).Path != "/test/" {
+				t.Errorf("POST URL = %s, want the bucket URL", upload.URL)
+			}
+			policy, err := base64.StdEncoding.DecodeString(upload.FormData["policy"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded struct {
+				Expiration string            `json:"expiration"`
+				Conditions []json.RawMessage `json:"conditions"`
+			}
+			if err := json.Unmarshal(policy, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(policy), tt.condition) {
+				t.Errorf("policy = %s, want the %s condition", policy, tt.condition)
+			}
+			if expiration, err := time.Parse(time.RFC3339Nano, decoded.Expiration); err != nil || expiration.Sub(time.Now()) > time.Minute {
+				t.Errorf("policy expiration = %q, want within a minute", decoded.Expiration)
+			}
+			if upload.FormData["x-amz-signature"] == "" {
+				t.Error("POST policy without signature")
+			}
+		})
+	}
+}
+
+func TestPresignedDownloadURL(t *testing.T) {
+	_, s := newFakeS3(t)
+	tracer := &recordingTracer{}
+	s.tracer = tracer
+
+	raw, err := s.PresignedDownloadURL(context.Background(), "a.pdf", "docs", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presigned := parseURL(t, raw)
+	if presigned.Path != "/test/docs/a.pdf" || presigned.Query().Get("X-Amz-Expires") != "3600" {
+		t.Errorf("URL = %s, want a GET of docs/a.pdf for the default hour", raw)
+	}
+
+	raw, err = s.PresignedDownloadURL(context.Background(), "a.pdf", "docs", 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseURL(t, raw).Query().Get("X-Amz-Expires") != "300" {
+		t.Errorf("URL = %s, want a 300s expiry", raw)
+	}
+	if span := tracer.span("minio.presigned-download-url"); span == nil || span.attributes["minio.object_name"].AsString() != "docs/a.pdf" {
+		t.Errorf("span = %+v, want the object name", span)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	_, s := newFakeS3(t)
+	tracer := &recordingTracer{}
+	s.tracer = tracer
+
+	raw, err := s.DownloadFile(context.Background(), "20240101000000-a.pdf", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parseURL(t, raw).Query()
+	if query.Get("X-Amz-Expires") != "3600" || query.Has("response-content-disposition") {
+		t.Errorf("URL = %s, want the default hour without disposition override", raw)
+	}
+	span := tracer.span("minio.download-file")
+	if span == nil || !span.ended || span.status != codes.Ok || span.attributes["minio.object_name"].AsString() != "docs/20240101000000-a.pdf" {
+		t.Errorf("span = %+v, want the object name", span)
+	}
+
+	tests := []struct {
+		name        string
+		opts        []DownloadOption
+		expires     string
+		disposition string
+	}{
+		{name: "expiry", opts: []DownloadOption{WithDownloadExpiry(10 * time.Minute)}, expires: "600"},
+		{
+			name:        "filename",
+			opts:        []DownloadOption{WithDownloadExpiry(7 * 24 * time.Hour), WithDownloadFilename("Hóa đơn/../tháng 1.pdf")},
+			expires:     "604800",
+			disposition: `attachment; filename*=utf-8''th%C3%A1ng%201.pdf`,
+		},
+		{name: "ascii filename", opts: []DownloadOption{WithDownloadFilename("invoice.pdf")}, expires: "3600", disposition: "attachment; filename=invoice.pdf"},
+		{name: "inline", opts: []DownloadOption{WithResponseContentDisposition("inline")}, expires: "3600", disposition: "inline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := s.DownloadFile(context.Background(), "20240101000000-a.pdf", "docs", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := parseURL(t, raw).Query()
+			if query.Get("X-Amz-Expires") != tt.expires {
+				t.Errorf("X-Amz-Expires = %q, want %q", query.Get("X-Amz-Expires"), tt.expires)
+			}
+			if got := query.Get("response-content-disposition"); got != tt.disposition {
+				t.Errorf("response-content-disposition = %q, want %q", got, tt.disposition)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ErrObjectNotFound is returned for objects that do not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+func (s *minioService) StatFile(ctx context.Context, fileID, folder string) (FileInfo, error) {
+	ctx, span := s.trace(ctx, "minio.stat-file")
+	defer span.End()
+
+	bucket := s.bucketName
+	objectName := folder + "/" + fileID
+	span.SetAttributes(
+		attribute.String("minio.file_id", fileID),
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.object_name", objectName),
+	)
+
+	info, err := s.statObject(ctx, bucket, objectName)
+	if errors.Is(err, ErrObjectNotFound) {
+		span.SetAttributes(attribute.Bool("minio.exists", false))
+		return FileInfo{}, err
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to stat file in MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return FileInfo{}, err
+	}
+
+	span.SetAttributes(
+		attribute.Bool("minio.exists", true),
+		attribute.Int64("minio.size", info.Size),
+		attribute.String("minio.content_type", info.ContentType),
+	)
+	span.SetStatus(codes.Ok, "File stat successfully")
+	return info, nil
+}
+
+func (s *minioService) FileExists(ctx context.Context, fileID, folder string) (bool, error) {
+	_, err := s.StatFile(ctx, fileID, folder)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// statObject returns the FileInfo of objectName, ErrObjectNotFound when it does not exist
+func (s *minioService) statObject(ctx context.Context, bucket, objectName string) (FileInfo, error) {
+	object, err := s.minioClient.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			return FileInfo{}, fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		}
+		return FileInfo{}, err
+	}
+	return fileInfoFromObject(object), nil
+}
+
+// isNotFound reports whether err is the missing object or bucket error of MinIO
+func isNotFound(err error) bool {
+	response := minio.ToErrorResponse(err)
+	switch response.Code {
+	case "NoSuchKey", "NoSuchBucket", "NotFound":
+		return true
+	}
+	return response.StatusCode == http.StatusNotFound
+}
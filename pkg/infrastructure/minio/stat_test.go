@@ -0,0 +1,57 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStatFile(t *testing.T) {
+	fake, s := newFakeS3(t)
+	putWithMetadata(fake, "docs/a.pdf")
+	stored, _ := fake.object("docs/a.pdf")
+
+	info, err := s.StatFile(context.Background(), "a.pdf", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "docs/a.pdf" || info.Size != int64(len(stored.data)) || info.ContentType != "application/pdf" ||
+		`"`+info.ETag+`"` != stored.etag() || info.LastModified.IsZero() || info.UserMetadata["Owner"] != "u1" {
+		t.Errorf("StatFile = %+v", info)
+	}
+
+	if _, err := s.StatFile(context.Background(), "missing.pdf", "docs"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("StatFile of a missing object = %v, want ErrObjectNotFound", err)
+	}
+
+	if ok, err := s.FileExists(context.Background(), "a.pdf", "docs"); !ok || err != nil {
+		t.Errorf("FileExists of an existing object = %v, %v", ok, err)
+	}
+	if ok, err := s.FileExists(context.Background(), "missing.pdf", "docs"); ok || err != nil {
+		t.Errorf("FileExists of a missing object = %v, %v", ok, err)
+	}
+}
+
+func TestDeleteFile(t *testing.T) {
+	fake, s := newFakeS3(t)
+	fake.put("docs/a.pdf", "application/pdf", []byte("a"))
+
+	if err := s.DeleteFile(context.Background(), "a.pdf", "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.object("docs/a.pdf"); ok {
+		t.Error("DeleteFile kept the object")
+	}
+	if err := s.DeleteFile(context.Background(), "a.pdf", "docs"); err != nil {
+		t.Errorf("DeleteFile of a missing object = %v, want nil by default", err)
+	}
+
+	fake, strict := newFakeS3(t, WithStrictDelete())
+	fake.put("docs/a.pdf", "application/pdf", []byte("a"))
+	if err := strict.DeleteFile(context.Background(), "a.pdf", "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := strict.DeleteFile(context.Background(), "a.pdf", "docs"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("strict DeleteFile of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
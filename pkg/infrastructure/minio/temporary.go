@@ -0,0 +1,217 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// MetadataTemporaryExpiresAt is the user metadata of temporary uploads, their RFC 3339 expiry
+const MetadataTemporaryExpiresAt = "Temporary-Expires-At"
+
+// DefaultTemporaryTTL is the lifetime of temporary uploads
+const DefaultTemporaryTTL = 24 * time.Hour
+
+// amzMetaPrefix prefixes the user metadata headers of objects
+const amzMetaPrefix = "X-Amz-Meta-"
+
+// WithTemporary tags the upload as temporary, expiring after ttl (DefaultTemporaryTTL when zero). A
+// storage janitor deletes it once expired unless MarkPermanent is called
+func WithTemporary(ttl time.Duration) UploadOption {
+	return func(o *uploadOptions) {
+		if ttl <= 0 {
+			ttl = DefaultTemporaryTTL
+		}
+		o.temporaryTTL = ttl
+	}
+}
+
+// metadata returns the user metadata of an upload made at now
+func (o uploadOptions) metadata(now time.Time) map[string]string {
+	if o.temporaryTTL <= 0 {
+		return o.userMetadata
+	}
+	metadata := maps.Clone(o.userMetadata)
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[MetadataTemporaryExpiresAt] = now.Add(o.temporaryTTL).UTC().Format(time.RFC3339)
+	return metadata
+}
+
+// TemporaryExpiry returns the expiry of a temporary upload, false for permanent files
+func TemporaryExpiry(info FileInfo) (time.Time, bool) {
+	key, ok := temporaryMetadataKey(info.UserMetadata)
+	if !ok {
+		return time.Time{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, info.UserMetadata[key])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiresAt, true
+}
+
+// temporaryMetadataKey finds the expiry in user metadata, listings keep the "X-Amz-Meta-" prefix that
+// stats strip
+func temporaryMetadataKey(metadata map[string]string) (string, bool) {
+	for key := range metadata {
+		name := key
+		if len(name) > len(amzMetaPrefix) && strings.EqualFold(name[:len(amzMetaPrefix)], amzMetaPrefix) {
+			name = name[len(amzMetaPrefix):]
+		}
+		if strings.EqualFold(name, MetadataTemporaryExpiresAt) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (s *minioService) ListTemporaryFiles(ctx context.Context, folder string, expiredBefore time.Time) ([]FileInfo, error) {
+	ctx, span := s.trace(ctx, "minio.list-temporary-files")
+	defer span.End()
+
+	bucket := s.bucketName
+	prefix := folderPrefix(folder)
+	span.SetAttributes(
+		attribute.String("minio.folder", folder),
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.prefix", prefix),
+		attribute.String("minio.expired_before", expiredBefore.UTC().Format(time.RFC3339)),
+	)
+
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objects := s.minioClient.ListObjects(listCtx, bucket, minio.ListObjectsOptions{
+		Prefix:       prefix,
+		Recursive:    true,
+		WithMetadata: true,
+	})
+
+	expired := []FileInfo{}
+	var listErr error
+	for object := range objects {
+		if listErr != nil {
+			continue
+		}
+		if object.Err != nil {
+			listErr = object.Err
+			cancel()
+			continue
+		}
+		info := fileInfoFromObject(object)
+		if expiresAt, ok := TemporaryExpiry(info); ok && expiresAt.Before(expiredBefore) {
+			expired = append(expired, info)
+		}
+	}
+	if listErr != nil {
+		s.logger.Errorf("Failed to list temporary files from MinIO: %v", listErr)
+		span.RecordError(listErr)
+		span.SetStatus(codes.Error, listErr.Error())
+		return nil, listErr
+	}
+
+	span.SetAttributes(attribute.Int("minio.count", len(expired)))
+	span.SetStatus(codes.Ok, "Temporary files listed successfully")
+	return expired, nil
+}
+
+func (s *minioService) MarkPermanent(ctx context.Context, objectName string) error {
+	ctx, span := s.trace(ctx, "minio.mark-permanent")
+	defer span.End()
+
+	bucket := s.bucketName
+	span.SetAttributes(
+		attribute.String("minio.bucket", bucket),
+		attribute.String("minio.object_name", objectName),
+	)
+
+	object, err := s.minioClient.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err == nil {
+		key, ok := temporaryMetadataKey(object.UserMetadata)
+		if !ok {
+			span.SetStatus(codes.Ok, "File already permanent")
+			return nil
+		}
+		// Metadata cannot be edited in place, the object is copied onto itself with the tag removed
+		metadata := maps.Clone(object.UserMetadata)
+		delete(metadata, key)
+		_, err = s.minioClient.CopyObject(ctx,
+			minio.CopyDestOptions{
+				Bucket:             bucket,
+				Object:             objectName,
+				ReplaceMetadata:    true,
+				UserMetadata:       metadata,
+				ContentType:        object.ContentType,
+				CacheControl:       object.Metadata.Get("Cache-Control"),
+				ContentDisposition: object.Metadata.Get("Content-Disposition"),
+				ContentEncoding:    object.Metadata.Get("Content-Encoding"),
+				ContentLanguage:    object.Metadata.Get("Content-Language"),
+			},
+			minio.CopySrcOptions{Bucket: bucket, Object: objectName},
+		)
+	}
+	if isNotFound(err) {
+		err = fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to mark file permanent in MinIO: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "File marked permanent successfully")
+	s.logger.Infof("Successfully marked file permanent in MinIO: %s", objectName)
+	return nil
+}
+
+type janitorStorage struct {
+	service MinioService
+	folder  string
+}
+
+// NewJanitorStorage returns the storage.FileStorage of the temporary uploads under folder, the whole
+// bucket when empty, for storage.NewJanitor. Listing expiries requires a MinIO server
+func NewJanitorStorage(service MinioService, folder string) storage.FileStorage {
+	return &janitorStorage{service: service, folder: folder}
+}
+
+func (j *janitorStorage) ListExpired(ctx context.Context, before time.Time) ([]string, error) {
+	files, err := j.service.ListTemporaryFiles(ctx, j.folder, before)
+	if err != nil {
+		return nil, err
+	}
+	objectNames := make([]string, 0, len(files))
+	for _, file := range files {
+		objectNames = append(objectNames, file.Name)
+	}
+	return objectNames, nil
+}
+
+func (j *janitorStorage) Delete(ctx context.Context, objectNames []string) ([]string, error) {
+	prefix := folderPrefix(j.folder)
+	fileIDs := make([]string, 0, len(objectNames))
+	for _, objectName := range objectNames {
+		fileIDs = append(fileIDs, strings.TrimPrefix(objectName, prefix))
+	}
+	failed, err := j.service.DeleteFiles(ctx, j.folder, fileIDs)
+	failedNames := make([]string, 0, len(failed))
+	for _, fileID := range failed {
+		failedNames = append(failedNames, prefix+fileID)
+	}
+	return failedNames, err
+}
+
+func (j *janitorStorage) MarkPermanent(ctx context.Context, objectName string) error {
+	return j.service.MarkPermanent(ctx, objectName)
+}
@@ -0,0 +1,111 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/storage"
+)
+
+func TestTemporaryExpiry(t *testing.T) {
+	expiresAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     bool
+	}{
+		{"stat", map[string]string{"Temporary-Expires-At": "2024-05-01T12:00:00Z"}, true},
+		{"listing", map[string]string{"X-Amz-Meta-Temporary-Expires-At": "2024-05-01T12:00:00Z"}, true},
+		{"lowercase", map[string]string{"x-amz-meta-temporary-expires-at": "2024-05-01T12:00:00Z"}, true},
+		{"permanent", map[string]string{"Owner": "u1"}, false},
+		{"invalid", map[string]string{"Temporary-Expires-At": "tomorrow"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TemporaryExpiry(FileInfo{UserMetadata: tt.metadata})
+			if ok != tt.want || (ok && !got.Equal(expiresAt)) {
+				t.Errorf("TemporaryExpiry = %s, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestJanitorCleansUpExpiredTemporaryUploads(t *testing.T) {
+	fake, s := newFakeS3(t)
+	ctx := context.Background()
+	start := time.Now()
+
+	upload := func(folder, name string, opts ...UploadOption) string {
+		t.Helper()
+		objectName, err := s.UploadBytes(ctx, folder, name, []byte(name), "image/png", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return objectName
+	}
+	orphan := upload("avatars", "orphan.png", WithTemporary(time.Hour), WithUserMetadata(map[string]string{"Owner": "u1"}))
+	attached := upload("avatars", "attached.png", WithTemporary(48*time.Hour), WithUserMetadata(map[string]string{"Owner": "u2"}), WithCacheControl("max-age=60"))
+	permanent := upload("avatars", "permanent.png")
+	otherFolder := upload("docs", "draft.png", WithTemporary(time.Hour))
+
+	info, err := s.StatFile(ctx, strings.TrimPrefix(orphan, "avatars/"), "avatars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt, ok := TemporaryExpiry(info)
+	if !ok || expiresAt.Before(start.Add(time.Hour).Truncate(time.Second)) || expiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("TemporaryExpiry = %s, %v, want about an hour from now", expiresAt, ok)
+	}
+
+	now := start.Add(2 * time.Hour)
+	logger, _ := test.NewNullLogger()
+	janitor := storage.NewJanitor(NewJanitorStorage(s, "avatars"), logger, storage.WithClock(func() time.Time { return now }))
+
+	deleted, err := janitor.RunCleanup(ctx, 0)
+	if err != nil || deleted != 1 {
+		t.Fatalf("RunCleanup = %d, %v, want the orphan deleted", deleted, err)
+	}
+	if got, want := fake.keys(), []string{attached, permanent, otherFolder}; !slices.Equal(got, want) {
+		t.Errorf("objects = %v, want %v", got, want)
+	}
+
+	if err := janitor.MarkPermanent(ctx, attached); err != nil {
+		t.Fatal(err)
+	}
+	object, _ := fake.object(attached)
+	if object.header.Get("X-Amz-Meta-Temporary-Expires-At") != "" || object.header.Get("X-Amz-Meta-Owner") != "u2" ||
+		object.header.Get("Content-Type") != "image/png" || object.header.Get("Cache-Control") != "max-age=60" {
+		t.Errorf("marked object header = %v, want the tag removed and the rest kept", object.header)
+	}
+
+	now = start.Add(72 * time.Hour)
+	if deleted, err := janitor.RunCleanup(ctx, 0); err != nil || deleted != 0 {
+		t.Errorf("RunCleanup after MarkPermanent = %d, %v, want nothing deleted", deleted, err)
+	}
+	if _, ok := fake.object(attached); !ok {
+		t.Error("permanent object was deleted")
+	}
+}
+
+func TestMarkPermanent(t *testing.T) {
+	fake, s := newFakeS3(t)
+	ctx := context.Background()
+	fake.put("avatars/permanent.png", "image/png", []byte("png"))
+
+	requests := len(fake.requests)
+	if err := s.MarkPermanent(ctx, "avatars/permanent.png"); err != nil {
+		t.Fatal(err)
+	}
+	if copies := slices.DeleteFunc(slices.Clone(fake.requests[requests:]), func(r string) bool { return !strings.HasPrefix(r, "PUT") }); len(copies) != 0 {
+		t.Errorf("requests = %v, want no copy of a permanent file", copies)
+	}
+
+	if err := s.MarkPermanent(ctx, "avatars/missing.png"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("MarkPermanent of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
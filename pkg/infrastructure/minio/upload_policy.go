@@ -0,0 +1,212 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rules of UploadPolicyError
+const (
+	RuleMaxSize     = "max_size"
+	RuleExtension   = "extension"
+	RuleContentType = "content_type"
+	RuleScan        = "scan"
+)
+
+// sniffLength is the number of bytes http.DetectContentType looks at
+const sniffLength = 512
+
+// UploadPolicy restricts what UploadFile, UploadReader and UploadBytes store
+type UploadPolicy struct {
+	// AllowedContentTypes are the accepted types detected from the first 512 bytes (http.DetectContentType),
+	// "image/*" accepts any image. The detected type is stored in place of the client Content-Type. Empty
+	// accepts any type
+	AllowedContentTypes []string
+	// AllowedExtensions are the accepted lower case filename extensions, e.g. ".pdf". Empty accepts any
+	AllowedExtensions []string
+	// MaxSize is the largest accepted upload in bytes, 0 for no limit
+	MaxSize int64
+}
+
+// Scanner scans uploads before they are stored, e.g. with ClamAV. A non-nil error rejects the upload
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// UploadPolicyError is returned for uploads violating the UploadPolicy or rejected by the Scanner
+type UploadPolicyError struct {
+	// Rule is the violated rule, one of the Rule* constants
+	Rule    string
+	Message string
+	// Err is the Scanner error of RuleScan
+	Err error
+}
+
+func (e *UploadPolicyError) Error() string {
+	return "upload rejected (" + e.Rule + "): " + e.Message
+}
+
+func (e *UploadPolicyError) Unwrap() error {
+	return e.Err
+}
+
+// ToErrorResponse returns the VALIDATION_ERROR response of the violation
+func (e *UploadPolicyError) ToErrorResponse() *common.ErrorResponse {
+	return common.ValidationErrorI18n(common.ErrorDetail{Field: "file", Message: e.Message, Value: e.Rule})
+}
+
+// WithUploadPolicy enforces policy on every upload
+func WithUploadPolicy(policy UploadPolicy) MinioOption {
+	return func(s *minioService) {
+		s.uploadPolicy = &policy
+	}
+}
+
+// WithScanner scans every upload with scanner before storing it
+func WithScanner(scanner Scanner) MinioOption {
+	return func(s *minioService) {
+		s.scanner = scanner
+	}
+}
+
+// WithPolicy overrides the upload policy of the service for one upload
+func WithPolicy(policy UploadPolicy) UploadOption {
+	return func(o *uploadOptions) {
+		o.policy = &policy
+	}
+}
+
+// SanitizeFilename returns filename without directories, control and reserved characters, in Unicode NFC
+func SanitizeFilename(filename string) string {
+	filename = norm.NFC.String(strings.ReplaceAll(filename, "\\", "/"))
+	filename = path.Base(filename)
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, filename)
+	filename = strings.Trim(filename, " .")
+	if filename == "" {
+		return "file"
+	}
+	return filename
+}
+
+// checkUpload enforces policy and scanner on r, returning the reader to store and the sniffed content
+// type, empty when the policy does not restrict content types
+func (s *minioService) checkUpload(ctx context.Context, policy *UploadPolicy, filename string, r io.Reader, size int64) (io.Reader, string, func(), error) {
+	cleanup := func() {}
+	sniffed := ""
+	if policy == nil && s.scanner == nil {
+		return r, sniffed, cleanup, nil
+	}
+
+	if policy != nil {
+		if len(policy.AllowedExtensions) > 0 {
+			extension := strings.ToLower(path.Ext(filename))
+			if !slices.Contains(policy.AllowedExtensions, extension) {
+				return nil, "", cleanup, &UploadPolicyError{Rule: RuleExtension, Message: fmt.Sprintf("extension %q is not allowed", extension)}
+			}
+		}
+		if policy.MaxSize > 0 {
+			if size > policy.MaxSize {
+				return nil, "", cleanup, maxSizeError(policy.MaxSize)
+			}
+			r = &maxSizeReader{r: r, remaining: policy.MaxSize, maxSize: policy.MaxSize}
+		}
+		if len(policy.AllowedContentTypes) > 0 {
+			head := make([]byte, sniffLength)
+			n, err := io.ReadFull(r, head)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+				return nil, "", cleanup, err
+			}
+			sniffed = http.DetectContentType(head[:n])
+			detected, _, _ := mime.ParseMediaType(sniffed)
+			if !contentTypeAllowed(detected, policy.AllowedContentTypes) {
+				return nil, "", cleanup, &UploadPolicyError{Rule: RuleContentType, Message: fmt.Sprintf("content type %q is not allowed", detected)}
+			}
+			r = io.MultiReader(bytes.NewReader(head[:n]), r)
+		}
+	}
+
+	if s.scanner == nil {
+		return r, sniffed, cleanup, nil
+	}
+	spooled, cleanup, err := spool(r)
+	if err != nil {
+		return nil, "", cleanup, err
+	}
+	if err := s.scanner.Scan(ctx, spooled); err != nil {
+		return nil, "", cleanup, &UploadPolicyError{Rule: RuleScan, Message: "file rejected by scanner", Err: err}
+	}
+	if _, err := spooled.Seek(0, io.SeekStart); err != nil {
+		return nil, "", cleanup, err
+	}
+	return spooled, sniffed, cleanup, nil
+}
+
+// contentTypeAllowed matches a detected media type against exact types and "type/*" prefixes
+func contentTypeAllowed(contentType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(contentType, prefix) || pattern == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// spool copies r to a temporary file so it can be scanned then stored
+func spool(r io.Reader) (*os.File, func(), error) {
+	file, err := os.CreateTemp("", "minio-upload-*")
+	if err != nil {
+		return nil, func() {}, err
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	return file, cleanup, nil
+}
+
+func maxSizeError(maxSize int64) *UploadPolicyError {
+	return &UploadPolicyError{Rule: RuleMaxSize, Message: fmt.Sprintf("file exceeds %d bytes", maxSize)}
+}
+
+// maxSizeReader fails with the max size violation once more than remaining bytes are read, for
+// readers of unknown size
+type maxSizeReader struct {
+	r         io.Reader
+	remaining int64
+	maxSize   int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, maxSizeError(m.maxSize)
+	}
+	return n, err
+}
@@ -0,0 +1,183 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// pngData starts with the PNG signature so http.DetectContentType sniffs image/png
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+// errInfected is returned by the test scanner for EICAR uploads
+var errInfected = errors.New("infected")
+
+// eicarScanner rejects uploads containing the EICAR marker
+type eicarScanner struct {
+	scanned int
+}
+
+func (s *eicarScanner) Scan(ctx context.Context, r io.Reader) error {
+	s.scanned++
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("EICAR")) {
+		return errInfected
+	}
+	return nil
+}
+
+// policyRule returns the rule of an UploadPolicyError, empty for other errors
+func policyRule(err error) string {
+	var policyErr *UploadPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Rule
+	}
+	return ""
+}
+
+func TestUploadPolicy(t *testing.T) {
+	fake, s := newFakeS3(t, WithUploadPolicy(UploadPolicy{
+		AllowedContentTypes: []string{"image/*"},
+		AllowedExtensions:   []string{".png", ".jpg"},
+		MaxSize:             200,
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		size     int64
+		rule     string
+	}{
+		{name: "image", filename: "photo.png", data: pngData, size: int64(len(pngData))},
+		{name: "renamed binary", filename: "photo.jpg", data: []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00"), size: 10, rule: RuleContentType},
+		{name: "extension", filename: "photo.exe", data: pngData, size: int64(len(pngData)), rule: RuleExtension},
+		{name: "oversized", filename: "photo.png", data: append(pngData, make([]byte, 200)...), size: 300, rule: RuleMaxSize},
+		{name: "oversized unknown size", filename: "photo.png", data: append(pngData, make([]byte, 200)...), size: -1, rule: RuleMaxSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(fake.keys())
+			_, err := s.UploadReader(ctx, "images", tt.filename, bytes.NewReader(tt.data), tt.size, "image/png")
+			if rule := policyRule(err); rule != tt.rule {
+				t.Fatalf("UploadReader = %v, want rule %q", err, tt.rule)
+			}
+			if stored := len(fake.keys()) - before; tt.rule != "" && stored != 0 {
+				t.Errorf("rejected upload stored %d objects", stored)
+			}
+		})
+	}
+}
+
+func TestUploadPolicyStoresSniffedContentType(t *testing.T) {
+	fake, s := newFakeS3(t, WithUploadPolicy(UploadPolicy{AllowedContentTypes: []string{"image/png", "text/plain"}}))
+
+	name, err := s.UploadBytes(context.Background(), "images", "photo.png", pngData, "application/x-msdownload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := fake.object(name); object.header.Get("Content-Type") != "image/png" {
+		t.Errorf("Content-Type = %q, want the sniffed image/png", object.header.Get("Content-Type"))
+	}
+
+	name, err = s.UploadBytes(context.Background(), "notes", "a.txt", []byte("hello"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := fake.object(name); object.header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want the sniffed text type", object.header.Get("Content-Type"))
+	}
+
+	// Without content type rules the client type is kept
+	name, err = s.UploadBytes(context.Background(), "notes", "a.txt", []byte("hello"), "text/markdown", WithPolicy(UploadPolicy{MaxSize: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := fake.object(name); object.header.Get("Content-Type") != "text/markdown" {
+		t.Errorf("Content-Type = %q, want the client type", object.header.Get("Content-Type"))
+	}
+}
+
+func TestUploadPolicyOverride(t *testing.T) {
+	_, s := newFakeS3(t, WithUploadPolicy(UploadPolicy{AllowedExtensions: []string{".png"}}))
+
+	if _, err := s.UploadBytes(context.Background(), "docs", "a.pdf", []byte("%PDF-1.4"), "application/pdf"); policyRule(err) != RuleExtension {
+		t.Errorf("UploadBytes = %v, want the service policy enforced", err)
+	}
+	if _, err := s.UploadBytes(context.Background(), "docs", "a.pdf", []byte("%PDF-1.4"), "application/pdf",
+		WithPolicy(UploadPolicy{AllowedExtensions: []string{".pdf"}})); err != nil {
+		t.Errorf("UploadBytes with WithPolicy = %v", err)
+	}
+}
+
+func TestUploadScanner(t *testing.T) {
+	scanner := &eicarScanner{}
+	fake, s := newFakeS3(t, WithScanner(scanner))
+
+	name, err := s.UploadReader(context.Background(), "docs", "clean.txt", strings.NewReader("clean content"), -1, "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := fake.object(name); string(object.data) != "clean content" {
+		t.Errorf("stored %q, want the scanned content", object.data)
+	}
+
+	_, err = s.UploadBytes(context.Background(), "docs", "virus.txt", []byte("X5O EICAR test"), "text/plain")
+	if policyRule(err) != RuleScan || !errors.Is(err, errInfected) {
+		t.Errorf("UploadBytes = %v, want the scanner error", err)
+	}
+	if scanner.scanned != 2 || len(fake.keys()) != 1 {
+		t.Errorf("scanned %d uploads and stored %v", scanner.scanned, fake.keys())
+	}
+}
+
+func TestUploadSanitizesFilename(t *testing.T) {
+	_, s := newFakeS3(t)
+
+	name, err := s.UploadBytes(context.Background(), "docs", "../../etc/passwd", []byte("root"), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !objectNamePattern("docs", "passwd").MatchString(name) {
+		t.Errorf("object name = %q, want the hostile path stripped", name)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"../../etc/passwd":      "passwd",
+		`..\..\windows\win.ini`: "win.ini",
+		"a<b>:c|d?.txt":         "abcd.txt",
+		"bad\x00name\n.pdf":     "badname.pdf",
+		"Cafe\u0301.txt":        "Caf\u00e9.txt",
+		" .hidden. ":            "hidden",
+		"..":                    "file",
+		"":                      "file",
+	}
+	for in, want := range tests {
+		if got := SanitizeFilename(in); got != want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUploadPolicyErrorResponse(t *testing.T) {
+	err := &UploadPolicyError{Rule: RuleExtension, Message: `extension ".exe" is not allowed`}
+
+	resp := err.ToErrorResponse()
+	if resp.Code != common.VALIDATION_ERROR || resp.Message != common.T(common.MsgErrorValidation) {
+		t.Errorf("response = %d %q", resp.Code, resp.Message)
+	}
+	if len(resp.Details) != 1 || resp.Details[0].Field != "file" || resp.Details[0].Message != err.Message || resp.Details[0].Value != RuleExtension {
+		t.Errorf("details = %+v", resp.Details)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -45,6 +46,12 @@ type RedisClient interface {
 	Close() error
 }
 
+// SlidingWindowLimiter is implemented by clients supporting sliding window rate limits, such as the
+// client returned by NewRedisClient. It is kept out of RedisClient so existing implementations keep compiling
+type SlidingWindowLimiter interface {
+	SlidingWindowAllow(ctx context.Context, key string, limit int64, window time.Duration) (*SlidingWindowResult, error)
+}
+
 // redisClient implements RedisClient interface
 type redisClient struct {
 	cluster *redis.ClusterClient
@@ -707,6 +714,81 @@ func (r *redisClient) Exists(ctx context.Context, key string) (bool, error) {
 	return result, nil
 }
 
+// SlidingWindowResult is the outcome of a sliding window rate limit check
+type SlidingWindowResult struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// ResetAt is when the oldest request of the window expires and a slot frees up
+	ResetAt time.Time
+}
+
+// slidingWindowScript atomically drops the requests older than the window, counts the remaining ones
+// and records the current request when under the limit. Scores are unix milliseconds
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+local reset = now + window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window
+end
+return {allowed, count, reset}
+`)
+
+// SlidingWindowAllow records a request in the sliding window of key and reports whether it is within limit
+func (r *redisClient) SlidingWindowAllow(ctx context.Context, key string, limit int64, window time.Duration) (*SlidingWindowResult, error) {
+	ctx, span := r.trace(ctx, "sliding_window_allow")
+	defer span.End()
+
+	fullKey := r.prefix + key
+	span.SetAttributes(
+		attribute.String("redis.key", fullKey),
+		attribute.String("redis.operation", "sliding_window_allow"),
+		attribute.Int64("redis.limit", limit),
+		attribute.Float64("redis.window_seconds", window.Seconds()),
+	)
+
+	now := time.Now().UnixMilli()
+	member := fmt.Sprintf("%d-%s", now, uuid.NewString())
+	values, err := slidingWindowScript.Run(ctx, r.getClient(), []string{fullKey}, now, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if len(values) != 3 {
+		err = fmt.Errorf("unexpected sliding window result: %v", values)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	result := &SlidingWindowResult{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: max(limit-values[1], 0),
+		ResetAt:   time.UnixMilli(values[2]),
+	}
+	span.SetAttributes(
+		attribute.Bool("redis.allowed", result.Allowed),
+		attribute.Int64("redis.remaining", result.Remaining),
+	)
+	span.SetStatus(codes.Ok, "success")
+	return result, nil
+}
+
 func (r *redisClient) Close() error {
 	if r.cluster != nil {
 		return r.cluster.Close()
@@ -0,0 +1,267 @@
+// Package redistest provides an in-memory redis.RedisClient for the tests of the packages using Redis
+package redistest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// ErrDown is returned by every call of a Client that is down
+var ErrDown = errors.New("redis: connection refused")
+
+// Client is an in-memory redis.RedisClient expiring keys with its own clock. Methods other than the
+// string, counter and hash commands used in this module panic through the nil embedded interface
+type Client struct {
+	redis.RedisClient
+
+	mu      sync.Mutex
+	down    bool
+	now     time.Time
+	values  map[string]string
+	expires map[string]time.Time
+	hashes  map[string]map[string]string
+	windows map[string][]time.Time
+}
+
+// New returns an empty Client whose clock starts at a fixed instant
+func New() *Client {
+	return &Client{
+		now:     time.Unix(1_700_000_000, 0),
+		values:  map[string]string{},
+		expires: map[string]time.Time{},
+		hashes:  map[string]map[string]string{},
+		windows: map[string][]time.Time{},
+	}
+}
+
+// Now returns the clock of the client, it can be used as the clock of the code under test
+func (c *Client) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// SetNow sets the clock of the client
+func (c *Client) SetNow(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock of the client, expiring the keys whose TTL elapsed
+func (c *Client) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SetDown makes every call fail with ErrDown
+func (c *Client) SetDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+// TTL returns the remaining TTL of key, zero without expiry
+func (c *Client) TTL(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at, ok := c.expires[key]; ok {
+		return at.Sub(c.now)
+	}
+	return 0
+}
+
+// Keys returns the string and counter keys that have not expired
+func (c *Client) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		if _, ok := c.live(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// live returns the value of key, dropping it when expired. c.mu must be held
+func (c *Client) live(key string) (string, bool) {
+	if at, ok := c.expires[key]; ok && !c.now.Before(at) {
+		delete(c.values, key)
+		delete(c.expires, key)
+	}
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// store sets key, expiring after exp when positive. c.mu must be held
+func (c *Client) store(key string, val any, exp time.Duration) {
+	switch v := val.(type) {
+	case string:
+		c.values[key] = v
+	case []byte:
+		c.values[key] = string(v)
+	default:
+		panic("redistest: unsupported value type")
+	}
+	delete(c.expires, key)
+	if exp > 0 {
+		c.expires[key] = c.now.Add(exp)
+	}
+}
+
+func (c *Client) Set(ctx context.Context, key string, val any, exp time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return ErrDown
+	}
+	c.store(key, val, exp)
+	return nil
+}
+
+func (c *Client) SetNX(ctx context.Context, key string, val any, exp time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return false, ErrDown
+	}
+	if _, ok := c.live(key); ok {
+		return false, nil
+	}
+	c.store(key, val, exp)
+	return true, nil
+}
+
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return "", ErrDown
+	}
+	value, ok := c.live(key)
+	if !ok {
+		return "", goredis.Nil
+	}
+	return value, nil
+}
+
+func (c *Client) Del(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return ErrDown
+	}
+	delete(c.values, key)
+	delete(c.expires, key)
+	delete(c.hashes, key)
+	return nil
+}
+
+func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return false, ErrDown
+	}
+	_, ok := c.live(key)
+	return ok, nil
+}
+
+// Incr increments key, keeping its TTL as Redis does
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return 0, ErrDown
+	}
+	value, _ := c.live(key)
+	n, _ := strconv.ParseInt(value, 10, 64)
+	n++
+	c.values[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// Expire sets the TTL of a string or counter key, hashes never expire
+func (c *Client) Expire(ctx context.Context, key string, exp time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return ErrDown
+	}
+	if _, ok := c.live(key); ok {
+		c.expires[key] = c.now.Add(exp)
+	}
+	return nil
+}
+
+func (c *Client) HSet(ctx context.Context, key string, hKey any, val any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return ErrDown
+	}
+	if c.hashes[key] == nil {
+		c.hashes[key] = map[string]string{}
+	}
+	c.hashes[key][fmt.Sprint(hKey)] = fmt.Sprint(val)
+	return nil
+}
+
+func (c *Client) HKeys(ctx context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return nil, ErrDown
+	}
+	var keys []string
+	for hKey := range c.hashes[key] {
+		keys = append(keys, hKey)
+	}
+	return keys, nil
+}
+
+// SlidingWindow is a Client implementing redis.SlidingWindowLimiter, a bare Client does not
+type SlidingWindow struct {
+	*Client
+}
+
+func (s SlidingWindow) SlidingWindowAllow(ctx context.Context, key string, limit int64, window time.Duration) (*redis.SlidingWindowResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return nil, ErrDown
+	}
+
+	now := s.now
+	var kept []time.Time
+	for _, at := range s.windows[key] {
+		if at.After(now.Add(-window)) {
+			kept = append(kept, at)
+		}
+	}
+	allowed := int64(len(kept)) < limit
+	if allowed {
+		kept = append(kept, now)
+	}
+	s.windows[key] = kept
+
+	resetAt := now.Add(window)
+	if len(kept) > 0 {
+		resetAt = kept[0].Add(window)
+	}
+	return &redis.SlidingWindowResult{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: max(limit-int64(len(kept)), 0),
+		ResetAt:   resetAt,
+	}, nil
+}
@@ -3,13 +3,16 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/thanhthanh221/msa-core/pkg/common"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormRepository struct {
@@ -317,10 +320,21 @@ func (r *gormRepository) GetOneByID(ctx context.Context, target interface{}, id
 		)
 	}
 
+	column := "id"
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(target); err == nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		field := stmt.Schema.PrioritizedPrimaryField
+		column = field.DBName
+		// A malformed id cannot match a uuid key, Postgres would fail the query instead of finding nothing
+		if value, ok := id.(string); ok && strings.EqualFold(string(field.DataType), "uuid") && !common.IsValidUUID(value) {
+			return ErrNotFound
+		}
+	}
+
+	// The column is qualified so default joins do not make it ambiguous
 	res := r.DBWithPreloads(ctx, preloads).
 		WithContext(ctx).
-		Where("id = ?", id).
-		Order("created_at DESC").
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: id}).
 		First(target)
 
 	return r.HandleOneError(ctx, res, span)
@@ -0,0 +1,238 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// testOrder is an entity keyed by a UUID
+type testOrder struct {
+	models.BaseEntity
+	Number string
+}
+
+// testLegacyOrder is an entity keyed by an integer
+type testLegacyOrder struct {
+	models.BaseEntityNumeric
+	Number string
+}
+
+// sqlRecorder is a gorm logger recording the statements of a dry run database
+type sqlRecorder struct {
+	logger.Interface
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, sql)
+}
+
+// take returns the statements recorded since the last call
+func (r *sqlRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statements := r.statements
+	r.statements = nil
+	return statements
+}
+
+// newDryRunRepository returns a repository on a gorm database building statements without running them
+func newDryRunRepository(t *testing.T) (TransactionRepository, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGormRepository(db, log.New(), noop.NewTracerProvider()), recorder
+}
+
+// onlyStatement returns the single statement recorded
+func onlyStatement(t *testing.T, recorder *sqlRecorder) string {
+	t.Helper()
+	statements := recorder.take()
+	if len(statements) != 1 {
+		t.Fatalf("statements = %q, want one", statements)
+	}
+	return statements[0]
+}
+
+// assertContains fails unless sql has every part
+func assertContains(t *testing.T, sql string, parts ...string) {
+	t.Helper()
+	for _, part := range parts {
+		if !strings.Contains(sql, part) {
+			t.Errorf("statement %s\nmissing %s", sql, part)
+		}
+	}
+}
+
+func TestCreateBaseEntity(t *testing.T) {
+	repo, recorder := newDryRunRepository(t)
+
+	t.Run("generates the ID", func(t *testing.T) {
+		order := &testOrder{Number: "A-1"}
+		if err := repo.Create(context.Background(), order); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := common.ParseUUID(order.ID, common.UUIDOptions{RequireRFC4122: true}); err != nil {
+			t.Errorf("ID = %q, want a generated UUID", order.ID)
+		}
+		if order.CreatedBy != nil || order.UpdatedBy != nil || order.CreatedAt.IsZero() {
+			t.Errorf("order = %+v, want timestamps without audit users", order.BaseEntity)
+		}
+		assertContains(t, onlyStatement(t, recorder), "INSERT INTO `test_orders`", `"`+order.ID+`"`, `"A-1"`)
+	})
+
+	t.Run("keeps a preset ID", func(t *testing.T) {
+		order := &testOrder{BaseEntity: models.BaseEntity{ID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"}}
+		if err := repo.Create(context.Background(), order); err != nil {
+			t.Fatal(err)
+		}
+		if order.ID != "7c9e6679-7425-40de-944b-e07fc1f90ae7" {
+			t.Errorf("ID = %q, want the preset one", order.ID)
+		}
+		assertContains(t, onlyStatement(t, recorder), `"7c9e6679-7425-40de-944b-e07fc1f90ae7"`)
+	})
+
+	t.Run("records the user of the context", func(t *testing.T) {
+		ctx := common.WithUserID(context.Background(), "user-1")
+		order := &testOrder{}
+		if err := repo.Create(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+		if order.CreatedBy == nil || *order.CreatedBy != "user-1" || order.UpdatedBy == nil || *order.UpdatedBy != "user-1" {
+			t.Errorf("audit users = %v, %v, want user-1", order.CreatedBy, order.UpdatedBy)
+		}
+		assertContains(t, onlyStatement(t, recorder), `"user-1"`)
+
+		importer := "importer"
+		order = &testOrder{BaseEntity: models.BaseEntity{CreatedBy: &importer}}
+		if err := repo.Create(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+		if *order.CreatedBy != "importer" || *order.UpdatedBy != "user-1" {
+			t.Errorf("audit users = %s, %s, want the preset creator kept", *order.CreatedBy, *order.UpdatedBy)
+		}
+		recorder.take()
+	})
+
+	t.Run("integer keys", func(t *testing.T) {
+		order := &testLegacyOrder{Number: "L-1"}
+		if err := repo.Create(common.WithUserID(context.Background(), "user-2"), order); err != nil {
+			t.Fatal(err)
+		}
+		if order.ID != 0 || order.CreatedBy == nil || *order.CreatedBy != "user-2" {
+			t.Errorf("order = %+v, want the key left to the database and the creator set", order.BaseEntityNumeric)
+		}
+		sql := onlyStatement(t, recorder)
+		assertContains(t, sql, "INSERT INTO `test_legacy_orders`", `"user-2"`)
+		if !strings.Contains(sql, "(`created_at`,") {
+			t.Errorf("statement %s inserts the auto-incremented id", sql)
+		}
+	})
+}
+
+func TestUpdateBaseEntity(t *testing.T) {
+	repo, recorder := newDryRunRepository(t)
+	ctx := common.WithUserID(context.Background(), "editor")
+	order := &testOrder{BaseEntity: models.BaseEntity{ID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"}}
+
+	// Map updates do not read the entity fields, BeforeUpdate sets the column itself
+	if err := repo.Update(ctx, order, map[string]interface{}{"number": "B-2"}, "number = ?", "A-1"); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, onlyStatement(t, recorder), "UPDATE `test_orders` SET", "`number`=\"B-2\"", "`updated_by`=\"editor\"", "`updated_at`=")
+
+	if err := repo.Update(context.Background(), order, map[string]interface{}{"number": "B-3"}, "number = ?", "B-2"); err != nil {
+		t.Fatal(err)
+	}
+	if sql := onlyStatement(t, recorder); strings.Contains(sql, "updated_by") {
+		t.Errorf("statement %s sets updated_by without a user", sql)
+	}
+}
+
+func TestSoftDeleteBaseEntity(t *testing.T) {
+	repo, recorder := newDryRunRepository(t)
+	order := &testOrder{BaseEntity: models.BaseEntity{ID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"}}
+
+	if err := repo.Delete(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+	sql := onlyStatement(t, recorder)
+	assertContains(t, sql, "UPDATE `test_orders` SET `deleted_at`=", "`test_orders`.`id` = \"7c9e6679-7425-40de-944b-e07fc1f90ae7\"",
+		"`test_orders`.`deleted_at` IS NULL")
+	if strings.Contains(sql, "DELETE") {
+		t.Errorf("statement %s deletes the row", sql)
+	}
+}
+
+func TestGetOneByID(t *testing.T) {
+	repo, recorder := newDryRunRepository(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		target interface{}
+		id     any
+		want   []string
+	}{
+		{
+			name:   "uuid key",
+			target: &testOrder{},
+			id:     "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+			want: []string{"FROM `test_orders` WHERE `test_orders`.`id` = \"7c9e6679-7425-40de-944b-e07fc1f90ae7\"",
+				"`test_orders`.`deleted_at` IS NULL", "LIMIT 1"},
+		},
+		{
+			name:   "integer key",
+			target: &testLegacyOrder{},
+			id:     42,
+			want:   []string{"FROM `test_legacy_orders` WHERE `test_legacy_orders`.`id` = 42", "`test_legacy_orders`.`deleted_at` IS NULL"},
+		},
+		{
+			name:   "integer key as a string",
+			target: &testLegacyOrder{},
+			id:     "42",
+			want:   []string{"`test_legacy_orders`.`id` = \"42\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Dry runs find no row
+			if err := repo.GetOneByID(ctx, tt.target, tt.id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetOneByID() = %v, want ErrNotFound", err)
+			}
+			sql := onlyStatement(t, recorder)
+			assertContains(t, sql, tt.want...)
+			if strings.Contains(sql, "created_at") {
+				t.Errorf("statement %s orders a primary key lookup by created_at", sql)
+			}
+		})
+	}
+
+	for _, id := range []any{"not-a-uuid", "", "7c9e6679-7425-40de-944b-e07fc1f90ae"} {
+		if err := repo.GetOneByID(ctx, &testOrder{}, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetOneByID(%q) = %v, want ErrNotFound", id, err)
+		}
+		if statements := recorder.take(); len(statements) != 0 {
+			t.Errorf("GetOneByID(%q) ran %q, want no query for a malformed uuid", id, statements)
+		}
+	}
+}
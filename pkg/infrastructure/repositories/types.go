@@ -29,8 +29,8 @@ type Repository interface {
 	ExistsByField(ctx context.Context, target interface{}, field string, value interface{}) (bool, error)
 	ExistsByFields(ctx context.Context, target interface{}, filters map[string]interface{}) (bool, error)
 
-	// GetOneByID assumes you have a PK column "id" which is a UUID. If this is not the case just ignore the method
-	// and add a custom struct with this Repository embedded.
+	// GetOneByID finds the entity by its primary key, the "id" column of models.BaseEntity (UUID) and
+	// models.BaseEntityNumeric (integer). Malformed ids of UUID keys and soft deleted rows return ErrNotFound
 	GetOneByID(ctx context.Context, target interface{}, id any, preloads ...string) error
 
 	Create(ctx context.Context, target interface{}) error
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultCleanupBatchSize is the number of files RunCleanup deletes per request
+const DefaultCleanupBatchSize = 100
+
+// FileStorage is a storage backend with temporary uploads, files are referenced by the public ID of the
+// backend (MinIO object names, Cloudinary public IDs or URLs)
+type FileStorage interface {
+	// ListExpired returns the temporary files whose expiry is before t
+	ListExpired(ctx context.Context, before time.Time) ([]string, error)
+	// Delete deletes files and returns the ones that could not be deleted
+	Delete(ctx context.Context, publicIDs []string) ([]string, error)
+	// MarkPermanent removes the temporary tag of a file so it is never cleaned up
+	MarkPermanent(ctx context.Context, publicID string) error
+}
+
+// Janitor deletes the temporary uploads of a FileStorage that were never attached to an entity
+type Janitor interface {
+	// RunCleanup deletes the temporary files that expired more than olderThan ago and returns the number
+	// deleted, or the number that would be deleted in dry-run mode
+	RunCleanup(ctx context.Context, olderThan time.Duration) (int, error)
+	// MarkPermanent keeps a temporary upload, call it when the owning entity is saved
+	MarkPermanent(ctx context.Context, publicID string) error
+}
+
+type janitor struct {
+	storage   FileStorage
+	logger    *logrus.Logger
+	batchSize int
+	dryRun    bool
+	now       func() time.Time
+}
+
+// JanitorOption configures NewJanitor
+type JanitorOption func(*janitor)
+
+// WithDryRun makes RunCleanup log the expired files without deleting them
+func WithDryRun() JanitorOption {
+	return func(j *janitor) {
+		j.dryRun = true
+	}
+}
+
+// WithBatchSize sets the number of files deleted per request, DefaultCleanupBatchSize by default
+func WithBatchSize(size int) JanitorOption {
+	return func(j *janitor) {
+		if size > 0 {
+			j.batchSize = size
+		}
+	}
+}
+
+// WithClock sets the clock expiries are compared to, time.Now by default
+func WithClock(now func() time.Time) JanitorOption {
+	return func(j *janitor) {
+		if now != nil {
+			j.now = now
+		}
+	}
+}
+
+// NewJanitor creates a Janitor cleaning up storage, run RunCleanup periodically, e.g. from a cron job.
+// A nil logger falls back to the standard logrus logger
+func NewJanitor(storage FileStorage, logger *logrus.Logger, opts ...JanitorOption) Janitor {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	j := &janitor{
+		storage:   storage,
+		logger:    logger,
+		batchSize: DefaultCleanupBatchSize,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+func (j *janitor) RunCleanup(ctx context.Context, olderThan time.Duration) (int, error) {
+	expiredBefore := j.now().Add(-max(olderThan, 0))
+	expired, err := j.storage.ListExpired(ctx, expiredBefore)
+	if err != nil {
+		j.logger.Errorf("Failed to list expired temporary files: %v", err)
+		return 0, err
+	}
+	if j.dryRun {
+		for _, publicID := range expired {
+			j.logger.Infof("Dry run, would delete expired temporary file: %s", publicID)
+		}
+		return len(expired), nil
+	}
+
+	deleted := 0
+	var errs []error
+	for start := 0; start < len(expired); start += j.batchSize {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		batch := expired[start:min(start+j.batchSize, len(expired))]
+		failed, err := j.storage.Delete(ctx, batch)
+		deleted += len(batch) - len(failed)
+		if err != nil {
+			j.logger.Errorf("Failed to delete %d expired temporary files: %v", len(failed), err)
+			errs = append(errs, err)
+		}
+	}
+
+	j.logger.Infof("Successfully deleted %d of %d expired temporary files", deleted, len(expired))
+	return deleted, errors.Join(errs...)
+}
+
+func (j *janitor) MarkPermanent(ctx context.Context, publicID string) error {
+	return j.storage.MarkPermanent(ctx, publicID)
+}
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var errDeleteDenied = errors.New("access denied")
+
+// fakeStorage keeps the expiry of its temporary files, permanent files have none
+type fakeStorage struct {
+	expiries   map[string]time.Time
+	permanent  map[string]bool
+	failDelete map[string]bool
+	listErr    error
+	batches    [][]string
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{expiries: map[string]time.Time{}, permanent: map[string]bool{}, failDelete: map[string]bool{}}
+}
+
+func (f *fakeStorage) ListExpired(ctx context.Context, before time.Time) ([]string, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	var expired []string
+	for publicID, expiresAt := range f.expiries {
+		if expiresAt.Before(before) {
+			expired = append(expired, publicID)
+		}
+	}
+	slices.Sort(expired)
+	return expired, nil
+}
+
+func (f *fakeStorage) Delete(ctx context.Context, publicIDs []string) ([]string, error) {
+	f.batches = append(f.batches, publicIDs)
+	var failed []string
+	for _, publicID := range publicIDs {
+		if f.failDelete[publicID] {
+			failed = append(failed, publicID)
+			continue
+		}
+		delete(f.expiries, publicID)
+	}
+	if len(failed) > 0 {
+		return failed, errDeleteDenied
+	}
+	return nil, nil
+}
+
+func (f *fakeStorage) MarkPermanent(ctx context.Context, publicID string) error {
+	delete(f.expiries, publicID)
+	f.permanent[publicID] = true
+	return nil
+}
+
+// remaining returns the temporary files left, sorted
+func (f *fakeStorage) remaining() []string {
+	return slices.Sorted(maps.Keys(f.expiries))
+}
+
+// fakeClock is a clock advanced by the tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestJanitor(storage FileStorage, clock *fakeClock, opts ...JanitorOption) Janitor {
+	logger, _ := test.NewNullLogger()
+	return NewJanitor(storage, logger, append([]JanitorOption{WithClock(clock.Now)}, opts...)...)
+}
+
+func TestRunCleanupDeletesOnlyExpiredTemporaries(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	storage := newFakeStorage()
+	storage.expiries["avatars/expired"] = clock.now.Add(-time.Hour)
+	storage.expiries["avatars/just-expired"] = clock.now.Add(-time.Second)
+	storage.expiries["avatars/now"] = clock.now
+	storage.expiries["avatars/later"] = clock.now.Add(time.Hour)
+	janitor := newTestJanitor(storage, clock)
+
+	deleted, err := janitor.RunCleanup(context.Background(), 0)
+	if err != nil || deleted != 2 {
+		t.Fatalf("RunCleanup = %d, %v, want 2 deleted", deleted, err)
+	}
+	if got, want := storage.remaining(), []string{"avatars/later", "avatars/now"}; !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if deleted, err := janitor.RunCleanup(context.Background(), 0); err != nil || deleted != 2 {
+		t.Errorf("RunCleanup after 2h = %d, %v, want 2 deleted", deleted, err)
+	}
+	if remaining := storage.remaining(); len(remaining) != 0 {
+		t.Errorf("remaining = %v, want none", remaining)
+	}
+}
+
+func TestRunCleanupOlderThan(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	storage := newFakeStorage()
+	storage.expiries["expired-3h-ago"] = clock.now.Add(-3 * time.Hour)
+	storage.expiries["expired-1h-ago"] = clock.now.Add(-time.Hour)
+	janitor := newTestJanitor(storage, clock)
+
+	if deleted, err := janitor.RunCleanup(context.Background(), 2*time.Hour); err != nil || deleted != 1 {
+		t.Fatalf("RunCleanup = %d, %v, want 1 deleted", deleted, err)
+	}
+	if got := storage.remaining(); !slices.Equal(got, []string{"expired-1h-ago"}) {
+		t.Errorf("remaining = %v, want the file expired 1h ago", got)
+	}
+	// A negative grace period is treated as zero
+	if deleted, err := janitor.RunCleanup(context.Background(), -time.Hour); err != nil || deleted != 1 {
+		t.Errorf("RunCleanup(-1h) = %d, %v, want 1 deleted", deleted, err)
+	}
+}
+
+func TestRunCleanupDryRun(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	storage := newFakeStorage()
+	storage.expiries["expired"] = clock.now.Add(-time.Minute)
+	storage.expiries["kept"] = clock.now.Add(time.Minute)
+	logger, hook := test.NewNullLogger()
+	janitor := NewJanitor(storage, logger, WithClock(clock.Now), WithDryRun())
+
+	deleted, err := janitor.RunCleanup(context.Background(), 0)
+	if err != nil || deleted != 1 {
+		t.Fatalf("RunCleanup = %d, %v, want 1 to delete", deleted, err)
+	}
+	if len(storage.batches) != 0 || len(storage.remaining()) != 2 {
+		t.Errorf("dry run deleted files: batches %v, remaining %v", storage.batches, storage.remaining())
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.InfoLevel || entry.Message != "Dry run, would delete expired temporary file: expired" {
+		t.Errorf("last log entry = %v", entry)
+	}
+}
+
+func TestRunCleanupBatches(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	storage := newFakeStorage()
+	for _, publicID := range []string{"a", "b", "c", "d", "e"} {
+		storage.expiries[publicID] = clock.now.Add(-time.Hour)
+	}
+	storage.failDelete["d"] = true
+	janitor := newTestJanitor(storage, clock, WithBatchSize(2))
+
+	deleted, err := janitor.RunCleanup(context.Background(), 0)
+	if !errors.Is(err, errDeleteDenied) || deleted != 4 {
+		t.Fatalf("RunCleanup = %d, %v, want 4 deleted and the delete error", deleted, err)
+	}
+	if want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !slices.EqualFunc(storage.batches, want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", storage.batches, want)
+	}
+	if got := storage.remaining(); !slices.Equal(got, []string{"d"}) {
+		t.Errorf("remaining = %v, want the file that failed", got)
+	}
+}
+
+func TestRunCleanupErrors(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	t.Run("list", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.listErr = errors.New("list failed")
+		if deleted, err := newTestJanitor(storage, clock).RunCleanup(context.Background(), 0); !errors.Is(err, storage.listErr) || deleted != 0 {
+			t.Errorf("RunCleanup = %d, %v, want the list error", deleted, err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		storage := newFakeStorage()
+		storage.expiries["expired"] = clock.now.Add(-time.Hour)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if deleted, err := newTestJanitor(storage, clock).RunCleanup(ctx, 0); !errors.Is(err, context.Canceled) || deleted != 0 {
+			t.Errorf("RunCleanup = %d, %v, want context.Canceled", deleted, err)
+		}
+		if len(storage.batches) != 0 {
+			t.Errorf("batches = %v, want none", storage.batches)
+		}
+	})
+}
+
+func TestMarkPermanent(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	storage := newFakeStorage()
+	storage.expiries["attached"] = clock.now.Add(time.Hour)
+	storage.expiries["orphan"] = clock.now.Add(time.Hour)
+	janitor := newTestJanitor(storage, clock)
+
+	if err := janitor.MarkPermanent(context.Background(), "attached"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(24 * time.Hour)
+	if deleted, err := janitor.RunCleanup(context.Background(), 0); err != nil || deleted != 1 {
+		t.Fatalf("RunCleanup = %d, %v, want 1 deleted", deleted, err)
+	}
+	if !storage.permanent["attached"] || len(storage.remaining()) != 0 {
+		t.Errorf("permanent = %v, remaining = %v", storage.permanent, storage.remaining())
+	}
+}
+
+func TestNewJanitorDefaults(t *testing.T) {
+	storage := newFakeStorage()
+	storage.expiries["expired"] = time.Now().Add(-time.Hour)
+	storage.expiries["kept"] = time.Now().Add(time.Hour)
+
+	// A nil logger and clock fall back to logrus.StandardLogger and time.Now
+	janitor := NewJanitor(storage, nil, WithClock(nil), WithBatchSize(0)).(*janitor)
+	if janitor.logger != logrus.StandardLogger() || janitor.batchSize != DefaultCleanupBatchSize {
+		t.Fatalf("janitor = %+v", janitor)
+	}
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(io.Discard)
+	t.Cleanup(func() { logrus.SetOutput(out) })
+	if deleted, err := janitor.RunCleanup(context.Background(), 0); err != nil || deleted != 1 {
+		t.Errorf("RunCleanup = %d, %v, want 1 deleted", deleted, err)
+	}
+}
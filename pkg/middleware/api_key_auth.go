@@ -1,22 +1,165 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// HeaderAPIKey is the default header carrying API keys
+const HeaderAPIKey = "X-Api-Key"
+
+// ContextKeyAPIKeyConsumer is the echo context key of the authenticated APIKeyConsumer
+const ContextKeyAPIKeyConsumer = "api_key_consumer"
+
+// ErrorCodeInvalidAPIKey is the error code of responses rejecting a missing or invalid API key
+const ErrorCodeInvalidAPIKey = "invalid_api_key"
+
+// ErrAPIKeyNotFound is returned by KeyStore implementations for unknown key ids
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyConsumer describes the owner of an API key
+type APIKeyConsumer struct {
+	KeyID      string            `json:"key_id"`
+	Name       string            `json:"name"`
+	Scopes     []string          `json:"scopes,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// KeyStore looks up API keys by id. hashedSecret is HashAPIKeySecret of the secret or a bcrypt hash
+type KeyStore interface {
+	LookupKey(ctx context.Context, keyID string) (hashedSecret string, consumer *APIKeyConsumer, err error)
+}
+
+// APIKeyAuthConfig configures APIKeyAuth
+type APIKeyAuthConfig struct {
+	// Header carries the key, HeaderAPIKey by default
+	Header string
+	// Logger logs KeyStore failures, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// dummySecretHash is verified for unknown key ids so they take as long as wrong secrets
+var dummySecretHash = HashAPIKeySecret("msa-core-dummy-secret")
+
+// GenerateAPIKey creates a key of the form "id.secret". Give key to the consumer once and store
+// keyID with hashedSecret in the KeyStore
+func GenerateAPIKey() (key, keyID, hashedSecret string, err error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	keyID = hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return keyID + "." + secret, keyID, HashAPIKeySecret(secret), nil
+}
+
+// HashAPIKeySecret returns the hex encoded SHA-256 of secret, as stored in KeyStore
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAPIKeySecret compares secret with a SHA-256 or bcrypt hash in constant time
+func VerifyAPIKeySecret(secret, hashedSecret string) bool {
+	if strings.HasPrefix(hashedSecret, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(hashedSecret), []byte(secret)) == nil
+	}
+	computed := HashAPIKeySecret(secret)
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(strings.ToLower(hashedSecret))) == 1
+}
+
+// ParseAPIKey splits a key of the form "id.secret"
+func ParseAPIKey(key string) (keyID, secret string, ok bool) {
+	keyID, secret, ok = strings.Cut(key, ".")
+	return keyID, secret, ok && keyID != "" && secret != ""
+}
+
+// APIKeyAuth authenticates requests with "id.secret" API keys verified against store and puts the
+// APIKeyConsumer into the echo context (see APIKeyConsumerFromContext)
+func APIKeyAuth(store KeyStore, cfg APIKeyAuthConfig) echo.MiddlewareFunc {
+	if cfg.Header == "" {
+		cfg.Header = HeaderAPIKey
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			keyID, secret, ok := ParseAPIKey(c.Request().Header.Get(cfg.Header))
+			if !ok {
+				return apiKeyError(c)
+			}
+
+			hashedSecret, consumer, err := store.LookupKey(c.Request().Context(), keyID)
+			if err != nil {
+				if !errors.Is(err, ErrAPIKeyNotFound) {
+					cfg.Logger.WithError(err).Errorf("api key auth: lookup of key %s failed", keyID)
+				}
+				// Keep the same cost as a wrong secret so key ids cannot be probed by timing
+				VerifyAPIKeySecret(secret, dummySecretHash)
+				return apiKeyError(c)
+			}
+			if !VerifyAPIKeySecret(secret, hashedSecret) {
+				return apiKeyError(c)
+			}
+
+			if consumer == nil {
+				consumer = &APIKeyConsumer{}
+			}
+			if consumer.KeyID == "" {
+				consumer.KeyID = keyID
+			}
+			c.Set(ContextKeyAPIKeyConsumer, consumer)
+			return next(c)
+		}
+	}
+}
+
+// APIKeyConsumerFromContext returns the consumer authenticated by APIKeyAuth
+func APIKeyConsumerFromContext(c echo.Context) (*APIKeyConsumer, bool) {
+	consumer, ok := c.Get(ContextKeyAPIKeyConsumer).(*APIKeyConsumer)
+	return consumer, ok && consumer != nil
+}
+
+// APIKeyAuthMiddleware checks a single shared API key in constant time
+//
+// Deprecated: use APIKeyAuth with a KeyStore, which supports per-consumer keys and rotation
 func APIKeyAuthMiddleware(expectedApiKey string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			apiKey := c.Request().Header.Get("X-Api-Key")
+			apiKey := c.Request().Header.Get(HeaderAPIKey)
 
-			if apiKey == "" || apiKey != expectedApiKey {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"message": "Invalid or missing API key",
-				})
+			if apiKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedApiKey)) != 1 {
+				return apiKeyError(c)
 			}
 			return next(c)
 		}
 	}
 }
+
+// apiKeyError writes the 401 error response translated with the request locale
+func apiKeyError(c echo.Context) error {
+	ctx := common.LocaleContext(c)
+	errorResp := common.UnauthorizedError(common.TWithContextAndFallback(ctx, common.MsgErrorInvalidAPIKey, "Invalid or missing API key"))
+	errorResp.ErrorCode = ErrorCodeInvalidAPIKey
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return c.JSON(http.StatusUnauthorized, errorResp)
+}
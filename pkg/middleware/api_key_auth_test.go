@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newAPIKeyServer returns a server authenticating with APIKeyAuth over store, answering the consumer name
+func newAPIKeyServer(store KeyStore) *echo.Echo {
+	logger, _ := test.NewNullLogger()
+	e := echo.New()
+	e.Use(APIKeyAuth(store, APIKeyAuthConfig{Logger: logger}))
+	e.GET("/", func(c echo.Context) error {
+		consumer, ok := APIKeyConsumerFromContext(c)
+		if !ok {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.String(http.StatusOK, consumer.KeyID+" "+consumer.Name+" "+consumer.Attributes["plan"])
+	})
+	return e
+}
+
+// serveAPIKey serves GET / with key in the X-Api-Key header
+func serveAPIKey(e *echo.Echo, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		req.Header.Set(HeaderAPIKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	key, keyID, hashedSecret, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, secret, _ := ParseAPIKey(key)
+
+	store := NewMemoryKeyStore()
+	store.Add(keyID, hashedSecret, &APIKeyConsumer{Name: "billing", Attributes: map[string]string{"plan": "pro"}})
+	e := newAPIKeyServer(store)
+
+	rec := serveAPIKey(e, key)
+	if rec.Code != http.StatusOK || rec.Body.String() != keyID+" billing pro" {
+		t.Fatalf("valid key status = %d, body = %q, want the consumer metadata", rec.Code, rec.Body.String())
+	}
+
+	rejected := map[string]string{
+		"missing":      "",
+		"no separator": keyID + secret,
+		"empty secret": keyID + ".",
+		"wrong secret": keyID + ".wrong",
+		"wrong id":     "0000000000000000." + secret,
+	}
+	messages := make(map[string]bool)
+	for name, candidate := range rejected {
+		rec := serveAPIKey(e, candidate)
+		var body common.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusUnauthorized || body.ErrorCode != ErrorCodeInvalidAPIKey {
+			t.Errorf("%s: status = %d, body = %s, want 401 %s", name, rec.Code, rec.Body.String(), ErrorCodeInvalidAPIKey)
+		}
+		messages[body.Message] = true
+	}
+	if len(messages) != 1 {
+		t.Errorf("rejection messages = %v, want one message so key ids cannot be probed", messages)
+	}
+
+	store.Remove(keyID)
+	if rec := serveAPIKey(e, key); rec.Code != http.StatusUnauthorized {
+		t.Errorf("removed key status = %d, want 401", rec.Code)
+	}
+}
+
+func TestAPIKeyAuthStoreFailure(t *testing.T) {
+	fake := redistest.New()
+	store := NewRedisKeyStore(fake, "")
+	key, keyID, hashedSecret, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(context.Background(), keyID, hashedSecret, &APIKeyConsumer{Name: "reports"}); err != nil {
+		t.Fatal(err)
+	}
+	e := newAPIKeyServer(store)
+
+	if rec := serveAPIKey(e, key); rec.Code != http.StatusOK || rec.Body.String() != keyID+" reports " {
+		t.Errorf("redis key status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	fake.SetDown(true)
+	if rec := serveAPIKey(e, key); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status with redis down = %d, want 401", rec.Code)
+	}
+}
+
+func TestVerifyAPIKeySecret(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, secret, hash string
+		want               bool
+	}{
+		{"sha256", "s3cret", HashAPIKeySecret("s3cret"), true},
+		{"sha256 upper case hex", "s3cret", strings.ToUpper(HashAPIKeySecret("s3cret")), true},
+		{"sha256 wrong", "other", HashAPIKeySecret("s3cret"), false},
+		{"bcrypt", "s3cret", string(bcryptHash), true},
+		{"bcrypt wrong", "other", string(bcryptHash), false},
+		{"empty hash", "s3cret", "", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyAPIKeySecret(tt.secret, tt.hash); got != tt.want {
+			t.Errorf("%s: VerifyAPIKeySecret = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyAuthMiddlewareLegacy(t *testing.T) {
+	e := echo.New()
+	e.Use(APIKeyAuthMiddleware("shared-key"))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	if rec := serveAPIKey(e, "shared-key"); rec.Code != http.StatusOK {
+		t.Errorf("shared key status = %d", rec.Code)
+	}
+	for _, key := range []string{"", "shared-ke", "shared-key2"} {
+		if rec := serveAPIKey(e, key); rec.Code != http.StatusUnauthorized {
+			t.Errorf("key %q status = %d, want 401", key, rec.Code)
+		}
+	}
+}
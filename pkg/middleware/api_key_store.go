@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// storedAPIKey is an API key entry of the KeyStore implementations
+type storedAPIKey struct {
+	HashedSecret string          `json:"hashed_secret"`
+	Consumer     *APIKeyConsumer `json:"consumer"`
+}
+
+// MemoryKeyStore is an in-memory KeyStore, for tests and single instance deployments
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]storedAPIKey
+}
+
+// NewMemoryKeyStore creates an empty in-memory KeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string]storedAPIKey)}
+}
+
+// Add stores or replaces a key
+func (s *MemoryKeyStore) Add(keyID, hashedSecret string, consumer *APIKeyConsumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[keyID] = storedAPIKey{HashedSecret: hashedSecret, Consumer: consumer}
+}
+
+// Remove revokes a key
+func (s *MemoryKeyStore) Remove(keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, keyID)
+}
+
+// LookupKey implements KeyStore
+func (s *MemoryKeyStore) LookupKey(ctx context.Context, keyID string) (string, *APIKeyConsumer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[keyID]
+	if !ok {
+		return "", nil, ErrAPIKeyNotFound
+	}
+	return key.HashedSecret, copyConsumer(key.Consumer), nil
+}
+
+// RedisKeyStore is a KeyStore shared by all instances, storing one JSON entry per key id
+type RedisKeyStore struct {
+	client redis.RedisClient
+	prefix string
+}
+
+// NewRedisKeyStore creates a Redis KeyStore. prefix namespaces the keys, "apikey:" by default
+func NewRedisKeyStore(client redis.RedisClient, prefix string) *RedisKeyStore {
+	if prefix == "" {
+		prefix = "apikey:"
+	}
+	return &RedisKeyStore{client: client, prefix: prefix}
+}
+
+// Add stores or replaces a key
+func (s *RedisKeyStore) Add(ctx context.Context, keyID, hashedSecret string, consumer *APIKeyConsumer) error {
+	data, err := json.Marshal(storedAPIKey{HashedSecret: hashedSecret, Consumer: consumer})
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+keyID, string(data), 0)
+}
+
+// Remove revokes a key
+func (s *RedisKeyStore) Remove(ctx context.Context, keyID string) error {
+	return s.client.Del(ctx, s.prefix+keyID)
+}
+
+// LookupKey implements KeyStore
+func (s *RedisKeyStore) LookupKey(ctx context.Context, keyID string) (string, *APIKeyConsumer, error) {
+	data, err := s.client.Get(ctx, s.prefix+keyID)
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return "", nil, ErrAPIKeyNotFound
+		}
+		return "", nil, err
+	}
+
+	var key storedAPIKey
+	if err := json.Unmarshal([]byte(data), &key); err != nil {
+		return "", nil, err
+	}
+	return key.HashedSecret, key.Consumer, nil
+}
+
+// copyConsumer copies consumer so handlers cannot modify the stored entry
+func copyConsumer(consumer *APIKeyConsumer) *APIKeyConsumer {
+	if consumer == nil {
+		return nil
+	}
+	copied := *consumer
+	return &copied
+}
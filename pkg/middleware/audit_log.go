@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/rabbitmq"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// AuditConfig configures AuditLog
+type AuditConfig struct {
+	// Exchange receives the audit events
+	Exchange string
+	// RoutingKey of the audit events, "audit.http" by default
+	RoutingKey string
+	// Service is the name of the service written in the events
+	Service string
+	// Methods are the audited methods, POST, PUT, PATCH and DELETE by default
+	Methods []string
+	// BodyRoutes lists the route patterns (e.g. "/orders/:id") whose JSON request body is recorded, with
+	// sensitive fields redacted. Bodies are never recorded for other routes
+	BodyRoutes []string
+	// MaxBodySize is the largest request body recorded, 64 KiB by default
+	MaxBodySize int
+	// EntityIDParam is the path parameter holding the entity id, "id" by default
+	EntityIDParam string
+	// BufferSize is the number of events waiting to be published, 1000 by default. Events are dropped when full
+	BufferSize int
+	// PublishTimeout bounds each publish, 5 seconds by default
+	PublishTimeout time.Duration
+	// Logger logs publish failures and dropped events, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// auditDropped counts the audit events dropped because the buffer was full
+var auditDropped atomic.Uint64
+
+// AuditDroppedEvents returns the number of audit events dropped because the publish buffer was full
+func AuditDroppedEvents() uint64 {
+	return auditDropped.Load()
+}
+
+// AuditLog publishes an AuditEvent to RabbitMQ for every audited write request once its response completes.
+// Events are published by a background worker: the request is never blocked nor failed by the audit log
+func AuditLog(client rabbitmq.RabbitMQClient, cfg AuditConfig) echo.MiddlewareFunc {
+	if cfg.RoutingKey == "" {
+		cfg.RoutingKey = "audit.http"
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 64 << 10
+	}
+	if cfg.EntityIDParam == "" {
+		cfg.EntityIDParam = "id"
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+
+	methods := make(map[string]bool, len(cfg.Methods))
+	for _, method := range cfg.Methods {
+		methods[strings.ToUpper(method)] = true
+	}
+	bodyRoutes := make(map[string]bool, len(cfg.BodyRoutes))
+	for _, route := range cfg.BodyRoutes {
+		bodyRoutes[route] = true
+	}
+
+	events := make(chan *models.AuditEvent, cfg.BufferSize)
+	go publishAuditEvents(client, cfg, events)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !methods[req.Method] {
+				return next(c)
+			}
+
+			start := time.Now()
+			var body any
+			if bodyRoutes[c.Path()] {
+				body = readAuditBody(c, cfg.MaxBodySize)
+			}
+
+			err := next(c)
+
+			// Read after the handler so the principal set by the auth middlewares is known
+			ctx := c.Request().Context()
+			userID, _ := common.UserID(ctx)
+			sessionID, _ := common.SID(ctx)
+			requestID, _ := common.RequestID(ctx)
+			// The error handler writes the response of a returned error after the middlewares return
+			status := responseStatus(c, err)
+
+			event := &models.AuditEvent{
+				ID:         uuid.NewString(),
+				Service:    cfg.Service,
+				UserID:     userID,
+				SessionID:  sessionID,
+				Method:     req.Method,
+				Route:      c.Path(),
+				Path:       req.URL.Path,
+				EntityID:   c.Param(cfg.EntityIDParam),
+				IP:         c.RealIP(),
+				UserAgent:  req.UserAgent(),
+				Status:     status,
+				RequestID:  requestID,
+				TraceID:    common.TraceIDFromRequest(c.Request()),
+				Body:       body,
+				DurationMs: time.Since(start).Milliseconds(),
+				Timestamp:  start,
+			}
+
+			select {
+			case events <- event:
+			default:
+				dropped := auditDropped.Add(1)
+				cfg.Logger.Warnf("audit: buffer full, event of %s %s dropped (%d dropped so far)", event.Method, event.Path, dropped)
+			}
+			return err
+		}
+	}
+}
+
+// publishAuditEvents publishes the buffered events one by one
+func publishAuditEvents(client rabbitmq.RabbitMQClient, cfg AuditConfig, events <-chan *models.AuditEvent) {
+	for event := range events {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.PublishTimeout)
+		if err := client.Publish(ctx, cfg.Exchange, cfg.RoutingKey, event); err != nil {
+			cfg.Logger.WithError(err).Errorf("audit: failed to publish event %s of %s %s", event.ID, event.Method, event.Path)
+		}
+		cancel()
+	}
+}
+
+// readAuditBody reads the JSON request body, restoring it for the handler, and redacts its sensitive fields.
+// Bodies larger than limit or not JSON are not recorded
+func readAuditBody(c echo.Context, limit int) any {
+	req := c.Request()
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, int64(limit)+1))
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), req.Body))
+	if err != nil || len(data) > limit {
+		return nil
+	}
+
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+	return common.RedactJSON(decoded)
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/rabbitmq"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// fakeRabbitMQ is a rabbitmq.RabbitMQClient sending the published audit events to a channel, methods not
+// used by AuditLog panic through the nil embedded interface
+type fakeRabbitMQ struct {
+	rabbitmq.RabbitMQClient
+	events  chan *models.AuditEvent
+	release chan struct{}
+	err     error
+}
+
+func newFakeRabbitMQ() *fakeRabbitMQ {
+	return &fakeRabbitMQ{events: make(chan *models.AuditEvent, 10)}
+}
+
+func (f *fakeRabbitMQ) Publish(ctx context.Context, exchange, routingKey string, message any) error {
+	f.events <- message.(*models.AuditEvent)
+	if f.release != nil {
+		<-f.release
+	}
+	return f.err
+}
+
+// nextEvent waits for the next published event
+func (f *fakeRabbitMQ) nextEvent(t *testing.T) *models.AuditEvent {
+	t.Helper()
+	select {
+	case event := <-f.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no audit event published")
+		return nil
+	}
+}
+
+// auditServer serves /orders/:id behind AuditLog, the X-User request header plays the authenticated user
+func auditServer(client rabbitmq.RabbitMQClient, cfg AuditConfig) *echo.Echo {
+	logger, _ := test.NewNullLogger()
+	cfg.Logger = logger
+	e := echo.New()
+	e.Use(AuditLog(client, cfg), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if user := c.Request().Header.Get("X-User"); user != "" {
+				c.SetRequest(c.Request().WithContext(common.WithUserID(c.Request().Context(), user)))
+			}
+			return next(c)
+		}
+	})
+	e.PUT("/orders/:id", func(c echo.Context) error {
+		var body map[string]any
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, body)
+	})
+	e.DELETE("/orders/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusConflict, "order shipped")
+	})
+	e.POST("/orders/:id", func(c echo.Context) error {
+		return common.ValidationErrorI18n(common.ErrorDetail{Field: "status"})
+	})
+	e.GET("/orders/:id", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	return e
+}
+
+// sendAudit sends a request with a JSON body and returns the recorder
+func sendAudit(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-User", "u1")
+	req.RemoteAddr = "203.0.113.7:1234"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuditLogPublishesWrites(t *testing.T) {
+	client := newFakeRabbitMQ()
+	e := auditServer(client, AuditConfig{Exchange: "audit", Service: "order-service"})
+
+	sendAudit(e, http.MethodPut, "/orders/42", `{"status":"paid"}`)
+	event := client.nextEvent(t)
+
+	if event.UserID != "u1" || event.Method != http.MethodPut || event.Route != "/orders/:id" || event.Path != "/orders/42" {
+		t.Errorf("event = %+v, want who and what of the request", event)
+	}
+	if event.EntityID != "42" || event.IP != "203.0.113.7" || event.Status != http.StatusOK || event.Service != "order-service" {
+		t.Errorf("event = %+v, want the entity, IP, status and service", event)
+	}
+	if event.Body != nil {
+		t.Errorf("body = %v recorded for a route not allow-listed", event.Body)
+	}
+}
+
+func TestAuditLogReturnedErrorStatus(t *testing.T) {
+	client := newFakeRabbitMQ()
+	e := auditServer(client, AuditConfig{Exchange: "audit"})
+
+	for _, tt := range []struct {
+		method string
+		want   int
+	}{
+		{http.MethodPost, http.StatusBadRequest},
+		{http.MethodDelete, http.StatusConflict},
+	} {
+		sendAudit(e, tt.method, "/orders/42", `{}`)
+		if event := client.nextEvent(t); event.Status != tt.want {
+			t.Errorf("%s audited with status %d, want %d", tt.method, event.Status, tt.want)
+		}
+	}
+}
+
+func TestAuditLogSkipsReads(t *testing.T) {
+	client := newFakeRabbitMQ()
+	e := auditServer(client, AuditConfig{Exchange: "audit"})
+
+	sendAudit(e, http.MethodGet, "/orders/42", "")
+	sendAudit(e, http.MethodDelete, "/orders/42", "")
+
+	if event := client.nextEvent(t); event.Method != http.MethodDelete {
+		t.Errorf("first event = %s, want the GET skipped", event.Method)
+	} else if event.Status != http.StatusConflict {
+		t.Errorf("status = %d, want the status of the handler error", event.Status)
+	}
+}
+
+func TestAuditLogRedactsBody(t *testing.T) {
+	client := newFakeRabbitMQ()
+	e := auditServer(client, AuditConfig{Exchange: "audit", BodyRoutes: []string{"/orders/:id"}})
+
+	rec := sendAudit(e, http.MethodPut, "/orders/42", `{"note":"gift","payment":{"card_number":"4111111111111111"}}`)
+	if !strings.Contains(rec.Body.String(), "4111111111111111") {
+		t.Errorf("handler body = %s, want the request body restored for the handler", rec.Body.String())
+	}
+
+	body, _ := client.nextEvent(t).Body.(map[string]any)
+	payment, _ := body["payment"].(map[string]any)
+	if body["note"] != "gift" || payment["card_number"] != common.RedactedValue {
+		t.Errorf("body = %v, want the card number redacted", body)
+	}
+}
+
+func TestAuditLogPublishFailureDoesNotFailRequest(t *testing.T) {
+	client := newFakeRabbitMQ()
+	client.err = errors.New("channel closed")
+	e := auditServer(client, AuditConfig{Exchange: "audit"})
+
+	if rec := sendAudit(e, http.MethodPut, "/orders/42", `{}`); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the request unaffected by the publish failure", rec.Code)
+	}
+	client.nextEvent(t)
+}
+
+func TestAuditLogDropsOnOverflow(t *testing.T) {
+	client := newFakeRabbitMQ()
+	client.release = make(chan struct{})
+	defer close(client.release)
+	e := auditServer(client, AuditConfig{Exchange: "audit", BufferSize: 1})
+
+	// The worker blocks on the first event, the second fills the buffer and the third is dropped
+	sendAudit(e, http.MethodPut, "/orders/1", `{}`)
+	client.nextEvent(t)
+	dropped := AuditDroppedEvents()
+	sendAudit(e, http.MethodPut, "/orders/2", `{}`)
+	rec := sendAudit(e, http.MethodPut, "/orders/3", `{}`)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the request served with a full buffer", rec.Code)
+	}
+	if got := AuditDroppedEvents() - dropped; got != 1 {
+		t.Errorf("dropped events = %d, want 1", got)
+	}
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// HeaderCache tells whether a response was served from the cache (HIT) or by the handler (MISS)
+const HeaderCache = "X-Cache"
+
+// cacheKeyPrefix prefixes the Redis keys of cached responses and route indexes
+const cacheKeyPrefix = "httpcache:"
+
+// CacheConfig configures Cache
+type CacheConfig struct {
+	// Routes maps route patterns as registered (e.g. "/products/:id") to the TTL of their responses
+	Routes map[string]time.Duration
+	// DefaultTTL caches the GET routes absent from Routes, they are not cached when zero
+	DefaultTTL time.Duration
+	// VaryByUser caches a response per authenticated user (user_id set by JWTAuthMiddleware)
+	VaryByUser bool
+	// MaxBodySize is the largest response body cached, 1 MiB by default
+	MaxBodySize int
+	// Logger logs Redis failures, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// cachedResponse is the Redis value of a cached response
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// Cache serves GET responses of the configured routes from Redis. The key is built from the path, the
+// sorted query, the request locale and, with VaryByUser, the user id. Only 200 responses under the size
+// cap are stored, with an ETag so conditional requests get 304 on hits. Register it before
+// ResponseHandlerMiddleware so the wrapped body is cached, and call InvalidateCache on writes
+func Cache(rc redis.RedisClient, cfg CacheConfig) echo.MiddlewareFunc {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodGet {
+				return next(c)
+			}
+			route := c.Path()
+			ttl, ok := cfg.Routes[route]
+			if !ok {
+				ttl = cfg.DefaultTTL
+			}
+			if ttl <= 0 {
+				return next(c)
+			}
+
+			variant := req.URL.Path + "?" + req.URL.Query().Encode() + "|" + common.ResolveLocale(c)
+			if cfg.VaryByUser {
+				userID, _ := c.Get("user_id").(string)
+				variant += "|" + userID
+			}
+			key := cacheRouteKey(route) + ":" + hashCacheKey(variant)
+
+			data, err := rc.Get(req.Context(), key)
+			if err == nil {
+				var cached cachedResponse
+				if err := json.Unmarshal([]byte(data), &cached); err == nil {
+					return writeCachedResponse(c, cached)
+				}
+			} else if !errors.Is(err, goredis.Nil) {
+				cfg.Logger.WithError(err).Warnf("cache: redis unavailable for %s", route)
+				return next(c)
+			}
+
+			// Miss: record the response while the handler writes it
+			c.Response().Header().Set(HeaderCache, "MISS")
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer, limit: cfg.MaxBodySize}
+			c.Response().Writer = recorder
+			handlerErr := next(c)
+			c.Response().Writer = recorder.ResponseWriter
+
+			if handlerErr != nil || c.Response().Status != http.StatusOK || !c.Response().Committed || recorder.overflow {
+				return handlerErr
+			}
+
+			header := c.Response().Header().Clone()
+			header.Del(HeaderCache)
+			header.Del(echo.HeaderContentLength)
+			if header.Get("ETag") == "" {
+				sum := sha1.Sum(recorder.body.Bytes())
+				header.Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+			}
+			encoded, _ := json.Marshal(cachedResponse{Status: http.StatusOK, Header: header, Body: recorder.body.Bytes()})
+
+			storeCtx := context.WithoutCancel(req.Context())
+			if err := rc.Set(storeCtx, key, string(encoded), ttl); err != nil {
+				cfg.Logger.WithError(err).Warnf("cache: failed to store response of %s", route)
+				return nil
+			}
+			// Index the key by route so InvalidateCache can delete every variant
+			indexKey := cacheRouteKey(route) + ":index"
+			if err := rc.HSet(storeCtx, indexKey, key, time.Now().Add(ttl).Unix()); err == nil {
+				_ = rc.Expire(storeCtx, indexKey, ttl)
+			}
+			return nil
+		}
+	}
+}
+
+// InvalidateCache deletes every cached response of routePattern (e.g. "/products/:id"), for write handlers
+func InvalidateCache(ctx context.Context, rc redis.RedisClient, routePattern string) error {
+	indexKey := cacheRouteKey(routePattern) + ":index"
+	keys, err := rc.HKeys(ctx, indexKey)
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return err
+	}
+
+	var errs []error
+	for _, key := range keys {
+		if err := rc.Del(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := rc.Del(ctx, indexKey); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// writeCachedResponse replays a cached response, answering 304 when If-None-Match matches its ETag
+func writeCachedResponse(c echo.Context, cached cachedResponse) error {
+	header := c.Response().Header()
+	for name, values := range cached.Header {
+		header[name] = values
+	}
+	header.Set(HeaderCache, "HIT")
+
+	if etag := cached.Header.Get("ETag"); etag != "" && etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	c.Response().WriteHeader(cached.Status)
+	_, err := c.Response().Write(cached.Body)
+	return err
+}
+
+// etagMatches reports whether an If-None-Match header lists etag or "*"
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheRouteKey returns the Redis key prefix of a route, hashed as route patterns contain ':' and '*'
+func cacheRouteKey(route string) string {
+	return cacheKeyPrefix + hashCacheKey(route)[:16]
+}
+
+// hashCacheKey hashes a cache key component to keep Redis keys short
+func hashCacheKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
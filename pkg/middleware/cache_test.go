@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+)
+
+// cacheServer serves /products/:id through Cache, counting the handler calls. The X-User request
+// header plays the user_id set by JWTAuthMiddleware
+func cacheServer(rc *redistest.Client, cfg CacheConfig, calls *int) *echo.Echo {
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if user := c.Request().Header.Get("X-User"); user != "" {
+				c.Set("user_id", user)
+			}
+			return next(c)
+		}
+	}, Cache(rc, cfg))
+	e.GET("/products/:id", func(c echo.Context) error {
+		*calls++
+		if c.Param("id") == "missing" {
+			return c.String(http.StatusNotFound, "missing")
+		}
+		return c.String(http.StatusOK, c.Param("id")+"#"+strconv.Itoa(*calls))
+	})
+	return e
+}
+
+// getCached requests path as user and returns the recorder
+func getCached(e *echo.Echo, path, user string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	var calls int
+	e := cacheServer(redistest.New(), CacheConfig{Routes: map[string]time.Duration{"/products/:id": time.Minute}}, &calls)
+
+	first := getCached(e, "/products/1?b=2&a=1", "", nil)
+	if first.Header().Get(HeaderCache) != "MISS" || first.Body.String() != "1#1" {
+		t.Fatalf("first response = %q, X-Cache %q, want a MISS", first.Body.String(), first.Header().Get(HeaderCache))
+	}
+
+	second := getCached(e, "/products/1?a=1&b=2", "", nil)
+	if second.Header().Get(HeaderCache) != "HIT" || second.Body.String() != "1#1" || second.Code != http.StatusOK {
+		t.Errorf("second response = %d %q, X-Cache %q, want the cached HIT with the query sorted", second.Code, second.Body.String(), second.Header().Get(HeaderCache))
+	}
+	if second.Header().Get(echo.HeaderContentType) != first.Header().Get(echo.HeaderContentType) {
+		t.Errorf("cached Content-Type = %q, want %q", second.Header().Get(echo.HeaderContentType), first.Header().Get(echo.HeaderContentType))
+	}
+
+	if other := getCached(e, "/products/2", "", nil); other.Header().Get(HeaderCache) != "MISS" || other.Body.String() != "2#2" {
+		t.Errorf("other path = %q, X-Cache %q, want a MISS", other.Body.String(), other.Header().Get(HeaderCache))
+	}
+	if calls != 2 {
+		t.Errorf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestCacheConditionalHit(t *testing.T) {
+	var calls int
+	e := cacheServer(redistest.New(), CacheConfig{DefaultTTL: time.Minute}, &calls)
+
+	getCached(e, "/products/1", "", nil)
+	hit := getCached(e, "/products/1", "", nil)
+	etag := hit.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("cached response without ETag")
+	}
+
+	rec := getCached(e, "/products/1", "", http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("conditional hit = %d %q, want 304 without body", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCacheOnlyStoresOK(t *testing.T) {
+	var calls int
+	e := cacheServer(redistest.New(), CacheConfig{DefaultTTL: time.Minute}, &calls)
+
+	getCached(e, "/products/missing", "", nil)
+	if rec := getCached(e, "/products/missing", "", nil); rec.Header().Get(HeaderCache) != "MISS" || rec.Code != http.StatusNotFound {
+		t.Errorf("404 = %d, X-Cache %q, want it not cached", rec.Code, rec.Header().Get(HeaderCache))
+	}
+
+	limited := cacheServer(redistest.New(), CacheConfig{DefaultTTL: time.Minute, MaxBodySize: 2}, &calls)
+	getCached(limited, "/products/1", "", nil)
+	if rec := getCached(limited, "/products/1", "", nil); rec.Header().Get(HeaderCache) != "MISS" || rec.Body.String() == "" {
+		t.Errorf("body over the cap = %q, X-Cache %q, want it served and not cached", rec.Body.String(), rec.Header().Get(HeaderCache))
+	}
+}
+
+func TestCacheVaryByUser(t *testing.T) {
+	var calls int
+	e := cacheServer(redistest.New(), CacheConfig{DefaultTTL: time.Minute, VaryByUser: true}, &calls)
+
+	alice := getCached(e, "/products/1", "alice", nil)
+	bob := getCached(e, "/products/1", "bob", nil)
+	if bob.Header().Get(HeaderCache) != "MISS" || bob.Body.String() == alice.Body.String() {
+		t.Errorf("bob = %q, X-Cache %q, want his own response", bob.Body.String(), bob.Header().Get(HeaderCache))
+	}
+	if again := getCached(e, "/products/1", "alice", nil); again.Header().Get(HeaderCache) != "HIT" || again.Body.String() != alice.Body.String() {
+		t.Errorf("alice again = %q, X-Cache %q, want her cached response", again.Body.String(), again.Header().Get(HeaderCache))
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	var calls int
+	rc := redistest.New()
+	e := cacheServer(rc, CacheConfig{DefaultTTL: time.Minute}, &calls)
+
+	getCached(e, "/products/1", "", nil)
+	getCached(e, "/products/2", "", nil)
+	if err := InvalidateCache(context.Background(), rc, "/products/:id"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/products/1", "/products/2"} {
+		if rec := getCached(e, path, "", nil); rec.Header().Get(HeaderCache) != "MISS" {
+			t.Errorf("%s after invalidation: X-Cache %q, want MISS", path, rec.Header().Get(HeaderCache))
+		}
+	}
+	if calls != 4 {
+		t.Errorf("handler calls = %d, want 4", calls)
+	}
+}
+
+func TestCacheRedisDown(t *testing.T) {
+	var calls int
+	rc := redistest.New()
+	e := cacheServer(rc, CacheConfig{DefaultTTL: time.Minute}, &calls)
+	rc.SetDown(true)
+
+	for range 2 {
+		if rec := getCached(e, "/products/1", "", nil); rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want the handler response while redis is down", rec.Code)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler calls = %d, want 2", calls)
+	}
+}
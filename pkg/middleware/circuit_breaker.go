@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/circuitbreaker"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// ErrorCodeCircuitOpen is the error code of requests rejected by an open circuit breaker
+const ErrorCodeCircuitOpen = "circuit_open"
+
+// CircuitBreaker opens a breaker per route when its 5xx rate spikes, answering 503 SERVICE_UNAVAILABLE
+// immediately instead of waiting on a failing dependency. A nil registry uses default settings
+func CircuitBreaker(registry *circuitbreaker.Registry) echo.MiddlewareFunc {
+	if registry == nil {
+		registry = circuitbreaker.NewRegistry(circuitbreaker.Config{})
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			breaker := registry.Get(c.Request().Method + " " + c.Path())
+			done, err := breaker.Allow()
+			if err != nil {
+				retryAfter := int64(breaker.RetryAfter().Seconds() + 0.5)
+				c.Response().Header().Set("Retry-After", strconv.FormatInt(max(retryAfter, 1), 10))
+				ctx := common.LocaleContext(c)
+				errorResp := common.CreateErrorResponse(common.SERVICE_UNAVAILABLE, common.TWithContextAndFallback(ctx, common.MsgErrorServiceUnavailable, "Service unavailable"))
+				errorResp.ErrorCode = ErrorCodeCircuitOpen
+				errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+				return c.JSON(http.StatusServiceUnavailable, errorResp)
+			}
+
+			err = next(c)
+			done(responseStatus(c, err) >= http.StatusInternalServerError)
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status the request ends with, from the returned error when the
+// response was not written yet
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	var appError *common.AppError
+	if errors.As(err, &appError) {
+		return common.HTTPStatusFor(appError.ToErrorResponse())
+	}
+	var errorResp *common.ErrorResponse
+	if errors.As(err, &errorResp) {
+		return common.HTTPStatusFor(errorResp)
+	}
+	return http.StatusInternalServerError
+}
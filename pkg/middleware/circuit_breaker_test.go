@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/circuitbreaker"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	registry := circuitbreaker.NewRegistry(circuitbreaker.Config{
+		MinRequests: 2,
+		CoolDown:    time.Minute,
+		Logger:      logger,
+		Now:         clock.Now,
+	})
+
+	e := echo.New()
+	e.Use(ErrorHandlerMiddleware(), CircuitBreaker(registry))
+	calls := 0
+	e.GET("/reports", func(c echo.Context) error {
+		calls++
+		return echo.NewHTTPError(http.StatusBadGateway, "upstream down")
+	})
+	e.GET("/orders", func(c echo.Context) error {
+		return common.NotFoundError("order not found")
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	get("/reports")
+	get("/reports")
+	rec := get("/reports")
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != ErrorCodeCircuitOpen {
+		t.Fatalf("response = %d %s, want 503 %s", rec.Code, rec.Body.String(), ErrorCodeCircuitOpen)
+	}
+	if calls != 2 {
+		t.Errorf("handler calls = %d, want the open breaker to skip the handler", calls)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want the cool-down", got)
+	}
+
+	// 4xx are not failures and the breaker is per route
+	for range 3 {
+		if rec := get("/orders"); rec.Code != http.StatusNotFound {
+			t.Errorf("/orders = %d, want the handler 404", rec.Code)
+		}
+	}
+	if states := registry.States(); states["GET /reports"] != "open" || states["GET /orders"] != "closed" {
+		t.Errorf("states = %v", states)
+	}
+
+	clock.Advance(time.Minute)
+	get("/reports")
+	if calls != 3 {
+		t.Errorf("handler calls = %d, want a probe after the cool-down", calls)
+	}
+}
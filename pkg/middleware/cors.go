@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// CORSOptions configures CORS
+type CORSOptions struct {
+	// AllowOrigins lists the allowed origins: exact origins ("https://app.example.com"), wildcard subdomains
+	// ("*.example.com" or "https://*.example.com") or "*". No cross-origin request is allowed when empty
+	AllowOrigins []string
+	// AllowMethods are the methods allowed in preflight responses, DefaultCORSMethods when empty
+	AllowMethods []string
+	// AllowHeaders are the request headers allowed in preflight responses, DefaultCORSHeaders when empty
+	AllowHeaders []string
+	// ExposeHeaders are the response headers readable by browsers
+	ExposeHeaders []string
+	// AllowCredentials allows cookies and Authorization headers. "*" is then ignored in AllowOrigins
+	AllowCredentials bool
+	// MaxAge is how long browsers cache preflight responses
+	MaxAge time.Duration
+}
+
+// DefaultCORSMethods are the methods allowed when CORSOptions.AllowMethods is empty
+var DefaultCORSMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// DefaultCORSHeaders are the request headers allowed when CORSOptions.AllowHeaders is empty
+var DefaultCORSHeaders = []string{
+	echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "Accept-Language",
+	common.HeaderRequestID, HeaderAPIKey, HeaderIdempotencyKey,
+}
+
+// CORSOptionsFromEnv reads CORSOptions from comma separated environment variables named prefix + ALLOW_ORIGINS,
+// ALLOW_METHODS, ALLOW_HEADERS, EXPOSE_HEADERS, ALLOW_CREDENTIALS (bool) and MAX_AGE (seconds or a duration),
+// e.g. CORS_ALLOW_ORIGINS="https://app.example.com, *.example.com" with prefix "CORS_"
+func CORSOptionsFromEnv(prefix string) CORSOptions {
+	opts := CORSOptions{
+		AllowOrigins:  ParseList(os.Getenv(prefix + "ALLOW_ORIGINS")),
+		AllowMethods:  ParseList(os.Getenv(prefix + "ALLOW_METHODS")),
+		AllowHeaders:  ParseList(os.Getenv(prefix + "ALLOW_HEADERS")),
+		ExposeHeaders: ParseList(os.Getenv(prefix + "EXPOSE_HEADERS")),
+	}
+	opts.AllowCredentials, _ = strconv.ParseBool(os.Getenv(prefix + "ALLOW_CREDENTIALS"))
+	if maxAge := strings.TrimSpace(os.Getenv(prefix + "MAX_AGE")); maxAge != "" {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			opts.MaxAge = time.Duration(seconds) * time.Second
+		} else if d, err := time.ParseDuration(maxAge); err == nil {
+			opts.MaxAge = d
+		}
+	}
+	return opts
+}
+
+// ParseList splits a comma separated list, trimming spaces and dropping empty items
+func ParseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// CORS answers preflight requests and sets the CORS headers of the allowed origins with echo's CORS
+// middleware. Preflight requests end here, register it before the auth middlewares
+func CORS(opts CORSOptions) echo.MiddlewareFunc {
+	if len(opts.AllowMethods) == 0 {
+		opts.AllowMethods = DefaultCORSMethods
+	}
+	if len(opts.AllowHeaders) == 0 {
+		opts.AllowHeaders = DefaultCORSHeaders
+	}
+
+	origins := make([]string, 0, len(opts.AllowOrigins))
+	for _, origin := range opts.AllowOrigins {
+		if origin == "*" && opts.AllowCredentials {
+			// Reflecting every origin with credentials would let any site act as the user
+			continue
+		}
+		origins = append(origins, strings.TrimSuffix(strings.ToLower(origin), "/"))
+	}
+
+	return echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			return originAllowed(strings.ToLower(origin), origins), nil
+		},
+		AllowMethods:     opts.AllowMethods,
+		AllowHeaders:     opts.AllowHeaders,
+		ExposeHeaders:    opts.ExposeHeaders,
+		AllowCredentials: opts.AllowCredentials,
+		MaxAge:           int(opts.MaxAge.Seconds()),
+	})
+}
+
+// originAllowed matches origin against exact origins, "*" and wildcard subdomain patterns
+func originAllowed(origin string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == origin || matchWildcardOrigin(origin, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcardOrigin matches origin against "*.example.com" (any scheme) or "https://*.example.com".
+// The apex domain itself does not match
+func matchWildcardOrigin(origin, pattern string) bool {
+	scheme, hostPattern, hasScheme := strings.Cut(pattern, "://")
+	if !hasScheme {
+		hostPattern = pattern
+	}
+	suffix, ok := strings.CutPrefix(hostPattern, "*.")
+	if !ok {
+		return false
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" || hasScheme && parsed.Scheme != scheme {
+		return false
+	}
+	host := parsed.Host
+	if !strings.Contains(suffix, ":") {
+		// Patterns without port match any port
+		host = parsed.Hostname()
+	}
+	return strings.HasSuffix(host, "."+suffix)
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// corsServer serves GET /orders behind CORS and a middleware rejecting every request as unauthenticated
+func corsServer(opts CORSOptions) *echo.Echo {
+	e := echo.New()
+	e.Use(CORS(opts), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+	})
+	e.GET("/orders", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	return e
+}
+
+// preflight sends the preflight request of a GET from origin
+func preflight(e *echo.Echo, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/orders", nil)
+	req.Header.Set(echo.HeaderOrigin, origin)
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodGet)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflight(t *testing.T) {
+	e := corsServer(CORSOptions{AllowOrigins: []string{"https://app.example.com"}, MaxAge: 10 * time.Minute})
+
+	rec := preflight(e, "https://app.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want the preflight answered before the auth middleware", rec.Code)
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowMethods); got == "" {
+		t.Error("preflight without Access-Control-Allow-Methods")
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlMaxAge); got != "600" {
+		t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	e := corsServer(CORSOptions{AllowOrigins: []string{"https://app.example.com"}})
+
+	rec := preflight(e, "https://evil.test")
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for a disallowed origin", got)
+	}
+}
+
+func TestCORSCredentialsIgnoreWildcard(t *testing.T) {
+	e := corsServer(CORSOptions{AllowOrigins: []string{"*"}, AllowCredentials: true})
+
+	if got := preflight(e, "https://evil.test").Header().Get(echo.HeaderAccessControlAllowOrigin); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want * ignored with credentials", got)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	patterns := []string{"https://app.example.com", "*.shop.test", "https://*.example.org", "*.local.test:8080"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"http://app.example.com", false},
+		{"https://a.shop.test", true},
+		{"http://a.b.shop.test:3000", true},
+		{"https://shop.test", false},
+		{"https://evilshop.test", false},
+		{"https://api.example.org", true},
+		{"http://api.example.org", false},
+		{"http://web.local.test:8080", true},
+		{"http://web.local.test:9090", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := originAllowed(tt.origin, patterns); got != tt.want {
+			t.Errorf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSOptionsFromEnv(t *testing.T) {
+	t.Setenv("TEST_CORS_ALLOW_ORIGINS", " https://app.example.com, ,*.example.com ")
+	t.Setenv("TEST_CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("TEST_CORS_MAX_AGE", "90")
+
+	opts := CORSOptionsFromEnv("TEST_CORS_")
+	if len(opts.AllowOrigins) != 2 || opts.AllowOrigins[1] != "*.example.com" {
+		t.Errorf("AllowOrigins = %q", opts.AllowOrigins)
+	}
+	if !opts.AllowCredentials || opts.MaxAge != 90*time.Second {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	t.Setenv("TEST_CORS_MAX_AGE", "2h")
+	if opts := CORSOptionsFromEnv("TEST_CORS_"); opts.MaxAge != 2*time.Hour {
+		t.Errorf("MaxAge = %s, want the duration", opts.MaxAge)
+	}
+}
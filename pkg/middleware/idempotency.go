@@ -0,0 +1,350 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// Idempotency headers
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+)
+
+// ErrorCodeIdempotencyInProgress is the error code of responses to a duplicate sent while the first attempt runs
+const ErrorCodeIdempotencyInProgress = "idempotency_in_progress"
+
+// ErrorCodeIdempotencyKeyReused is the error code of responses to a request reusing an Idempotency-Key
+// with a different body
+const ErrorCodeIdempotencyKeyReused = "idempotency_key_reused"
+
+// defaultIdempotencyReplayHeaders are the response headers replayed unless WithIdempotencyReplayHeaders is used
+var defaultIdempotencyReplayHeaders = []string{
+	echo.HeaderContentType,
+	echo.HeaderLocation,
+	"Content-Language",
+	"ETag",
+	echo.HeaderLastModified,
+	"Cache-Control",
+}
+
+// maxIdempotencyKeyLength bounds the accepted Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// idempotencyConfig holds the Idempotency options
+type idempotencyConfig struct {
+	methods     map[string]bool
+	paths       []string
+	keyPrefix   string
+	maxBodySize int
+	lockTTL     time.Duration
+	logger      *logrus.Logger
+	// replayHeaders are the canonical names of the stored response headers
+	replayHeaders []string
+}
+
+// IdempotencyOption configures Idempotency
+type IdempotencyOption func(*idempotencyConfig)
+
+// WithIdempotencyMethods replaces the methods honoring Idempotency-Key, POST by default
+func WithIdempotencyMethods(methods ...string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.methods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			cfg.methods[strings.ToUpper(method)] = true
+		}
+	}
+}
+
+// WithIdempotencyPaths restricts Idempotency to the paths (exact paths or prefixes ending with '*'), all paths by default
+func WithIdempotencyPaths(paths ...string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.paths = append(cfg.paths, paths...)
+	}
+}
+
+// WithIdempotencyKeyPrefix sets the Redis key prefix, "idempotency" by default
+func WithIdempotencyKeyPrefix(prefix string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.keyPrefix = prefix
+	}
+}
+
+// WithIdempotencyMaxBodySize sets the largest response body stored for replay, 1 MiB by default.
+// Larger responses are not stored and a retry runs the handler again
+func WithIdempotencyMaxBodySize(size int) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.maxBodySize = size
+	}
+}
+
+// WithIdempotencyLockTTL bounds how long the "processing" placeholder of a running first attempt lives,
+// 1 minute by default so a crashed instance does not block the key until the replay TTL
+func WithIdempotencyLockTTL(ttl time.Duration) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.lockTTL = ttl
+	}
+}
+
+// WithIdempotencyReplayHeaders replaces the response headers stored and replayed, Content-Type, Location,
+// Content-Language, ETag, Last-Modified and Cache-Control by default. Other headers, Set-Cookie in
+// particular, are never replayed
+func WithIdempotencyReplayHeaders(headers ...string) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.replayHeaders = make([]string, 0, len(headers))
+		for _, header := range headers {
+			cfg.replayHeaders = append(cfg.replayHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+}
+
+// WithIdempotencyLogger sets the logger of Redis failures and skipped responses, the standard logrus logger by default
+func WithIdempotencyLogger(logger *logrus.Logger) IdempotencyOption {
+	return func(cfg *idempotencyConfig) {
+		cfg.logger = logger
+	}
+}
+
+// idempotencyRecord is the Redis value of an idempotency key
+type idempotencyRecord struct {
+	Processing bool `json:"processing,omitempty"`
+	// BodyHash is the SHA-256 of the request body, a retry with another body is rejected
+	BodyHash string      `json:"body_hash,omitempty"`
+	Status   int         `json:"status,omitempty"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     []byte      `json:"body,omitempty"`
+}
+
+// Idempotency replays the stored response of requests retried with the same Idempotency-Key header for ttl.
+// The first request stores a "processing" placeholder with SetNX, duplicates sent while it runs get
+// 409 idempotency_in_progress, and a retry with a different request body gets 422 idempotency_key_reused.
+// Responses with a 5xx status, returned errors or bodies above the size cap are not stored so the client
+// can retry. Only the allow-listed headers are replayed, see WithIdempotencyReplayHeaders. Keys are scoped
+// by method, path and authenticated user
+func Idempotency(rc redis.RedisClient, ttl time.Duration, opts ...IdempotencyOption) echo.MiddlewareFunc {
+	cfg := &idempotencyConfig{
+		methods:     map[string]bool{http.MethodPost: true},
+		keyPrefix:   "idempotency",
+		maxBodySize: 1 << 20,
+		lockTTL:     time.Minute,
+		logger:      logrus.StandardLogger(),
+
+		replayHeaders: defaultIdempotencyReplayHeaders,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	lockTTL := min(cfg.lockTTL, ttl)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			idempotencyKey := req.Header.Get(HeaderIdempotencyKey)
+			if idempotencyKey == "" || !cfg.methods[req.Method] ||
+				len(cfg.paths) > 0 && !matchPath(req.URL.Path, cfg.paths) {
+				return next(c)
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				ctx := common.LocaleContext(c)
+				errorResp := common.CreateErrorResponse(common.BAD_REQUEST, common.TWithContext(ctx, common.MsgErrorBadRequest),
+					common.ErrorDetail{Field: HeaderIdempotencyKey, Message: "Idempotency-Key is too long"})
+				errorResp.TraceID = common.TraceIDFromRequest(req)
+				return c.JSON(http.StatusBadRequest, errorResp)
+			}
+
+			key := cfg.keyPrefix + ":" + req.Method + ":" + req.URL.Path + ":"
+			if userID, ok := c.Get("user_id").(string); ok && userID != "" {
+				key += userID + ":"
+			}
+			key += idempotencyKey
+
+			bodyHash, err := hashRequestBody(req)
+			if err != nil {
+				return err
+			}
+
+			placeholder, _ := json.Marshal(idempotencyRecord{Processing: true, BodyHash: bodyHash})
+			acquired, err := rc.SetNX(req.Context(), key, string(placeholder), lockTTL)
+			var record *idempotencyRecord
+			if err == nil && !acquired {
+				record, err = readIdempotencyRecord(req.Context(), rc, key)
+				if err == nil && record == nil {
+					// The first attempt released the key between SetNX and Get, acquire it once more
+					acquired, err = rc.SetNX(req.Context(), key, string(placeholder), lockTTL)
+					if err == nil && !acquired {
+						record, err = readIdempotencyRecord(req.Context(), rc, key)
+					}
+				}
+			}
+			if err != nil {
+				// Fail open, the request runs without idempotency protection
+				cfg.logger.WithError(err).Warnf("idempotency: redis unavailable for key %s", idempotencyKey)
+				return next(c)
+			}
+			if !acquired {
+				return replayIdempotent(c, record, bodyHash)
+			}
+
+			// Capture the response while it is written
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer, limit: cfg.maxBodySize}
+			c.Response().Writer = recorder
+			handlerErr := next(c)
+			c.Response().Writer = recorder.ResponseWriter
+
+			// The request context may be canceled once the response is written
+			storeCtx := context.WithoutCancel(req.Context())
+			status := c.Response().Status
+			switch {
+			case handlerErr != nil || !c.Response().Committed || status >= http.StatusInternalServerError:
+				if delErr := rc.Del(storeCtx, key); delErr != nil {
+					cfg.logger.WithError(delErr).Warnf("idempotency: failed to release key %s", idempotencyKey)
+				}
+			case recorder.overflow:
+				cfg.logger.Warnf("idempotency: response of %s %s exceeds %d bytes and is not stored", req.Method, req.URL.Path, cfg.maxBodySize)
+				if delErr := rc.Del(storeCtx, key); delErr != nil {
+					cfg.logger.WithError(delErr).Warnf("idempotency: failed to release key %s", idempotencyKey)
+				}
+			default:
+				record, _ := json.Marshal(idempotencyRecord{
+					BodyHash: bodyHash,
+					Status:   status,
+					Header:   replayableHeaders(c.Response().Header(), cfg.replayHeaders),
+					Body:     recorder.body.Bytes(),
+				})
+				if setErr := rc.Set(storeCtx, key, string(record), ttl); setErr != nil {
+					cfg.logger.WithError(setErr).Warnf("idempotency: failed to store response for key %s", idempotencyKey)
+				}
+			}
+
+			return handlerErr
+		}
+	}
+}
+
+// hashRequestBody returns the hex SHA-256 of the request body, which is restored for the handler
+func hashRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return hashBytes(nil), nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return hashBytes(body), nil
+}
+
+// hashBytes returns the hex SHA-256 of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readIdempotencyRecord reads the record of key, nil when the key does not exist
+func readIdempotencyRecord(ctx context.Context, rc redis.RedisClient, key string) (*idempotencyRecord, error) {
+	data, err := rc.Get(ctx, key)
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// replayableHeaders returns the allowed headers of header
+func replayableHeaders(header http.Header, allowed []string) http.Header {
+	replayable := make(http.Header, len(allowed))
+	for _, name := range allowed {
+		if values := header.Values(name); len(values) > 0 {
+			replayable[name] = append([]string(nil), values...)
+		}
+	}
+	return replayable
+}
+
+// replayIdempotent answers a duplicate request with the stored response, 409 while the first attempt runs
+// and 422 when the body differs from the first attempt
+func replayIdempotent(c echo.Context, record *idempotencyRecord, bodyHash string) error {
+	if record != nil && record.BodyHash != "" && record.BodyHash != bodyHash {
+		return idempotencyKeyReused(c)
+	}
+	if record == nil || record.Processing {
+		// A nil record was released twice by failing first attempts, the client may retry
+		return idempotencyInProgress(c)
+	}
+
+	header := c.Response().Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(HeaderIdempotencyReplayed, "true")
+	c.Response().WriteHeader(record.Status)
+	_, err := c.Response().Write(record.Body)
+	return err
+}
+
+// idempotencyKeyReused writes the 422 response of a request reusing an Idempotency-Key with another body
+func idempotencyKeyReused(c echo.Context) error {
+	ctx := common.LocaleContext(c)
+	errorResp := common.CreateErrorResponse(common.ResponseCodeForHTTPStatus(http.StatusUnprocessableEntity),
+		common.TWithContextAndFallback(ctx, common.MsgErrorIdempotencyKeyReused, "The Idempotency-Key was used with a different request body"))
+	errorResp.ErrorCode = ErrorCodeIdempotencyKeyReused
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return c.JSON(http.StatusUnprocessableEntity, errorResp)
+}
+
+// idempotencyInProgress writes the 409 response of a duplicate sent while the first attempt runs
+func idempotencyInProgress(c echo.Context) error {
+	ctx := common.LocaleContext(c)
+	errorResp := common.CreateErrorResponse(common.CONFLICT,
+		common.TWithContextAndFallback(ctx, common.MsgErrorIdempotencyInProgress, "A request with this Idempotency-Key is being processed"))
+	errorResp.ErrorCode = ErrorCodeIdempotencyInProgress
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return c.JSON(http.StatusConflict, errorResp)
+}
+
+// responseRecorder copies the response body, up to limit bytes, while writing it to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+// Write writes p to the client and records it
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.overflow {
+		if r.body.Len()+len(p) > r.limit {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+)
+
+// racingRedis loses the first SetNX race against a first attempt that released its key right after
+type racingRedis struct {
+	*redistest.Client
+	lost atomic.Bool
+}
+
+func (r *racingRedis) SetNX(ctx context.Context, key string, val any, exp time.Duration) (bool, error) {
+	if r.lost.CompareAndSwap(false, true) {
+		return false, nil
+	}
+	return r.Client.SetNX(ctx, key, val, exp)
+}
+
+// idempotencyServer counts the executions of POST /payments, answering the body with a cookie
+type idempotencyServer struct {
+	*echo.Echo
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func newIdempotencyServer(rc redis.RedisClient, ttl time.Duration, opts ...IdempotencyOption) *idempotencyServer {
+	logger, _ := test.NewNullLogger()
+	s := &idempotencyServer{Echo: echo.New()}
+	s.Use(Idempotency(rc, ttl, append([]IdempotencyOption{WithIdempotencyLogger(logger)}, opts...)...))
+	s.POST("/payments", func(c echo.Context) error {
+		n := s.calls.Add(1)
+		if s.release != nil {
+			<-s.release
+		}
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		c.SetCookie(&http.Cookie{Name: "session", Value: "secret"})
+		c.Response().Header().Set(echo.HeaderLocation, "/payments/1")
+		c.Response().Header().Set("X-Internal", "1")
+		return c.String(http.StatusCreated, string(body)+" #"+strconv.Itoa(int(n)))
+	})
+	s.POST("/fail", func(c echo.Context) error {
+		s.calls.Add(1)
+		return c.NoContent(http.StatusBadGateway)
+	})
+	return s
+}
+
+// post sends a POST of body to path with the Idempotency-Key key
+func (s *idempotencyServer) post(path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	s := newIdempotencyServer(redistest.New(), time.Hour)
+
+	first := s.post("/payments", "key-1", "pay 10")
+	if first.Code != http.StatusCreated || first.Body.String() != "pay 10 #1" {
+		t.Fatalf("first status = %d, body = %q", first.Code, first.Body.String())
+	}
+
+	replay := s.post("/payments", "key-1", "pay 10")
+	if replay.Code != http.StatusCreated || replay.Body.String() != "pay 10 #1" {
+		t.Errorf("replay status = %d, body = %q, want the first response", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get(HeaderIdempotencyReplayed) != "true" || replay.Header().Get(echo.HeaderLocation) != "/payments/1" {
+		t.Errorf("replay headers = %v, want the allow-listed headers", replay.Header())
+	}
+	if replay.Header().Get("Set-Cookie") != "" || replay.Header().Get("X-Internal") != "" {
+		t.Errorf("replay headers = %v, want Set-Cookie and other headers dropped", replay.Header())
+	}
+	if got := s.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+
+	if rec := s.post("/payments", "key-2", "pay 10"); rec.Body.String() != "pay 10 #2" {
+		t.Errorf("other key body = %q, want a new execution", rec.Body.String())
+	}
+	if rec := s.post("/payments", "", "pay 10"); rec.Body.String() != "pay 10 #3" || rec.Header().Get(HeaderIdempotencyReplayed) != "" {
+		t.Errorf("request without key body = %q, want a new execution", rec.Body.String())
+	}
+}
+
+func TestIdempotencyReplayHeadersOption(t *testing.T) {
+	s := newIdempotencyServer(redistest.New(), time.Hour, WithIdempotencyReplayHeaders("x-internal"))
+	s.post("/payments", "key-1", "pay")
+
+	replay := s.post("/payments", "key-1", "pay")
+	if replay.Header().Get("X-Internal") != "1" || replay.Header().Get(echo.HeaderLocation) != "" {
+		t.Errorf("replay headers = %v, want only the configured headers", replay.Header())
+	}
+}
+
+func TestIdempotencyBodyMismatch(t *testing.T) {
+	s := newIdempotencyServer(redistest.New(), time.Hour)
+	s.post("/payments", "key-1", "pay 10")
+
+	rec := s.post("/payments", "key-1", "pay 1000")
+	if rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != ErrorCodeIdempotencyKeyReused {
+		t.Errorf("status = %d, body = %s, want 422 %s", rec.Code, rec.Body.String(), ErrorCodeIdempotencyKeyReused)
+	}
+	if got := s.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	s := newIdempotencyServer(redistest.New(), time.Hour)
+	s.release = make(chan struct{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- s.post("/payments", "key-1", "pay") }()
+	for s.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := s.post("/payments", "key-1", "pay")
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != ErrorCodeIdempotencyInProgress {
+		t.Errorf("duplicate status = %d, body = %s, want 409 %s", rec.Code, rec.Body.String(), ErrorCodeIdempotencyInProgress)
+	}
+	if rec := s.post("/payments", "key-1", "other"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("duplicate with another body status = %d, want 422", rec.Code)
+	}
+
+	close(s.release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first status = %d", first.Code)
+	}
+	if rec := s.post("/payments", "key-1", "pay"); rec.Header().Get(HeaderIdempotencyReplayed) != "true" {
+		t.Error("response of the first attempt not replayed once stored")
+	}
+}
+
+func TestIdempotencyLostRace(t *testing.T) {
+	s := newIdempotencyServer(&racingRedis{Client: redistest.New()}, time.Hour)
+
+	rec := s.post("/payments", "key-1", "pay")
+	if rec.Code != http.StatusCreated || s.calls.Load() != 1 {
+		t.Errorf("status = %d, calls = %d, want the key acquired on retry", rec.Code, s.calls.Load())
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	fake := redistest.New()
+	s := newIdempotencyServer(fake, time.Hour)
+
+	s.post("/payments", "key-1", "pay")
+	fake.Advance(59 * time.Minute)
+	if rec := s.post("/payments", "key-1", "pay"); rec.Body.String() != "pay #1" {
+		t.Errorf("body before expiry = %q, want the replay", rec.Body.String())
+	}
+	fake.Advance(2 * time.Minute)
+	if rec := s.post("/payments", "key-1", "pay"); rec.Body.String() != "pay #2" || rec.Header().Get(HeaderIdempotencyReplayed) != "" {
+		t.Errorf("body after expiry = %q, want a new execution", rec.Body.String())
+	}
+}
+
+func TestIdempotencyDoesNotStoreFailures(t *testing.T) {
+	fake := redistest.New()
+	s := newIdempotencyServer(fake, time.Hour)
+
+	s.post("/fail", "key-1", "")
+	s.post("/fail", "key-1", "")
+	if got := s.calls.Load(); got != 2 {
+		t.Errorf("failing handler ran %d times, want 5xx responses retried", got)
+	}
+
+	fake.SetDown(true)
+	if rec := s.post("/payments", "key-2", "pay"); rec.Code != http.StatusCreated {
+		t.Errorf("status with redis down = %d, want the request to fail open", rec.Code)
+	}
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// ErrorCodeIPForbidden is the error code of requests rejected by IPFilter
+const ErrorCodeIPForbidden = "ip_forbidden"
+
+// IPFilterConfig configures IPFilter. Entries are IPs or CIDR ranges, IPv4 or IPv6
+type IPFilterConfig struct {
+	// Allow lists the clients served, every client when empty
+	Allow []string
+	// Deny lists the clients rejected, it takes precedence over Allow
+	Deny []string
+	// TrustedProxies are the direct peers whose X-Forwarded-For / X-Real-IP headers are honored.
+	// Without them the client IP is always the peer address, so the headers cannot be spoofed
+	TrustedProxies []string
+	// Logger logs rejected requests, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// ipFilterRules are the parsed lists of an IPFilterConfig
+type ipFilterRules struct {
+	allow          []netip.Prefix
+	deny           []netip.Prefix
+	trustedProxies []netip.Prefix
+	logger         *logrus.Logger
+}
+
+// IPFilterMiddleware restricts routes to client IP ranges, its configuration can be reloaded at runtime
+type IPFilterMiddleware struct {
+	rules atomic.Pointer[ipFilterRules]
+}
+
+// NewIPFilterMiddleware creates an IP filter, failing on invalid IPs or CIDR ranges
+func NewIPFilterMiddleware(cfg IPFilterConfig) (*IPFilterMiddleware, error) {
+	m := &IPFilterMiddleware{}
+	if err := m.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IPFilter restricts routes to client IP ranges, answering 403 FORBIDDEN to other clients.
+// It panics on invalid IPs or CIDR ranges, use NewIPFilterMiddleware to reload the lists at runtime
+func IPFilter(cfg IPFilterConfig) echo.MiddlewareFunc {
+	m, err := NewIPFilterMiddleware(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return m.Middleware()
+}
+
+// SetConfig replaces the lists, safe while requests are served. Invalid entries keep the current lists
+func (m *IPFilterMiddleware) SetConfig(cfg IPFilterConfig) error {
+	allow, err := parsePrefixList(cfg.Allow)
+	if err != nil {
+		return fmt.Errorf("ip filter allow list: %w", err)
+	}
+	deny, err := parsePrefixList(cfg.Deny)
+	if err != nil {
+		return fmt.Errorf("ip filter deny list: %w", err)
+	}
+	trustedProxies, err := parsePrefixList(cfg.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("ip filter trusted proxies: %w", err)
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+
+	m.rules.Store(&ipFilterRules{
+		allow:          allow,
+		deny:           deny,
+		trustedProxies: trustedProxies,
+		logger:         logger,
+	})
+	return nil
+}
+
+// Middleware returns the Echo middleware
+func (m *IPFilterMiddleware) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			rules := m.rules.Load()
+			clientIP, ok := rules.clientIP(c.Request())
+			if ok && rules.allowed(clientIP) {
+				return next(c)
+			}
+
+			rules.logger.WithFields(logrus.Fields{
+				"ip":   clientIP.String(),
+				"path": c.Request().URL.Path,
+			}).Warn("ip filter: request rejected")
+
+			ctx := common.LocaleContext(c)
+			errorResp := common.CreateErrorResponse(common.FORBIDDEN, common.TWithContextAndFallback(ctx, common.MsgErrorIPForbidden, "Access denied from this IP address"))
+			errorResp.ErrorCode = ErrorCodeIPForbidden
+			errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+			return c.JSON(http.StatusForbidden, errorResp)
+		}
+	}
+}
+
+// allowed reports whether ip is not denied and, with an allow list, allowed
+func (r *ipFilterRules) allowed(ip netip.Addr) bool {
+	if containsAddr(r.deny, ip) {
+		return false
+	}
+	return len(r.allow) == 0 || containsAddr(r.allow, ip)
+}
+
+// clientIP returns the client address of req, see resolveClientIP
+func (r *ipFilterRules) clientIP(req *http.Request) (netip.Addr, bool) {
+	return resolveClientIP(req, r.trustedProxies)
+}
+
+// trustedClientIP returns the client address of req as resolved by IPFilter, or the peer host when the
+// forwarded headers of a trusted proxy are malformed. The X-Forwarded-For and X-Real-IP headers of other
+// peers are ignored, unlike echo.Context.RealIP without an IPExtractor
+func trustedClientIP(req *http.Request, trustedProxies []netip.Prefix) string {
+	if addr, ok := resolveClientIP(req, trustedProxies); ok {
+		return addr.String()
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// resolveClientIP returns the peer address, or the address forwarded by a trusted proxy peer. X-Forwarded-For
+// is read from the right, skipping the trusted proxies, so entries prepended by the client are ignored
+func resolveClientIP(req *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	peer = peer.Unmap()
+	if !containsAddr(trustedProxies, peer) {
+		return peer, true
+	}
+
+	if forwarded := req.Header.Values(echo.HeaderXForwardedFor); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return netip.Addr{}, false
+			}
+			hop = hop.Unmap()
+			if !containsAddr(trustedProxies, hop) {
+				return hop, true
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(req.Header.Get(echo.HeaderXRealIP)); realIP != "" {
+		addr, err := netip.ParseAddr(realIP)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		return addr.Unmap(), true
+	}
+	return peer, true
+}
+
+// parsePrefixList parses IPs and CIDR ranges, failing on the first invalid entry
+func parsePrefixList(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// parsePrefixes parses IPs and CIDR ranges, logging and skipping invalid entries
+func parsePrefixes(values []string, logger *logrus.Logger) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			logger.WithError(err).Warnf("invalid IP or CIDR range %q ignored", value)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// parsePrefix parses a CIDR range, or an IP as a single address range
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// matchIP reports whether ip is inside one of prefixes
+func matchIP(ip string, prefixes []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return containsAddr(prefixes, addr.Unmap())
+}
+
+// containsAddr reports whether addr is inside one of prefixes
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
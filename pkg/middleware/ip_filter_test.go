@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// ipFilterServer serves GET /admin behind m
+func ipFilterServer(m *IPFilterMiddleware) *echo.Echo {
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/admin", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	return e
+}
+
+// getFrom requests /admin from the peer remoteAddr with the given headers
+func getFrom(e *echo.Echo, remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIPFilter(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	m, err := NewIPFilterMiddleware(IPFilterConfig{
+		Allow:          []string{"10.0.0.0/8", "2001:db8::/32", "198.51.100.7"},
+		Deny:           []string{"10.6.6.0/24"},
+		TrustedProxies: []string{"192.0.2.1", "192.0.2.2"},
+		Logger:         logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := ipFilterServer(m)
+
+	tests := []struct {
+		name   string
+		peer   string
+		header map[string]string
+		want   int
+	}{
+		{"allowed ipv4", "10.1.2.3:1234", nil, http.StatusOK},
+		{"allowed single ip", "198.51.100.7:1234", nil, http.StatusOK},
+		{"ipv4 mapped ipv6", "[::ffff:10.1.2.3]:1234", nil, http.StatusOK},
+		{"allowed ipv6", "[2001:db8::1]:1234", nil, http.StatusOK},
+		{"not allowed ipv6", "[2001:db9::1]:1234", nil, http.StatusForbidden},
+		{"denied inside allowed range", "10.6.6.6:1234", nil, http.StatusForbidden},
+		{"not allowed", "203.0.113.7:1234", nil, http.StatusForbidden},
+		{"forwarded by trusted proxy", "192.0.2.1:1234", map[string]string{echo.HeaderXForwardedFor: "10.1.2.3"}, http.StatusOK},
+		{"forwarded through trusted proxies", "192.0.2.1:1234", map[string]string{echo.HeaderXForwardedFor: "10.1.2.3, 192.0.2.2"}, http.StatusOK},
+		{"spoofed entry prepended by the client", "192.0.2.1:1234", map[string]string{echo.HeaderXForwardedFor: "10.1.2.3, 203.0.113.7"}, http.StatusForbidden},
+		{"forwarded by untrusted peer", "203.0.113.7:1234", map[string]string{echo.HeaderXForwardedFor: "10.1.2.3"}, http.StatusForbidden},
+		{"real ip by trusted proxy", "192.0.2.1:1234", map[string]string{echo.HeaderXRealIP: "2001:db8::5"}, http.StatusOK},
+		{"real ip by untrusted peer", "203.0.113.7:1234", map[string]string{echo.HeaderXRealIP: "10.1.2.3"}, http.StatusForbidden},
+		{"invalid forwarded header", "192.0.2.1:1234", map[string]string{echo.HeaderXForwardedFor: "not-an-ip"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getFrom(e, tt.peer, tt.header)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusForbidden && errorCode(t, rec) != ErrorCodeIPForbidden {
+				t.Errorf("body = %s, want the %s error code", rec.Body.String(), ErrorCodeIPForbidden)
+			}
+		})
+	}
+}
+
+func TestIPFilterInvalidConfig(t *testing.T) {
+	if _, err := NewIPFilterMiddleware(IPFilterConfig{Allow: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("invalid CIDR range accepted")
+	}
+
+	m, err := NewIPFilterMiddleware(IPFilterConfig{Allow: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetConfig(IPFilterConfig{Allow: []string{"nope"}}); err == nil {
+		t.Error("invalid reload accepted")
+	}
+	if rec := getFrom(ipFilterServer(m), "10.1.2.3:1234", nil); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the previous lists kept after an invalid reload", rec.Code)
+	}
+}
+
+func TestIPFilterReload(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	m, err := NewIPFilterMiddleware(IPFilterConfig{Allow: []string{"10.0.0.0/8"}, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := ipFilterServer(m)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				getFrom(e, "10.1.2.3:1234", nil)
+			}
+		}()
+	}
+	for range 50 {
+		if err := m.SetConfig(IPFilterConfig{Allow: []string{"10.0.0.0/8", "203.0.113.0/24"}, Logger: logger}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	if rec := getFrom(e, "203.0.113.7:1234", nil); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the reloaded allow list", rec.Code)
+	}
+}
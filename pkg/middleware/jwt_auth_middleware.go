@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"slices"
 	"strings"
@@ -16,220 +17,305 @@ import (
 // JWTAuthMiddleware handles JWT authentication for API calls
 type JWTAuthMiddleware struct {
 	logger     *logrus.Logger
-	jwtService services.JWTService
+	jwtService services.TokenValidator
+	cookieName string
+	queryParam string
+	// contextClaims are the custom claims copied into the request context
+	contextClaims []string
 }
 
+// JWTAuthOption configures NewJWTAuthMiddlewareWithOptions
+type JWTAuthOption func(*JWTAuthMiddleware)
+
+// WithTokenCookie reads the token from the named cookie when the Authorization header is absent
+func WithTokenCookie(name string) JWTAuthOption {
+	return func(m *JWTAuthMiddleware) {
+		m.cookieName = name
+	}
+}
+
+// ErrorCodeTokenRevoked is the error code of responses rejecting a blacklisted token
+const ErrorCodeTokenRevoked = "token_revoked"
+
+// ErrorCodeRevocationCheckFailed is the error code of responses rejecting a token whose blacklist
+// entry could not be checked
+const ErrorCodeRevocationCheckFailed = "revocation_check_failed"
+
 // NewJWTAuthMiddleware creates a new JWT auth middleware
 func NewJWTAuthMiddleware(secretKey string, redisClient redis.RedisClient, logger *logrus.Logger) *JWTAuthMiddleware {
-	return &JWTAuthMiddleware{
+	return NewJWTAuthMiddlewareWithService(services.NewJWTService(secretKey, redisClient), logger)
+}
+
+// WithContextClaims copies the custom claims keys into the request context, read with common.Claim.
+// The tenant id claim is always copied (common.TenantID)
+func WithContextClaims(keys ...string) JWTAuthOption {
+	return func(m *JWTAuthMiddleware) {
+		m.contextClaims = append(m.contextClaims, keys...)
+	}
+}
+
+// NewJWTAuthMiddlewareWithService creates a JWT auth middleware using jwtService, so the token
+// blacklist it enforces is shared with the service revoking tokens (e.g. on logout)
+func NewJWTAuthMiddlewareWithService(jwtService services.JWTService, logger *logrus.Logger) *JWTAuthMiddleware {
+	return NewJWTAuthMiddlewareWithOptions(jwtService, logger)
+}
+
+// NewJWTAuthMiddlewareWithOptions creates a JWT auth middleware validating tokens with jwtService, a
+// JWTService or a JWKS validator (services.NewJWTValidatorFromJWKS), with additional token sources
+// tried after the Authorization header
+func NewJWTAuthMiddlewareWithOptions(jwtService services.TokenValidator, logger *logrus.Logger, opts ...JWTAuthOption) *JWTAuthMiddleware {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	m := &JWTAuthMiddleware{
 		logger:     logger,
-		jwtService: services.NewJWTService(secretKey, redisClient),
+		jwtService: jwtService,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
-// RequireAuth middleware that validates JWT tokens
-func (m *JWTAuthMiddleware) RequireAuth() echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// Get Authorization header
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error":             "missing_authorization_header",
-					"error_description": "Authorization header is required",
-				})
-			}
+// WithQueryToken returns a copy of the middleware also reading the token from the named query parameter,
+// tried last. Tokens in URLs leak through logs and referrers, so enable it only on the route groups that
+// need it (SSE streams, download links)
+func (m *JWTAuthMiddleware) WithQueryToken(param string) *JWTAuthMiddleware {
+	clone := *m
+	clone.queryParam = param
+	return &clone
+}
 
-			// Check if it's a Bearer token
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error":             "invalid_authorization_header",
-					"error_description": "Authorization header must start with 'Bearer '",
-				})
-			}
+// authError is a rejected authentication, written by the middlewares as JSON
+type authError struct {
+	status int
+	body   any
+}
 
-			// Extract token
-			token := strings.TrimPrefix(authHeader, "Bearer ")
-			if token == "" {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error":             "missing_token",
-					"error_description": "Token is required",
-				})
-			}
+// unauthorized builds an authError with the legacy OAuth style body
+func unauthorized(code, description string) *authError {
+	return &authError{
+		status: http.StatusUnauthorized,
+		body: map[string]string{
+			"error":             code,
+			"error_description": description,
+		},
+	}
+}
 
-			// Validate token
-			claims, err := m.jwtService.ValidateToken(token)
-			if err != nil {
-				m.logger.Warn("Invalid JWT token: ", err)
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error":             "invalid_token",
-					"error_description": err.Error(),
-				})
-			}
+// tokenRevoked builds the authError of a revoked token
+func tokenRevoked(c echo.Context) *authError {
+	ctx := common.LocaleContext(c)
+	errorResp := common.UnauthorizedError(common.TWithContextAndFallback(ctx, common.MsgErrorTokenRevoked, "Token has been revoked"))
+	errorResp.ErrorCode = ErrorCodeTokenRevoked
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return &authError{status: http.StatusUnauthorized, body: errorResp}
+}
+
+// revocationCheckFailed builds the authError of a token whose blacklist entry could not be checked,
+// 503 so clients retry instead of discarding a token that may be valid
+func revocationCheckFailed(c echo.Context) *authError {
+	ctx := common.LocaleContext(c)
+	errorResp := common.CreateErrorResponse(common.SERVICE_UNAVAILABLE,
+		common.TWithContextAndFallback(ctx, common.MsgErrorServiceUnavailable, "Service unavailable"))
+	errorResp.ErrorCode = ErrorCodeRevocationCheckFailed
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return &authError{status: http.StatusServiceUnavailable, body: errorResp}
+}
+
+// extractToken reads the token from the Authorization header, then the cookie and the query parameter
+// when configured
+func (m *JWTAuthMiddleware) extractToken(c echo.Context) (string, *authError) {
+	// Get Authorization header
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader != "" {
+		// Check if it's a Bearer token
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return "", unauthorized("invalid_authorization_header", "Authorization header must start with 'Bearer '")
+		}
+
+		// Extract token
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if token == "" {
+			return "", unauthorized("missing_token", "Token is required")
+		}
+		return token, nil
+	}
+
+	if m.cookieName != "" {
+		if cookie, err := c.Cookie(m.cookieName); err == nil && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+
+	if m.queryParam != "" {
+		if token := c.QueryParam(m.queryParam); token != "" {
+			m.logger.WithField("path", c.Request().URL.Path).Warn("JWT token read from query parameter")
+			return token, nil
+		}
+	}
+
+	return "", unauthorized("missing_authorization_header", "Authorization header is required")
+}
+
+// authenticate validates the token of the request, rejects blacklisted tokens and puts the
+// principal into both Echo context and request context (typed key)
+func (m *JWTAuthMiddleware) authenticate(c echo.Context) (*models.JWTClaims, *authError) {
+	token, authErr := m.extractToken(c)
+	if authErr != nil {
+		return nil, authErr
+	}
+
+	// Validate token
+	claims, err := m.jwtService.ValidateToken(token)
+	if errors.Is(err, services.ErrTokenRevoked) {
+		return nil, tokenRevoked(c)
+	}
+	if err != nil {
+		m.logger.Warn("Invalid JWT token: ", err)
+		return nil, unauthorized("invalid_token", err.Error())
+	}
 
-			// Put principal into both Echo context and request context (typed key)
-			c.Set("user", &claims.User)
-			c.Set("scopes", claims.Scopes)
-			c.Set("claims", claims)
-			c.Set("user_id", claims.User.ID)
-			c.Set("sid", claims.SID)
+	// Reject revoked tokens, failing closed when the blacklist cannot be checked
+	revoked, err := m.jwtService.IsTokenBlacklisted(c.Request().Context(), token)
+	if err != nil {
+		m.logger.WithError(err).Error("JWT blacklist check failed")
+		return nil, revocationCheckFailed(c)
+	}
+	if revoked {
+		return nil, tokenRevoked(c)
+	}
 
-			req := c.Request()
-			goCtx := common.WithUserID(req.Context(), claims.User.ID)
-			goCtx = common.WithSID(goCtx, claims.SID)
-			c.SetRequest(req.WithContext(goCtx))
+	roles := claims.AllRoles()
+	c.Set("user", &claims.User)
+	c.Set("scopes", claims.Scopes)
+	c.Set("roles", roles)
+	c.Set("claims", claims)
+	c.Set("user_id", claims.User.ID)
+	c.Set("sid", claims.SID)
+
+	req := c.Request()
+	goCtx := common.WithUserID(req.Context(), claims.User.ID)
+	goCtx = common.WithSID(goCtx, claims.SID)
+	goCtx = common.WithRoles(goCtx, roles)
+	if tenantID, ok := claims.GetStringClaim(models.ClaimTenantID); ok {
+		c.Set("tenant_id", tenantID)
+		goCtx = common.WithTenantID(goCtx, tenantID)
+	}
+	for _, key := range m.contextClaims {
+		if value, ok := claims.GetClaim(key); ok {
+			goCtx = common.WithClaim(goCtx, key, value)
+		}
+	}
+	c.SetRequest(req.WithContext(goCtx))
+
+	return claims, nil
+}
+
+// RequireAuth middleware that validates JWT tokens
+func (m *JWTAuthMiddleware) RequireAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, authErr := m.authenticate(c); authErr != nil {
+				return c.JSON(authErr.status, authErr.body)
+			}
 
 			return next(c)
 		}
 	}
 }
 
-// RequireScope middleware that checks if user has required scope
+// ErrorCodeInsufficientScope is the error code of responses rejecting a token without the required scopes
+const ErrorCodeInsufficientScope = "insufficient_scope"
+
+// RequireScope middleware that checks if user has required scope, wildcards like "orders:*" are honored
 func (m *JWTAuthMiddleware) RequireScope(requiredScope string) echo.MiddlewareFunc {
+	return m.RequireAnyScope(requiredScope)
+}
+
+// RequireAnyScope middleware that checks if user has at least one of the scopes
+func (m *JWTAuthMiddleware) RequireAnyScope(scopes ...string) echo.MiddlewareFunc {
+	return m.requireScopes(scopes, false)
+}
+
+// RequireAllScopes middleware that checks if user has every one of the scopes
+func (m *JWTAuthMiddleware) RequireAllScopes(scopes ...string) echo.MiddlewareFunc {
+	return m.requireScopes(scopes, true)
+}
+
+// requireScopes rejects with 403 insufficient_scope the requests whose token lacks the required scopes
+func (m *JWTAuthMiddleware) requireScopes(requiredScopes []string, all bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Prefer scopes already set by RequireAuth (if present)
 			scopes, ok := c.Get("scopes").([]string)
 			if !ok || len(scopes) == 0 {
-				// Otherwise, validate token directly to make RequireScope independent
-				authHeader := c.Request().Header.Get("Authorization")
-				if authHeader == "" {
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "missing_authorization_header",
-						"error_description": "Authorization header is required",
-					})
-				}
-
-				if !strings.HasPrefix(authHeader, "Bearer ") {
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "invalid_authorization_header",
-						"error_description": "Authorization header must start with 'Bearer '",
-					})
+				// Otherwise, validate token directly to make the scope check independent
+				claims, authErr := m.authenticate(c)
+				if authErr != nil {
+					return c.JSON(authErr.status, authErr.body)
 				}
-
-				token := strings.TrimPrefix(authHeader, "Bearer ")
-				if token == "" {
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "missing_token",
-						"error_description": "Token is required",
-					})
-				}
-
-				claims, err := m.jwtService.ValidateToken(token)
-				if err != nil {
-					m.logger.Warn("Invalid JWT token: ", err)
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "invalid_token",
-						"error_description": err.Error(),
-					})
-				}
-
 				scopes = claims.Scopes
-				c.Set("user", &claims.User)
-				c.Set("scopes", claims.Scopes)
-				c.Set("claims", claims)
-				c.Set("user_id", claims.User.ID)
-				c.Set("sid", claims.SID)
-
-				req := c.Request()
-				goCtx := common.WithUserID(req.Context(), claims.User.ID)
-				goCtx = common.WithSID(goCtx, claims.SID)
-				c.SetRequest(req.WithContext(goCtx))
 			}
 
-			// Check if required scope is present
-			hasScope := slices.Contains(scopes, requiredScope)
+			// Check if required scopes are present
+			missing := MissingScopes(scopes, requiredScopes)
+			if len(missing) == 0 || !all && len(missing) < len(requiredScopes) {
+				return next(c)
+			}
 
-			if !hasScope {
-				return c.JSON(http.StatusForbidden, map[string]string{
-					"error":             "insufficient_scope",
-					"error_description": "Insufficient scope. Required: " + requiredScope,
+			details := make([]common.ErrorDetail, 0, len(missing))
+			for _, scope := range missing {
+				details = append(details, common.ErrorDetail{
+					Field:   "scope",
+					Message: "Required scope: " + scope,
+					Value:   scope,
 				})
 			}
-
-			return next(c)
+			ctx := common.LocaleContext(c)
+			errorResp := common.CreateErrorResponse(common.FORBIDDEN,
+				common.TWithContextAndFallback(ctx, common.MsgErrorInsufficientScope, "Insufficient scope"), details...)
+			errorResp.ErrorCode = ErrorCodeInsufficientScope
+			errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+			return c.JSON(http.StatusForbidden, errorResp)
 		}
 	}
 }
 
-// RequireRole middleware that checks if user has required role
-func (m *JWTAuthMiddleware) RequireRole(requiredRole string) echo.MiddlewareFunc {
+// ErrorCodeInsufficientRole is the error code of responses rejecting a token without the required roles
+const ErrorCodeInsufficientRole = "insufficient_role"
+
+// RequireRole middleware that checks if user has at least one of the roles
+func (m *JWTAuthMiddleware) RequireRole(requiredRoles ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Prefer roles already set by RequireAuth / RequireScope (if present)
 			roles, ok := c.Get("roles").([]string)
-			if !ok || len(roles) == 0 {
-				// Fallback to roles from user in context
-				if u, ok := c.Get("user").(*models.OAuthUser); ok && u != nil && len(u.Roles) > 0 {
-					roles = u.Roles
-					c.Set("roles", roles)
-				}
-			}
-
-			if len(roles) == 0 {
+			if !ok {
 				// Otherwise, validate token directly to make RequireRole independent
-				authHeader := c.Request().Header.Get("Authorization")
-				if authHeader == "" {
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "missing_authorization_header",
-						"error_description": "Authorization header is required",
-					})
+				claims, authErr := m.authenticate(c)
+				if authErr != nil {
+					return c.JSON(authErr.status, authErr.body)
 				}
-
-				if !strings.HasPrefix(authHeader, "Bearer ") {
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "invalid_authorization_header",
-						"error_description": "Authorization header must start with 'Bearer '",
-					})
-				}
-
-				token := strings.TrimPrefix(authHeader, "Bearer ")
-				if token == "" {
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "missing_token",
-						"error_description": "Token is required",
-					})
-				}
-
-				claims, err := m.jwtService.ValidateToken(token)
-				if err != nil {
-					m.logger.Warn("Invalid JWT token: ", err)
-					return c.JSON(http.StatusUnauthorized, map[string]string{
-						"error":             "invalid_token",
-						"error_description": err.Error(),
-					})
-				}
-
-				roles = claims.User.Roles
-
-				c.Set("user", &claims.User)
-				c.Set("scopes", claims.Scopes)
-				c.Set("claims", claims)
-				c.Set("user_id", claims.User.ID)
-				c.Set("sid", claims.SID)
-				c.Set("roles", roles)
-
-				req := c.Request()
-				goCtx := common.WithUserID(req.Context(), claims.User.ID)
-				goCtx = common.WithSID(goCtx, claims.SID)
-				c.SetRequest(req.WithContext(goCtx))
+				roles = claims.AllRoles()
 			}
 
-			if len(roles) == 0 {
-				return c.JSON(http.StatusForbidden, map[string]string{
-					"error":             "missing_role",
-					"error_description": "No role found in token",
-				})
+			if len(requiredRoles) == 0 || slices.ContainsFunc(requiredRoles, func(role string) bool {
+				return slices.Contains(roles, role)
+			}) {
+				return next(c)
 			}
 
-			if !slices.Contains(roles, requiredRole) {
-				return c.JSON(http.StatusForbidden, map[string]string{
-					"error":             "insufficient_role",
-					"error_description": "Insufficient role. Required: " + requiredRole,
+			ctx := common.LocaleContext(c)
+			errorResp := common.CreateErrorResponse(common.FORBIDDEN,
+				common.TWithContextAndFallback(ctx, common.MsgErrorInsufficientRole, "Insufficient role"),
+				common.ErrorDetail{
+					Field:   "role",
+					Message: "Required role: " + strings.Join(requiredRoles, " or "),
 				})
-			}
-
-			return next(c)
+			errorResp.ErrorCode = ErrorCodeInsufficientRole
+			errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+			return c.JSON(http.StatusForbidden, errorResp)
 		}
 	}
 }
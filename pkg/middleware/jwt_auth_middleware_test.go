@@ -0,0 +1,336 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+	services "github.com/thanhthanh221/msa-core/pkg/service"
+)
+
+// failingBlacklistStore is a blacklist store whose lookups fail
+type failingBlacklistStore struct{}
+
+func (failingBlacklistStore) Add(context.Context, string, time.Duration) error { return nil }
+
+func (failingBlacklistStore) Exists(context.Context, string) (bool, error) {
+	return false, redistest.ErrDown
+}
+
+// testClock is a settable clock for the stores under test
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newAuthServer returns a server requiring authentication with jwtService
+func newAuthServer(jwtService services.JWTService) *echo.Echo {
+	logger, _ := test.NewNullLogger()
+	e := echo.New()
+	e.Use(NewJWTAuthMiddlewareWithService(jwtService, logger).RequireAuth())
+	e.GET("/me", func(c echo.Context) error {
+		userID, _ := common.UserID(c.Request().Context())
+		return c.String(http.StatusOK, userID)
+	})
+	return e
+}
+
+// issueToken returns an access token of user u1
+func issueToken(t *testing.T, jwtService services.JWTService) string {
+	t.Helper()
+	token, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1", Email: "u1@example.com"}, []string{"profile"}, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// getWithToken serves GET /me with token as bearer token
+func getWithToken(e *echo.Echo, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// errorCode decodes the error code of an error response
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return body.ErrorCode
+}
+
+// oauthErrorCode decodes the error of a legacy OAuth style error body
+func oauthErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return body.Error
+}
+
+func TestRequireAuthBlacklist(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	jwtService := services.NewJWTService("test-secret", nil, services.WithTokenBlacklistStore(
+		services.NewMemoryTokenBlacklistStore(services.WithBlacklistClock(clock.Now)),
+	))
+	e := newAuthServer(jwtService)
+	token := issueToken(t, jwtService)
+
+	rec := getWithToken(e, token)
+	if rec.Code != http.StatusOK || rec.Body.String() != "u1" {
+		t.Fatalf("clean token status = %d, body = %q, want the user authenticated", rec.Code, rec.Body.String())
+	}
+
+	if err := jwtService.BlacklistToken(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	rec = getWithToken(e, token)
+	if rec.Code != http.StatusUnauthorized || errorCode(t, rec) != ErrorCodeTokenRevoked {
+		t.Errorf("revoked token status = %d, body = %s, want 401 %s", rec.Code, rec.Body.String(), ErrorCodeTokenRevoked)
+	}
+
+	// The entry outlives the token by construction, an expired entry no longer rejects
+	clock.Advance(2 * time.Hour)
+	if rec := getWithToken(e, token); rec.Code != http.StatusOK {
+		t.Errorf("token with expired blacklist entry status = %d, want the entry dropped", rec.Code)
+	}
+}
+
+func TestRequireAuthBlacklistUnavailable(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil, services.WithTokenBlacklistStore(failingBlacklistStore{}))
+	e := newAuthServer(jwtService)
+
+	rec := getWithToken(e, issueToken(t, jwtService))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if code := errorCode(t, rec); code != ErrorCodeRevocationCheckFailed {
+		t.Errorf("error code = %q, want %q", code, ErrorCodeRevocationCheckFailed)
+	}
+}
+
+func TestRequireAuthRejectsInvalidTokens(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil)
+	e := newAuthServer(jwtService)
+
+	forged := issueToken(t, services.NewJWTService("other-secret", nil))
+	if rec := getWithToken(e, forged); rec.Code != http.StatusUnauthorized {
+		t.Errorf("forged token status = %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing token status = %d, want 401", rec.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil)
+	logger, _ := test.NewNullLogger()
+	auth := NewJWTAuthMiddlewareWithService(jwtService, logger)
+
+	e := echo.New()
+	e.GET("/admin", func(c echo.Context) error {
+		if !common.HasRole(c.Request().Context(), "admin") {
+			t.Error("role missing from the request context")
+		}
+		return c.NoContent(http.StatusOK)
+	}, auth.RequireAuth(), auth.RequireRole("admin", "manager"))
+	e.GET("/standalone", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, auth.RequireRole("admin"))
+
+	issue := func(roles []string, userRoles []string) string {
+		token, err := jwtService.GenerateTokenWithRoles(models.OAuthUser{ID: "u1", Roles: userRoles}, nil, roles, "test", "sid-1", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("/admin", issue([]string{"admin"}, nil)); rec.Code != http.StatusOK {
+		t.Errorf("token with the role status = %d, want 200", rec.Code)
+	}
+	if rec := serve("/standalone", issue([]string{"admin"}, nil)); rec.Code != http.StatusOK {
+		t.Errorf("RequireRole without RequireAuth status = %d, want 200", rec.Code)
+	}
+	// Older tokens carry the roles in the user claim only
+	if rec := serve("/admin", issue(nil, []string{"admin"})); rec.Code != http.StatusOK {
+		t.Errorf("legacy token status = %d, want the user roles honored", rec.Code)
+	}
+
+	rec := serve("/admin", issue([]string{"viewer"}, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("token without the role status = %d, want 403", rec.Code)
+	}
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != common.FORBIDDEN || body.ErrorCode != ErrorCodeInsufficientRole || body.Message == "" {
+		t.Errorf("body = %+v, want a translated FORBIDDEN %s", body, ErrorCodeInsufficientRole)
+	}
+}
+
+func TestValidateTokenWithoutRolesClaim(t *testing.T) {
+	claims := jwt.MapClaims{
+		"user": map[string]any{"id": "u1", "email": "u1@example.com"},
+		"sub":  "u1",
+		"iss":  "legacy",
+		"iat":  time.Now().Unix(),
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := services.NewJWTService("test-secret", nil).ValidateToken(legacy)
+	if err != nil {
+		t.Fatalf("ValidateToken of a token without roles: %v", err)
+	}
+	if roles := parsed.AllRoles(); len(roles) != 0 {
+		t.Errorf("roles = %v, want none", roles)
+	}
+}
+
+func TestTokenSources(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil)
+	token := issueToken(t, jwtService)
+	logger, hook := test.NewNullLogger()
+	auth := NewJWTAuthMiddlewareWithOptions(jwtService, logger, WithTokenCookie("session"))
+
+	e := echo.New()
+	me := func(c echo.Context) error {
+		userID, _ := common.UserID(c.Request().Context())
+		return c.String(http.StatusOK, userID)
+	}
+	e.GET("/me", me, auth.RequireAuth())
+	e.GET("/stream", me, auth.WithQueryToken("access_token").RequireAuth())
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		cookie   string
+		wantCode string
+	}{
+		{"header", "/me", "Bearer " + token, "", ""},
+		{"cookie", "/me", "", token, ""},
+		{"header wins over cookie", "/me", "Bearer " + token, "garbage", ""},
+		{"invalid header not replaced by cookie", "/me", "Basic abc", token, "invalid_authorization_header"},
+		{"query not enabled", "/me?access_token=" + token, "", "", "missing_authorization_header"},
+		{"query enabled", "/stream?access_token=" + token, "", "", ""},
+		{"cookie wins over query", "/stream?access_token=garbage", "", token, ""},
+		{"none", "/stream", "", "", "missing_authorization_header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if tt.wantCode == "" {
+				if rec.Code != http.StatusOK || rec.Body.String() != "u1" {
+					t.Errorf("response = %d %q, want the principal u1", rec.Code, rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != http.StatusUnauthorized || oauthErrorCode(t, rec) != tt.wantCode {
+				t.Errorf("response = %d %s, want 401 %s", rec.Code, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+
+	hook.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream?access_token="+token, nil))
+	if entry := hook.LastEntry(); entry == nil || entry.Message != "JWT token read from query parameter" {
+		t.Errorf("log entry = %+v, want a warning for the query token", entry)
+	}
+}
+
+func TestContextClaims(t *testing.T) {
+	jwtService := services.NewJWTService("test-secret", nil)
+	logger, _ := test.NewNullLogger()
+	auth := NewJWTAuthMiddlewareWithOptions(jwtService, logger, WithContextClaims("plan"))
+
+	e := echo.New()
+	e.GET("/me", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		tenantID, _ := common.TenantID(ctx)
+		plan, _ := common.Claim(ctx, "plan")
+		_, hasRegion := common.Claim(ctx, "region")
+		return c.JSON(http.StatusOK, map[string]any{"tenant": tenantID, "plan": plan, "region": hasRegion})
+	}, auth.RequireAuth())
+
+	token, err := jwtService.IssueToken(services.TokenRequest{
+		User:      models.OAuthUser{ID: "u1"},
+		Issuer:    "test",
+		Extra:     map[string]any{models.ClaimTenantID: "acme", "plan": "pro", "region": "eu"},
+		ExpiresIn: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := getWithToken(e, token)
+	var body struct {
+		Tenant string `json:"tenant"`
+		Plan   string `json:"plan"`
+		Region bool   `json:"region"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	if body.Tenant != "acme" || body.Plan != "pro" || body.Region {
+		t.Errorf("context claims = %+v, want tenant acme and plan pro only", body)
+	}
+
+	body.Tenant, body.Plan = "", ""
+	rec = getWithToken(e, issueToken(t, jwtService))
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Tenant != "" || body.Plan != "" {
+		t.Errorf("token without custom claims = %s, want none in the context", rec.Body.String())
+	}
+}
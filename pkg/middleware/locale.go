@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/common/format"
+)
+
+// HeaderContentLanguage is the response header carrying the language of the response
+const HeaderContentLanguage = "Content-Language"
+
+// LocaleOptions configures Locale
+type LocaleOptions struct {
+	// Resolver overrides the config of common.SetLocaleResolverConfig for these routes, empty fields keep their default
+	Resolver *common.LocaleResolverConfig
+	// SkipContentLanguage does not set the Content-Language response header
+	SkipContentLanguage bool
+}
+
+// Locale resolves the request locale once and stores it on the echo context (common.ContextKeyLocale)
+// and in the request context, so common.ResolveLocale, common.LocaleContext and the *WithContext
+// translations of handlers and error middlewares reuse it. It also sets the Content-Language header
+func Locale(opts LocaleOptions) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var locale string
+			if opts.Resolver != nil {
+				locale = common.ResolveLocaleWith(c, *opts.Resolver)
+			} else {
+				locale = common.ResolveLocale(c)
+			}
+
+			c.Set(common.ContextKeyLocale, locale)
+			c.SetRequest(c.Request().WithContext(common.SetLocaleInContext(c.Request().Context(), locale)))
+			if !opts.SkipContentLanguage {
+				c.Response().Header().Set(HeaderContentLanguage, format.LanguageTag(locale).String())
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+func TestLocalePrecedence(t *testing.T) {
+	useTestI18n(t)
+
+	tests := []struct {
+		name       string
+		opts       LocaleOptions
+		query      string
+		cookie     string
+		header     string
+		want       string
+		wantHeader string
+	}{
+		{"default", LocaleOptions{}, "", "", "", common.DefaultLocale, "vi"},
+		{"header", LocaleOptions{}, "", "", "en-US,vi;q=0.5", "en", "en"},
+		{"cookie over header", LocaleOptions{}, "", "vn", "en", "vn", "vi"},
+		{"query over cookie", LocaleOptions{}, "en", "vn", "vn", "en", "en"},
+		{"unsupported skipped", LocaleOptions{}, "fr", "", "en", "en", "en"},
+		{"resolver override", LocaleOptions{Resolver: &common.LocaleResolverConfig{Order: []common.LocaleSource{common.LocaleSourceHeader}}}, "en", "", "vn", "vn", "vi"},
+		{"skip content language", LocaleOptions{SkipContentLanguage: true}, "en", "", "", "en", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var echoLocale, ctxLocale string
+			e := echo.New()
+			e.Use(Locale(tt.opts))
+			e.GET("/", func(c echo.Context) error {
+				echoLocale, _ = c.Get(common.ContextKeyLocale).(string)
+				ctxLocale = common.GetLocaleFromContext(c.Request().Context())
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/?lang="+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if echoLocale != tt.want || ctxLocale != tt.want {
+				t.Errorf("echo locale = %q, context locale = %q, want %q", echoLocale, ctxLocale, tt.want)
+			}
+			if got := rec.Header().Get(HeaderContentLanguage); got != tt.wantHeader {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestLocaleResolvedOnce(t *testing.T) {
+	useTestI18n(t)
+
+	e := echo.New()
+	e.Use(Locale(LocaleOptions{}), ErrorHandlerMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		// Later changes to the request do not change the locale resolved by the middleware
+		c.Request().Header.Set("Accept-Language", "vn")
+		if got := common.ResolveLocale(c); got != "en" {
+			t.Errorf("ResolveLocale in handler = %q, want the resolved en", got)
+		}
+		return common.ErrNotFoundResponse
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !strings.Contains(body, "Not found") {
+		t.Errorf("body = %s, want the error translated in the resolved locale", body)
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/authguard"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// ErrorCodeLoginLocked is the error code of login attempts rejected by LoginThrottle
+const ErrorCodeLoginLocked = "login_locked"
+
+// LoginThrottleConfig configures LoginThrottle
+type LoginThrottleConfig struct {
+	// Account returns the account of the login attempt, by default the first of AccountFields found
+	// in the JSON or form body
+	Account func(c echo.Context) string
+	// AccountFields are the body fields read by the default Account, "email" and "username" by default
+	AccountFields []string
+	// IsFailure reports whether the login failed from its response status, 401 by default
+	IsFailure func(status int) bool
+	// TrustedProxies are the direct peers whose X-Forwarded-For / X-Real-IP headers give the client IP,
+	// as IPFilterConfig.TrustedProxies. Without them the client IP is the peer address
+	TrustedProxies []string
+	// FailClosed rejects logins with 503 when Redis is unavailable, by default they are allowed
+	FailClosed bool
+	// Logger logs Redis failures, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// LoginThrottle protects login routes against credential stuffing: locked account and IP pairs are
+// answered 429 TOO_MANY_REQUESTS with Retry-After, failed logins are recorded in guard and a successful
+// login resets the pair
+func LoginThrottle(guard *authguard.Guard, cfg LoginThrottleConfig) echo.MiddlewareFunc {
+	if len(cfg.AccountFields) == 0 {
+		cfg.AccountFields = []string{"email", "username"}
+	}
+	if cfg.Account == nil {
+		cfg.Account = func(c echo.Context) string {
+			return accountFromBody(c, cfg.AccountFields)
+		}
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(status int) bool {
+			return status == http.StatusUnauthorized
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	trustedProxies := parsePrefixes(cfg.TrustedProxies, cfg.Logger)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			id := authguard.Identifier{Account: cfg.Account(c), IP: trustedClientIP(c.Request(), trustedProxies)}
+
+			allowed, retryAfter, err := guard.CheckLoginAllowed(ctx, id)
+			if err != nil {
+				cfg.Logger.WithError(err).Warn("login throttle: redis unavailable")
+				if cfg.FailClosed {
+					return rateLimitError(c, http.StatusServiceUnavailable, common.SERVICE_UNAVAILABLE, common.MsgErrorServiceUnavailable)
+				}
+			}
+			if !allowed {
+				seconds := int64(retryAfter.Seconds() + 0.5)
+				c.Response().Header().Set("Retry-After", strconv.FormatInt(max(seconds, 1), 10))
+				localeCtx := common.LocaleContext(c)
+				errorResp := common.CreateErrorResponse(common.TOO_MANY_REQUESTS,
+					common.TWithContextAndFallback(localeCtx, common.MsgErrorLoginLocked, "Too many failed login attempts"))
+				errorResp.ErrorCode = ErrorCodeLoginLocked
+				errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+				return c.JSON(http.StatusTooManyRequests, errorResp)
+			}
+
+			err = next(c)
+			status := responseStatus(c, err)
+			switch {
+			case cfg.IsFailure(status):
+				if recordErr := guard.RecordFailedLogin(ctx, id); recordErr != nil {
+					cfg.Logger.WithError(recordErr).Warn("login throttle: failed to record failed login")
+				}
+			case status >= 200 && status < 300:
+				if resetErr := guard.ResetOnSuccess(ctx, id); resetErr != nil {
+					cfg.Logger.WithError(resetErr).Warn("login throttle: failed to reset login failures")
+				}
+			}
+			return err
+		}
+	}
+}
+
+// accountFromBody returns the first non-empty field of a JSON or URL-encoded form body, restoring the body
+func accountFromBody(c echo.Context, fields []string) string {
+	req := c.Request()
+	body, err := readSignedBody(req, 64<<10)
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+
+	contentType := req.Header.Get(echo.HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+		var values map[string]any
+		if json.Unmarshal(body, &values) != nil {
+			return ""
+		}
+		for _, field := range fields {
+			if value, ok := values[field].(string); ok && value != "" {
+				return value
+			}
+		}
+	case strings.HasPrefix(contentType, echo.MIMEApplicationForm):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ""
+		}
+		for _, field := range fields {
+			if value := values.Get(field); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/authguard"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+)
+
+// newLoginServer returns a login route throttled by LoginThrottle, accepting the password "right"
+func newLoginServer(rc *redistest.Client, cfg LoginThrottleConfig) *echo.Echo {
+	logger, _ := test.NewNullLogger()
+	cfg.Logger = logger
+	guard := authguard.New(rc, authguard.Config{MaxAttempts: 2, BaseLockout: time.Minute, Logger: logger, Now: rc.Now})
+
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		var body struct {
+			Password string `json:"password"`
+		}
+		if err := c.Bind(&body); err != nil || body.Password != "right" {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+		return c.NoContent(http.StatusOK)
+	}, LoginThrottle(guard, cfg))
+	return e
+}
+
+// login posts a JSON login of account with password from 10.0.0.1
+func login(e *echo.Echo, account, password string) *httptest.ResponseRecorder {
+	return loginFrom(e, `{"email":"`+account+`","password":"`+password+`"}`, "10.0.0.1:4000", "")
+}
+
+// loginFrom posts a JSON login body from remoteAddr with an optional X-Forwarded-For header
+func loginFrom(e *echo.Echo, body, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoginThrottle(t *testing.T) {
+	useTestI18n(t)
+	rc := redistest.New()
+	e := newLoginServer(rc, LoginThrottleConfig{})
+
+	for range 2 {
+		if rec := login(e, "alice@example.com", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failed login = %d, want 401", rec.Code)
+		}
+	}
+	rec := login(e, "alice@example.com", "right")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("locked login = %d Retry-After %q, want 429 after 60s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if code := errorCode(t, rec); code != ErrorCodeLoginLocked {
+		t.Errorf("error code = %q, want %s", code, ErrorCodeLoginLocked)
+	}
+	if rec := login(e, "bob@example.com", "right"); rec.Code != http.StatusOK {
+		t.Errorf("other account from the same IP = %d, want 200", rec.Code)
+	}
+
+	rc.Advance(time.Minute)
+	if rec := login(e, "alice@example.com", "right"); rec.Code != http.StatusOK {
+		t.Fatalf("login after the lockout = %d, want 200", rec.Code)
+	}
+	// The success reset the pair, a single failure does not lock it again
+	login(e, "alice@example.com", "wrong")
+	if rec := login(e, "alice@example.com", "right"); rec.Code != http.StatusOK {
+		t.Errorf("login after a reset and one failure = %d, want 200", rec.Code)
+	}
+}
+
+func TestLoginThrottleRedisDown(t *testing.T) {
+	useTestI18n(t)
+	rc := redistest.New()
+	rc.SetDown(true)
+
+	if rec := login(newLoginServer(rc, LoginThrottleConfig{}), "alice@example.com", "right"); rec.Code != http.StatusOK {
+		t.Errorf("fail open login = %d, want 200", rec.Code)
+	}
+	rec := login(newLoginServer(rc, LoginThrottleConfig{FailClosed: true}), "alice@example.com", "right")
+	var body common.ErrorResponse
+	if rec.Code != http.StatusServiceUnavailable || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body.Code != common.SERVICE_UNAVAILABLE {
+		t.Errorf("fail closed login = %d %s, want 503 SERVICE_UNAVAILABLE", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLoginThrottleClientIP(t *testing.T) {
+	useTestI18n(t)
+	const wrong, right = `{"email":"alice@example.com","password":"wrong"}`, `{"email":"alice@example.com","password":"right"}`
+
+	t.Run("spoofed X-Forwarded-For", func(t *testing.T) {
+		e := newLoginServer(redistest.New(), LoginThrottleConfig{})
+		// A direct client rotating X-Forwarded-For is still throttled by its peer address
+		loginFrom(e, wrong, "203.0.113.7:4000", "198.51.100.1")
+		loginFrom(e, wrong, "203.0.113.7:4000", "198.51.100.2")
+		if rec := loginFrom(e, right, "203.0.113.7:4000", "198.51.100.3"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("login with a new X-Forwarded-For = %d, want 429", rec.Code)
+		}
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		e := newLoginServer(redistest.New(), LoginThrottleConfig{TrustedProxies: []string{"10.0.0.0/8"}})
+		loginFrom(e, wrong, "10.0.0.1:4000", "198.51.100.1")
+		loginFrom(e, wrong, "10.0.0.2:4000", "198.51.100.1")
+		if rec := loginFrom(e, right, "10.0.0.1:4000", "198.51.100.1"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("locked client behind the proxy = %d, want 429", rec.Code)
+		}
+		if rec := loginFrom(e, right, "10.0.0.1:4000", "198.51.100.2"); rec.Code != http.StatusOK {
+			t.Errorf("other client behind the proxy = %d, want 200", rec.Code)
+		}
+	})
+}
+
+func TestLoginThrottleLargeBody(t *testing.T) {
+	useTestI18n(t)
+	e := newLoginServer(redistest.New(), LoginThrottleConfig{})
+
+	// The account is not read from a body over 64 KiB, but the handler still gets the whole body
+	body := `{"email":"alice@example.com","password":"right","padding":"` + strings.Repeat("x", 70<<10) + `"}`
+	if rec := loginFrom(e, body, "10.0.0.1:4000", ""); rec.Code != http.StatusOK {
+		t.Errorf("login with a large body = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// MaintenanceKey is the Redis key holding the maintenance state
+const MaintenanceKey = "maintenance"
+
+// ErrorCodeMaintenance is the error code of requests rejected during maintenance
+const ErrorCodeMaintenance = "maintenance"
+
+// MaintenanceState is the maintenance toggle stored in Redis by SetMaintenance
+type MaintenanceState struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Until   time.Time `json:"until,omitempty"`
+}
+
+// MaintenanceConfig configures Maintenance
+type MaintenanceConfig struct {
+	// CacheTTL is how long the state read from Redis is reused locally, 5 seconds by default
+	CacheTTL time.Duration
+	// AllowReads lets GET, HEAD and OPTIONS requests through, turning maintenance into read-only mode
+	AllowReads bool
+	// AllowPaths are always served, exact paths or prefixes ending with '*' (e.g. "/health", "/admin/*")
+	AllowPaths []string
+	// AllowIPs are always served, IPs or CIDR ranges (e.g. "10.0.0.0/8")
+	AllowIPs []string
+	// RetryAfter is sent as Retry-After when the state has no Until, 5 minutes by default
+	RetryAfter time.Duration
+	// Logger logs Redis failures, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// Maintenance answers 503 SERVICE_UNAVAILABLE with a Retry-After header while maintenance is enabled
+// with SetMaintenance. Redis failures are logged and requests are let through (fail open)
+func Maintenance(rc redis.RedisClient, cfg MaintenanceConfig) echo.MiddlewareFunc {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 5 * time.Second
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 5 * time.Minute
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	allowedIPs := parsePrefixes(cfg.AllowIPs, cfg.Logger)
+
+	var (
+		mu        sync.Mutex
+		state     MaintenanceState
+		fetchedAt time.Time
+	)
+	currentState := func(ctx context.Context) MaintenanceState {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(fetchedAt) < cfg.CacheTTL {
+			return state
+		}
+		fetched, err := GetMaintenance(ctx, rc)
+		if err != nil {
+			cfg.Logger.WithError(err).Warn("maintenance: redis unavailable, serving requests")
+		}
+		state, fetchedAt = fetched, time.Now()
+		return state
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if cfg.AllowReads && isReadMethod(req.Method) {
+				return next(c)
+			}
+
+			current := currentState(req.Context())
+			if !current.active(time.Now()) ||
+				matchPath(req.URL.Path, cfg.AllowPaths) ||
+				matchIP(c.RealIP(), allowedIPs) {
+				return next(c)
+			}
+
+			retryAfter := cfg.RetryAfter
+			if !current.Until.IsZero() {
+				retryAfter = time.Until(current.Until)
+			}
+			c.Response().Header().Set("Retry-After", strconv.FormatInt(max(int64(retryAfter.Seconds()+0.5), 1), 10))
+
+			message := current.Message
+			if message == "" {
+				message = common.TWithContextAndFallback(common.LocaleContext(c), common.MsgErrorMaintenance, "Service is under maintenance")
+			}
+			errorResp := common.CreateErrorResponse(common.SERVICE_UNAVAILABLE, message)
+			errorResp.ErrorCode = ErrorCodeMaintenance
+			errorResp.TraceID = common.TraceIDFromRequest(req)
+			return c.JSON(http.StatusServiceUnavailable, errorResp)
+		}
+	}
+}
+
+// SetMaintenance enables or disables maintenance. A non-zero until ends it automatically, message
+// overrides the translated default message
+func SetMaintenance(ctx context.Context, rc redis.RedisClient, enabled bool, message string, until time.Time) error {
+	if !enabled {
+		return rc.Del(ctx, MaintenanceKey)
+	}
+
+	var exp time.Duration
+	if !until.IsZero() {
+		if exp = time.Until(until); exp <= 0 {
+			return rc.Del(ctx, MaintenanceKey)
+		}
+	}
+	data, err := json.Marshal(MaintenanceState{Enabled: true, Message: message, Until: until})
+	if err != nil {
+		return err
+	}
+	return rc.Set(ctx, MaintenanceKey, string(data), exp)
+}
+
+// GetMaintenance returns the maintenance state, disabled when it was never set
+func GetMaintenance(ctx context.Context, rc redis.RedisClient) (MaintenanceState, error) {
+	var state MaintenanceState
+	data, err := rc.Get(ctx, MaintenanceKey)
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return state, nil
+		}
+		return state, err
+	}
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return MaintenanceState{}, err
+	}
+	return state, nil
+}
+
+// active reports whether maintenance is enabled and not over at now
+func (s MaintenanceState) active(now time.Time) bool {
+	return s.Enabled && (s.Until.IsZero() || now.Before(s.Until))
+}
+
+// isReadMethod reports whether method does not modify resources
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+)
+
+// maintenanceServer serves every method on /orders and /admin/jobs behind Maintenance. A CacheTTL of
+// a nanosecond reads the state from Redis on every request
+func maintenanceServer(rc *redistest.Client, cfg MaintenanceConfig) *echo.Echo {
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Nanosecond
+	}
+	cfg.Logger, _ = test.NewNullLogger()
+	e := echo.New()
+	e.Use(Maintenance(rc, cfg))
+	handler := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.Any("/orders", handler)
+	e.Any("/admin/jobs", handler)
+	return e
+}
+
+// serveMaintenance sends a request from remoteAddr and returns the recorder
+func serveMaintenance(e *echo.Echo, method, path, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if remoteAddr != "" {
+		req.RemoteAddr = remoteAddr
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMaintenanceDisabled(t *testing.T) {
+	e := maintenanceServer(redistest.New(), MaintenanceConfig{})
+
+	if rec := serveMaintenance(e, http.MethodPost, "/orders", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 without maintenance", rec.Code)
+	}
+}
+
+func TestMaintenanceEnabled(t *testing.T) {
+	rc := redistest.New()
+	e := maintenanceServer(rc, MaintenanceConfig{RetryAfter: 90 * time.Second})
+	if err := SetMaintenance(context.Background(), rc, true, "Database migration", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serveMaintenance(e, http.MethodPost, "/orders", "")
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != ErrorCodeMaintenance {
+		t.Fatalf("response = %d %s, want 503 %s", rec.Code, rec.Body.String(), ErrorCodeMaintenance)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Retry-After = %q, want the configured 90 seconds", got)
+	}
+	if body := decodeErrorResponse(t, rec); body.Message != "Database migration" || body.Code != common.SERVICE_UNAVAILABLE {
+		t.Errorf("body = %+v, want the message of the state", body)
+	}
+
+	if err := SetMaintenance(context.Background(), rc, false, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveMaintenance(e, http.MethodPost, "/orders", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d after disabling, want 200", rec.Code)
+	}
+}
+
+func TestMaintenanceUntil(t *testing.T) {
+	rc := redistest.New()
+	e := maintenanceServer(rc, MaintenanceConfig{})
+	if err := SetMaintenance(context.Background(), rc, true, "", time.Now().Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serveMaintenance(e, http.MethodPost, "/orders", "")
+	retryAfter, _ := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if rec.Code != http.StatusServiceUnavailable || retryAfter < 110 || retryAfter > 120 {
+		t.Errorf("response = %d, Retry-After %d, want 503 until the end of maintenance", rec.Code, retryAfter)
+	}
+	if err := SetMaintenance(context.Background(), rc, true, "", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveMaintenance(e, http.MethodPost, "/orders", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d with maintenance over, want 200", rec.Code)
+	}
+}
+
+func TestMaintenanceAllowList(t *testing.T) {
+	rc := redistest.New()
+	e := maintenanceServer(rc, MaintenanceConfig{
+		AllowReads: true,
+		AllowPaths: []string{"/admin/*"},
+		AllowIPs:   []string{"10.0.0.0/8"},
+	})
+	if err := SetMaintenance(context.Background(), rc, true, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		remoteAddr string
+		want       int
+	}{
+		{"write", http.MethodPost, "/orders", "203.0.113.7:1234", http.StatusServiceUnavailable},
+		{"read", http.MethodGet, "/orders", "203.0.113.7:1234", http.StatusOK},
+		{"allowed path", http.MethodPost, "/admin/jobs", "203.0.113.7:1234", http.StatusOK},
+		{"allowed ip", http.MethodPost, "/orders", "10.1.2.3:1234", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveMaintenance(e, tt.method, tt.path, tt.remoteAddr); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceCachesState(t *testing.T) {
+	rc := redistest.New()
+	e := maintenanceServer(rc, MaintenanceConfig{CacheTTL: time.Hour})
+
+	serveMaintenance(e, http.MethodPost, "/orders", "")
+	if err := SetMaintenance(context.Background(), rc, true, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveMaintenance(e, http.MethodPost, "/orders", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the cached disabled state", rec.Code)
+	}
+}
+
+func TestMaintenanceRedisUnavailable(t *testing.T) {
+	rc := redistest.New()
+	e := maintenanceServer(rc, MaintenanceConfig{})
+	if err := SetMaintenance(context.Background(), rc, true, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	rc.SetDown(true)
+
+	if rec := serveMaintenance(e, http.MethodPost, "/orders", ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want requests served while redis is down", rec.Code)
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// Rate limit response headers
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// RateLimitConfig configures RateLimit
+type RateLimitConfig struct {
+	// Limit is the number of requests allowed per Window for a key
+	Limit int64
+	// Window is the sliding window duration
+	Window time.Duration
+	// KeyPrefix prefixes the Redis keys, "ratelimit" by default
+	KeyPrefix string
+	// KeyExtractor returns the client key, RateLimitByIP by default. An empty key skips limiting
+	KeyExtractor func(c echo.Context) string
+	// TrustedProxies are the direct peers whose X-Forwarded-For / X-Real-IP headers give the client IP of
+	// RateLimitByIP, as IPFilterConfig.TrustedProxies. Without them the client IP is the peer address
+	TrustedProxies []string
+	// ExemptPaths are not limited, exact paths or prefixes ending with '*' (e.g. "/health", "/public/*")
+	ExemptPaths []string
+	// FailClosed rejects requests with 503 when Redis is unavailable, by default they are allowed
+	FailClosed bool
+	// Logger logs Redis failures, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// clientIPKey is the context key of the client IP resolved by RateLimit with its trusted proxies
+const clientIPKey = "rate_limit_client_ip"
+
+// RateLimitByIP uses the client IP as rate limit key. Forwarded headers are only honored from the
+// RateLimitConfig.TrustedProxies peers, so clients cannot pick their key with X-Forwarded-For
+func RateLimitByIP(c echo.Context) string {
+	ip, ok := c.Get(clientIPKey).(string)
+	if !ok {
+		ip = trustedClientIP(c.Request(), nil)
+	}
+	return "ip:" + ip
+}
+
+// RateLimitByUser uses the authenticated user id set by JWTAuthMiddleware, falling back to the client IP
+func RateLimitByUser(c echo.Context) string {
+	if userID, ok := c.Get("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByAPIKey uses the X-Api-Key header, falling back to the client IP
+func RateLimitByAPIKey(c echo.Context) string {
+	if apiKey := c.Request().Header.Get("X-Api-Key"); apiKey != "" {
+		return "apikey:" + apiKey
+	}
+	return RateLimitByIP(c)
+}
+
+// errSlidingWindowUnsupported is reported when the Redis client does not implement redis.SlidingWindowLimiter
+var errSlidingWindowUnsupported = errors.New("redis client does not support sliding windows")
+
+// RateLimit throttles clients with the Redis sliding window limiter, setting the X-RateLimit-* headers
+// and answering 429 TOO_MANY_REQUESTS when the limit is exceeded. rc must implement redis.SlidingWindowLimiter,
+// otherwise every request is handled as a Redis failure
+func RateLimit(rc redis.RedisClient, cfg RateLimitConfig) echo.MiddlewareFunc {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "ratelimit"
+	}
+	if cfg.KeyExtractor == nil {
+		cfg.KeyExtractor = RateLimitByIP
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	limiter, _ := rc.(redis.SlidingWindowLimiter)
+	trustedProxies := parsePrefixes(cfg.TrustedProxies, cfg.Logger)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cfg.Limit <= 0 || cfg.Window <= 0 || matchPath(c.Request().URL.Path, cfg.ExemptPaths) {
+				return next(c)
+			}
+
+			c.Set(clientIPKey, trustedClientIP(c.Request(), trustedProxies))
+			key := cfg.KeyExtractor(c)
+			if key == "" {
+				return next(c)
+			}
+
+			result, err := slidingWindowAllow(c, limiter, fmt.Sprintf("%s:%s", cfg.KeyPrefix, key), cfg)
+			if err != nil {
+				cfg.Logger.WithError(err).Warnf("rate limit: redis unavailable for key %s", key)
+				if cfg.FailClosed {
+					return rateLimitError(c, http.StatusServiceUnavailable, common.SERVICE_UNAVAILABLE, common.MsgErrorServiceUnavailable)
+				}
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.FormatInt(result.Limit, 10))
+			header.Set(HeaderRateLimitRemaining, strconv.FormatInt(result.Remaining, 10))
+			header.Set(HeaderRateLimitReset, strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+			if !result.Allowed {
+				retryAfter := int64(time.Until(result.ResetAt).Seconds() + 1)
+				header.Set("Retry-After", strconv.FormatInt(max(retryAfter, 1), 10))
+				return rateLimitError(c, http.StatusTooManyRequests, common.TOO_MANY_REQUESTS, common.MsgErrorTooManyRequests)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// slidingWindowAllow records the request in the window of key, failing when limiter is not available
+func slidingWindowAllow(c echo.Context, limiter redis.SlidingWindowLimiter, key string, cfg RateLimitConfig) (*redis.SlidingWindowResult, error) {
+	if limiter == nil {
+		return nil, errSlidingWindowUnsupported
+	}
+	return limiter.SlidingWindowAllow(c.Request().Context(), key, cfg.Limit, cfg.Window)
+}
+
+// rateLimitError writes an error response translated with the request locale
+func rateLimitError(c echo.Context, status int, code common.ResponseCode, messageKey string) error {
+	ctx := common.LocaleContext(c)
+	errorResp := common.CreateErrorResponse(code, common.TWithContext(ctx, messageKey))
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return c.JSON(status, errorResp)
+}
+
+// matchPath matches path against exact paths and prefixes ending with '*'
+func matchPath(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+			continue
+		}
+		if path == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+)
+
+// newRateLimitServer returns a server limited by RateLimit with rc
+func newRateLimitServer(rc redis.RedisClient, cfg RateLimitConfig) *echo.Echo {
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.New()
+		cfg.Logger.SetLevel(logrus.PanicLevel)
+	}
+	e := echo.New()
+	e.Use(RateLimit(rc, cfg))
+	handler := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.GET("/items", handler)
+	e.GET("/health", handler)
+	return e
+}
+
+// get serves a GET of path from remoteAddr
+func get(e *echo.Echo, path, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitAllowAndDeny(t *testing.T) {
+	fake := redistest.New()
+	fake.SetNow(time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC))
+	e := newRateLimitServer(redistest.SlidingWindow{Client: fake}, RateLimitConfig{Limit: 2, Window: time.Minute, ExemptPaths: []string{"/health"}})
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rec := get(e, "/items", "10.0.0.1:1234")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want allowed", i, rec.Code)
+		}
+		if rec.Header().Get(HeaderRateLimitLimit) != "2" || rec.Header().Get(HeaderRateLimitRemaining) != wantRemaining {
+			t.Errorf("request %d headers = %v", i, rec.Header())
+		}
+		if rec.Header().Get(HeaderRateLimitReset) != "1709632860" {
+			t.Errorf("reset = %q, want the expiry of the oldest request", rec.Header().Get(HeaderRateLimitReset))
+		}
+	}
+
+	rec := get(e, "/items", "10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != common.TOO_MANY_REQUESTS || body.Message == "" {
+		t.Errorf("body = %+v, want a translated TOO_MANY_REQUESTS", body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After missing")
+	}
+
+	if rec := get(e, "/items", "10.0.0.2:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("other client status = %d, want its own window", rec.Code)
+	}
+	if rec := get(e, "/health", "10.0.0.1:1234"); rec.Code != http.StatusNoContent || rec.Header().Get(HeaderRateLimitLimit) != "" {
+		t.Errorf("exempt path status = %d, headers = %v", rec.Code, rec.Header())
+	}
+
+	fake.Advance(time.Minute)
+	if rec := get(e, "/items", "10.0.0.1:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("status after the window = %d, want allowed", rec.Code)
+	}
+}
+
+func TestRateLimitRedisDown(t *testing.T) {
+	fake := redistest.New()
+	fake.SetDown(true)
+
+	if rec := get(newRateLimitServer(redistest.SlidingWindow{Client: fake}, RateLimitConfig{Limit: 1, Window: time.Minute}), "/items", "10.0.0.1:1"); rec.Code != http.StatusNoContent {
+		t.Errorf("fail open status = %d, want allowed", rec.Code)
+	}
+
+	rec := get(newRateLimitServer(redistest.SlidingWindow{Client: fake}, RateLimitConfig{Limit: 1, Window: time.Minute, FailClosed: true}), "/items", "10.0.0.1:1")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("fail closed status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRateLimitWithoutSlidingWindow(t *testing.T) {
+	fake := redistest.New()
+
+	if rec := get(newRateLimitServer(fake, RateLimitConfig{Limit: 1, Window: time.Minute}), "/items", "10.0.0.1:1"); rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want the unsupported client handled as an outage", rec.Code)
+	}
+	rec := get(newRateLimitServer(fake, RateLimitConfig{Limit: 1, Window: time.Minute, FailClosed: true}), "/items", "10.0.0.1:1")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("fail closed status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRateLimitKeyExtractors(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.9:80"
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	if got := RateLimitByUser(c); got != "ip:10.0.0.9" {
+		t.Errorf("RateLimitByUser without user = %q", got)
+	}
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+	if got := RateLimitByIP(c); got != "ip:10.0.0.9" {
+		t.Errorf("RateLimitByIP with a spoofed X-Forwarded-For = %q, want the peer address", got)
+	}
+	c.Set("user_id", "u1")
+	if got := RateLimitByUser(c); got != "user:u1" {
+		t.Errorf("RateLimitByUser = %q", got)
+	}
+	req.Header.Set("X-Api-Key", "k1")
+	if got := RateLimitByAPIKey(c); got != "apikey:k1" {
+		t.Errorf("RateLimitByAPIKey = %q", got)
+	}
+}
+
+func TestRateLimitTrustedProxies(t *testing.T) {
+	serve := func(e *echo.Echo, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A direct client cannot pick a new key with X-Forwarded-For
+	e := newRateLimitServer(redistest.SlidingWindow{Client: redistest.New()}, RateLimitConfig{Limit: 1, Window: time.Minute})
+	serve(e, "203.0.113.7:4000", "198.51.100.1")
+	if code := serve(e, "203.0.113.7:4000", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("request with a new X-Forwarded-For = %d, want 429", code)
+	}
+
+	// Behind a trusted proxy each forwarded client has its own key
+	e = newRateLimitServer(redistest.SlidingWindow{Client: redistest.New()}, RateLimitConfig{Limit: 1, Window: time.Minute, TrustedProxies: []string{"10.0.0.0/8"}})
+	serve(e, "10.0.0.1:4000", "198.51.100.1")
+	if code := serve(e, "10.0.0.2:4000", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("second request of the forwarded client = %d, want 429", code)
+	}
+	if code := serve(e, "10.0.0.1:4000", "198.51.100.2"); code != http.StatusNoContent {
+		t.Errorf("other forwarded client = %d, want 204", code)
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Recover catches panics of the next handlers and answers a standard INTERNAL_ERROR response.
+// The panic value and stack are logged with the trace id and recorded on the active span, but never
+// sent to the client. Register it after TracingMiddleware so the request span exists
+func Recover(logger *logrus.Logger) echo.MiddlewareFunc {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (returnErr error) {
+			start := time.Now()
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					// Let net/http abort the connection as intended
+					panic(recovered)
+				}
+
+				err, ok := recovered.(error)
+				if !ok {
+					err = fmt.Errorf("%v", recovered)
+				}
+				stack := debug.Stack()
+				traceID := common.TraceIDFromRequest(c.Request())
+				requestID, _ := common.RequestID(c.Request().Context())
+
+				logger.WithFields(logrus.Fields{
+					"trace_id":   traceID,
+					"request_id": requestID,
+					"method":     c.Request().Method,
+					"path":       c.Request().URL.Path,
+					"stack":      string(stack),
+				}).Errorf("panic recovered: %v", err)
+
+				if span := trace.SpanFromContext(c.Request().Context()); span.IsRecording() {
+					span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", string(stack))))
+					span.SetStatus(codes.Error, "panic recovered")
+				}
+
+				if c.Response().Committed {
+					returnErr = errors.Join(errors.New("panic recovered after response was committed"), err)
+					return
+				}
+
+				ctx := common.LocaleContext(c)
+				errorResp := common.InternalError(common.TWithContext(ctx, common.MsgErrorInternal))
+				errorResp.ProcessingTime = time.Since(start).Milliseconds()
+				errorResp.TraceID = traceID
+				returnErr = c.JSON(http.StatusInternalServerError, errorResp)
+			}()
+
+			return next(c)
+		}
+	}
+}
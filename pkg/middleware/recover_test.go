@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingSpan is a recording span keeping the errors and status set on it
+type recordingSpan struct {
+	noop.Span
+
+	mu     sync.Mutex
+	errs   []error
+	status codes.Code
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+}
+
+func TestRecoverRespondsWithEnvelope(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	span := &recordingSpan{}
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.SetRequest(c.Request().WithContext(trace.ContextWithSpan(c.Request().Context(), span)))
+			return next(c)
+		}
+	})
+	e.Use(Recover(logger))
+	e.GET("/panic", func(c echo.Context) error { panic("secret: db password hunter2") })
+	e.GET("/panic-error", func(c echo.Context) error { panic(errors.New("boom")) })
+	e.GET("/ok", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(common.HeaderTraceID, "trace-123")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("body %q leaks the panic message", rec.Body.String())
+	}
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != common.INTERNAL_ERROR || body.Message == "" || body.TraceID != "trace-123" {
+		t.Errorf("body = %+v, want a translated INTERNAL_ERROR with the trace id", body)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.ErrorLevel || entry.Data["trace_id"] != "trace-123" {
+		t.Fatalf("log entry = %+v, want an error with the trace id", entry)
+	}
+	if stack, _ := entry.Data["stack"].(string); !strings.Contains(stack, "recover_test.go") {
+		t.Error("logged stack does not include the panicking handler")
+	}
+	if len(span.errs) != 1 || span.status != codes.Error {
+		t.Errorf("span errors = %v, status = %v, want the panic recorded", span.errs, span.status)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic-error", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("error panic status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("after panics status = %d, body = %q, want the server to keep serving", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecoverRepanicsAbortHandler(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	e := echo.New()
+	e.Use(Recover(logger))
+	e.GET("/", func(c echo.Context) error { panic(http.ErrAbortHandler) })
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler to propagate", recovered)
+		}
+	}()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoverAfterCommit(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	e := echo.New()
+	var returned error
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			returned = next(c)
+			return nil
+		}
+	})
+	e.Use(Recover(logger))
+	e.GET("/", func(c echo.Context) error {
+		_ = c.String(http.StatusOK, "partial")
+		panic("late")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("status = %d, body = %q, want the committed response untouched", rec.Code, rec.Body.String())
+	}
+	if returned == nil || !strings.Contains(returned.Error(), "late") {
+		t.Errorf("returned error = %v, want the panic reported", returned)
+	}
+}
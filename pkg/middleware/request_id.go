@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// ContextKeyRequestID is the echo context key holding the request id
+const ContextKeyRequestID = "request_id"
+
+// RequestID reads the X-Request-Id or X-Trace-Id header, generating a UUID when absent or malformed,
+// and stores it in the echo context, the request context (common.RequestID) and the X-Request-Id response header
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			requestID := req.Header.Get(common.HeaderRequestID)
+			if !common.IsValidRequestID(requestID) {
+				requestID = req.Header.Get(common.HeaderTraceID)
+			}
+			if !common.IsValidRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+
+			c.Set(ContextKeyRequestID, requestID)
+			c.SetRequest(req.WithContext(common.WithRequestID(req.Context(), requestID)))
+			c.Response().Header().Set(common.HeaderRequestID, requestID)
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		want      string
+		generated bool
+	}{
+		{"request id header", map[string]string{common.HeaderRequestID: "req-1", common.HeaderTraceID: "trace-1"}, "req-1", false},
+		{"trace id header", map[string]string{common.HeaderTraceID: "trace-1"}, "trace-1", false},
+		{"absent", nil, "", true},
+		{"malformed", map[string]string{common.HeaderRequestID: "bad\nid"}, "", true},
+		{"too long", map[string]string{common.HeaderRequestID: strings.Repeat("a", 129)}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var echoID, ctxID, traceID string
+			e := echo.New()
+			e.Use(RequestID())
+			e.GET("/", func(c echo.Context) error {
+				echoID, _ = c.Get(ContextKeyRequestID).(string)
+				ctxID, _ = common.RequestID(c.Request().Context())
+				traceID = common.TraceIDFromRequest(c.Request())
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			want := tt.want
+			if tt.generated {
+				if _, err := uuid.Parse(echoID); err != nil {
+					t.Fatalf("generated id %q is not a UUID", echoID)
+				}
+				want = echoID
+			}
+			if echoID != want || ctxID != want {
+				t.Errorf("echo id = %q, context id = %q, want %q", echoID, ctxID, want)
+			}
+			if got := rec.Header().Get(common.HeaderRequestID); got != want {
+				t.Errorf("response header = %q, want %q", got, want)
+			}
+			if tt.generated && traceID != want {
+				t.Errorf("TraceIDFromRequest = %q, want the generated id %q", traceID, want)
+			}
+		})
+	}
+}
+
+func TestRequestIDInResponseBody(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestID(), ErrorHandlerMiddleware())
+	e.GET("/", func(c echo.Context) error { return common.NotFoundError("missing") })
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	requestID := rec.Header().Get(common.HeaderRequestID)
+	if requestID == "" || !strings.Contains(rec.Body.String(), `"trace_id":"`+requestID+`"`) {
+		t.Errorf("body = %s, want the generated request id %q as trace id", rec.Body.String(), requestID)
+	}
+}
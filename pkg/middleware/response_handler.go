@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 	"github.com/thanhthanh221/msa-core/pkg/common"
 )
 
@@ -35,20 +39,32 @@ func ResponseHandlerMiddleware() echo.MiddlewareFunc {
 				return err
 			}
 
-			// Get the response status
-			status := c.Response().Status
-
-			// If status is not 200, don't wrap (let ErrorHandlerMiddleware handle it)
-			if status != http.StatusOK {
+			// A handler that wrote its own response is not wrapped
+			if c.Response().Committed {
 				return nil
 			}
 
 			// Get the response data from context
 			responseData := c.Get("responseData")
 			responseMessage := c.Get("responseMessage")
+			pagination, _ := c.Get("responsePagination").(*common.PaginationInfo)
+			meta, _ := c.Get("responseMeta").(map[string]any)
+			status, hasStatus := c.Get("responseStatus").(int)
+			if !hasStatus {
+				status = c.Response().Status
+			}
 
 			// If no response data is set, don't wrap
-			if responseData == nil {
+			if responseData == nil && pagination == nil && meta == nil && !hasStatus {
+				return nil
+			}
+
+			// Only success statuses with a body are wrapped, an explicit bodyless status such as 204 is
+			// written without a body
+			if status < http.StatusOK || status >= http.StatusMultipleChoices || status == http.StatusNoContent {
+				if hasStatus {
+					return c.NoContent(status)
+				}
 				return nil
 			}
 
@@ -60,11 +76,17 @@ func ResponseHandlerMiddleware() echo.MiddlewareFunc {
 				}
 			}
 
-			baseResponse := common.SuccessResponse(responseData, message)
+			builder := common.NewSuccessResponse().WithData(responseData).WithMessage(message).WithMeta(meta)
+			if pagination != nil {
+				builder = builder.WithPagination(*pagination)
+			}
+			baseResponse := builder.Build()
 			baseResponse.ProcessingTime = processingTime
+			baseResponse.TraceID = common.TraceIDFromRequest(c.Request())
+			baseResponse.Warnings = common.GetWarnings(c)
 
 			// Return the wrapped response
-			return c.JSON(http.StatusOK, baseResponse)
+			return c.JSON(status, baseResponse)
 		}
 	}
 }
@@ -76,6 +98,29 @@ func SetResponseData(c echo.Context, data any, message string) {
 	c.Set("responseMessage", message)
 }
 
+// SetResponsePagination sets the pagination information of the wrapped response
+func SetResponsePagination(c echo.Context, pagination common.PaginationInfo) {
+	c.Set("responsePagination", &pagination)
+}
+
+// SetResponseStatus sets the HTTP status of the wrapped response (e.g. 201 Created), 200 by default.
+// Statuses without a body such as 204 No Content are written without the wrapper
+func SetResponseStatus(c echo.Context, code int) {
+	c.Set("responseStatus", code)
+}
+
+// SetResponseMeta merges metadata into the wrapped response
+func SetResponseMeta(c echo.Context, meta map[string]any) {
+	merged, _ := c.Get("responseMeta").(map[string]any)
+	if merged == nil {
+		merged = make(map[string]any, len(meta))
+	}
+	for key, value := range meta {
+		merged[key] = value
+	}
+	c.Set("responseMeta", merged)
+}
+
 // SetResponseDataOnly sets only the response data (uses default message)
 func SetResponseDataOnly(c echo.Context, data any) {
 	c.Set("responseData", data)
@@ -96,38 +141,63 @@ func GetProcessingTime(c echo.Context) int64 {
 }
 
 // ErrorHandlerMiddleware handles errors and converts them to standard error responses
+// translated with the request locale
 func ErrorHandlerMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			start := time.Now()
 			err := next(c)
 
+			// A handler that already wrote its response is left to the Echo error handler
+			if err != nil && c.Response().Committed {
+				return err
+			}
 			if err != nil {
-				// Get processing time from header
-				processingTimeStr := c.Response().Header().Get("X-Processing-Time")
-				processingTime := int64(0)
-				if processingTimeStr != "" {
-					// Convert string to int64 if possible
-					if pt, err := strconv.ParseInt(processingTimeStr, 10, 64); err == nil {
-						processingTime = pt
-					}
+				// Processing time since ResponseHandlerMiddleware started, or since this middleware when it is not used
+				processingTime := time.Since(start).Milliseconds()
+				if _, ok := c.Get("startTime").(time.Time); ok {
+					processingTime = GetProcessingTime(c)
+				}
+				ctx := common.LocaleContext(c)
+
+				// Validation errors returned by c.Validate are rendered in the request locale
+				var validationErrors *common.ValidationErrors
+				if errors.As(err, &validationErrors) {
+					errorResp := validationErrors.ToErrorResponse(ctx)
+					errorResp.ProcessingTime = processingTime
+					errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+					return c.JSON(http.StatusBadRequest, errorResp)
 				}
 
 				// Handle different types of errors
-				switch e := err.(type) {
-				case *echo.HTTPError:
+				var errorResp *common.ErrorResponse
+				var appError *common.AppError
+				var httpError *echo.HTTPError
+				if errors.As(err, &errorResp) {
+					// Copied so the error returned by the handler, possibly a shared sentinel, is not modified
+					errorResp = translatedErrorResponse(ctx, errorResp)
+				} else if errors.As(err, &appError) {
+					errorResp = translatedErrorResponse(ctx, appError.ToErrorResponse())
+				} else if errors.As(err, &httpError) {
 					// Convert HTTP status code to ResponseCode
-					errorResp := common.CreateErrorResponse(
-						common.ResponseCode(e.Code),
-						e.Message.(string),
-					)
-					errorResp.ProcessingTime = processingTime
-					return c.JSON(e.Code, errorResp)
-				default:
+					code := common.ResponseCodeForHTTPStatus(httpError.Code)
+					errorResp = common.CreateErrorResponse(code, httpErrorMessage(ctx, httpError, code)).WithCause(err)
+				} else {
 					// Handle unknown errors
-					errorResp := common.InternalError("Lỗi hệ thống không xác định")
-					errorResp.ProcessingTime = processingTime
-					return c.JSON(http.StatusInternalServerError, errorResp)
+					errorResp = common.InternalError(common.TWithContext(ctx, common.MsgErrorInternal)).WithCause(err)
 				}
+
+				// Log the underlying cause, it is never exposed to the client. Echo's own HTTP errors
+				// (e.g. 404 on unknown routes) have no cause worth logging
+				if cause := errorResp.Cause(); cause != nil && httpError == nil {
+					logrus.WithError(cause).
+						WithField("code", errorResp.Code).
+						WithField("trace_id", common.TraceIDFromRequest(c.Request())).
+						Errorf("%s %s failed: %s", c.Request().Method, c.Request().URL.Path, errorResp.Message)
+				}
+				errorResp.ProcessingTime = processingTime
+				errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+				return c.JSON(common.HTTPStatusFor(errorResp), errorResp)
 			}
 
 			return nil
@@ -135,6 +205,45 @@ func ErrorHandlerMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// translatedErrorResponse returns a copy of errorResp with its message key translated in the locale of ctx,
+// the generic message of its code when it has none
+func translatedErrorResponse(ctx context.Context, errorResp *common.ErrorResponse) *common.ErrorResponse {
+	messageKey := errorResp.Message
+	if messageKey == "" {
+		messageKey = common.MessageKeyForCode(errorResp.Code)
+	}
+	translated := common.CreateErrorResponse(errorResp.Code, common.TWithContextAndFallback(ctx, messageKey, messageKey), errorResp.Details...)
+	translated.ErrorCode = errorResp.ErrorCode
+	return translated.WithCause(errorResp.Cause())
+}
+
+// httpErrorMessage returns the client message of an echo.HTTPError in the locale of ctx.
+// Messages of any type are stringified, i18n keys are translated and echo's default status
+// texts are replaced by the generic message of code
+func httpErrorMessage(ctx context.Context, httpError *echo.HTTPError, code common.ResponseCode) string {
+	var message string
+	switch m := httpError.Message.(type) {
+	case nil:
+	case string:
+		message = m
+	case error:
+		message = m.Error()
+	case fmt.Stringer:
+		message = m.String()
+	default:
+		if encoded, err := json.Marshal(m); err == nil {
+			message = string(encoded)
+		} else {
+			message = fmt.Sprint(m)
+		}
+	}
+
+	if message == "" || message == http.StatusText(httpError.Code) {
+		return common.TWithContext(ctx, common.MessageKeyForCode(code))
+	}
+	return common.TWithContextAndFallback(ctx, message, message)
+}
+
 // ValidationErrorHandler handles validation errors specifically
 func ValidationErrorHandler() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -142,12 +251,20 @@ func ValidationErrorHandler() echo.MiddlewareFunc {
 			err := next(c)
 
 			if err != nil {
+				var validationErrors *common.ValidationErrors
+				if errors.As(err, &validationErrors) {
+					errorResp := validationErrors.ToErrorResponse(common.LocaleContext(c))
+					errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+					return c.JSON(http.StatusBadRequest, errorResp)
+				}
+
 				// Check if it's a validation error
 				if httpError, ok := err.(*echo.HTTPError); ok && httpError.Code == http.StatusBadRequest {
 					processingTime := int64(0)
 
 					errorResp := common.ValidationError("Dữ liệu không hợp lệ")
 					errorResp.ProcessingTime = processingTime
+					errorResp.TraceID = common.TraceIDFromRequest(c.Request())
 					return c.JSON(http.StatusBadRequest, errorResp)
 				}
 			}
@@ -0,0 +1,407 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// testBundles are the vn and en messages of the middleware tests
+var testBundles = fstest.MapFS{
+	"vn.json": {Data: []byte(`{"response": {"error": {"forbidden": "Không có quyền", "not_found": "Không tìm thấy", "internal": "Lỗi hệ thống"}}, "test": {"blocked": "Bị chặn"}}`)},
+	"en.json": {Data: []byte(`{"response": {"error": {"forbidden": "Forbidden", "not_found": "Not found", "internal": "Internal error"}}, "test": {"blocked": "Blocked"}}`)},
+}
+
+var initTestI18nOnce sync.Once
+
+// useTestI18n installs testBundles as the global i18n bundles, vn by default with the en fallback
+func useTestI18n(t *testing.T) {
+	t.Helper()
+	initTestI18nOnce.Do(func() {
+		if err := common.InitGlobalI18nFromFS(testBundles, "vn", "en"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// serveError runs handler behind ErrorHandlerMiddleware and decodes the error response
+func serveError(t *testing.T, handler echo.HandlerFunc) (int, common.ErrorResponse) {
+	t.Helper()
+	e := echo.New()
+	e.Use(ErrorHandlerMiddleware())
+	e.GET("/", handler)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, body
+}
+
+func TestErrorHandlerRendersErrorResponse(t *testing.T) {
+	status, body := serveError(t, func(c echo.Context) error {
+		return fmt.Errorf("find order: %w", common.NotFoundError("order not found"))
+	})
+
+	if status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", status, http.StatusNotFound)
+	}
+	if body.Code != common.NOT_FOUND || body.Message != "order not found" {
+		t.Errorf("body = %+v, want the NOT_FOUND response of the handler", body)
+	}
+}
+
+func TestErrorHandlerDoesNotModifySentinels(t *testing.T) {
+	status, body := serveError(t, func(c echo.Context) error {
+		return common.ErrConflictResponse
+	})
+
+	if status != http.StatusConflict || body.Code != common.CONFLICT {
+		t.Errorf("status = %d, body = %+v, want CONFLICT", status, body)
+	}
+	if body.Message == "" {
+		t.Error("sentinel without message rendered without the generic message")
+	}
+	if common.ErrConflictResponse.Message != "" || common.ErrConflictResponse.TraceID != "" {
+		t.Errorf("sentinel modified: %+v", common.ErrConflictResponse)
+	}
+}
+
+func TestErrorHandlerRendersAppError(t *testing.T) {
+	common.MustRegisterErrorCode("MIDDLEWARE_TEST_QUOTA", common.TOO_MANY_REQUESTS, http.StatusTooManyRequests, "errors.quota")
+
+	status, body := serveError(t, func(c echo.Context) error {
+		return common.NewAppError("MIDDLEWARE_TEST_QUOTA").WithDetails(common.ErrorDetail{Field: "plan", Message: "free"})
+	})
+
+	if status != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if body.ErrorCode != "MIDDLEWARE_TEST_QUOTA" || body.Code != common.TOO_MANY_REQUESTS {
+		t.Errorf("body = %+v, want the app error code", body)
+	}
+	if len(body.Details) != 1 || body.Details[0].Field != "plan" {
+		t.Errorf("details = %+v", body.Details)
+	}
+}
+
+func TestErrorHandlerUnknownError(t *testing.T) {
+	status, body := serveError(t, func(c echo.Context) error {
+		return errors.New("database password is hunter2")
+	})
+
+	if status != http.StatusInternalServerError || body.Code != common.INTERNAL_ERROR {
+		t.Errorf("status = %d, body = %+v, want INTERNAL_ERROR", status, body)
+	}
+	if body.Message == "database password is hunter2" {
+		t.Error("unknown error message exposed to the client")
+	}
+}
+
+// captureStandardLog records the entries of the standard logrus logger until the test ends
+func captureStandardLog(t *testing.T) *test.Hook {
+	t.Helper()
+	logger := logrus.StandardLogger()
+	hooks, output := logger.ReplaceHooks(logrus.LevelHooks{}), logger.Out
+	logger.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		logger.ReplaceHooks(hooks)
+		logger.SetOutput(output)
+	})
+	return test.NewLocal(logger)
+}
+
+func TestErrorHandlerLogsCause(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCause string
+	}{
+		{"error response", fmt.Errorf("load order: %w", common.InternalErrorFrom(errors.New("connection refused"))), "connection refused"},
+		{"unknown error", errors.New("disk full"), "disk full"},
+		{"without cause", common.NotFoundError("order not found"), ""},
+		{"http error", echo.NewHTTPError(http.StatusNotFound), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := captureStandardLog(t)
+			serveError(t, func(c echo.Context) error { return tt.err })
+
+			entries := hook.AllEntries()
+			if tt.wantCause == "" {
+				if len(entries) != 0 {
+					t.Errorf("logged %q, want nothing", entries[0].Message)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("logged %d entries, want the cause", len(entries))
+			}
+			if cause, _ := entries[0].Data[logrus.ErrorKey].(error); cause == nil || cause.Error() != tt.wantCause {
+				t.Errorf("logged error = %v, want %q", entries[0].Data[logrus.ErrorKey], tt.wantCause)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerCommittedResponse(t *testing.T) {
+	handlerErr := errors.New("stream interrupted")
+	var returned error
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			returned = next(c)
+			return returned
+		}
+	}, ErrorHandlerMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		if err := c.String(http.StatusOK, "partial"); err != nil {
+			return err
+		}
+		return handlerErr
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want the committed response untouched", rec.Code, rec.Body.String())
+	}
+	if returned != handlerErr {
+		t.Errorf("returned error = %v, want the handler error", returned)
+	}
+}
+
+func TestResponseHandlerMergesWarnings(t *testing.T) {
+	e := echo.New()
+	e.Use(ResponseHandlerMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		common.AddWarning(c, "test.warning.deprecated")
+		SetResponseData(c, map[string]string{"id": "1"}, "ok")
+		SetResponseMeta(c, map[string]any{"version": "v1"})
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body common.BaseResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	if len(body.Warnings) != 1 || body.Warnings[0] != "test.warning.deprecated" {
+		t.Errorf("warnings = %v, want the warning added by the handler", body.Warnings)
+	}
+	if body.Meta["version"] != "v1" {
+		t.Errorf("meta = %v", body.Meta)
+	}
+}
+
+func TestErrorHandlerRendersValidationErrors(t *testing.T) {
+	e := echo.New()
+	e.Validator = common.NewEchoValidator()
+	e.Use(ErrorHandlerMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		return c.Validate(&struct {
+			Name string `json:"name" validate:"required"`
+		}{})
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	if body.Code != common.VALIDATION_ERROR || len(body.Details) != 1 || body.Details[0].Field != "name" {
+		t.Errorf("body = %+v, want a VALIDATION_ERROR on name", body)
+	}
+}
+
+func TestErrorHandlerHTTPError(t *testing.T) {
+	useTestI18n(t)
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    common.ResponseCode
+		wantMessage string
+	}{
+		{"map message", echo.NewHTTPError(http.StatusForbidden, map[string]string{"reason": "blocked"}), http.StatusForbidden, common.FORBIDDEN, `{"reason":"blocked"}`},
+		{"error message", echo.NewHTTPError(http.StatusBadRequest, errors.New("code=400, message=Syntax error")), http.StatusBadRequest, common.BAD_REQUEST, "code=400, message=Syntax error"},
+		{"nil message", &echo.HTTPError{Code: http.StatusNotFound}, http.StatusNotFound, common.NOT_FOUND, "Không tìm thấy"},
+		{"default status text", echo.ErrNotFound, http.StatusNotFound, common.NOT_FOUND, "Không tìm thấy"},
+		{"i18n key", echo.NewHTTPError(http.StatusForbidden, "test.blocked"), http.StatusForbidden, common.FORBIDDEN, "Bị chặn"},
+		{"wrapped", fmt.Errorf("bind: %w", echo.NewHTTPError(http.StatusForbidden, "test.blocked")), http.StatusForbidden, common.FORBIDDEN, "Bị chặn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serveError(t, func(c echo.Context) error { return tt.err })
+			if status != tt.wantStatus || body.Code != tt.wantCode {
+				t.Errorf("status = %d, code = %v, want %d, %v", status, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerAcceptLanguage(t *testing.T) {
+	useTestI18n(t)
+
+	e := echo.New()
+	e.Use(ErrorHandlerMiddleware())
+	e.GET("/http", func(c echo.Context) error { return echo.NewHTTPError(http.StatusForbidden, "test.blocked") })
+	e.GET("/response", func(c echo.Context) error { return common.ErrNotFoundResponse })
+
+	for path, want := range map[string]string{"/http": "Blocked", "/response": "Not found"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var body common.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Message != want {
+			t.Errorf("%s message = %q, want the en message %q", path, body.Message, want)
+		}
+	}
+}
+
+func TestErrorHandlerProcessingTime(t *testing.T) {
+	e := echo.New()
+	e.Use(ErrorHandlerMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		c.Set("startTime", time.Now().Add(-time.Second))
+		return common.NotFoundError("missing")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ProcessingTime < 1000 {
+		t.Errorf("processing_time = %d, want it measured from the startTime context value", body.ProcessingTime)
+	}
+}
+
+func TestResponseHandlerSetters(t *testing.T) {
+	pagination := common.PaginationInfo{CurrentPage: 2, PageSize: 10, TotalPages: 3, TotalItems: 25, HasNext: true, HasPrev: true}
+
+	tests := []struct {
+		name           string
+		handler        echo.HandlerFunc
+		wantStatus     int
+		wantWrapped    bool
+		wantPagination bool
+		wantMeta       bool
+	}{
+		{"data only", func(c echo.Context) error {
+			SetResponseData(c, []string{"a"}, "ok")
+			return nil
+		}, http.StatusOK, true, false, false},
+		{"pagination", func(c echo.Context) error {
+			SetResponseData(c, []string{"a"}, "ok")
+			SetResponsePagination(c, pagination)
+			return nil
+		}, http.StatusOK, true, true, false},
+		{"meta", func(c echo.Context) error {
+			SetResponseMeta(c, map[string]any{"version": "v1"})
+			return nil
+		}, http.StatusOK, true, false, true},
+		{"status", func(c echo.Context) error {
+			SetResponseData(c, map[string]string{"id": "1"}, "created")
+			SetResponseStatus(c, http.StatusCreated)
+			return nil
+		}, http.StatusCreated, true, false, false},
+		{"combined", func(c echo.Context) error {
+			SetResponseData(c, []string{"a"}, "ok")
+			SetResponsePagination(c, pagination)
+			SetResponseMeta(c, map[string]any{"version": "v1"})
+			SetResponseStatus(c, http.StatusAccepted)
+			return nil
+		}, http.StatusAccepted, true, true, true},
+		{"no content status", func(c echo.Context) error {
+			SetResponseStatus(c, http.StatusNoContent)
+			return nil
+		}, http.StatusNoContent, false, false, false},
+		{"no content status with data", func(c echo.Context) error {
+			SetResponseData(c, "ignored", "ok")
+			SetResponseStatus(c, http.StatusNoContent)
+			return nil
+		}, http.StatusNoContent, false, false, false},
+		{"not modified status", func(c echo.Context) error {
+			SetResponseStatus(c, http.StatusNotModified)
+			return nil
+		}, http.StatusNotModified, false, false, false},
+		{"handler response", func(c echo.Context) error {
+			SetResponseData(c, "ignored", "ok")
+			return c.String(http.StatusCreated, "raw")
+		}, http.StatusCreated, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(ResponseHandlerMiddleware())
+			e.GET("/", tt.handler)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body common.BaseResponse
+			wrapped := json.Unmarshal(rec.Body.Bytes(), &body) == nil && body.Message != ""
+			if wrapped != tt.wantWrapped {
+				t.Fatalf("body = %q, wrapped %v, want %v", rec.Body.String(), wrapped, tt.wantWrapped)
+			}
+			if !wrapped {
+				return
+			}
+			if got := body.Pagination != nil && *body.Pagination == pagination; got != tt.wantPagination {
+				t.Errorf("pagination = %+v, want it %v", body.Pagination, tt.wantPagination)
+			}
+			if got := body.Meta["version"] == "v1"; got != tt.wantMeta {
+				t.Errorf("meta = %v, want it %v", body.Meta, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestSetResponseMetaMerges(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	SetResponseMeta(c, map[string]any{"version": "v1", "region": "eu"})
+	SetResponseMeta(c, map[string]any{"region": "ap"})
+
+	meta, _ := c.Get("responseMeta").(map[string]any)
+	if meta["version"] != "v1" || meta["region"] != "ap" {
+		t.Errorf("meta = %v, want the later values merged over the earlier ones", meta)
+	}
+}
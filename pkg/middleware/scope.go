@@ -0,0 +1,19 @@
+package middleware
+
+import "github.com/thanhthanh221/msa-core/pkg/common"
+
+// ScopeMatches reports whether a granted scope satisfies a required one, see common.ScopeMatches
+func ScopeMatches(granted, required string) bool {
+	return common.ScopeMatches(granted, required)
+}
+
+// HasScope reports whether the granted scopes satisfy required, following the scope hierarchy
+// registered with common.DefineScopeImplication
+func HasScope(granted []string, required string) bool {
+	return common.NewScopeSet(granted...).Has(required)
+}
+
+// MissingScopes returns the required scopes that the granted scopes do not satisfy, in order
+func MissingScopes(granted, required []string) []string {
+	return common.NewScopeSet(granted...).Missing(required...)
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+func TestScopeMatches(t *testing.T) {
+	tests := []struct {
+		granted, required string
+		want              bool
+	}{
+		{"orders:read", "orders:read", true},
+		{"orders:read", "orders:write", false},
+		{"orders:*", "orders:read", true},
+		{"orders:*", "orders:*", true},
+		{"orders:*", "orders", false},
+		{"orders:*", "ordersx:read", false},
+		{"orders*", "orders:read", false},
+		{"*", "anything", true},
+		{"orders:read", "orders:*", false},
+		{"", "orders:read", false},
+	}
+	for _, tt := range tests {
+		if got := ScopeMatches(tt.granted, tt.required); got != tt.want {
+			t.Errorf("ScopeMatches(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	granted := []string{"orders:*", "profile"}
+	if got := MissingScopes(granted, []string{"orders:read", "admin", "profile", "billing:read"}); !slices.Equal(got, []string{"admin", "billing:read"}) {
+		t.Errorf("MissingScopes = %v, want the unsatisfied scopes in order", got)
+	}
+	if !HasScope(granted, "orders:write") || HasScope(granted, "admin") {
+		t.Error("HasScope does not follow wildcard matching")
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	auth := NewJWTAuthMiddlewareWithOptions(nil, logger)
+
+	tests := []struct {
+		name        string
+		middleware  echo.MiddlewareFunc
+		granted     []string
+		wantStatus  int
+		wantMissing []string
+	}{
+		{"any satisfied by one", auth.RequireAnyScope("read", "admin"), []string{"admin"}, http.StatusOK, nil},
+		{"any none", auth.RequireAnyScope("read", "admin"), []string{"profile"}, http.StatusForbidden, []string{"read", "admin"}},
+		{"all satisfied", auth.RequireAllScopes("orders:read", "orders:write"), []string{"orders:*"}, http.StatusOK, nil},
+		{"all partial", auth.RequireAllScopes("orders:read", "billing:read"), []string{"orders:*"}, http.StatusForbidden, []string{"billing:read"}},
+		{"single wildcard", auth.RequireScope("orders:read"), []string{"orders:*"}, http.StatusOK, nil},
+		{"single missing", auth.RequireScope("orders:read"), []string{"profile"}, http.StatusForbidden, []string{"orders:read"}},
+		{"implied by the hierarchy", auth.RequireAllScopes("orders:read", "read"), []string{"orders:admin", "write"}, http.StatusOK, nil},
+		{"not implied upwards", auth.RequireScope("orders:write"), []string{"orders:read"}, http.StatusForbidden, []string{"orders:write"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					c.Set("scopes", tt.granted)
+					return next(c)
+				}
+			}, tt.middleware)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+
+			var body common.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != common.FORBIDDEN || body.ErrorCode != ErrorCodeInsufficientScope {
+				t.Errorf("body = %+v, want a FORBIDDEN %s error response", body, ErrorCodeInsufficientScope)
+			}
+			var missing []string
+			for _, detail := range body.Details {
+				missing = append(missing, detail.Value)
+			}
+			if !slices.Equal(missing, tt.wantMissing) {
+				t.Errorf("missing scopes = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
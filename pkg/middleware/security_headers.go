@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SecurityHeadersOptions configures SecurityHeaders, empty fields keep their default
+type SecurityHeadersOptions struct {
+	// FrameOptions is the X-Frame-Options value, "DENY" by default
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy value, "strict-origin-when-cross-origin" by default
+	ReferrerPolicy string
+	// ContentSecurityPolicy is the Content-Security-Policy value, not sent when empty
+	ContentSecurityPolicy string
+	// HSTS sends Strict-Transport-Security on TLS requests (or behind a proxy setting X-Forwarded-Proto: https)
+	HSTS bool
+	// HSTSMaxAge is the max-age of Strict-Transport-Security, one year by default
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains adds includeSubDomains to Strict-Transport-Security
+	HSTSIncludeSubdomains bool
+	// HSTSPreload adds preload to Strict-Transport-Security
+	HSTSPreload bool
+}
+
+// SecurityHeaders sets X-Content-Type-Options: nosniff, X-Frame-Options, Referrer-Policy and, when configured,
+// Content-Security-Policy and Strict-Transport-Security on every response
+func SecurityHeaders(opts SecurityHeadersOptions) echo.MiddlewareFunc {
+	if opts.FrameOptions == "" {
+		opts.FrameOptions = "DENY"
+	}
+	if opts.ReferrerPolicy == "" {
+		opts.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+	if opts.HSTSMaxAge <= 0 {
+		opts.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+
+	hsts := "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge.Seconds()), 10)
+	if opts.HSTSIncludeSubdomains {
+		hsts += "; includeSubDomains"
+	}
+	if opts.HSTSPreload {
+		hsts += "; preload"
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+			header.Set(echo.HeaderXFrameOptions, opts.FrameOptions)
+			header.Set(echo.HeaderReferrerPolicy, opts.ReferrerPolicy)
+			if opts.ContentSecurityPolicy != "" {
+				header.Set(echo.HeaderContentSecurityPolicy, opts.ContentSecurityPolicy)
+			}
+			if opts.HSTS && (c.IsTLS() || c.Request().Header.Get(echo.HeaderXForwardedProto) == "https") {
+				header.Set(echo.HeaderStrictTransportSecurity, hsts)
+			}
+			return next(c)
+		}
+	}
+}
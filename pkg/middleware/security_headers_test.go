@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// serveSecurityHeaders returns the response headers of a request behind SecurityHeaders
+func serveSecurityHeaders(opts SecurityHeadersOptions, forwardedProto string) http.Header {
+	e := echo.New()
+	e.Use(SecurityHeaders(opts))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if forwardedProto != "" {
+		req.Header.Set(echo.HeaderXForwardedProto, forwardedProto)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Header()
+}
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+	header := serveSecurityHeaders(SecurityHeadersOptions{HSTS: true}, "")
+
+	want := map[string]string{
+		echo.HeaderXContentTypeOptions:     "nosniff",
+		echo.HeaderXFrameOptions:           "DENY",
+		echo.HeaderReferrerPolicy:          "strict-origin-when-cross-origin",
+		echo.HeaderContentSecurityPolicy:   "",
+		echo.HeaderStrictTransportSecurity: "",
+	}
+	for name, value := range want {
+		if got := header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestSecurityHeadersHSTS(t *testing.T) {
+	opts := SecurityHeadersOptions{
+		HSTS:                  true,
+		HSTSMaxAge:            time.Hour,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		FrameOptions:          "SAMEORIGIN",
+		ContentSecurityPolicy: "default-src 'self'",
+	}
+	header := serveSecurityHeaders(opts, "https")
+
+	if got := header.Get(echo.HeaderStrictTransportSecurity); got != "max-age=3600; includeSubDomains; preload" {
+		t.Errorf("Strict-Transport-Security = %q", got)
+	}
+	if got := header.Get(echo.HeaderXFrameOptions); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q", got)
+	}
+	if got := header.Get(echo.HeaderContentSecurityPolicy); got != "default-src 'self'" {
+		t.Errorf("Content-Security-Policy = %q", got)
+	}
+
+	if got := serveSecurityHeaders(SecurityHeadersOptions{}, "https").Get(echo.HeaderStrictTransportSecurity); got != "" {
+		t.Errorf("Strict-Transport-Security = %q with HSTS disabled", got)
+	}
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// SignatureAlgorithm is the HMAC hash of webhook signatures, also used as signature prefix ("sha256=...")
+type SignatureAlgorithm string
+
+const (
+	SignatureSHA256 SignatureAlgorithm = "sha256"
+	SignatureSHA1   SignatureAlgorithm = "sha1"
+)
+
+// Error codes of rejected webhook signatures
+const (
+	ErrorCodeInvalidSignature = "invalid_signature"
+	ErrorCodeSignatureExpired = "signature_expired"
+)
+
+// SignatureConfig configures VerifySignature
+type SignatureConfig struct {
+	// Header carries the signature, "X-Signature" by default. Values look like "sha256=<hex>" or "<hex>"
+	Header string
+	// Algorithm is the HMAC hash, SignatureSHA256 by default
+	Algorithm SignatureAlgorithm
+	// Secret is the shared secret, used when SecretLookup is nil
+	Secret string
+	// SecretLookup returns the secret of the request source, e.g. from a ":partner" path parameter.
+	// An empty secret rejects the request
+	SecretLookup func(c echo.Context) (string, error)
+	// TimestampHeader enables replay protection: it carries the Unix time of the signature, and the signed
+	// payload becomes "<timestamp>.<body>"
+	TimestampHeader string
+	// MaxSkew is the accepted distance between the timestamp and the server clock, 5 minutes by default
+	MaxSkew time.Duration
+	// MaxBodySize is the largest body verified, 1 MiB by default
+	MaxBodySize int64
+	// Logger logs secret lookup failures, the standard logrus logger by default
+	Logger *logrus.Logger
+}
+
+// VerifySignature verifies the HMAC signature of inbound webhooks in constant time, rejecting invalid
+// signatures and, with TimestampHeader, stale ones with 401. The body is restored so handlers can bind it
+func VerifySignature(cfg SignatureConfig) echo.MiddlewareFunc {
+	if cfg.Header == "" {
+		cfg.Header = "X-Signature"
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = SignatureSHA256
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	newHash := sha256.New
+	if cfg.Algorithm == SignatureSHA1 {
+		newHash = sha1.New
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			signature, ok := parseSignature(req.Header.Get(cfg.Header), cfg.Algorithm)
+			if !ok {
+				return signatureError(c, ErrorCodeInvalidSignature)
+			}
+
+			timestamp := ""
+			if cfg.TimestampHeader != "" {
+				timestamp = strings.TrimSpace(req.Header.Get(cfg.TimestampHeader))
+				seconds, err := strconv.ParseInt(timestamp, 10, 64)
+				if err != nil {
+					return signatureError(c, ErrorCodeInvalidSignature)
+				}
+				if skew := time.Since(time.Unix(seconds, 0)); skew > cfg.MaxSkew || skew < -cfg.MaxSkew {
+					return signatureError(c, ErrorCodeSignatureExpired)
+				}
+			}
+
+			secret := cfg.Secret
+			if cfg.SecretLookup != nil {
+				var err error
+				if secret, err = cfg.SecretLookup(c); err != nil {
+					cfg.Logger.WithError(err).Warnf("signature: secret lookup failed for %s", req.URL.Path)
+					secret = ""
+				}
+			}
+			if secret == "" {
+				return signatureError(c, ErrorCodeInvalidSignature)
+			}
+
+			body, err := readSignedBody(req, cfg.MaxBodySize)
+			if err != nil {
+				return signatureError(c, ErrorCodeInvalidSignature)
+			}
+
+			if !hmac.Equal(signature, computeSignature(newHash, secret, timestamp, body)) {
+				return signatureError(c, ErrorCodeInvalidSignature)
+			}
+			return next(c)
+		}
+	}
+}
+
+// ComputeSignature returns the "<algorithm>=<hex>" signature of body, timestamp is empty without replay protection.
+// It is the value senders put in the signature header
+func ComputeSignature(algorithm SignatureAlgorithm, secret, timestamp string, body []byte) string {
+	newHash := sha256.New
+	if algorithm == SignatureSHA1 {
+		newHash = sha1.New
+	}
+	return string(algorithm) + "=" + hex.EncodeToString(computeSignature(newHash, secret, timestamp, body))
+}
+
+// computeSignature computes the HMAC of body, prefixed by "<timestamp>." when timestamp is set
+func computeSignature(newHash func() hash.Hash, secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	if timestamp != "" {
+		mac.Write([]byte(timestamp + "."))
+	}
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// parseSignature decodes "<algorithm>=<hex>" or "<hex>", rejecting another algorithm prefix
+func parseSignature(value string, algorithm SignatureAlgorithm) ([]byte, bool) {
+	value = strings.TrimSpace(value)
+	if prefix, encoded, found := strings.Cut(value, "="); found {
+		if !strings.EqualFold(prefix, string(algorithm)) {
+			return nil, false
+		}
+		value = encoded
+	}
+	signature, err := hex.DecodeString(value)
+	return signature, err == nil && len(signature) > 0
+}
+
+// readSignedBody reads the request body and restores it for the handler, also when it exceeds limit
+func readSignedBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+		return nil, io.ErrShortBuffer
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// signatureError writes the 401 error response of a rejected signature
+func signatureError(c echo.Context, errorCode string) error {
+	messageKey, fallback := common.MsgErrorInvalidSignature, "Invalid signature"
+	if errorCode == ErrorCodeSignatureExpired {
+		messageKey, fallback = common.MsgErrorSignatureExpired, "Signature has expired"
+	}
+	errorResp := common.UnauthorizedError(common.TWithContextAndFallback(common.LocaleContext(c), messageKey, fallback))
+	errorResp.ErrorCode = errorCode
+	errorResp.TraceID = common.TraceIDFromRequest(c.Request())
+	return c.JSON(http.StatusUnauthorized, errorResp)
+}
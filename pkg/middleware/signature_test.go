@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// signatureServer serves POST /webhooks/:partner behind VerifySignature, echoing the body read by the handler
+func signatureServer(cfg SignatureConfig) *echo.Echo {
+	cfg.Logger, _ = test.NewNullLogger()
+	e := echo.New()
+	e.Use(VerifySignature(cfg))
+	e.POST("/webhooks/:partner", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	})
+	return e
+}
+
+// postWebhook posts body with the given headers
+func postWebhook(e *echo.Echo, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestVerifySignature(t *testing.T) {
+	const body = `{"event":"paid"}`
+	e := signatureServer(SignatureConfig{Secret: "shh"})
+	valid := ComputeSignature(SignatureSHA256, "shh", "", []byte(body))
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		wantCode  string
+	}{
+		{"valid", body, valid, ""},
+		{"valid without prefix", body, strings.TrimPrefix(valid, "sha256="), ""},
+		{"tampered body", `{"event":"refunded"}`, valid, ErrorCodeInvalidSignature},
+		{"wrong secret", body, ComputeSignature(SignatureSHA256, "other", "", []byte(body)), ErrorCodeInvalidSignature},
+		{"other algorithm", body, ComputeSignature(SignatureSHA1, "shh", "", []byte(body)), ErrorCodeInvalidSignature},
+		{"not hex", body, "sha256=zz", ErrorCodeInvalidSignature},
+		{"missing", body, "", ErrorCodeInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postWebhook(e, "/webhooks/acme", tt.body, map[string]string{"X-Signature": tt.signature})
+			if tt.wantCode == "" {
+				if rec.Code != http.StatusOK || rec.Body.String() != tt.body {
+					t.Errorf("response = %d %q, want the body restored for the handler", rec.Code, rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != http.StatusUnauthorized || errorCode(t, rec) != tt.wantCode {
+				t.Errorf("response = %d %s, want 401 %s", rec.Code, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureTimestamp(t *testing.T) {
+	const body = `{"event":"paid"}`
+	e := signatureServer(SignatureConfig{Secret: "shh", TimestampHeader: "X-Timestamp", MaxSkew: time.Minute})
+
+	signed := func(at time.Time) map[string]string {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		return map[string]string{
+			"X-Signature": ComputeSignature(SignatureSHA256, "shh", timestamp, []byte(body)),
+			"X-Timestamp": timestamp,
+		}
+	}
+
+	if rec := postWebhook(e, "/webhooks/acme", body, signed(time.Now())); rec.Code != http.StatusOK {
+		t.Errorf("fresh signature = %d %s, want 200", rec.Code, rec.Body.String())
+	}
+	for name, at := range map[string]time.Time{"stale": time.Now().Add(-2 * time.Minute), "future": time.Now().Add(2 * time.Minute)} {
+		if rec := postWebhook(e, "/webhooks/acme", body, signed(at)); rec.Code != http.StatusUnauthorized || errorCode(t, rec) != ErrorCodeSignatureExpired {
+			t.Errorf("%s signature = %d %s, want 401 %s", name, rec.Code, rec.Body.String(), ErrorCodeSignatureExpired)
+		}
+	}
+
+	// The timestamp is signed: replaying the signature with a new timestamp fails
+	header := signed(time.Now().Add(-2 * time.Minute))
+	header["X-Timestamp"] = strconv.FormatInt(time.Now().Unix(), 10)
+	if rec := postWebhook(e, "/webhooks/acme", body, header); errorCode(t, rec) != ErrorCodeInvalidSignature {
+		t.Errorf("replayed signature = %d %s, want %s", rec.Code, rec.Body.String(), ErrorCodeInvalidSignature)
+	}
+}
+
+func TestVerifySignatureSecretLookup(t *testing.T) {
+	const body = `{}`
+	e := signatureServer(SignatureConfig{
+		Algorithm: SignatureSHA1,
+		SecretLookup: func(c echo.Context) (string, error) {
+			switch c.Param("partner") {
+			case "acme":
+				return "acme-secret", nil
+			case "broken":
+				return "", errors.New("vault unavailable")
+			}
+			return "", nil
+		},
+	})
+	signature := ComputeSignature(SignatureSHA1, "acme-secret", "", []byte(body))
+
+	if rec := postWebhook(e, "/webhooks/acme", body, map[string]string{"X-Signature": signature}); rec.Code != http.StatusOK {
+		t.Errorf("acme = %d %s, want 200", rec.Code, rec.Body.String())
+	}
+	for _, partner := range []string{"unknown", "broken"} {
+		if rec := postWebhook(e, "/webhooks/"+partner, body, map[string]string{"X-Signature": signature}); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s = %d, want 401 without secret", partner, rec.Code)
+		}
+	}
+}
+
+func TestVerifySignatureBodyLimit(t *testing.T) {
+	body := strings.Repeat("a", 20)
+	e := signatureServer(SignatureConfig{Secret: "shh", MaxBodySize: 10})
+
+	rec := postWebhook(e, "/webhooks/acme", body, map[string]string{"X-Signature": ComputeSignature(SignatureSHA256, "shh", "", []byte(body))})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401 for a body over the limit", rec.Code)
+	}
+}
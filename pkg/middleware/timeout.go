@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// timeoutConfig holds the Timeout options
+type timeoutConfig struct {
+	status int
+	routes map[string]time.Duration
+	logger *logrus.Logger
+}
+
+// TimeoutOption configures Timeout
+type TimeoutOption func(*timeoutConfig)
+
+// WithTimeoutStatus sets the status of timed out requests, http.StatusRequestTimeout by default
+// (http.StatusGatewayTimeout is the usual alternative)
+func WithTimeoutStatus(status int) TimeoutOption {
+	return func(cfg *timeoutConfig) {
+		cfg.status = status
+	}
+}
+
+// WithRouteTimeout overrides the timeout of a route pattern as registered (e.g. "/reports/:id"),
+// a zero duration disables the timeout for the route
+func WithRouteTimeout(route string, d time.Duration) TimeoutOption {
+	return func(cfg *timeoutConfig) {
+		cfg.routes[route] = d
+	}
+}
+
+// WithTimeoutLogger sets the logger of timed out requests, the standard logrus logger by default
+func WithTimeoutLogger(logger *logrus.Logger) TimeoutOption {
+	return func(cfg *timeoutConfig) {
+		cfg.logger = logger
+	}
+}
+
+// Timeout bounds the request context with a deadline of d so repository, Redis and RabbitMQ calls abort
+// when it expires. If the handler has not written its response by then, a REQUEST_TIMEOUT error response
+// is written once and later writes of the handler are discarded
+func Timeout(d time.Duration, opts ...TimeoutOption) echo.MiddlewareFunc {
+	cfg := &timeoutConfig{
+		status: http.StatusRequestTimeout,
+		routes: make(map[string]time.Duration),
+		logger: logrus.StandardLogger(),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout := d
+			if routeTimeout, ok := cfg.routes[c.Path()]; ok {
+				timeout = routeTimeout
+			}
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			req := c.Request()
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			c.SetRequest(req.WithContext(ctx))
+
+			// Prepare the timeout response now, the echo context must not be used by the timer goroutine
+			code := common.ResponseCodeForHTTPStatus(cfg.status)
+			errorResp := common.CreateErrorResponse(code, common.TWithContext(common.LocaleContext(c), common.MessageKeyForCode(code)))
+			errorResp.TraceID = common.TraceIDFromRequest(req)
+			start := time.Now()
+
+			res := c.Response()
+			writer := newTimeoutWriter(ctx, res.Writer, cfg.status, errorResp, start)
+			res.Writer = writer
+			stop := context.AfterFunc(ctx, writer.timeout)
+
+			err := next(c)
+			stop()
+			// Handlers returning as soon as the context expires answer with the timeout response too
+			writer.timeout()
+			res.Writer = writer.ResponseWriter
+
+			if writer.finish() {
+				cfg.logger.WithField("trace_id", errorResp.TraceID).
+					Warnf("%s %s timed out after %s", req.Method, req.URL.Path, timeout)
+				// The timeout response is already written, keep echo from writing the handler error
+				res.Committed = true
+				res.Status = cfg.status
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// timeoutWriter serializes the writes of the handler and of the timeout response. The handler gets
+// its own header map so the timer goroutine never races with it
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	status    int
+	errorResp *common.ErrorResponse
+	start     time.Time
+
+	mu       sync.Mutex
+	header   http.Header
+	wrote    bool
+	timedOut bool
+	done     bool
+}
+
+// newTimeoutWriter wraps w, keeping the headers already set by previous middlewares. errorResp is
+// written with status once ctx exceeds its deadline
+func newTimeoutWriter(ctx context.Context, w http.ResponseWriter, status int, errorResp *common.ErrorResponse, start time.Time) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		status:         status,
+		errorResp:      errorResp,
+		start:          start,
+		header:         w.Header().Clone(),
+	}
+}
+
+// Header returns the header map of the handler
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader forwards the handler status and headers unless the request timed out
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timeoutLocked() || w.wrote {
+		return
+	}
+	w.writeHeaderLocked(status)
+}
+
+// Write forwards the handler body, it fails with http.ErrHandlerTimeout once the request timed out
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timeoutLocked() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !w.wrote {
+		w.writeHeaderLocked(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush flushes the handler output unless the request timed out
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && !w.timedOut {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeHeaderLocked copies the handler headers to the wrapped writer and writes status
+func (w *timeoutWriter) writeHeaderLocked(status int) {
+	header := w.ResponseWriter.Header()
+	for name, values := range w.header {
+		header[name] = values
+	}
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// timeout writes the timeout response if the deadline passed before the handler started its own
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timeoutLocked()
+}
+
+// timeoutLocked writes the timeout response once the deadline passed, unless the handler response was
+// started first, and reports whether the request timed out. Handler writes check it too, so a handler
+// woken by the deadline cannot write before the timer goroutine. w.mu must be held
+func (w *timeoutWriter) timeoutLocked() bool {
+	if w.timedOut || w.wrote || w.done || !errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		return w.timedOut
+	}
+	w.timedOut = true
+	w.errorResp.ProcessingTime = time.Since(w.start).Milliseconds()
+
+	header := w.ResponseWriter.Header()
+	header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	header.Del(echo.HeaderContentLength)
+	w.ResponseWriter.WriteHeader(w.status)
+	body, _ := json.Marshal(w.errorResp)
+	_, _ = w.ResponseWriter.Write(body)
+	return true
+}
+
+// finish stops the timeout response from being written once the handler returned and reports whether it was
+func (w *timeoutWriter) finish() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	return w.timedOut
+}
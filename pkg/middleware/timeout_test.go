@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/common"
+)
+
+// serveTimeout runs handler on /slow behind Timeout and returns the recorder
+func serveTimeout(t *testing.T, d time.Duration, handler echo.HandlerFunc, opts ...TimeoutOption) *httptest.ResponseRecorder {
+	t.Helper()
+	logger, _ := test.NewNullLogger()
+	e := echo.New()
+	e.Use(ErrorHandlerMiddleware(), Timeout(d, append([]TimeoutOption{WithTimeoutLogger(logger)}, opts...)...))
+	e.GET("/slow", handler)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	return rec
+}
+
+// decodeErrorResponse decodes the error response of rec
+func decodeErrorResponse(t *testing.T, rec *httptest.ResponseRecorder) common.ErrorResponse {
+	t.Helper()
+	var body common.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+func TestTimeoutCancelsContext(t *testing.T) {
+	rec := serveTimeout(t, 20*time.Millisecond, func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		// Late writes and errors of the handler are discarded
+		_ = c.String(http.StatusOK, "late")
+		return c.Request().Context().Err()
+	})
+
+	if rec.Code != http.StatusRequestTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestTimeout)
+	}
+	if body := decodeErrorResponse(t, rec); body.Code != common.REQUEST_TIMEOUT {
+		t.Errorf("body = %+v, want a REQUEST_TIMEOUT response", body)
+	}
+}
+
+func TestTimeoutHandlerIgnoringContext(t *testing.T) {
+	rec := serveTimeout(t, 10*time.Millisecond, func(c echo.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return c.String(http.StatusOK, "late")
+	}, WithTimeoutStatus(http.StatusGatewayTimeout))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	body := decodeErrorResponse(t, rec)
+	if body.Code != common.ResponseCodeForHTTPStatus(http.StatusGatewayTimeout) {
+		t.Errorf("body = %+v, want the gateway timeout response", body)
+	}
+	if body.ProcessingTime < 10 {
+		t.Errorf("processing_time = %d, want at least the timeout", body.ProcessingTime)
+	}
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	rec := serveTimeout(t, time.Second, func(c echo.Context) error {
+		if _, ok := c.Request().Context().Deadline(); !ok {
+			t.Error("request context without deadline")
+		}
+		return c.String(http.StatusCreated, "done")
+	})
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != "done" {
+		t.Errorf("response = %d %q, want the handler response", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimeoutHandlerError(t *testing.T) {
+	rec := serveTimeout(t, time.Second, func(c echo.Context) error {
+		return common.NotFoundError("order not found")
+	})
+
+	if rec.Code != http.StatusNotFound || decodeErrorResponse(t, rec).Message != "order not found" {
+		t.Errorf("response = %d %q, want the handler error", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimeoutRouteOverride(t *testing.T) {
+	rec := serveTimeout(t, 10*time.Millisecond, func(c echo.Context) error {
+		if _, ok := c.Request().Context().Deadline(); ok {
+			t.Error("deadline set on a route with the timeout disabled")
+		}
+		time.Sleep(20 * time.Millisecond)
+		return c.String(http.StatusOK, "done")
+	}, WithRouteTimeout("/slow", 0))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the handler response", rec.Code)
+	}
+}
+
+func TestTimeoutLogs(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	e := echo.New()
+	e.Use(Timeout(5*time.Millisecond, WithTimeoutLogger(logger)))
+	e.GET("/slow", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return nil
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.WarnLevel {
+		t.Errorf("log entry = %+v, want a warning of the timed out request", entry)
+	}
+}
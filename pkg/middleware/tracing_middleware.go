@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"math/rand/v2"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
@@ -20,30 +22,90 @@ type TracingMiddleware struct {
 	tracerProvider trace.TracerProvider
 	logger         *logrus.Logger
 	propagator     propagation.TextMapPropagator
+	tracedMethods  map[string]bool
+	includePaths   []string
+	excludePaths   []string
+	readSampleRate float64
 }
 
+// TracingOption configures a TracingMiddleware
+type TracingOption func(*TracingMiddleware)
+
+// WithTracedMethods replaces the traced HTTP methods, POST, PUT, DELETE and PATCH by default
+func WithTracedMethods(methods ...string) TracingOption {
+	return func(m *TracingMiddleware) {
+		m.tracedMethods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			m.tracedMethods[strings.ToUpper(method)] = true
+		}
+	}
+}
+
+// WithIncludePaths always traces the paths, whatever the method (exact paths or prefixes ending with '*')
+func WithIncludePaths(paths ...string) TracingOption {
+	return func(m *TracingMiddleware) {
+		m.includePaths = append(m.includePaths, paths...)
+	}
+}
+
+// WithExcludePaths never traces the paths, e.g. "/health" (exact paths or prefixes ending with '*')
+func WithExcludePaths(paths ...string) TracingOption {
+	return func(m *TracingMiddleware) {
+		m.excludePaths = append(m.excludePaths, paths...)
+	}
+}
+
+// WithReadSampleRate traces this fraction (0 to 1) of the requests whose method is not traced, e.g. 0.01 for 1% of GETs
+func WithReadSampleRate(fraction float64) TracingOption {
+	return func(m *TracingMiddleware) {
+		m.readSampleRate = min(max(fraction, 0), 1)
+	}
+}
+
+// sampleFloat is the random source of the read sample rate, in [0, 1)
+var sampleFloat = rand.Float64
+
 // NewTracingMiddleware creates a new tracing middleware
-func NewTracingMiddleware(tracerProvider trace.TracerProvider, logger *logrus.Logger) *TracingMiddleware {
-	return &TracingMiddleware{
+func NewTracingMiddleware(tracerProvider trace.TracerProvider, logger *logrus.Logger, opts ...TracingOption) *TracingMiddleware {
+	m := &TracingMiddleware{
 		tracerProvider: tracerProvider,
 		logger:         logger,
 		propagator:     otel.GetTextMapPropagator(), // W3C Trace Context propagator
+		tracedMethods: map[string]bool{
+			http.MethodPost:   true,
+			http.MethodPut:    true,
+			http.MethodDelete: true,
+			http.MethodPatch:  true,
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// shouldTrace decides whether a request gets a root span: excluded paths never, included paths always,
+// traced methods always, other requests with the read sample rate
+func (m *TracingMiddleware) shouldTrace(method, path string) bool {
+	if matchPath(path, m.excludePaths) {
+		return false
+	}
+	if matchPath(path, m.includePaths) || m.tracedMethods[method] {
+		return true
 	}
+	return m.readSampleRate > 0 && sampleFloat() < m.readSampleRate
 }
 
 // Middleware returns the echo middleware function
-// Only creates trace for write operations (POST, PUT, DELETE, PATCH)
+// By default only creates trace for write operations (POST, PUT, DELETE, PATCH), see TracingOption
 func (m *TracingMiddleware) Middleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			req := c.Request()
 			method := req.Method
 
-			// Only trace write operations (POST, PUT, DELETE, PATCH)
-			isWriteOperation := method == http.MethodPost ||
-				method == http.MethodPut ||
-				method == http.MethodDelete ||
-				method == http.MethodPatch
+			// Only trace the configured methods and paths
+			isWriteOperation := m.shouldTrace(method, req.URL.Path)
 
 			var ctx context.Context
 			var span trace.Span
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingTracerProvider records the names of the spans started by its tracers
+type recordingTracerProvider struct {
+	noop.TracerProvider
+
+	mu    sync.Mutex
+	spans []string
+}
+
+func (p *recordingTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p}
+}
+
+// started returns the span names recorded since the last call
+func (p *recordingTracerProvider) started() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	spans := p.spans
+	p.spans = nil
+	return spans
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingTracerProvider
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.provider.mu.Lock()
+	t.provider.spans = append(t.provider.spans, name)
+	t.provider.mu.Unlock()
+	span := &recordingSpan{}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// useSampleFloat replaces the read sampling random source for the duration of the test
+func useSampleFloat(t *testing.T, value float64) {
+	t.Helper()
+	previous := sampleFloat
+	sampleFloat = func() float64 { return value }
+	t.Cleanup(func() { sampleFloat = previous })
+}
+
+func TestTracingMiddlewareSpanCreation(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []TracingOption
+		sample float64
+		method string
+		path   string
+		want   bool
+	}{
+		{"default write", nil, 0, http.MethodPost, "/items", true},
+		{"default read", nil, 0, http.MethodGet, "/items", false},
+		{"traced methods", []TracingOption{WithTracedMethods("get")}, 0, http.MethodGet, "/items", true},
+		{"traced methods replace defaults", []TracingOption{WithTracedMethods(http.MethodGet)}, 0, http.MethodPost, "/items", false},
+		{"included path", []TracingOption{WithIncludePaths("/reports/*")}, 0, http.MethodGet, "/reports/daily", true},
+		{"excluded path", []TracingOption{WithExcludePaths("/health")}, 0, http.MethodPost, "/health", false},
+		{"exclude wins over include", []TracingOption{WithIncludePaths("/health"), WithExcludePaths("/health")}, 0, http.MethodGet, "/health", false},
+		{"sampled read", []TracingOption{WithReadSampleRate(0.01)}, 0.005, http.MethodGet, "/items", true},
+		{"unsampled read", []TracingOption{WithReadSampleRate(0.01)}, 0.5, http.MethodGet, "/items", false},
+		{"rate clamped", []TracingOption{WithReadSampleRate(2)}, 0.999, http.MethodGet, "/items", true},
+		{"negative rate", []TracingOption{WithReadSampleRate(-1)}, 0, http.MethodGet, "/items", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSampleFloat(t, tt.sample)
+			provider := &recordingTracerProvider{}
+			tracing := NewTracingMiddleware(provider, logrus.New(), tt.opts...)
+
+			var hasSpan bool
+			e := echo.New()
+			e.Use(tracing.Middleware())
+			e.Any("/*", func(c echo.Context) error {
+				hasSpan = trace.SpanFromContext(c.Request().Context()).IsRecording()
+				return c.NoContent(http.StatusNoContent)
+			})
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			spans := provider.started()
+			if (len(spans) == 1) != tt.want || hasSpan != tt.want {
+				t.Errorf("spans = %v, handler span %v, want traced %v", spans, hasSpan, tt.want)
+			}
+			if tt.want && spans[0] != tt.method+" /*" {
+				t.Errorf("span name = %q, want the method and route", spans[0])
+			}
+		})
+	}
+}
+
+func TestTracingMiddlewareRecordsErrorStatus(t *testing.T) {
+	provider := &recordingTracerProvider{}
+	var span *recordingSpan
+	e := echo.New()
+	e.Use(NewTracingMiddleware(provider, logrus.New()).Middleware())
+	e.POST("/", func(c echo.Context) error {
+		span, _ = c.Get("span").(*recordingSpan)
+		return echo.NewHTTPError(http.StatusConflict, "taken")
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if span == nil || len(span.errs) != 1 {
+		t.Fatalf("span = %+v, want the handler error recorded", span)
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// AuditEvent records a write operation for compliance (non-DB)
+// @model AuditEvent
+type AuditEvent struct {
+	// @Description Event ID
+	// @example "7f9c2a4e-1b7d-4c55-9f0e-2d3b8a6c1e42"
+	ID string `json:"id" example:"7f9c2a4e-1b7d-4c55-9f0e-2d3b8a6c1e42"`
+	// @Description Service emitting the event
+	// @example "order-service"
+	Service string `json:"service,omitempty" example:"order-service"`
+	// @Description Authenticated user ID (empty for anonymous requests)
+	// @example "bc198ec4-3f81-4729-ac5d-04b838d2ab3c"
+	UserID string `json:"user_id,omitempty" example:"bc198ec4-3f81-4729-ac5d-04b838d2ab3c"`
+	// @Description Session ID
+	// @example "c1c0b6b8-2b31-4b2f-9d6f-9c6a3a2e3d65"
+	SessionID string `json:"session_id,omitempty" example:"c1c0b6b8-2b31-4b2f-9d6f-9c6a3a2e3d65"`
+	// @Description HTTP method
+	// @example "PUT"
+	Method string `json:"method" example:"PUT"`
+	// @Description Route pattern
+	// @example "/orders/:id"
+	Route string `json:"route" example:"/orders/:id"`
+	// @Description Request path
+	// @example "/orders/42"
+	Path string `json:"path" example:"/orders/42"`
+	// @Description Entity ID taken from the path parameters
+	// @example "42"
+	EntityID string `json:"entity_id,omitempty" example:"42"`
+	// @Description Client IP
+	// @example "203.0.113.7"
+	IP string `json:"ip" example:"203.0.113.7"`
+	// @Description Client user agent
+	// @example "Mozilla/5.0"
+	UserAgent string `json:"user_agent,omitempty" example:"Mozilla/5.0"`
+	// @Description Response status code
+	// @example 200
+	Status int `json:"status" example:"200"`
+	// @Description Request ID
+	// @example "0f8fad5b-d9cb-469f-a165-70867728950e"
+	RequestID string `json:"request_id,omitempty" example:"0f8fad5b-d9cb-469f-a165-70867728950e"`
+	// @Description Trace ID
+	// @example "4bf92f3577b34da6a3ce929d0e0e4736"
+	TraceID string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	// @Description Request body with sensitive fields redacted (allow-listed routes only)
+	Body any `json:"body,omitempty"`
+	// @Description Request duration (milliseconds)
+	// @example 35
+	DurationMs int64 `json:"duration_ms" example:"35"`
+	// @Description When the request was received
+	// @example "2024-01-15T10:30:00Z"
+	Timestamp time.Time `json:"timestamp" example:"2024-01-15T10:30:00Z"`
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/json"
+	"math"
+	"slices"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -40,7 +44,106 @@ type JWTClaims struct {
 	// @Description Session ID (links access token to refresh/session)
 	// @example "c1c0b6b8-2b31-4b2f-9d6f-9c6a3a2e3d65"
 	SID string `json:"sid" example:"c1c0b6b8-2b31-4b2f-9d6f-9c6a3a2e3d65"`
+	// @Description Roles (e.g. admin, manager, viewer)
+	// @example ["admin"]
+	Roles []string `json:"roles,omitempty" example:"[\"admin\"]"`
+	// @Description Custom claims (e.g. tenant_id, feature flags)
+	// @example {"tenant_id":"acme"}
+	Extra map[string]any `json:"extra,omitempty" swaggertype:"object"`
+	// @Description Token type, "access" (empty for older access tokens) or "refresh"
+	// @example "access"
+	TokenType string `json:"typ,omitempty" example:"access"`
 	// @Description Registered claims
 	// @example "RegisteredClaims"
 	jwt.RegisteredClaims
 }
+
+// AllRoles returns the roles of the token, merging the roles claim with the user roles of older tokens
+func (c *JWTClaims) AllRoles() []string {
+	roles := make([]string, 0, len(c.Roles)+len(c.User.Roles))
+	for _, role := range slices.Concat(c.Roles, c.User.Roles) {
+		if role != "" && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Token types of the typ claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// ClaimTenantID is the custom claim carrying the tenant id
+const ClaimTenantID = "tenant_id"
+
+// GetClaim returns the custom claim key
+func (c *JWTClaims) GetClaim(key string) (any, bool) {
+	value, ok := c.Extra[key]
+	return value, ok
+}
+
+// GetStringClaim returns the custom claim key when it is a string
+func (c *JWTClaims) GetStringClaim(key string) (string, bool) {
+	value, ok := c.Extra[key].(string)
+	return value, ok
+}
+
+// GetInt64Claim returns the custom claim key when it is an integer, JSON numbers decode as float64
+func (c *JWTClaims) GetInt64Claim(key string) (int64, bool) {
+	switch value := c.Extra[key].(type) {
+	case int64:
+		return value, true
+	case int:
+		return int64(value), true
+	case float64:
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(value), true
+	case json.Number:
+		parsed, err := value.Int64()
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// IntrospectionResponse represents an OAuth2 token introspection response (RFC 7662) (non-DB)
+// @model IntrospectionResponse
+type IntrospectionResponse struct {
+	// @Description Whether the token is valid, the other fields are only set for active tokens
+	// @example true
+	Active bool `json:"active" example:"true"`
+	// @Description Space separated scopes
+	// @example "read write"
+	Scope string `json:"scope,omitempty" example:"read write"`
+	// @Description Human readable identifier of the resource owner
+	// @example "john.doe@example.com"
+	Username string `json:"username,omitempty" example:"john.doe@example.com"`
+	// @Description Token type
+	// @example "Bearer"
+	TokenType string `json:"token_type,omitempty" example:"Bearer"`
+	// @Description Expiry time (Unix seconds)
+	// @example 1735689600
+	Exp int64 `json:"exp,omitempty" example:"1735689600"`
+	// @Description Issue time (Unix seconds)
+	// @example 1735686000
+	Iat int64 `json:"iat,omitempty" example:"1735686000"`
+	// @Description Not before time (Unix seconds)
+	// @example 1735686000
+	Nbf int64 `json:"nbf,omitempty" example:"1735686000"`
+	// @Description Subject (user ID)
+	// @example "bc198ec4-3f81-4729-ac5d-04b838d2ab3c"
+	Sub string `json:"sub,omitempty" example:"bc198ec4-3f81-4729-ac5d-04b838d2ab3c"`
+	// @Description Audiences
+	// @example ["msa"]
+	Aud []string `json:"aud,omitempty" example:"[\"msa\"]"`
+	// @Description Issuer
+	// @example "msa-auth"
+	Iss string `json:"iss,omitempty" example:"msa-auth"`
+	// @Description Token ID
+	// @example "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+	Jti string `json:"jti,omitempty" example:"1b4e28ba-2fa1-11d2-883f-0016d3cca427"`
+}
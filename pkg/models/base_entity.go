@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"github.com/thanhthanh221/msa-core/pkg/common"
+	"gorm.io/gorm"
+)
+
+// BaseEntity holds the UUID primary key, timestamps and audit columns of entities, embed it so the
+// created_at ordering and soft deletes of the repositories work on every table. Entities defining their
+// own hooks must call the BaseEntity ones
+// @model BaseEntity
+type BaseEntity struct {
+	// @Description Entity ID, generated on create when empty
+	// @example "7f9c2a4e-1b7d-4c55-9f0e-2d3b8a6c1e42"
+	ID string `json:"id" gorm:"type:uuid;primaryKey" example:"7f9c2a4e-1b7d-4c55-9f0e-2d3b8a6c1e42"`
+	// @Description Creation time
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	// @Description Last update time
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt soft deletes rows, the repository finders skip deleted rows
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	// @Description ID of the user who created the entity, empty for system writes
+	// @example "bc198ec4-3f81-4729-ac5d-04b838d2ab3c"
+	CreatedBy *string `json:"created_by,omitempty" gorm:"size:64" example:"bc198ec4-3f81-4729-ac5d-04b838d2ab3c"`
+	// @Description ID of the user who last updated the entity, empty for system writes
+	// @example "bc198ec4-3f81-4729-ac5d-04b838d2ab3c"
+	UpdatedBy *string `json:"updated_by,omitempty" gorm:"size:64" example:"bc198ec4-3f81-4729-ac5d-04b838d2ab3c"`
+}
+
+// BeforeCreate generates the ID when empty and records the user of the context as creator
+func (e *BaseEntity) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = common.MustNewUUID()
+	}
+	setCreatedBy(tx, &e.CreatedBy, &e.UpdatedBy)
+	return nil
+}
+
+// BeforeUpdate records the user of the context as last updater
+func (e *BaseEntity) BeforeUpdate(tx *gorm.DB) error {
+	setUpdatedBy(tx)
+	return nil
+}
+
+// BaseEntityNumeric is BaseEntity for legacy tables with auto-incremented integer keys
+// @model BaseEntityNumeric
+type BaseEntityNumeric struct {
+	// @Description Entity ID
+	// @example 42
+	ID uint64 `json:"id" gorm:"primaryKey;autoIncrement" example:"42"`
+	// @Description Creation time
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	// @Description Last update time
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt soft deletes rows, the repository finders skip deleted rows
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	// @Description ID of the user who created the entity, empty for system writes
+	// @example "bc198ec4-3f81-4729-ac5d-04b838d2ab3c"
+	CreatedBy *string `json:"created_by,omitempty" gorm:"size:64" example:"bc198ec4-3f81-4729-ac5d-04b838d2ab3c"`
+	// @Description ID of the user who last updated the entity, empty for system writes
+	// @example "bc198ec4-3f81-4729-ac5d-04b838d2ab3c"
+	UpdatedBy *string `json:"updated_by,omitempty" gorm:"size:64" example:"bc198ec4-3f81-4729-ac5d-04b838d2ab3c"`
+}
+
+// BeforeCreate records the user of the context as creator
+func (e *BaseEntityNumeric) BeforeCreate(tx *gorm.DB) error {
+	setCreatedBy(tx, &e.CreatedBy, &e.UpdatedBy)
+	return nil
+}
+
+// BeforeUpdate records the user of the context as last updater
+func (e *BaseEntityNumeric) BeforeUpdate(tx *gorm.DB) error {
+	setUpdatedBy(tx)
+	return nil
+}
+
+// setCreatedBy fills the empty audit fields of a new entity with the user of the statement context
+func setCreatedBy(tx *gorm.DB, createdBy, updatedBy **string) {
+	userID, ok := common.UserID(tx.Statement.Context)
+	if !ok || userID == "" {
+		return
+	}
+	if *createdBy == nil {
+		*createdBy = &userID
+	}
+	if *updatedBy == nil {
+		*updatedBy = &userID
+	}
+}
+
+// setUpdatedBy sets updated_by to the user of the statement context. SetColumn also covers updates with
+// a map, which do not read the fields of the entity
+func setUpdatedBy(tx *gorm.DB) {
+	if userID, ok := common.UserID(tx.Statement.Context); ok && userID != "" {
+		tx.Statement.SetColumn("updated_by", userID)
+	}
+}
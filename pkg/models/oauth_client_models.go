@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// OAuthClient is a machine client authenticating with the client_credentials grant
+// @model OAuthClient
+type OAuthClient struct {
+	// @Description Client ID
+	// @example "5d8f6a2c-7c1e-4b8e-9a43-0f2d6e1b7c90"
+	ID string `json:"id" gorm:"primaryKey;size:36" example:"5d8f6a2c-7c1e-4b8e-9a43-0f2d6e1b7c90"`
+	// @Description Client name
+	// @example "billing-worker"
+	Name string `json:"name" gorm:"size:255;not null" example:"billing-worker"`
+	// SecretHash is the SHA-256 of the client secret, the secret itself is never stored
+	SecretHash string `json:"-" gorm:"size:64;not null"`
+	// @Description Scopes granted to the client tokens
+	// @example ["orders:read"]
+	Scopes []string `json:"scopes" gorm:"serializer:json" example:"[\"orders:read\"]"`
+	// @Description Allowed redirect URIs
+	// @example ["https://billing.example.com/callback"]
+	RedirectURIs []string `json:"redirect_uris,omitempty" gorm:"serializer:json" example:"[\"https://billing.example.com/callback\"]"`
+	// @Description Revocation time, revoked clients cannot authenticate
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// @Description Creation time
+	CreatedAt time.Time `json:"created_at"`
+	// @Description Last update time
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table of OAuth clients
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// Revoked reports whether the client was revoked
+func (c *OAuthClient) Revoked() bool {
+	return c.RevokedAt != nil
+}
+
+// ClientCredentials is returned once when registering a client, the secret cannot be read again
+// @model ClientCredentials
+type ClientCredentials struct {
+	// @Description Client ID
+	// @example "5d8f6a2c-7c1e-4b8e-9a43-0f2d6e1b7c90"
+	ClientID string `json:"client_id" example:"5d8f6a2c-7c1e-4b8e-9a43-0f2d6e1b7c90"`
+	// @Description Client secret, only visible in the registration response
+	// @example "q3Jx0v1b6ZpS9n7W..."
+	ClientSecret string `json:"client_secret" example:"q3Jx0v1b6ZpS9n7W..."`
+	// @Description Registered client
+	Client OAuthClient `json:"client"`
+}
+
+// ClientTokenResponse is the OAuth2 token response of the client_credentials grant (RFC 6749 5.1)
+// @model ClientTokenResponse
+type ClientTokenResponse struct {
+	// @Description Access token
+	// @example "eyJhbGciOiJIUzI1NiIs..."
+	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	// @Description Token type
+	// @example "Bearer"
+	TokenType string `json:"token_type" example:"Bearer"`
+	// @Description Lifetime in seconds
+	// @example 3600
+	ExpiresIn int64 `json:"expires_in" example:"3600"`
+	// @Description Space separated granted scopes
+	// @example "orders:read"
+	Scope string `json:"scope,omitempty" example:"orders:read"`
+}
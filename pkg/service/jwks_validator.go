@@ -0,0 +1,479 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// TokenValidator validates access tokens, JWTAuthMiddleware accepts any implementation
+type TokenValidator interface {
+	ValidateToken(tokenString string) (*models.JWTClaims, error)
+	IsTokenBlacklisted(ctx context.Context, tokenString string) (bool, error)
+}
+
+// JWKSValidator validates tokens of an external issuer (Keycloak, Auth0...) against its published key set
+type JWKSValidator interface {
+	TokenValidator
+	// BlacklistToken revokes a token until it expires
+	BlacklistToken(ctx context.Context, tokenString string) error
+	// Refresh fetches the key set now
+	Refresh(ctx context.Context) error
+	// Close stops the background refresh
+	Close()
+}
+
+var (
+	// ErrJWKSUnavailable is returned when no usable key set could be fetched
+	ErrJWKSUnavailable = errors.New("jwks: key set unavailable")
+	// ErrJWKSUnknownKey is returned for tokens whose kid is not in the key set, even after a refresh
+	ErrJWKSUnknownKey = errors.New("jwks: unknown signing key")
+)
+
+// JWKSOption configures NewJWTValidatorFromJWKS
+type JWKSOption func(*jwksValidator)
+
+// WithJWKSHTTPClient fetches the key set with client, a client with a 10 seconds timeout by default
+func WithJWKSHTTPClient(client *http.Client) JWKSOption {
+	return func(v *jwksValidator) {
+		v.client = client
+	}
+}
+
+// WithJWKSIssuer rejects tokens whose iss claim is not issuer
+func WithJWKSIssuer(issuer string) JWKSOption {
+	return func(v *jwksValidator) {
+		v.issuer = issuer
+	}
+}
+
+// WithJWKSAudience rejects tokens whose aud claim does not contain audience
+func WithJWKSAudience(audience string) JWKSOption {
+	return func(v *jwksValidator) {
+		v.audience = audience
+	}
+}
+
+// WithJWKSCacheTTL bounds how long a key set is cached: ttl is used when the response has no
+// Cache-Control max-age (1 hour by default), and minTTL is the shortest refresh interval (1 minute by default)
+func WithJWKSCacheTTL(ttl, minTTL time.Duration) JWKSOption {
+	return func(v *jwksValidator) {
+		if ttl > 0 {
+			v.defaultTTL = ttl
+		}
+		if minTTL > 0 {
+			v.minTTL = minTTL
+		}
+	}
+}
+
+// WithJWKSMaxStale keeps using an expired key set for maxStale while the JWKS URL fails, 24 hours by default
+func WithJWKSMaxStale(maxStale time.Duration) JWKSOption {
+	return func(v *jwksValidator) {
+		v.maxStale = maxStale
+	}
+}
+
+// WithJWKSBlacklistStore stores revoked tokens in store, process-local by default
+func WithJWKSBlacklistStore(store TokenBlacklistStore) JWKSOption {
+	return func(v *jwksValidator) {
+		if store != nil {
+			v.blacklist = store
+		}
+	}
+}
+
+// jwksRetryInterval is the delay before retrying a failed background refresh
+const jwksRetryInterval = 30 * time.Second
+
+// jwksMaxBodySize bounds the size of a key set response
+const jwksMaxBodySize = 1 << 20
+
+type jwksValidator struct {
+	url        string
+	client     *http.Client
+	issuer     string
+	audience   string
+	defaultTTL time.Duration
+	minTTL     time.Duration
+	maxStale   time.Duration
+	blacklist  TokenBlacklistStore
+
+	mu        sync.RWMutex
+	keys      map[string]jwtKey
+	expiresAt time.Time
+	fetchedAt time.Time
+	lastErr   error
+
+	// refreshMu serializes fetches so concurrent cache misses trigger a single request
+	refreshMu sync.Mutex
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewJWTValidatorFromJWKS creates a validator of RS256/ES256 tokens signed by the keys published at
+// jwksURL. The key set is cached following Cache-Control and refreshed in the background, and on
+// unknown kids for key rollover. Standard OIDC claims are mapped to models.OAuthUser
+func NewJWTValidatorFromJWKS(jwksURL string, opts ...JWKSOption) JWKSValidator {
+	v := &jwksValidator{
+		url:        jwksURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		defaultTTL: time.Hour,
+		minTTL:     time.Minute,
+		maxStale:   24 * time.Hour,
+		blacklist:  NewMemoryTokenBlacklistStore(),
+		keys:       make(map[string]jwtKey),
+		stop:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	go v.refreshLoop()
+	return v
+}
+
+// Close stops the background refresh
+func (v *jwksValidator) Close() {
+	v.closeOnce.Do(func() { close(v.stop) })
+}
+
+// refreshLoop fetches the key set when it expires, retrying failures after jwksRetryInterval
+func (v *jwksValidator) refreshLoop() {
+	delay := time.Duration(0)
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-v.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := v.Refresh(ctx)
+		cancel()
+
+		if err != nil {
+			delay = jwksRetryInterval
+			continue
+		}
+		v.mu.RLock()
+		delay = max(time.Until(v.expiresAt), v.minTTL)
+		v.mu.RUnlock()
+	}
+}
+
+// Refresh fetches and caches the key set, keeping the previous one when the fetch fails
+func (v *jwksValidator) Refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	return v.refresh(ctx)
+}
+
+// refresh fetches the key set, refreshMu must be held
+func (v *jwksValidator) refresh(ctx context.Context) error {
+	keys, ttl, err := v.fetch(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err != nil {
+		v.lastErr = err
+		return err
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.expiresAt = v.fetchedAt.Add(ttl)
+	v.lastErr = nil
+	return nil
+}
+
+// fetch downloads and parses the key set, returning its cache TTL
+func (v *jwksValidator) fetch(ctx context.Context) (map[string]jwtKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwks: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwks: fetch %s: %w", v.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("jwks: fetch %s: unexpected status %d", v.url, resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBodySize)).Decode(&set); err != nil {
+		return nil, 0, fmt.Errorf("jwks: decode key set: %w", err)
+	}
+
+	keys := make(map[string]jwtKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kid == "" || jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.verificationKey()
+		if err != nil {
+			// Keys of unsupported types are skipped, the others stay usable
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, 0, errors.New("jwks: key set has no usable signing key")
+	}
+	return keys, v.cacheTTL(resp.Header.Get("Cache-Control")), nil
+}
+
+// cacheTTL returns the max-age of a Cache-Control header bounded by minTTL, or defaultTTL
+func (v *jwksValidator) cacheTTL(cacheControl string) time.Duration {
+	ttl := v.defaultTTL
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return v.minTTL
+		}
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(value); err == nil {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return max(ttl, v.minTTL)
+}
+
+// key returns the verification key of kid, refreshing the key set on unknown kids at most once per minTTL
+func (v *jwksValidator) key(kid string) (jwtKey, error) {
+	if key, ok, err := v.cachedKey(kid); ok || err != nil {
+		return key, err
+	}
+
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	// Another request may have refreshed the key set while waiting
+	if key, ok, err := v.cachedKey(kid); ok || err != nil {
+		return key, err
+	}
+
+	v.mu.RLock()
+	recentlyFetched := time.Since(v.fetchedAt) < v.minTTL
+	v.mu.RUnlock()
+	if !recentlyFetched {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := v.refresh(ctx); err != nil {
+			if v.usable() {
+				return jwtKey{}, fmt.Errorf("%w %q", ErrJWKSUnknownKey, kid)
+			}
+			return jwtKey{}, fmt.Errorf("%w: %v", ErrJWKSUnavailable, err)
+		}
+		if key, ok, err := v.cachedKey(kid); ok || err != nil {
+			return key, err
+		}
+	}
+	return jwtKey{}, fmt.Errorf("%w %q", ErrJWKSUnknownKey, kid)
+}
+
+// cachedKey looks kid up in the cached key set, failing when the set is stale beyond maxStale
+func (v *jwksValidator) cachedKey(kid string) (jwtKey, bool, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if len(v.keys) == 0 {
+		return jwtKey{}, false, nil
+	}
+	if time.Since(v.expiresAt) > v.maxStale {
+		return jwtKey{}, false, fmt.Errorf("%w: cached key set expired at %s: %v", ErrJWKSUnavailable, v.expiresAt.Format(time.RFC3339), v.lastErr)
+	}
+	key, ok := v.keys[kid]
+	return key, ok, nil
+}
+
+// usable reports whether a key set within maxStale is cached
+func (v *jwksValidator) usable() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return len(v.keys) > 0 && time.Since(v.expiresAt) <= v.maxStale
+}
+
+// keyFunc returns the key of the token kid, whose family must match the token alg
+func (v *jwksValidator) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+	key, err := v.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if methodFamily(token.Method) != methodFamily(key.method) {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.key, nil
+}
+
+// oidcClaims are the standard OIDC claims of externally issued access tokens
+type oidcClaims struct {
+	Email             string   `json:"email"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Scope             string   `json:"scope"`
+	Scp               []string `json:"scp"`
+	SID               string   `json:"sid"`
+	Roles             []string `json:"roles"`
+	RealmAccess       struct {
+		Roles []string `json:"roles"`
+	} `json:"realm_access"`
+	jwt.RegisteredClaims
+}
+
+func (v *jwksValidator) ValidateToken(tokenString string) (*models.JWTClaims, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(v.audience))
+	}
+
+	claims := &oidcClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, v.keyFunc, parserOpts...); err != nil {
+		return nil, err
+	}
+
+	scopes := claims.Scp
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	roles := claims.Roles
+	if len(roles) == 0 {
+		roles = claims.RealmAccess.Roles
+	}
+
+	return &models.JWTClaims{
+		User: models.OAuthUser{
+			ID:       claims.Subject,
+			Email:    claims.Email,
+			Name:     name,
+			Provider: claims.Issuer,
+		},
+		Scopes:           scopes,
+		SID:              claims.SID,
+		Roles:            roles,
+		RegisteredClaims: claims.RegisteredClaims,
+	}, nil
+}
+
+// BlacklistToken revokes tokenString until its expiry claim
+func (v *jwksValidator) BlacklistToken(ctx context.Context, tokenString string) error {
+	if tokenString == "" {
+		return errors.New("token is required")
+	}
+	key, expiresAt := blacklistKey(tokenString)
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return v.blacklist.Add(ctx, key, ttl)
+}
+
+func (v *jwksValidator) IsTokenBlacklisted(ctx context.Context, tokenString string) (bool, error) {
+	key, _ := blacklistKey(tokenString)
+	return v.blacklist.Exists(ctx, key)
+}
+
+// jsonWebKey is an RSA or EC public key of a JWKS (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey decodes the public key and the signing method family it verifies
+func (k jsonWebKey) verificationKey() (jwtKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return jwtKey{}, errors.New("invalid RSA exponent")
+		}
+		return jwtKey{method: jwt.SigningMethodRS256, key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return jwtKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return jwtKey{}, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return jwtKey{}, errors.New("EC point is not on the curve")
+		}
+		return jwtKey{method: jwt.SigningMethodES256, key: &ecdsa.PublicKey{Curve: curve, X: x, Y: y}}, nil
+	default:
+		return jwtKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeJWKInt decodes a base64url encoded big-endian integer
+func decodeJWKInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
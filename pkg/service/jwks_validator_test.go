@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer is a local JWKS endpoint whose key set and availability can be changed
+type jwksServer struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	keys         []map[string]string
+	cacheControl string
+	down         bool
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// setKeys replaces the published key set
+func (s *jwksServer) setKeys(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// setDown makes the endpoint answer 503
+func (s *jwksServer) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// b64 encodes a big-endian integer as a JWK parameter
+func b64(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N), "e": b64(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X), "y": b64(key.Y)}
+}
+
+// externalToken signs OIDC claims with key and kid like an external issuer
+func externalToken(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	base := jwt.MapClaims{"sub": "u1", "iss": "https://idp.test", "aud": "orders", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range claims {
+		base[name] = value
+	}
+	token := jwt.NewWithClaims(method, base)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// newTestJWKSValidator returns a validator of server closed at the end of the test
+func newTestJWKSValidator(t *testing.T, server *jwksServer, opts ...JWKSOption) JWKSValidator {
+	t.Helper()
+	validator := NewJWTValidatorFromJWKS(server.URL, opts...)
+	t.Cleanup(validator.Close)
+	return validator
+}
+
+func TestJWKSValidatorMapsClaims(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t), newECKey(t)
+	server := newJWKSServer(t, rsaJWK("rsa-1", rsaKey), ecJWK("ec-1", ecKey))
+	validator := newTestJWKSValidator(t, server, WithJWKSIssuer("https://idp.test"), WithJWKSAudience("orders"))
+
+	token := externalToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{
+		"email":              "u1@example.com",
+		"preferred_username": "user one",
+		"scope":              "orders:read orders:write",
+		"realm_access":       map[string]any{"roles": []string{"admin"}},
+		"sid":                "sid-1",
+	})
+	claims, err := validator.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := claims.User
+	if user.ID != "u1" || user.Email != "u1@example.com" || user.Name != "user one" || user.Provider != "https://idp.test" {
+		t.Errorf("user = %+v, want the OIDC claims mapped", user)
+	}
+	if len(claims.Scopes) != 2 || claims.Scopes[1] != "orders:write" {
+		t.Errorf("scopes = %v", claims.Scopes)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != "admin" || claims.SID != "sid-1" {
+		t.Errorf("roles = %v, sid = %q", claims.Roles, claims.SID)
+	}
+
+	if _, err := validator.ValidateToken(externalToken(t, jwt.SigningMethodES256, "ec-1", ecKey, jwt.MapClaims{"name": "User"})); err != nil {
+		t.Errorf("ES256 token: %v", err)
+	}
+}
+
+func TestJWKSValidatorRejects(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("rsa-1", rsaKey))
+	validator := newTestJWKSValidator(t, server, WithJWKSIssuer("https://idp.test"), WithJWKSAudience("orders"))
+
+	tests := map[string]string{
+		"other issuer":   externalToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{"iss": "https://evil.test"}),
+		"other audience": externalToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{"aud": "billing"}),
+		"expired":        externalToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+		"no expiry":      externalToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{"exp": nil}),
+		"other key":      externalToken(t, jwt.SigningMethodRS256, "rsa-1", newRSAKey(t), nil),
+		"HS256":          externalToken(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), nil),
+	}
+	for name, token := range tests {
+		if _, err := validator.ValidateToken(token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestJWKSValidatorKeyRollover(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("2024-01", oldKey))
+	validator := newTestJWKSValidator(t, server, WithJWKSCacheTTL(time.Hour, time.Nanosecond))
+
+	if _, err := validator.ValidateToken(externalToken(t, jwt.SigningMethodRS256, "2024-01", oldKey, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The issuer publishes the new key: the unknown kid triggers a refresh of the cached set
+	server.setKeys(rsaJWK("2024-01", oldKey), rsaJWK("2024-06", newKey))
+	if _, err := validator.ValidateToken(externalToken(t, jwt.SigningMethodRS256, "2024-06", newKey, nil)); err != nil {
+		t.Errorf("token of the new key: %v", err)
+	}
+
+	_, err := validator.ValidateToken(externalToken(t, jwt.SigningMethodRS256, "2025-01", newKey, nil))
+	if !errors.Is(err, ErrJWKSUnknownKey) {
+		t.Errorf("unknown kid: %v, want ErrJWKSUnknownKey", err)
+	}
+}
+
+func TestJWKSValidatorStaleWhileError(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("rsa-1", key))
+	validator := newTestJWKSValidator(t, server, WithJWKSCacheTTL(time.Millisecond, time.Millisecond), WithJWKSMaxStale(time.Hour))
+	token := externalToken(t, jwt.SigningMethodRS256, "rsa-1", key, nil)
+
+	if err := validator.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	server.setDown(true)
+	time.Sleep(5 * time.Millisecond)
+
+	if err := validator.Refresh(context.Background()); err == nil {
+		t.Error("Refresh succeeded while the JWKS URL fails")
+	}
+	if _, err := validator.ValidateToken(token); err != nil {
+		t.Errorf("token with an expired cached key set: %v, want the stale set used", err)
+	}
+}
+
+func TestJWKSValidatorUnavailable(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t)
+	server.setDown(true)
+	validator := newTestJWKSValidator(t, server, WithJWKSCacheTTL(time.Millisecond, time.Nanosecond), WithJWKSMaxStale(time.Millisecond))
+	token := externalToken(t, jwt.SigningMethodRS256, "rsa-1", key, nil)
+
+	if _, err := validator.ValidateToken(token); !errors.Is(err, ErrJWKSUnavailable) {
+		t.Errorf("without key set: %v, want ErrJWKSUnavailable", err)
+	}
+
+	server.setDown(false)
+	server.setKeys(rsaJWK("rsa-1", key))
+	if _, err := validator.ValidateToken(token); err != nil {
+		t.Fatalf("after recovery: %v", err)
+	}
+
+	server.setDown(true)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := validator.ValidateToken(token); !errors.Is(err, ErrJWKSUnavailable) {
+		t.Errorf("key set stale beyond max stale: %v, want ErrJWKSUnavailable", err)
+	}
+}
+
+func TestJWKSCacheTTL(t *testing.T) {
+	v := &jwksValidator{defaultTTL: time.Hour, minTTL: time.Minute}
+
+	tests := map[string]time.Duration{
+		"":                        time.Hour,
+		"public, max-age=600":     10 * time.Minute,
+		"max-age=5":               time.Minute,
+		"no-cache":                time.Minute,
+		"no-store, max-age=86400": time.Minute,
+	}
+	for cacheControl, want := range tests {
+		if got := v.cacheTTL(cacheControl); got != want {
+			t.Errorf("cacheTTL(%q) = %s, want %s", cacheControl, got, want)
+		}
+	}
+}
+
+func TestJWKSSkipsUnusableKeys(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t,
+		map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(key.N), "e": "AQAB"},
+		rsaJWK("rsa-1", key),
+	)
+	validator := newTestJWKSValidator(t, server)
+
+	if _, err := validator.ValidateToken(externalToken(t, jwt.SigningMethodRS256, "rsa-1", key, nil)); err != nil {
+		t.Errorf("signing key next to unusable keys: %v", err)
+	}
+	if _, err := validator.ValidateToken(externalToken(t, jwt.SigningMethodRS256, "enc", key, nil)); err == nil {
+		t.Error("token accepted with an encryption key")
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is a signing or verification key bound to the signing method it is used with
+type jwtKey struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+// WithSigningKey signs new tokens with privateKey (RSA for RS256/PS256, ECDSA for ES256) and sets
+// their kid header. Its public key is added to the verification keys
+func WithSigningKey(kid string, method jwt.SigningMethod, privateKey crypto.Signer) JWTServiceOption {
+	return func(s *jwtService) {
+		s.signingKID = kid
+		s.signingKey = &jwtKey{method: method, key: privateKey}
+		s.verificationKeys[kid] = jwtKey{method: method, key: privateKey.Public()}
+	}
+}
+
+// WithVerificationKey accepts tokens whose kid header is kid, e.g. tokens signed by the previous
+// key during a rotation or by another issuer
+func WithVerificationKey(kid string, method jwt.SigningMethod, publicKey crypto.PublicKey) JWTServiceOption {
+	return func(s *jwtService) {
+		s.verificationKeys[kid] = jwtKey{method: method, key: publicKey}
+	}
+}
+
+// ParsePrivateKeyPEM parses a PKCS#1, PKCS#8 or SEC 1 PEM encoded RSA or ECDSA private key
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS#1 PEM encoded RSA or ECDSA public key, or the key of a certificate
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// LoadPrivateKeyPEMFile reads and parses a PEM encoded private key file
+func LoadPrivateKeyPEMFile(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKeyPEM(data)
+}
+
+// LoadPublicKeyPEMFile reads and parses a PEM encoded public key or certificate file
+func LoadPublicKeyPEMFile(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKeyPEM(data)
+}
+
+// sign signs claims with the signing key and its kid, or HS256 with the secret when none is configured
+func (s *jwtService) sign(claims jwt.Claims) (string, error) {
+	if s.signingKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secretKey)
+	}
+
+	token := jwt.NewWithClaims(s.signingKey.method, claims)
+	token.Header["kid"] = s.signingKID
+	return token.SignedString(s.signingKey.key)
+}
+
+// keyFunc returns the verification key of a token: the key of its kid, whose signing method family must
+// match the token alg, or the HS256 secret for tokens without kid when a secret is configured
+func (s *jwtService) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(s.secretKey) == 0 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return s.secretKey, nil
+	}
+
+	key, ok := s.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if methodFamily(token.Method) != methodFamily(key.method) {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.key, nil
+}
+
+// methodFamily returns the key type a signing method works with: "RSA" (RS*, PS*), "EC" (ES*) or "HMAC" (HS*)
+func methodFamily(method jwt.SigningMethod) string {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return "RSA"
+	case *jwt.SigningMethodECDSA:
+		return "EC"
+	case *jwt.SigningMethodHMAC:
+		return "HMAC"
+	default:
+		return ""
+	}
+}
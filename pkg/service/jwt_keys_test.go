@@ -0,0 +1,214 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// newRSAKey generates a test RSA key
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newECKey generates a test P-256 key
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// issueTestToken issues a token of user u1 with jwtService
+func issueTestToken(t *testing.T, jwtService JWTService) string {
+	t.Helper()
+	token, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1"}, nil, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// tokenKID returns the kid header of token
+func tokenKID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	before := NewJWTService("", nil, WithSigningKey("2024-01", jwt.SigningMethodRS256, oldKey))
+	oldToken := issueTestToken(t, before)
+
+	after := NewJWTService("", nil,
+		WithSigningKey("2024-06", jwt.SigningMethodRS256, newKey),
+		WithVerificationKey("2024-01", jwt.SigningMethodRS256, oldKey.Public()),
+	)
+	newToken := issueTestToken(t, after)
+
+	if kid := tokenKID(t, newToken); kid != "2024-06" {
+		t.Errorf("kid of the new token = %q, want the new key", kid)
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if claims, err := after.ValidateToken(token); err != nil || claims.User.ID != "u1" {
+			t.Errorf("%s token: %v, want it valid after the rotation", name, err)
+		}
+	}
+	if _, err := before.ValidateToken(newToken); err == nil {
+		t.Error("token of the new key accepted by a service that does not know it")
+	}
+}
+
+func TestSigningKeyES256(t *testing.T) {
+	jwtService := NewJWTService("", nil, WithSigningKey("ec-1", jwt.SigningMethodES256, newECKey(t)))
+
+	token := issueTestToken(t, jwtService)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if parsed.Method.Alg() != "ES256" {
+		t.Errorf("alg = %s, want ES256", parsed.Method.Alg())
+	}
+	if _, err := jwtService.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken = %v", err)
+	}
+}
+
+func TestValidateTokenRejectsKeyMismatch(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	jwtService := NewJWTService("", nil, WithSigningKey("rsa-1", jwt.SigningMethodRS256, rsaKey))
+	claims := jwt.MapClaims{"sub": "u1", "iss": "test", "sid": "sid-1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	publicDER, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+
+	tests := map[string]string{
+		"unknown kid":                 sign(jwt.SigningMethodRS256, "rsa-2", rsaKey),
+		"HS256 with the RSA kid":      sign(jwt.SigningMethodHS256, "rsa-1", publicDER),
+		"ES256 with the RSA kid":      sign(jwt.SigningMethodES256, "rsa-1", newECKey(t)),
+		"HS256 without kid or secret": sign(jwt.SigningMethodHS256, "", []byte("")),
+		"RS256 without kid":           sign(jwt.SigningMethodRS256, "", rsaKey),
+	}
+	for name, token := range tests {
+		if _, err := jwtService.ValidateToken(token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestHS256StillSupported(t *testing.T) {
+	jwtService := NewJWTService("test-secret", nil)
+	token := issueTestToken(t, jwtService)
+
+	if kid := tokenKID(t, token); kid != "" {
+		t.Errorf("kid = %q, want none for HS256 tokens", kid)
+	}
+	if _, err := jwtService.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken = %v", err)
+	}
+	if _, err := NewJWTService("other-secret", nil).ValidateToken(token); err == nil {
+		t.Error("token accepted with another secret")
+	}
+}
+
+func TestParseKeysPEM(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t), newECKey(t)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	pkix, _ := x509.MarshalPKIXPublicKey(ecKey.Public())
+	encode := func(blockType string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	}
+
+	privateKeys := map[string][]byte{
+		"PKCS#1": encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+		"PKCS#8": encode("PRIVATE KEY", pkcs8),
+		"SEC 1":  encode("EC PRIVATE KEY", sec1),
+	}
+	for name, data := range privateKeys {
+		if _, err := ParsePrivateKeyPEM(data); err != nil {
+			t.Errorf("ParsePrivateKeyPEM(%s) = %v", name, err)
+		}
+	}
+
+	publicKeys := map[string][]byte{
+		"PKIX":   encode("PUBLIC KEY", pkix),
+		"PKCS#1": encode("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)),
+	}
+	for name, data := range publicKeys {
+		if _, err := ParsePublicKeyPEM(data); err != nil {
+			t.Errorf("ParsePublicKeyPEM(%s) = %v", name, err)
+		}
+	}
+
+	for name, data := range map[string][]byte{"no PEM": []byte("not a key"), "wrong type": encode("CERTIFICATE REQUEST", nil)} {
+		if _, err := ParsePrivateKeyPEM(data); err == nil {
+			t.Errorf("ParsePrivateKeyPEM(%s) succeeded", name)
+		}
+		if _, err := ParsePublicKeyPEM(data); err == nil {
+			t.Errorf("ParsePublicKeyPEM(%s) succeeded", name)
+		}
+	}
+}
+
+func TestLoadKeysPEMFile(t *testing.T) {
+	key := newECKey(t)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkix, _ := x509.MarshalPKIXPublicKey(key.Public())
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "key.pem"), filepath.Join(dir, "key.pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := LoadPrivateKeyPEMFile(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := LoadPublicKeyPEMFile(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing := NewJWTService("", nil, WithSigningKey("file", jwt.SigningMethodES256, signer))
+	verifying := NewJWTService("", nil, WithVerificationKey("file", jwt.SigningMethodES256, publicKey))
+	if _, err := verifying.ValidateToken(issueTestToken(t, signing)); err != nil {
+		t.Errorf("token signed with the file key: %v", err)
+	}
+
+	if _, err := LoadPrivateKeyPEMFile(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
@@ -2,11 +2,17 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/thanhthanh221/msa-core/pkg/common"
 	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
 	"github.com/thanhthanh221/msa-core/pkg/models"
 )
@@ -14,46 +20,149 @@ import (
 // JWTService issues and validates JWT access/refresh tokens.
 type JWTService interface {
 	GenerateToken(user models.OAuthUser, scopes []string, issuer, sid string, expiresIn time.Duration) (string, error)
+	// GenerateTokenWithRoles issues an access token carrying roles, GenerateToken uses the user roles
+	GenerateTokenWithRoles(user models.OAuthUser, scopes, roles []string, issuer, sid string, expiresIn time.Duration) (string, error)
+	// IssueToken issues an access token described by req, including custom claims
+	IssueToken(req TokenRequest) (string, error)
 	GenerateRefreshToken(user models.OAuthUser, issuer, sid string, expiresIn time.Duration) (string, error)
 
 	RefreshToken(tokenString string, expiresIn time.Duration) (string, error)
 
 	ValidateToken(tokenString string) (*models.JWTClaims, error)
 	ValidateRefreshToken(tokenString string) (userID string, sid string, err error)
+
+	// BlacklistToken revokes a token until it expires
+	BlacklistToken(ctx context.Context, tokenString string) error
+	IsTokenBlacklisted(ctx context.Context, tokenString string) (bool, error)
+	// RevokeAllForUser revokes every token of userID issued up to the current second included, the
+	// blacklist store must implement UserRevocationStore
+	RevokeAllForUser(ctx context.Context, userID string) error
+
+	// Introspect describes a token (RFC 7662), invalid, expired and revoked tokens are inactive
+	Introspect(ctx context.Context, tokenString string) (models.IntrospectionResponse, error)
+	// UserInfo returns the user of a valid, non revoked token
+	UserInfo(ctx context.Context, tokenString string) (*models.OAuthUser, error)
+	// CreateSession starts the session sid of userID for ttl, ValidateToken rejects the tokens of a sid
+	// without session. It does nothing for a stateless service (nil Redis client)
+	CreateSession(ctx context.Context, sid, userID string, ttl time.Duration) error
+	// ExtendSession extends the session sid to ttl, ErrSessionNotFound when it ended or expired
+	ExtendSession(ctx context.Context, sid string, ttl time.Duration) error
+	// DeleteSession ends the session sid, invalidating every access token issued for it
+	DeleteSession(ctx context.Context, sid string) error
+
+	// ExtractUser returns the user of a fully validated token (signature, expiry, session and blacklist)
+	ExtractUser(ctx context.Context, tokenString string) (*models.OAuthUser, error)
+	// ExtractUserUnverified returns the user of a token WITHOUT verifying it. Anyone can forge its
+	// output: never use it for authentication or authorization, only to log or debug a rejected token
+	ExtractUserUnverified(tokenString string) (*models.OAuthUser, error)
 }
 
+// ErrTokenRevoked is returned by ValidateToken for tokens revoked by RevokeAllForUser
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// ErrSessionNotFound is returned by ExtendSession for ended or expired sessions
+var ErrSessionNotFound = errors.New("session not found")
+
 type jwtService struct {
 	secretKey []byte
 	redis     redis.RedisClient
+	blacklist TokenBlacklistStore
+	// consumeMu serializes ConsumeToken for blacklist stores without AddIfAbsent
+	consumeMu sync.Mutex
+	// signingKey signs new tokens with signingKID, HS256 with secretKey is used when nil
+	signingKey *jwtKey
+	signingKID string
+	// verificationKeys maps kid headers to the keys validating tokens
+	verificationKeys map[string]jwtKey
+}
+
+// JWTServiceOption configures NewJWTService
+type JWTServiceOption func(*jwtService)
+
+// WithTokenBlacklistStore stores revoked tokens in store, e.g. NewRedisTokenBlacklistStore so logout
+// revokes tokens on every replica. The default store is process-local
+func WithTokenBlacklistStore(store TokenBlacklistStore) JWTServiceOption {
+	return func(s *jwtService) {
+		if store != nil {
+			s.blacklist = store
+		}
+	}
 }
 
+// defaultBlacklistTTL keeps revoked tokens without expiry claim blacklisted for this duration
+const defaultBlacklistTTL = 24 * time.Hour
+
+// userRevocationTTL keeps RevokeAllForUser records, it must outlive the longest token (refresh tokens included)
+const userRevocationTTL = 30 * 24 * time.Hour
+
 const redisSessionKeyPrefix = "session:"
 
 func sessionRedisKey(sid string) string { return redisSessionKeyPrefix + sid }
 
-func NewJWTService(secretKey string, redisClient redis.RedisClient) JWTService {
-	return &jwtService{
+// NewJWTService creates a JWTService signing with secretKey (HS256) unless WithSigningKey is used.
+// ValidateToken checks the session of the token sid in redisClient, a nil client issues stateless
+// tokens whose sessions are not checked
+func NewJWTService(secretKey string, redisClient redis.RedisClient, opts ...JWTServiceOption) JWTService {
+	s := &jwtService{
 		secretKey: []byte(secretKey),
 		redis:     redisClient,
+		blacklist: NewMemoryTokenBlacklistStore(),
+
+		verificationKeys: make(map[string]jwtKey),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *jwtService) GenerateToken(user models.OAuthUser, scopes []string, issuer, sid string, expiresIn time.Duration) (string, error) {
+	return s.GenerateTokenWithRoles(user, scopes, user.Roles, issuer, sid, expiresIn)
+}
+
+func (s *jwtService) GenerateTokenWithRoles(user models.OAuthUser, scopes, roles []string, issuer, sid string, expiresIn time.Duration) (string, error) {
+	return s.IssueToken(TokenRequest{
+		User:      user,
+		Scopes:    scopes,
+		Roles:     roles,
+		Issuer:    issuer,
+		SID:       sid,
+		ExpiresIn: expiresIn,
+	})
+}
+
+// TokenRequest describes an access token issued by IssueToken
+type TokenRequest struct {
+	User   models.OAuthUser
+	Scopes []string
+	Roles  []string
+	// Extra are custom claims (e.g. models.ClaimTenantID), returned by ValidateToken in JWTClaims.Extra
+	Extra     map[string]any
+	Issuer    string
+	SID       string
+	ExpiresIn time.Duration
+}
+
+func (s *jwtService) IssueToken(req TokenRequest) (string, error) {
+	now := time.Now()
 	claims := models.JWTClaims{
-		User:   user,
-		SID:    sid,
-		Scopes: scopes,
+		User:      req.User,
+		SID:       req.SID,
+		Scopes:    common.NormalizeScopes(req.Scopes),
+		Roles:     req.Roles,
+		Extra:     req.Extra,
+		TokenType: models.TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    issuer,
-			Subject:   user.ID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(req.ExpiresIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    req.Issuer,
+			Subject:   req.User.ID,
+			ID:        uuid.NewString(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString(s.secretKey)
+	signed, err := s.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -65,13 +174,13 @@ func (s *jwtService) GenerateRefreshToken(user models.OAuthUser, issuer, sid str
 		"sub": user.ID,
 		"iss": issuer,
 		"sid": sid,
-		"typ": "refresh",
+		"typ": models.TokenTypeRefresh,
+		"jti": uuid.NewString(),
 		"exp": time.Now().Add(expiresIn).Unix(),
 		"iat": time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString(s.secretKey)
+	signed, err := s.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -79,13 +188,7 @@ func (s *jwtService) GenerateRefreshToken(user models.OAuthUser, issuer, sid str
 }
 
 func (s *jwtService) ValidateToken(tokenString string) (*models.JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			fmt.Println("unexpected signing method")
-			return nil, errors.New("unexpected signing method")
-		}
-		return s.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, s.keyFunc)
 
 	if err != nil {
 		fmt.Println("error parsing token", err)
@@ -98,13 +201,19 @@ func (s *jwtService) ValidateToken(tokenString string) (*models.JWTClaims, error
 		return nil, errors.New("invalid token")
 	}
 
+	// Refresh and other typed tokens are not access tokens, tokens without typ predate it
+	if claims.TokenType != "" && claims.TokenType != models.TokenTypeAccess {
+		return nil, errors.New("invalid token type")
+	}
+
 	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
 		fmt.Println("token expired")
 		return nil, errors.New("token expired or invalid")
 	}
 
 	// Validate session existence by SID (logout invalidates immediately by deleting the session key).
-	if claims.SID == "" {
+	// Without Redis client tokens are stateless and have no session.
+	if claims.SID == "" && s.redis != nil {
 		fmt.Println("missing session id")
 		return nil, errors.New("missing session id")
 	}
@@ -116,6 +225,17 @@ func (s *jwtService) ValidateToken(tokenString string) (*models.JWTClaims, error
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
+	userID := claims.User.ID
+	if userID == "" {
+		userID = claims.Subject
+	}
+	if err := s.checkUserRevocation(ctx, userID, claims.IssuedAt); err != nil {
+		return nil, err
+	}
+
+	if s.redis == nil {
+		return claims, nil
+	}
 	exists, err := s.redis.Exists(ctx, sessionRedisKey(claims.SID))
 	if err != nil {
 		fmt.Println("error checking session existence", err)
@@ -135,13 +255,19 @@ func (s *jwtService) RefreshToken(tokenString string, expiresIn time.Duration) (
 		fmt.Println("error refreshing token", err)
 		return "", err
 	}
-	return s.GenerateToken(claims.User, claims.Scopes, claims.Issuer, claims.SID, expiresIn)
+	return s.IssueToken(TokenRequest{
+		User:      claims.User,
+		Scopes:    claims.Scopes,
+		Roles:     claims.AllRoles(),
+		Extra:     claims.Extra,
+		Issuer:    claims.Issuer,
+		SID:       claims.SID,
+		ExpiresIn: expiresIn,
+	})
 }
 
 func (s *jwtService) ValidateRefreshToken(tokenString string) (string, string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return s.secretKey, nil
-	})
+	token, err := jwt.Parse(tokenString, s.keyFunc)
 
 	if err != nil {
 		fmt.Println("error parsing refresh token", err)
@@ -154,7 +280,7 @@ func (s *jwtService) ValidateRefreshToken(tokenString string) (string, string, e
 		return "", "", errors.New("invalid refresh token")
 	}
 
-	if claims["typ"] != "refresh" {
+	if claims["typ"] != models.TokenTypeRefresh {
 		fmt.Println("invalid refresh token type")
 		return "", "", errors.New("invalid refresh token type")
 	}
@@ -171,5 +297,238 @@ func (s *jwtService) ValidateRefreshToken(tokenString string) (string, string, e
 		return "", "", errors.New("missing session id")
 	}
 
+	issuedAt, _ := claims.GetIssuedAt()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.checkUserRevocation(ctx, userID, issuedAt); err != nil {
+		return "", "", err
+	}
+
 	return userID, sid, nil
 }
+
+// BlacklistToken revokes tokenString until its expiry claim, the signature is not verified
+// so already invalid tokens can be revoked too. Tokens are keyed by jti when present, so only
+// revoke tokens the caller was authenticated with
+func (s *jwtService) BlacklistToken(ctx context.Context, tokenString string) error {
+	if tokenString == "" {
+		return errors.New("token is required")
+	}
+
+	key, expiresAt := blacklistKey(tokenString)
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Expired tokens are rejected by ValidateToken anyway
+		return nil
+	}
+	return s.blacklist.Add(ctx, key, ttl)
+}
+
+// TokenConsumer is implemented by JWT services able to consume single use tokens atomically, the
+// AuthController refresh rotation relies on it
+type TokenConsumer interface {
+	// ConsumeToken blacklists tokenString until its expiry claim and reports whether this call revoked
+	// it, false when it was already blacklisted
+	ConsumeToken(ctx context.Context, tokenString string) (bool, error)
+}
+
+// ConsumeToken blacklists tokenString and reports whether it was not blacklisted yet, so only one of
+// concurrent calls with the same token succeeds. Stores implementing ConsumableBlacklistStore make it
+// atomic across replicas, other stores within the process only
+func (s *jwtService) ConsumeToken(ctx context.Context, tokenString string) (bool, error) {
+	if tokenString == "" {
+		return false, errors.New("token is required")
+	}
+
+	key, expiresAt := blacklistKey(tokenString)
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Expired tokens are rejected by ValidateToken anyway
+		return false, nil
+	}
+	if store, ok := s.blacklist.(ConsumableBlacklistStore); ok {
+		return store.AddIfAbsent(ctx, key, ttl)
+	}
+
+	s.consumeMu.Lock()
+	defer s.consumeMu.Unlock()
+	revoked, err := s.blacklist.Exists(ctx, key)
+	if err != nil || revoked {
+		return false, err
+	}
+	if err := s.blacklist.Add(ctx, key, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsTokenBlacklisted reports whether tokenString was revoked and has not expired yet
+func (s *jwtService) IsTokenBlacklisted(ctx context.Context, tokenString string) (bool, error) {
+	key, _ := blacklistKey(tokenString)
+	return s.blacklist.Exists(ctx, key)
+}
+
+// RevokeAllForUser revokes every token of userID whose iat is at or before the current second.
+// As iat has a one second precision, tokens issued later in that same second are revoked too:
+// only tokens issued from the next second on stay valid
+func (s *jwtService) RevokeAllForUser(ctx context.Context, userID string) error {
+	if userID == "" {
+		return errors.New("user id is required")
+	}
+	store, ok := s.blacklist.(UserRevocationStore)
+	if !ok {
+		return fmt.Errorf("token blacklist store %T cannot revoke users", s.blacklist)
+	}
+	return store.SetNotBefore(ctx, userID, time.Now().Truncate(time.Second), userRevocationTTL)
+}
+
+// checkUserRevocation returns ErrTokenRevoked when userID was revoked at or after issuedAt, compared in
+// whole seconds. Tokens without iat of a revoked user are rejected
+func (s *jwtService) checkUserRevocation(ctx context.Context, userID string, issuedAt *jwt.NumericDate) error {
+	store, ok := s.blacklist.(UserRevocationStore)
+	if !ok || userID == "" {
+		return nil
+	}
+	notBefore, err := store.NotBefore(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !notBefore.IsZero() && (issuedAt == nil || !issuedAt.Time.After(notBefore)) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// validateActive validates tokenString and rejects it when blacklisted
+func (s *jwtService) validateActive(ctx context.Context, tokenString string) (*models.JWTClaims, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.IsTokenBlacklisted(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// Introspect describes tokenString. A token failing validation is reported inactive without error,
+// errors are only returned when the blacklist cannot be checked
+func (s *jwtService) Introspect(ctx context.Context, tokenString string) (models.IntrospectionResponse, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return models.IntrospectionResponse{Active: false}, nil
+	}
+	revoked, err := s.IsTokenBlacklisted(ctx, tokenString)
+	if err != nil {
+		return models.IntrospectionResponse{}, err
+	}
+	if revoked {
+		return models.IntrospectionResponse{Active: false}, nil
+	}
+
+	response := models.IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		Username:  claims.User.Email,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}
+	if response.Sub == "" {
+		response.Sub = claims.User.ID
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		response.Nbf = claims.NotBefore.Unix()
+	}
+	return response, nil
+}
+
+// UserInfo returns the user of tokenString after full validation (signature, expiry, session and blacklist)
+func (s *jwtService) UserInfo(ctx context.Context, tokenString string) (*models.OAuthUser, error) {
+	claims, err := s.validateActive(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	user := claims.User
+	user.Roles = claims.AllRoles()
+	return &user, nil
+}
+
+func (s *jwtService) CreateSession(ctx context.Context, sid, userID string, ttl time.Duration) error {
+	if sid == "" {
+		return errors.New("session id is required")
+	}
+	if s.redis == nil {
+		return nil
+	}
+	return s.redis.Set(ctx, sessionRedisKey(sid), userID, ttl)
+}
+
+func (s *jwtService) ExtendSession(ctx context.Context, sid string, ttl time.Duration) error {
+	if s.redis == nil {
+		return nil
+	}
+	exists, err := s.redis.Exists(ctx, sessionRedisKey(sid))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSessionNotFound
+	}
+	return s.redis.Expire(ctx, sessionRedisKey(sid), ttl)
+}
+
+func (s *jwtService) DeleteSession(ctx context.Context, sid string) error {
+	if s.redis == nil || sid == "" {
+		return nil
+	}
+	return s.redis.Del(ctx, sessionRedisKey(sid))
+}
+
+// ExtractUser returns the user of tokenString after full validation, like UserInfo
+func (s *jwtService) ExtractUser(ctx context.Context, tokenString string) (*models.OAuthUser, error) {
+	return s.UserInfo(ctx, tokenString)
+}
+
+// ExtractUserUnverified decodes the user of tokenString without checking its signature, expiry,
+// session or revocation. UNSAFE: a forged token yields whatever user it claims, use ExtractUser
+// for any security decision
+func (s *jwtService) ExtractUserUnverified(tokenString string) (*models.OAuthUser, error) {
+	claims := &models.JWTClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, err
+	}
+	user := claims.User
+	user.Roles = claims.AllRoles()
+	return &user, nil
+}
+
+// blacklistKey returns the blacklist key of tokenString, its jti when present or else its SHA-256,
+// and the time until which it must stay blacklisted
+func blacklistKey(tokenString string) (string, time.Time) {
+	expiresAt := time.Now().Add(defaultBlacklistTTL)
+	claims := &models.JWTClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err == nil {
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+		if claims.ID != "" {
+			return "jti:" + claims.ID, expiresAt
+		}
+	}
+
+	sum := sha256.Sum256([]byte(tokenString))
+	return "sha256:" + hex.EncodeToString(sum[:]), expiresAt
+}
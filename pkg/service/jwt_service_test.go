@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+func TestGenerateTokenSetsJTI(t *testing.T) {
+	jwtService := NewJWTService("test-secret", nil)
+
+	first, err := jwtService.ValidateToken(issueTestToken(t, jwtService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := jwtService.ValidateToken(issueTestToken(t, jwtService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == "" || first.ID == second.ID {
+		t.Errorf("jti = %q and %q, want a unique id per token", first.ID, second.ID)
+	}
+}
+
+func TestBlacklistSingleToken(t *testing.T) {
+	ctx := context.Background()
+	jwtService := NewJWTService("test-secret", nil)
+	revoked, kept := issueTestToken(t, jwtService), issueTestToken(t, jwtService)
+
+	if err := jwtService.BlacklistToken(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtService.UserInfo(ctx, revoked); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("UserInfo(revoked) = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := jwtService.UserInfo(ctx, kept); err != nil {
+		t.Errorf("UserInfo(other token of the user) = %v, want it valid", err)
+	}
+}
+
+func TestRevokeAllForUser(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range blacklistStores() {
+		t.Run(tt.name, func(t *testing.T) {
+			jwtService := NewJWTService("test-secret", nil, WithTokenBlacklistStore(tt.store))
+			access := issueTestToken(t, jwtService)
+			refresh, err := jwtService.GenerateRefreshToken(models.OAuthUser{ID: "u1"}, "test", "sid-1", time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := jwtService.GenerateToken(models.OAuthUser{ID: "u2"}, nil, "test", "sid-2", time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := jwtService.RevokeAllForUser(ctx, "u1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := jwtService.ValidateToken(access); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("access token = %v, want ErrTokenRevoked", err)
+			}
+			if _, _, err := jwtService.ValidateRefreshToken(refresh); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("refresh token = %v, want ErrTokenRevoked", err)
+			}
+			// Tokens issued in the revocation second are revoked too
+			if _, err := jwtService.ValidateToken(issueTestToken(t, jwtService)); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("token issued in the revocation second = %v, want ErrTokenRevoked", err)
+			}
+			if _, err := jwtService.ValidateToken(other); err != nil {
+				t.Errorf("token of another user = %v, want it valid", err)
+			}
+		})
+	}
+}
+
+func TestRevokeAllForUserKeepsNewerTokens(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range blacklistStores() {
+		t.Run(tt.name, func(t *testing.T) {
+			jwtService := NewJWTService("test-secret", nil, WithTokenBlacklistStore(tt.store))
+			// The user was revoked two seconds before the token was issued
+			revokedAt := time.Now().Add(-2 * time.Second)
+			if err := tt.store.(UserRevocationStore).SetNotBefore(ctx, "u1", revokedAt, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := jwtService.ValidateToken(issueTestToken(t, jwtService)); err != nil {
+				t.Errorf("token issued after the revocation = %v, want it valid", err)
+			}
+			notBefore, err := tt.store.(UserRevocationStore).NotBefore(ctx, "u1")
+			if err != nil || !notBefore.Equal(revokedAt.Truncate(time.Second)) {
+				t.Errorf("NotBefore = %s, %v, want the revocation second", notBefore, err)
+			}
+		})
+	}
+}
+
+func TestRevokeAllForUserErrors(t *testing.T) {
+	ctx := context.Background()
+	if err := NewJWTService("test-secret", nil).RevokeAllForUser(ctx, ""); err == nil {
+		t.Error("RevokeAllForUser without user id succeeded")
+	}
+
+	store := &recordingBlacklistStore{TokenBlacklistStore: NewMemoryTokenBlacklistStore(), keys: map[string]time.Duration{}}
+	jwtService := NewJWTService("test-secret", nil, WithTokenBlacklistStore(store))
+	if err := jwtService.RevokeAllForUser(ctx, "u1"); err == nil {
+		t.Error("RevokeAllForUser succeeded with a store that cannot revoke users")
+	}
+	if _, err := jwtService.ValidateToken(issueTestToken(t, jwtService)); err != nil {
+		t.Errorf("ValidateToken = %v, want user revocation skipped without UserRevocationStore", err)
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	ctx := context.Background()
+	jwtService := NewJWTService("test-secret", nil)
+	valid, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1", Email: "u1@example.com"}, []string{"orders:read", "profile"}, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := jwtService.IssueToken(TokenRequest{User: models.OAuthUser{ID: "u1"}, Issuer: "test", SID: "sid-1", ExpiresIn: -time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked := issueTestToken(t, jwtService)
+	if err := jwtService.BlacklistToken(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := jwtService.Introspect(ctx, valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Active || response.Sub != "u1" || response.Username != "u1@example.com" || response.TokenType != "Bearer" {
+		t.Errorf("response = %+v, want the active token described", response)
+	}
+	if response.Scope == "" || response.Jti == "" || response.Iss != "test" || response.Exp <= response.Iat {
+		t.Errorf("response = %+v, want scope, jti, iss, exp and iat", response)
+	}
+
+	for name, token := range map[string]string{"expired": expired, "revoked": revoked, "garbage": "not-a-token"} {
+		response, err := jwtService.Introspect(ctx, token)
+		if err != nil || response.Active || response.Sub != "" {
+			t.Errorf("%s: %+v, %v, want an inactive response without details", name, response, err)
+		}
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	ctx := context.Background()
+	jwtService := NewJWTService("test-secret", nil)
+	token, err := jwtService.GenerateTokenWithRoles(models.OAuthUser{ID: "u1", Email: "u1@example.com"}, nil, []string{"admin"}, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := jwtService.UserInfo(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "u1" || len(user.Roles) != 1 || user.Roles[0] != "admin" {
+		t.Errorf("user = %+v, want the user with its roles", user)
+	}
+
+	forged, err := NewJWTService("other-secret", nil).GenerateToken(models.OAuthUser{ID: "admin"}, nil, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtService.UserInfo(ctx, forged); err == nil {
+		t.Error("UserInfo of a forged token succeeded")
+	}
+	if user, err := jwtService.ExtractUserUnverified(forged); err != nil || user.ID != "admin" {
+		t.Errorf("ExtractUserUnverified = %+v, %v, want the claimed user without verification", user, err)
+	}
+}
+
+func TestCustomClaimsRoundTrip(t *testing.T) {
+	jwtService := NewJWTService("test-secret", nil)
+	token, err := jwtService.IssueToken(TokenRequest{
+		User:   models.OAuthUser{ID: "u1"},
+		Issuer: "test",
+		Extra: map[string]any{
+			models.ClaimTenantID: "acme",
+			"plan_level":         3,
+			"features":           map[string]any{"beta": true, "limits": []any{1, "two"}},
+		},
+		ExpiresIn: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenantID, ok := claims.GetStringClaim(models.ClaimTenantID); !ok || tenantID != "acme" {
+		t.Errorf("tenant id = %q, %v, want acme", tenantID, ok)
+	}
+	if level, ok := claims.GetInt64Claim("plan_level"); !ok || level != 3 {
+		t.Errorf("plan_level = %d, %v, want 3", level, ok)
+	}
+	if _, ok := claims.GetInt64Claim(models.ClaimTenantID); ok {
+		t.Error("GetInt64Claim of a string claim reports ok")
+	}
+	if _, ok := claims.GetStringClaim("missing"); ok {
+		t.Error("GetStringClaim of a missing claim reports ok")
+	}
+	features, ok := claims.GetClaim("features")
+	nested, _ := features.(map[string]any)
+	limits, _ := nested["limits"].([]any)
+	if !ok || nested["beta"] != true || len(limits) != 2 || limits[0] != float64(1) || limits[1] != "two" {
+		t.Errorf("features = %#v, want the nested values", features)
+	}
+
+	refreshed, err := jwtService.RefreshToken(token, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = jwtService.ValidateToken(refreshed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenantID, _ := claims.GetStringClaim(models.ClaimTenantID); tenantID != "acme" {
+		t.Errorf("refreshed tenant id = %q, want the custom claims kept", tenantID)
+	}
+}
+
+func TestTokenWithoutCustomClaims(t *testing.T) {
+	jwtService := NewJWTService("test-secret", nil)
+	claims, err := jwtService.ValidateToken(issueTestToken(t, jwtService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Extra != nil {
+		t.Errorf("Extra = %v, want none", claims.Extra)
+	}
+	if _, ok := claims.GetClaim(models.ClaimTenantID); ok {
+		t.Error("GetClaim of a token without custom claims reports ok")
+	}
+}
+
+func TestIssueTokenNormalizesScopes(t *testing.T) {
+	jwtService := NewJWTService("test-secret", nil)
+	token, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1"}, []string{"write", " read", "write", ""}, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claims.Scopes, []string{"read", "write"}) {
+		t.Errorf("scopes = %v, want [read write]", claims.Scopes)
+	}
+}
+
+func TestExtractUser(t *testing.T) {
+	ctx := context.Background()
+	jwtService := NewJWTService("test-secret", nil)
+	valid := issueTestToken(t, jwtService)
+	forged, err := NewJWTService("other-secret", nil).GenerateToken(models.OAuthUser{ID: "admin"}, nil, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1"}, nil, "test", "sid-1", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked := issueTestToken(t, jwtService)
+	if err := jwtService.BlacklistToken(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	if user, err := jwtService.ExtractUser(ctx, valid); err != nil || user.ID != "u1" {
+		t.Errorf("ExtractUser(valid) = %+v, %v, want u1", user, err)
+	}
+	for name, token := range map[string]string{"forged": forged, "expired": expired, "revoked": revoked, "garbage": "not-a-jwt"} {
+		if user, err := jwtService.ExtractUser(ctx, token); err == nil {
+			t.Errorf("ExtractUser(%s) = %+v, want an error", name, user)
+		}
+	}
+
+	// The unverified variant trusts whatever the token claims
+	if user, err := jwtService.ExtractUserUnverified(forged); err != nil || user.ID != "admin" {
+		t.Errorf("ExtractUserUnverified(forged) = %+v, %v, want the forged user", user, err)
+	}
+	if user, err := jwtService.ExtractUserUnverified(expired); err != nil || user.ID != "u1" {
+		t.Errorf("ExtractUserUnverified(expired) = %+v, %v, want the expired user", user, err)
+	}
+	if _, err := jwtService.ExtractUserUnverified("not-a-jwt"); err == nil {
+		t.Error("ExtractUserUnverified of a malformed token succeeded")
+	}
+}
+
+func TestValidateTokenRejectsOtherTokenTypes(t *testing.T) {
+	jwtService := NewJWTService("test-secret", nil)
+	refresh, err := jwtService.GenerateRefreshToken(models.OAuthUser{ID: "u1"}, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtService.ValidateToken(refresh); err == nil {
+		t.Error("ValidateToken accepted a refresh token")
+	}
+
+	claims, err := jwtService.ValidateToken(issueTestToken(t, jwtService))
+	if err != nil || claims.TokenType != models.TokenTypeAccess {
+		t.Errorf("access token = %+v, %v, want typ %q", claims, err, models.TokenTypeAccess)
+	}
+	if _, _, err := jwtService.ValidateRefreshToken(issueTestToken(t, jwtService)); err == nil {
+		t.Error("ValidateRefreshToken accepted an access token")
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// registerTestClient registers a client granted orders:write and profile
+func registerTestClient(t *testing.T, clients OAuthClientService) *models.ClientCredentials {
+	t.Helper()
+	credentials, err := clients.RegisterClient(context.Background(), " billing-worker ", []string{"profile", "orders:write", "profile"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credentials
+}
+
+func TestRegisterClient(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryOAuthClientStore()
+	clients := NewOAuthClientService(store, NewJWTService("test-secret", nil), nil)
+
+	credentials := registerTestClient(t, clients)
+	if credentials.ClientID == "" || len(credentials.ClientSecret) < 40 {
+		t.Fatalf("credentials = %+v, want a client id and a 256-bit secret", credentials)
+	}
+	if credentials.Client.Name != "billing-worker" || !slices.Equal(credentials.Client.Scopes, []string{"orders:write", "profile"}) {
+		t.Errorf("client = %+v, want the trimmed name and normalized scopes", credentials.Client)
+	}
+
+	stored, err := store.Get(ctx, credentials.ClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.SecretHash == "" || strings.Contains(stored.SecretHash, credentials.ClientSecret) {
+		t.Errorf("stored secret hash = %q, want the secret hashed", stored.SecretHash)
+	}
+	listed, err := clients.ListClients(ctx)
+	if err != nil || len(listed) != 1 || listed[0].ID != credentials.ClientID {
+		t.Errorf("ListClients = %v, %v, want the registered client", listed, err)
+	}
+
+	if _, err := clients.RegisterClient(ctx, "  ", nil, nil); err == nil {
+		t.Error("RegisterClient without a name succeeded")
+	}
+}
+
+func TestValidateClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	clients := NewOAuthClientService(NewMemoryOAuthClientStore(), NewJWTService("test-secret", nil), nil)
+	credentials := registerTestClient(t, clients)
+	revoked := registerTestClient(t, clients)
+	if err := clients.RevokeClient(ctx, revoked.ClientID); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := clients.ValidateClientCredentials(ctx, credentials.ClientID, credentials.ClientSecret)
+	if err != nil || client.ID != credentials.ClientID {
+		t.Fatalf("ValidateClientCredentials = %v, %v, want the client", client, err)
+	}
+	for name, pair := range map[string][2]string{
+		"wrong secret":   {credentials.ClientID, credentials.ClientSecret + "x"},
+		"other secret":   {credentials.ClientID, revoked.ClientSecret},
+		"unknown client": {"unknown", credentials.ClientSecret},
+		"revoked client": {revoked.ClientID, revoked.ClientSecret},
+		"empty secret":   {credentials.ClientID, ""},
+	} {
+		if _, err := clients.ValidateClientCredentials(ctx, pair[0], pair[1]); !errors.Is(err, ErrInvalidClientCredentials) {
+			t.Errorf("%s = %v, want ErrInvalidClientCredentials", name, err)
+		}
+	}
+	if err := clients.RevokeClient(ctx, "unknown"); !errors.Is(err, ErrOAuthClientNotFound) {
+		t.Errorf("RevokeClient of an unknown client = %v, want ErrOAuthClientNotFound", err)
+	}
+}
+
+func TestIssueClientToken(t *testing.T) {
+	ctx := context.Background()
+	rc := redistest.New()
+	jwtService := NewJWTService("test-secret", rc)
+	clients := NewOAuthClientService(NewMemoryOAuthClientStore(), jwtService, rc, WithClientTokenIssuer("billing"))
+	credentials := registerTestClient(t, clients)
+
+	response, err := clients.IssueClientToken(ctx, &credentials.Client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.TokenType != "Bearer" || response.ExpiresIn != 3600 || response.Scope != "orders:write profile" {
+		t.Errorf("response = %+v, want a 1 hour bearer token with every client scope", response)
+	}
+	claims, err := jwtService.ValidateToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken of a client token: %v", err)
+	}
+	if claims.User.ID != credentials.ClientID || claims.User.Provider != ClientTokenProvider || claims.Issuer != "billing" {
+		t.Errorf("claims = %+v, want the client as a client_credentials principal", claims)
+	}
+
+	// Scopes implied by the client scopes may be requested
+	response, err = clients.IssueClientToken(ctx, &credentials.Client, []string{"orders:read"})
+	if err != nil || response.Scope != "orders:read" {
+		t.Errorf("narrowed token = %+v, %v, want orders:read only", response, err)
+	}
+	if _, err := clients.IssueClientToken(ctx, &credentials.Client, []string{"orders:admin"}); !errors.Is(err, ErrInvalidClientScope) {
+		t.Errorf("token beyond the client scopes = %v, want ErrInvalidClientScope", err)
+	}
+
+	if err := clients.RevokeClient(ctx, credentials.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtService.ValidateToken(response.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token of a revoked client = %v, want ErrTokenRevoked", err)
+	}
+}
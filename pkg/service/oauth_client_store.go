@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/repositories"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// ErrOAuthClientNotFound is returned for unknown client ids
+var ErrOAuthClientNotFound = errors.New("oauth client not found")
+
+// OAuthClientStore stores the OAuth clients of OAuthClientService
+type OAuthClientStore interface {
+	Create(ctx context.Context, client *models.OAuthClient) error
+	// Get returns ErrOAuthClientNotFound for unknown clients, revoked clients are returned
+	Get(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	List(ctx context.Context) ([]models.OAuthClient, error)
+	Revoke(ctx context.Context, clientID string, revokedAt time.Time) error
+}
+
+type memoryOAuthClientStore struct {
+	mu      sync.RWMutex
+	clients map[string]models.OAuthClient
+}
+
+// NewMemoryOAuthClientStore returns a process-local OAuthClientStore, for tests and single instances
+func NewMemoryOAuthClientStore() OAuthClientStore {
+	return &memoryOAuthClientStore{clients: make(map[string]models.OAuthClient)}
+}
+
+func (s *memoryOAuthClientStore) Create(_ context.Context, client *models.OAuthClient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.clients[client.ID]; exists {
+		return errors.New("oauth client already exists")
+	}
+	s.clients[client.ID] = cloneOAuthClient(*client)
+	return nil
+}
+
+func (s *memoryOAuthClientStore) Get(_ context.Context, clientID string) (*models.OAuthClient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clients[clientID]
+	if !ok {
+		return nil, ErrOAuthClientNotFound
+	}
+	client = cloneOAuthClient(client)
+	return &client, nil
+}
+
+func (s *memoryOAuthClientStore) List(_ context.Context) ([]models.OAuthClient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clients := make([]models.OAuthClient, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, cloneOAuthClient(client))
+	}
+	slices.SortFunc(clients, func(a, b models.OAuthClient) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return clients, nil
+}
+
+func (s *memoryOAuthClientStore) Revoke(_ context.Context, clientID string, revokedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[clientID]
+	if !ok {
+		return ErrOAuthClientNotFound
+	}
+	client.RevokedAt = &revokedAt
+	client.UpdatedAt = revokedAt
+	s.clients[clientID] = client
+	return nil
+}
+
+// cloneOAuthClient copies the slices of client so callers cannot modify the stored client
+func cloneOAuthClient(client models.OAuthClient) models.OAuthClient {
+	client.Scopes = slices.Clone(client.Scopes)
+	client.RedirectURIs = slices.Clone(client.RedirectURIs)
+	if client.RevokedAt != nil {
+		revokedAt := *client.RevokedAt
+		client.RevokedAt = &revokedAt
+	}
+	return client
+}
+
+type gormOAuthClientStore struct {
+	repo repositories.Repository
+}
+
+// NewGormOAuthClientStore returns an OAuthClientStore persisting clients in the oauth_clients table
+// through repo, migrate it with db.AutoMigrate(&models.OAuthClient{})
+func NewGormOAuthClientStore(repo repositories.Repository) OAuthClientStore {
+	return &gormOAuthClientStore{repo: repo}
+}
+
+func (s *gormOAuthClientStore) Create(ctx context.Context, client *models.OAuthClient) error {
+	return s.repo.Create(ctx, client)
+}
+
+func (s *gormOAuthClientStore) Get(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := s.repo.GetOneByID(ctx, &client, clientID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (s *gormOAuthClientStore) List(ctx context.Context) ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	if err := s.repo.GetWhereWithOrder(ctx, &clients, "1 = 1", "created_at ASC", -1, -1, nil); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (s *gormOAuthClientStore) Revoke(ctx context.Context, clientID string, revokedAt time.Time) error {
+	if _, err := s.Get(ctx, clientID); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, &models.OAuthClient{}, map[string]interface{}{
+		"revoked_at": revokedAt,
+		"updated_at": revokedAt,
+	}, "id = ?", clientID)
+}
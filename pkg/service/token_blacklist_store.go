@@ -0,0 +1,338 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis"
+)
+
+// TokenBlacklistStore stores revoked token keys until their TTL elapses
+type TokenBlacklistStore interface {
+	// Add blacklists key for ttl, adding an already blacklisted key is not an error
+	Add(ctx context.Context, key string, ttl time.Duration) error
+	// Exists reports whether key is blacklisted
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// UserRevocationStore is implemented by blacklist stores able to revoke every token of a user,
+// JWTService.RevokeAllForUser requires it
+type UserRevocationStore interface {
+	// SetNotBefore revokes the tokens of userID issued at or before notBefore, in whole seconds. The
+	// record is kept for ttl
+	SetNotBefore(ctx context.Context, userID string, notBefore time.Time, ttl time.Duration) error
+	// NotBefore returns the revocation time of userID, zero when the user was never revoked
+	NotBefore(ctx context.Context, userID string) (time.Time, error)
+}
+
+// ConsumableBlacklistStore is implemented by blacklist stores able to blacklist a key only when it is not
+// yet in one atomic step, so concurrent uses of a single use token cannot both succeed
+type ConsumableBlacklistStore interface {
+	// AddIfAbsent blacklists key for ttl and reports whether it was absent, false when already blacklisted
+	AddIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// memoryTokenBlacklistStore keeps revoked keys in process memory, they are not shared between replicas
+type memoryTokenBlacklistStore struct {
+	mu sync.Mutex
+	// entries maps revoked keys to their expiry time
+	entries map[string]time.Time
+	// expiries orders the entries by expiry time, items outdated by a later Add are skipped
+	expiries blacklistHeap
+	// notBefore maps user ids to their memoryNotBefore
+	notBefore map[string]memoryNotBefore
+
+	maxEntries    int
+	sweepInterval time.Duration
+	ctx           context.Context
+	now           func() time.Time
+	logger        *logrus.Logger
+	onEvict       func(evicted int)
+	janitor       sync.Once
+}
+
+// memoryNotBefore is the revocation time of a user and the expiry of the record
+type memoryNotBefore struct {
+	notBefore time.Time
+	expiresAt time.Time
+}
+
+// MemoryBlacklistOption configures NewMemoryTokenBlacklistStore
+type MemoryBlacklistOption func(*memoryTokenBlacklistStore)
+
+// WithBlacklistMaxEntries caps the number of revoked keys, 100000 by default and unlimited when negative.
+// At the cap the keys expiring first are evicted, so their tokens become valid again until they expire
+func WithBlacklistMaxEntries(maxEntries int) MemoryBlacklistOption {
+	return func(s *memoryTokenBlacklistStore) {
+		if maxEntries != 0 {
+			s.maxEntries = maxEntries
+		}
+	}
+}
+
+// WithBlacklistSweepInterval sets how often expired keys are removed, every minute by default
+func WithBlacklistSweepInterval(interval time.Duration) MemoryBlacklistOption {
+	return func(s *memoryTokenBlacklistStore) {
+		if interval > 0 {
+			s.sweepInterval = interval
+		}
+	}
+}
+
+// WithBlacklistContext stops the background sweeper when ctx is done
+func WithBlacklistContext(ctx context.Context) MemoryBlacklistOption {
+	return func(s *memoryTokenBlacklistStore) {
+		if ctx != nil {
+			s.ctx = ctx
+		}
+	}
+}
+
+// WithBlacklistClock sets the clock of the store, time.Now by default
+func WithBlacklistClock(now func() time.Time) MemoryBlacklistOption {
+	return func(s *memoryTokenBlacklistStore) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// WithBlacklistLogger logs evictions at the cap, the standard logrus logger by default
+func WithBlacklistLogger(logger *logrus.Logger) MemoryBlacklistOption {
+	return func(s *memoryTokenBlacklistStore) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithBlacklistOnEvict is called with the number of keys evicted at the cap, e.g. to export a metric
+func WithBlacklistOnEvict(onEvict func(evicted int)) MemoryBlacklistOption {
+	return func(s *memoryTokenBlacklistStore) {
+		s.onEvict = onEvict
+	}
+}
+
+// defaultBlacklistMaxEntries caps the in-memory blacklist unless WithBlacklistMaxEntries is used
+const defaultBlacklistMaxEntries = 100000
+
+// NewMemoryTokenBlacklistStore creates a process-local blacklist store, the default of NewJWTService.
+// A background sweeper started on the first revocation removes expired keys
+func NewMemoryTokenBlacklistStore(opts ...MemoryBlacklistOption) TokenBlacklistStore {
+	s := &memoryTokenBlacklistStore{
+		entries:       make(map[string]time.Time),
+		notBefore:     make(map[string]memoryNotBefore),
+		maxEntries:    defaultBlacklistMaxEntries,
+		sweepInterval: time.Minute,
+		ctx:           context.Background(),
+		now:           time.Now,
+		logger:        logrus.StandardLogger(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *memoryTokenBlacklistStore) Add(ctx context.Context, key string, ttl time.Duration) error {
+	s.startJanitor()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt := s.now().Add(ttl)
+	if current, ok := s.entries[key]; ok && !current.Before(expiresAt) {
+		return nil
+	}
+	if _, ok := s.entries[key]; !ok && s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.evictLocked(len(s.entries) - s.maxEntries + 1)
+	}
+	s.entries[key] = expiresAt
+	heap.Push(&s.expiries, blacklistItem{key: key, expiresAt: expiresAt})
+	return nil
+}
+
+func (s *memoryTokenBlacklistStore) AddIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.startJanitor()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if expiresAt, ok := s.entries[key]; ok && expiresAt.After(now) {
+		return false, nil
+	}
+	if _, ok := s.entries[key]; !ok && s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.evictLocked(len(s.entries) - s.maxEntries + 1)
+	}
+	expiresAt := now.Add(ttl)
+	s.entries[key] = expiresAt
+	heap.Push(&s.expiries, blacklistItem{key: key, expiresAt: expiresAt})
+	return true, nil
+}
+
+func (s *memoryTokenBlacklistStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if !expiresAt.After(s.now()) {
+		delete(s.entries, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *memoryTokenBlacklistStore) SetNotBefore(ctx context.Context, userID string, notBefore time.Time, ttl time.Duration) error {
+	s.startJanitor()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notBefore[userID] = memoryNotBefore{notBefore: notBefore.Truncate(time.Second), expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryTokenBlacklistStore) NotBefore(ctx context.Context, userID string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.notBefore[userID]
+	if !ok {
+		return time.Time{}, nil
+	}
+	if !record.expiresAt.After(s.now()) {
+		delete(s.notBefore, userID)
+		return time.Time{}, nil
+	}
+	return record.notBefore, nil
+}
+
+// Sweep removes the expired keys and user revocations, the background sweeper calls it periodically
+func (s *memoryTokenBlacklistStore) Sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for s.expiries.Len() > 0 && !s.expiries[0].expiresAt.After(now) {
+		item := heap.Pop(&s.expiries).(blacklistItem)
+		if expiresAt, ok := s.entries[item.key]; ok && expiresAt.Equal(item.expiresAt) {
+			delete(s.entries, item.key)
+		}
+	}
+	for userID, record := range s.notBefore {
+		if !record.expiresAt.After(now) {
+			delete(s.notBefore, userID)
+		}
+	}
+}
+
+// evictLocked removes the count keys expiring first
+func (s *memoryTokenBlacklistStore) evictLocked(count int) {
+	evicted := 0
+	for evicted < count && s.expiries.Len() > 0 {
+		item := heap.Pop(&s.expiries).(blacklistItem)
+		if expiresAt, ok := s.entries[item.key]; ok && expiresAt.Equal(item.expiresAt) {
+			delete(s.entries, item.key)
+			evicted++
+		}
+	}
+	if evicted == 0 {
+		return
+	}
+	s.logger.Warnf("token blacklist: %d entries cap reached, evicted %d keys expiring first", s.maxEntries, evicted)
+	if s.onEvict != nil {
+		s.onEvict(evicted)
+	}
+}
+
+// startJanitor starts the background sweeper once
+func (s *memoryTokenBlacklistStore) startJanitor() {
+	s.janitor.Do(func() {
+		go func() {
+			ticker := time.NewTicker(s.sweepInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-s.ctx.Done():
+					return
+				case <-ticker.C:
+					s.Sweep()
+				}
+			}
+		}()
+	})
+}
+
+// blacklistItem is a revoked key in the expiry heap
+type blacklistItem struct {
+	key       string
+	expiresAt time.Time
+}
+
+// blacklistHeap is a min-heap of revoked keys by expiry time
+type blacklistHeap []blacklistItem
+
+func (h blacklistHeap) Len() int           { return len(h) }
+func (h blacklistHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h blacklistHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *blacklistHeap) Push(x any)        { *h = append(*h, x.(blacklistItem)) }
+func (h *blacklistHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// defaultBlacklistKeyPrefix prefixes the Redis keys of revoked tokens
+const defaultBlacklistKeyPrefix = "jwt:blacklist:"
+
+// redisTokenBlacklistStore keeps revoked keys in Redis, shared by every replica and expired by Redis
+type redisTokenBlacklistStore struct {
+	client redis.RedisClient
+	prefix string
+}
+
+// NewRedisTokenBlacklistStore creates a Redis blacklist store, prefix defaults to "jwt:blacklist:"
+func NewRedisTokenBlacklistStore(client redis.RedisClient, prefix string) TokenBlacklistStore {
+	if prefix == "" {
+		prefix = defaultBlacklistKeyPrefix
+	}
+	return &redisTokenBlacklistStore{client: client, prefix: prefix}
+}
+
+func (s *redisTokenBlacklistStore) Add(ctx context.Context, key string, ttl time.Duration) error {
+	// SetNX keeps the TTL of the first revocation, which is already bound to the token expiry
+	_, err := s.client.SetNX(ctx, s.prefix+key, "1", ttl)
+	return err
+}
+
+func (s *redisTokenBlacklistStore) AddIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, "1", ttl)
+}
+
+func (s *redisTokenBlacklistStore) Exists(ctx context.Context, key string) (bool, error) {
+	return s.client.Exists(ctx, s.prefix+key)
+}
+
+func (s *redisTokenBlacklistStore) SetNotBefore(ctx context.Context, userID string, notBefore time.Time, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+"user:"+userID, strconv.FormatInt(notBefore.Unix(), 10), ttl)
+}
+
+func (s *redisTokenBlacklistStore) NotBefore(ctx context.Context, userID string) (time.Time, error) {
+	value, err := s.client.Get(ctx, s.prefix+"user:"+userID)
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/thanhthanh221/msa-core/pkg/infrastructure/redis/redistest"
+	"github.com/thanhthanh221/msa-core/pkg/models"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1_700_000_000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// blacklistStoreCase is a store under test with the function advancing its clock
+type blacklistStoreCase struct {
+	name    string
+	store   TokenBlacklistStore
+	advance func(time.Duration)
+}
+
+func blacklistStores() []blacklistStoreCase {
+	clock := newFakeClock()
+	rc := redistest.New()
+	return []blacklistStoreCase{
+		{"memory", NewMemoryTokenBlacklistStore(WithBlacklistClock(clock.Now), WithBlacklistContext(canceledContext())), clock.Advance},
+		{"redis", NewRedisTokenBlacklistStore(rc, ""), rc.Advance},
+	}
+}
+
+// canceledContext stops the background sweeper of the memory stores under test
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestTokenBlacklistStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range blacklistStores() {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.store.Add(ctx, "jti:a", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if exists, err := tt.store.Exists(ctx, "jti:a"); err != nil || !exists {
+				t.Fatalf("Exists = %v, %v, want the added key", exists, err)
+			}
+			if exists, _ := tt.store.Exists(ctx, "jti:b"); exists {
+				t.Error("unknown key reported blacklisted")
+			}
+
+			// Adding again is not an error
+			if err := tt.store.Add(ctx, "jti:a", time.Minute); err != nil {
+				t.Errorf("second Add = %v", err)
+			}
+
+			tt.advance(59 * time.Second)
+			if exists, _ := tt.store.Exists(ctx, "jti:a"); !exists {
+				t.Error("key expired before its TTL")
+			}
+			tt.advance(time.Second)
+			if exists, _ := tt.store.Exists(ctx, "jti:a"); exists {
+				t.Error("key still blacklisted after its TTL")
+			}
+		})
+	}
+}
+
+func TestTokenBlacklistStoreAddIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range blacklistStores() {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store.(ConsumableBlacklistStore)
+			if added, err := store.AddIfAbsent(ctx, "jti:a", time.Minute); err != nil || !added {
+				t.Fatalf("first AddIfAbsent = %v, %v, want the key added", added, err)
+			}
+			if exists, _ := tt.store.Exists(ctx, "jti:a"); !exists {
+				t.Error("key added by AddIfAbsent not blacklisted")
+			}
+			if added, err := store.AddIfAbsent(ctx, "jti:a", time.Minute); err != nil || added {
+				t.Errorf("second AddIfAbsent = %v, %v, want the key already present", added, err)
+			}
+
+			// An expired key is absent again
+			tt.advance(time.Minute)
+			if added, err := store.AddIfAbsent(ctx, "jti:a", time.Minute); err != nil || !added {
+				t.Errorf("AddIfAbsent after the TTL = %v, %v, want the key added again", added, err)
+			}
+		})
+	}
+}
+
+func TestConsumeToken(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range blacklistStores() {
+		t.Run(tt.name, func(t *testing.T) {
+			jwtService := NewJWTService("test-secret", nil, WithTokenBlacklistStore(tt.store))
+			consumer := jwtService.(TokenConsumer)
+
+			token, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1"}, nil, "test", "sid-1", time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if consumed, err := consumer.ConsumeToken(ctx, token); err != nil || !consumed {
+				t.Fatalf("first ConsumeToken = %v, %v, want the token consumed", consumed, err)
+			}
+			if revoked, _ := jwtService.IsTokenBlacklisted(ctx, token); !revoked {
+				t.Error("consumed token not blacklisted")
+			}
+			if consumed, err := consumer.ConsumeToken(ctx, token); err != nil || consumed {
+				t.Errorf("second ConsumeToken = %v, %v, want the token already consumed", consumed, err)
+			}
+			if _, err := consumer.ConsumeToken(ctx, ""); err == nil {
+				t.Error("ConsumeToken of an empty token succeeded")
+			}
+		})
+	}
+}
+
+func TestRedisTokenBlacklistStoreKeys(t *testing.T) {
+	ctx := context.Background()
+	rc := redistest.New()
+	store := NewRedisTokenBlacklistStore(rc, "auth:revoked:")
+
+	if err := store.Add(ctx, "jti:a", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.TTL("auth:revoked:jti:a"); got != time.Hour {
+		t.Errorf("TTL of the prefixed key = %s, want 1h", got)
+	}
+	// SetNX keeps the TTL of the first revocation
+	rc.Advance(time.Minute)
+	if err := store.Add(ctx, "jti:a", 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := rc.TTL("auth:revoked:jti:a"); got != 59*time.Minute {
+		t.Errorf("TTL after a second Add = %s, want the first TTL kept", got)
+	}
+
+	rc.SetDown(true)
+	if _, err := store.Exists(ctx, "jti:a"); err == nil {
+		t.Error("Exists succeeded while redis is down")
+	}
+}
+
+// recordingBlacklistStore records the keys and TTLs added
+type recordingBlacklistStore struct {
+	TokenBlacklistStore
+	keys map[string]time.Duration
+}
+
+func (s *recordingBlacklistStore) Add(ctx context.Context, key string, ttl time.Duration) error {
+	s.keys[key] = ttl
+	return s.TokenBlacklistStore.Add(ctx, key, ttl)
+}
+
+func TestBlacklistTokenKeys(t *testing.T) {
+	ctx := context.Background()
+	store := &recordingBlacklistStore{TokenBlacklistStore: NewMemoryTokenBlacklistStore(), keys: map[string]time.Duration{}}
+	jwtService := NewJWTService("test-secret", nil, WithTokenBlacklistStore(store))
+
+	token, err := jwtService.GenerateToken(models.OAuthUser{ID: "u1"}, nil, "test", "sid-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jwtService.BlacklistToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	ttl, ok := store.keys["jti:"+claims.ID]
+	if !ok || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("keys = %v, want the jti blacklisted until the token expires", store.keys)
+	}
+	if revoked, err := jwtService.IsTokenBlacklisted(ctx, token); err != nil || !revoked {
+		t.Errorf("IsTokenBlacklisted = %v, %v, want the revoked token", revoked, err)
+	}
+
+	// Tokens without jti are keyed by their hash
+	if err := jwtService.BlacklistToken(ctx, "opaque-token"); err != nil {
+		t.Fatal(err)
+	}
+	hashed := false
+	for key := range store.keys {
+		hashed = hashed || strings.HasPrefix(key, "sha256:")
+	}
+	if !hashed {
+		t.Errorf("keys = %v, want the opaque token keyed by its hash", store.keys)
+	}
+
+	if err := jwtService.BlacklistToken(ctx, ""); err == nil {
+		t.Error("BlacklistToken of an empty token succeeded")
+	}
+}
+
+// newTestMemoryStore returns a memory store on clock whose background sweeper is stopped
+func newTestMemoryStore(clock *fakeClock, opts ...MemoryBlacklistOption) *memoryTokenBlacklistStore {
+	logger, _ := test.NewNullLogger()
+	opts = append([]MemoryBlacklistOption{WithBlacklistClock(clock.Now), WithBlacklistContext(canceledContext()), WithBlacklistLogger(logger)}, opts...)
+	return NewMemoryTokenBlacklistStore(opts...).(*memoryTokenBlacklistStore)
+}
+
+// entryCount returns the number of keys held by s, expired or not
+func (s *memoryTokenBlacklistStore) entryCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func TestMemoryBlacklistSweep(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	store := newTestMemoryStore(clock)
+
+	for key, ttl := range map[string]time.Duration{"jti:a": time.Minute, "jti:b": 2 * time.Minute, "jti:c": time.Hour} {
+		if err := store.Add(ctx, key, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Extending a key leaves an outdated heap item, it must not remove the key early
+	if err := store.Add(ctx, "jti:a", 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetNotBefore(ctx, "u1", clock.Now(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(2 * time.Minute)
+	store.Sweep()
+	if got := store.entryCount(); got != 2 {
+		t.Errorf("entries after the sweep = %d, want jti:a and jti:c", got)
+	}
+	if exists, _ := store.Exists(ctx, "jti:a"); !exists {
+		t.Error("extended key removed by the sweep")
+	}
+	store.mu.Lock()
+	_, kept := store.notBefore["u1"]
+	store.mu.Unlock()
+	if kept {
+		t.Error("expired user revocation kept by the sweep")
+	}
+
+	clock.Advance(time.Hour)
+	store.Sweep()
+	if got := store.entryCount(); got != 0 {
+		t.Errorf("entries once every key expired = %d, want 0", got)
+	}
+}
+
+func TestMemoryBlacklistCap(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	var evicted []int
+	store := newTestMemoryStore(clock, WithBlacklistMaxEntries(3), WithBlacklistOnEvict(func(n int) { evicted = append(evicted, n) }))
+
+	for key, ttl := range map[string]time.Duration{"jti:a": 3 * time.Minute, "jti:b": time.Minute, "jti:c": 2 * time.Minute} {
+		if err := store.Add(ctx, key, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Re-adding a key at the cap evicts nothing
+	if err := store.Add(ctx, "jti:c", 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 0 {
+		t.Fatalf("evictions = %v, want none below the cap", evicted)
+	}
+
+	if err := store.Add(ctx, "jti:d", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if store.entryCount() != 3 || len(evicted) != 1 || evicted[0] != 1 {
+		t.Fatalf("entries = %d, evictions = %v, want one eviction at the cap", store.entryCount(), evicted)
+	}
+	for key, want := range map[string]bool{"jti:a": true, "jti:b": false, "jti:c": true, "jti:d": true} {
+		if exists, _ := store.Exists(ctx, key); exists != want {
+			t.Errorf("Exists(%s) = %v, want %v: the key expiring first is evicted", key, exists, want)
+		}
+	}
+
+	unlimited := newTestMemoryStore(clock, WithBlacklistMaxEntries(-1))
+	for i := range 10 {
+		if err := unlimited.Add(ctx, "jti:"+strconv.Itoa(i), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := unlimited.entryCount(); got != 10 {
+		t.Errorf("unlimited store entries = %d, want 10", got)
+	}
+}
+
+func TestMemoryBlacklistJanitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := newFakeClock()
+	store := newTestMemoryStore(clock, WithBlacklistContext(ctx), WithBlacklistSweepInterval(time.Millisecond))
+
+	if err := store.Add(context.Background(), "jti:a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return store.entryCount() == 0 })
+
+	// Once the context is done the sweeper stops
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	if err := store.Add(context.Background(), "jti:b", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	if got := store.entryCount(); got != 1 {
+		t.Errorf("entries after the context was canceled = %d, want the expired key left unswept", got)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}